@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ConradIrwin/font/lint"
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// Check prints problems found in the font, most severe first.
+func Check(font *sfnt.Font) error {
+	findings := lint.Lint(font)
+	for _, f := range findings {
+		fmt.Println(f)
+	}
+
+	if lint.HasErrors(findings) {
+		return errors.New("font check failed")
+	}
+	if len(findings) == 0 {
+		fmt.Println("No problems found")
+	}
+	return nil
+}
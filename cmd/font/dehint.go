@@ -0,0 +1,17 @@
+package main
+
+import (
+	"os"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// Dehint removes the hinting instructions and tables, and writes the font to stdout.
+func Dehint(font *sfnt.Font) error {
+	if err := font.Dehint(); err != nil {
+		return err
+	}
+
+	_, err := font.WriteOTF(os.Stdout)
+	return err
+}
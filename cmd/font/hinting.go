@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ConradIrwin/font/hinting"
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// Hinting prints the gasp and cvt tables, and the hinting instructions of the
+// fpgm and prep tables and of each glyph.
+func Hinting(font *sfnt.Font) error {
+	if font.HasTable(sfnt.TagGasp) {
+		gasp, err := font.GaspTable()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Grid-fitting and scan conversion procedure (gasp):")
+		for _, r := range gasp.Ranges {
+			fmt.Printf("\tUp to %d ppem: %s\n", r.MaxPPEM, r)
+		}
+	}
+
+	if font.HasTable(sfnt.TagCvt) {
+		cvt, err := font.CvtTable()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Control values (cvt):")
+		for i, v := range cvt.Values {
+			fmt.Printf("\t%d: %d\n", i, v)
+		}
+	}
+
+	for _, program := range []struct {
+		tag  sfnt.Tag
+		name string
+	}{
+		{sfnt.TagFpgm, "Font program (fpgm)"},
+		{sfnt.TagPrep, "Control value program (prep)"},
+	} {
+		if !font.HasTable(program.tag) {
+			continue
+		}
+		t, err := font.Table(program.tag)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s:\n", program.name)
+		if err := printInstructions(t.Bytes()); err != nil {
+			return fmt.Errorf("%q: %w", program.tag, err)
+		}
+	}
+
+	if font.HasTable(sfnt.TagGlyf) {
+		glyf, err := font.GlyfTable()
+		if err != nil {
+			return err
+		}
+
+		for id := 0; id < glyf.NumGlyphs(); id++ {
+			glyph, err := glyf.Glyph(sfnt.GlyphID(id))
+			if err != nil {
+				return err
+			}
+			if len(glyph.Instructions) == 0 {
+				continue
+			}
+			fmt.Printf("Glyph %d:\n", id)
+			if err := printInstructions(glyph.Instructions); err != nil {
+				return fmt.Errorf("glyph %d: %w", id, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func printInstructions(code []byte) error {
+	instructions, err := hinting.Disassemble(code)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.SplitAfter(hinting.Format(instructions), "\n") {
+		if line != "" {
+			fmt.Printf("\t%s", line)
+		}
+	}
+	return nil
+}
@@ -11,21 +11,42 @@ import (
 
 func usage() {
 	fmt.Println(`
-Usage: font [-i font-index] <features|info|metrics|scrub|stats> font.[otf,ttf,ttc,woff,woff2] ...
+Usage: font [-i font-index] <check|dehint|features|hinting|info|merge|metrics|scrub|stats|subset|svg> [command flags] font.[otf,ttf,ttc,dfont,woff,woff2] ...
 
+check: prints problems found in the font, exits non-zero on errors
+dehint: removes the hinting (makes web fonts smaller), and writes the font to stdout
 features: prints the gpos/gsub tables (contains font features)
+hinting: prints the gasp and cvt tables and the hinting instructions
 info: prints the name table (contains metadata)
-metrics: prints the hhea table (contains font metrics)
+merge: combines the glyphs and features of all the fonts, and writes the font to stdout or --output
+       (the fonts must all have TrueType or all have CFF outlines; CFF2 and variable fonts cannot be merged)
+metrics: prints the hhea and vhea tables (contains font metrics)
 scrub: remove the name table (saves significant space)
-stats: prints each table and the amount of space used`)
+stats: prints each table and the amount of space used
+subset: keeps only the given characters and features, and writes the font to stdout or --output
+        (members of a collection are written to separate files, unless -i is given)
+svg: adds the SVG files in a directory as color glyphs, and writes the font to stdout or --output`)
 }
 
+// collectionIndex is the index of the collection member the command is run
+// on, when it is run on every member of a collection, and -1 otherwise.
+var collectionIndex = -1
+
 func main() {
-	fontIndex := flag.Int("i", -1, "select `font-index` for TrueType Collection (.ttc/.otc), starting from 0.")
+	fontIndex := flag.Int("i", -1, "select `font-index` for TrueType Collection (.ttc/.otc) or resource fork (.dfont), starting from 0.")
 
 	flag.Usage = func() {
 		usage()
 		flag.PrintDefaults()
+		fmt.Println("\nsubset flags:")
+		subsetFlags.SetOutput(os.Stdout)
+		subsetFlags.PrintDefaults()
+		fmt.Println("\nmerge flags:")
+		mergeFlags.SetOutput(os.Stdout)
+		mergeFlags.PrintDefaults()
+		fmt.Println("\nsvg flags:")
+		svgFlags.SetOutput(os.Stdout)
+		svgFlags.PrintDefaults()
 	}
 	flag.Parse()
 
@@ -35,20 +56,52 @@ func main() {
 	}
 
 	cmds := map[string]func(*sfnt.Font) error{
+		"check":    Check,
 		"scrub":    Scrub,
+		"dehint":   Dehint,
 		"info":     Info,
 		"stats":    Stats,
 		"metrics":  Metrics,
 		"features": Features,
+		"hinting":  Hinting,
+		"subset":   Subset,
+		"svg":      SVG,
+	}
+	// fontsCmds are the commands run once on the fonts of all files.
+	fontsCmds := map[string]func([]*sfnt.Font) error{
+		"merge": Merge,
+	}
+	// commandFlags are the flags of the commands that have their own, which
+	// follow the command name.
+	commandFlags := map[string]*flag.FlagSet{
+		"subset": subsetFlags,
+		"merge":  mergeFlags,
+		"svg":    svgFlags,
 	}
-	if _, found := cmds[command]; !found || len(flag.Args()) < 2 {
+	_, found := cmds[command]
+	_, allFonts := fontsCmds[command]
+	if (!found && !allFonts) || len(flag.Args()) < 2 {
 		flag.Usage()
 		os.Exit(2)
 	}
 
 	filenames := flag.Args()[1:]
+	if flags, found := commandFlags[command]; found {
+		flags.Usage = flag.Usage
+		flags.Parse(filenames)
+		filenames = flags.Args()
+		if len(filenames) == 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
 	exitCode := 0
+	var fonts []*sfnt.Font
 	runCommand := func(font *sfnt.Font) {
+		if allFonts {
+			fonts = append(fonts, font)
+			return
+		}
 		if err := cmds[command](font); err != nil {
 			fmt.Fprintf(os.Stderr, "%s\n", err)
 			exitCode = 1
@@ -64,7 +117,7 @@ func main() {
 		}
 		defer file.Close()
 
-		if len(filenames) > 1 {
+		if len(filenames) > 1 && !allFonts {
 			fmt.Println("==>", filename, "<==")
 		}
 
@@ -103,12 +156,23 @@ func main() {
 				}
 
 				for i, font := range fonts {
-					fmt.Printf("==>font index: %d<==\n", i)
+					if !allFonts {
+						fmt.Printf("==>font index: %d<==\n", i)
+					}
 
+					collectionIndex = i
 					runCommand(font)
 				}
+				collectionIndex = -1
 			}
 		}
 	}
+
+	if allFonts && exitCode == 0 {
+		if err := fontsCmds[command](fonts); err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			exitCode = 1
+		}
+	}
 	os.Exit(exitCode)
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ConradIrwin/font/merge"
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// mergeFlags are the flags of the merge command, which follow the command
+// name.
+var mergeFlags = flag.NewFlagSet("merge", flag.ExitOnError)
+
+var (
+	mergeScale      = mergeFlags.Bool("scale", false, "scale fonts with a different unitsPerEm to that of the first font, instead of failing")
+	mergePrecedence = mergeFlags.String("precedence", "", "map characters found in several fonts to the glyphs of the fonts at `indexes` such as 2,0 first")
+	mergeOutput     = mergeFlags.String("output", "", "write the merged font to `file`, as WOFF or WOFF2 if it has that extension, instead of stdout")
+)
+
+// Merge writes a font with the glyphs and features of all fonts to the
+// output file, or to stdout.
+func Merge(fonts []*sfnt.Font) error {
+	opts := merge.Options{ScaleUnits: *mergeScale}
+	if *mergePrecedence != "" {
+		for _, field := range strings.Split(*mergePrecedence, ",") {
+			i, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return fmt.Errorf("invalid font index %q: %w", field, err)
+			}
+			opts.CmapPrecedence = append(opts.CmapPrecedence, i)
+		}
+	}
+
+	out, err := merge.Merge(fonts, opts)
+	if err != nil {
+		return err
+	}
+	return writeFont(out, *mergeOutput)
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/ConradIrwin/font/sfnt"
 )
 
-// Metrics prints the hhea table (contains font metrics).
+// Metrics prints the hhea and vhea tables (contains font metrics).
 func Metrics(font *sfnt.Font) error {
 	if font.HasTable(sfnt.TagHhea) {
 		hhea, err := font.HheaTable()
@@ -25,6 +25,33 @@ func Metrics(font *sfnt.Font) error {
 		fmt.Println("Min right side bearing:", hhea.MinRightSideBearing)
 	}
 
+	if font.HasTable(sfnt.TagVhea) {
+		vhea, err := font.VheaTable()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Vertical ascent:", vhea.Ascent)
+		fmt.Println("Vertical descent:", vhea.Descent)
+		fmt.Println("Vertical line gap:", vhea.LineGap)
+		fmt.Println("Vertical caret offset:", vhea.CaretOffset)
+		fmt.Println("Vertical caret slope rise:", vhea.CaretSlopeRise)
+		fmt.Println("Vertical caret slope run:", vhea.CaretSlopeRun)
+		fmt.Println("Advance height max:", vhea.AdvanceHeightMax)
+		fmt.Println("Min top side bearing:", vhea.MinTopSideBearing)
+		fmt.Println("Min bottom side bearing:", vhea.MinBottomSideBearing)
+	}
+
+	if font.HasTable(sfnt.TagVORG) {
+		vorg, err := font.VORGTable()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("Default vertical origin:", vorg.DefaultVertOriginY)
+		fmt.Println("Glyphs with other vertical origins:", len(vorg.Origins))
+	}
+
 	if font.HasTable(sfnt.TagOS2) {
 		os2, err := font.OS2Table()
 		if err != nil {
@@ -38,8 +65,6 @@ func Metrics(font *sfnt.Font) error {
 		fmt.Println("Win Descent:", os2.UsWinDescent)
 		fmt.Println("Width Class", os2.USWidthClass)
 		fmt.Println("Weight Class", os2.USWeightClass)
-
-		fmt.Println("TODO: SHOW MORE METRICS")
 	}
 
 	return nil
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ConradIrwin/font/sfnt"
+	"github.com/ConradIrwin/font/subset"
+)
+
+// subsetFlags are the flags of the subset command, which follow the command
+// name.
+var subsetFlags = flag.NewFlagSet("subset", flag.ExitOnError)
+
+var (
+	subsetUnicodes = subsetFlags.String("unicodes", "", "keep the characters in `ranges` such as U+0000-00FF,U+20AC")
+	subsetTextFile = subsetFlags.String("text-file", "", "keep the characters in `file`")
+	subsetFeatures = subsetFlags.String("features", "", "keep only the layout features in `tags` such as liga,kern")
+	subsetOutput   = subsetFlags.String("output", "", "write the subset to `file`, as WOFF or WOFF2 if it has that extension, instead of stdout")
+)
+
+// Subset writes the font restricted to the requested characters and layout
+// features to the output file, or to stdout.
+func Subset(font *sfnt.Font) error {
+	var opts subset.Options
+	if *subsetUnicodes != "" {
+		runes, err := parseUnicodes(*subsetUnicodes)
+		if err != nil {
+			return err
+		}
+		opts.Runes = append(opts.Runes, runes...)
+	}
+	if *subsetTextFile != "" {
+		text, err := os.ReadFile(*subsetTextFile)
+		if err != nil {
+			return err
+		}
+		opts.Runes = append(opts.Runes, []rune(string(text))...)
+	}
+	if *subsetFeatures != "" {
+		opts.Features = []sfnt.Tag{}
+		for _, name := range strings.Split(*subsetFeatures, ",") {
+			tag, err := sfnt.NamedTag(fmt.Sprintf("%-4s", strings.TrimSpace(name)))
+			if err != nil {
+				return fmt.Errorf("invalid feature %q: %w", name, err)
+			}
+			opts.Features = append(opts.Features, tag)
+		}
+	}
+
+	out, err := subset.Subset(font, opts)
+	if err != nil {
+		return err
+	}
+
+	return writeFont(out, outputFilename(*subsetOutput))
+}
+
+// outputFilename returns the file to which the command writes the font it
+// is run on, which has the index of the font added when it is run on every
+// member of a collection, so that each is written to its own file.
+func outputFilename(filename string) string {
+	if filename == "" || collectionIndex < 0 {
+		return filename
+	}
+	ext := filepath.Ext(filename)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(filename, ext), collectionIndex, ext)
+}
+
+// writeFont writes the font to the file, as WOFF or WOFF2 if it has that
+// extension, or to stdout if filename is empty.
+func writeFont(font *sfnt.Font, filename string) error {
+	if filename == "" {
+		_, err := font.WriteOTF(os.Stdout)
+		return err
+	}
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	var write func(io.Writer) (int, error)
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".woff":
+		write = font.WriteWOFF
+	case ".woff2":
+		write = font.WriteWOFF2
+	default:
+		write = font.WriteOTF
+	}
+	if _, err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// parseUnicodes returns the characters of a comma-separated list of code
+// points and ranges of code points, such as U+0000-00FF,U+20AC.
+func parseUnicodes(s string) ([]rune, error) {
+	var runes []rune
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		first, last := field, field
+		if i := strings.IndexByte(field, '-'); i >= 0 {
+			first, last = field[:i], field[i+1:]
+		}
+		lo, err := parseCodePoint(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unicode range %q: %w", field, err)
+		}
+		hi, err := parseCodePoint(last)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unicode range %q: %w", field, err)
+		}
+		if lo > hi {
+			return nil, fmt.Errorf("invalid unicode range %q: %U is after %U", field, lo, hi)
+		}
+		for r := lo; r <= hi; r++ {
+			runes = append(runes, r)
+		}
+	}
+	return runes, nil
+}
+
+// parseCodePoint parses a hexadecimal code point, with an optional U+ prefix.
+func parseCodePoint(s string) (rune, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "U+"), "u+")
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, err
+	}
+	if v > 0x10FFFF {
+		return 0, fmt.Errorf("%X is not a code point", v)
+	}
+	return rune(v), nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// svgFlags are the flags of the svg command, which follow the command name.
+var svgFlags = flag.NewFlagSet("svg", flag.ExitOnError)
+
+var (
+	svgDir      = svgFlags.String("dir", "", "add the SVG files in `directory`, named glyph<ID>.svg, after the glyph name, or like uni20AC.svg")
+	svgCompress = svgFlags.Bool("compress", false, "gzip compress the SVG documents")
+	svgOutput   = svgFlags.String("output", "", "write the font to `file`, as WOFF or WOFF2 if it has that extension, instead of stdout")
+)
+
+// SVG adds the SVG documents in the directory to the 'SVG ' table of the
+// font, and writes the font to the output file, or to stdout.
+func SVG(font *sfnt.Font) error {
+	if *svgDir == "" {
+		return errors.New("svg: no directory given with -dir")
+	}
+
+	table := sfnt.NewTableSVG()
+	if font.HasTable(sfnt.TagSVG) {
+		var err error
+		if table, err = font.SVGTable(); err != nil {
+			return err
+		}
+	}
+	if err := table.AddDirectory(font, os.DirFS(*svgDir), *svgCompress); err != nil {
+		return err
+	}
+	font.AddTable(sfnt.TagSVG, table)
+
+	return writeFont(font, outputFilename(*svgOutput))
+}
@@ -3,8 +3,6 @@ module github.com/ConradIrwin/font
 go 1.16
 
 require (
-	dmitri.shuralyov.com/font/woff2 v0.0.0-20180220214647-957792cbbdab
-	github.com/dsnet/compress v0.0.1 // indirect
-	github.com/shurcooL/gofontwoff v0.0.0-20181114050219-180f79e6909d // indirect
+	github.com/dsnet/compress v0.0.1
 	golang.org/x/text v0.3.5
 )
@@ -0,0 +1,364 @@
+package hinting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// maxComponentDepth limits the nesting of composite glyphs.
+const maxComponentDepth = 64
+
+var errNoSize = errors.New("no size set")
+
+// Hinter grid-fits the outlines of the glyphs of a font with TrueType
+// outlines, by running its hinting instructions at a size.
+// Variations are not supported, so the outlines are those of the default
+// instance of variable fonts.
+type Hinter struct {
+	glyf       *sfnt.TableGlyf
+	hmtx       *sfnt.TableHmtx
+	hhea       *sfnt.TableHhea
+	vmtx       *sfnt.TableVmtx
+	maxp       *sfnt.TableMaxp
+	unitsPerEm int32
+	cvt        []int16
+	prep       []byte
+
+	// functions and instructionDefs are those defined by the font program.
+	functions       map[int32][]byte
+	instructionDefs map[int32][]byte
+
+	// The state after running the control value program at the current size.
+	ppem  int32
+	scale int64 // scale converts font units to pixels, as a 16.16 fixed-point number.
+	prepd *interpreter
+}
+
+// NewHinter returns a Hinter for a font, and runs its font program.
+func NewHinter(font *sfnt.Font) (*Hinter, error) {
+	h := &Hinter{}
+
+	head, err := font.HeadTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", sfnt.TagHead, err)
+	}
+	h.unitsPerEm = int32(head.UnitsPerEm)
+	if h.unitsPerEm == 0 {
+		return nil, errors.New("units per em is 0")
+	}
+	if h.maxp, err = font.MaxpTable(); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", sfnt.TagMaxp, err)
+	}
+	if h.glyf, err = font.GlyfTable(); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", sfnt.TagGlyf, err)
+	}
+	if h.hmtx, err = font.HmtxTable(); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", sfnt.TagHmtx, err)
+	}
+	if h.hhea, err = font.HheaTable(); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", sfnt.TagHhea, err)
+	}
+	if font.HasTable(sfnt.TagVmtx) {
+		if h.vmtx, err = font.VmtxTable(); err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", sfnt.TagVmtx, err)
+		}
+	}
+	if font.HasTable(sfnt.TagCvt) {
+		cvt, err := font.CvtTable()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", sfnt.TagCvt, err)
+		}
+		h.cvt = cvt.Values
+	}
+	if font.HasTable(sfnt.TagPrep) {
+		prep, err := font.Table(sfnt.TagPrep)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", sfnt.TagPrep, err)
+		}
+		h.prep = prep.Bytes()
+	}
+
+	in := h.newInterpreter()
+	if font.HasTable(sfnt.TagFpgm) {
+		fpgm, err := font.Table(sfnt.TagFpgm)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", sfnt.TagFpgm, err)
+		}
+		if err := in.run(fpgm.Bytes(), 0); err != nil {
+			return nil, fmt.Errorf("running %q: %w", sfnt.TagFpgm, err)
+		}
+	}
+	h.functions, h.instructionDefs = in.functions, in.instructionDefs
+	return h, nil
+}
+
+// newInterpreter returns an interpreter in its initial state.
+func (h *Hinter) newInterpreter() *interpreter {
+	in := &interpreter{
+		gs:              defaultGraphicsState,
+		cvt:             make([]f26dot6, len(h.cvt)),
+		storage:         make([]int32, h.maxp.MaxStorage),
+		functions:       make(map[int32][]byte),
+		instructionDefs: make(map[int32][]byte),
+		// Many fonts understate the stack they use.
+		maxStack: int(h.maxp.MaxStackElements) + 32,
+	}
+	in.zones[0] = newZone(int(h.maxp.MaxTwilightPoints))
+	return in
+}
+
+// SetPPEM sets the size at which glyphs are hinted, in pixels per em, and
+// runs the control value program at that size.
+func (h *Hinter) SetPPEM(ppem int) error {
+	if ppem <= 0 {
+		return fmt.Errorf("invalid size %d", ppem)
+	}
+	h.ppem, h.prepd = 0, nil
+
+	in := h.newInterpreter()
+	in.ppem, in.scale = int32(ppem), divFix(int64(ppem)*64, int64(h.unitsPerEm))
+	in.functions, in.instructionDefs, in.sharedDefs = h.functions, h.instructionDefs, true
+	for i, v := range h.cvt {
+		in.cvt[i] = in.fromFUnits(int32(v))
+	}
+	if err := in.run(h.prep, 0); err != nil {
+		return fmt.Errorf("running %q: %w", sfnt.TagPrep, err)
+	}
+	in.sharedDefs = true
+
+	h.ppem, h.scale, h.prepd = in.ppem, in.scale, in
+	return nil
+}
+
+// PPEM returns the size set by SetPPEM, or 0 if none has been set.
+func (h *Hinter) PPEM() int {
+	return int(h.ppem)
+}
+
+// GlyphOutline returns the outline of a glyph grid-fitted at the size set
+// by SetPPEM, in pixels, with composite glyphs resolved. The glyph origin is
+// at (0, 0), and the advance width is a whole number of pixels.
+func (h *Hinter) GlyphOutline(glyph sfnt.GlyphID) (*sfnt.Outline, error) {
+	if h.prepd == nil {
+		return nil, errNoSize
+	}
+
+	// Each glyph starts from the state left by the control value program,
+	// so the results don't depend on the glyphs hinted before.
+	prepd := h.prepd
+	in := &interpreter{
+		gs:              prepd.gs,
+		cvt:             append([]f26dot6(nil), prepd.cvt...),
+		storage:         append([]int32(nil), prepd.storage...),
+		functions:       prepd.functions,
+		instructionDefs: prepd.instructionDefs,
+		sharedDefs:      true,
+		ppem:            prepd.ppem,
+		scale:           prepd.scale,
+		maxStack:        prepd.maxStack,
+		glyphProgram:    true,
+	}
+	twilight := &prepd.zones[0]
+	in.zones[0] = zone{
+		current:  append([]point(nil), twilight.current...),
+		original: append([]point(nil), twilight.original...),
+		touched:  append([]uint8(nil), twilight.touched...),
+		onCurve:  append([]bool(nil), twilight.onCurve...),
+	}
+	if in.gs.instructControl&2 != 0 {
+		in.gs = defaultGraphicsState
+		in.gs.instructControl = prepd.gs.instructControl
+	}
+
+	g, err := h.load(in, glyph, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(g.current) - numPhantomPoints
+	phantom := g.current[n:]
+	origin := phantom[0].X
+	outline := &sfnt.Outline{
+		Points:         make([]sfnt.OutlinePoint, n),
+		EndPoints:      g.endPoints,
+		AdvanceWidth:   pixels(phantom[1].X - origin),
+		AdvanceHeight:  pixels(phantom[2].Y - phantom[3].Y),
+		VerticalOrigin: pixels(phantom[2].Y),
+	}
+	for i, p := range g.current[:n] {
+		outline.Points[i] = sfnt.OutlinePoint{X: pixels(p.X - origin), Y: pixels(p.Y), OnCurve: g.onCurve[i]}
+	}
+	return outline, nil
+}
+
+// numPhantomPoints is the number of points appended to each glyph to track
+// its metrics: the left and right side, and the top and bottom.
+const numPhantomPoints = 4
+
+func pixels(v f26dot6) float64 {
+	return float64(v) / 64
+}
+
+// load returns the hinted points of a glyph as a glyph zone, with the
+// phantom points at the end.
+func (h *Hinter) load(in *interpreter, id sfnt.GlyphID, depth int) (*zone, error) {
+	if depth > maxComponentDepth {
+		return nil, errors.New("composite glyphs are nested too deeply")
+	}
+	glyph, err := h.glyf.Glyph(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The phantom points, in font units.
+	metric := h.hmtx.Metric(id)
+	left := int32(glyph.XMin) - int32(metric.LeftSideBearing)
+	top, bottom := int32(h.hhea.Ascent), int32(h.hhea.Descent)
+	if h.vmtx != nil {
+		vmetric := h.vmtx.Metric(id)
+		top = int32(glyph.YMax) + int32(vmetric.TopSideBearing)
+		bottom = top - int32(vmetric.AdvanceHeight)
+	}
+	phantom := []point{{X: left}, {X: left + int32(metric.AdvanceWidth)}, {Y: top}, {Y: bottom}}
+
+	var g *zone
+	if glyph.IsComposite() {
+		if g, err = h.loadComposite(in, glyph, phantom, depth); err != nil {
+			return nil, fmt.Errorf("glyph %d: %w", id, err)
+		}
+	} else {
+		g = &zone{scale: h.scale}
+		for _, p := range glyph.Points {
+			g.unscaled = append(g.unscaled, point{int32(p.X), int32(p.Y)})
+			g.onCurve = append(g.onCurve, p.OnCurve)
+		}
+		g.unscaled = append(g.unscaled, phantom...)
+		g.onCurve = append(g.onCurve, make([]bool, numPhantomPoints)...)
+		for _, end := range glyph.EndPoints {
+			g.endPoints = append(g.endPoints, int(end))
+		}
+
+		g.current = make([]point, len(g.unscaled))
+		for i, p := range g.unscaled {
+			g.current[i] = point{f26dot6(mulFix(int64(p.X), h.scale)), f26dot6(mulFix(int64(p.Y), h.scale))}
+		}
+		g.original = append([]point(nil), g.current...)
+		roundPhantomPoints(g)
+	}
+	g.touched = make([]uint8, len(g.current))
+
+	if err := h.hint(in, g, glyph.Instructions); err != nil {
+		return nil, fmt.Errorf("glyph %d: %w", id, err)
+	}
+	return g, nil
+}
+
+// loadComposite returns the hinted points of the components of a glyph,
+// followed by the phantom points of the glyph, or of the component whose
+// metrics the glyph uses.
+func (h *Hinter) loadComposite(in *interpreter, glyph *sfnt.Glyph, phantom []point, depth int) (*zone, error) {
+	g := &zone{}
+	var metrics []point
+	for i, comp := range glyph.Components {
+		child, err := h.load(in, comp.Glyph, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		n := len(child.current) - numPhantomPoints
+		points := child.current[:n]
+		if comp.Transform != [4]float64{1, 0, 0, 1} {
+			// The transform is exact in 16.16 fixed-point, as it's stored in 2.14.
+			var t [4]int64
+			for j, v := range comp.Transform {
+				t[j] = int64(v * 0x10000)
+			}
+			for j, p := range points {
+				x, y := int64(p.X), int64(p.Y)
+				points[j] = point{f26dot6(mulFix(x, t[0]) + mulFix(y, t[2])), f26dot6(mulFix(x, t[1]) + mulFix(y, t[3]))}
+			}
+		}
+
+		var dx, dy f26dot6
+		if comp.IsXYOffset() {
+			x, y := float64(comp.Arg1), float64(comp.Arg2)
+			if comp.Flags&sfnt.ComponentScaledOffset != 0 && comp.Flags&sfnt.ComponentUnscaledOffset == 0 {
+				x, y = comp.Apply(x, y)
+			}
+			dx = f26dot6(mulFix(int64(math.Round(x)), h.scale))
+			dy = f26dot6(mulFix(int64(math.Round(y)), h.scale))
+			if comp.Flags&sfnt.ComponentRoundXYToGrid != 0 {
+				dx, dy = roundPixel(dx), roundPixel(dy)
+			}
+		} else {
+			parent, own := int(comp.Arg1), int(comp.Arg2)
+			if parent >= len(g.current) || own >= len(points) {
+				return nil, fmt.Errorf("component %d matches points out of range", i)
+			}
+			dx = g.current[parent].X - points[own].X
+			dy = g.current[parent].Y - points[own].Y
+		}
+
+		start := len(g.current)
+		for _, p := range points {
+			g.current = append(g.current, point{p.X + dx, p.Y + dy})
+		}
+		g.onCurve = append(g.onCurve, child.onCurve[:n]...)
+		for _, end := range child.endPoints {
+			g.endPoints = append(g.endPoints, start+end)
+		}
+
+		if comp.Flags&sfnt.ComponentUseMyMetrics != 0 {
+			metrics = child.current[n:]
+		}
+	}
+
+	if metrics == nil {
+		for _, p := range phantom {
+			g.current = append(g.current, point{f26dot6(mulFix(int64(p.X), h.scale)), f26dot6(mulFix(int64(p.Y), h.scale))})
+		}
+	} else {
+		g.current = append(g.current, metrics...)
+	}
+	g.onCurve = append(g.onCurve, make([]bool, numPhantomPoints)...)
+	roundPhantomPoints(g)
+
+	// The instructions of composite glyphs refer to the hinted components,
+	// so they are also the original outline.
+	g.original = append([]point(nil), g.current...)
+	g.unscaled = g.original
+	g.scale = 0x10000
+	return g, nil
+}
+
+// roundPhantomPoints rounds the phantom points of a glyph to the pixel grid,
+// so the glyph has whole pixel metrics.
+func roundPhantomPoints(g *zone) {
+	phantom := g.current[len(g.current)-numPhantomPoints:]
+	phantom[0].X = roundPixel(phantom[0].X)
+	phantom[1].X = roundPixel(phantom[1].X)
+	phantom[2].Y = roundPixel(phantom[2].Y)
+	phantom[3].Y = roundPixel(phantom[3].Y)
+}
+
+func roundPixel(v f26dot6) f26dot6 {
+	return (v + 32) &^ 63
+}
+
+// hint runs the instructions of a glyph on its points.
+func (h *Hinter) hint(in *interpreter, g *zone, instructions []byte) error {
+	if len(instructions) == 0 || in.gs.instructControl&1 != 0 {
+		return nil
+	}
+
+	// Each glyph starts with the graphics state left by the control value program.
+	gs := in.gs
+	in.gs.reset()
+	in.stack = in.stack[:0]
+	in.zones[1] = *g
+	err := in.run(instructions, 0)
+	in.gs = gs
+	return err
+}
@@ -0,0 +1,146 @@
+package hinting
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+func TestRoundTrip(t *testing.T) {
+	file, err := os.Open(filepath.Join("..", "sfnt", "testdata", "Go-Regular.woff2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	font, err := sfnt.Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tag := range []sfnt.Tag{sfnt.TagFpgm, sfnt.TagPrep} {
+		table, err := font.Table(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := table.Bytes()
+
+		instructions, err := Disassemble(code)
+		if err != nil {
+			t.Fatalf("Disassemble(%q) err = %q, want nil", tag, err)
+		}
+		encoded, err := Encode(instructions)
+		if err != nil {
+			t.Fatalf("Encode(%q) err = %q, want nil", tag, err)
+		}
+		if !bytes.Equal(encoded, code) {
+			t.Errorf("Encode(Disassemble(%q)) differs from the table", tag)
+		}
+
+		assembled, err := Assemble(Format(instructions))
+		if err != nil {
+			t.Fatalf("Assemble(%q) err = %q, want nil", tag, err)
+		}
+		if !bytes.Equal(assembled, code) {
+			t.Errorf("Assemble(Format(%q)) differs from the table", tag)
+		}
+	}
+}
+
+func TestDisassemble(t *testing.T) {
+	code := []byte{
+		0xB1, 4, 12, // PUSHB[001]
+		0xB8, 0xFF, 0xFE, // PUSHW[000]
+		0x40, 2, 1, 2, // NPUSHB
+		0x41, 1, 0x01, 0x00, // NPUSHW
+		0x58, 0xF6, 0x1B, 0x28, 0x59, // IF MIRP[10110] ELSE INS_28 EIF
+	}
+	want := []Instruction{
+		{Opcode: 0xB1, Values: []int32{4, 12}},
+		{Opcode: 0xB8, Values: []int32{-2}},
+		{Opcode: 0x40, Values: []int32{1, 2}},
+		{Opcode: 0x41, Values: []int32{256}},
+		{Opcode: 0x58}, {Opcode: 0xF6}, {Opcode: 0x1B}, {Opcode: 0x28}, {Opcode: 0x59},
+	}
+
+	got, err := Disassemble(code)
+	if err != nil {
+		t.Fatalf("Disassemble() err = %q, want nil", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Disassemble() = %v, want %v", got, want)
+	}
+
+	text := "PUSHB[001] 4 12\nPUSHW[000] -2\nNPUSHB 1 2\nNPUSHW 256\nIF\n  MIRP[10110]\nELSE\n  INS_28\nEIF\n"
+	if s := Format(got); s != text {
+		t.Errorf("Format() = %q, want %q", s, text)
+	}
+
+	for _, truncated := range [][]byte{{0xB1, 4}, {0x40}, {0x41, 1, 0}} {
+		if _, err := Disassemble(truncated); !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("Disassemble(%v) err = %v, want %v", truncated, err, io.ErrUnexpectedEOF)
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want []byte
+	}{
+		{"SVTCA[1] ; x axis\nMDAP[1]", []byte{0x01, 0x2F}},
+		{"PUSH 1 2 300 -1 4", []byte{0xB1, 1, 2, 0xB9, 0x01, 0x2C, 0xFF, 0xFF, 0xB0, 4}},
+		{"PUSH\nCALL", []byte{0x2B}},
+		{"NPUSHW 0x10", []byte{0x41, 1, 0x00, 0x10}},
+	} {
+		got, err := Assemble(test.text)
+		if err != nil {
+			t.Errorf("Assemble(%q) err = %q, want nil", test.text, err)
+			continue
+		}
+		if !bytes.Equal(got, test.want) {
+			t.Errorf("Assemble(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+
+	for _, text := range []string{
+		"1 2",
+		"MIRP[101]",
+		"SRP0[1]",
+		"NOPE",
+		"PUSHB[001] 1",
+		"PUSHB[000] 256",
+		"CALL 1",
+	} {
+		if _, err := Assemble(text); err == nil {
+			t.Errorf("Assemble(%q) err = nil, want an error", text)
+		}
+	}
+}
+
+func TestOpcodeString(t *testing.T) {
+	for op, want := range map[Opcode]string{
+		0x00: "SVTCA[0]",
+		0x49: "MD[0]",
+		0x4A: "MD[1]",
+		0x4B: "MPPEM",
+		0x83: "INS_83",
+		0xB7: "PUSHB[111]",
+		0xFF: "MIRP[11111]",
+	} {
+		if got := op.String(); got != want {
+			t.Errorf("Opcode(%#x).String() = %q, want %q", byte(op), got, want)
+		}
+		if want[:4] == "INS_" {
+			continue
+		}
+		if parsed, err := parseOpcode(want); err != nil || parsed != op {
+			t.Errorf("parseOpcode(%q) = %#x, %v, want %#x", want, byte(parsed), err, byte(op))
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Package hinting reads and writes the TrueType hinting instructions found in
+// the 'fpgm' and 'prep' tables and in glyphs, and runs them to grid-fit
+// glyph outlines.
+package hinting
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Instruction is a decoded TrueType instruction.
+type Instruction struct {
+	Opcode Opcode
+	// Values contains the values pushed by PUSHB, PUSHW, NPUSHB and NPUSHW,
+	// which are the only instructions with operands in the instruction stream.
+	Values []int32
+}
+
+// IsPush returns true if the instruction pushes values from the instruction stream.
+func (ins Instruction) IsPush() bool {
+	switch ins.Opcode.Name() {
+	case "PUSHB", "PUSHW", "NPUSHB", "NPUSHW":
+		return true
+	}
+	return false
+}
+
+// String returns the instruction as written by the assembler, such as
+// "PUSHB[001] 4 12".
+func (ins Instruction) String() string {
+	if len(ins.Values) == 0 {
+		return ins.Opcode.String()
+	}
+	var b strings.Builder
+	b.WriteString(ins.Opcode.String())
+	for _, v := range ins.Values {
+		b.WriteByte(' ')
+		b.WriteString(strconv.Itoa(int(v)))
+	}
+	return b.String()
+}
+
+// Disassemble decodes a stream of instructions. Opcodes that are not defined
+// by the specification are returned as they are, since fonts can define
+// them using IDEF.
+func Disassemble(code []byte) ([]Instruction, error) {
+	var instructions []Instruction
+	for pc := 0; pc < len(code); {
+		op := Opcode(code[pc])
+		pc++
+
+		ins := Instruction{Opcode: op}
+		count, words := 0, false
+		switch {
+		case op == opNPUSHB || op == opNPUSHW:
+			if pc >= len(code) {
+				return nil, fmt.Errorf("%s at %d: %w", op, pc-1, io.ErrUnexpectedEOF)
+			}
+			count, words = int(code[pc]), op == opNPUSHW
+			pc++
+		case op.Name() == "PUSHB" || op.Name() == "PUSHW":
+			count, words = int(op.Flags())+1, op.Name() == "PUSHW"
+		}
+
+		if count > 0 {
+			size := count
+			if words {
+				size *= 2
+			}
+			if pc+size > len(code) {
+				return nil, fmt.Errorf("%s at %d: %w", op, pc-1, io.ErrUnexpectedEOF)
+			}
+			ins.Values = make([]int32, count)
+			for i := range ins.Values {
+				if words {
+					ins.Values[i] = int32(int16(uint16(code[pc])<<8 | uint16(code[pc+1])))
+					pc += 2
+				} else {
+					ins.Values[i] = int32(code[pc])
+					pc++
+				}
+			}
+		}
+		instructions = append(instructions, ins)
+	}
+	return instructions, nil
+}
+
+// Encode returns the bytes of a stream of instructions. It is the inverse of
+// Disassemble, so the pushed values must fit the form of push chosen.
+func Encode(instructions []Instruction) ([]byte, error) {
+	var code []byte
+	for i, ins := range instructions {
+		code = append(code, byte(ins.Opcode))
+		if !ins.IsPush() {
+			if len(ins.Values) > 0 {
+				return nil, fmt.Errorf("instruction %d: %s has no operands", i, ins.Opcode)
+			}
+			continue
+		}
+
+		name := ins.Opcode.Name()
+		switch {
+		case name == "NPUSHB" || name == "NPUSHW":
+			if len(ins.Values) > 255 {
+				return nil, fmt.Errorf("instruction %d: %s of %d values", i, ins.Opcode, len(ins.Values))
+			}
+			code = append(code, byte(len(ins.Values)))
+		case len(ins.Values) != int(ins.Opcode.Flags())+1:
+			return nil, fmt.Errorf("instruction %d: %s of %d values", i, ins.Opcode, len(ins.Values))
+		}
+
+		words := name == "PUSHW" || name == "NPUSHW"
+		for _, v := range ins.Values {
+			if words {
+				if v < -0x8000 || v > 0x7FFF {
+					return nil, fmt.Errorf("instruction %d: %s value %d out of range", i, ins.Opcode, v)
+				}
+				code = append(code, byte(uint16(v)>>8), byte(v))
+			} else {
+				if v < 0 || v > 0xFF {
+					return nil, fmt.Errorf("instruction %d: %s value %d out of range", i, ins.Opcode, v)
+				}
+				code = append(code, byte(v))
+			}
+		}
+	}
+	return code, nil
+}
+
+// Format returns the instructions as text, one per line, with the bodies of
+// IF, ELSE, FDEF and IDEF indented.
+func Format(instructions []Instruction) string {
+	var b strings.Builder
+	depth := 0
+	for _, ins := range instructions {
+		switch ins.Opcode {
+		case opEIF, opENDF, opELSE:
+			if depth > 0 {
+				depth--
+			}
+		}
+		b.WriteString(strings.Repeat("  ", depth))
+		b.WriteString(ins.String())
+		b.WriteByte('\n')
+		switch ins.Opcode {
+		case opIF, opELSE, opFDEF, opIDEF:
+			depth++
+		}
+	}
+	return b.String()
+}
+
+var errMissingOpcode = errors.New("values without an instruction")
+
+// Assemble parses instructions written as by Format, and returns their bytes.
+// Each instruction is a name, with any flags in binary in brackets, followed
+// by the values it pushes. The name "PUSH" pushes values using the shortest
+// encoding. Text after a ';' on a line is ignored.
+func Assemble(text string) ([]byte, error) {
+	type statement struct {
+		ins      Instruction
+		shortest bool // shortest is set for "PUSH".
+	}
+	var statements []statement
+	var pushing *statement
+	for n, line := range strings.Split(text, "\n") {
+		if i := strings.IndexByte(line, ';'); i >= 0 {
+			line = line[:i]
+		}
+		for _, field := range strings.Fields(line) {
+			if v, err := strconv.ParseInt(field, 0, 32); err == nil {
+				if pushing == nil {
+					return nil, fmt.Errorf("line %d: %q: %w", n+1, field, errMissingOpcode)
+				}
+				pushing.ins.Values = append(pushing.ins.Values, int32(v))
+				continue
+			}
+
+			s := statement{shortest: field == "PUSH"}
+			if !s.shortest {
+				op, err := parseOpcode(field)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", n+1, err)
+				}
+				s.ins.Opcode = op
+			}
+			statements = append(statements, s)
+			pushing = nil
+			if s.shortest || s.ins.IsPush() {
+				pushing = &statements[len(statements)-1]
+			}
+		}
+	}
+
+	var instructions []Instruction
+	for _, s := range statements {
+		if s.shortest {
+			instructions = append(instructions, Push(s.ins.Values)...)
+		} else {
+			instructions = append(instructions, s.ins)
+		}
+	}
+	return Encode(instructions)
+}
+
+// parseOpcode parses the name of an opcode, such as "MIRP[10110]" or "INS_92".
+func parseOpcode(s string) (Opcode, error) {
+	if strings.HasPrefix(s, "INS_") {
+		v, err := strconv.ParseUint(s[4:], 16, 8)
+		if err != nil {
+			return 0, fmt.Errorf("invalid opcode %q", s)
+		}
+		return Opcode(v), nil
+	}
+
+	name, flags := s, ""
+	if i := strings.IndexByte(s, '['); i >= 0 && strings.HasSuffix(s, "]") {
+		name, flags = s[:i], s[i+1:len(s)-1]
+	}
+	base, ok := opcodeByName[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown instruction %q", s)
+	}
+	bits := opcodes[base].bits
+	if bits == 0 {
+		if flags != "" {
+			return 0, fmt.Errorf("instruction %q has no flags", s)
+		}
+		return base, nil
+	}
+	if uint(len(flags)) != bits {
+		return 0, fmt.Errorf("instruction %q needs %d flag bits", s, bits)
+	}
+	v, err := strconv.ParseUint(flags, 2, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid flags in %q", s)
+	}
+	return base + Opcode(v), nil
+}
+
+// Push returns the shortest instructions that push values, which must be in
+// the range of a 16-bit signed integer.
+func Push(values []int32) []Instruction {
+	var instructions []Instruction
+	for len(values) > 0 {
+		// Take the longest run of values that fit in a byte, or that don't.
+		bytes := values[0] >= 0 && values[0] <= 0xFF
+		n := 1
+		for n < len(values) && n < 255 && (values[n] >= 0 && values[n] <= 0xFF) == bytes {
+			n++
+		}
+
+		run := values[:n]
+		values = values[n:]
+		switch {
+		case bytes && n <= 8:
+			instructions = append(instructions, Instruction{Opcode: opPUSHB + Opcode(n-1), Values: run})
+		case bytes:
+			instructions = append(instructions, Instruction{Opcode: opNPUSHB, Values: run})
+		case n <= 8:
+			instructions = append(instructions, Instruction{Opcode: opPUSHW + Opcode(n-1), Values: run})
+		default:
+			instructions = append(instructions, Instruction{Opcode: opNPUSHW, Values: run})
+		}
+	}
+	return instructions
+}
@@ -0,0 +1,758 @@
+package hinting
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Limits that stop programs that loop or recurse forever.
+const (
+	maxCallDepth = 64
+	maxSteps     = 1 << 20
+)
+
+var (
+	errStackUnderflow = errors.New("stack underflow")
+	errStackOverflow  = errors.New("stack overflow")
+	errCallDepth      = errors.New("function calls are nested too deeply")
+	errTooManySteps   = errors.New("too many instructions executed")
+)
+
+// point is a position in 26.6 fixed-point pixels.
+type point struct {
+	X, Y f26dot6
+}
+
+// Flags of the points that have been moved by instructions, which are
+// left in place by IUP.
+const (
+	touchedX = 1 << iota
+	touchedY
+)
+
+// zone contains the points that instructions move: the twilight zone
+// contains points that are not in the outline, and the glyph zone contains
+// the points of the glyph followed by its phantom points.
+type zone struct {
+	current  []point
+	original []point // original contains the scaled points before hinting.
+	// unscaled contains the points in font units, for measuring the
+	// original outline precisely, and scale converts them to pixels as a
+	// 16.16 fixed-point number. They are unused in the twilight zone.
+	unscaled []point
+	scale    int64
+	touched  []uint8
+	onCurve  []bool
+	// endPoints contains the index of the last point of each contour, and
+	// is empty for the twilight zone.
+	endPoints []int
+}
+
+func newZone(n int) zone {
+	return zone{
+		current:  make([]point, n),
+		original: make([]point, n),
+		touched:  make([]uint8, n),
+		onCurve:  make([]bool, n),
+	}
+}
+
+// numOutlinePoints returns the number of points in the zone, excluding the
+// phantom points of the glyph zone.
+func (z *zone) numOutlinePoints() int {
+	if len(z.endPoints) == 0 {
+		return len(z.current)
+	}
+	return z.endPoints[len(z.endPoints)-1] + 1
+}
+
+// interpreter runs TrueType instructions.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/tt_instructions
+type interpreter struct {
+	gs      graphicsState
+	stack   []int32
+	zones   [2]zone
+	cvt     []f26dot6
+	storage []int32
+
+	functions       map[int32][]byte
+	instructionDefs map[int32][]byte
+	// sharedDefs is true while functions and instructionDefs are shared
+	// with other interpreters, so they're copied before being changed.
+	sharedDefs bool
+
+	ppem     int32
+	scale    int64 // scale converts font units to pixels, as a 16.16 fixed-point number.
+	maxStack int
+	// glyphProgram is true while running the instructions of a glyph,
+	// which can't use INSTCTRL.
+	glyphProgram bool
+
+	steps int
+	err   error // err is the first error found by the current instruction.
+}
+
+// fail records an error found while executing an instruction.
+func (in *interpreter) fail(err error) {
+	if in.err == nil {
+		in.err = err
+	}
+}
+
+func (in *interpreter) push(v int32) {
+	if len(in.stack) >= in.maxStack {
+		in.fail(errStackOverflow)
+		return
+	}
+	in.stack = append(in.stack, v)
+}
+
+func (in *interpreter) pop() int32 {
+	if len(in.stack) == 0 {
+		in.fail(errStackUnderflow)
+		return 0
+	}
+	v := in.stack[len(in.stack)-1]
+	in.stack = in.stack[:len(in.stack)-1]
+	return v
+}
+
+// fromFUnits converts a distance in font units to pixels.
+func (in *interpreter) fromFUnits(v int32) f26dot6 {
+	return f26dot6(mulFix(int64(v), in.scale))
+}
+
+// zone returns the zone referenced by a zone pointer.
+func (in *interpreter) zone(zp int) *zone {
+	return &in.zones[in.gs.zp[zp]]
+}
+
+// valid returns true if i is a point in z, and otherwise records an error.
+func (in *interpreter) valid(z *zone, i int32) bool {
+	if i < 0 || int(i) >= len(z.current) {
+		in.fail(fmt.Errorf("point %d out of range", i))
+		return false
+	}
+	return true
+}
+
+func (in *interpreter) project(a, b point) f26dot6 {
+	return in.gs.projection.dot(a.X-b.X, a.Y-b.Y)
+}
+
+func (in *interpreter) dualProject(a, b point) f26dot6 {
+	return in.gs.dualProjection.dot(a.X-b.X, a.Y-b.Y)
+}
+
+// displacement returns the movement along the freedom vector that changes
+// the projection of a point by d.
+func (in *interpreter) displacement(d f26dot6) (dx, dy f26dot6) {
+	fv, pv := in.gs.freedom, in.gs.projection
+	if fv == pv && (fv == xAxis || fv == yAxis) {
+		if fv == xAxis {
+			return d, 0
+		}
+		return 0, d
+	}
+	dot := (int64(fv.X)*int64(pv.X) + int64(fv.Y)*int64(pv.Y)) >> 14
+	if abs64(dot) < 0x400 {
+		// The vectors are almost perpendicular, so the movement would be huge.
+		dot = 0x4000
+	}
+	return f26dot6(mulDiv(int64(d), int64(fv.X), dot)), f26dot6(mulDiv(int64(d), int64(fv.Y), dot))
+}
+
+// shift moves point i of z by (dx, dy), and marks it touched along the
+// freedom vector if touch is set.
+func (in *interpreter) shift(z *zone, i int32, dx, dy f26dot6, touch bool) {
+	z.current[i].X += dx
+	z.current[i].Y += dy
+	if touch {
+		if in.gs.freedom.X != 0 {
+			z.touched[i] |= touchedX
+		}
+		if in.gs.freedom.Y != 0 {
+			z.touched[i] |= touchedY
+		}
+	}
+}
+
+// move moves point i of z along the freedom vector so that its projection
+// changes by d, and marks it touched.
+func (in *interpreter) move(z *zone, i int32, d f26dot6) {
+	dx, dy := in.displacement(d)
+	in.shift(z, i, dx, dy, true)
+}
+
+// cvtValue returns a control value, or records an error if n is out of range.
+func (in *interpreter) cvtValue(n int32) f26dot6 {
+	if n < 0 || int(n) >= len(in.cvt) {
+		in.fail(fmt.Errorf("control value %d out of range", n))
+		return 0
+	}
+	return in.cvt[n]
+}
+
+// instructionEnd returns the position after the instruction at pc.
+func instructionEnd(code []byte, pc int) (int, error) {
+	op := Opcode(code[pc])
+	end := pc + 1
+	switch {
+	case op == opNPUSHB || op == opNPUSHW:
+		if end >= len(code) {
+			return 0, fmt.Errorf("%s at %d: %w", op, pc, io.ErrUnexpectedEOF)
+		}
+		n := int(code[end])
+		if op == opNPUSHW {
+			n *= 2
+		}
+		end += 1 + n
+	case op >= opPUSHB && op < opPUSHW:
+		end += int(op-opPUSHB) + 1
+	case op >= opPUSHW && op < opPUSHW+8:
+		end += 2 * (int(op-opPUSHW) + 1)
+	}
+	if end > len(code) {
+		return 0, fmt.Errorf("%s at %d: %w", op, pc, io.ErrUnexpectedEOF)
+	}
+	return end, nil
+}
+
+// skipIf returns the position after the ELSE or EIF that ends the body of
+// the IF or ELSE whose body starts at pc. ELSE is only matched if
+// stopAtElse is set.
+func skipIf(code []byte, pc int, stopAtElse bool) (int, error) {
+	depth := 0
+	for pc < len(code) {
+		op := Opcode(code[pc])
+		end, err := instructionEnd(code, pc)
+		if err != nil {
+			return 0, err
+		}
+		switch {
+		case op == opIF:
+			depth++
+		case op == opELSE && depth == 0 && stopAtElse:
+			return end, nil
+		case op == opEIF:
+			if depth == 0 {
+				return end, nil
+			}
+			depth--
+		}
+		pc = end
+	}
+	return 0, errors.New("IF without EIF")
+}
+
+// skipDef returns the position of the ENDF that ends the body of the
+// function or instruction definition starting at pc.
+func skipDef(code []byte, pc int) (int, error) {
+	for pc < len(code) {
+		switch Opcode(code[pc]) {
+		case opENDF:
+			return pc, nil
+		case opFDEF, opIDEF:
+			return 0, fmt.Errorf("%s inside a definition", Opcode(code[pc]))
+		}
+		end, err := instructionEnd(code, pc)
+		if err != nil {
+			return 0, err
+		}
+		pc = end
+	}
+	return 0, errors.New("definition without ENDF")
+}
+
+// define stores a function, or an instruction definition if function is false.
+func (in *interpreter) define(function bool, n int32, body []byte) {
+	if in.sharedDefs {
+		in.functions = copyDefs(in.functions)
+		in.instructionDefs = copyDefs(in.instructionDefs)
+		in.sharedDefs = false
+	}
+	if function {
+		in.functions[n] = body
+	} else {
+		in.instructionDefs[n] = body
+	}
+}
+
+func copyDefs(defs map[int32][]byte) map[int32][]byte {
+	c := make(map[int32][]byte, len(defs))
+	for k, v := range defs {
+		c[k] = v
+	}
+	return c
+}
+
+// call runs a function, or an instruction definition.
+func (in *interpreter) call(body []byte, depth int) error {
+	if depth >= maxCallDepth {
+		return errCallDepth
+	}
+	return in.run(body, depth+1)
+}
+
+// run executes code. Function bodies are run with depth greater than 0.
+func (in *interpreter) run(code []byte, depth int) error {
+	for pc := 0; pc < len(code); {
+		if in.steps++; in.steps > maxSteps {
+			return errTooManySteps
+		}
+		op := Opcode(code[pc])
+		next, err := instructionEnd(code, pc)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case op == opNPUSHB || (op >= opPUSHB && op < opPUSHW):
+			start := pc + 1
+			if op == opNPUSHB {
+				start++
+			}
+			for _, b := range code[start:next] {
+				in.push(int32(b))
+			}
+
+		case op == opNPUSHW || (op >= opPUSHW && op < opPUSHW+8):
+			start := pc + 1
+			if op == opNPUSHW {
+				start++
+			}
+			for i := start; i < next; i += 2 {
+				in.push(int32(int16(uint16(code[i])<<8 | uint16(code[i+1]))))
+			}
+
+		case op == opIF:
+			if in.pop() == 0 && in.err == nil {
+				if next, err = skipIf(code, next, true); err != nil {
+					return fmt.Errorf("%s at %d: %w", op, pc, err)
+				}
+			}
+
+		case op == opELSE:
+			// The IF was true, so skip the ELSE body.
+			if next, err = skipIf(code, next, false); err != nil {
+				return fmt.Errorf("%s at %d: %w", op, pc, err)
+			}
+
+		case op == opEIF:
+
+		case op == opFDEF || op == opIDEF:
+			n := in.pop()
+			if in.err != nil {
+				break
+			}
+			end, err := skipDef(code, next)
+			if err != nil {
+				return fmt.Errorf("%s at %d: %w", op, pc, err)
+			}
+			in.define(op == opFDEF, n, code[next:end])
+			next = end + 1
+
+		case op == opENDF:
+			if depth == 0 {
+				return fmt.Errorf("%s at %d outside a definition", op, pc)
+			}
+			return nil
+
+		case op == 0x2A || op == 0x2B: // LOOPCALL, CALL
+			f := in.pop()
+			count := int32(1)
+			if op == 0x2A {
+				count = in.pop()
+			}
+			if in.err != nil {
+				break
+			}
+			body, ok := in.functions[f]
+			if !ok {
+				return fmt.Errorf("%s at %d: undefined function %d", op, pc, f)
+			}
+			for i := int32(0); i < count; i++ {
+				if err := in.call(body, depth); err != nil {
+					return fmt.Errorf("function %d: %w", f, err)
+				}
+			}
+
+		case op == 0x1C || op == 0x78 || op == 0x79: // JMPR, JROT, JROF
+			jump := true
+			if op != 0x1C {
+				e := in.pop()
+				jump = (e != 0) == (op == 0x78)
+			}
+			offset := in.pop()
+			if in.err != nil || !jump {
+				break
+			}
+			next = pc + int(offset)
+			if next < 0 || next > len(code) || offset == 0 {
+				return fmt.Errorf("%s at %d: jump by %d out of range", op, pc, offset)
+			}
+
+		case op.Name() == "" || op == 0x91: // GETVARIATION is unsupported without variations.
+			body, ok := in.instructionDefs[int32(op)]
+			if !ok {
+				return fmt.Errorf("undefined instruction %s at %d", op, pc)
+			}
+			if err := in.call(body, depth); err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+		default:
+			in.execute(op)
+		}
+
+		if in.err != nil {
+			err := in.err
+			in.err = nil
+			return fmt.Errorf("%s at %d: %w", op, pc, err)
+		}
+		pc = next
+	}
+	return nil
+}
+
+// execute runs an instruction that doesn't change the flow of the program.
+func (in *interpreter) execute(op Opcode) {
+	gs := &in.gs
+	switch op {
+	case 0x00, 0x01: // SVTCA
+		v := axis(op)
+		gs.projection, gs.dualProjection, gs.freedom = v, v, v
+	case 0x02, 0x03: // SPVTCA
+		gs.projection, gs.dualProjection = axis(op), axis(op)
+	case 0x04, 0x05: // SFVTCA
+		gs.freedom = axis(op)
+	case 0x06, 0x07: // SPVTL
+		if v, _, ok := in.lineVector(op); ok {
+			gs.projection, gs.dualProjection = v, v
+		}
+	case 0x08, 0x09: // SFVTL
+		if v, _, ok := in.lineVector(op); ok {
+			gs.freedom = v
+		}
+	case 0x86, 0x87: // SDPVTL
+		if v, dual, ok := in.lineVector(op); ok {
+			gs.projection, gs.dualProjection = v, dual
+		}
+	case 0x0A, 0x0B: // SPVFS, SFVFS
+		y, x := in.pop(), in.pop()
+		v := normalize(float64(int16(x)), float64(int16(y)))
+		if op == 0x0A {
+			gs.projection, gs.dualProjection = v, v
+		} else {
+			gs.freedom = v
+		}
+	case 0x0C: // GPV
+		in.push(gs.projection.X)
+		in.push(gs.projection.Y)
+	case 0x0D: // GFV
+		in.push(gs.freedom.X)
+		in.push(gs.freedom.Y)
+	case 0x0E: // SFVTPV
+		gs.freedom = gs.projection
+	case 0x0F: // ISECT
+		in.intersect()
+
+	case 0x10, 0x11, 0x12: // SRP0, SRP1, SRP2
+		gs.rp[op-0x10] = in.pop()
+	case 0x13, 0x14, 0x15, 0x16: // SZP0, SZP1, SZP2, SZPS
+		z := in.pop()
+		if z != 0 && z != 1 {
+			in.fail(fmt.Errorf("invalid zone %d", z))
+			return
+		}
+		if op == 0x16 {
+			gs.zp = [3]int32{z, z, z}
+		} else {
+			gs.zp[op-0x13] = z
+		}
+	case 0x17: // SLOOP
+		n := in.pop()
+		if n <= 0 {
+			in.fail(fmt.Errorf("invalid loop count %d", n))
+			return
+		}
+		gs.loop = n
+	case 0x18: // RTG
+		gs.setRound(64, 0, 32)
+	case 0x19: // RTHG
+		gs.setRound(64, 32, 32)
+	case 0x3D: // RTDG
+		gs.setRound(32, 0, 16)
+	case 0x7A: // ROFF
+		gs.setRound(0, 0, 0)
+	case 0x7C: // RUTG
+		gs.setRound(64, 0, 63)
+	case 0x7D: // RDTG
+		gs.setRound(64, 0, 0)
+	case 0x76: // SROUND
+		gs.setSuperRound(in.pop(), 0x4000, false)
+	case 0x77: // S45ROUND
+		gs.setSuperRound(in.pop(), 0x2D41, true) // sqrt(2)/2
+	case 0x1A: // SMD
+		gs.minDistance = in.pop()
+	case 0x1D: // SCVTCI
+		gs.controlValueCutIn = in.pop()
+	case 0x1E: // SSWCI
+		gs.singleWidthCutIn = in.pop()
+	case 0x1F: // SSW
+		gs.singleWidth = in.fromFUnits(in.pop())
+	case 0x4D: // FLIPON
+		gs.autoFlip = true
+	case 0x4E: // FLIPOFF
+		gs.autoFlip = false
+	case 0x5E: // SDB
+		gs.deltaBase = in.pop()
+	case 0x5F: // SDS
+		gs.deltaShift = in.pop()
+		if gs.deltaShift < 0 || gs.deltaShift > 6 {
+			in.fail(fmt.Errorf("invalid delta shift %d", gs.deltaShift))
+		}
+	case 0x7E, 0x7F, 0x4F: // SANGW, AA, DEBUG
+		in.pop()
+	case 0x85: // SCANCTRL
+		gs.scanControl = in.pop()
+	case 0x8D: // SCANTYPE
+		gs.scanType = in.pop()
+	case 0x8E: // INSTCTRL
+		selector, value := in.pop(), in.pop()
+		if selector < 1 || selector > 3 {
+			in.fail(fmt.Errorf("invalid selector %d", selector))
+			return
+		}
+		if in.glyphProgram {
+			return
+		}
+		flag := int32(1) << (selector - 1)
+		gs.instructControl &^= flag
+		if value != 0 {
+			gs.instructControl |= flag
+		}
+
+	case 0x20: // DUP
+		v := in.pop()
+		in.push(v)
+		in.push(v)
+	case 0x21: // POP
+		in.pop()
+	case 0x22: // CLEAR
+		in.stack = in.stack[:0]
+	case 0x23: // SWAP
+		b, a := in.pop(), in.pop()
+		in.push(b)
+		in.push(a)
+	case 0x24: // DEPTH
+		in.push(int32(len(in.stack)))
+	case 0x25, 0x26: // CINDEX, MINDEX
+		k := in.pop()
+		if k <= 0 || int(k) > len(in.stack) {
+			in.fail(fmt.Errorf("stack index %d out of range", k))
+			return
+		}
+		i := len(in.stack) - int(k)
+		v := in.stack[i]
+		if op == 0x26 {
+			in.stack = append(in.stack[:i], in.stack[i+1:]...)
+		}
+		in.push(v)
+	case 0x8A: // ROLL
+		c, b, a := in.pop(), in.pop(), in.pop()
+		in.push(b)
+		in.push(c)
+		in.push(a)
+
+	case 0x42: // WS
+		v, i := in.pop(), in.pop()
+		if i < 0 || int(i) >= len(in.storage) {
+			in.fail(fmt.Errorf("storage location %d out of range", i))
+			return
+		}
+		in.storage[i] = v
+	case 0x43: // RS
+		i := in.pop()
+		if i < 0 || int(i) >= len(in.storage) {
+			in.fail(fmt.Errorf("storage location %d out of range", i))
+			return
+		}
+		in.push(in.storage[i])
+	case 0x44, 0x70: // WCVTP, WCVTF
+		v, n := in.pop(), in.pop()
+		if op == 0x70 {
+			v = in.fromFUnits(v)
+		}
+		if in.cvtValue(n); in.err == nil {
+			in.cvt[n] = v
+		}
+	case 0x45: // RCVT
+		in.push(in.cvtValue(in.pop()))
+
+	case 0x4B, 0x4C: // MPPEM, MPS
+		// Pixels are square, so the point size is the same as the ppem.
+		in.push(in.ppem)
+	case 0x88: // GETINFO
+		selector := in.pop()
+		var result int32
+		if selector&1 != 0 {
+			result = 35 // The version of the rasterizer in Windows 3.1.
+		}
+		in.push(result)
+
+	case 0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x5A, 0x5B: // LT, LTEQ, GT, GTEQ, EQ, NEQ, AND, OR
+		b, a := in.pop(), in.pop()
+		var result bool
+		switch op {
+		case 0x50:
+			result = a < b
+		case 0x51:
+			result = a <= b
+		case 0x52:
+			result = a > b
+		case 0x53:
+			result = a >= b
+		case 0x54:
+			result = a == b
+		case 0x55:
+			result = a != b
+		case 0x5A:
+			result = a != 0 && b != 0
+		case 0x5B:
+			result = a != 0 || b != 0
+		}
+		in.push(boolValue(result))
+	case 0x56, 0x57: // ODD, EVEN
+		v := gs.round(in.pop()) & 127
+		in.push(boolValue((op == 0x56) == (v == 64)))
+	case 0x5C: // NOT
+		in.push(boolValue(in.pop() == 0))
+
+	case 0x60, 0x61, 0x62, 0x63, 0x8B, 0x8C: // ADD, SUB, DIV, MUL, MAX, MIN
+		b, a := in.pop(), in.pop()
+		var result int32
+		switch op {
+		case 0x60:
+			result = a + b
+		case 0x61:
+			result = a - b
+		case 0x62:
+			if b == 0 {
+				in.fail(errors.New("division by zero"))
+				return
+			}
+			result = int32(int64(a) * 64 / int64(b))
+		case 0x63:
+			result = int32(mulDiv(int64(a), int64(b), 64))
+		case 0x8B:
+			result = max32(a, b)
+		case 0x8C:
+			result = min32(a, b)
+		}
+		in.push(result)
+	case 0x64: // ABS
+		if v := in.pop(); v < 0 {
+			in.push(-v)
+		} else {
+			in.push(v)
+		}
+	case 0x65: // NEG
+		in.push(-in.pop())
+	case 0x66: // FLOOR
+		in.push(in.pop() &^ 63)
+	case 0x67: // CEILING
+		in.push((in.pop() + 63) &^ 63)
+	case 0x68, 0x69, 0x6A, 0x6B: // ROUND
+		in.push(gs.round(in.pop()))
+	case 0x6C, 0x6D, 0x6E, 0x6F: // NROUND
+		// There's no engine compensation, so NROUND leaves the value unchanged.
+
+	case 0x5D, 0x71, 0x72: // DELTAP1, DELTAP2, DELTAP3
+		in.deltaP(op)
+	case 0x73, 0x74, 0x75: // DELTAC1, DELTAC2, DELTAC3
+		in.deltaC(op)
+
+	case 0x2E, 0x2F: // MDAP
+		in.mdap(op)
+	case 0x3E, 0x3F: // MIAP
+		in.miap(op)
+	case 0x3A, 0x3B: // MSIRP
+		in.msirp(op)
+	case 0x30, 0x31: // IUP
+		in.iup(op)
+	case 0x32, 0x33: // SHP
+		in.shp(op)
+	case 0x34, 0x35: // SHC
+		in.shc(op)
+	case 0x36, 0x37: // SHZ
+		in.shz(op)
+	case 0x38: // SHPIX
+		in.shpix()
+	case 0x39: // IP
+		in.ip()
+	case 0x3C: // ALIGNRP
+		in.alignrp()
+	case 0x27: // ALIGNPTS
+		in.alignpts()
+	case 0x29: // UTP
+		in.utp()
+	case 0x46, 0x47: // GC
+		in.gc(op)
+	case 0x48: // SCFS
+		in.scfs()
+	case 0x49, 0x4A: // MD
+		in.md(op)
+	case 0x80: // FLIPPT
+		in.flippt()
+	case 0x81, 0x82: // FLIPRGON, FLIPRGOFF
+		in.fliprg(op == 0x81)
+
+	default:
+		switch {
+		case op >= 0xC0 && op < 0xE0:
+			in.mdrp(op)
+		case op >= 0xE0:
+			in.mirp(op)
+		default:
+			in.fail(fmt.Errorf("unsupported instruction %s", op))
+		}
+	}
+}
+
+// axis returns the y axis if the low bit of op is 0, and the x axis if it is 1.
+func axis(op Opcode) vector {
+	if op&1 == 0 {
+		return yAxis
+	}
+	return xAxis
+}
+
+func boolValue(b bool) int32 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func max32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min32(a, b int32) int32 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func abs32(a int32) int32 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
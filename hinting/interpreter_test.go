@@ -0,0 +1,373 @@
+package hinting
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// newTestInterpreter returns an interpreter at 12 ppem in which a font unit
+// is 1/64 of a pixel, with two control values, and a glyph zone containing
+// one contour of points.
+func newTestInterpreter(points []point) *interpreter {
+	in := &interpreter{
+		gs:              defaultGraphicsState,
+		cvt:             []f26dot6{100, 10},
+		storage:         make([]int32, 4),
+		functions:       make(map[int32][]byte),
+		instructionDefs: make(map[int32][]byte),
+		ppem:            12,
+		scale:           0x10000,
+		maxStack:        32,
+	}
+	if len(points) > 0 {
+		in.zones[1] = newZone(len(points))
+		g := &in.zones[1]
+		copy(g.current, points)
+		copy(g.original, points)
+		g.unscaled = append([]point(nil), points...)
+		g.scale = 0x10000
+		g.endPoints = []int{len(points) - 1}
+	}
+	return in
+}
+
+// runProgram assembles and runs a program, returning the stack.
+func runProgram(t *testing.T, text string) ([]int32, error) {
+	t.Helper()
+	in, err := runGlyphProgram(t, nil, text)
+	return in.stack, err
+}
+
+// runGlyphProgram assembles and runs a program on a glyph, returning the
+// interpreter.
+func runGlyphProgram(t *testing.T, points []point, text string) (*interpreter, error) {
+	t.Helper()
+	code, err := Assemble(text)
+	if err != nil {
+		t.Fatalf("Assemble(%q) err = %q, want nil", text, err)
+	}
+	in := newTestInterpreter(points)
+	err = in.run(code, 0)
+	return in, err
+}
+
+func TestRun(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want []int32
+	}{
+		{"PUSH 3 4 ADD PUSH 128 MUL", []int32{14}},
+		{"PUSH 128 192 MUL PUSH 64 DIV", []int32{384}},
+		{"PUSH 1 2 SWAP", []int32{2, 1}},
+		{"PUSH 1 2 3 PUSH 3 CINDEX", []int32{1, 2, 3, 1}},
+		{"PUSH 1 2 3 ROLL", []int32{2, 3, 1}},
+		{"PUSH 1 IF PUSH 5 ELSE PUSH 6 EIF", []int32{5}},
+		{"PUSH 0 IF PUSH 5 IF PUSH 7 EIF ELSE PUSH 6 EIF", []int32{6}},
+		{"PUSH 2 9 WS PUSH 2 RS", []int32{9}},
+		{"PUSH 0 FDEF PUSH 1 ADD ENDF PUSH 5 0 CALL", []int32{6}},
+		{"PUSH 0 FDEF DUP ENDF PUSH 7 3 0 LOOPCALL", []int32{7, 7, 7, 7}},
+		{"PUSH 0x92 IDEF PUSH 8 ENDF INS_92", []int32{8}},
+		{"PUSH 5 3 JMPR PUSH 6", []int32{5}},
+		{"PUSH 100 RTG ROUND[00] PUSH 100 RDTG ROUND[00]", []int32{128, 64}},
+		{"PUSH 100 RTHG ROUND[00] PUSH 100 ROFF ROUND[00]", []int32{96, 100}},
+		{"PUSH 0x48 SROUND PUSH 100 ROUND[00]", []int32{128}},
+		{"PUSH -100 RTG ROUND[00] PUSH -10 NEG", []int32{-128, 10}},
+		{"MPPEM MPS", []int32{12, 12}},
+		{"PUSH 0x37 0 1 DELTAC1 PUSH 0 RCVT", []int32{92}},
+		{"PUSH 0x2F 1 0x3F 1 2 DELTAC1 PUSH 1 RCVT", []int32{74}},
+	} {
+		got, err := runProgram(t, test.text)
+		if err != nil {
+			t.Errorf("run(%q) err = %q, want nil", test.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("run(%q) stack = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		want error
+	}{
+		{"POP", errStackUnderflow},
+		{"PUSH 1 ADD", errStackUnderflow},
+		{"PUSHW[000] -3 JMPR", errTooManySteps},
+		{"PUSH 0 FDEF PUSH 0 CALL ENDF PUSH 0 CALL", errCallDepth},
+	} {
+		if _, err := runProgram(t, test.text); !errors.Is(err, test.want) {
+			t.Errorf("run(%q) err = %v, want %v", test.text, err, test.want)
+		}
+	}
+
+	for _, text := range []string{
+		"PUSH 0 IF PUSH 2",
+		"PUSH 3 CALL",
+		"PUSH 1 0 DIV",
+		"PUSH 9 RS",
+		"PUSH 2 SZP0",
+		"PUSH 0 SLOOP",
+		"PUSH 7 SDS",
+	} {
+		if _, err := runProgram(t, text); err == nil {
+			t.Errorf("run(%q) err = nil, want an error", text)
+		}
+	}
+}
+
+func TestGraphicsState(t *testing.T) {
+	diagonal := []point{{0, 0}, {64, 64}}
+	for _, test := range []struct {
+		text string
+		want func(gs *graphicsState)
+	}{
+		{"SVTCA[0]", func(gs *graphicsState) {
+			gs.projection, gs.dualProjection, gs.freedom = yAxis, yAxis, yAxis
+		}},
+		{"SFVTCA[0]", func(gs *graphicsState) { gs.freedom = yAxis }},
+		{"PUSH 1 0 SPVTL[0]", func(gs *graphicsState) {
+			gs.projection, gs.dualProjection = vector{0x2D41, 0x2D41}, vector{0x2D41, 0x2D41}
+		}},
+		{"PUSH 1 0 SPVTL[1] SFVTPV", func(gs *graphicsState) {
+			v := vector{-0x2D41, 0x2D41}
+			gs.projection, gs.dualProjection, gs.freedom = v, v, v
+		}},
+		{"PUSH 3 4 SFVFS", func(gs *graphicsState) { gs.freedom = vector{0x2666, 0x3333} }},
+		{"PUSH 3 2 1 SRP0 SRP1 SRP2", func(gs *graphicsState) { gs.rp = [3]int32{1, 2, 3} }},
+		{"PUSH 0 SZP1", func(gs *graphicsState) { gs.zp = [3]int32{1, 0, 1} }},
+		{"PUSH 0 SZPS", func(gs *graphicsState) { gs.zp = [3]int32{} }},
+		{"PUSH 3 SLOOP", func(gs *graphicsState) { gs.loop = 3 }},
+		{"PUSH 96 SMD PUSH 32 SCVTCI PUSH 16 SSWCI PUSH 128 SSW", func(gs *graphicsState) {
+			gs.minDistance, gs.controlValueCutIn = 96, 32
+			gs.singleWidthCutIn, gs.singleWidth = 16, 128
+		}},
+		{"PUSH 12 SDB PUSH 2 SDS FLIPOFF", func(gs *graphicsState) {
+			gs.deltaBase, gs.deltaShift, gs.autoFlip = 12, 2, false
+		}},
+		{"RTDG", func(gs *graphicsState) { gs.roundPeriod, gs.roundThreshold = 32, 16 }},
+		{"ROFF", func(gs *graphicsState) { gs.roundPeriod, gs.roundThreshold = 0, 0 }},
+	} {
+		in, err := runGlyphProgram(t, diagonal, test.text)
+		if err != nil {
+			t.Errorf("run(%q) err = %q, want nil", test.text, err)
+			continue
+		}
+		want := defaultGraphicsState
+		test.want(&want)
+		if in.gs != want {
+			t.Errorf("run(%q) state = %+v, want %+v", test.text, in.gs, want)
+		}
+	}
+}
+
+func TestMovePoints(t *testing.T) {
+	line := []point{{0, 0}, {100, 0}, {200, 0}, {300, 0}}
+	// The distances from point 0 are those of control value 0 (100) within
+	// the cut-in, larger than the cut-in, opposite, and below the minimum
+	// distance from control value 1 (10).
+	distances := []point{{0, 0}, {160, 0}, {-160, 0}, {20, 0}}
+	for _, test := range []struct {
+		points []point
+		text   string
+		want   []point
+	}{
+		{line, "SVTCA[0] PUSH 1 32 SHPIX", []point{{0, 0}, {100, 32}, {200, 0}, {300, 0}}},
+
+		// SHP moves points by the movement of rp2, or of rp1.
+		{line, "PUSH 0 64 SHPIX PUSH 0 SRP2 PUSH 1 2 2 SLOOP SHP[0]", []point{{64, 0}, {164, 0}, {264, 0}, {300, 0}}},
+		{line, "PUSH 0 64 SHPIX PUSH 0 SRP1 PUSH 3 SHP[1]", []point{{64, 0}, {100, 0}, {200, 0}, {364, 0}}},
+
+		// IP keeps points in proportion between rp1 and rp2.
+		{line, "PUSH 3 60 SHPIX PUSH 0 SRP1 PUSH 3 SRP2 PUSH 1 2 2 SLOOP IP", []point{{0, 0}, {120, 0}, {240, 0}, {360, 0}}},
+
+		// IUP moves the untouched points with a single touched point, and
+		// otherwise interpolates between touched points, or moves the points
+		// outside them with the nearest one.
+		{line, "PUSH 2 32 SHPIX IUP[1]", []point{{32, 0}, {132, 0}, {232, 0}, {332, 0}}},
+		{line, "PUSH 0 -64 SHPIX PUSH 2 32 SHPIX IUP[1]", []point{{-64, 0}, {84, 0}, {232, 0}, {332, 0}}},
+		{line, "PUSH 2 32 SHPIX IUP[0]", []point{{0, 0}, {100, 0}, {232, 0}, {300, 0}}},
+
+		// MIRP uses the control value unless it is rounded and further than
+		// the cut-in from the original distance.
+		{distances, "PUSH 0 SRP0 PUSH 1 0 MIRP[00000]", []point{{0, 0}, {100, 0}, {-160, 0}, {20, 0}}},
+		{distances, "PUSH 0 SRP0 PUSH 1 0 MIRP[00100]", []point{{0, 0}, {128, 0}, {-160, 0}, {20, 0}}},
+		{distances, "PUSH 32 SCVTCI PUSH 0 SRP0 PUSH 1 0 MIRP[00100]", []point{{0, 0}, {192, 0}, {-160, 0}, {20, 0}}},
+		{distances, "PUSH 0 SRP0 PUSH 2 0 MIRP[00100]", []point{{0, 0}, {160, 0}, {-128, 0}, {20, 0}}},
+		{distances, "PUSH 0 SRP0 PUSH 3 1 MIRP[01100]", []point{{0, 0}, {160, 0}, {-160, 0}, {64, 0}}},
+
+		// DELTAP1 moves points by 1/8 pixel steps at 9 + 3 ppem, and the
+		// delta base and shift change the size and the steps.
+		{line, "PUSH 0x3B 1 0x4F 2 2 DELTAP1", []point{{0, 0}, {132, 0}, {200, 0}, {300, 0}}},
+		{line, "PUSH 12 SDB PUSH 0 SDS PUSH 0x0F 3 1 DELTAP1", []point{{0, 0}, {100, 0}, {200, 0}, {812, 0}}},
+		{line, "PUSH -4 SDB PUSH 0x0F 1 1 DELTAP2", []point{{0, 0}, {164, 0}, {200, 0}, {300, 0}}},
+	} {
+		in, err := runGlyphProgram(t, test.points, test.text)
+		if err != nil {
+			t.Errorf("run(%q) err = %q, want nil", test.text, err)
+			continue
+		}
+		if got := in.zones[1].current; !reflect.DeepEqual(got, test.want) {
+			t.Errorf("run(%q) points = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestHinter(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		hinted bool
+	}{
+		{"open-sans-v15-latin-regular.woff", true},
+		{"Roboto-BoldItalic.ttf", false},
+	} {
+		file, err := os.Open(filepath.Join("..", "sfnt", "testdata", test.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		font, err := sfnt.Parse(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		h, err := NewHinter(font)
+		if err != nil {
+			t.Fatalf("NewHinter(%s) err = %q, want nil", test.name, err)
+		}
+		if _, err := h.GlyphOutline(0); !errors.Is(err, errNoSize) {
+			t.Errorf("%s: GlyphOutline() before SetPPEM err = %v, want %v", test.name, err, errNoSize)
+		}
+		const ppem = 12
+		if err := h.SetPPEM(ppem); err != nil {
+			t.Fatalf("%s: SetPPEM(%d) err = %q, want nil", test.name, ppem, err)
+		}
+		head, err := font.HeadTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		scale := float64(ppem) / float64(head.UnitsPerEm)
+
+		maxp, err := font.MaxpTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		glyf, err := font.GlyfTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for id := sfnt.GlyphID(0); id < sfnt.GlyphID(maxp.NumGlyphs); id++ {
+			got, err := h.GlyphOutline(id)
+			if err != nil {
+				t.Fatalf("%s: GlyphOutline(%d) err = %q, want nil", test.name, id, err)
+			}
+			if got.AdvanceWidth != float64(int(got.AdvanceWidth)) {
+				t.Errorf("%s: glyph %d advance %v is not a whole number of pixels", test.name, id, got.AdvanceWidth)
+			}
+			if test.hinted {
+				continue
+			}
+
+			// Without instructions, the outline of a simple glyph is the
+			// scaled outline rounded to 1/64 of a pixel. The offsets of
+			// components may be rounded to the grid.
+			glyph, err := glyf.Glyph(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if glyph.IsComposite() {
+				continue
+			}
+			want, err := font.GlyphOutline(id, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got.Points) != len(want.Points) {
+				t.Fatalf("%s: glyph %d has %d points, want %d", test.name, id, len(got.Points), len(want.Points))
+			}
+			for i, p := range want.Points {
+				q := got.Points[i]
+				if abs(q.X-p.X*scale) > 1.0/32 || abs(q.Y-p.Y*scale) > 1.0/32 || q.OnCurve != p.OnCurve {
+					t.Errorf("%s: glyph %d point %d = %v, want %v scaled by %v", test.name, id, i, q, p, scale)
+					break
+				}
+			}
+		}
+	}
+}
+
+// TestHinterFreeType compares grid-fitted glyphs of Open Sans at 12 ppem,
+// including a composite glyph, with those of FreeType 2.12 using its v35
+// interpreter, loaded with FT_LOAD_TARGET_MONO | FT_LOAD_NO_AUTOHINT.
+func TestHinterFreeType(t *testing.T) {
+	file, err := os.Open(filepath.Join("..", "sfnt", "testdata", "open-sans-v15-latin-regular.woff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	font, err := sfnt.Parse(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := NewHinter(font)
+	if err != nil {
+		t.Fatalf("NewHinter() err = %q, want nil", err)
+	}
+	if err := h.SetPPEM(12); err != nil {
+		t.Fatalf("SetPPEM(12) err = %q, want nil", err)
+	}
+
+	// The advances and the x and y of each point, in 26.6 fixed-point pixels.
+	for _, test := range []struct {
+		glyph   sfnt.GlyphID
+		advance int32
+		points  []int32
+	}{
+		{20, 448, []int32{ // one
+			273, 0, 212, 0, 212, 400, 212, 450, 215, 495, 207, 485, 187, 464, 106, 384,
+			72, 437, 221, 576, 273, 576,
+		}},
+		{43, 576, []int32{ // H
+			500, 0, 436, 0, 436, 256, 141, 256, 141, 0, 77, 0, 77, 576, 141, 576,
+			141, 320, 436, 320, 436, 576, 500, 576,
+		}},
+		{81, 448, []int32{ // o
+			406, 192, 406, 102, 308, 0, 222, 0, 169, 0, 87, 47, 42, 134, 42, 192,
+			42, 283, 139, 384, 225, 384, 308, 384, 406, 281, 107, 193, 107, 130, 166, 64,
+			224, 64, 281, 64, 341, 129, 341, 193, 341, 255, 281, 320, 223, 320, 166, 320,
+			107, 256,
+		}},
+		{166, 448, []int32{ // eacute
+			249, 0, 155, 0, 45, 100, 45, 189, 45, 279, 147, 384, 232, 384, 313, 384,
+			406, 297, 406, 226, 406, 192, 110, 192, 112, 129, 185, 64, 251, 64, 320, 64,
+			388, 92, 388, 31, 354, 15, 293, 0, 232, 320, 179, 320, 117, 286, 111, 256,
+			339, 256, 339, 287, 283, 320, 149, 458, 167, 482, 209, 551, 221, 576, 298, 576,
+			298, 568, 281, 542, 215, 468, 191, 448, 149, 448,
+		}},
+	} {
+		outline, err := h.GlyphOutline(test.glyph)
+		if err != nil {
+			t.Fatalf("GlyphOutline(%d) err = %q, want nil", test.glyph, err)
+		}
+		got := []int32{}
+		for _, p := range outline.Points {
+			got = append(got, int32(p.X*64), int32(p.Y*64))
+		}
+		if advance := int32(outline.AdvanceWidth * 64); advance != test.advance || !reflect.DeepEqual(got, test.points) {
+			t.Errorf("GlyphOutline(%d) = %d, %v, want %d, %v", test.glyph, advance, got, test.advance, test.points)
+		}
+	}
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,136 @@
+package hinting
+
+import "fmt"
+
+// Opcode is a TrueType instruction opcode.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/tt_instructions
+type Opcode byte
+
+// Opcodes with operands in the instruction stream, or which change the flow
+// of the program. Opcodes with flag bits are the first of their range.
+const (
+	opIF     Opcode = 0x58
+	opELSE   Opcode = 0x1B
+	opEIF    Opcode = 0x59
+	opFDEF   Opcode = 0x2C
+	opIDEF   Opcode = 0x89
+	opENDF   Opcode = 0x2D
+	opNPUSHB Opcode = 0x40
+	opNPUSHW Opcode = 0x41
+	opPUSHB  Opcode = 0xB0
+	opPUSHW  Opcode = 0xB8
+)
+
+// opcodeInfo describes the opcodes whose low bits are flags.
+type opcodeInfo struct {
+	name string
+	bits uint // bits is the number of low bits of the opcode that are flags.
+}
+
+// opcodes contains the name of every defined opcode, indexed by the first
+// opcode of its range.
+var opcodes = [256]opcodeInfo{
+	0x00: {"SVTCA", 1}, 0x02: {"SPVTCA", 1}, 0x04: {"SFVTCA", 1}, 0x06: {"SPVTL", 1},
+	0x08: {"SFVTL", 1}, 0x0A: {"SPVFS", 0}, 0x0B: {"SFVFS", 0}, 0x0C: {"GPV", 0},
+	0x0D: {"GFV", 0}, 0x0E: {"SFVTPV", 0}, 0x0F: {"ISECT", 0},
+
+	0x10: {"SRP0", 0}, 0x11: {"SRP1", 0}, 0x12: {"SRP2", 0}, 0x13: {"SZP0", 0},
+	0x14: {"SZP1", 0}, 0x15: {"SZP2", 0}, 0x16: {"SZPS", 0}, 0x17: {"SLOOP", 0},
+	0x18: {"RTG", 0}, 0x19: {"RTHG", 0}, 0x1A: {"SMD", 0}, 0x1B: {"ELSE", 0},
+	0x1C: {"JMPR", 0}, 0x1D: {"SCVTCI", 0}, 0x1E: {"SSWCI", 0}, 0x1F: {"SSW", 0},
+
+	0x20: {"DUP", 0}, 0x21: {"POP", 0}, 0x22: {"CLEAR", 0}, 0x23: {"SWAP", 0},
+	0x24: {"DEPTH", 0}, 0x25: {"CINDEX", 0}, 0x26: {"MINDEX", 0}, 0x27: {"ALIGNPTS", 0},
+	0x29: {"UTP", 0}, 0x2A: {"LOOPCALL", 0}, 0x2B: {"CALL", 0}, 0x2C: {"FDEF", 0},
+	0x2D: {"ENDF", 0}, 0x2E: {"MDAP", 1},
+
+	0x30: {"IUP", 1}, 0x32: {"SHP", 1}, 0x34: {"SHC", 1}, 0x36: {"SHZ", 1},
+	0x38: {"SHPIX", 0}, 0x39: {"IP", 0}, 0x3A: {"MSIRP", 1}, 0x3C: {"ALIGNRP", 0},
+	0x3D: {"RTDG", 0}, 0x3E: {"MIAP", 1},
+
+	0x40: {"NPUSHB", 0}, 0x41: {"NPUSHW", 0}, 0x42: {"WS", 0}, 0x43: {"RS", 0},
+	0x44: {"WCVTP", 0}, 0x45: {"RCVT", 0}, 0x46: {"GC", 1}, 0x48: {"SCFS", 0},
+	0x49: {"MD", 1}, 0x4B: {"MPPEM", 0}, 0x4C: {"MPS", 0}, 0x4D: {"FLIPON", 0},
+	0x4E: {"FLIPOFF", 0}, 0x4F: {"DEBUG", 0},
+
+	0x50: {"LT", 0}, 0x51: {"LTEQ", 0}, 0x52: {"GT", 0}, 0x53: {"GTEQ", 0},
+	0x54: {"EQ", 0}, 0x55: {"NEQ", 0}, 0x56: {"ODD", 0}, 0x57: {"EVEN", 0},
+	0x58: {"IF", 0}, 0x59: {"EIF", 0}, 0x5A: {"AND", 0}, 0x5B: {"OR", 0},
+	0x5C: {"NOT", 0}, 0x5D: {"DELTAP1", 0}, 0x5E: {"SDB", 0}, 0x5F: {"SDS", 0},
+
+	0x60: {"ADD", 0}, 0x61: {"SUB", 0}, 0x62: {"DIV", 0}, 0x63: {"MUL", 0},
+	0x64: {"ABS", 0}, 0x65: {"NEG", 0}, 0x66: {"FLOOR", 0}, 0x67: {"CEILING", 0},
+	0x68: {"ROUND", 2}, 0x6C: {"NROUND", 2},
+
+	0x70: {"WCVTF", 0}, 0x71: {"DELTAP2", 0}, 0x72: {"DELTAP3", 0}, 0x73: {"DELTAC1", 0},
+	0x74: {"DELTAC2", 0}, 0x75: {"DELTAC3", 0}, 0x76: {"SROUND", 0}, 0x77: {"S45ROUND", 0},
+	0x78: {"JROT", 0}, 0x79: {"JROF", 0}, 0x7A: {"ROFF", 0}, 0x7C: {"RUTG", 0},
+	0x7D: {"RDTG", 0}, 0x7E: {"SANGW", 0}, 0x7F: {"AA", 0},
+
+	0x80: {"FLIPPT", 0}, 0x81: {"FLIPRGON", 0}, 0x82: {"FLIPRGOFF", 0}, 0x85: {"SCANCTRL", 0},
+	0x86: {"SDPVTL", 1}, 0x88: {"GETINFO", 0}, 0x89: {"IDEF", 0}, 0x8A: {"ROLL", 0},
+	0x8B: {"MAX", 0}, 0x8C: {"MIN", 0}, 0x8D: {"SCANTYPE", 0}, 0x8E: {"INSTCTRL", 0},
+
+	0x91: {"GETVARIATION", 0},
+
+	0xB0: {"PUSHB", 3}, 0xB8: {"PUSHW", 3},
+	0xC0: {"MDRP", 5}, 0xE0: {"MIRP", 5},
+}
+
+// opcodeByName maps the names of opcodes to the first opcode of their range.
+var opcodeByName = make(map[string]Opcode)
+
+// opcodeBase contains the first opcode of the range of every defined opcode.
+var opcodeBase [256]Opcode
+
+func init() {
+	for i, info := range opcodes {
+		if info.name == "" {
+			continue
+		}
+		opcodeByName[info.name] = Opcode(i)
+		for j := 0; j < 1<<info.bits; j++ {
+			opcodeBase[i+j] = Opcode(i)
+		}
+	}
+}
+
+// info returns the name and number of flag bits of the range containing op,
+// and the first opcode of the range. The name is empty for undefined opcodes.
+func (op Opcode) info() (opcodeInfo, Opcode) {
+	base := opcodeBase[op]
+	info := opcodes[base]
+	if info.name == "" || op-base >= 1<<info.bits {
+		return opcodeInfo{}, op
+	}
+	return info, base
+}
+
+// Name returns the name of the opcode without its flags, such as "MIRP", or
+// "" if the opcode is undefined.
+func (op Opcode) Name() string {
+	info, _ := op.info()
+	return info.name
+}
+
+// Flags returns the value of the low bits of opcodes such as MIRP, which
+// select how the instruction behaves. For PUSHB and PUSHW, it is one less
+// than the number of values pushed.
+func (op Opcode) Flags() byte {
+	_, base := op.info()
+	return byte(op - base)
+}
+
+// String returns the opcode as written by the assembler, such as
+// "MIRP[10110]" with the flags in binary, or "INS_92" for undefined
+// opcodes, which may be defined by the font using IDEF.
+func (op Opcode) String() string {
+	info, base := op.info()
+	if info.name == "" {
+		return fmt.Sprintf("INS_%02X", byte(op))
+	}
+	if info.bits == 0 {
+		return info.name
+	}
+	return fmt.Sprintf("%s[%0*b]", info.name, info.bits, op-base)
+}
@@ -0,0 +1,590 @@
+package hinting
+
+import (
+	"errors"
+	"fmt"
+)
+
+// lineVector pops two points and returns the unit vectors from the second
+// to the first in the current and the original outline, rotated 90 degrees
+// counter-clockwise if the low bit of op is set.
+func (in *interpreter) lineVector(op Opcode) (current, original vector, ok bool) {
+	i2, i1 := in.pop(), in.pop()
+	z1, z2 := in.zone(1), in.zone(2)
+	if in.err != nil || !in.valid(z1, i1) || !in.valid(z2, i2) {
+		return vector{}, vector{}, false
+	}
+	line := func(a, b point) vector {
+		dx, dy := float64(a.X-b.X), float64(a.Y-b.Y)
+		if op&1 != 0 {
+			dx, dy = -dy, dx
+		}
+		return normalize(dx, dy)
+	}
+	return line(z1.current[i1], z2.current[i2]), line(z1.original[i1], z2.original[i2]), true
+}
+
+// intersect moves a point to the intersection of two lines (ISECT).
+func (in *interpreter) intersect() {
+	b1, b0, a1, a0, p := in.pop(), in.pop(), in.pop(), in.pop(), in.pop()
+	za, zb, zp := in.zone(1), in.zone(0), in.zone(2)
+	if in.err != nil || !in.valid(za, a0) || !in.valid(za, a1) ||
+		!in.valid(zb, b0) || !in.valid(zb, b1) || !in.valid(zp, p) {
+		return
+	}
+	pa0, pa1 := za.current[a0], za.current[a1]
+	pb0, pb1 := zb.current[b0], zb.current[b1]
+
+	dbx, dby := int64(pb1.X-pb0.X), int64(pb1.Y-pb0.Y)
+	dax, day := int64(pa1.X-pa0.X), int64(pa1.Y-pa0.Y)
+	dx, dy := int64(pb0.X-pa0.X), int64(pb0.Y-pa0.Y)
+	discriminant := mulDiv(dax, -dby, 64) + mulDiv(day, dbx, 64)
+	dotProduct := mulDiv(dax, dbx, 64) + mulDiv(day, dby, 64)
+
+	if 19*abs64(discriminant) > abs64(dotProduct) {
+		v := mulDiv(dx, -dby, 64) + mulDiv(dy, dbx, 64)
+		zp.current[p] = point{
+			X: pa0.X + f26dot6(mulDiv(v, dax, discriminant)),
+			Y: pa0.Y + f26dot6(mulDiv(v, day, discriminant)),
+		}
+	} else {
+		// The lines are almost parallel, so use the middle of their points.
+		zp.current[p] = point{
+			X: (pa0.X + pa1.X + pb0.X + pb1.X) / 4,
+			Y: (pa0.Y + pa1.Y + pb0.Y + pb1.Y) / 4,
+		}
+	}
+	zp.touched[p] |= touchedX | touchedY
+}
+
+// deltaStep returns the movement of a DELTAP or DELTAC exception at the
+// current size, or false if it applies at another size.
+func (in *interpreter) deltaStep(arg int32, base int32) (f26dot6, bool) {
+	if in.gs.deltaBase+base+(arg>>4&15) != in.ppem {
+		return 0, false
+	}
+	step := arg&15 - 8
+	if step >= 0 {
+		step++
+	}
+	return step * 64 / (1 << in.gs.deltaShift), true
+}
+
+// deltaP moves points at particular sizes (DELTAP1, DELTAP2 and DELTAP3).
+func (in *interpreter) deltaP(op Opcode) {
+	base := int32(op-0x70) * 16 // DELTAP2 and DELTAP3
+	if op == 0x5D {
+		base = 0
+	}
+	z := in.zone(0)
+	for n := in.pop(); n > 0 && in.err == nil; n-- {
+		p, arg := in.pop(), in.pop()
+		d, ok := in.deltaStep(arg, base)
+		// Like other rasterizers, ignore exceptions for missing points.
+		if ok && p >= 0 && int(p) < len(z.current) {
+			in.move(z, p, d)
+		}
+	}
+}
+
+// deltaC changes control values at particular sizes (DELTAC1, DELTAC2 and DELTAC3).
+func (in *interpreter) deltaC(op Opcode) {
+	base := int32(op-0x73) * 16
+	for n := in.pop(); n > 0 && in.err == nil; n-- {
+		c, arg := in.pop(), in.pop()
+		if d, ok := in.deltaStep(arg, base); ok {
+			if in.cvtValue(c); in.err == nil {
+				in.cvt[c] += d
+			}
+		}
+	}
+}
+
+// mdap touches a point, rounding it if the low bit of op is set (MDAP).
+func (in *interpreter) mdap(op Opcode) {
+	p := in.pop()
+	z := in.zone(0)
+	if in.err != nil || !in.valid(z, p) {
+		return
+	}
+	var d f26dot6
+	if op&1 != 0 {
+		cur := in.gs.projection.dot(z.current[p].X, z.current[p].Y)
+		d = in.gs.round(cur) - cur
+	}
+	in.move(z, p, d)
+	in.gs.rp[0], in.gs.rp[1] = p, p
+}
+
+// miap moves a point to a control value, rounding it if the low bit of op
+// is set (MIAP).
+func (in *interpreter) miap(op Opcode) {
+	n, p := in.pop(), in.pop()
+	z := in.zone(0)
+	d := in.cvtValue(n)
+	if in.err != nil || !in.valid(z, p) {
+		return
+	}
+	if in.gs.zp[0] == 0 {
+		x, y := in.gs.freedom.scale(d)
+		z.original[p] = point{x, y}
+		z.current[p] = z.original[p]
+	}
+	cur := in.gs.projection.dot(z.current[p].X, z.current[p].Y)
+	if op&1 != 0 {
+		if abs32(d-cur) > in.gs.controlValueCutIn {
+			d = cur
+		}
+		d = in.gs.round(d)
+	}
+	in.move(z, p, d-cur)
+	in.gs.rp[0], in.gs.rp[1] = p, p
+}
+
+// msirp moves a point to a distance from rp0 (MSIRP), and sets it as rp0
+// if the low bit of op is set.
+func (in *interpreter) msirp(op Opcode) {
+	d, p := in.pop(), in.pop()
+	z0, z1 := in.zone(0), in.zone(1)
+	rp0 := in.gs.rp[0]
+	if in.err != nil || !in.valid(z0, rp0) || !in.valid(z1, p) {
+		return
+	}
+	if in.gs.zp[1] == 0 {
+		dx, dy := in.displacement(d)
+		z1.original[p] = point{z0.original[rp0].X + dx, z0.original[rp0].Y + dy}
+		z1.current[p] = z1.original[p]
+	}
+	cur := in.project(z1.current[p], z0.current[rp0])
+	in.move(z1, p, d-cur)
+	in.gs.rp[1], in.gs.rp[2] = rp0, p
+	if op&1 != 0 {
+		in.gs.rp[0] = p
+	}
+}
+
+// originalDistance returns the distance from point b of zb to point a of za
+// in the original outline along the dual projection vector. Outside the
+// twilight zone, it is measured in font units for precision.
+func (in *interpreter) originalDistance(za *zone, a int32, zb *zone, b int32, twilight bool) f26dot6 {
+	if twilight {
+		return in.dualProject(za.original[a], zb.original[b])
+	}
+	d := in.dualProject(za.unscaled[a], zb.unscaled[b])
+	return f26dot6(mulFix(int64(d), za.scale))
+}
+
+// singleWidth returns d, or the single width value with the sign of d if
+// d is within the single width cut-in of the single width value.
+func (in *interpreter) singleWidth(d f26dot6) f26dot6 {
+	if in.gs.singleWidthCutIn > 0 && abs32(d-in.gs.singleWidth) < in.gs.singleWidthCutIn {
+		if d < 0 {
+			return -in.gs.singleWidth
+		}
+		return in.gs.singleWidth
+	}
+	return d
+}
+
+// minimumDistance returns d increased to the minimum distance, keeping the
+// sign of the original distance.
+func (in *interpreter) minimumDistance(d, original f26dot6) f26dot6 {
+	if original >= 0 {
+		return max32(d, in.gs.minDistance)
+	}
+	return min32(d, -in.gs.minDistance)
+}
+
+// mdrp moves a point to its original distance from rp0 (MDRP).
+func (in *interpreter) mdrp(op Opcode) {
+	p := in.pop()
+	z0, z1 := in.zone(0), in.zone(1)
+	rp0 := in.gs.rp[0]
+	if in.err != nil || !in.valid(z0, rp0) || !in.valid(z1, p) {
+		return
+	}
+
+	twilight := in.gs.zp[0] == 0 || in.gs.zp[1] == 0
+	original := in.singleWidth(in.originalDistance(z1, p, z0, rp0, twilight))
+	d := original
+	if op&4 != 0 {
+		d = in.gs.round(d)
+	}
+	if op&8 != 0 {
+		d = in.minimumDistance(d, original)
+	}
+	in.move(z1, p, d-in.project(z1.current[p], z0.current[rp0]))
+
+	in.gs.rp[1], in.gs.rp[2] = rp0, p
+	if op&16 != 0 {
+		in.gs.rp[0] = p
+	}
+}
+
+// mirp moves a point to the distance of a control value from rp0 (MIRP).
+func (in *interpreter) mirp(op Opcode) {
+	n, p := in.pop(), in.pop()
+	z0, z1 := in.zone(0), in.zone(1)
+	rp0 := in.gs.rp[0]
+	cvt := in.singleWidth(in.cvtValue(n))
+	if in.err != nil || !in.valid(z0, rp0) || !in.valid(z1, p) {
+		return
+	}
+
+	if in.gs.zp[1] == 0 {
+		dx, dy := in.gs.freedom.scale(cvt)
+		z1.original[p] = point{z0.original[rp0].X + dx, z0.original[rp0].Y + dy}
+		z1.current[p] = z1.original[p]
+	}
+	original := in.dualProject(z1.original[p], z0.original[rp0])
+	current := in.project(z1.current[p], z0.current[rp0])
+	if in.gs.autoFlip && (original^cvt) < 0 {
+		cvt = -cvt
+	}
+
+	d := cvt
+	if op&4 != 0 {
+		// The cut-in only applies when both points are in the same zone.
+		if in.gs.zp[0] == in.gs.zp[1] && abs32(cvt-original) > in.gs.controlValueCutIn {
+			d = original
+		}
+		d = in.gs.round(d)
+	}
+	if op&8 != 0 {
+		d = in.minimumDistance(d, original)
+	}
+	in.move(z1, p, d-current)
+
+	in.gs.rp[1], in.gs.rp[2] = rp0, p
+	if op&16 != 0 {
+		in.gs.rp[0] = p
+	}
+}
+
+// loop calls f for each point popped by a looping instruction, such as IP.
+func (in *interpreter) loop(f func(p int32)) {
+	for ; in.gs.loop > 0 && in.err == nil; in.gs.loop-- {
+		if p := in.pop(); in.err == nil {
+			f(p)
+		}
+	}
+	in.gs.loop = 1
+}
+
+// reference returns the reference point used by SHP, SHC and SHZ, and the
+// displacement of the reference point along the freedom vector.
+func (in *interpreter) reference(op Opcode) (z *zone, rp int32, dx, dy f26dot6, ok bool) {
+	if op&1 == 0 {
+		z, rp = in.zone(1), in.gs.rp[2]
+	} else {
+		z, rp = in.zone(0), in.gs.rp[1]
+	}
+	if !in.valid(z, rp) {
+		return nil, 0, 0, 0, false
+	}
+	dx, dy = in.displacement(in.project(z.current[rp], z.original[rp]))
+	return z, rp, dx, dy, true
+}
+
+// shp shifts points by the movement of the reference point (SHP).
+func (in *interpreter) shp(op Opcode) {
+	_, _, dx, dy, ok := in.reference(op)
+	if !ok {
+		return
+	}
+	z := in.zone(2)
+	in.loop(func(p int32) {
+		if in.valid(z, p) {
+			in.shift(z, p, dx, dy, true)
+		}
+	})
+}
+
+// shc shifts a contour by the movement of the reference point (SHC).
+func (in *interpreter) shc(op Opcode) {
+	c := in.pop()
+	ref, rp, dx, dy, ok := in.reference(op)
+	if in.err != nil || !ok {
+		return
+	}
+	z := in.zone(2)
+	if c < 0 || int(c) >= len(z.endPoints) {
+		in.fail(fmt.Errorf("contour %d out of range", c))
+		return
+	}
+	start := int32(0)
+	if c > 0 {
+		start = int32(z.endPoints[c-1] + 1)
+	}
+	for p := start; p <= int32(z.endPoints[c]); p++ {
+		if z != ref || p != rp {
+			in.shift(z, p, dx, dy, true)
+		}
+	}
+}
+
+// shz shifts the points of a zone by the movement of the reference point
+// (SHZ). Like other rasterizers, it shifts the zone referenced by zp2, not
+// the zone it pops.
+func (in *interpreter) shz(op Opcode) {
+	e := in.pop()
+	ref, rp, dx, dy, ok := in.reference(op)
+	if in.err != nil || !ok {
+		return
+	}
+	if e != 0 && e != 1 {
+		in.fail(fmt.Errorf("invalid zone %d", e))
+		return
+	}
+	z := in.zone(2)
+	for p := int32(0); p < int32(z.numOutlinePoints()); p++ {
+		if z != ref || p != rp {
+			in.shift(z, p, dx, dy, false)
+		}
+	}
+}
+
+// shpix shifts points by a distance along the freedom vector (SHPIX).
+func (in *interpreter) shpix() {
+	dx, dy := in.gs.freedom.scale(in.pop())
+	z := in.zone(2)
+	in.loop(func(p int32) {
+		if in.valid(z, p) {
+			in.shift(z, p, dx, dy, true)
+		}
+	})
+}
+
+// ip moves points so that they keep their original position relative to
+// rp1 and rp2 (IP).
+func (in *interpreter) ip() {
+	z0, z1, z2 := in.zone(0), in.zone(1), in.zone(2)
+	rp1, rp2 := in.gs.rp[1], in.gs.rp[2]
+	if !in.valid(z0, rp1) || !in.valid(z1, rp2) {
+		return
+	}
+
+	// Outside the twilight zone, the original outline is measured in font units.
+	twilight := in.gs.zp[0] == 0 || in.gs.zp[1] == 0 || in.gs.zp[2] == 0
+	original := func(z *zone) []point {
+		if twilight {
+			return z.original
+		}
+		return z.unscaled
+	}
+	base := original(z0)[rp1]
+	originalRange := in.gs.dualProjection.dot(original(z1)[rp2].X-base.X, original(z1)[rp2].Y-base.Y)
+	currentRange := in.project(z1.current[rp2], z0.current[rp1])
+
+	in.loop(func(p int32) {
+		if !in.valid(z2, p) {
+			return
+		}
+		o := original(z2)[p]
+		d := in.gs.dualProjection.dot(o.X-base.X, o.Y-base.Y)
+		switch {
+		case d != 0 && originalRange != 0:
+			d = f26dot6(mulDiv(int64(d), int64(currentRange), int64(originalRange)))
+		case !twilight:
+			d = f26dot6(mulFix(int64(d), z2.scale))
+		}
+		in.move(z2, p, d-in.project(z2.current[p], z0.current[rp1]))
+	})
+}
+
+// alignrp moves points to rp0 along the projection vector (ALIGNRP).
+func (in *interpreter) alignrp() {
+	z0, z1 := in.zone(0), in.zone(1)
+	rp0 := in.gs.rp[0]
+	if !in.valid(z0, rp0) {
+		return
+	}
+	in.loop(func(p int32) {
+		if in.valid(z1, p) {
+			in.move(z1, p, -in.project(z1.current[p], z0.current[rp0]))
+		}
+	})
+}
+
+// alignpts moves two points to the middle of them (ALIGNPTS).
+func (in *interpreter) alignpts() {
+	p2, p1 := in.pop(), in.pop()
+	z0, z1 := in.zone(0), in.zone(1)
+	if in.err != nil || !in.valid(z1, p1) || !in.valid(z0, p2) {
+		return
+	}
+	d := in.project(z0.current[p2], z1.current[p1]) / 2
+	in.move(z1, p1, d)
+	in.move(z0, p2, -d)
+}
+
+// utp marks a point untouched along the freedom vector (UTP).
+func (in *interpreter) utp() {
+	p := in.pop()
+	z := in.zone(0)
+	if in.err != nil || !in.valid(z, p) {
+		return
+	}
+	if in.gs.freedom.X != 0 {
+		z.touched[p] &^= touchedX
+	}
+	if in.gs.freedom.Y != 0 {
+		z.touched[p] &^= touchedY
+	}
+}
+
+// gc pushes the projection of a point, in the original outline if the low
+// bit of op is set (GC).
+func (in *interpreter) gc(op Opcode) {
+	p := in.pop()
+	z := in.zone(2)
+	if in.err != nil || !in.valid(z, p) {
+		return
+	}
+	if op&1 == 0 {
+		in.push(in.gs.projection.dot(z.current[p].X, z.current[p].Y))
+	} else {
+		in.push(in.gs.dualProjection.dot(z.original[p].X, z.original[p].Y))
+	}
+}
+
+// scfs moves a point to a position along the projection vector (SCFS).
+func (in *interpreter) scfs() {
+	k, p := in.pop(), in.pop()
+	z := in.zone(2)
+	if in.err != nil || !in.valid(z, p) {
+		return
+	}
+	in.move(z, p, k-in.gs.projection.dot(z.current[p].X, z.current[p].Y))
+	if in.gs.zp[2] == 0 {
+		z.original[p] = z.current[p]
+	}
+}
+
+// md pushes the distance between two points, in the current outline if
+// the low bit of op is set, as in other rasterizers (MD).
+func (in *interpreter) md(op Opcode) {
+	p1, p2 := in.pop(), in.pop()
+	z0, z1 := in.zone(0), in.zone(1)
+	if in.err != nil || !in.valid(z1, p1) || !in.valid(z0, p2) {
+		return
+	}
+	if op&1 != 0 {
+		in.push(in.project(z0.current[p2], z1.current[p1]))
+	} else {
+		in.push(in.originalDistance(z0, p2, z1, p1, in.gs.zp[0] == 0 || in.gs.zp[1] == 0))
+	}
+}
+
+// flippt flips points between on and off the curve (FLIPPT).
+func (in *interpreter) flippt() {
+	z := in.zone(0)
+	in.loop(func(p int32) {
+		if in.valid(z, p) {
+			z.onCurve[p] = !z.onCurve[p]
+		}
+	})
+}
+
+// fliprg sets a range of points on or off the curve (FLIPRGON, FLIPRGOFF).
+func (in *interpreter) fliprg(on bool) {
+	hi, lo := in.pop(), in.pop()
+	z := in.zone(0)
+	if in.err != nil || !in.valid(z, lo) || !in.valid(z, hi) {
+		return
+	}
+	for p := lo; p <= hi; p++ {
+		z.onCurve[p] = on
+	}
+}
+
+var errTwilightIUP = errors.New("IUP in the twilight zone")
+
+// iup interpolates the points of the glyph that no instruction has moved
+// along an axis, between the points that have been moved (IUP).
+func (in *interpreter) iup(op Opcode) {
+	z := in.zone(2)
+	if in.gs.zp[2] == 0 {
+		in.fail(errTwilightIUP)
+		return
+	}
+	flag := uint8(touchedY)
+	coord := func(p *point) *f26dot6 { return &p.Y }
+	if op&1 != 0 {
+		flag = touchedX
+		coord = func(p *point) *f26dot6 { return &p.X }
+	}
+
+	start := 0
+	for _, end := range z.endPoints {
+		// Find the touched points of the contour.
+		first := -1
+		for p := start; p <= end; p++ {
+			if z.touched[p]&flag != 0 {
+				first = p
+				break
+			}
+		}
+		if first < 0 {
+			start = end + 1
+			continue
+		}
+
+		for t1 := first; ; {
+			t2 := t1
+			for {
+				if t2++; t2 > end {
+					t2 = start
+				}
+				if z.touched[t2]&flag != 0 {
+					break
+				}
+			}
+			interpolate(z, coord, t1, t2, start, end)
+			if t1 = t2; t1 == first {
+				break
+			}
+		}
+		start = end + 1
+	}
+}
+
+// interpolate moves the points after t1 and before t2 in a contour from
+// start to end, keeping their position relative to t1 and t2. If t1 and t2
+// are the same point, the other points move with it.
+func interpolate(z *zone, coord func(*point) *f26dot6, t1, t2, start, end int) {
+	r1, r2 := t1, t2
+	if *coord(&z.unscaled[r1]) > *coord(&z.unscaled[r2]) {
+		r1, r2 = r2, r1
+	}
+	u1, u2 := *coord(&z.unscaled[r1]), *coord(&z.unscaled[r2])
+	o1, o2 := *coord(&z.original[r1]), *coord(&z.original[r2])
+	c1, c2 := *coord(&z.current[r1]), *coord(&z.current[r2])
+
+	// The scale from font units to the current outline, as a 16.16 fixed-point number.
+	var scale int64
+	if c1 != c2 && u1 != u2 {
+		scale = divFix(int64(c2-c1), int64(u2-u1))
+	}
+
+	for p := t1; ; {
+		if p++; p > end {
+			p = start
+		}
+		if p == t2 {
+			break
+		}
+		o := *coord(&z.original[p])
+		v := coord(&z.current[p])
+		switch {
+		case o <= o1:
+			*v = o + c1 - o1
+		case o >= o2:
+			*v = o + c2 - o2
+		case scale == 0:
+			*v = c1
+		default:
+			*v = c1 + f26dot6(mulFix(int64(*coord(&z.unscaled[p])-u1), scale))
+		}
+	}
+}
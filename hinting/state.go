@@ -0,0 +1,196 @@
+package hinting
+
+import "math"
+
+// f26dot6 is a signed fixed-point number with 6 fractional bits, the unit
+// of distances in the interpreter, so 64 is one pixel.
+type f26dot6 = int32
+
+// vector is a unit vector with components in 2.14 fixed-point, so 0x4000 is 1.
+type vector struct {
+	X, Y int32
+}
+
+var (
+	xAxis = vector{0x4000, 0}
+	yAxis = vector{0, 0x4000}
+)
+
+// normalize returns the unit vector in the direction of (x, y), or the x
+// axis if both are zero.
+func normalize(x, y float64) vector {
+	length := math.Hypot(x, y)
+	if length == 0 {
+		return xAxis
+	}
+	return vector{int32(math.Round(x * 0x4000 / length)), int32(math.Round(y * 0x4000 / length))}
+}
+
+// dot returns the dot product of a point and a vector, which is the
+// projection of the point onto the vector.
+func (v vector) dot(x, y f26dot6) f26dot6 {
+	return f26dot6(roundShift(int64(x)*int64(v.X)+int64(y)*int64(v.Y), 14))
+}
+
+// scale returns the vector multiplied by d.
+func (v vector) scale(d f26dot6) (x, y f26dot6) {
+	return f26dot6(roundShift(int64(d)*int64(v.X), 14)), f26dot6(roundShift(int64(d)*int64(v.Y), 14))
+}
+
+// roundShift divides x by 2^shift, rounding to the nearest integer.
+func roundShift(x int64, shift uint) int64 {
+	half := int64(1) << (shift - 1)
+	if x < 0 {
+		return -((-x + half) >> shift)
+	}
+	return (x + half) >> shift
+}
+
+// mulDiv returns a*b/c rounded to the nearest integer.
+func mulDiv(a, b, c int64) int64 {
+	if c == 0 {
+		return 0
+	}
+	n := a * b
+	if (n < 0) != (c < 0) {
+		return -((abs64(n) + abs64(c)/2) / abs64(c))
+	}
+	return (abs64(n) + abs64(c)/2) / abs64(c)
+}
+
+// mulFix returns a*b, where b is a 16.16 fixed-point number, rounded to the
+// nearest integer.
+func mulFix(a, b int64) int64 {
+	return mulDiv(a, b, 0x10000)
+}
+
+// divFix returns a/b as a 16.16 fixed-point number.
+func divFix(a, b int64) int64 {
+	return mulDiv(a, 0x10000, b)
+}
+
+func abs64(x int64) int64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// graphicsState is the state that instructions read and set, such as the
+// vectors along which points are measured and moved.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/tt_graphics_state
+type graphicsState struct {
+	// projection is the vector along which distances are measured, and
+	// dualProjection the one used to measure the original outline, which
+	// differs when it was set from a line between points.
+	projection, dualProjection vector
+	// freedom is the vector along which points are moved.
+	freedom vector
+
+	rp   [3]int32 // rp contains the reference points.
+	zp   [3]int32 // zp contains the zone of each zone pointer, 0 for the twilight zone and 1 for the glyph.
+	loop int32    // loop is the number of times the next looping instruction is repeated.
+
+	controlValueCutIn f26dot6
+	singleWidthCutIn  f26dot6
+	singleWidth       f26dot6
+	minDistance       f26dot6
+	deltaBase         int32
+	deltaShift        int32
+	autoFlip          bool
+
+	// The rounding state, set by instructions such as RTG and SROUND, rounds
+	// distances to a multiple of roundPeriod, offset by roundPhase, rounding
+	// up from roundThreshold. A roundPeriod of 0 turns rounding off.
+	roundPeriod, roundPhase, roundThreshold f26dot6
+	roundSuper45                            bool
+
+	instructControl int32
+	scanControl     int32
+	scanType        int32
+}
+
+// defaultGraphicsState is the graphics state at the start of the font
+// program, and of the control value program.
+var defaultGraphicsState = graphicsState{
+	projection:        xAxis,
+	dualProjection:    xAxis,
+	freedom:           xAxis,
+	zp:                [3]int32{1, 1, 1},
+	loop:              1,
+	controlValueCutIn: 68, // 17/16 pixels
+	minDistance:       64,
+	deltaBase:         9,
+	deltaShift:        3,
+	autoFlip:          true,
+	roundPeriod:       64,
+	roundThreshold:    32,
+}
+
+// reset sets the parts of the state that are reset at the start of each
+// program, leaving those that the control value program sets for glyphs.
+func (gs *graphicsState) reset() {
+	gs.projection, gs.dualProjection, gs.freedom = xAxis, xAxis, xAxis
+	gs.rp = [3]int32{}
+	gs.zp = [3]int32{1, 1, 1}
+	gs.loop = 1
+}
+
+// setRound sets the rounding state used by instructions such as RTG.
+func (gs *graphicsState) setRound(period, phase, threshold f26dot6) {
+	gs.roundPeriod, gs.roundPhase, gs.roundThreshold = period, phase, threshold
+	gs.roundSuper45 = false
+}
+
+// setSuperRound sets the rounding state from the argument of SROUND or
+// S45ROUND, using gridPeriod, in 2.14 fixed-point pixels, as the unit of
+// the period.
+func (gs *graphicsState) setSuperRound(n int32, gridPeriod int32, super45 bool) {
+	var period, phase, threshold int32
+	switch n & 0xC0 {
+	case 0x00:
+		period = gridPeriod / 2
+	case 0x40, 0xC0:
+		period = gridPeriod
+	case 0x80:
+		period = gridPeriod * 2
+	}
+	phase = period * (n >> 4 & 3) / 4
+	if t := n & 15; t == 0 {
+		threshold = period - 1
+	} else {
+		threshold = (t - 4) * period / 8
+	}
+	// Convert from 2.14 to 26.6.
+	gs.roundPeriod, gs.roundPhase, gs.roundThreshold = period>>8, phase>>8, threshold>>8
+	gs.roundSuper45 = super45
+}
+
+// round rounds a distance using the rounding state. Rounding never changes
+// the sign of a distance.
+func (gs *graphicsState) round(x f26dot6) f26dot6 {
+	if gs.roundPeriod == 0 {
+		return x
+	}
+	if x >= 0 {
+		r := gs.roundDown(x - gs.roundPhase + gs.roundThreshold)
+		if r < 0 {
+			r = 0
+		}
+		return r + gs.roundPhase
+	}
+	r := gs.roundDown(-x - gs.roundPhase + gs.roundThreshold)
+	if r < 0 {
+		r = 0
+	}
+	return -r - gs.roundPhase
+}
+
+// roundDown rounds x down to a multiple of the round period, or towards
+// zero if the period isn't a power of two.
+func (gs *graphicsState) roundDown(x f26dot6) f26dot6 {
+	if gs.roundSuper45 {
+		return x / gs.roundPeriod * gs.roundPeriod
+	}
+	return x &^ (gs.roundPeriod - 1)
+}
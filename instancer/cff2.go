@@ -0,0 +1,71 @@
+package instancer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// instanceCFF2 applies the blends of the 'CFF2' charstrings and Private
+// DICTs at the pinned location, and updates 'hmtx' and the bounds in 'head'
+// and 'hhea' to match. Unlike TrueType outlines, the advances vary with
+// 'HVAR' rather than with phantom points.
+func (inst *instancer) instanceCFF2() error {
+	if !inst.font.HasTable(sfnt.TagCFF2) {
+		return nil
+	}
+
+	cff2, err := inst.font.CFF2Table()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagCFF2, err)
+	}
+	if cff2.VarStore != nil {
+		pinned := inst.pinStore(cff2.VarStore)
+		err := cff2.Pin(pinned.store, func(vsindex int, value float64, deltas []float64) (float64, []float64) {
+			delta, rest := pinned.pinDeltas(vsindex, deltas)
+			for i, d := range rest {
+				rest[i] = math.Round(d)
+			}
+			if delta != 0 {
+				value = math.Round(value + delta)
+			}
+			return value, rest
+		})
+		if err != nil {
+			return fmt.Errorf("instancing %q: %w", sfnt.TagCFF2, err)
+		}
+	}
+	inst.out.AddTable(sfnt.TagCFF2, cff2)
+
+	hmtx, err := inst.font.HmtxTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHmtx, err)
+	}
+	var hvar *sfnt.TableHvar
+	var advances *pinnedStore
+	if inst.font.HasTable(sfnt.TagHvar) {
+		if hvar, err = inst.font.HvarTable(); err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagHvar, err)
+		}
+		advances = inst.pinStore(hvar.VarStore)
+	}
+
+	glyphs := make([]glyphMetrics, len(cff2.CharStrings))
+	for id := range glyphs {
+		g := &glyphs[id]
+		g.right = float64(hmtx.Metric(sfnt.GlyphID(id)).AdvanceWidth)
+		if hvar != nil {
+			index := sfnt.VariationIndex{Inner: uint16(id)}
+			if hvar.AdvanceMap != nil {
+				index = hvar.AdvanceMap.Index(id)
+			}
+			g.right += float64(advances.roundedDelta(index))
+		}
+		if g.xMin, g.yMin, g.xMax, g.yMax, err = cff2.Bounds(sfnt.GlyphID(id)); err != nil {
+			return fmt.Errorf("glyph %d: %w", id, err)
+		}
+		g.empty = g.xMin == 0 && g.yMin == 0 && g.xMax == 0 && g.yMax == 0
+	}
+	return inst.updateMetrics(glyphs, nil)
+}
@@ -0,0 +1,324 @@
+package instancer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// featureCondition is a condition of a FeatureVariation record, which is met
+// when the normalized coordinate of an axis is between min and max. Conditions
+// in unknown formats have an axis of -1, and are never met.
+type featureCondition struct {
+	axis     int
+	min, max sfnt.F2Dot14
+}
+
+// featureSubstitution replaces the feature table at index in the FeatureList
+// with the feature table at offset in the original table.
+type featureSubstitution struct {
+	index  int
+	offset int
+}
+
+// featureVariation is a FeatureVariation record, whose substitutions are
+// applied if all of its conditions are met.
+type featureVariation struct {
+	conditions    []featureCondition
+	substitutions []featureSubstitution
+}
+
+// instanceFeatureVariations resolves the feature variations of 'GSUB' and
+// 'GPOS' at the pinned location. The feature tables of the first record
+// whose conditions are met on the pinned axes replace the default features,
+// and the records that depend on the remaining axes are kept.
+func (inst *instancer) instanceFeatureVariations() error {
+	for _, tag := range []sfnt.Tag{sfnt.TagGsub, sfnt.TagGpos} {
+		if !inst.out.HasTable(tag) {
+			continue
+		}
+		buf, err := inst.rawTable(tag)
+		if err != nil {
+			return err
+		}
+		buf, err = inst.pinFeatureVariations(buf)
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", tag, err)
+		}
+		if buf == nil {
+			continue
+		}
+		if err := inst.addRawTable(tag, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pinConditions returns the conditions that remain once the pinned axes are
+// removed, with their axes renumbered, or false if a condition can no longer
+// be met.
+func (inst *instancer) pinConditions(conditions []featureCondition) ([]featureCondition, bool) {
+	var rest []featureCondition
+	for _, c := range conditions {
+		if c.axis < 0 || c.axis >= len(inst.pinned) {
+			return nil, false
+		}
+		if inst.pinned[c.axis] {
+			if v := inst.coords[c.axis]; v < c.min || v > c.max {
+				return nil, false
+			}
+			continue
+		}
+		axis := 0
+		for _, pinned := range inst.pinned[:c.axis] {
+			if !pinned {
+				axis++
+			}
+		}
+		c.axis = axis
+		rest = append(rest, c)
+	}
+	return rest, true
+}
+
+// pinFeatureVariations returns a copy of a 'GSUB' or 'GPOS' table with its
+// FeatureVariations resolved at the pinned location, or nil if it has none.
+//
+// The new FeatureList and FeatureVariations are written before the original
+// table, which is otherwise unchanged, so that the feature tables that are no
+// longer used need not be removed.
+func (inst *instancer) pinFeatureVariations(buf []byte) ([]byte, error) {
+	r := &layoutReader{buf: buf}
+	if len(buf) < 14 || r.u16(0) != 1 || r.u16(2) != 1 {
+		return nil, nil
+	}
+	scriptList, featureList, lookupList := r.u16(4), r.u16(6), r.u16(8)
+	variationsOffset := r.u32(10)
+	if variationsOffset == 0 {
+		return nil, nil
+	}
+
+	numFeatures := r.u16(featureList)
+	tags := make([]int, numFeatures)
+	features := make([]int, numFeatures)
+	for i := range features {
+		tags[i] = r.u32(featureList + 2 + 6*i)
+		features[i] = featureList + r.u16(featureList+6+6*i)
+	}
+	variations := r.featureVariations(variationsOffset, numFeatures)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// Records after the first one that is always met are never used, and
+	// the ones before it fall back to its features rather than the defaults,
+	// unless they substitute them.
+	defaults := append([]int(nil), features...)
+	var kept []featureVariation
+	for _, v := range variations {
+		conditions, ok := inst.pinConditions(v.conditions)
+		if !ok {
+			continue
+		}
+		if len(conditions) > 0 {
+			v.conditions = conditions
+			kept = append(kept, v)
+			continue
+		}
+		for _, s := range v.substitutions {
+			defaults[s.index] = s.offset
+		}
+		for i := range kept {
+			substituted := make(map[int]bool)
+			for _, s := range kept[i].substitutions {
+				substituted[s.index] = true
+			}
+			for _, s := range v.substitutions {
+				if !substituted[s.index] {
+					kept[i].substitutions = append(kept[i].substitutions, featureSubstitution{s.index, features[s.index]})
+				}
+			}
+			subs := kept[i].substitutions
+			sort.Slice(subs, func(a, b int) bool { return subs[a].index < subs[b].index })
+		}
+		break
+	}
+
+	headerSize := 10
+	if len(kept) > 0 {
+		headerSize = 14
+	}
+	size := headerSize + 2 + 6*len(features)
+	for _, f := range defaults {
+		size += 4 + 2*r.u16(f+2)
+	}
+	variationsStart := size
+	if len(kept) > 0 {
+		size += 8
+		for _, v := range kept {
+			size += 8 + 2 + 12*len(v.conditions) + 6 + 6*len(v.substitutions)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	// base is the position of the original table in the new one.
+	base := size
+
+	w := &layoutWriter{buf: make([]byte, headerSize, base+len(buf))}
+	w.put16(0, 1)
+	if scriptList != 0 {
+		w.put16(4, base+scriptList)
+	}
+	w.put16(6, headerSize)
+	if lookupList != 0 {
+		w.put16(8, base+lookupList)
+	}
+
+	w.u16(len(features))
+	records := len(w.buf)
+	w.buf = append(w.buf, make([]byte, 6*len(features))...)
+	for i, f := range defaults {
+		binary.BigEndian.PutUint32(w.buf[records+6*i:], uint32(tags[i]))
+		w.put16(records+6*i+4, len(w.buf)-headerSize)
+		params, count := r.u16(f), r.u16(f+2)
+		if params != 0 {
+			params += base + f - len(w.buf)
+		}
+		w.u16(params)
+		w.u16(count)
+		for j := 0; j < count; j++ {
+			w.u16(r.u16(f + 4 + 2*j))
+		}
+	}
+
+	if len(kept) > 0 {
+		w.put16(2, 1)
+		binary.BigEndian.PutUint32(w.buf[10:], uint32(variationsStart))
+		w.u32(0x00010000)
+		w.u32(len(kept))
+		records := len(w.buf)
+		w.buf = append(w.buf, make([]byte, 8*len(kept))...)
+		for i, v := range kept {
+			set := len(w.buf)
+			binary.BigEndian.PutUint32(w.buf[records+8*i:], uint32(set-variationsStart))
+			w.u16(len(v.conditions))
+			for j := range v.conditions {
+				w.u32(2 + 4*len(v.conditions) + 8*j)
+			}
+			for _, c := range v.conditions {
+				w.u16(1)
+				w.u16(c.axis)
+				w.u16(int(uint16(c.min)))
+				w.u16(int(uint16(c.max)))
+			}
+
+			subst := len(w.buf)
+			binary.BigEndian.PutUint32(w.buf[records+8*i+4:], uint32(subst-variationsStart))
+			w.u32(0x00010000)
+			w.u16(len(v.substitutions))
+			for _, s := range v.substitutions {
+				w.u16(s.index)
+				w.u32(base + s.offset - subst)
+			}
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if w.err != nil {
+		return nil, w.err
+	}
+	return append(w.buf, buf...), nil
+}
+
+// layoutReader reads the fields of a 'GSUB' or 'GPOS' table, and records the
+// first field that is out of range in err.
+type layoutReader struct {
+	buf []byte
+	err error
+}
+
+func (r *layoutReader) u16(offset int) int {
+	if offset < 0 || offset+2 > len(r.buf) {
+		r.err = errInvalidOffset
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(r.buf[offset:]))
+}
+
+func (r *layoutReader) u32(offset int) int {
+	if offset < 0 || offset+4 > len(r.buf) {
+		r.err = errInvalidOffset
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(r.buf[offset:]))
+}
+
+// featureVariations reads the records of the FeatureVariations table at offset.
+func (r *layoutReader) featureVariations(offset, numFeatures int) []featureVariation {
+	var variations []featureVariation
+	count := r.u32(offset + 4)
+	for i := 0; i < count && r.err == nil; i++ {
+		record := offset + 8 + 8*i
+		var v featureVariation
+		if set := r.u32(record); set != 0 {
+			set += offset
+			n := r.u16(set)
+			for j := 0; j < n && r.err == nil; j++ {
+				c := set + r.u32(set+2+4*j)
+				condition := featureCondition{axis: -1}
+				if r.u16(c) == 1 {
+					condition.axis = r.u16(c + 2)
+					condition.min = sfnt.F2Dot14(r.u16(c + 4))
+					condition.max = sfnt.F2Dot14(r.u16(c + 6))
+				}
+				v.conditions = append(v.conditions, condition)
+			}
+		}
+		if subst := r.u32(record + 4); subst != 0 {
+			subst += offset
+			n := r.u16(subst + 4)
+			for j := 0; j < n && r.err == nil; j++ {
+				s := featureSubstitution{index: r.u16(subst + 6 + 6*j), offset: subst + r.u32(subst+8+6*j)}
+				if s.index >= numFeatures {
+					r.err = errInvalidOffset
+				}
+				r.u16(s.offset + 2)
+				v.substitutions = append(v.substitutions, s)
+			}
+		}
+		variations = append(variations, v)
+	}
+	return variations
+}
+
+// layoutWriter writes the fields of a 'GSUB' or 'GPOS' table, and records an
+// error in err if a value does not fit in its field.
+type layoutWriter struct {
+	buf []byte
+	err error
+}
+
+func (w *layoutWriter) u16(v int) {
+	w.buf = append(w.buf, 0, 0)
+	w.put16(len(w.buf)-2, v)
+}
+
+func (w *layoutWriter) u32(v int) {
+	if v < 0 || int64(v) > 0xFFFFFFFF {
+		w.err = fmt.Errorf("%w: offset overflow", ErrUnsupported)
+	}
+	w.buf = append(w.buf, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(w.buf[len(w.buf)-4:], uint32(v))
+}
+
+func (w *layoutWriter) put16(offset, v int) {
+	if v < 0 || v > 0xFFFF {
+		w.err = fmt.Errorf("%w: offset overflow", ErrUnsupported)
+	}
+	binary.BigEndian.PutUint16(w.buf[offset:], uint16(v))
+}
@@ -0,0 +1,375 @@
+package instancer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// numPhantomPoints is the number of points appended to each glyph by 'gvar'
+// to vary its metrics: the left and right side, and the top and bottom.
+const numPhantomPoints = 4
+
+// instancedGlyph is a glyph moved to the pinned location.
+type instancedGlyph struct {
+	glyph *sfnt.Glyph
+	// left and right are the positions of the horizontal phantom points.
+	left, right float64
+	// variations contains the variations along the remaining axes.
+	variations []sfnt.TupleVariation
+}
+
+// instanceGlyf moves the glyphs to the pinned location, and updates 'hmtx'
+// and the bounds in 'head' and 'hhea' to match.
+func (inst *instancer) instanceGlyf() error {
+	if !inst.font.HasTable(sfnt.TagGlyf) {
+		return nil
+	}
+
+	glyf, err := inst.font.GlyfTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagGlyf, err)
+	}
+	hmtx, err := inst.font.HmtxTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHmtx, err)
+	}
+	hhea, err := inst.font.HheaTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHhea, err)
+	}
+	var gvar *sfnt.TableGvar
+	if inst.font.HasTable(sfnt.TagGvar) {
+		if gvar, err = inst.font.GvarTable(); err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagGvar, err)
+		}
+	}
+
+	glyphs := make([]*instancedGlyph, glyf.NumGlyphs())
+	for id := range glyphs {
+		g, err := inst.instanceGlyph(sfnt.GlyphID(id), glyf, hmtx, hhea, gvar)
+		if err != nil {
+			return fmt.Errorf("glyph %d: %w", id, err)
+		}
+		glyphs[id] = g
+	}
+
+	// The bounds of composite glyphs depend on their instanced components.
+	encoded := make([][]byte, len(glyphs))
+	for id, g := range glyphs {
+		encoded[id] = g.glyph.Bytes()
+	}
+	newGlyf, _ := sfnt.NewTableGlyf(encoded)
+	for id, g := range glyphs {
+		if !g.glyph.IsComposite() {
+			continue
+		}
+		g.glyph.XMin, g.glyph.YMin, g.glyph.XMax, g.glyph.YMax, err = newGlyf.Bounds(sfnt.GlyphID(id))
+		if err != nil {
+			return fmt.Errorf("glyph %d: %w", id, err)
+		}
+		encoded[id] = g.glyph.Bytes()
+	}
+	newGlyf, newLoca := sfnt.NewTableGlyf(encoded)
+	inst.out.AddTable(sfnt.TagGlyf, newGlyf)
+	inst.out.AddTable(sfnt.TagLoca, newLoca)
+
+	metrics := make([]glyphMetrics, len(glyphs))
+	for id, g := range glyphs {
+		metrics[id] = glyphMetrics{
+			left: g.left, right: g.right,
+			xMin: g.glyph.XMin, yMin: g.glyph.YMin, xMax: g.glyph.XMax, yMax: g.glyph.YMax,
+			empty: g.glyph.IsEmpty(),
+		}
+	}
+	if err := inst.updateMetrics(metrics, newLoca); err != nil {
+		return err
+	}
+
+	if gvar == nil {
+		return nil
+	}
+	if inst.full() {
+		inst.out.RemoveTable(sfnt.TagGvar)
+		return nil
+	}
+	variations := make([][]sfnt.TupleVariation, len(glyphs))
+	for id, g := range glyphs {
+		variations[id] = g.variations
+	}
+	inst.out.AddTable(sfnt.TagGvar, sfnt.NewTableGvar(inst.numAxes(), variations))
+	return nil
+}
+
+// instanceCvar adds the deltas of the control values at the pinned location
+// to 'cvt ', and reduces 'cvar' to the remaining axes.
+func (inst *instancer) instanceCvar() error {
+	if !inst.font.HasTable(sfnt.TagCvar) {
+		return nil
+	}
+	if !inst.font.HasTable(sfnt.TagCvt) {
+		inst.out.RemoveTable(sfnt.TagCvar)
+		return nil
+	}
+
+	cvar, err := inst.font.CvarTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagCvar, err)
+	}
+	cvt, err := inst.font.CvtTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagCvt, err)
+	}
+	variations, err := cvar.Variations(len(inst.fvar.Axes), len(cvt.Values))
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagCvar, err)
+	}
+
+	values := make([]sfnt.OutlinePoint, len(cvt.Values))
+	for i, v := range cvt.Values {
+		values[i].X = float64(v)
+	}
+	values, variations = inst.pinTuples(variations, values, nil)
+	newCvt := &sfnt.TableCvt{Values: make([]int16, len(values))}
+	for i, v := range values {
+		newCvt.Values[i] = int16(v.X)
+	}
+	inst.out.AddTable(sfnt.TagCvt, newCvt)
+
+	if inst.full() || len(variations) == 0 {
+		inst.out.RemoveTable(sfnt.TagCvar)
+		return nil
+	}
+	for i := range variations {
+		variations[i].DeltasY = nil
+	}
+	inst.out.AddTable(sfnt.TagCvar, sfnt.NewTableCvar(inst.numAxes(), variations))
+	return nil
+}
+
+// instanceGlyph applies the variations of a glyph at the pinned location.
+func (inst *instancer) instanceGlyph(id sfnt.GlyphID, glyf *sfnt.TableGlyf, hmtx *sfnt.TableHmtx, hhea *sfnt.TableHhea, gvar *sfnt.TableGvar) (*instancedGlyph, error) {
+	glyph, err := glyf.Glyph(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// The points varied by 'gvar' are the offsets of the components of
+	// composite glyphs, and the points of simple glyphs.
+	var points []sfnt.OutlinePoint
+	var endPoints []int
+	if glyph.IsComposite() {
+		for _, c := range glyph.Components {
+			points = append(points, sfnt.OutlinePoint{X: float64(c.Arg1), Y: float64(c.Arg2)})
+		}
+	} else {
+		for _, p := range glyph.Points {
+			points = append(points, sfnt.OutlinePoint{X: float64(p.X), Y: float64(p.Y), OnCurve: p.OnCurve})
+		}
+		for _, end := range glyph.EndPoints {
+			endPoints = append(endPoints, int(end))
+		}
+	}
+	metric := hmtx.Metric(id)
+	left := float64(glyph.XMin) - float64(metric.LeftSideBearing)
+	points = append(points,
+		sfnt.OutlinePoint{X: left},
+		sfnt.OutlinePoint{X: left + float64(metric.AdvanceWidth)},
+		sfnt.OutlinePoint{Y: float64(hhea.Ascent)},
+		sfnt.OutlinePoint{Y: float64(hhea.Descent)},
+	)
+
+	g := &instancedGlyph{glyph: glyph}
+	if gvar != nil {
+		variations, err := gvar.GlyphVariations(id, len(points))
+		if err != nil {
+			return nil, err
+		}
+		points, g.variations = inst.pinTuples(variations, points, endPoints)
+	}
+
+	phantom := points[len(points)-numPhantomPoints:]
+	g.left, g.right = phantom[0].X, phantom[1].X
+
+	if glyph.IsComposite() {
+		for i := range glyph.Components {
+			c := &glyph.Components[i]
+			if c.IsXYOffset() {
+				c.Arg1, c.Arg2 = int32(points[i].X), int32(points[i].Y)
+			}
+		}
+		if inst.full() && len(glyph.Components) > 0 {
+			glyph.Components[0].Flags |= sfnt.ComponentOverlapCompound
+		}
+		return g, nil
+	}
+
+	for i := range glyph.Points {
+		glyph.Points[i].X, glyph.Points[i].Y = int16(points[i].X), int16(points[i].Y)
+	}
+	if len(glyph.Points) > 0 {
+		glyph.XMin, glyph.YMin = glyph.Points[0].X, glyph.Points[0].Y
+		glyph.XMax, glyph.YMax = glyph.XMin, glyph.YMin
+		for _, p := range glyph.Points {
+			glyph.XMin, glyph.XMax = min16(glyph.XMin, p.X), max16(glyph.XMax, p.X)
+			glyph.YMin, glyph.YMax = min16(glyph.YMin, p.Y), max16(glyph.YMax, p.Y)
+		}
+		if inst.full() {
+			glyph.Overlap = true
+		}
+	}
+	return g, nil
+}
+
+// pinTuples applies the tuple variations of a glyph, or of the control
+// values, that apply at the pinned location to its points, which are
+// returned rounded along with the variations along the remaining axes.
+func (inst *instancer) pinTuples(variations []sfnt.TupleVariation, points []sfnt.OutlinePoint, endPoints []int) ([]sfnt.OutlinePoint, []sfnt.TupleVariation) {
+	base := make([]sfnt.OutlinePoint, len(points))
+	var regions []sfnt.VariationRegion
+	var remaining [][]sfnt.OutlinePoint
+	keys := make(map[string]int)
+
+	for _, v := range variations {
+		scalar, rest, collapsed := inst.pinRegion(v.Region)
+		if scalar == 0 {
+			continue
+		}
+
+		// Deltas are inferred from the original points, before they are moved.
+		deltas := v.Deltas(points, endPoints)
+		target := base
+		if !collapsed {
+			key := regionKey(rest)
+			i, ok := keys[key]
+			if !ok {
+				i = len(regions)
+				keys[key] = i
+				regions = append(regions, rest)
+				remaining = append(remaining, make([]sfnt.OutlinePoint, len(points)))
+			}
+			target = remaining[i]
+		}
+		for i, d := range deltas {
+			target[i].X += scalar * d.X
+			target[i].Y += scalar * d.Y
+		}
+	}
+
+	moved := make([]sfnt.OutlinePoint, len(points))
+	for i, p := range points {
+		moved[i] = sfnt.OutlinePoint{X: math.Round(p.X + base[i].X), Y: math.Round(p.Y + base[i].Y), OnCurve: p.OnCurve}
+	}
+
+	var tuples []sfnt.TupleVariation
+	for i, deltas := range remaining {
+		v := sfnt.TupleVariation{
+			Region:  regions[i],
+			DeltasX: make([]int32, len(deltas)),
+			DeltasY: make([]int32, len(deltas)),
+		}
+		zero := true
+		for j, d := range deltas {
+			v.DeltasX[j], v.DeltasY[j] = int32(math.Round(d.X)), int32(math.Round(d.Y))
+			zero = zero && v.DeltasX[j] == 0 && v.DeltasY[j] == 0
+		}
+		if !zero {
+			tuples = append(tuples, v)
+		}
+	}
+	return moved, tuples
+}
+
+// glyphMetrics contains the horizontal metrics and bounds of an instanced glyph.
+type glyphMetrics struct {
+	// left and right are the positions of the horizontal phantom points.
+	left, right            float64
+	xMin, yMin, xMax, yMax int16
+	empty                  bool
+}
+
+// updateMetrics replaces 'hmtx' with the metrics of the instanced glyphs, and
+// updates the summaries of the metrics in 'head' and 'hhea'. loca is the new
+// 'loca' table, or nil for fonts with CFF outlines.
+func (inst *instancer) updateMetrics(glyphs []glyphMetrics, loca *sfnt.TableLoca) error {
+	head, err := inst.font.HeadTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHead, err)
+	}
+	hhea, err := inst.font.HheaTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHhea, err)
+	}
+	newHead, newHhea := *head, *hhea
+
+	metrics := make([]sfnt.HMetric, len(glyphs))
+	first := true
+	for id, g := range glyphs {
+		metrics[id] = sfnt.HMetric{
+			AdvanceWidth:    uint16(math.Max(0, g.right-g.left)),
+			LeftSideBearing: int16(float64(g.xMin) - g.left),
+		}
+		if g.empty {
+			continue
+		}
+
+		advance := metrics[id].AdvanceWidth
+		rsb := int16(advance) - metrics[id].LeftSideBearing - (g.xMax - g.xMin)
+		extent := metrics[id].LeftSideBearing + (g.xMax - g.xMin)
+		if first {
+			newHead.XMin, newHead.YMin, newHead.XMax, newHead.YMax = g.xMin, g.yMin, g.xMax, g.yMax
+			newHhea.MinLeftSideBearing, newHhea.MinRightSideBearing, newHhea.XMaxExtent = metrics[id].LeftSideBearing, rsb, extent
+			first = false
+		}
+		newHead.XMin, newHead.XMax = min16(newHead.XMin, g.xMin), max16(newHead.XMax, g.xMax)
+		newHead.YMin, newHead.YMax = min16(newHead.YMin, g.yMin), max16(newHead.YMax, g.yMax)
+		newHhea.MinLeftSideBearing = min16(newHhea.MinLeftSideBearing, metrics[id].LeftSideBearing)
+		newHhea.MinRightSideBearing = min16(newHhea.MinRightSideBearing, rsb)
+		newHhea.XMaxExtent = max16(newHhea.XMaxExtent, extent)
+	}
+
+	// Glyphs at the end with the same advance only need a left side bearing.
+	numLong := len(metrics)
+	for numLong > 1 && metrics[numLong-1].AdvanceWidth == metrics[numLong-2].AdvanceWidth {
+		numLong--
+	}
+	hmtx := &sfnt.TableHmtx{Metrics: metrics[:numLong]}
+	for _, m := range metrics[numLong:] {
+		hmtx.LeftSideBearings = append(hmtx.LeftSideBearings, m.LeftSideBearing)
+	}
+
+	newHhea.AdvanceWidthMax = 0
+	for _, m := range metrics {
+		if m.AdvanceWidth > newHhea.AdvanceWidthMax {
+			newHhea.AdvanceWidthMax = m.AdvanceWidth
+		}
+	}
+	newHhea.NumOfLongHorMetrics = int16(numLong)
+
+	if loca != nil {
+		newHead.IndexToLocFormat = 1
+		if loca.Short {
+			newHead.IndexToLocFormat = 0
+		}
+	}
+
+	inst.out.AddTable(sfnt.TagHead, &newHead)
+	inst.out.AddTable(sfnt.TagHhea, &newHhea)
+	inst.out.AddTable(sfnt.TagHmtx, hmtx)
+	return nil
+}
+
+func min16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,441 @@
+package instancer
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+var errInvalidOffset = errors.New("offset out of range")
+
+// variationIndexFormat is the deltaFormat of device tables that refer to the
+// ItemVariationStore in 'GDEF', rather than containing hinting deltas.
+const variationIndexFormat = 0x8000
+
+// Lookup types in 'GPOS'.
+const (
+	gposSingle       = 1
+	gposPair         = 2
+	gposCursive      = 3
+	gposMarkToBase   = 4
+	gposMarkToLig    = 5
+	gposMarkToMark   = 6
+	gposExtension    = 9
+	valueDeviceShift = 4 // valueDeviceShift is the bit of the first device offset in a ValueFormat.
+)
+
+// instanceGpos adds the deltas at the pinned location to the values and
+// anchors in 'GPOS' and the ligature carets in 'GDEF', and reduces the
+// ItemVariationStore in 'GDEF' to the remaining axes.
+func (inst *instancer) instanceGpos() error {
+	if !inst.font.HasTable(tagGDEF) {
+		return nil
+	}
+	gdef, err := inst.rawTable(tagGDEF)
+	if err != nil {
+		return err
+	}
+	if len(gdef) < 18 || binary.BigEndian.Uint16(gdef[2:]) < 3 {
+		return nil
+	}
+	storeOffset := int(binary.BigEndian.Uint32(gdef[14:]))
+	if storeOffset == 0 {
+		return nil
+	}
+	if storeOffset >= len(gdef) {
+		return fmt.Errorf("parsing %q: %w", tagGDEF, errInvalidOffset)
+	}
+	store, err := sfnt.ParseItemVariationStore(gdef[storeOffset:])
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", tagGDEF, err)
+	}
+
+	p := &devicePatcher{pinned: inst.pinStore(store), clear: inst.full()}
+
+	if inst.font.HasTable(sfnt.TagGpos) {
+		gpos, err := inst.rawTable(sfnt.TagGpos)
+		if err != nil {
+			return err
+		}
+		p.buf, p.visited = gpos, make(map[int]bool)
+		if err := p.patchGpos(); err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagGpos, err)
+		}
+		if err := inst.addRawTable(sfnt.TagGpos, gpos); err != nil {
+			return err
+		}
+	}
+
+	p.buf, p.visited = gdef, make(map[int]bool)
+	if err := p.patchLigatureCarets(); err != nil {
+		return fmt.Errorf("parsing %q: %w", tagGDEF, err)
+	}
+	if p.pinned.store == nil {
+		binary.BigEndian.PutUint32(gdef[14:], 0)
+	} else {
+		binary.BigEndian.PutUint32(gdef[14:], uint32(len(gdef)))
+		gdef = append(gdef, p.pinned.store.Bytes()...)
+	}
+	return inst.addRawTable(tagGDEF, gdef)
+}
+
+// devicePatcher adds the deltas of VariationIndex device tables to the values
+// that refer to them, in the bytes of a table.
+type devicePatcher struct {
+	buf    []byte
+	pinned *pinnedStore
+	// clear is true if the offsets to the device tables should be removed,
+	// because the font will no longer be variable.
+	clear bool
+	// visited contains the subtables that have been patched, as they may be
+	// shared and must only be patched once.
+	visited map[int]bool
+}
+
+func (p *devicePatcher) u16(offset int) (int, error) {
+	if offset < 0 || offset+2 > len(p.buf) {
+		return 0, errInvalidOffset
+	}
+	return int(binary.BigEndian.Uint16(p.buf[offset:])), nil
+}
+
+// offset returns the absolute position of the subtable at the 16-bit offset
+// stored at field, relative to base, or 0 if the offset is null.
+func (p *devicePatcher) offset(base, field int) (int, error) {
+	v, err := p.u16(field)
+	if err != nil || v == 0 {
+		return 0, err
+	}
+	if base+v >= len(p.buf) {
+		return 0, errInvalidOffset
+	}
+	return base + v, nil
+}
+
+// patchValue adds the delta of the device table at the offset stored at
+// deviceField, relative to base, to the 16-bit value at valueField.
+func (p *devicePatcher) patchValue(base, valueField, deviceField int) error {
+	device, err := p.offset(base, deviceField)
+	if err != nil || device == 0 {
+		return err
+	}
+	format, err := p.u16(device + 4)
+	if err != nil || format != variationIndexFormat {
+		return err
+	}
+	outer, _ := p.u16(device)
+	inner, _ := p.u16(device + 2)
+
+	if valueField >= 0 {
+		value, err := p.u16(valueField)
+		if err != nil {
+			return err
+		}
+		delta := p.pinned.roundedDelta(sfnt.VariationIndex{Outer: uint16(outer), Inner: uint16(inner)})
+		binary.BigEndian.PutUint16(p.buf[valueField:], uint16(int16(value)+int16(delta)))
+	}
+	if p.clear {
+		binary.BigEndian.PutUint16(p.buf[deviceField:], 0)
+	}
+	return nil
+}
+
+// patchValueRecord patches a ValueRecord at record, whose device offsets are
+// relative to base, and returns its size.
+func (p *devicePatcher) patchValueRecord(base, record int, format int) (int, error) {
+	var fields [8]int
+	n := 0
+	for bit := 0; bit < 8; bit++ {
+		fields[bit] = -1
+		if format&(1<<uint(bit)) != 0 {
+			fields[bit] = record + 2*n
+			n++
+		}
+	}
+	for bit := valueDeviceShift; bit < 8; bit++ {
+		if fields[bit] < 0 {
+			continue
+		}
+		if err := p.patchValue(base, fields[bit-valueDeviceShift], fields[bit]); err != nil {
+			return 0, err
+		}
+	}
+	return 2 * n, nil
+}
+
+// patchAnchor patches the anchor table at the offset stored at field, relative to base.
+func (p *devicePatcher) patchAnchor(base, field int) error {
+	anchor, err := p.offset(base, field)
+	if err != nil || anchor == 0 || p.visited[anchor] {
+		return err
+	}
+	p.visited[anchor] = true
+
+	if format, err := p.u16(anchor); err != nil || format != 3 {
+		return err
+	}
+	if err := p.patchValue(anchor, anchor+2, anchor+6); err != nil {
+		return err
+	}
+	return p.patchValue(anchor, anchor+4, anchor+8)
+}
+
+func (p *devicePatcher) patchGpos() error {
+	lookupList, err := p.offset(0, 8)
+	if err != nil || lookupList == 0 {
+		return err
+	}
+	lookupCount, err := p.u16(lookupList)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < lookupCount; i++ {
+		lookup, err := p.offset(lookupList, lookupList+2+2*i)
+		if err != nil {
+			return err
+		}
+		lookupType, err := p.u16(lookup)
+		if err != nil {
+			return err
+		}
+		subtableCount, err := p.u16(lookup + 4)
+		if err != nil {
+			return err
+		}
+		for j := 0; j < subtableCount; j++ {
+			subtable, err := p.offset(lookup, lookup+6+2*j)
+			if err != nil {
+				return err
+			}
+			if err := p.patchSubtable(lookupType, subtable); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *devicePatcher) patchSubtable(lookupType, subtable int) error {
+	if subtable == 0 || p.visited[subtable] {
+		return nil
+	}
+	p.visited[subtable] = true
+
+	format, err := p.u16(subtable)
+	if err != nil {
+		return err
+	}
+
+	switch lookupType {
+	case gposSingle:
+		valueFormat, err := p.u16(subtable + 4)
+		if err != nil {
+			return err
+		}
+		if format == 1 {
+			_, err = p.patchValueRecord(subtable, subtable+6, valueFormat)
+			return err
+		}
+		count, err := p.u16(subtable + 6)
+		if err != nil {
+			return err
+		}
+		record := subtable + 8
+		for i := 0; i < count; i++ {
+			size, err := p.patchValueRecord(subtable, record, valueFormat)
+			if err != nil {
+				return err
+			}
+			record += size
+		}
+
+	case gposPair:
+		return p.patchPair(subtable, format)
+
+	case gposCursive:
+		count, err := p.u16(subtable + 4)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < 2*count; i++ {
+			if err := p.patchAnchor(subtable, subtable+6+2*i); err != nil {
+				return err
+			}
+		}
+
+	case gposMarkToBase, gposMarkToLig, gposMarkToMark:
+		classCount, err := p.u16(subtable + 6)
+		if err != nil {
+			return err
+		}
+		markArray, err := p.offset(subtable, subtable+8)
+		if err != nil {
+			return err
+		}
+		if err := p.patchAnchorArray(markArray, 2, 4, 1); err != nil {
+			return err
+		}
+		array, err := p.offset(subtable, subtable+10)
+		if err != nil || array == 0 {
+			return err
+		}
+		if lookupType != gposMarkToLig {
+			return p.patchAnchorArray(array, 0, 2*classCount, classCount)
+		}
+		count, err := p.u16(array)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < count; i++ {
+			attach, err := p.offset(array, array+2+2*i)
+			if err != nil {
+				return err
+			}
+			if err := p.patchAnchorArray(attach, 0, 2*classCount, classCount); err != nil {
+				return err
+			}
+		}
+
+	case gposExtension:
+		extensionType, err := p.u16(subtable + 2)
+		if err != nil {
+			return err
+		}
+		if subtable+8 > len(p.buf) {
+			return errInvalidOffset
+		}
+		extension := subtable + int(binary.BigEndian.Uint32(p.buf[subtable+4:]))
+		if extension >= len(p.buf) {
+			return errInvalidOffset
+		}
+		return p.patchSubtable(extensionType, extension)
+	}
+	return nil
+}
+
+// patchAnchorArray patches the anchors of an array of records, each of the
+// given size and containing count anchor offsets from start, relative to array.
+func (p *devicePatcher) patchAnchorArray(array, start, size, count int) error {
+	if array == 0 {
+		return nil
+	}
+	n, err := p.u16(array)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		record := array + 2 + i*size
+		for j := 0; j < count; j++ {
+			if err := p.patchAnchor(array, record+start+2*j); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *devicePatcher) patchPair(subtable, format int) error {
+	valueFormat1, err := p.u16(subtable + 4)
+	if err != nil {
+		return err
+	}
+	valueFormat2, err := p.u16(subtable + 6)
+	if err != nil {
+		return err
+	}
+	size1, size2 := 2*bits.OnesCount16(uint16(valueFormat1)), 2*bits.OnesCount16(uint16(valueFormat2))
+
+	// patchPair patches the pair of ValueRecords at record.
+	patchPair := func(record int) error {
+		if _, err := p.patchValueRecord(subtable, record, valueFormat1); err != nil {
+			return err
+		}
+		_, err := p.patchValueRecord(subtable, record+size1, valueFormat2)
+		return err
+	}
+
+	if format == 1 {
+		count, err := p.u16(subtable + 8)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < count; i++ {
+			pairSet, err := p.offset(subtable, subtable+10+2*i)
+			if err != nil {
+				return err
+			}
+			if pairSet == 0 || p.visited[pairSet] {
+				continue
+			}
+			p.visited[pairSet] = true
+
+			pairs, err := p.u16(pairSet)
+			if err != nil {
+				return err
+			}
+			for j := 0; j < pairs; j++ {
+				if err := patchPair(pairSet + 2 + j*(2+size1+size2) + 2); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	class1Count, err := p.u16(subtable + 12)
+	if err != nil {
+		return err
+	}
+	class2Count, err := p.u16(subtable + 14)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < class1Count*class2Count; i++ {
+		if err := patchPair(subtable + 16 + i*(size1+size2)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchLigatureCarets patches the caret values with device tables in 'GDEF'.
+func (p *devicePatcher) patchLigatureCarets() error {
+	ligCaretList, err := p.offset(0, 8)
+	if err != nil || ligCaretList == 0 {
+		return err
+	}
+	count, err := p.u16(ligCaretList + 2)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		ligGlyph, err := p.offset(ligCaretList, ligCaretList+4+2*i)
+		if err != nil {
+			return err
+		}
+		carets, err := p.u16(ligGlyph)
+		if err != nil {
+			return err
+		}
+		for j := 0; j < carets; j++ {
+			caret, err := p.offset(ligGlyph, ligGlyph+2+2*j)
+			if err != nil {
+				return err
+			}
+			if caret == 0 || p.visited[caret] {
+				continue
+			}
+			p.visited[caret] = true
+			if format, err := p.u16(caret); err != nil || format != 3 {
+				return err
+			}
+			if err := p.patchValue(caret, caret+2, caret+4); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
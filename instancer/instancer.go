@@ -0,0 +1,223 @@
+// Package instancer creates instances of variable fonts, for use where
+// variable fonts are not supported.
+//
+// Instance pins some or all of the axes of a variable font to fixed values.
+// When every axis is pinned the result is a static font, with the outlines,
+// metrics and positioning of the instance, and without the variation tables.
+// Otherwise the variation tables are reduced to the remaining axes.
+//
+// Both TrueType outlines ('glyf' and 'gvar') and 'CFF2' outlines are
+// supported, though 'CFF2' subroutines are inlined. The variations of the
+// TrueType control values in 'cvar' are applied to 'cvt ', and the feature
+// variations in 'GSUB' and 'GPOS' are resolved at the pinned location.
+package instancer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+var (
+	tagGDEF = sfnt.MustNamedTag("GDEF")
+	tagWght = sfnt.MustNamedTag("wght")
+	tagWdth = sfnt.MustNamedTag("wdth")
+)
+
+// ErrUnsupported is wrapped by the error returned from Instance for fonts
+// that cannot be instanced by this package.
+var ErrUnsupported = errors.New("unsupported font")
+
+// instancer holds the state used while instancing a font.
+type instancer struct {
+	font *sfnt.Font // font is the variable font being instanced.
+	out  *sfnt.Font // out is the instance, which shares unmodified tables with font.
+	fvar *sfnt.TableFvar
+
+	// location contains the user coordinates of the pinned axes, clamped to their range.
+	location map[sfnt.Tag]float64
+	// pinned is true for each axis in fvar that is pinned.
+	pinned []bool
+	// coords contains the normalized coordinates of each axis, which are
+	// zero for axes that are not pinned.
+	coords []sfnt.F2Dot14
+}
+
+// Instance returns a new font with the axes in location pinned to the given
+// user coordinates, for example {'wght': 700}. Axes that are not in location
+// remain variable, so to create a static font every axis must be given.
+//
+// The original font is not modified, though unchanged tables are shared
+// between the two fonts.
+func Instance(font *sfnt.Font, location map[sfnt.Tag]float64) (*sfnt.Font, error) {
+	fvar, err := font.FvarTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", sfnt.TagFvar, err)
+	}
+
+	inst := &instancer{
+		font:     font,
+		out:      sfnt.New(font.Type()),
+		fvar:     fvar,
+		location: make(map[sfnt.Tag]float64),
+		pinned:   make([]bool, len(fvar.Axes)),
+	}
+
+	for tag, v := range location {
+		axis := fvar.Axis(tag)
+		if axis == nil {
+			return nil, fmt.Errorf("font has no %q axis", tag)
+		}
+		inst.location[tag] = math.Max(axis.Min, math.Min(axis.Max, v))
+	}
+	for i, axis := range fvar.Axes {
+		_, inst.pinned[i] = inst.location[axis.Tag]
+	}
+
+	if !inst.full() && font.HasTable(sfnt.TagAvar) {
+		avar, err := font.AvarTable()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", sfnt.TagAvar, err)
+		}
+		if avar.MajorVersion > 1 {
+			return nil, fmt.Errorf("%w: partially instancing %q version %d", ErrUnsupported, sfnt.TagAvar, avar.MajorVersion)
+		}
+	}
+
+	if inst.coords, err = font.NormalizeCoordinates(inst.location); err != nil {
+		return nil, err
+	}
+	for i := range inst.coords {
+		if !inst.pinned[i] {
+			inst.coords[i] = 0
+		}
+	}
+
+	for _, tag := range font.Tags() {
+		table, err := font.Table(tag)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", tag, err)
+		}
+		inst.out.AddTable(tag, table)
+	}
+
+	steps := []func() error{
+		inst.instanceGlyf,
+		inst.instanceCFF2,
+		inst.instanceCvar,
+		inst.instanceMetricVariations,
+		inst.instanceMvar,
+		inst.instanceGpos,
+		inst.instanceFeatureVariations,
+		inst.instanceAxes,
+		inst.instanceOS2,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	return inst.out, nil
+}
+
+// full returns true if every axis is pinned, so that the instance is a static font.
+func (inst *instancer) full() bool {
+	for _, pinned := range inst.pinned {
+		if !pinned {
+			return false
+		}
+	}
+	return true
+}
+
+// numAxes returns the number of axes that remain in the instance.
+func (inst *instancer) numAxes() int {
+	n := 0
+	for _, pinned := range inst.pinned {
+		if !pinned {
+			n++
+		}
+	}
+	return n
+}
+
+// instanceAxes updates 'fvar' and 'avar' to contain only the remaining axes,
+// or removes them along with the other tables of variable fonts if every
+// axis is pinned.
+func (inst *instancer) instanceAxes() error {
+	if inst.full() {
+		for _, tag := range []sfnt.Tag{sfnt.TagFvar, sfnt.TagAvar, sfnt.TagSTAT} {
+			inst.out.RemoveTable(tag)
+		}
+		return inst.instanceNames()
+	}
+
+	fvar := *inst.fvar
+	fvar.Axes = nil
+	for i, axis := range inst.fvar.Axes {
+		if !inst.pinned[i] {
+			fvar.Axes = append(fvar.Axes, axis)
+		}
+	}
+
+	// Keep the named instances that are at the pinned location.
+	fvar.Instances = nil
+	for _, instance := range inst.fvar.Instances {
+		keep := true
+		var coords []float64
+		for i, v := range instance.Coordinates {
+			if i >= len(inst.pinned) {
+				break
+			}
+			if !inst.pinned[i] {
+				coords = append(coords, v)
+			} else if v != inst.location[inst.fvar.Axes[i].Tag] {
+				keep = false
+			}
+		}
+		if keep {
+			instance.Coordinates = coords
+			fvar.Instances = append(fvar.Instances, instance)
+		}
+	}
+	inst.out.AddTable(sfnt.TagFvar, &fvar)
+
+	if !inst.font.HasTable(sfnt.TagAvar) {
+		return nil
+	}
+	original, err := inst.font.AvarTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagAvar, err)
+	}
+	avar := *original
+	avar.SegmentMaps = nil
+	for i, m := range original.SegmentMaps {
+		if i < len(inst.pinned) && !inst.pinned[i] {
+			avar.SegmentMaps = append(avar.SegmentMaps, m)
+		}
+	}
+	inst.out.AddTable(sfnt.TagAvar, &avar)
+	return nil
+}
+
+// rawTable returns a copy of the bytes of a table, so they can be modified.
+func (inst *instancer) rawTable(tag sfnt.Tag) ([]byte, error) {
+	table, err := inst.out.Table(tag)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", tag, err)
+	}
+	return append([]byte(nil), table.Bytes()...), nil
+}
+
+// addRawTable adds a table modified as bytes to the instance.
+func (inst *instancer) addRawTable(tag sfnt.Tag, buf []byte) error {
+	table, err := sfnt.ParseTable(tag, buf)
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", tag, err)
+	}
+	inst.out.AddTable(tag, table)
+	return nil
+}
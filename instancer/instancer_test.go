@@ -0,0 +1,600 @@
+package instancer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+func u16s(values ...int) []byte {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return b
+}
+
+var (
+	wghtMax = sfnt.RegionAxis{Peak: 1 << 14, End: 1 << 14}
+	wdthMin = sfnt.RegionAxis{Start: -1 << 14, Peak: -1 << 14}
+)
+
+// testFont returns a variable font with 'wght' (100 to 900) and 'wdth' (75
+// to 100) axes, and three glyphs: an empty glyph, a 100 unit square, and a
+// composite of two squares, 200 units apart. At the maximum weight the
+// squares are 50 units wider, and at the minimum width they are 20 units
+// narrower. The composite glyph also has a single pair positioning adjustment
+// which is 10 units at the maximum weight, and there are three control
+// values, the first 50 units larger at the maximum weight, and the last 20
+// units smaller at the minimum width.
+func testFont(t *testing.T) *sfnt.Font {
+	t.Helper()
+
+	square := &sfnt.Glyph{
+		NumberOfContours: 1,
+		XMax:             100,
+		YMax:             100,
+		EndPoints:        []uint16{3},
+		Points:           []sfnt.GlyphPoint{{X: 0, Y: 0, OnCurve: true}, {X: 0, Y: 100, OnCurve: true}, {X: 100, Y: 100, OnCurve: true}, {X: 100, Y: 0, OnCurve: true}},
+	}
+	composite := &sfnt.Glyph{
+		NumberOfContours: -1,
+		XMax:             300,
+		YMax:             100,
+		Components: []sfnt.GlyphComponent{
+			{Flags: sfnt.ComponentArgsAreXYValues, Glyph: 1, Transform: [4]float64{1, 0, 0, 1}},
+			{Flags: sfnt.ComponentArgsAreXYValues, Glyph: 1, Arg1: 200, Transform: [4]float64{1, 0, 0, 1}},
+		},
+	}
+	glyf, loca := sfnt.NewTableGlyf([][]byte{nil, square.Bytes(), composite.Bytes()})
+
+	gvar := sfnt.NewTableGvar(2, [][]sfnt.TupleVariation{
+		nil,
+		{
+			{Region: sfnt.VariationRegion{wghtMax, {}}, DeltasX: []int32{0, 0, 50, 50, 0, 50, 0, 0}, DeltasY: make([]int32, 8)},
+			{Region: sfnt.VariationRegion{{}, wdthMin}, DeltasX: []int32{0, 0, -20, -20, 0, -20, 0, 0}, DeltasY: make([]int32, 8)},
+		},
+		{
+			{Region: sfnt.VariationRegion{wghtMax, {}}, Points: []uint16{1, 3}, DeltasX: []int32{50, 100}, DeltasY: []int32{0, 0}},
+			{Region: sfnt.VariationRegion{{}, wdthMin}, Points: []uint16{1, 3}, DeltasX: []int32{-20, -40}, DeltasY: []int32{0, 0}},
+		},
+	})
+
+	cvar := sfnt.NewTableCvar(2, []sfnt.TupleVariation{
+		{Region: sfnt.VariationRegion{wghtMax, {}}, Points: []uint16{0}, DeltasX: []int32{50}},
+		{Region: sfnt.VariationRegion{{}, wdthMin}, Points: []uint16{2}, DeltasX: []int32{-20}},
+	})
+
+	// A store with a single item, varying by 50 units at the maximum weight,
+	// used by 'MVAR' for the x-height.
+	metrics := &sfnt.ItemVariationStore{
+		Regions: []sfnt.VariationRegion{{wghtMax, {}}},
+		Data:    []sfnt.ItemVariationData{{RegionIndexes: []uint16{0}, Deltas: [][]int32{{50}}}},
+	}
+
+	head := &sfnt.TableHead{}
+	head.UnitsPerEm, head.MagicNumber = 1000, 0x5F0F3CF5
+	if !loca.Short {
+		head.IndexToLocFormat = 1
+	}
+	hhea := &sfnt.TableHhea{}
+	hhea.Ascent, hhea.Descent, hhea.NumOfLongHorMetrics = 800, -200, 3
+	maxp := &sfnt.TableMaxp{}
+	maxp.Version.Major, maxp.NumGlyphs = 1, 3
+	os2 := &sfnt.TableOS2{}
+	os2.Version, os2.USWeightClass, os2.USWidthClass, os2.SxHeigh, os2.FsSelection = 4, 400, 5, 500, fsSelectionRegular
+
+	names := sfnt.NewTableName()
+	for id, value := range map[sfnt.NameID]string{
+		sfnt.NameFontFamily:    "Test",
+		sfnt.NameFontSubfamily: "Regular",
+		256:                    "Bold",
+		257:                    "Condensed",
+	} {
+		if err := names.AddMicrosoftEnglishEntry(id, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wght, wdth := sfnt.MustNamedTag("wght"), sfnt.MustNamedTag("wdth")
+	fvar := &sfnt.TableFvar{
+		Axes: []sfnt.VariationAxis{
+			{Tag: wght, Min: 100, Default: 400, Max: 900},
+			{Tag: wdth, Min: 75, Default: 100, Max: 100},
+		},
+		Instances: []sfnt.NamedInstance{
+			{SubfamilyNameID: 2, Coordinates: []float64{400, 100}, PostScriptNameID: sfnt.NoPostScriptName},
+			{SubfamilyNameID: 256, Coordinates: []float64{900, 100}, PostScriptNameID: sfnt.NoPostScriptName},
+			{SubfamilyNameID: 257, Coordinates: []float64{400, 75}, PostScriptNameID: sfnt.NoPostScriptName},
+		},
+	}
+
+	// A 'GPOS' table with a single positioning lookup, adjusting the advance of
+	// glyph 1 by a VariationIndex device table.
+	gpos := u16s(1, 0, 10, 12, 14, 0, 0)
+	gpos = append(gpos, u16s(1, 4)...)                       // LookupList
+	gpos = append(gpos, u16s(1, 0, 1, 8)...)                 // Lookup
+	gpos = append(gpos, u16s(1, 10, 0x44, 0, 16)...)         // SinglePosFormat1
+	gpos = append(gpos, u16s(1, 1, 1)...)                    // Coverage
+	gpos = append(gpos, u16s(0, 0, variationIndexFormat)...) // VariationIndex
+	gdef := append(u16s(1, 3, 0, 0, 0, 0, 0, 0, 18), (&sfnt.ItemVariationStore{
+		Regions: []sfnt.VariationRegion{{wghtMax, {}}},
+		Data:    []sfnt.ItemVariationData{{RegionIndexes: []uint16{0}, Deltas: [][]int32{{10}}}},
+	}).Bytes()...)
+
+	font := sfnt.New(sfnt.TypeTrueType)
+	font.AddTable(sfnt.TagHead, head)
+	font.AddTable(sfnt.TagHhea, hhea)
+	font.AddTable(sfnt.TagMaxp, maxp)
+	font.AddTable(sfnt.TagOS2, os2)
+	font.AddTable(sfnt.TagName, names)
+	font.AddTable(sfnt.TagHmtx, &sfnt.TableHmtx{Metrics: []sfnt.HMetric{{AdvanceWidth: 0}, {AdvanceWidth: 100}, {AdvanceWidth: 300}}})
+	font.AddTable(sfnt.TagLoca, loca)
+	font.AddTable(sfnt.TagGlyf, glyf)
+	font.AddTable(sfnt.TagGvar, gvar)
+	font.AddTable(sfnt.TagCvt, &sfnt.TableCvt{Values: []int16{100, 200, 300}})
+	font.AddTable(sfnt.TagCvar, cvar)
+	font.AddTable(sfnt.TagFvar, fvar)
+	font.AddTable(sfnt.TagMvar, &sfnt.TableMvar{
+		VarStore: metrics,
+		Values:   []sfnt.MvarValue{{Tag: sfnt.MetricXHeight}},
+	})
+	for tag, buf := range map[sfnt.Tag][]byte{sfnt.TagGpos: gpos, tagGDEF: gdef} {
+		table, err := sfnt.ParseTable(tag, buf)
+		if err != nil {
+			t.Fatalf("ParseTable(%q) err = %q, want nil", tag, err)
+		}
+		font.AddTable(tag, table)
+	}
+
+	return font
+}
+
+// roundTrip writes and parses a font, to check that its tables are serialized correctly.
+func roundTrip(t *testing.T, font *sfnt.Font) *sfnt.Font {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if _, err := font.WriteOTF(&buf); err != nil {
+		t.Fatalf("WriteOTF() err = %q, want nil", err)
+	}
+	parsed, err := sfnt.Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Parse() err = %q, want nil", err)
+	}
+	return parsed
+}
+
+// glyphXs returns the x coordinates of a glyph's outline, and its advance width.
+func glyphXs(t *testing.T, font *sfnt.Font, glyph sfnt.GlyphID, coords []sfnt.F2Dot14) ([]float64, float64) {
+	t.Helper()
+
+	outline, err := font.GlyphOutline(glyph, coords)
+	if err != nil {
+		t.Fatalf("GlyphOutline(%d) err = %q, want nil", glyph, err)
+	}
+	var xs []float64
+	for _, p := range outline.Points {
+		xs = append(xs, p.X)
+	}
+	return xs, outline.AdvanceWidth
+}
+
+func TestInstanceFull(t *testing.T) {
+	font := testFont(t)
+	instance, err := Instance(font, map[sfnt.Tag]float64{
+		sfnt.MustNamedTag("wght"): 900,
+		sfnt.MustNamedTag("wdth"): 100,
+	})
+	if err != nil {
+		t.Fatalf("Instance() err = %q, want nil", err)
+	}
+	instance = roundTrip(t, instance)
+
+	for _, tag := range []sfnt.Tag{sfnt.TagFvar, sfnt.TagGvar, sfnt.TagMvar, sfnt.TagCvar} {
+		if instance.HasTable(tag) {
+			t.Errorf("instance has %q table, want it removed", tag)
+		}
+	}
+
+	xs, advance := glyphXs(t, instance, 1, nil)
+	if want := []float64{0, 0, 150, 150}; !reflect.DeepEqual(xs, want) || advance != 150 {
+		t.Errorf("glyph 1 = %v, %v, want %v, %v", xs, advance, want, 150)
+	}
+	xs, advance = glyphXs(t, instance, 2, nil)
+	if want := []float64{0, 0, 150, 150, 250, 250, 400, 400}; !reflect.DeepEqual(xs, want) || advance != 400 {
+		t.Errorf("glyph 2 = %v, %v, want %v, %v", xs, advance, want, 400)
+	}
+
+	cvt, err := instance.CvtTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int16{150, 200, 300}; !reflect.DeepEqual(cvt.Values, want) {
+		t.Errorf("cvt = %v, want %v", cvt.Values, want)
+	}
+
+	glyf, err := instance.GlyfTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	glyph, err := glyf.Glyph(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if glyph.XMin != 0 || glyph.XMax != 400 {
+		t.Errorf("glyph 2 bounds = %d to %d, want 0 to 400", glyph.XMin, glyph.XMax)
+	}
+
+	os2, err := instance.OS2Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os2.USWeightClass != 900 || os2.SxHeigh != 550 || os2.FsSelection != fsSelectionBold {
+		t.Errorf("OS/2 weight, x-height, fsSelection = %d, %d, %#x, want 900, 550, %#x",
+			os2.USWeightClass, os2.SxHeigh, os2.FsSelection, fsSelectionBold)
+	}
+
+	names, err := instance.NameTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[sfnt.NameID]string{
+		sfnt.NameFontFamily:    "Test",
+		sfnt.NameFontSubfamily: "Bold",
+		sfnt.NameFull:          "Test Bold",
+		sfnt.NamePostscript:    "Test-Bold",
+	} {
+		if got := names.Find(id); got == nil || got.String() != want {
+			t.Errorf("name %d = %v, want %q", id, got, want)
+		}
+	}
+
+	gpos, err := instance.Table(sfnt.TagGpos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The XAdvance and XAdvDevice fields of the SinglePos subtable.
+	if got := gpos.Bytes()[32:36]; !bytes.Equal(got, u16s(10, 0)) {
+		t.Errorf("GPOS value record = %v, want XAdvance 10 and no device", got)
+	}
+}
+
+func TestInstancePartial(t *testing.T) {
+	font := testFont(t)
+	instance, err := Instance(font, map[sfnt.Tag]float64{sfnt.MustNamedTag("wdth"): 75})
+	if err != nil {
+		t.Fatalf("Instance() err = %q, want nil", err)
+	}
+	instance = roundTrip(t, instance)
+
+	fvar, err := instance.FvarTable()
+	if err != nil {
+		t.Fatalf("FvarTable() err = %q, want nil", err)
+	}
+	if len(fvar.Axes) != 1 || fvar.Axes[0].Tag != sfnt.MustNamedTag("wght") {
+		t.Errorf("fvar.Axes = %v, want only 'wght'", fvar.Axes)
+	}
+	if len(fvar.Instances) != 1 || !reflect.DeepEqual(fvar.Instances[0].Coordinates, []float64{400}) {
+		t.Errorf("fvar.Instances = %v, want the condensed instance at 400", fvar.Instances)
+	}
+
+	tests := []struct {
+		glyph   sfnt.GlyphID
+		coords  []sfnt.F2Dot14
+		xs      []float64
+		advance float64
+	}{
+		{1, nil, []float64{0, 0, 80, 80}, 80},
+		{1, []sfnt.F2Dot14{1 << 14}, []float64{0, 0, 130, 130}, 130},
+		{2, nil, []float64{0, 0, 80, 80, 180, 180, 260, 260}, 260},
+		{2, []sfnt.F2Dot14{1 << 14}, []float64{0, 0, 130, 130, 230, 230, 360, 360}, 360},
+	}
+	for _, test := range tests {
+		xs, advance := glyphXs(t, instance, test.glyph, test.coords)
+		if !reflect.DeepEqual(xs, test.xs) || advance != test.advance {
+			t.Errorf("glyph %d at %v = %v, %v, want %v, %v", test.glyph, test.coords, xs, advance, test.xs, test.advance)
+		}
+	}
+
+	cvt, err := instance.CvtTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int16{100, 200, 280}; !reflect.DeepEqual(cvt.Values, want) {
+		t.Errorf("cvt = %v, want %v", cvt.Values, want)
+	}
+	cvar, err := instance.CvarTable()
+	if err != nil {
+		t.Fatalf("CvarTable() err = %q, want nil", err)
+	}
+	variations, err := cvar.Variations(1, 3)
+	want := []sfnt.TupleVariation{{Region: sfnt.VariationRegion{wghtMax}, DeltasX: []int32{50, 0, 0}}}
+	if err != nil || !reflect.DeepEqual(variations, want) {
+		t.Errorf("cvar variations = %+v, %v, want %+v", variations, err, want)
+	}
+
+	xHeight, err := instance.Metric(sfnt.MetricXHeight, []sfnt.F2Dot14{1 << 14})
+	if err != nil || xHeight != 550 {
+		t.Errorf("Metric(xhgt) = %v, %v, want 550", xHeight, err)
+	}
+
+	os2, err := instance.OS2Table()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os2.USWeightClass != 400 || os2.USWidthClass != 3 {
+		t.Errorf("OS/2 weight and width = %d, %d, want 400, 3", os2.USWeightClass, os2.USWidthClass)
+	}
+}
+
+func TestInstanceErrors(t *testing.T) {
+	font := testFont(t)
+	if _, err := Instance(font, map[sfnt.Tag]float64{sfnt.MustNamedTag("opsz"): 12}); err == nil {
+		t.Errorf("Instance(opsz) err = nil, want error for missing axis")
+	}
+}
+
+// cff2Index returns a CFF2 INDEX containing items, which are less than 255 bytes in total.
+func cff2Index(items ...[]byte) []byte {
+	buf := []byte{0, 0, 0, byte(len(items)), 1, 1}
+	offset := 1
+	for _, item := range items {
+		offset += len(item)
+		buf = append(buf, byte(offset))
+	}
+	for _, item := range items {
+		buf = append(buf, item...)
+	}
+	return buf
+}
+
+// dictInt encodes an operand of a CFF DICT in 5 bytes.
+func dictInt(v int) []byte {
+	return []byte{29, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+// testCFF2Font returns the font from testFont with 'CFF2' outlines, whose
+// advances vary with 'HVAR'. The square varies as in testFont, while glyph 2
+// is a curve whose control points are at the corners of the square, drawn by
+// a global subroutine.
+func testCFF2Font(t *testing.T) *sfnt.Font {
+	t.Helper()
+
+	// n encodes a charstring operand between -107 and 107.
+	n := func(v int) byte { return byte(v + 139) }
+	const rmoveto, hlineto, rrcurveto, ret, blend, callgsubr = 21, 6, 8, 11, 16, 29
+	square := []byte{
+		n(0), n(0), rmoveto,
+		n(100), n(50), n(-20), n(1), blend, n(100), n(-100), n(-50), n(20), n(1), blend, hlineto,
+	}
+	curve := []byte{n(0), n(0), rmoveto, n(-107), callgsubr}
+	subr := []byte{n(0), n(100), n(100), n(50), n(-20), n(1), blend, n(0), n(0), n(-100), rrcurveto, ret}
+	// StdVW is 80, and blended like the width of the square.
+	private := []byte{n(80), n(20), n(-10), n(1), 23, 11}
+
+	regions := []sfnt.VariationRegion{{wghtMax, {}}, {{}, wdthMin}}
+	store := (&sfnt.ItemVariationStore{
+		Regions: regions,
+		Data:    []sfnt.ItemVariationData{{RegionIndexes: []uint16{0, 1}}},
+	}).Bytes()
+
+	const topDictSize = 19
+	buf := append([]byte{2, 0, 5}, u16s(topDictSize)...)
+	gsubrs := cff2Index(subr)
+	vstore := 5 + topDictSize + len(gsubrs)
+	charStrings := vstore + 2 + len(store)
+	charStringIndex := cff2Index(nil, square, curve)
+	fdArray := charStrings + len(charStringIndex)
+	privateOffset := fdArray + len(cff2Index(make([]byte, 11)))
+
+	buf = append(buf, dictInt(charStrings)...)
+	buf = append(buf, 17)
+	buf = append(buf, dictInt(vstore)...)
+	buf = append(buf, 24)
+	buf = append(buf, dictInt(fdArray)...)
+	buf = append(buf, 12, 36)
+	buf = append(buf, gsubrs...)
+	buf = append(buf, u16s(len(store))...)
+	buf = append(buf, store...)
+	buf = append(buf, charStringIndex...)
+	buf = append(buf, cff2Index(append(append(dictInt(len(private)), dictInt(privateOffset)...), 18))...)
+	buf = append(buf, private...)
+
+	font := testFont(t)
+	for _, tag := range []sfnt.Tag{sfnt.TagGlyf, sfnt.TagLoca, sfnt.TagGvar, sfnt.TagCvt, sfnt.TagCvar} {
+		font.RemoveTable(tag)
+	}
+	cff2, err := sfnt.ParseTable(sfnt.TagCFF2, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	font.AddTable(sfnt.TagCFF2, cff2)
+	font.AddTable(sfnt.TagHmtx, &sfnt.TableHmtx{Metrics: []sfnt.HMetric{{AdvanceWidth: 0}, {AdvanceWidth: 100}, {AdvanceWidth: 100}}})
+	font.AddTable(sfnt.TagHvar, &sfnt.TableHvar{VarStore: &sfnt.ItemVariationStore{
+		Regions: regions,
+		Data:    []sfnt.ItemVariationData{{RegionIndexes: []uint16{0, 1}, Deltas: [][]int32{{0, 0}, {50, -20}, {50, -20}}}},
+	}})
+	return font
+}
+
+func TestInstanceCFF2(t *testing.T) {
+	wght, wdth := sfnt.MustNamedTag("wght"), sfnt.MustNamedTag("wdth")
+	font := testCFF2Font(t)
+	partial, err := Instance(font, map[sfnt.Tag]float64{wdth: 75})
+	if err != nil {
+		t.Fatalf("Instance(wdth) err = %q, want nil", err)
+	}
+	partial = roundTrip(t, partial)
+
+	tests := []struct {
+		name     string
+		font     *sfnt.Font
+		location map[sfnt.Tag]float64
+		width    int16 // width is the width and advance of glyphs 1 and 2.
+		variable bool
+	}{
+		{"full", font, map[sfnt.Tag]float64{wght: 900, wdth: 100}, 150, false},
+		{"partial", font, map[sfnt.Tag]float64{wdth: 75}, 80, true},
+		{"partial then full", partial, map[sfnt.Tag]float64{wght: 900}, 130, false},
+	}
+	for _, test := range tests {
+		instance, err := Instance(test.font, test.location)
+		if err != nil {
+			t.Fatalf("%s: Instance() err = %q, want nil", test.name, err)
+		}
+		instance = roundTrip(t, instance)
+
+		cff2, err := instance.CFF2Table()
+		if err != nil {
+			t.Fatalf("%s: CFF2Table() err = %q, want nil", test.name, err)
+		}
+		if got := cff2.VarStore != nil; got != test.variable || instance.HasTable(sfnt.TagHvar) != test.variable {
+			t.Errorf("%s: has variations = %v, want %v", test.name, got, test.variable)
+		}
+		if len(cff2.GlobalSubrs) != 0 {
+			t.Errorf("%s: %d global subrs, want them inlined", test.name, len(cff2.GlobalSubrs))
+		}
+
+		// The curve reaches three quarters of the height of its control points.
+		for glyph, want := range map[sfnt.GlyphID][4]int16{1: {0, 0, test.width, 100}, 2: {0, 0, test.width, 75}} {
+			xMin, yMin, xMax, yMax, err := cff2.Bounds(glyph)
+			if got := [4]int16{xMin, yMin, xMax, yMax}; err != nil || got != want {
+				t.Errorf("%s: Bounds(%d) = %v, %v, want %v", test.name, glyph, got, err, want)
+			}
+		}
+
+		hmtx, err := instance.HmtxTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hmtx.Metric(2).AdvanceWidth; got != uint16(test.width) {
+			t.Errorf("%s: advance of glyph 2 = %d, want %d", test.name, got, test.width)
+		}
+		head, err := instance.HeadTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if head.XMax != test.width || head.YMax != 100 {
+			t.Errorf("%s: head bounds = %d, %d, want %d, 100", test.name, head.XMax, head.YMax, test.width)
+		}
+	}
+}
+
+// testFeatureVariations returns a 'GSUB' table with 'liga' and 'calt'
+// features, which use lookup 0 by default. From a weight of 650 'liga' uses
+// lookup 1, and otherwise below a width of 87.5 both use lookup 2.
+func testFeatureVariations() []byte {
+	gsub := u16s(1, 1, 14, 16, 42, 0, 68)
+	gsub = append(gsub, u16s(0)...) // ScriptList
+	gsub = append(gsub, u16s(2)...) // FeatureList
+	gsub = append(append(gsub, "liga"...), u16s(14)...)
+	gsub = append(append(gsub, "calt"...), u16s(20)...)
+	gsub = append(gsub, u16s(0, 1, 0, 0, 1, 0)...)                              // Features
+	gsub = append(gsub, u16s(3, 8, 14, 20, 1, 0, 0, 1, 0, 0, 1, 0, 0)...)       // LookupList
+	gsub = append(gsub, u16s(1, 0, 0, 2, 0, 24, 0, 38, 0, 56, 0, 70)...)        // FeatureVariations
+	gsub = append(gsub, u16s(1, 0, 6, 1, 0, 0x2000, 0x4000)...)                 // ConditionSet
+	gsub = append(gsub, u16s(1, 0, 1, 0, 0, 12, 0, 1, 1)...)                    // FeatureTableSubstitution
+	gsub = append(gsub, u16s(1, 0, 6, 1, 1, 0xC000, 0xE000)...)                 // ConditionSet
+	gsub = append(gsub, u16s(1, 0, 2, 0, 0, 18, 1, 0, 24, 0, 1, 2, 0, 1, 2)...) // FeatureTableSubstitution
+	return gsub
+}
+
+// featureLookups returns the lookups of the features in a layout table, and
+// the conditions and lookups of the features substituted by each of its
+// feature variations.
+func featureLookups(t *testing.T, buf []byte) ([][]int, []featureCondition, [][][]int) {
+	t.Helper()
+
+	r := &layoutReader{buf: buf}
+	lookups := func(offset int) []int {
+		var lookups []int
+		for i := 0; i < r.u16(offset+2); i++ {
+			lookups = append(lookups, r.u16(offset+4+2*i))
+		}
+		return lookups
+	}
+
+	featureList := r.u16(6)
+	features := make([][]int, r.u16(featureList))
+	for i := range features {
+		features[i] = lookups(featureList + r.u16(featureList+6+6*i))
+	}
+	var conditions []featureCondition
+	var substitutions [][][]int
+	if r.u16(2) == 1 && r.u32(10) != 0 {
+		for _, v := range r.featureVariations(r.u32(10), len(features)) {
+			conditions = append(conditions, v.conditions...)
+			substituted := make([][]int, len(features))
+			for _, s := range v.substitutions {
+				substituted[s.index] = lookups(s.offset)
+			}
+			substitutions = append(substitutions, substituted)
+		}
+	}
+	if r.err != nil {
+		t.Fatalf("reading features err = %q, want nil", r.err)
+	}
+	return features, conditions, substitutions
+}
+
+func TestInstanceFeatureVariations(t *testing.T) {
+	wght, wdth := sfnt.MustNamedTag("wght"), sfnt.MustNamedTag("wdth")
+	font := testFont(t)
+	gsub, err := sfnt.ParseTable(sfnt.TagGsub, testFeatureVariations())
+	if err != nil {
+		t.Fatalf("ParseTable() err = %q, want nil", err)
+	}
+	font.AddTable(sfnt.TagGsub, gsub)
+
+	tests := []struct {
+		name          string
+		location      map[sfnt.Tag]float64
+		features      [][]int
+		conditions    []featureCondition
+		substitutions [][][]int
+	}{
+		{"default", map[sfnt.Tag]float64{wght: 400, wdth: 100}, [][]int{{0}, {0}}, nil, nil},
+		{"bold", map[sfnt.Tag]float64{wght: 900, wdth: 100}, [][]int{{1}, {0}}, nil, nil},
+		{"bold condensed", map[sfnt.Tag]float64{wght: 900, wdth: 75}, [][]int{{1}, {0}}, nil, nil},
+		{"condensed", map[sfnt.Tag]float64{wght: 400, wdth: 75}, [][]int{{2}, {2}}, nil, nil},
+		// The weight variation falls back to the original 'calt', rather than
+		// the one of the condensed variation.
+		{
+			"partial condensed", map[sfnt.Tag]float64{wdth: 75}, [][]int{{2}, {2}},
+			[]featureCondition{{axis: 0, min: 0x2000, max: 0x4000}},
+			[][][]int{{{1}, {0}}},
+		},
+		{
+			"partial wide", map[sfnt.Tag]float64{wdth: 100}, [][]int{{0}, {0}},
+			[]featureCondition{{axis: 0, min: 0x2000, max: 0x4000}},
+			[][][]int{{{1}, nil}},
+		},
+		{
+			"partial regular", map[sfnt.Tag]float64{wght: 400}, [][]int{{0}, {0}},
+			[]featureCondition{{axis: 0, min: -0x4000, max: -0x2000}},
+			[][][]int{{{2}, {2}}},
+		},
+	}
+	for _, test := range tests {
+		instance, err := Instance(font, test.location)
+		if err != nil {
+			t.Fatalf("%s: Instance() err = %q, want nil", test.name, err)
+		}
+		instance = roundTrip(t, instance)
+		gsub, err := instance.Table(sfnt.TagGsub)
+		if err != nil {
+			t.Fatalf("%s: Table(GSUB) err = %q, want nil", test.name, err)
+		}
+		features, conditions, substitutions := featureLookups(t, gsub.Bytes())
+		if !reflect.DeepEqual(features, test.features) {
+			t.Errorf("%s: features = %v, want %v", test.name, features, test.features)
+		}
+		if !reflect.DeepEqual(conditions, test.conditions) || !reflect.DeepEqual(substitutions, test.substitutions) {
+			t.Errorf("%s: variations = %v, %v, want %v, %v", test.name, conditions, substitutions, test.conditions, test.substitutions)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package instancer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// instanceMetricVariations reduces 'HVAR' and 'VVAR' to the remaining axes.
+// The horizontal advances are already set from the phantom points in 'gvar',
+// or from 'HVAR' for 'CFF2' outlines, while the vertical advances in 'vmtx'
+// are updated from 'VVAR'.
+func (inst *instancer) instanceMetricVariations() error {
+	for _, tag := range []sfnt.Tag{sfnt.TagHvar, sfnt.TagVvar} {
+		if !inst.font.HasTable(tag) {
+			continue
+		}
+		table, err := inst.font.Table(tag)
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", tag, err)
+		}
+		hvar := *table.(*sfnt.TableHvar)
+		pinned := inst.pinStore(hvar.VarStore)
+
+		if tag == sfnt.TagVvar && inst.font.HasTable(sfnt.TagVmtx) {
+			if err := inst.updateVmtx(&hvar, pinned); err != nil {
+				return err
+			}
+		}
+
+		if pinned.store == nil {
+			inst.out.RemoveTable(tag)
+			continue
+		}
+		hvar.VarStore = pinned.store
+		inst.out.AddTable(tag, &hvar)
+	}
+	return nil
+}
+
+// updateVmtx adds the deltas of the vertical advances at the pinned location to 'vmtx'.
+func (inst *instancer) updateVmtx(vvar *sfnt.TableHvar, pinned *pinnedStore) error {
+	vmtx, err := inst.out.VmtxTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagVmtx, err)
+	}
+
+	updated := *vmtx
+	updated.Metrics = make([]sfnt.VMetric, len(vmtx.Metrics))
+	for i, m := range vmtx.Metrics {
+		index := sfnt.VariationIndex{Inner: uint16(i)}
+		if vvar.AdvanceMap != nil {
+			index = vvar.AdvanceMap.Index(i)
+		}
+		advance := int(m.AdvanceHeight) + pinned.roundedDelta(index)
+		m.AdvanceHeight = uint16(math.Max(0, float64(advance)))
+		updated.Metrics[i] = m
+	}
+	inst.out.AddTable(sfnt.TagVmtx, &updated)
+	return nil
+}
+
+// instanceMvar adds the deltas of font-wide metrics at the pinned location to
+// the tables containing them, and reduces 'MVAR' to the remaining axes.
+func (inst *instancer) instanceMvar() error {
+	if !inst.font.HasTable(sfnt.TagMvar) {
+		return nil
+	}
+	original, err := inst.font.MvarTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagMvar, err)
+	}
+	if original.VarStore == nil {
+		inst.out.RemoveTable(sfnt.TagMvar)
+		return nil
+	}
+
+	pinned := inst.pinStore(original.VarStore)
+	deltas := make(map[sfnt.Tag]int)
+	for _, value := range original.Values {
+		if d := pinned.roundedDelta(value.Index); d != 0 {
+			deltas[value.Tag] = d
+		}
+	}
+	if err := inst.applyMetricDeltas(deltas); err != nil {
+		return err
+	}
+
+	if pinned.store == nil {
+		inst.out.RemoveTable(sfnt.TagMvar)
+		return nil
+	}
+	mvar := *original
+	mvar.VarStore = pinned.store
+	inst.out.AddTable(sfnt.TagMvar, &mvar)
+	return nil
+}
+
+// metricFields contains the offsets of metrics stored as 16-bit values in
+// tables that are modified as bytes.
+var metricFields = map[sfnt.Tag]struct {
+	table  sfnt.Tag
+	offset int
+}{
+	sfnt.MetricUnderlineOffset:     {sfnt.TagPost, 8},
+	sfnt.MetricUnderlineSize:       {sfnt.TagPost, 10},
+	sfnt.MetricVerticalAscender:    {sfnt.TagVhea, 4},
+	sfnt.MetricVerticalDescender:   {sfnt.TagVhea, 6},
+	sfnt.MetricVerticalLineGap:     {sfnt.TagVhea, 8},
+	sfnt.MetricVerticalCaretRise:   {sfnt.TagVhea, 18},
+	sfnt.MetricVerticalCaretRun:    {sfnt.TagVhea, 20},
+	sfnt.MetricVerticalCaretOffset: {sfnt.TagVhea, 22},
+}
+
+// applyMetricDeltas adds deltas to the metrics identified by 'MVAR' value tags.
+func (inst *instancer) applyMetricDeltas(deltas map[sfnt.Tag]int) error {
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	if inst.out.HasTable(sfnt.TagOS2) {
+		os2, err := inst.out.OS2Table()
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagOS2, err)
+		}
+		t := *os2
+		fields := map[sfnt.Tag]*int16{
+			sfnt.MetricHorizontalAscender:  &t.STypoAscender,
+			sfnt.MetricHorizontalDescender: &t.STypoDescender,
+			sfnt.MetricHorizontalLineGap:   &t.STypoLineGap,
+			sfnt.MetricXHeight:             &t.SxHeigh,
+			sfnt.MetricCapHeight:           &t.SCapHeight,
+			sfnt.MetricSubscriptXSize:      &t.YSubscriptXSize,
+			sfnt.MetricSubscriptYSize:      &t.YSubscriptYSize,
+			sfnt.MetricSubscriptXOffset:    &t.YSubscriptXOffset,
+			sfnt.MetricSubscriptYOffset:    &t.YSubscriptYOffset,
+			sfnt.MetricSuperscriptXSize:    &t.YSuperscriptXSize,
+			sfnt.MetricSuperscriptYSize:    &t.YSuperscriptYSize,
+			sfnt.MetricSuperscriptXOffset:  &t.YSuperscriptXOffset,
+			sfnt.MetricSuperscriptYOffset:  &t.YSuperscriptYOffset,
+			sfnt.MetricStrikeoutSize:       &t.YStrikeoutSize,
+			sfnt.MetricStrikeoutOffset:     &t.YStrikeoutPosition,
+		}
+		for tag, field := range fields {
+			*field += int16(deltas[tag])
+		}
+		t.UsWinAscent = uint16(int(t.UsWinAscent) + deltas[sfnt.MetricHorizontalClipAscent])
+		t.UsWinDescent = uint16(int(t.UsWinDescent) + deltas[sfnt.MetricHorizontalClipDescent])
+		inst.out.AddTable(sfnt.TagOS2, &t)
+	}
+
+	hhea, err := inst.out.HheaTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHhea, err)
+	}
+	newHhea := *hhea
+	newHhea.CaretSlopeRise += int16(deltas[sfnt.MetricHorizontalCaretRise])
+	newHhea.CaretSlopeRun += int16(deltas[sfnt.MetricHorizontalCaretRun])
+	newHhea.CaretOffset += int16(deltas[sfnt.MetricHorizontalCaretOffset])
+	inst.out.AddTable(sfnt.TagHhea, &newHhea)
+
+	raw := make(map[sfnt.Tag][]byte)
+	for tag, d := range deltas {
+		field, ok := metricFields[tag]
+		if !ok || !inst.out.HasTable(field.table) {
+			continue
+		}
+		buf, ok := raw[field.table]
+		if !ok {
+			if buf, err = inst.rawTable(field.table); err != nil {
+				return err
+			}
+			raw[field.table] = buf
+		}
+		if len(buf) < field.offset+2 {
+			return fmt.Errorf("parsing %q: table too short", field.table)
+		}
+		v := int16(binary.BigEndian.Uint16(buf[field.offset:])) + int16(d)
+		binary.BigEndian.PutUint16(buf[field.offset:], uint16(v))
+	}
+	for tag, buf := range raw {
+		if err := inst.addRawTable(tag, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package instancer
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// nameVariationsPostScriptPrefix is the name used to build the PostScript
+// names of instances of a variable font.
+const nameVariationsPostScriptPrefix = sfnt.NameID(25)
+
+// Style bits in OS/2.fsSelection and head.macStyle.
+const (
+	fsSelectionItalic  = 0x0001
+	fsSelectionBold    = 0x0020
+	fsSelectionRegular = 0x0040
+	macStyleBold       = 0x0001
+	macStyleItalic     = 0x0002
+)
+
+// widthClasses contains the 'wdth' value of each OS/2.usWidthClass, from 1 to 9.
+var widthClasses = []float64{50, 62.5, 75, 87.5, 100, 112.5, 125, 150, 200}
+
+// instanceOS2 sets the weight and width classes in 'OS/2' from the pinned
+// 'wght' and 'wdth' axes.
+func (inst *instancer) instanceOS2() error {
+	if !inst.out.HasTable(sfnt.TagOS2) {
+		return nil
+	}
+	os2, err := inst.out.OS2Table()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagOS2, err)
+	}
+	t := *os2
+
+	if wght, ok := inst.location[tagWght]; ok {
+		t.USWeightClass = uint16(math.Max(1, math.Min(1000, math.Round(wght))))
+	}
+	if wdth, ok := inst.location[tagWdth]; ok {
+		class := 0
+		for i, v := range widthClasses {
+			if math.Abs(v-wdth) < math.Abs(widthClasses[class]-wdth) {
+				class = i
+			}
+		}
+		t.USWidthClass = uint16(class + 1)
+	}
+
+	inst.out.AddTable(sfnt.TagOS2, &t)
+	return nil
+}
+
+// instanceNames updates the family, subfamily, full and PostScript names of
+// a static instance, along with the style bits in 'OS/2' and 'head'. The
+// subfamily is taken from the named instance at the pinned location, if there is one.
+func (inst *instancer) instanceNames() error {
+	if !inst.font.HasTable(sfnt.TagName) {
+		return nil
+	}
+	names, err := inst.font.NameTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagName, err)
+	}
+	find := func(ids ...sfnt.NameID) string {
+		for _, id := range ids {
+			if entry := names.Find(id); entry != nil {
+				return entry.String()
+			}
+		}
+		return ""
+	}
+
+	family := find(sfnt.NamePreferredFamily, sfnt.NameFontFamily)
+	subfamily, postscript := inst.instanceName(names)
+	if subfamily == "" {
+		subfamily = find(sfnt.NamePreferredSubfamily, sfnt.NameFontSubfamily)
+	}
+	if postscript == "" {
+		prefix := find(nameVariationsPostScriptPrefix)
+		if prefix == "" {
+			prefix = strings.ReplaceAll(family, " ", "")
+		}
+		postscript = prefix + "-" + strings.ReplaceAll(subfamily, " ", "")
+	}
+
+	bold := subfamily == "Bold" || strings.HasPrefix(subfamily, "Bold ")
+	italic := subfamily == "Italic" || strings.HasSuffix(subfamily, " Italic")
+
+	// Legacy applications only understand the four styles Regular, Italic,
+	// Bold and Bold Italic; other styles are moved into the family name.
+	updated := map[sfnt.NameID]string{
+		sfnt.NameFull:       family + " " + subfamily,
+		sfnt.NamePostscript: postscript,
+	}
+	switch subfamily {
+	case "Regular", "Italic", "Bold", "Bold Italic":
+		updated[sfnt.NameFontFamily] = family
+		updated[sfnt.NameFontSubfamily] = subfamily
+	default:
+		legacy := strings.TrimSuffix(subfamily, " Italic")
+		bold = false
+		updated[sfnt.NameFontFamily] = family + " " + legacy
+		updated[sfnt.NameFontSubfamily] = "Regular"
+		if italic {
+			updated[sfnt.NameFontSubfamily] = "Italic"
+		}
+		updated[sfnt.NamePreferredFamily] = family
+		updated[sfnt.NamePreferredSubfamily] = subfamily
+	}
+
+	out := sfnt.NewTableName()
+	for _, entry := range names.List() {
+		_, replaced := updated[entry.NameID]
+		removed := entry.NameID == nameVariationsPostScriptPrefix ||
+			entry.NameID == sfnt.NamePreferredFamily || entry.NameID == sfnt.NamePreferredSubfamily
+		if !replaced && !removed {
+			out.Add(entry)
+		}
+	}
+	for id, value := range updated {
+		if err := out.AddMicrosoftEnglishEntry(id, value); err != nil {
+			return fmt.Errorf("name %d: %w", id, err)
+		}
+	}
+
+	entries := out.List()
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.PlatformID != b.PlatformID {
+			return a.PlatformID < b.PlatformID
+		}
+		if a.EncodingID != b.EncodingID {
+			return a.EncodingID < b.EncodingID
+		}
+		if a.LanguageID != b.LanguageID {
+			return a.LanguageID < b.LanguageID
+		}
+		return a.NameID < b.NameID
+	})
+	inst.out.AddTable(sfnt.TagName, out)
+
+	return inst.setStyleBits(bold, italic)
+}
+
+// instanceName returns the subfamily and PostScript names of the named
+// instance at the pinned location, or "" if there is none.
+func (inst *instancer) instanceName(names *sfnt.TableName) (subfamily, postscript string) {
+	for _, instance := range inst.fvar.Instances {
+		match := len(instance.Coordinates) == len(inst.fvar.Axes)
+		for i, axis := range inst.fvar.Axes {
+			match = match && instance.Coordinates[i] == inst.location[axis.Tag]
+		}
+		if match {
+			return instance.SubfamilyName(names), instance.PostScriptName(names)
+		}
+	}
+	return "", ""
+}
+
+// setStyleBits sets the bold and italic bits of OS/2.fsSelection and head.macStyle.
+func (inst *instancer) setStyleBits(bold, italic bool) error {
+	head, err := inst.out.HeadTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHead, err)
+	}
+	newHead := *head
+	newHead.MacStyle &^= macStyleBold | macStyleItalic
+	if bold {
+		newHead.MacStyle |= macStyleBold
+	}
+	if italic {
+		newHead.MacStyle |= macStyleItalic
+	}
+	inst.out.AddTable(sfnt.TagHead, &newHead)
+
+	if !inst.out.HasTable(sfnt.TagOS2) {
+		return nil
+	}
+	os2, err := inst.out.OS2Table()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagOS2, err)
+	}
+	t := *os2
+	t.FsSelection &^= fsSelectionBold | fsSelectionItalic | fsSelectionRegular
+	switch {
+	case bold || italic:
+		if bold {
+			t.FsSelection |= fsSelectionBold
+		}
+		if italic {
+			t.FsSelection |= fsSelectionItalic
+		}
+	default:
+		t.FsSelection |= fsSelectionRegular
+	}
+	inst.out.AddTable(sfnt.TagOS2, &t)
+	return nil
+}
@@ -0,0 +1,165 @@
+package instancer
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// pinRegion splits a region into its scalar along the pinned axes, and the
+// region along the remaining axes. collapsed is true if the region does not
+// vary along the remaining axes, so its deltas apply to the default instance.
+func (inst *instancer) pinRegion(region sfnt.VariationRegion) (scalar float64, rest sfnt.VariationRegion, collapsed bool) {
+	pinned := make(sfnt.VariationRegion, len(region))
+	collapsed = true
+	for i, axis := range region {
+		if i < len(inst.pinned) && inst.pinned[i] {
+			pinned[i] = axis
+			continue
+		}
+		rest = append(rest, axis)
+		collapsed = collapsed && axis.Peak == 0
+	}
+	return pinned.Scalar(inst.coords), rest, collapsed
+}
+
+// regionKey returns a string identifying a region, used to merge the deltas
+// of regions that are the same once the pinned axes are removed.
+func regionKey(region sfnt.VariationRegion) string {
+	return fmt.Sprint([]sfnt.RegionAxis(region))
+}
+
+// pinnedStore is an ItemVariationStore with the pinned axes removed.
+type pinnedStore struct {
+	// store contains the remaining variations, with the same indexes as the
+	// original store. It is nil if no variations remain.
+	store *sfnt.ItemVariationStore
+	// defaults contains the delta of each item at the pinned location, which
+	// must be added to the values in the default instance.
+	defaults [][]float64
+	// columns contains, for each ItemVariationData of the original store,
+	// where the deltas of each of its regions go.
+	columns [][]pinnedColumn
+	// widths contains the number of regions of each ItemVariationData of
+	// the new store.
+	widths []int
+}
+
+// pinnedColumn is a region of an ItemVariationData at the pinned location.
+type pinnedColumn struct {
+	scalar float64
+	// index is the index of the region in the new ItemVariationData, or -1
+	// if its deltas apply to the default instance.
+	index int
+}
+
+// delta returns the delta at the pinned location of the item with the given index.
+func (s *pinnedStore) delta(index sfnt.VariationIndex) float64 {
+	if int(index.Outer) >= len(s.defaults) || int(index.Inner) >= len(s.defaults[index.Outer]) {
+		return 0
+	}
+	return s.defaults[index.Outer][index.Inner]
+}
+
+// roundedDelta returns delta rounded to the nearest integer.
+func (s *pinnedStore) roundedDelta(index sfnt.VariationIndex) int {
+	return int(math.Round(s.delta(index)))
+}
+
+// pinDeltas splits the deltas of an item of the ItemVariationData with the
+// given index into its delta at the pinned location, and its deltas for the
+// regions of the new ItemVariationData.
+func (s *pinnedStore) pinDeltas(data int, row []float64) (float64, []float64) {
+	var delta float64
+	deltas := make([]float64, s.widths[data])
+	for k, d := range row {
+		switch c := s.columns[data][k]; {
+		case c.scalar == 0:
+		case c.index < 0:
+			delta += c.scalar * d
+		default:
+			deltas[c.index] += c.scalar * d
+		}
+	}
+	return delta, deltas
+}
+
+// pinStore removes the pinned axes from store.
+func (inst *instancer) pinStore(store *sfnt.ItemVariationStore) *pinnedStore {
+	type pinnedRegion struct {
+		scalar    float64
+		index     int // index is the index of the region in the new store.
+		collapsed bool
+	}
+
+	out := &sfnt.ItemVariationStore{}
+	keys := make(map[string]int)
+	regions := make([]pinnedRegion, len(store.Regions))
+	for i, region := range store.Regions {
+		scalar, rest, collapsed := inst.pinRegion(region)
+		regions[i] = pinnedRegion{scalar: scalar, collapsed: collapsed}
+		if scalar == 0 || collapsed {
+			continue
+		}
+
+		key := regionKey(rest)
+		index, ok := keys[key]
+		if !ok {
+			index = len(out.Regions)
+			keys[key] = index
+			out.Regions = append(out.Regions, rest)
+		}
+		regions[i].index = index
+	}
+
+	pinned := &pinnedStore{
+		defaults: make([][]float64, len(store.Data)),
+		columns:  make([][]pinnedColumn, len(store.Data)),
+		widths:   make([]int, len(store.Data)),
+	}
+	out.Data = make([]sfnt.ItemVariationData, len(store.Data))
+	for i, data := range store.Data {
+		// indexes maps the regions of the new store to the deltas of the new data.
+		indexes := make(map[int]int)
+		var regionIndexes []uint16
+		pinned.columns[i] = make([]pinnedColumn, len(data.RegionIndexes))
+		for k, r := range data.RegionIndexes {
+			region := regions[r]
+			column := &pinned.columns[i][k]
+			column.scalar, column.index = region.scalar, -1
+			if region.scalar == 0 || region.collapsed {
+				continue
+			}
+			if _, ok := indexes[region.index]; !ok {
+				indexes[region.index] = len(regionIndexes)
+				regionIndexes = append(regionIndexes, uint16(region.index))
+			}
+			column.index = indexes[region.index]
+		}
+		pinned.widths[i] = len(regionIndexes)
+
+		defaults := make([]float64, len(data.Deltas))
+		deltas := make([][]int32, len(data.Deltas))
+		for j, row := range data.Deltas {
+			values := make([]float64, len(row))
+			for k, d := range row {
+				values[k] = float64(d)
+			}
+			var scaled []float64
+			defaults[j], scaled = pinned.pinDeltas(i, values)
+			deltas[j] = make([]int32, len(scaled))
+			for k, d := range scaled {
+				deltas[j][k] = int32(math.Round(d))
+			}
+		}
+
+		pinned.defaults[i] = defaults
+		out.Data[i] = sfnt.ItemVariationData{RegionIndexes: regionIndexes, Deltas: deltas}
+	}
+
+	if len(out.Regions) > 0 {
+		pinned.store = out
+	}
+	return pinned
+}
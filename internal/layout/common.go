@@ -0,0 +1,294 @@
+package layout
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+var errInvalidOffset = errors.New("offset out of range")
+
+// reader reads the fields of a table. Reading out of range sets err, which
+// is checked once a subtable has been read, and returns zero values.
+type reader struct {
+	buf []byte
+	err error
+}
+
+func (r *reader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *reader) u16(pos int) int {
+	if pos < 0 || pos+2 > len(r.buf) {
+		r.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	return int(binary.BigEndian.Uint16(r.buf[pos:]))
+}
+
+func (r *reader) u32(pos int) int {
+	if pos < 0 || pos+4 > len(r.buf) {
+		r.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	return int(binary.BigEndian.Uint32(r.buf[pos:]))
+}
+
+// u16s reads n 16-bit values.
+func (r *reader) u16s(pos, n int) []int {
+	if pos < 0 || pos+2*n > len(r.buf) {
+		r.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+	values := make([]int, n)
+	for i := range values {
+		values[i] = int(binary.BigEndian.Uint16(r.buf[pos+2*i:]))
+	}
+	return values
+}
+
+// glyphs reads n glyph IDs.
+func (r *reader) glyphs(pos, n int) []sfnt.GlyphID {
+	values := r.u16s(pos, n)
+	glyphs := make([]sfnt.GlyphID, len(values))
+	for i, v := range values {
+		glyphs[i] = sfnt.GlyphID(v)
+	}
+	return glyphs
+}
+
+// bytes returns n bytes.
+func (r *reader) bytes(pos, n int) []byte {
+	if pos < 0 || n < 0 || pos+n > len(r.buf) {
+		r.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+	return r.buf[pos : pos+n]
+}
+
+// offset returns the position of the subtable at the 16-bit offset stored
+// at field, relative to base, or 0 if the offset is null.
+func (r *reader) offset(base, field int) int {
+	v := r.u16(field)
+	if v == 0 {
+		return 0
+	}
+	if base+v >= len(r.buf) {
+		r.fail(errInvalidOffset)
+		return 0
+	}
+	return base + v
+}
+
+// offset32 returns the position of the subtable at the 32-bit offset stored
+// at field, relative to base, or 0 if the offset is null.
+func (r *reader) offset32(base, field int) int {
+	v := r.u32(field)
+	if v == 0 {
+		return 0
+	}
+	if v < 0 || base+v >= len(r.buf) {
+		r.fail(errInvalidOffset)
+		return 0
+	}
+	return base + v
+}
+
+// coverage reads the coverage table at pos, returning the glyphs in the
+// order of their coverage indexes.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#coverage-table
+func (r *reader) coverage(pos int) []sfnt.GlyphID {
+	format, count := r.u16(pos), r.u16(pos+2)
+	switch format {
+	case 1:
+		return r.glyphs(pos+4, count)
+	case 2:
+		var glyphs []sfnt.GlyphID
+		ranges := r.u16s(pos+4, 3*count)
+		for i := 0; i+2 < len(ranges); i += 3 {
+			if ranges[i] > ranges[i+1] || ranges[i+2] != len(glyphs) {
+				r.fail(errors.New("invalid coverage table"))
+				return nil
+			}
+			for g := ranges[i]; g <= ranges[i+1]; g++ {
+				glyphs = append(glyphs, sfnt.GlyphID(g))
+			}
+		}
+		return glyphs
+	}
+	r.fail(fmt.Errorf("unsupported coverage format %d", format))
+	return nil
+}
+
+// classDef maps glyphs to their class. Glyphs that are not in a classDef
+// are in class 0.
+type classDef map[sfnt.GlyphID]int
+
+// classDef reads the class definition table at pos.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#class-definition-table
+func (r *reader) classDef(pos int) classDef {
+	classes := make(classDef)
+	if pos == 0 {
+		return classes
+	}
+	switch format := r.u16(pos); format {
+	case 1:
+		start, count := r.u16(pos+2), r.u16(pos+4)
+		for i, class := range r.u16s(pos+6, count) {
+			if class != 0 {
+				classes[sfnt.GlyphID(start+i)] = class
+			}
+		}
+	case 2:
+		count := r.u16(pos + 2)
+		ranges := r.u16s(pos+4, 3*count)
+		for i := 0; i+2 < len(ranges); i += 3 {
+			for g := ranges[i]; g <= ranges[i+1] && ranges[i+2] != 0; g++ {
+				classes[sfnt.GlyphID(g)] = ranges[i+2]
+			}
+		}
+	default:
+		r.fail(fmt.Errorf("unsupported class definition format %d", format))
+	}
+	return classes
+}
+
+// device returns a copy of the device or variation index table at pos, or
+// nil if pos is 0. The table does not refer to glyphs, so it is copied as it is.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#device-and-variationindex-tables
+func (r *reader) device(pos int) *object {
+	if pos == 0 {
+		return nil
+	}
+	start, end, format := r.u16(pos), r.u16(pos+2), r.u16(pos+4)
+	size := 6
+	if format >= 1 && format <= 3 && end >= start {
+		bits := (end - start + 1) << format // 2, 4 or 8 bits for each size.
+		size += 2 * ((bits + 15) / 16)
+	}
+	return &object{data: r.bytes(pos, size)}
+}
+
+// GlyphMap maps the glyphs of the font to the glyphs of the subset. Glyphs
+// that are not in the subset are not in the map.
+type GlyphMap map[sfnt.GlyphID]sfnt.GlyphID
+
+// all returns the glyphs of the subset for glyphs, and false if any of them
+// is not in the subset.
+func (m GlyphMap) all(glyphs []sfnt.GlyphID) ([]sfnt.GlyphID, bool) {
+	mapped := make([]sfnt.GlyphID, len(glyphs))
+	for i, g := range glyphs {
+		var found bool
+		if mapped[i], found = m[g]; !found {
+			return nil, false
+		}
+	}
+	return mapped, true
+}
+
+// covered returns the glyphs of the subset for the glyphs of a coverage
+// table that are in the subset, sorted, along with their coverage indexes.
+func (m GlyphMap) covered(coverage []sfnt.GlyphID) ([]sfnt.GlyphID, []int) {
+	var glyphs []sfnt.GlyphID
+	var indexes []int
+	for i, g := range coverage {
+		if mapped, found := m[g]; found {
+			glyphs = append(glyphs, mapped)
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Sort(byGlyph{glyphs, indexes})
+	return glyphs, indexes
+}
+
+// classes returns the class definitions of the glyphs in the subset.
+func (m GlyphMap) classes(classes classDef) classDef {
+	mapped := make(classDef)
+	for g, class := range classes {
+		if n, found := m[g]; found {
+			mapped[n] = class
+		}
+	}
+	return mapped
+}
+
+// byGlyph sorts glyphs along with their indexes.
+type byGlyph struct {
+	glyphs  []sfnt.GlyphID
+	indexes []int
+}
+
+func (s byGlyph) Len() int           { return len(s.glyphs) }
+func (s byGlyph) Less(i, j int) bool { return s.glyphs[i] < s.glyphs[j] }
+func (s byGlyph) Swap(i, j int) {
+	s.glyphs[i], s.glyphs[j] = s.glyphs[j], s.glyphs[i]
+	s.indexes[i], s.indexes[j] = s.indexes[j], s.indexes[i]
+}
+
+// coverageObject returns a coverage table of glyphs, which must be sorted,
+// in whichever format is smaller.
+func coverageObject(glyphs []sfnt.GlyphID) *object {
+	var ranges []int
+	for i, g := range glyphs {
+		if n := len(ranges); n > 0 && int(g) == ranges[n-2]+1 {
+			ranges[n-2]++
+		} else {
+			ranges = append(ranges, int(g), int(g), i)
+		}
+	}
+	o := &object{}
+	if len(ranges) < len(glyphs) {
+		o.u16(2, len(ranges)/3)
+		o.u16(ranges...)
+		return o
+	}
+	o.u16(1, len(glyphs))
+	for _, g := range glyphs {
+		o.u16(int(g))
+	}
+	return o
+}
+
+// classDefObject returns a class definition table, in whichever format is smaller.
+func classDefObject(classes classDef) *object {
+	glyphs := make([]sfnt.GlyphID, 0, len(classes))
+	for g, class := range classes {
+		if class != 0 {
+			glyphs = append(glyphs, g)
+		}
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+
+	var ranges []int
+	for _, g := range glyphs {
+		if n := len(ranges); n > 0 && int(g) == ranges[n-2]+1 && classes[g] == ranges[n-1] {
+			ranges[n-2]++
+		} else {
+			ranges = append(ranges, int(g), int(g), classes[g])
+		}
+	}
+
+	o := &object{}
+	if len(glyphs) == 0 {
+		o.u16(1, 0, 0)
+		return o
+	}
+	start, end := int(glyphs[0]), int(glyphs[len(glyphs)-1])
+	if end-start+2 <= len(ranges) {
+		o.u16(1, start, end-start+1)
+		for g := start; g <= end; g++ {
+			o.u16(classes[sfnt.GlyphID(g)])
+		}
+		return o
+	}
+	o.u16(2, len(ranges)/3)
+	o.u16(ranges...)
+	return o
+}
@@ -0,0 +1,305 @@
+package layout
+
+import (
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// contextSubtable is a contextual or chained contextual subtable of 'GSUB'
+// or 'GPOS', which applies other lookups to sequences of glyphs.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#sequence-context-format-1-simple-glyph-contexts
+type contextSubtable struct {
+	chained bool
+	format  int
+
+	// coverage contains the first glyphs of the sequences, for formats 1
+	// and 2.
+	coverage []sfnt.GlyphID
+	// sets contains the rules for the first glyph of the sequences, by
+	// coverage index for format 1, and by class for format 2.
+	sets [][]contextRule
+	// classDefs contains the classes of the backtrack, input and lookahead
+	// glyphs, for format 2. Only input is used when the subtable is not chained.
+	classDefs [3]classDef
+
+	// coverages contains the coverages of the backtrack, input and lookahead
+	// glyphs, for format 3.
+	coverages [3][][]sfnt.GlyphID
+	records   []lookupRecord
+}
+
+// contextRule matches a sequence of glyphs, or of classes for format 2.
+type contextRule struct {
+	// sequences contains the backtrack glyphs, the input glyphs after the
+	// first, and the lookahead glyphs.
+	sequences [3][]int
+	records   []lookupRecord
+}
+
+// lookupRecord applies a lookup at an index of the input sequence.
+type lookupRecord struct {
+	index  int
+	lookup int
+}
+
+// Indexes of contextRule.sequences.
+const (
+	contextBacktrack = 0
+	contextInput     = 1
+	contextLookahead = 2
+)
+
+func (r *reader) context(pos int, chained bool) *contextSubtable {
+	s := &contextSubtable{chained: chained, format: r.u16(pos)}
+	switch s.format {
+	case 1, 2:
+		s.coverage = r.coverage(r.offset(pos, pos+2))
+		at := pos + 4
+		if s.format == 2 {
+			if chained {
+				for i := range s.classDefs {
+					s.classDefs[i] = r.classDef(r.offset(pos, at))
+					at += 2
+				}
+			} else {
+				s.classDefs[contextInput] = r.classDef(r.offset(pos, at))
+				at += 2
+			}
+		}
+		for i, n := 0, r.u16(at); i < n && r.err == nil; i++ {
+			var rules []contextRule
+			if set := r.offset(pos, at+2+2*i); set != 0 {
+				for j, count := 0, r.u16(set); j < count && r.err == nil; j++ {
+					rules = append(rules, r.contextRule(r.offset(set, set+2+2*j), chained))
+				}
+			}
+			s.sets = append(s.sets, rules)
+		}
+		if s.format == 1 && len(s.sets) != len(s.coverage) {
+			s.coverage, s.sets = nil, nil
+		}
+	case 3:
+		at := pos + 2
+		if chained {
+			for i := range s.coverages {
+				s.coverages[i], at = r.coverages(pos, at)
+			}
+			s.records = r.lookupRecords(at+2, r.u16(at))
+		} else {
+			n, count := r.u16(at), r.u16(at+2)
+			at += 4
+			for i := 0; i < n && r.err == nil; i++ {
+				s.coverages[contextInput] = append(s.coverages[contextInput], r.coverage(r.offset(pos, at+2*i)))
+			}
+			s.records = r.lookupRecords(at+2*n, count)
+		}
+	}
+	return s
+}
+
+func (r *reader) contextRule(pos int, chained bool) contextRule {
+	var rule contextRule
+	if !chained {
+		n, count := r.u16(pos), r.u16(pos+2)
+		if n == 0 {
+			r.fail(errInvalidOffset)
+			return rule
+		}
+		rule.sequences[contextInput] = r.u16s(pos+4, n-1)
+		rule.records = r.lookupRecords(pos+4+2*(n-1), count)
+		return rule
+	}
+	at := pos
+	for i := range rule.sequences {
+		n := r.u16(at)
+		if i == contextInput {
+			if n == 0 {
+				r.fail(errInvalidOffset)
+				return rule
+			}
+			n--
+		}
+		rule.sequences[i] = r.u16s(at+2, n)
+		at += 2 + 2*n
+	}
+	rule.records = r.lookupRecords(at+2, r.u16(at))
+	return rule
+}
+
+func (r *reader) lookupRecords(pos, n int) []lookupRecord {
+	values := r.u16s(pos, 2*n)
+	records := make([]lookupRecord, n)
+	for i := 0; i+1 < len(values); i += 2 {
+		records[i/2] = lookupRecord{index: values[i], lookup: values[i+1]}
+	}
+	return records
+}
+
+func (s *contextSubtable) nested() []int {
+	var lookups []int
+	for _, r := range s.records {
+		lookups = append(lookups, r.lookup)
+	}
+	for _, rules := range s.sets {
+		for _, rule := range rules {
+			for _, r := range rule.records {
+				lookups = append(lookups, r.lookup)
+			}
+		}
+	}
+	return lookups
+}
+
+func (s *contextSubtable) write(m *Mapping) *object {
+	switch s.format {
+	case 1:
+		return s.writeGlyphs(m)
+	case 2:
+		return s.writeClasses(m)
+	case 3:
+		return s.writeCoverages(m)
+	}
+	return nil
+}
+
+// writeGlyphs writes a subtable of format 1, keeping the rules whose glyphs
+// are all in the subset.
+func (s *contextSubtable) writeGlyphs(m *Mapping) *object {
+	glyphs, indexes := m.Glyphs.covered(s.coverage)
+	var covered []sfnt.GlyphID
+	var sets []*object
+	for i, g := range glyphs {
+		var rules []contextRule
+	rules:
+		for _, rule := range s.sets[indexes[i]] {
+			for j, sequence := range rule.sequences {
+				mapped := make([]int, len(sequence))
+				for k, v := range sequence {
+					n, found := m.Glyphs[sfnt.GlyphID(v)]
+					if !found {
+						continue rules
+					}
+					mapped[k] = int(n)
+				}
+				rule.sequences[j] = mapped
+			}
+			rules = append(rules, rule)
+		}
+		if len(rules) == 0 {
+			continue
+		}
+		covered = append(covered, g)
+		sets = append(sets, s.ruleSet(m, rules))
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+
+	o := &object{}
+	o.u16(1)
+	o.offset16(coverageObject(covered))
+	o.u16(len(sets))
+	for _, set := range sets {
+		o.offset16(set)
+	}
+	return o
+}
+
+// writeClasses writes a subtable of format 2. Classes are not renumbered,
+// but the rules for classes with no glyphs left are removed.
+func (s *contextSubtable) writeClasses(m *Mapping) *object {
+	covered, _ := m.Glyphs.covered(s.coverage)
+	if len(covered) == 0 {
+		return nil
+	}
+	var classDefs [3]classDef
+	for i, c := range s.classDefs {
+		classDefs[i] = m.Glyphs.classes(c)
+	}
+	used := map[int]bool{0: true}
+	for _, class := range classDefs[contextInput] {
+		used[class] = true
+	}
+
+	o := &object{}
+	o.u16(2)
+	o.offset16(coverageObject(covered))
+	if s.chained {
+		for _, c := range classDefs {
+			o.offset16(classDefObject(c))
+		}
+	} else {
+		o.offset16(classDefObject(classDefs[contextInput]))
+	}
+	o.u16(len(s.sets))
+	for class, rules := range s.sets {
+		if !used[class] || len(rules) == 0 {
+			o.offset16(nil)
+			continue
+		}
+		o.offset16(s.ruleSet(m, rules))
+	}
+	return o
+}
+
+func (s *contextSubtable) ruleSet(m *Mapping, rules []contextRule) *object {
+	o := &object{}
+	o.u16(len(rules))
+	for _, rule := range rules {
+		ro := &object{}
+		if s.chained {
+			for i, sequence := range rule.sequences {
+				n := len(sequence)
+				if i == contextInput {
+					n++
+				}
+				ro.u16(n)
+				ro.u16(sequence...)
+			}
+			ro.u16(len(rule.records))
+		} else {
+			input := rule.sequences[contextInput]
+			ro.u16(len(input)+1, len(rule.records))
+			ro.u16(input...)
+		}
+		writeLookupRecords(ro, m, rule.records)
+		o.offset16(ro)
+	}
+	return o
+}
+
+// writeCoverages writes a subtable of format 3, unless a coverage has no
+// glyphs left.
+func (s *contextSubtable) writeCoverages(m *Mapping) *object {
+	var coverages [3][]*object
+	for i, c := range s.coverages {
+		var ok bool
+		if coverages[i], ok = coverageObjects(m, c); !ok {
+			return nil
+		}
+	}
+
+	o := &object{}
+	o.u16(3)
+	if s.chained {
+		for _, c := range coverages {
+			o.u16(len(c))
+			for _, coverage := range c {
+				o.offset16(coverage)
+			}
+		}
+		o.u16(len(s.records))
+	} else {
+		o.u16(len(coverages[contextInput]), len(s.records))
+		for _, coverage := range coverages[contextInput] {
+			o.offset16(coverage)
+		}
+	}
+	writeLookupRecords(o, m, s.records)
+	return o
+}
+
+func writeLookupRecords(o *object, m *Mapping, records []lookupRecord) {
+	for _, r := range records {
+		o.u16(r.index, m.Lookups[r.lookup])
+	}
+}
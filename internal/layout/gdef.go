@@ -0,0 +1,217 @@
+package layout
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// GDEF is a parsed 'GDEF' table. The subtables that are absent from
+// the table are nil.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gdef
+type GDEF struct {
+	minor        int
+	glyphClasses classDef
+	// attachPoints contains a copy of the AttachPoint table of each glyph.
+	attachPoints map[sfnt.GlyphID]*object
+	// ligCarets contains the LigGlyph table of each ligature.
+	ligCarets         map[sfnt.GlyphID]*object
+	markAttachClasses classDef
+	markGlyphSets     [][]sfnt.GlyphID
+	varStore          *object
+}
+
+// ParseGDEF parses a 'GDEF' table.
+func ParseGDEF(buf []byte) (*GDEF, error) {
+	r := &reader{buf: buf}
+	major, minor := r.u16(0), r.u16(2)
+	if r.err == nil && (major != 1 || minor > 3) {
+		return nil, fmt.Errorf("unsupported version %d.%d", major, minor)
+	}
+
+	t := &GDEF{minor: minor}
+	if glyphClassDef := r.offset(0, 4); glyphClassDef != 0 {
+		t.glyphClasses = r.classDef(glyphClassDef)
+	}
+	t.attachPoints = r.attachList(r.offset(0, 6))
+	t.ligCarets = r.ligCaretList(r.offset(0, 8))
+	if markAttachClassDef := r.offset(0, 10); markAttachClassDef != 0 {
+		t.markAttachClasses = r.classDef(markAttachClassDef)
+	}
+	if minor >= 2 {
+		t.markGlyphSets = r.markGlyphSets(r.offset(0, 12))
+	}
+	if minor >= 3 {
+		if pos := r.offset32(0, 14); pos != 0 {
+			store, err := sfnt.ParseItemVariationStore(buf[pos:])
+			if err != nil {
+				return nil, fmt.Errorf("parsing item variation store: %w", err)
+			}
+			t.varStore = &object{data: store.Bytes()}
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return t, nil
+}
+
+// attachList returns the attachment points of each glyph of the AttachList
+// table at pos.
+func (r *reader) attachList(pos int) map[sfnt.GlyphID]*object {
+	if pos == 0 {
+		return nil
+	}
+	points := make(map[sfnt.GlyphID]*object)
+	count := r.u16(pos + 4)
+	for i, g := range r.coverage(r.offset(pos, pos+2)) {
+		if i >= count {
+			break
+		}
+		point := r.offset(pos, pos+6+2*i)
+		points[g] = &object{data: r.bytes(point, 2+2*r.u16(point))}
+	}
+	return points
+}
+
+// ligCaretList returns the caret positions of each ligature of the
+// LigCaretList table at pos.
+func (r *reader) ligCaretList(pos int) map[sfnt.GlyphID]*object {
+	if pos == 0 {
+		return nil
+	}
+	ligatures := make(map[sfnt.GlyphID]*object)
+	count := r.u16(pos + 4)
+	for i, g := range r.coverage(r.offset(pos, pos+2)) {
+		if i >= count || r.err != nil {
+			break
+		}
+		lig := r.offset(pos, pos+6+2*i)
+		o := &object{}
+		n := r.u16(lig)
+		o.u16(n)
+		for j := 0; j < n && r.err == nil; j++ {
+			o.offset16(r.caretValue(r.offset(lig, lig+2+2*j)))
+		}
+		ligatures[g] = o
+	}
+	return ligatures
+}
+
+func (r *reader) caretValue(pos int) *object {
+	switch format := r.u16(pos); format {
+	case 1, 2:
+		return &object{data: r.bytes(pos, 4)}
+	case 3:
+		o := &object{data: append([]byte(nil), r.bytes(pos, 4)...)}
+		o.offset16(r.device(r.offset(pos, pos+4)))
+		return o
+	default:
+		r.fail(fmt.Errorf("unsupported caret value format %d", format))
+	}
+	return nil
+}
+
+// markGlyphSets returns the glyphs of each set of the MarkGlyphSets table at pos.
+func (r *reader) markGlyphSets(pos int) [][]sfnt.GlyphID {
+	if pos == 0 {
+		return nil
+	}
+	n := r.u16(pos + 2)
+	sets := make([][]sfnt.GlyphID, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		sets = append(sets, r.coverage(r.offset32(pos, pos+4+4*i)))
+	}
+	return sets
+}
+
+// Mapped returns the table restricted to the glyphs of m, renumbered.
+// Empty mark glyph sets are kept, since lookups refer to the sets by index.
+func (t *GDEF) Mapped(m GlyphMap) *GDEF {
+	mapped := &GDEF{minor: t.minor, varStore: t.varStore}
+	if t.glyphClasses != nil {
+		mapped.glyphClasses = m.classes(t.glyphClasses)
+	}
+	mapped.attachPoints = m.objects(t.attachPoints)
+	mapped.ligCarets = m.objects(t.ligCarets)
+	if t.markAttachClasses != nil {
+		mapped.markAttachClasses = m.classes(t.markAttachClasses)
+	}
+	if t.markGlyphSets != nil {
+		mapped.markGlyphSets = make([][]sfnt.GlyphID, len(t.markGlyphSets))
+		for i, set := range t.markGlyphSets {
+			mapped.markGlyphSets[i], _ = m.covered(set)
+		}
+	}
+	return mapped
+}
+
+// objects returns the objects of the glyphs in the subset, or nil if
+// objects is nil.
+func (m GlyphMap) objects(objects map[sfnt.GlyphID]*object) map[sfnt.GlyphID]*object {
+	if objects == nil {
+		return nil
+	}
+	mapped := make(map[sfnt.GlyphID]*object)
+	for g, o := range objects {
+		if n, found := m[g]; found {
+			mapped[n] = o
+		}
+	}
+	return mapped
+}
+
+// Bytes returns the encoded table.
+func (t *GDEF) Bytes() ([]byte, error) {
+	o := &object{}
+	o.u16(1, t.minor)
+	if t.glyphClasses != nil {
+		o.offset16(classDefObject(t.glyphClasses))
+	} else {
+		o.offset16(nil)
+	}
+	o.offset16(glyphObjectList(t.attachPoints))
+	o.offset16(glyphObjectList(t.ligCarets))
+	if t.markAttachClasses != nil {
+		o.offset16(classDefObject(t.markAttachClasses))
+	} else {
+		o.offset16(nil)
+	}
+	if t.minor >= 2 {
+		var sets *object
+		if t.markGlyphSets != nil {
+			sets = &object{}
+			sets.u16(1, len(t.markGlyphSets))
+			for _, set := range t.markGlyphSets {
+				sets.offset32(coverageObject(set))
+			}
+		}
+		o.offset16(sets)
+	}
+	if t.minor >= 3 {
+		o.offset32(t.varStore)
+	}
+	return pack(o)
+}
+
+// glyphObjectList returns an AttachList or LigCaretList table of the
+// objects of each glyph, or nil if there are none.
+func glyphObjectList(objects map[sfnt.GlyphID]*object) *object {
+	if len(objects) == 0 {
+		return nil
+	}
+	glyphs := make([]sfnt.GlyphID, 0, len(objects))
+	for g := range objects {
+		glyphs = append(glyphs, g)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+
+	o := &object{}
+	o.offset16(coverageObject(glyphs))
+	o.u16(len(glyphs))
+	for _, g := range glyphs {
+		o.offset16(objects[g])
+	}
+	return o
+}
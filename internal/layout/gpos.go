@@ -0,0 +1,515 @@
+package layout
+
+import (
+	"sort"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// Lookup types in 'GPOS'.
+const (
+	gposSingle       = 1
+	gposPair         = 2
+	gposCursive      = 3
+	gposMarkToBase   = 4
+	gposMarkToLig    = 5
+	gposMarkToMark   = 6
+	gposContext      = 7
+	gposChainContext = 8
+)
+
+// gposSubtable reads a 'GPOS' subtable, returning nil for unknown types.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gpos
+func (r *reader) gposSubtable(typ, pos int) subtable {
+	switch typ {
+	case gposSingle:
+		return r.singlePos(pos)
+	case gposPair:
+		return r.pairPos(pos)
+	case gposCursive:
+		return r.cursivePos(pos)
+	case gposMarkToBase, gposMarkToLig, gposMarkToMark:
+		return r.markPos(pos, typ)
+	case gposContext:
+		return r.context(pos, false)
+	case gposChainContext:
+		return r.context(pos, true)
+	}
+	return nil
+}
+
+// valueRecord adjusts the position of a glyph. It has a value for each bit
+// set in the value format, which is an offset to a device table for the
+// four upper bits.
+type valueRecord struct {
+	values  []int
+	devices []*object
+}
+
+// Bits of value formats.
+const (
+	valueFormatDevices = 0x00F0
+	valueFormatAll     = 0x00FF
+)
+
+// valueRecord reads a value record at pos, with the offsets of its devices
+// relative to base, returning the position after it.
+func (r *reader) valueRecord(base, pos, format int) (valueRecord, int) {
+	var v valueRecord
+	for bit := 1; bit <= 0x80; bit <<= 1 {
+		if format&bit == 0 {
+			continue
+		}
+		if bit&valueFormatDevices != 0 {
+			v.devices = append(v.devices, r.device(r.offset(base, pos)))
+		} else {
+			v.values = append(v.values, r.u16(pos))
+		}
+		pos += 2
+	}
+	return v, pos
+}
+
+// write appends the value record, with its devices relative to o.
+func (v valueRecord) write(o *object) {
+	o.u16(v.values...)
+	for _, d := range v.devices {
+		o.offset16(d)
+	}
+}
+
+// singlePos adjusts the position of each glyph of coverage.
+type singlePos struct {
+	valueFormat int
+	coverage    []sfnt.GlyphID
+	// values contains one value for each glyph, or one for all of them.
+	values []valueRecord
+}
+
+func (r *reader) singlePos(pos int) *singlePos {
+	s := &singlePos{valueFormat: r.u16(pos+4) & valueFormatAll}
+	switch format := r.u16(pos); format {
+	case 1:
+		s.coverage = r.coverage(r.offset(pos, pos+2))
+		v, _ := r.valueRecord(pos, pos+6, s.valueFormat)
+		s.values = []valueRecord{v}
+	case 2:
+		s.coverage = r.coverage(r.offset(pos, pos+2))
+		at := pos + 8
+		for i, n := 0, r.u16(pos+6); i < n && r.err == nil; i++ {
+			var v valueRecord
+			v, at = r.valueRecord(pos, at, s.valueFormat)
+			s.values = append(s.values, v)
+		}
+		if len(s.values) != len(s.coverage) {
+			s.coverage = nil
+		}
+	}
+	return s
+}
+
+func (s *singlePos) write(m *Mapping) *object {
+	glyphs, indexes := m.Glyphs.covered(s.coverage)
+	if len(glyphs) == 0 {
+		return nil
+	}
+	o := &object{}
+	if len(s.values) == 1 {
+		o.u16(1)
+		o.offset16(coverageObject(glyphs))
+		o.u16(s.valueFormat)
+		s.values[0].write(o)
+		return o
+	}
+	o.u16(2)
+	o.offset16(coverageObject(glyphs))
+	o.u16(s.valueFormat, len(glyphs))
+	for _, i := range indexes {
+		s.values[i].write(o)
+	}
+	return o
+}
+
+// pairPos adjusts the positions of pairs of glyphs, whose first glyph is in
+// coverage.
+type pairPos struct {
+	format       int
+	valueFormats [2]int
+	coverage     []sfnt.GlyphID
+
+	// sets contains the pairs for each glyph of coverage, for format 1.
+	sets [][]pairValue
+
+	// classDefs contains the classes of the first and second glyphs, and
+	// values the values for each pair of classes, for format 2.
+	classDefs [2]classDef
+	values    [][][2]valueRecord
+}
+
+type pairValue struct {
+	second sfnt.GlyphID
+	values [2]valueRecord
+}
+
+func (r *reader) pairPos(pos int) *pairPos {
+	s := &pairPos{format: r.u16(pos)}
+	s.coverage = r.coverage(r.offset(pos, pos+2))
+	s.valueFormats = [2]int{r.u16(pos+4) & valueFormatAll, r.u16(pos+6) & valueFormatAll}
+	switch s.format {
+	case 1:
+		for i, n := 0, r.u16(pos+8); i < n && r.err == nil; i++ {
+			var pairs []pairValue
+			set := r.offset(pos, pos+10+2*i)
+			at := set + 2
+			for j, count := 0, r.u16(set); j < count && r.err == nil; j++ {
+				p := pairValue{second: sfnt.GlyphID(r.u16(at))}
+				p.values[0], at = r.valueRecord(set, at+2, s.valueFormats[0])
+				p.values[1], at = r.valueRecord(set, at, s.valueFormats[1])
+				pairs = append(pairs, p)
+			}
+			s.sets = append(s.sets, pairs)
+		}
+		if len(s.sets) != len(s.coverage) {
+			s.coverage = nil
+		}
+	case 2:
+		s.classDefs[0] = r.classDef(r.offset(pos, pos+8))
+		s.classDefs[1] = r.classDef(r.offset(pos, pos+10))
+		count1, count2 := r.u16(pos+12), r.u16(pos+14)
+		if count1 == 0 || count2 == 0 {
+			r.fail(errInvalidOffset)
+		}
+		at := pos + 16
+		for i := 0; i < count1 && r.err == nil; i++ {
+			values := make([][2]valueRecord, count2)
+			for j := range values {
+				values[j][0], at = r.valueRecord(pos, at, s.valueFormats[0])
+				values[j][1], at = r.valueRecord(pos, at, s.valueFormats[1])
+			}
+			s.values = append(s.values, values)
+		}
+		for _, c := range s.classDefs[0] {
+			if c >= count1 {
+				r.fail(errInvalidOffset)
+			}
+		}
+		for _, c := range s.classDefs[1] {
+			if c >= count2 {
+				r.fail(errInvalidOffset)
+			}
+		}
+	default:
+		s.coverage = nil
+	}
+	return s
+}
+
+func (s *pairPos) write(m *Mapping) *object {
+	if s.format == 1 {
+		return s.writeGlyphs(m)
+	}
+	return s.writeClasses(m)
+}
+
+func (s *pairPos) writeGlyphs(m *Mapping) *object {
+	glyphs, indexes := m.Glyphs.covered(s.coverage)
+	var covered []sfnt.GlyphID
+	var sets []*object
+	for i, g := range glyphs {
+		var pairs []pairValue
+		for _, p := range s.sets[indexes[i]] {
+			if second, found := m.Glyphs[p.second]; found {
+				p.second = second
+				pairs = append(pairs, p)
+			}
+		}
+		if len(pairs) == 0 {
+			continue
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].second < pairs[j].second })
+		set := &object{}
+		set.u16(len(pairs))
+		for _, p := range pairs {
+			set.u16(int(p.second))
+			p.values[0].write(set)
+			p.values[1].write(set)
+		}
+		covered = append(covered, g)
+		sets = append(sets, set)
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+
+	o := &object{}
+	o.u16(1)
+	o.offset16(coverageObject(covered))
+	o.u16(s.valueFormats[0], s.valueFormats[1], len(sets))
+	for _, set := range sets {
+		o.offset16(set)
+	}
+	return o
+}
+
+// writeClasses writes a subtable of format 2, removing the classes with no
+// glyphs left.
+func (s *pairPos) writeClasses(m *Mapping) *object {
+	covered, _ := m.Glyphs.covered(s.coverage)
+	if len(covered) == 0 {
+		return nil
+	}
+	var classDefs [2]classDef
+	var classes [2][]int
+	for i, c := range s.classDefs {
+		classDefs[i], classes[i] = renumberClasses(m.Glyphs.classes(c))
+	}
+
+	o := &object{}
+	o.u16(2)
+	o.offset16(coverageObject(covered))
+	o.u16(s.valueFormats[0], s.valueFormats[1])
+	o.offset16(classDefObject(classDefs[0]))
+	o.offset16(classDefObject(classDefs[1]))
+	o.u16(len(classes[0]), len(classes[1]))
+	for _, c1 := range classes[0] {
+		for _, c2 := range classes[1] {
+			s.values[c1][c2][0].write(o)
+			s.values[c1][c2][1].write(o)
+		}
+	}
+	return o
+}
+
+// renumberClasses renumbers the classes of a class definition in order,
+// keeping class 0, and returns the old class of each new class.
+func renumberClasses(classes classDef) (classDef, []int) {
+	used := map[int]bool{0: true}
+	for _, c := range classes {
+		used[c] = true
+	}
+	old := make([]int, 0, len(used))
+	for c := range used {
+		old = append(old, c)
+	}
+	sort.Ints(old)
+	renumbered := make(map[int]int, len(old))
+	for n, c := range old {
+		renumbered[c] = n
+	}
+	mapped := make(classDef, len(classes))
+	for g, c := range classes {
+		mapped[g] = renumbered[c]
+	}
+	return mapped, old
+}
+
+// anchor returns a copy of the anchor table at pos, or nil if pos is 0.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gpos#anchor-tables
+func (r *reader) anchor(pos int) *object {
+	if pos == 0 {
+		return nil
+	}
+	switch format := r.u16(pos); format {
+	case 1:
+		return &object{data: r.bytes(pos, 6)}
+	case 2:
+		return &object{data: r.bytes(pos, 8)}
+	case 3:
+		o := &object{data: append([]byte(nil), r.bytes(pos, 6)...)}
+		o.offset16(r.device(r.offset(pos, pos+6)))
+		o.offset16(r.device(r.offset(pos, pos+8)))
+		return o
+	default:
+		r.fail(errInvalidOffset)
+	}
+	return nil
+}
+
+// anchors reads n offsets to anchors relative to base.
+func (r *reader) anchors(base, pos, n int) []*object {
+	anchors := make([]*object, n)
+	for i := range anchors {
+		anchors[i] = r.anchor(r.offset(base, pos+2*i))
+	}
+	return anchors
+}
+
+// cursivePos connects the exit anchor of each glyph of coverage to the
+// entry anchor of the next.
+type cursivePos struct {
+	coverage []sfnt.GlyphID
+	anchors  [][]*object // anchors contains the entry and exit anchors of each glyph.
+}
+
+func (r *reader) cursivePos(pos int) *cursivePos {
+	s := &cursivePos{}
+	if r.u16(pos) != 1 {
+		return s
+	}
+	coverage := r.coverage(r.offset(pos, pos+2))
+	for i, n := 0, r.u16(pos+4); i < n && r.err == nil; i++ {
+		s.anchors = append(s.anchors, r.anchors(pos, pos+6+4*i, 2))
+	}
+	if len(s.anchors) == len(coverage) {
+		s.coverage = coverage
+	}
+	return s
+}
+
+func (s *cursivePos) write(m *Mapping) *object {
+	glyphs, indexes := m.Glyphs.covered(s.coverage)
+	if len(glyphs) == 0 {
+		return nil
+	}
+	o := &object{}
+	o.u16(1)
+	o.offset16(coverageObject(glyphs))
+	o.u16(len(glyphs))
+	for _, i := range indexes {
+		o.offset16(s.anchors[i][0])
+		o.offset16(s.anchors[i][1])
+	}
+	return o
+}
+
+// markPos attaches marks to bases, ligatures or other marks.
+type markPos struct {
+	typ          int
+	markCoverage []sfnt.GlyphID
+	marks        []markRecord
+	baseCoverage []sfnt.GlyphID
+	// bases contains the anchors of each base for each mark class, by
+	// component for ligatures, and as a single component otherwise.
+	bases [][][]*object
+}
+
+type markRecord struct {
+	class  int
+	anchor *object
+}
+
+func (r *reader) markPos(pos, typ int) *markPos {
+	s := &markPos{typ: typ}
+	if r.u16(pos) != 1 {
+		return s
+	}
+	markCoverage := r.coverage(r.offset(pos, pos+2))
+	baseCoverage := r.coverage(r.offset(pos, pos+4))
+	classCount := r.u16(pos + 6)
+	markArray, baseArray := r.offset(pos, pos+8), r.offset(pos, pos+10)
+	for i, n := 0, r.u16(markArray); i < n && r.err == nil; i++ {
+		record := markArray + 2 + 4*i
+		class := r.u16(record)
+		if class >= classCount {
+			r.fail(errInvalidOffset)
+		}
+		s.marks = append(s.marks, markRecord{class: class, anchor: r.anchor(r.offset(markArray, record+2))})
+	}
+	for i, n := 0, r.u16(baseArray); i < n && r.err == nil; i++ {
+		if typ != gposMarkToLig {
+			record := baseArray + 2 + 2*classCount*i
+			s.bases = append(s.bases, [][]*object{r.anchors(baseArray, record, classCount)})
+			continue
+		}
+		var components [][]*object
+		attach := r.offset(baseArray, baseArray+2+2*i)
+		for j, count := 0, r.u16(attach); j < count && r.err == nil; j++ {
+			components = append(components, r.anchors(attach, attach+2+2*classCount*j, classCount))
+		}
+		s.bases = append(s.bases, components)
+	}
+	if len(s.marks) == len(markCoverage) && len(s.bases) == len(baseCoverage) {
+		s.markCoverage, s.baseCoverage = markCoverage, baseCoverage
+	}
+	return s
+}
+
+// write writes the subtable, removing the mark classes with no marks left.
+func (s *markPos) write(m *Mapping) *object {
+	marks, markIndexes := m.Glyphs.covered(s.markCoverage)
+	bases, baseIndexes := m.Glyphs.covered(s.baseCoverage)
+	if len(marks) == 0 || len(bases) == 0 {
+		return nil
+	}
+	used := make(map[int]bool)
+	for _, i := range markIndexes {
+		used[s.marks[i].class] = true
+	}
+	classes := make([]int, 0, len(used))
+	for c := range used {
+		classes = append(classes, c)
+	}
+	sort.Ints(classes)
+	renumbered := make(map[int]int, len(classes))
+	for n, c := range classes {
+		renumbered[c] = n
+	}
+
+	markArray := &object{}
+	markArray.u16(len(marks))
+	for _, i := range markIndexes {
+		markArray.u16(renumbered[s.marks[i].class])
+		markArray.offset16(s.marks[i].anchor)
+	}
+
+	baseArray := &object{}
+	baseArray.u16(len(bases))
+	for _, i := range baseIndexes {
+		if s.typ != gposMarkToLig {
+			for _, c := range classes {
+				baseArray.offset16(s.bases[i][0][c])
+			}
+			continue
+		}
+		attach := &object{}
+		attach.u16(len(s.bases[i]))
+		for _, component := range s.bases[i] {
+			for _, c := range classes {
+				attach.offset16(component[c])
+			}
+		}
+		baseArray.offset16(attach)
+	}
+
+	o := &object{}
+	o.u16(1)
+	o.offset16(coverageObject(marks))
+	o.offset16(coverageObject(bases))
+	o.u16(len(classes))
+	o.offset16(markArray)
+	o.offset16(baseArray)
+	return o
+}
+
+// Kerning returns the x advance adjustment of the first pair positioning
+// subtable of the 'kern' feature that applies to a pair of glyphs, or false
+// if there is none.
+func (t *Table) Kerning(first, second sfnt.GlyphID) (int, bool) {
+	for _, f := range t.features {
+		if f.tag != sfnt.MustNamedTag("kern") {
+			continue
+		}
+		for _, i := range f.lookups {
+			for _, s := range t.lookups[i].subtables {
+				s, ok := s.(*pairPos)
+				if !ok || s.valueFormats[0] != 0x0004 {
+					continue
+				}
+				for j, g := range s.coverage {
+					if g != first {
+						continue
+					}
+					if s.format == 2 {
+						return s.values[s.classDefs[0][first]][s.classDefs[1][second]][0].values[0], true
+					}
+					for _, p := range s.sets[j] {
+						if p.second == second {
+							return p.values[0].values[0], true
+						}
+					}
+				}
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,424 @@
+package layout
+
+import (
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// Lookup types in 'GSUB'.
+const (
+	gsubSingle         = 1
+	gsubMultiple       = 2
+	gsubAlternate      = 3
+	gsubLigature       = 4
+	gsubContext        = 5
+	gsubChainContext   = 6
+	gsubReverseChained = 8
+)
+
+// gsubSubtable reads a 'GSUB' subtable, returning nil for unknown types.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gsub
+func (r *reader) gsubSubtable(typ, pos int) subtable {
+	switch typ {
+	case gsubSingle:
+		return r.singleSubst(pos)
+	case gsubMultiple, gsubAlternate:
+		return r.sequenceSubst(pos, typ == gsubAlternate)
+	case gsubLigature:
+		return r.ligatureSubst(pos)
+	case gsubContext:
+		return r.context(pos, false)
+	case gsubChainContext:
+		return r.context(pos, true)
+	case gsubReverseChained:
+		return r.reverseChainedSubst(pos)
+	}
+	return nil
+}
+
+// singleSubst replaces each glyph of coverage with the glyph at the same
+// index in substitutes.
+type singleSubst struct {
+	coverage    []sfnt.GlyphID
+	substitutes []sfnt.GlyphID
+}
+
+func (r *reader) singleSubst(pos int) *singleSubst {
+	s := &singleSubst{coverage: r.coverage(r.offset(pos, pos+2))}
+	switch format := r.u16(pos); format {
+	case 1:
+		delta := r.u16(pos + 4)
+		for _, g := range s.coverage {
+			s.substitutes = append(s.substitutes, sfnt.GlyphID(int(g)+delta))
+		}
+	case 2:
+		s.substitutes = r.glyphs(pos+6, r.u16(pos+4))
+		if len(s.substitutes) != len(s.coverage) {
+			s.substitutes, s.coverage = nil, nil
+		}
+	default:
+		s.coverage = nil
+	}
+	return s
+}
+
+func (s *singleSubst) closure(set GlyphSet) {
+	for i, g := range s.coverage {
+		if set[g] {
+			set[s.substitutes[i]] = true
+		}
+	}
+}
+
+func (s *singleSubst) write(m *Mapping) *object {
+	glyphs, indexes := m.Glyphs.covered(s.coverage)
+	var covered, substitutes []sfnt.GlyphID
+	for i, g := range glyphs {
+		if sub, found := m.Glyphs[s.substitutes[indexes[i]]]; found {
+			covered = append(covered, g)
+			substitutes = append(substitutes, sub)
+		}
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+
+	o := &object{}
+	delta := uint16(substitutes[0] - covered[0])
+	same := true
+	for i, g := range covered {
+		same = same && uint16(substitutes[i]-g) == delta
+	}
+	if same {
+		o.u16(1)
+		o.offset16(coverageObject(covered))
+		o.u16(int(delta))
+		return o
+	}
+	o.u16(2)
+	o.offset16(coverageObject(covered))
+	o.u16(len(substitutes))
+	for _, g := range substitutes {
+		o.u16(int(g))
+	}
+	return o
+}
+
+// sequenceSubst replaces each glyph of coverage with the sequence of glyphs
+// at the same index in sequences, or with one of them for alternates.
+type sequenceSubst struct {
+	alternate bool
+	coverage  []sfnt.GlyphID
+	sequences [][]sfnt.GlyphID
+}
+
+func (r *reader) sequenceSubst(pos int, alternate bool) *sequenceSubst {
+	s := &sequenceSubst{alternate: alternate}
+	if r.u16(pos) != 1 {
+		return s
+	}
+	s.coverage = r.coverage(r.offset(pos, pos+2))
+	count := r.u16(pos + 4)
+	if count != len(s.coverage) {
+		s.coverage = nil
+		return s
+	}
+	for i := 0; i < count && r.err == nil; i++ {
+		sequence := r.offset(pos, pos+6+2*i)
+		s.sequences = append(s.sequences, r.glyphs(sequence+2, r.u16(sequence)))
+	}
+	return s
+}
+
+func (s *sequenceSubst) closure(set GlyphSet) {
+	for i, g := range s.coverage {
+		if set[g] {
+			for _, sub := range s.sequences[i] {
+				set[sub] = true
+			}
+		}
+	}
+}
+
+func (s *sequenceSubst) write(m *Mapping) *object {
+	glyphs, indexes := m.Glyphs.covered(s.coverage)
+	var covered []sfnt.GlyphID
+	var sequences []*object
+	for i, g := range glyphs {
+		var sequence []sfnt.GlyphID
+		if s.alternate {
+			for _, sub := range s.sequences[indexes[i]] {
+				if n, found := m.Glyphs[sub]; found {
+					sequence = append(sequence, n)
+				}
+			}
+			if len(sequence) == 0 {
+				continue
+			}
+		} else {
+			var ok bool
+			if sequence, ok = m.Glyphs.all(s.sequences[indexes[i]]); !ok {
+				continue
+			}
+		}
+		covered = append(covered, g)
+		sequences = append(sequences, glyphArray(sequence))
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+
+	o := &object{}
+	o.u16(1)
+	o.offset16(coverageObject(covered))
+	o.u16(len(sequences))
+	for _, sequence := range sequences {
+		o.offset16(sequence)
+	}
+	return o
+}
+
+// glyphArray returns a count followed by the glyphs.
+func glyphArray(glyphs []sfnt.GlyphID) *object {
+	o := &object{}
+	o.u16(len(glyphs))
+	for _, g := range glyphs {
+		o.u16(int(g))
+	}
+	return o
+}
+
+// ligatureSubst replaces sequences of glyphs starting with each glyph of
+// coverage with ligatures.
+type ligatureSubst struct {
+	coverage []sfnt.GlyphID
+	sets     [][]ligature
+}
+
+type ligature struct {
+	glyph sfnt.GlyphID
+	// components contains the glyphs of the sequence after the first.
+	components []sfnt.GlyphID
+}
+
+func (r *reader) ligatureSubst(pos int) *ligatureSubst {
+	s := &ligatureSubst{}
+	if r.u16(pos) != 1 {
+		return s
+	}
+	s.coverage = r.coverage(r.offset(pos, pos+2))
+	count := r.u16(pos + 4)
+	if count != len(s.coverage) {
+		s.coverage = nil
+		return s
+	}
+	for i := 0; i < count && r.err == nil; i++ {
+		set := r.offset(pos, pos+6+2*i)
+		var ligatures []ligature
+		for j, n := 0, r.u16(set); j < n && r.err == nil; j++ {
+			lig := r.offset(set, set+2+2*j)
+			numComponents := r.u16(lig + 2)
+			if numComponents == 0 {
+				r.fail(errInvalidOffset)
+				break
+			}
+			ligatures = append(ligatures, ligature{
+				glyph:      sfnt.GlyphID(r.u16(lig)),
+				components: r.glyphs(lig+4, numComponents-1),
+			})
+		}
+		s.sets = append(s.sets, ligatures)
+	}
+	return s
+}
+
+func (s *ligatureSubst) closure(set GlyphSet) {
+	for i, g := range s.coverage {
+		if !set[g] {
+			continue
+		}
+		for _, lig := range s.sets[i] {
+			all := true
+			for _, c := range lig.components {
+				all = all && set[c]
+			}
+			if all {
+				set[lig.glyph] = true
+			}
+		}
+	}
+}
+
+func (s *ligatureSubst) write(m *Mapping) *object {
+	glyphs, indexes := m.Glyphs.covered(s.coverage)
+	var covered []sfnt.GlyphID
+	var sets []*object
+	for i, g := range glyphs {
+		set := &object{}
+		var ligatures []*object
+		for _, lig := range s.sets[indexes[i]] {
+			glyph, found := m.Glyphs[lig.glyph]
+			components, ok := m.Glyphs.all(lig.components)
+			if !found || !ok {
+				continue
+			}
+			o := &object{}
+			o.u16(int(glyph), len(components)+1)
+			for _, c := range components {
+				o.u16(int(c))
+			}
+			ligatures = append(ligatures, o)
+		}
+		if len(ligatures) == 0 {
+			continue
+		}
+		set.u16(len(ligatures))
+		for _, lig := range ligatures {
+			set.offset16(lig)
+		}
+		covered = append(covered, g)
+		sets = append(sets, set)
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+
+	o := &object{}
+	o.u16(1)
+	o.offset16(coverageObject(covered))
+	o.u16(len(sets))
+	for _, set := range sets {
+		o.offset16(set)
+	}
+	return o
+}
+
+// reverseChainedSubst replaces each glyph of coverage with the glyph at the
+// same index in substitutes, when it is between glyphs matching the
+// backtrack and lookahead coverages. It is applied from the end of the text.
+type reverseChainedSubst struct {
+	coverage             []sfnt.GlyphID
+	backtrack, lookahead [][]sfnt.GlyphID
+	substitutes          []sfnt.GlyphID
+}
+
+func (r *reader) reverseChainedSubst(pos int) *reverseChainedSubst {
+	s := &reverseChainedSubst{}
+	if r.u16(pos) != 1 {
+		return s
+	}
+	coverage := r.coverage(r.offset(pos, pos+2))
+	at := pos + 4
+	s.backtrack, at = r.coverages(pos, at)
+	s.lookahead, at = r.coverages(pos, at)
+	s.substitutes = r.glyphs(at+2, r.u16(at))
+	if len(s.substitutes) == len(coverage) {
+		s.coverage = coverage
+	}
+	return s
+}
+
+// coverages reads a count followed by offsets to coverage tables from base,
+// returning the coverages and the position after the offsets.
+func (r *reader) coverages(base, pos int) ([][]sfnt.GlyphID, int) {
+	n := r.u16(pos)
+	var coverages [][]sfnt.GlyphID
+	for i := 0; i < n && r.err == nil; i++ {
+		coverages = append(coverages, r.coverage(r.offset(base, pos+2+2*i)))
+	}
+	return coverages, pos + 2 + 2*n
+}
+
+func (s *reverseChainedSubst) closure(set GlyphSet) {
+	for i, g := range s.coverage {
+		if set[g] {
+			set[s.substitutes[i]] = true
+		}
+	}
+}
+
+func (s *reverseChainedSubst) write(m *Mapping) *object {
+	glyphs, indexes := m.Glyphs.covered(s.coverage)
+	var covered, substitutes []sfnt.GlyphID
+	for i, g := range glyphs {
+		if sub, found := m.Glyphs[s.substitutes[indexes[i]]]; found {
+			covered = append(covered, g)
+			substitutes = append(substitutes, sub)
+		}
+	}
+	if len(covered) == 0 {
+		return nil
+	}
+	backtrack, ok := coverageObjects(m, s.backtrack)
+	if !ok {
+		return nil
+	}
+	lookahead, ok := coverageObjects(m, s.lookahead)
+	if !ok {
+		return nil
+	}
+
+	o := &object{}
+	o.u16(1)
+	o.offset16(coverageObject(covered))
+	for _, coverages := range [][]*object{backtrack, lookahead} {
+		o.u16(len(coverages))
+		for _, c := range coverages {
+			o.offset16(c)
+		}
+	}
+	o.u16(len(substitutes))
+	for _, g := range substitutes {
+		o.u16(int(g))
+	}
+	return o
+}
+
+// coverageObjects returns the coverage tables restricted to the subset, and
+// false if any of them has no glyphs left.
+func coverageObjects(m *Mapping, coverages [][]sfnt.GlyphID) ([]*object, bool) {
+	objects := make([]*object, len(coverages))
+	for i, c := range coverages {
+		glyphs, _ := m.Glyphs.covered(c)
+		if len(glyphs) == 0 {
+			return nil, false
+		}
+		objects[i] = coverageObject(glyphs)
+	}
+	return objects, true
+}
+
+// Ligature returns the ligature of glyphs in the 'liga' feature, or false if
+// there is none.
+func (t *Table) Ligature(glyphs ...sfnt.GlyphID) (sfnt.GlyphID, bool) {
+	for _, f := range t.features {
+		if f.tag != sfnt.MustNamedTag("liga") {
+			continue
+		}
+		for _, i := range f.lookups {
+			for _, s := range t.lookups[i].subtables {
+				s, ok := s.(*ligatureSubst)
+				if !ok {
+					continue
+				}
+				for j, g := range s.coverage {
+					if g != glyphs[0] {
+						continue
+					}
+				ligatures:
+					for _, lig := range s.sets[j] {
+						if len(lig.components) != len(glyphs)-1 {
+							continue
+						}
+						for k, c := range lig.components {
+							if c != glyphs[k+1] {
+								continue ligatures
+							}
+						}
+						return lig.glyph, true
+					}
+				}
+			}
+		}
+	}
+	return 0, false
+}
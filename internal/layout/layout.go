@@ -0,0 +1,630 @@
+// Package layout reads, subsets and merges the OpenType layout tables
+// 'GSUB', 'GPOS' and 'GDEF', for the subset and merge packages.
+package layout
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// Table is a parsed 'GSUB' or 'GPOS' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2
+type Table struct {
+	Tag        sfnt.Tag
+	scripts    []script
+	features   []feature
+	lookups    []lookup
+	variations []featureVariation
+}
+
+type script struct {
+	tag         sfnt.Tag
+	defaultLang *langSys
+	langs       []langSys
+}
+
+type langSys struct {
+	tag      sfnt.Tag
+	required int // required is the index of the required feature, or -1.
+	features []int
+}
+
+type feature struct {
+	tag     sfnt.Tag
+	params  *object // params is a copy of the FeatureParams table, or nil.
+	lookups []int
+}
+
+type lookup struct {
+	typ              int // typ is the type of the subtables, which is never an extension.
+	flag             int
+	markFilteringSet int
+	subtables        []subtable
+}
+
+// featureVariation replaces some of the features of the table when the
+// font is at a location matching its conditions.
+type featureVariation struct {
+	conditions    []*object
+	substitutions []featureSubstitution
+}
+
+type featureSubstitution struct {
+	index   int // index is the index of the feature that is replaced.
+	feature feature
+}
+
+// Flags of lookups.
+const (
+	lookupUseMarkFilteringSet = 0x0010
+)
+
+// subtable is a lookup subtable.
+type subtable interface {
+	// write returns the subtable restricted to the glyphs of the subset, or
+	// nil if no glyphs of the subset are affected by it.
+	write(m *Mapping) *object
+}
+
+// substitution is a subtable of 'GSUB', which can add glyphs to a subset.
+type substitution interface {
+	subtable
+	// closure adds the glyphs that may be substituted for the glyphs in set.
+	closure(set GlyphSet)
+}
+
+// contextual is a subtable that applies other lookups.
+type contextual interface {
+	subtable
+	nested() []int
+}
+
+// Mapping maps the glyphs and lookups of a font to those of its subset.
+type Mapping struct {
+	Glyphs  GlyphMap
+	Lookups map[int]int
+}
+
+// GlyphSet contains the glyphs of a subset.
+type GlyphSet map[sfnt.GlyphID]bool
+
+// Parse parses a 'GSUB' or 'GPOS' table.
+func Parse(tag sfnt.Tag, buf []byte) (*Table, error) {
+	r := &reader{buf: buf}
+	t := &Table{Tag: tag}
+	major, minor := r.u16(0), r.u16(2)
+	if r.err == nil && (major != 1 || minor > 1) {
+		return nil, fmt.Errorf("unsupported version %d.%d", major, minor)
+	}
+	scriptList, featureList, lookupList := r.offset(0, 4), r.offset(0, 6), r.offset(0, 8)
+	variations := 0
+	if minor == 1 {
+		variations = r.offset32(0, 10)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if scriptList != 0 {
+		for i, n := 0, r.u16(scriptList); i < n && r.err == nil; i++ {
+			record := scriptList + 2 + 6*i
+			t.scripts = append(t.scripts, r.script(sfnt.Tag{Number: uint32(r.u32(record))}, r.offset(scriptList, record+4)))
+		}
+	}
+	if featureList != 0 {
+		for i, n := 0, r.u16(featureList); i < n && r.err == nil; i++ {
+			record := featureList + 2 + 6*i
+			tag := sfnt.Tag{Number: uint32(r.u32(record))}
+			t.features = append(t.features, r.feature(tag, r.offset(featureList, record+4)))
+		}
+	}
+	if lookupList != 0 {
+		for i, n := 0, r.u16(lookupList); i < n && r.err == nil; i++ {
+			l, err := r.lookup(tag, r.offset(lookupList, lookupList+2+2*i))
+			if err != nil {
+				return nil, fmt.Errorf("lookup %d: %w", i, err)
+			}
+			t.lookups = append(t.lookups, l)
+		}
+	}
+	if variations != 0 {
+		for i, n := 0, r.u32(variations+4); i < n && r.err == nil; i++ {
+			record := variations + 8 + 8*i
+			t.variations = append(t.variations, r.featureVariation(t.features, r.offset32(variations, record), r.offset32(variations, record+4)))
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	// Check the indices, so that they can be used without checks.
+	for _, s := range t.scripts {
+		langs := s.langs
+		if s.defaultLang != nil {
+			langs = append(langs, *s.defaultLang)
+		}
+		for _, l := range langs {
+			if l.required >= len(t.features) {
+				return nil, fmt.Errorf("invalid required feature %d", l.required)
+			}
+			for _, f := range l.features {
+				if f >= len(t.features) {
+					return nil, fmt.Errorf("invalid feature %d", f)
+				}
+			}
+		}
+	}
+	features := append([]feature(nil), t.features...)
+	for _, v := range t.variations {
+		for _, s := range v.substitutions {
+			if s.index >= len(t.features) {
+				return nil, fmt.Errorf("invalid feature %d", s.index)
+			}
+			features = append(features, s.feature)
+		}
+	}
+	for _, f := range features {
+		for _, l := range f.lookups {
+			if l >= len(t.lookups) {
+				return nil, fmt.Errorf("invalid lookup %d", l)
+			}
+		}
+	}
+	for i, l := range t.lookups {
+		for _, s := range l.subtables {
+			if c, ok := s.(contextual); ok {
+				for _, n := range c.nested() {
+					if n >= len(t.lookups) {
+						return nil, fmt.Errorf("lookup %d: invalid nested lookup %d", i, n)
+					}
+				}
+			}
+		}
+	}
+	return t, nil
+}
+
+func (r *reader) script(tag sfnt.Tag, pos int) script {
+	s := script{tag: tag}
+	if pos == 0 {
+		return s
+	}
+	if lang := r.offset(pos, pos); lang != 0 {
+		l := r.langSys(lang)
+		s.defaultLang = &l
+	}
+	for i, n := 0, r.u16(pos+2); i < n && r.err == nil; i++ {
+		record := pos + 4 + 6*i
+		l := r.langSys(r.offset(pos, record+4))
+		l.tag = sfnt.Tag{Number: uint32(r.u32(record))}
+		s.langs = append(s.langs, l)
+	}
+	return s
+}
+
+func (r *reader) langSys(pos int) langSys {
+	l := langSys{required: -1}
+	if pos == 0 {
+		return l
+	}
+	if required := r.u16(pos + 2); required != 0xFFFF {
+		l.required = required
+	}
+	l.features = r.u16s(pos+6, r.u16(pos+4))
+	return l
+}
+
+func (r *reader) feature(tag sfnt.Tag, pos int) feature {
+	f := feature{tag: tag}
+	if pos == 0 {
+		return f
+	}
+	if params := r.offset(pos, pos); params != 0 {
+		if size := r.featureParamsSize(tag, params); size > 0 {
+			f.params = &object{data: r.bytes(params, size)}
+		}
+	}
+	f.lookups = r.u16s(pos+4, r.u16(pos+2))
+	return f
+}
+
+// featureParamsSize returns the size of the FeatureParams table at pos, or
+// 0 if the feature has no parameters.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/features_ae#tag-cv01--cv99
+func (r *reader) featureParamsSize(tag sfnt.Tag, pos int) int {
+	name := tag.String()
+	switch {
+	case name == "size":
+		return 10
+	case len(name) == 4 && name[:2] == "ss":
+		return 4
+	case len(name) == 4 && name[:2] == "cv":
+		return 14 + 3*r.u16(pos+12)
+	}
+	return 0
+}
+
+func (r *reader) lookup(tag sfnt.Tag, pos int) (lookup, error) {
+	l := lookup{typ: r.u16(pos), flag: r.u16(pos + 2)}
+	offsets := r.u16s(pos+6, r.u16(pos+4))
+	if l.flag&lookupUseMarkFilteringSet != 0 {
+		l.markFilteringSet = r.u16(pos + 6 + 2*len(offsets))
+	}
+	if r.err != nil {
+		return l, r.err
+	}
+
+	extension := extensionType(tag)
+	lookupType := l.typ
+	for i, offset := range offsets {
+		sub := pos + offset
+		typ := lookupType
+		for depth := 0; typ == extension; depth++ {
+			if depth > 0 {
+				return l, fmt.Errorf("subtable %d: nested extension", i)
+			}
+			typ = r.u16(sub + 2)
+			sub = r.offset32(sub, sub+4)
+		}
+		if i == 0 {
+			l.typ = typ
+		} else if typ != l.typ {
+			return l, fmt.Errorf("subtable %d: type %d differs from the lookup", i, typ)
+		}
+
+		var s subtable
+		if tag == sfnt.TagGsub {
+			s = r.gsubSubtable(typ, sub)
+		} else {
+			s = r.gposSubtable(typ, sub)
+		}
+		if r.err != nil {
+			return l, fmt.Errorf("subtable %d: %w", i, r.err)
+		}
+		if s != nil {
+			l.subtables = append(l.subtables, s)
+		}
+	}
+	return l, nil
+}
+
+func (r *reader) featureVariation(features []feature, conditionSet, substitutions int) featureVariation {
+	var v featureVariation
+	if conditionSet != 0 {
+		for i, n := 0, r.u16(conditionSet); i < n && r.err == nil; i++ {
+			condition := r.offset32(conditionSet, conditionSet+2+4*i)
+			// Conditions of format 1 are the only ones defined, and refer to an axis.
+			v.conditions = append(v.conditions, &object{data: r.bytes(condition, 8)})
+		}
+	}
+	if substitutions != 0 {
+		for i, n := 0, r.u16(substitutions+4); i < n && r.err == nil; i++ {
+			record := substitutions + 6 + 6*i
+			index := r.u16(record)
+			var tag sfnt.Tag
+			if index < len(features) {
+				tag = features[index].tag
+			}
+			v.substitutions = append(v.substitutions, featureSubstitution{
+				index:   index,
+				feature: r.feature(tag, r.offset32(substitutions, record+2)),
+			})
+		}
+	}
+	return v
+}
+
+// extensionType returns the lookup type of extension subtables in the table.
+func extensionType(tag sfnt.Tag) int {
+	if tag == sfnt.TagGpos {
+		return 9
+	}
+	return 7
+}
+
+// FeatureTags returns the tag of each feature of the table.
+func (t *Table) FeatureTags() []sfnt.Tag {
+	tags := make([]sfnt.Tag, len(t.features))
+	for i, f := range t.features {
+		tags[i] = f.tag
+	}
+	return tags
+}
+
+// NumLookups returns the number of lookups of the table.
+func (t *Table) NumLookups() int {
+	return len(t.lookups)
+}
+
+// HasFeatureVariations returns true if the table replaces some of its
+// features at some locations of a variable font.
+func (t *Table) HasFeatureVariations() bool {
+	return len(t.variations) > 0
+}
+
+// FeatureFilter returns the features of the table to keep, mapped to their
+// index in the subset. If tags is nil all features are kept.
+func (t *Table) FeatureFilter(tags []sfnt.Tag) map[int]int {
+	keep := make(map[int]int)
+	for i, f := range t.features {
+		if tags == nil || containsTag(tags, f.tag) {
+			keep[i] = len(keep)
+		}
+	}
+	return keep
+}
+
+func containsTag(tags []sfnt.Tag, tag sfnt.Tag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupFilter returns the lookups used by the features that are kept,
+// including through other lookups, mapped to their index in the subset.
+func (t *Table) LookupFilter(features map[int]int) map[int]int {
+	used := make(map[int]bool)
+	var use func(i int)
+	use = func(i int) {
+		if used[i] {
+			return
+		}
+		used[i] = true
+		for _, s := range t.lookups[i].subtables {
+			if c, ok := s.(contextual); ok {
+				for _, n := range c.nested() {
+					use(n)
+				}
+			}
+		}
+	}
+	for i, f := range t.features {
+		if _, found := features[i]; found {
+			for _, l := range f.lookups {
+				use(l)
+			}
+		}
+	}
+	for _, v := range t.variations {
+		for _, s := range v.substitutions {
+			if _, found := features[s.index]; found {
+				for _, l := range s.feature.lookups {
+					use(l)
+				}
+			}
+		}
+	}
+
+	indices := make([]int, 0, len(used))
+	for i := range used {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	keep := make(map[int]int, len(indices))
+	for n, i := range indices {
+		keep[i] = n
+	}
+	return keep
+}
+
+// Closure adds the glyphs that the lookups can substitute for the glyphs of
+// set, until no more are added.
+func (t *Table) Closure(lookups map[int]int, set GlyphSet) {
+	for {
+		n := len(set)
+		for i := range lookups {
+			for _, s := range t.lookups[i].subtables {
+				if s, ok := s.(substitution); ok {
+					s.closure(set)
+				}
+			}
+		}
+		if len(set) == n {
+			return
+		}
+	}
+}
+
+// Write returns the table restricted to the features, lookups and glyphs of m.
+func (t *Table) Write(m *Mapping, features map[int]int) ([]byte, error) {
+	// Lookups are written as extensions if their subtables are too far
+	// from the lookup list for 16-bit offsets.
+	buf, err := t.pack(m, features, false)
+	if err == errOffsetOverflow {
+		buf, err = t.pack(m, features, true)
+	}
+	return buf, err
+}
+
+// pack writes the table. The subtables are written again for each call, as
+// packing replaces the objects they refer to with shared ones.
+func (t *Table) pack(m *Mapping, features map[int]int, extension bool) ([]byte, error) {
+	subtables := make([][]*object, len(m.Lookups))
+	for i, n := range m.Lookups {
+		for _, s := range t.lookups[i].subtables {
+			if o := s.write(m); o != nil {
+				subtables[n] = append(subtables[n], o)
+			}
+		}
+	}
+
+	header := &object{}
+	scriptList, featureList, lookupList := t.scriptList(features), t.featureList(m, features), &object{}
+	variations := t.featureVariations(m, features)
+	minor := 0
+	if variations != nil {
+		minor = 1
+	}
+	header.u16(1, minor)
+	header.offset16(scriptList)
+	header.offset16(featureList)
+	header.offset16(lookupList)
+	if variations != nil {
+		header.offset32(variations)
+	}
+
+	order := make([]int, len(m.Lookups))
+	for i, n := range m.Lookups {
+		order[n] = i
+	}
+	lookupList.u16(len(order))
+	lookups := make([]*object, len(order))
+	extensions := make([][]*object, len(order))
+	for n, i := range order {
+		l := t.lookups[i]
+		o := &object{}
+		typ := l.typ
+		if extension {
+			typ = extensionType(t.Tag)
+		}
+		o.u16(typ, l.flag, len(subtables[n]))
+		for _, s := range subtables[n] {
+			if extension {
+				ext := &object{}
+				ext.u16(1, l.typ)
+				ext.offset32(s)
+				extensions[n] = append(extensions[n], ext)
+				s = ext
+			}
+			o.offset16(s)
+		}
+		if l.flag&lookupUseMarkFilteringSet != 0 {
+			o.u16(l.markFilteringSet)
+		}
+		lookupList.offset16(o)
+		lookups[n] = o
+	}
+
+	p := newPacker()
+	if extension {
+		// Each extension subtable can be anywhere in the table, so
+		// subtables are only shared within a lookup.
+		for _, exts := range extensions {
+			for _, o := range exts {
+				p.share(o)
+			}
+			p.isolate()
+		}
+	}
+	p.place(header)
+	p.placeTree(scriptList)
+	p.placeTree(featureList)
+	p.place(lookupList)
+	for _, o := range lookups {
+		p.place(o)
+	}
+	for _, exts := range extensions {
+		for _, o := range exts {
+			p.place(o)
+		}
+	}
+	for _, o := range lookups {
+		p.placeTree(o)
+	}
+	p.placeTree(header)
+	return p.bytes()
+}
+
+func (t *Table) scriptList(features map[int]int) *object {
+	o := &object{}
+	o.u16(len(t.scripts))
+	for _, s := range t.scripts {
+		so := &object{}
+		var defaultLang *object
+		if s.defaultLang != nil {
+			defaultLang = s.defaultLang.object(features)
+		}
+		so.offset16(defaultLang)
+		so.u16(len(s.langs))
+		for _, l := range s.langs {
+			so.u32(l.tag.Number)
+			so.offset16(l.object(features))
+		}
+		o.u32(s.tag.Number)
+		o.offset16(so)
+	}
+	return o
+}
+
+func (l *langSys) object(features map[int]int) *object {
+	o := &object{}
+	required := 0xFFFF
+	if n, found := features[l.required]; found {
+		required = n
+	}
+	var kept []int
+	for _, f := range l.features {
+		if n, found := features[f]; found {
+			kept = append(kept, n)
+		}
+	}
+	o.u16(0, required, len(kept))
+	o.u16(kept...)
+	return o
+}
+
+func (t *Table) featureList(m *Mapping, features map[int]int) *object {
+	order := make([]int, len(features))
+	for i, n := range features {
+		order[n] = i
+	}
+	o := &object{}
+	o.u16(len(order))
+	for _, i := range order {
+		o.u32(t.features[i].tag.Number)
+		o.offset16(t.features[i].object(m))
+	}
+	return o
+}
+
+func (f *feature) object(m *Mapping) *object {
+	o := &object{}
+	o.offset16(f.params)
+	var lookups []int
+	for _, l := range f.lookups {
+		lookups = append(lookups, m.Lookups[l])
+	}
+	o.u16(len(lookups))
+	o.u16(lookups...)
+	return o
+}
+
+// featureVariations returns the FeatureVariations table, or nil if the
+// table has none.
+func (t *Table) featureVariations(m *Mapping, features map[int]int) *object {
+	if len(t.variations) == 0 {
+		return nil
+	}
+	o := &object{}
+	o.u16(1, 0)
+	o.u32(uint32(len(t.variations)))
+	for _, v := range t.variations {
+		conditionSet := &object{}
+		conditionSet.u16(len(v.conditions))
+		for _, c := range v.conditions {
+			conditionSet.offset32(c)
+		}
+
+		substitutions := &object{}
+		var kept []featureSubstitution
+		for _, s := range v.substitutions {
+			if _, found := features[s.index]; found {
+				kept = append(kept, s)
+			}
+		}
+		substitutions.u16(1, 0, len(kept))
+		for _, s := range kept {
+			substitutions.u16(features[s.index])
+			substitutions.offset32(s.feature.object(m))
+		}
+
+		o.offset32(conditionSet)
+		o.offset32(substitutions)
+	}
+	return o
+}
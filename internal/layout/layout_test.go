@@ -0,0 +1,114 @@
+package layout
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+func parseFont(t *testing.T, filename string) *sfnt.Font {
+	t.Helper()
+	buf, err := os.ReadFile(filepath.Join("..", "..", "sfnt", "testdata", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := sfnt.Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func parseLayout(t *testing.T, font *sfnt.Font, tag sfnt.Tag) *Table {
+	t.Helper()
+	table, err := font.Table(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := Parse(tag, table.Bytes())
+	if err != nil {
+		t.Fatalf("parsing %q: %q", tag, err)
+	}
+	return l
+}
+
+func TestCoverageObject(t *testing.T) {
+	for _, glyphs := range [][]sfnt.GlyphID{
+		nil,
+		{5},
+		{1, 3, 9},
+		{1, 2, 3, 4, 10, 11, 12, 20},
+	} {
+		buf, err := pack(coverageObject(glyphs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &reader{buf: buf}
+		if got := r.coverage(0); r.err != nil || len(got) != len(glyphs) || (len(got) > 0 && !reflect.DeepEqual(got, glyphs)) {
+			t.Errorf("coverage of %v = %v, %v", glyphs, got, r.err)
+		}
+	}
+}
+
+func TestClassDefObject(t *testing.T) {
+	for _, classes := range []classDef{
+		{},
+		{3: 1, 4: 2, 7: 1},
+		{10: 1, 11: 1, 12: 1, 13: 1, 100: 2, 101: 2, 200: 3},
+	} {
+		// The class definition is written after an offset to it, since
+		// reading the position 0 returns an empty class definition.
+		o := &object{}
+		o.offset16(classDefObject(classes))
+		buf, err := pack(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &reader{buf: buf}
+		if got := r.classDef(r.offset(0, 0)); r.err != nil || !reflect.DeepEqual(got, classes) {
+			t.Errorf("class definition of %v = %v, %v", classes, got, r.err)
+		}
+	}
+}
+
+// TestLayoutRoundTrip checks that the layout tables are unchanged by
+// subsetting them to every glyph, once they have been written by this
+// package.
+func TestLayoutRoundTrip(t *testing.T) {
+	for _, filename := range []string{"Roboto-BoldItalic.ttf", "Raleway-v4020-Regular.otf"} {
+		font := parseFont(t, filename)
+		maxp, err := font.MaxpTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := &Mapping{Glyphs: make(GlyphMap)}
+		for g := 0; g < int(maxp.NumGlyphs); g++ {
+			m.Glyphs[sfnt.GlyphID(g)] = sfnt.GlyphID(g)
+		}
+
+		for _, tag := range []sfnt.Tag{sfnt.TagGsub, sfnt.TagGpos} {
+			l := parseLayout(t, font, tag)
+			var written [2][]byte
+			for i := range written {
+				features := l.FeatureFilter(nil)
+				m.Lookups = l.LookupFilter(features)
+				if written[i], err = l.Write(m, features); err != nil {
+					t.Fatalf("%s: writing %q: %q", filename, tag, err)
+				}
+				if l, err = Parse(tag, written[i]); err != nil {
+					t.Fatalf("%s: parsing %q: %q", filename, tag, err)
+				}
+			}
+			if !bytes.Equal(written[0], written[1]) {
+				t.Errorf("%s: %q changed when written again", filename, tag)
+			}
+			if n := len(l.lookups); n != len(parseLayout(t, font, tag).lookups) {
+				t.Errorf("%s: %q has %d lookups, want %d", filename, tag, n, len(parseLayout(t, font, tag).lookups))
+			}
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package layout
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+var tagDFLT = sfnt.MustNamedTag("DFLT")
+
+// Merger merges the 'GDEF', 'GSUB' and 'GPOS' tables of several fonts.
+type Merger struct {
+	// Glyphs maps the glyphs of each font to the merged font.
+	Glyphs []GlyphMap
+	// Scales contains the scale from the units of each font to those of the
+	// merged font.
+	Scales []Scale
+
+	// markClasses and markSets are added to the mark attachment classes
+	// and the indexes of the mark glyph sets of each font in 'GDEF'.
+	markClasses []int
+	markSets    []int
+}
+
+// MergeGDEF returns a 'GDEF' table merging the tables of each font, which
+// are nil for the fonts without one, or nil if no font has one. The mark
+// attachment classes and mark glyph sets of each font are renumbered after
+// those of the previous fonts. It must be called before MergeLayout, which
+// uses these numbers.
+func (m *Merger) MergeGDEF(tables []*GDEF) ([]byte, error) {
+	m.markClasses = make([]int, len(tables))
+	m.markSets = make([]int, len(tables))
+
+	var merged *GDEF
+	for i, t := range tables {
+		if t == nil {
+			continue
+		}
+		t = t.Mapped(m.Glyphs[i])
+		if merged == nil {
+			merged = &GDEF{}
+		}
+
+		merged.glyphClasses = mergeClasses(merged.glyphClasses, t.glyphClasses, 0)
+		merged.attachPoints = mergeObjects(merged.attachPoints, t.attachPoints)
+		if s := m.Scales[i]; s != 1 {
+			for g, lig := range t.ligCarets {
+				t.ligCarets[g] = s.ligCarets(lig)
+			}
+		}
+		merged.ligCarets = mergeObjects(merged.ligCarets, t.ligCarets)
+
+		for _, c := range merged.markAttachClasses {
+			if c > m.markClasses[i] {
+				m.markClasses[i] = c
+			}
+		}
+		merged.markAttachClasses = mergeClasses(merged.markAttachClasses, t.markAttachClasses, m.markClasses[i])
+		for _, c := range merged.markAttachClasses {
+			if c > 0xFF {
+				return nil, fmt.Errorf("font %d: too many mark attachment classes", i)
+			}
+		}
+
+		m.markSets[i] = len(merged.markGlyphSets)
+		if t.markGlyphSets != nil {
+			merged.markGlyphSets = append(merged.markGlyphSets, t.markGlyphSets...)
+		}
+	}
+	if merged == nil {
+		return nil, nil
+	}
+
+	// Variable fonts are not merged, so there is no variation store.
+	merged.minor = 0
+	if merged.markGlyphSets != nil {
+		merged.minor = 2
+	}
+	return merged.Bytes()
+}
+
+// mergeClasses adds the classes of src, increased by base, to dst.
+func mergeClasses(dst, src classDef, base int) classDef {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = make(classDef)
+	}
+	for g, c := range src {
+		if c != 0 {
+			dst[g] = c + base
+		}
+	}
+	return dst
+}
+
+// mergeObjects adds the objects of src to dst.
+func mergeObjects(dst, src map[sfnt.GlyphID]*object) map[sfnt.GlyphID]*object {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[sfnt.GlyphID]*object)
+	}
+	for g, o := range src {
+		dst[g] = o
+	}
+	return dst
+}
+
+// MergeLayout returns a 'GSUB' or 'GPOS' table merging the tables of each
+// font, which are nil for the fonts without one. The lookups and features of
+// each table follow those of the previous tables, and apply to the scripts of
+// the table.
+func (m *Merger) MergeLayout(tag sfnt.Tag, tables []*Table) ([]byte, error) {
+	t := m.mergeTables(tag, tables)
+	mapping := &Mapping{Lookups: identity(len(t.lookups))}
+	return t.Write(mapping, identity(len(t.features)))
+}
+
+// identity maps the integers up to n to themselves.
+func identity(n int) map[int]int {
+	m := make(map[int]int, n)
+	for i := 0; i < n; i++ {
+		m[i] = i
+	}
+	return m
+}
+
+// mergedSubtable is a subtable of one of the merged fonts, which is written
+// with the glyphs and lookups of that font mapped to the merged font.
+type mergedSubtable struct {
+	subtable
+	m *Mapping
+}
+
+func (s mergedSubtable) write(*Mapping) *object {
+	return s.subtable.write(s.m)
+}
+
+// mergeTables returns a table with the lookups and features of each table
+// following those of the previous tables, and the scripts of all tables.
+func (m *Merger) mergeTables(tag sfnt.Tag, tables []*Table) *Table {
+	merged := &Table{Tag: tag}
+	// features contains the index in the merged table of the first feature of each table.
+	features := make([]int, len(tables))
+	for i, t := range tables {
+		if t == nil {
+			continue
+		}
+		lookups := make(map[int]int, len(t.lookups))
+		for j := range t.lookups {
+			lookups[j] = len(merged.lookups) + j
+		}
+		mapping := &Mapping{Glyphs: m.Glyphs[i], Lookups: lookups}
+		for _, l := range t.lookups {
+			merged.lookups = append(merged.lookups, m.mergedLookup(i, l, mapping))
+		}
+
+		features[i] = len(merged.features)
+		for _, f := range t.features {
+			mergedFeature := feature{tag: f.tag, params: f.params}
+			for _, l := range f.lookups {
+				mergedFeature.lookups = append(mergedFeature.lookups, lookups[l])
+			}
+			merged.features = append(merged.features, mergedFeature)
+		}
+	}
+
+	// Features are sorted by tag, as required by the specification.
+	order := make([]int, len(merged.features))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return merged.features[order[i]].tag.Number < merged.features[order[j]].tag.Number
+	})
+	sorted := make([]feature, len(order))
+	renumbered := make([]int, len(order))
+	for n, i := range order {
+		sorted[n] = merged.features[i]
+		renumbered[i] = n
+	}
+	merged.features = sorted
+
+	featureIndex := func(table, f int) int {
+		return renumbered[features[table]+f]
+	}
+	merged.scripts = mergeScripts(tables, featureIndex)
+	return merged
+}
+
+// mergedLookup returns a lookup of font i in the merged table, with its
+// subtables scaled to the units of the merged font.
+func (m *Merger) mergedLookup(i int, l lookup, mapping *Mapping) lookup {
+	merged := lookup{typ: l.typ, flag: l.flag, markFilteringSet: l.markFilteringSet}
+	if l.flag&lookupUseMarkFilteringSet != 0 {
+		merged.markFilteringSet += m.markSets[i]
+	}
+	if class := l.flag >> 8; class != 0 {
+		merged.flag = l.flag&0xFF | (class+m.markClasses[i])<<8
+	}
+	for _, s := range l.subtables {
+		if scalable, ok := s.(scalable); ok && m.Scales[i] != 1 {
+			s = scalable.scaled(m.Scales[i])
+		}
+		merged.subtables = append(merged.subtables, mergedSubtable{subtable: s, m: mapping})
+	}
+	return merged
+}
+
+// langSource is a language system of one of the merged tables.
+type langSource struct {
+	table int
+	lang  *langSys
+}
+
+// mergeScripts returns the scripts of all tables, sorted by tag. The
+// features of a table apply to a script if it has the script, and
+// otherwise if it has the default script. In the same way, the features of
+// the default language system of a script apply to the languages that the
+// script of the table does not have.
+func mergeScripts(tables []*Table, featureIndex func(table, f int) int) []script {
+	tags := make(map[sfnt.Tag]bool)
+	for _, t := range tables {
+		if t != nil {
+			for _, s := range t.scripts {
+				tags[s.tag] = true
+			}
+		}
+	}
+	sortedTags := make([]sfnt.Tag, 0, len(tags))
+	for tag := range tags {
+		sortedTags = append(sortedTags, tag)
+	}
+	sortTags(sortedTags)
+
+	scripts := make([]script, 0, len(sortedTags))
+	for _, tag := range sortedTags {
+		sources := make([]*script, len(tables))
+		langTags := make(map[sfnt.Tag]bool)
+		for i, t := range tables {
+			if t == nil {
+				continue
+			}
+			if sources[i] = t.findScript(tag); sources[i] != nil {
+				for _, l := range sources[i].langs {
+					langTags[l.tag] = true
+				}
+			} else {
+				sources[i] = t.findScript(tagDFLT)
+			}
+		}
+
+		var defaults []langSource
+		for i, s := range sources {
+			if s != nil {
+				defaults = append(defaults, langSource{table: i, lang: s.defaultLang})
+			}
+		}
+		merged := script{tag: tag, defaultLang: mergeLangs(defaults, featureIndex)}
+
+		sortedLangs := make([]sfnt.Tag, 0, len(langTags))
+		for l := range langTags {
+			sortedLangs = append(sortedLangs, l)
+		}
+		sortTags(sortedLangs)
+		for _, langTag := range sortedLangs {
+			var langs []langSource
+			for i, s := range sources {
+				if s == nil {
+					continue
+				}
+				lang := s.defaultLang
+				if s.tag == tag {
+					if l := s.findLang(langTag); l != nil {
+						lang = l
+					}
+				}
+				langs = append(langs, langSource{table: i, lang: lang})
+			}
+			if l := mergeLangs(langs, featureIndex); l != nil {
+				l.tag = langTag
+				merged.langs = append(merged.langs, *l)
+			} else {
+				merged.langs = append(merged.langs, langSys{tag: langTag, required: -1})
+			}
+		}
+		scripts = append(scripts, merged)
+	}
+	return scripts
+}
+
+// mergeLangs returns a language system with the features of langs, or nil
+// if none of them exist. Only one feature can be required, so the required
+// features of the other tables are added to the features.
+func mergeLangs(langs []langSource, featureIndex func(table, f int) int) *langSys {
+	var merged *langSys
+	for _, source := range langs {
+		l := source.lang
+		if l == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &langSys{required: -1}
+		}
+		if l.required >= 0 {
+			if required := featureIndex(source.table, l.required); merged.required < 0 {
+				merged.required = required
+			} else {
+				merged.features = append(merged.features, required)
+			}
+		}
+		for _, f := range l.features {
+			merged.features = append(merged.features, featureIndex(source.table, f))
+		}
+	}
+	if merged != nil {
+		sort.Ints(merged.features)
+	}
+	return merged
+}
+
+func (t *Table) findScript(tag sfnt.Tag) *script {
+	for i := range t.scripts {
+		if t.scripts[i].tag == tag {
+			return &t.scripts[i]
+		}
+	}
+	return nil
+}
+
+func (s *script) findLang(tag sfnt.Tag) *langSys {
+	for i := range s.langs {
+		if s.langs[i].tag == tag {
+			return &s.langs[i]
+		}
+	}
+	return nil
+}
+
+func sortTags(tags []sfnt.Tag) {
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Number < tags[j].Number })
+}
@@ -0,0 +1,142 @@
+package layout
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// Scale multiplies coordinates in design units, to convert them to the
+// units per em of another font.
+type Scale float64
+
+// Apply returns the coordinate v, read as an unsigned 16-bit value, scaled
+// and rounded.
+func (s Scale) Apply(v int) int {
+	scaled := math.Round(float64(int16(v)) * float64(s))
+	return int(math.Max(math.MinInt16, math.Min(math.MaxInt16, scaled)))
+}
+
+// Coordinate returns the coordinate v scaled.
+func (s Scale) Coordinate(v int16) int16 {
+	return int16(s.Apply(int(uint16(v))))
+}
+
+// Distance returns the unsigned distance v scaled.
+func (s Scale) Distance(v uint16) uint16 {
+	return uint16(math.Min(math.MaxUint16, math.Round(float64(v)*float64(s))))
+}
+
+// scalable is a subtable containing coordinates.
+type scalable interface {
+	subtable
+	scaled(s Scale) subtable
+}
+
+// coordinates returns a copy of o with the 16-bit coordinates at positions
+// in its data scaled. The offsets of o are kept.
+func (s Scale) coordinates(o *object, positions ...int) *object {
+	if o == nil {
+		return nil
+	}
+	scaled := &object{data: append([]byte(nil), o.data...), links: o.links}
+	for _, pos := range positions {
+		if pos+2 <= len(scaled.data) {
+			v := s.Apply(int(binary.BigEndian.Uint16(scaled.data[pos:])))
+			binary.BigEndian.PutUint16(scaled.data[pos:], uint16(v))
+		}
+	}
+	return scaled
+}
+
+// anchor returns a copy of an anchor table with its coordinates scaled.
+func (s Scale) anchor(anchor *object) *object {
+	return s.coordinates(anchor, 2, 4)
+}
+
+func (s Scale) anchors(anchors []*object) []*object {
+	scaled := make([]*object, len(anchors))
+	for i, a := range anchors {
+		scaled[i] = s.anchor(a)
+	}
+	return scaled
+}
+
+// ligCarets returns a copy of a LigGlyph table with the coordinates of its
+// caret values scaled. Caret values of format 2 are contour points.
+func (s Scale) ligCarets(lig *object) *object {
+	scaled := &object{data: lig.data}
+	for _, l := range lig.links {
+		caret := l.child
+		if format := binary.BigEndian.Uint16(caret.data); format == 1 || format == 3 {
+			caret = s.coordinates(caret, 2)
+		}
+		scaled.links = append(scaled.links, link{pos: l.pos, wide: l.wide, child: caret})
+	}
+	return scaled
+}
+
+// valueRecord returns a copy of v with its values scaled. The values of
+// value records are all placements and advances.
+func (s Scale) valueRecord(v valueRecord) valueRecord {
+	scaled := valueRecord{values: make([]int, len(v.values)), devices: v.devices}
+	for i, value := range v.values {
+		scaled.values[i] = s.Apply(value)
+	}
+	return scaled
+}
+
+func (p *singlePos) scaled(s Scale) subtable {
+	scaled := *p
+	scaled.values = make([]valueRecord, len(p.values))
+	for i, v := range p.values {
+		scaled.values[i] = s.valueRecord(v)
+	}
+	return &scaled
+}
+
+func (p *pairPos) scaled(s Scale) subtable {
+	scaled := *p
+	scaled.sets = make([][]pairValue, len(p.sets))
+	for i, set := range p.sets {
+		scaled.sets[i] = make([]pairValue, len(set))
+		for j, pair := range set {
+			scaled.sets[i][j] = pairValue{
+				second: pair.second,
+				values: [2]valueRecord{s.valueRecord(pair.values[0]), s.valueRecord(pair.values[1])},
+			}
+		}
+	}
+	scaled.values = make([][][2]valueRecord, len(p.values))
+	for i, row := range p.values {
+		scaled.values[i] = make([][2]valueRecord, len(row))
+		for j, v := range row {
+			scaled.values[i][j] = [2]valueRecord{s.valueRecord(v[0]), s.valueRecord(v[1])}
+		}
+	}
+	return &scaled
+}
+
+func (p *cursivePos) scaled(s Scale) subtable {
+	scaled := *p
+	scaled.anchors = make([][]*object, len(p.anchors))
+	for i, anchors := range p.anchors {
+		scaled.anchors[i] = s.anchors(anchors)
+	}
+	return &scaled
+}
+
+func (p *markPos) scaled(s Scale) subtable {
+	scaled := *p
+	scaled.marks = make([]markRecord, len(p.marks))
+	for i, m := range p.marks {
+		scaled.marks[i] = markRecord{class: m.class, anchor: s.anchor(m.anchor)}
+	}
+	scaled.bases = make([][][]*object, len(p.bases))
+	for i, components := range p.bases {
+		scaled.bases[i] = make([][]*object, len(components))
+		for j, anchors := range components {
+			scaled.bases[i][j] = s.anchors(anchors)
+		}
+	}
+	return &scaled
+}
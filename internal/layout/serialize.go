@@ -0,0 +1,233 @@
+package layout
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var errOffsetOverflow = errors.New("offset overflow")
+
+// object is a subtable being written, which refers to other subtables with
+// offsets from its start.
+type object struct {
+	data  []byte
+	links []link
+}
+
+// link is an offset in the data of an object to another object.
+type link struct {
+	pos   int
+	wide  bool // wide is set for 32-bit offsets.
+	child *object
+}
+
+func (o *object) u16(values ...int) {
+	for _, v := range values {
+		o.data = append(o.data, byte(v>>8), byte(v))
+	}
+}
+
+func (o *object) u32(v uint32) {
+	o.data = append(o.data, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// offset16 appends a 16-bit offset to child, which is null if child is nil.
+func (o *object) offset16(child *object) {
+	o.offset(child, false)
+}
+
+// offset32 appends a 32-bit offset to child, which is null if child is nil.
+func (o *object) offset32(child *object) {
+	o.offset(child, true)
+}
+
+func (o *object) offset(child *object, wide bool) {
+	if child != nil {
+		o.links = append(o.links, link{pos: len(o.data), wide: wide, child: child})
+	}
+	if wide {
+		o.u32(0)
+	} else {
+		o.u16(0)
+	}
+}
+
+// packer places objects one after the other, and writes them with the
+// offsets between them. Objects with the same data and offsets are written
+// once, as subsetting often leaves many copies of the same subtable.
+type packer struct {
+	order  []*object
+	placed map[*object]int
+	// visited contains the objects whose children have been placed.
+	visited map[*object]bool
+
+	// shared maps the data and offsets of each object to the first object
+	// seen with them, and canonical maps each object to that object.
+	shared    map[string]*object
+	canonical map[*object]*object
+	// scope is part of the keys of shared, so that objects are only
+	// shared with the objects seen since the last call to isolate.
+	scope int
+}
+
+func newPacker() *packer {
+	return &packer{
+		placed:    make(map[*object]int),
+		visited:   make(map[*object]bool),
+		shared:    make(map[string]*object),
+		canonical: make(map[*object]*object),
+	}
+}
+
+// share returns the first object seen that is equal to o, after replacing
+// the objects it refers to in the same way.
+func (p *packer) share(o *object) *object {
+	if c, found := p.canonical[o]; found {
+		return c
+	}
+	var key strings.Builder
+	fmt.Fprintf(&key, "%d|", p.scope)
+	key.Write(o.data)
+	for i := range o.links {
+		o.links[i].child = p.share(o.links[i].child)
+		fmt.Fprintf(&key, "|%d %t %p", o.links[i].pos, o.links[i].wide, o.links[i].child)
+	}
+	c, found := p.shared[key.String()]
+	if !found {
+		c = o
+		p.shared[key.String()] = o
+	}
+	p.canonical[o] = c
+	return c
+}
+
+// isolate stops the objects shared next from sharing the objects shared so
+// far, which may be placed too far from them for 16-bit offsets.
+func (p *packer) isolate() {
+	p.scope++
+}
+
+// place places an object, if it has not been placed already.
+func (p *packer) place(o *object) {
+	o = p.share(o)
+	if _, found := p.placed[o]; found {
+		return
+	}
+	p.placed[o] = len(p.order)
+	p.order = append(p.order, o)
+}
+
+// placeTree places an object, if it has not been placed already, and then
+// the objects it refers to, depth first.
+func (p *packer) placeTree(o *object) {
+	o = p.share(o)
+	if p.visited[o] {
+		return
+	}
+	p.visited[o] = true
+	p.place(o)
+	for _, l := range o.links {
+		p.placeTree(l.child)
+	}
+}
+
+// sort reorders the placed objects so that each object comes after every
+// object that refers to it, as offsets cannot be negative, while keeping
+// them as close as possible to the order in which they were placed.
+func (p *packer) sort() error {
+	parents := make([]int, len(p.order))
+	for _, o := range p.order {
+		for _, l := range o.links {
+			i, found := p.placed[l.child]
+			if !found {
+				return errors.New("subtable was not placed")
+			}
+			parents[i]++
+		}
+	}
+
+	var ready indexHeap
+	for i, n := range parents {
+		if n == 0 {
+			heap.Push(&ready, i)
+		}
+	}
+	order := make([]*object, 0, len(p.order))
+	for ready.Len() > 0 {
+		o := p.order[heap.Pop(&ready).(int)]
+		order = append(order, o)
+		for _, l := range o.links {
+			i := p.placed[l.child]
+			if parents[i]--; parents[i] == 0 {
+				heap.Push(&ready, i)
+			}
+		}
+	}
+	if len(order) != len(p.order) {
+		return errors.New("subtables refer to each other in a cycle")
+	}
+
+	p.order = order
+	for i, o := range order {
+		p.placed[o] = i
+	}
+	return nil
+}
+
+// indexHeap is a min-heap of indexes.
+type indexHeap []int
+
+func (h indexHeap) Len() int            { return len(h) }
+func (h indexHeap) Less(i, j int) bool  { return h[i] < h[j] }
+func (h indexHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *indexHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *indexHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// bytes writes the placed objects, returning errOffsetOverflow if an offset
+// is out of range. Every object that is referred to must have been placed.
+func (p *packer) bytes() ([]byte, error) {
+	if err := p.sort(); err != nil {
+		return nil, err
+	}
+	positions := make([]int, len(p.order))
+	size := 0
+	for i, o := range p.order {
+		positions[i] = size
+		size += len(o.data)
+		// Subtables are aligned to 2 bytes.
+		size += size % 2
+	}
+
+	buf := make([]byte, size)
+	for i, o := range p.order {
+		copy(buf[positions[i]:], o.data)
+		for _, l := range o.links {
+			offset := positions[p.placed[l.child]] - positions[i]
+			at := positions[i] + l.pos
+			switch {
+			case l.wide:
+				binary.BigEndian.PutUint32(buf[at:], uint32(offset))
+			case offset > 0xFFFF:
+				return nil, errOffsetOverflow
+			default:
+				binary.BigEndian.PutUint16(buf[at:], uint16(offset))
+			}
+		}
+	}
+	return buf, nil
+}
+
+// pack writes an object and the objects it refers to, depth first.
+func pack(root *object) ([]byte, error) {
+	p := newPacker()
+	p.placeTree(root)
+	return p.bytes()
+}
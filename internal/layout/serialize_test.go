@@ -0,0 +1,44 @@
+package layout
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPack(t *testing.T) {
+	// The two leaves are equal, so they are written once, after both of
+	// the objects that refer to them.
+	leaf1, leaf2 := &object{}, &object{}
+	leaf1.u16(7)
+	leaf2.u16(7)
+	left, right := &object{}, &object{}
+	left.offset16(leaf1)
+	right.u16(1)
+	right.offset16(leaf2)
+	root := &object{}
+	root.offset16(left)
+	root.offset32(right)
+	root.offset16(nil)
+
+	got, err := pack(root)
+	if err != nil {
+		t.Fatalf("pack() err = %q, want nil", err)
+	}
+	want := []byte{
+		0, 8, 0, 0, 0, 10, 0, 0, // root
+		0, 6, // left
+		0, 1, 0, 4, // right
+		0, 7, // leaf
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("pack() = %v, want %v", got, want)
+	}
+
+	far := &object{data: make([]byte, 0x10000)}
+	root = &object{}
+	root.offset16(far)
+	root.offset16(leaf1)
+	if _, err := pack(root); err != errOffsetOverflow {
+		t.Errorf("pack() err = %v, want %v", err, errOffsetOverflow)
+	}
+}
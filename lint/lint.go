@@ -0,0 +1,116 @@
+// Package lint checks fonts against a set of rules that catch common mistakes,
+// such as missing names or metrics that disagree between tables.
+//
+// Each Rule inspects a font and returns findings with a severity. Custom rules
+// can be passed to New alongside, or instead of, DefaultRules.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// Severity indicates how serious a finding is.
+type Severity int
+
+const (
+	// Info findings are suggestions that do not affect how the font works.
+	Info Severity = iota
+	// Warning findings are likely to cause problems in some environments.
+	Warning
+	// Error findings are problems that should be fixed before the font is used.
+	Error
+)
+
+// String returns the lowercase name of the severity.
+func (s Severity) String() string {
+	switch s {
+	case Info:
+		return "info"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	default:
+		return fmt.Sprintf("severity %d", int(s))
+	}
+}
+
+// Finding is a single problem reported by a rule.
+type Finding struct {
+	Rule     string
+	Severity Severity
+	Message  string
+}
+
+// String returns the finding formatted as "severity: [rule] message".
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: [%s] %s", f.Severity, f.Rule, f.Message)
+}
+
+// Rule is a named check run against a font. Check returns the problems found,
+// or an error if the font could not be checked (for example, if a table it
+// needs does not parse). Errors are reported as findings with Error severity.
+type Rule struct {
+	Name        string
+	Description string
+	Check       func(font *sfnt.Font) ([]Finding, error)
+}
+
+// Linter runs a set of rules against fonts.
+type Linter struct {
+	rules []Rule
+}
+
+// New returns a Linter that runs the given rules.
+func New(rules ...Rule) *Linter {
+	return &Linter{rules: rules}
+}
+
+// Add adds rules to the linter.
+func (l *Linter) Add(rules ...Rule) {
+	l.rules = append(l.rules, rules...)
+}
+
+// Rules returns the rules run by the linter.
+func (l *Linter) Rules() []Rule {
+	return l.rules
+}
+
+// Lint runs every rule against font and returns the findings, ordered with
+// the most severe first. Findings of the same severity keep the order of the rules.
+func (l *Linter) Lint(font *sfnt.Font) []Finding {
+	var findings []Finding
+	for _, rule := range l.rules {
+		found, err := rule.Check(font)
+		if err != nil {
+			found = append(found, Finding{Severity: Error, Message: err.Error()})
+		}
+		for _, f := range found {
+			f.Rule = rule.Name
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Severity > findings[j].Severity
+	})
+	return findings
+}
+
+// Lint runs DefaultRules against font.
+func Lint(font *sfnt.Font) []Finding {
+	return New(DefaultRules...).Lint(font)
+}
+
+// HasErrors returns true if any of the findings have Error severity.
+func HasErrors(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity >= Error {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+func parseFixture(t *testing.T, filename string) *sfnt.Font {
+	t.Helper()
+
+	file, err := os.Open(filepath.Join("..", "sfnt", "testdata", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+
+	font, err := sfnt.Parse(file)
+	if err != nil {
+		t.Fatalf("Parse(%q) err = %q, want nil", filename, err)
+	}
+	return font
+}
+
+func TestLintFixtures(t *testing.T) {
+	for _, filename := range []string{"Roboto-BoldItalic.ttf", "Raleway-v4020-Regular.otf"} {
+		font := parseFixture(t, filename)
+
+		if findings := Lint(font); HasErrors(findings) {
+			t.Errorf("Lint(%q) = %q, want no errors", filename, findings)
+		}
+	}
+}
+
+func TestLintStyleBits(t *testing.T) {
+	font := parseFixture(t, "Roboto-BoldItalic.ttf")
+
+	head, err := font.HeadTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	head.MacStyle &^= macStyleBold
+
+	findings := Lint(font)
+	if !HasErrors(findings) {
+		t.Fatalf("Lint() = %q, want errors", findings)
+	}
+	if findings[0].Rule != "style-bits" || findings[0].Severity != Error {
+		t.Errorf("Lint()[0] = %q, want style-bits error first", findings[0])
+	}
+}
+
+func TestLintCustomRule(t *testing.T) {
+	font := parseFixture(t, "Raleway-v4020-Regular.otf")
+
+	linter := New(Rule{
+		Name: "has-glyf",
+		Check: func(font *sfnt.Font) ([]Finding, error) {
+			if !font.HasTable(sfnt.TagGlyf) {
+				return []Finding{warningf("no glyf table")}, nil
+			}
+			return nil, nil
+		},
+	})
+
+	findings := linter.Lint(font)
+	if len(findings) != 1 || findings[0].Rule != "has-glyf" || findings[0].Severity != Warning {
+		t.Errorf("Lint() = %q, want one has-glyf warning", findings)
+	}
+}
+
+func TestSubfamilyWeight(t *testing.T) {
+	tests := []struct {
+		subfamily string
+		want      uint16
+	}{
+		{"Regular", 400},
+		{"Italic", 400},
+		{"Bold Italic", 700},
+		{"SemiBold", 600},
+		{"Extra-Light Italic", 200},
+		{"SemiLight", 350},
+		{"Demi Light Italic", 350},
+		{"Light", 300},
+		{"Black", 900},
+	}
+
+	for _, test := range tests {
+		if _, got := subfamilyWeight(test.subfamily); got != test.want {
+			t.Errorf("subfamilyWeight(%q) = %d, want %d", test.subfamily, got, test.want)
+		}
+	}
+}
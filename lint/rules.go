@@ -0,0 +1,226 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// DefaultRules contains the rules run by Lint.
+var DefaultRules = []Rule{
+	{
+		Name:        "name-required",
+		Description: "The name table contains the family, subfamily, full and PostScript names.",
+		Check:       checkRequiredNames,
+	},
+	{
+		Name:        "postscript-name",
+		Description: "The PostScript name is at most 63 printable ASCII characters, excluding '[](){}<>/%'.",
+		Check:       checkPostScriptName,
+	},
+	{
+		Name:        "vertical-metrics",
+		Description: "The OS/2 typographic ascender, descender and line gap agree with hhea.",
+		Check:       checkVerticalMetrics,
+	},
+	{
+		Name:        "weight-class",
+		Description: "The OS/2 weight class matches the weight in the subfamily name.",
+		Check:       checkWeightClass,
+	},
+	{
+		Name:        "style-bits",
+		Description: "The bold and italic bits of head.MacStyle and OS/2.FsSelection agree.",
+		Check:       checkStyleBits,
+	},
+}
+
+func errorf(format string, args ...interface{}) Finding {
+	return Finding{Severity: Error, Message: fmt.Sprintf(format, args...)}
+}
+
+func warningf(format string, args ...interface{}) Finding {
+	return Finding{Severity: Warning, Message: fmt.Sprintf(format, args...)}
+}
+
+func checkRequiredNames(font *sfnt.Font) ([]Finding, error) {
+	if !font.HasTable(sfnt.TagName) {
+		return []Finding{errorf("missing %q table", sfnt.TagName)}, nil
+	}
+	name, err := font.NameTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, id := range []sfnt.NameID{sfnt.NameFontFamily, sfnt.NameFontSubfamily, sfnt.NameFull, sfnt.NamePostscript} {
+		if entry := name.Find(id); entry == nil || entry.String() == "" {
+			findings = append(findings, errorf("missing name ID %d (%s)", id, id))
+		}
+	}
+	return findings, nil
+}
+
+func checkPostScriptName(font *sfnt.Font) ([]Finding, error) {
+	if !font.HasTable(sfnt.TagName) {
+		return nil, nil
+	}
+	name, err := font.NameTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, entry := range name.List() {
+		if entry.NameID != sfnt.NamePostscript {
+			continue
+		}
+
+		value := entry.String()
+		if len(value) > 63 {
+			findings = append(findings, errorf("%s PostScript name %q is longer than 63 characters", entry.Platform(), value))
+		}
+		for _, r := range value {
+			if r < 33 || r > 126 || strings.ContainsRune("[](){}<>/%", r) {
+				findings = append(findings, errorf("%s PostScript name %q contains invalid character %q", entry.Platform(), value, r))
+				break
+			}
+		}
+	}
+	return findings, nil
+}
+
+func checkVerticalMetrics(font *sfnt.Font) ([]Finding, error) {
+	if !font.HasTable(sfnt.TagOS2) || !font.HasTable(sfnt.TagHhea) {
+		return nil, nil
+	}
+	os2, err := font.OS2Table()
+	if err != nil {
+		return nil, err
+	}
+	hhea, err := font.HheaTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	if os2.STypoAscender != hhea.Ascent {
+		findings = append(findings, warningf("OS/2 sTypoAscender %d does not match hhea ascender %d", os2.STypoAscender, hhea.Ascent))
+	}
+	if os2.STypoDescender != hhea.Descent {
+		findings = append(findings, warningf("OS/2 sTypoDescender %d does not match hhea descender %d", os2.STypoDescender, hhea.Descent))
+	}
+	if os2.STypoLineGap != hhea.LineGap {
+		findings = append(findings, warningf("OS/2 sTypoLineGap %d does not match hhea lineGap %d", os2.STypoLineGap, hhea.LineGap))
+	}
+	return findings, nil
+}
+
+// weightNames maps the weight names used in subfamily names to weight classes.
+// Longer names come first, so that "semibold" is matched before "bold".
+var weightNames = []struct {
+	name   string
+	weight uint16
+}{
+	{"extralight", 200},
+	{"ultralight", 200},
+	{"extrabold", 800},
+	{"ultrabold", 800},
+	{"semibold", 600},
+	{"demibold", 600},
+	{"semilight", 350},
+	{"demilight", 350},
+	{"hairline", 100},
+	{"regular", 400},
+	{"medium", 500},
+	{"normal", 400},
+	{"black", 900},
+	{"heavy", 900},
+	{"light", 300},
+	{"thin", 100},
+	{"book", 400},
+	{"bold", 700},
+}
+
+// subfamilyWeight returns the weight class implied by a subfamily name.
+// Names without a weight, such as "Italic", are regular.
+func subfamilyWeight(subfamily string) (string, uint16) {
+	normalized := strings.NewReplacer(" ", "", "-", "", "_", "").Replace(strings.ToLower(subfamily))
+	for _, w := range weightNames {
+		if strings.Contains(normalized, w.name) {
+			return w.name, w.weight
+		}
+	}
+	return "regular", 400
+}
+
+func checkWeightClass(font *sfnt.Font) ([]Finding, error) {
+	if !font.HasTable(sfnt.TagOS2) || !font.HasTable(sfnt.TagName) {
+		return nil, nil
+	}
+	os2, err := font.OS2Table()
+	if err != nil {
+		return nil, err
+	}
+	name, err := font.NameTable()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := name.Find(sfnt.NamePreferredSubfamily)
+	if entry == nil {
+		entry = name.Find(sfnt.NameFontSubfamily)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	weightName, weight := subfamilyWeight(entry.String())
+	if os2.USWeightClass != weight {
+		return []Finding{warningf("OS/2 usWeightClass %d does not match subfamily %q (%s is %d)", os2.USWeightClass, entry.String(), weightName, weight)}, nil
+	}
+	return nil, nil
+}
+
+// Bits of head.MacStyle.
+const (
+	macStyleBold   = 1 << 0
+	macStyleItalic = 1 << 1
+)
+
+// Bits of OS/2.FsSelection.
+const (
+	fsSelectionItalic  = 1 << 0
+	fsSelectionBold    = 1 << 5
+	fsSelectionRegular = 1 << 6
+)
+
+func checkStyleBits(font *sfnt.Font) ([]Finding, error) {
+	if !font.HasTable(sfnt.TagOS2) || !font.HasTable(sfnt.TagHead) {
+		return nil, nil
+	}
+	os2, err := font.OS2Table()
+	if err != nil {
+		return nil, err
+	}
+	head, err := font.HeadTable()
+	if err != nil {
+		return nil, err
+	}
+
+	macBold, macItalic := head.MacStyle&macStyleBold != 0, head.MacStyle&macStyleItalic != 0
+	fsBold, fsItalic := os2.FsSelection&fsSelectionBold != 0, os2.FsSelection&fsSelectionItalic != 0
+
+	var findings []Finding
+	if macBold != fsBold {
+		findings = append(findings, errorf("head.MacStyle bold is %t but OS/2.FsSelection bold is %t", macBold, fsBold))
+	}
+	if macItalic != fsItalic {
+		findings = append(findings, errorf("head.MacStyle italic is %t but OS/2.FsSelection italic is %t", macItalic, fsItalic))
+	}
+	if os2.FsSelection&fsSelectionRegular != 0 && (fsBold || fsItalic) {
+		findings = append(findings, errorf("OS/2.FsSelection has the regular bit set with bold or italic"))
+	}
+	return findings, nil
+}
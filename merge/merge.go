@@ -0,0 +1,834 @@
+// Package merge combines several fonts into one, with the glyphs, characters
+// and layout features of each, for example to add the glyphs of another
+// script to a font.
+//
+// The fonts must all have TrueType outlines, or all have 'CFF ' outlines,
+// which are merged into a CID-keyed 'CFF ' table. Fonts with 'CFF2'
+// outlines, and variable fonts, are not supported.
+package merge
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ConradIrwin/font/internal/layout"
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+var tagGDEF = sfnt.MustNamedTag("GDEF")
+
+// ErrUnsupported is wrapped by the error returned from Merge for fonts that
+// cannot be merged by this package.
+var ErrUnsupported = errors.New("unsupported font")
+
+// Options controls how fonts are merged.
+type Options struct {
+	// ScaleUnits scales the fonts whose unitsPerEm differ from that of the
+	// first font to its units, instead of returning an error. The merged
+	// font is dehinted if any font is scaled.
+	ScaleUnits bool
+	// CmapPrecedence contains the indexes of the fonts whose glyphs are
+	// used for the characters mapped by several fonts, from the highest
+	// precedence. The fonts that are not listed follow in order, so that by
+	// default the first font that maps a character is used.
+	CmapPrecedence []int
+}
+
+// merger holds the state used while merging fonts.
+type merger struct {
+	fonts []*sfnt.Font
+	opts  Options
+	out   *sfnt.Font
+
+	// scales contains the scale from the units of each font to those of the
+	// merged font.
+	scales []layout.Scale
+	// glyphs maps the glyphs of each font to the merged font.
+	glyphs []layout.GlyphMap
+	// order contains the font and glyph of each glyph of the merged font.
+	order []fontGlyph
+	// bounds contains the bounding box of each glyph of the merged font.
+	bounds []glyphBounds
+	// metrics contains the horizontal metrics of the merged font.
+	metrics []sfnt.HMetric
+
+	// cff is set if the fonts have 'CFF ' outlines instead of TrueType outlines.
+	cff  bool
+	head *sfnt.TableHead
+	// dehint is set when the hinting of the fonts cannot be merged.
+	dehint bool
+
+	// layout merges the layout tables, numbering the mark classes of each
+	// font in 'GDEF' for the lookups of 'GSUB' and 'GPOS'.
+	layout *layout.Merger
+}
+
+type fontGlyph struct {
+	font  int
+	glyph sfnt.GlyphID
+}
+
+type glyphBounds struct {
+	empty                  bool
+	xMin, yMin, xMax, yMax int16
+}
+
+// Merge returns a font containing the glyphs of all fonts, which must all
+// have TrueType outlines or all have 'CFF ' outlines, as an error wrapping
+// ErrUnsupported is returned for other fonts. The glyphs of each font follow
+// those of the previous fonts, except for the missing glyph, which is that
+// of the first font.
+//
+// The 'CFF ' tables are merged into a CID-keyed table, whose FDArray has the
+// Private DICTs of all fonts, so the glyph names in the charset of name-keyed
+// fonts are not kept. 'CFF ' outlines are not scaled, so the fonts must have
+// the same unitsPerEm, and a 'VORG' table is merged if any font has one.
+//
+// The characters mapped by several fonts are mapped to the glyph chosen
+// by opts. The 'GSUB' and 'GPOS' features of each font apply to its own
+// glyphs, in the scripts of the font, or in every script if the font has
+// a default script. Glyph names that collide are renamed by adding a
+// suffix such as ".1". The 'head', 'name' and 'gasp' tables are those of
+// the first font, and the metrics of 'hhea' and 'OS/2' are combined to fit
+// all glyphs. The hinting is kept if all fonts have the same hinting tables,
+// and the tables that are not merged are not kept.
+//
+// The fonts are not modified, though tables are shared with the merged font.
+func Merge(fonts []*sfnt.Font, opts Options) (*sfnt.Font, error) {
+	if len(fonts) == 0 {
+		return nil, errors.New("no fonts to merge")
+	}
+	m := &merger{fonts: fonts, opts: opts, out: sfnt.New(fonts[0].Type())}
+
+	steps := []func() error{
+		m.checkFonts,
+		m.mapGlyphs,
+		m.copyTables,
+		m.mergeOutlines,
+		m.mergeMetrics,
+		m.mergeVertical,
+		m.mergeVORG,
+		m.mergeCmap,
+		m.mergeGDEF,
+		m.mergeLayout,
+		m.mergePost,
+		m.mergeOS2,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	if m.dehint {
+		if err := m.out.Dehint(); err != nil {
+			return nil, err
+		}
+	}
+	return m.out, nil
+}
+
+// checkFonts checks that the fonts can be merged, and computes their scales.
+func (m *merger) checkFonts() error {
+	var unitsPerEm uint16
+	for i, font := range m.fonts {
+		for _, tag := range []sfnt.Tag{sfnt.TagCFF2, sfnt.TagFvar} {
+			if font.HasTable(tag) {
+				return fmt.Errorf("%w: merging fonts with %q tables is not supported", ErrUnsupported, tag)
+			}
+		}
+		cff := font.HasTable(sfnt.TagCFF)
+		if i == 0 {
+			m.cff = cff
+		} else if cff != m.cff {
+			return fmt.Errorf("%w: merging fonts with TrueType and CFF outlines is not supported", ErrUnsupported)
+		}
+		if !cff && !font.HasTable(sfnt.TagGlyf) {
+			return fmt.Errorf("font %d: %w %q", i, sfnt.ErrMissingTable, sfnt.TagGlyf)
+		}
+
+		head, err := font.HeadTable()
+		if err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagHead, err)
+		}
+		if head.UnitsPerEm == 0 {
+			return fmt.Errorf("font %d: invalid unitsPerEm 0", i)
+		}
+		if i == 0 {
+			unitsPerEm = head.UnitsPerEm
+		} else if head.UnitsPerEm != unitsPerEm && !m.opts.ScaleUnits {
+			return fmt.Errorf("font %d: unitsPerEm %d differs from %d", i, head.UnitsPerEm, unitsPerEm)
+		}
+		m.scales = append(m.scales, layout.Scale(float64(unitsPerEm)/float64(head.UnitsPerEm)))
+		if m.cff && m.scales[i] != 1 {
+			return fmt.Errorf("%w: scaling CFF outlines is not supported", ErrUnsupported)
+		}
+	}
+	return nil
+}
+
+// mapGlyphs numbers the glyphs of the merged font.
+func (m *merger) mapGlyphs() error {
+	for i, font := range m.fonts {
+		maxp, err := font.MaxpTable()
+		if err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagMaxp, err)
+		}
+		glyphs := make(layout.GlyphMap, maxp.NumGlyphs)
+		first := 0
+		if i > 0 {
+			first = 1 // Only the missing glyph of the first font is kept.
+		}
+		for g := first; g < int(maxp.NumGlyphs); g++ {
+			glyphs[sfnt.GlyphID(g)] = sfnt.GlyphID(len(m.order))
+			m.order = append(m.order, fontGlyph{font: i, glyph: sfnt.GlyphID(g)})
+		}
+		m.glyphs = append(m.glyphs, glyphs)
+	}
+	if len(m.order) > math.MaxUint16 {
+		return fmt.Errorf("merged font has %d glyphs, more than the maximum of %d", len(m.order), math.MaxUint16)
+	}
+	return nil
+}
+
+// copyTables copies 'head', 'name' and 'gasp' from the first font, and the
+// hinting tables if they are the same in all fonts.
+func (m *merger) copyTables() error {
+	head, err := m.fonts[0].HeadTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHead, err)
+	}
+	newHead := *head
+	m.head = &newHead
+	m.out.AddTable(sfnt.TagHead, m.head)
+
+	for _, tag := range []sfnt.Tag{sfnt.TagName, sfnt.TagGasp} {
+		if !m.fonts[0].HasTable(tag) {
+			continue
+		}
+		table, err := m.fonts[0].Table(tag)
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", tag, err)
+		}
+		m.out.AddTable(tag, table)
+	}
+
+	// The instructions of the glyphs use the functions and values of these
+	// tables, so fonts can only be merged with different tables by
+	// removing the hinting.
+	for _, tag := range []sfnt.Tag{sfnt.TagCvt, sfnt.TagFpgm, sfnt.TagPrep} {
+		var first sfnt.Table
+		var firstBytes []byte
+		for i, font := range m.fonts {
+			var buf []byte
+			if font.HasTable(tag) {
+				table, err := font.Table(tag)
+				if err != nil {
+					return fmt.Errorf("font %d: parsing %q: %w", i, tag, err)
+				}
+				if i == 0 {
+					first = table
+				}
+				buf = table.Bytes()
+			}
+			if i == 0 {
+				firstBytes = buf
+			} else if !bytes.Equal(buf, firstBytes) {
+				m.dehint = true
+			}
+		}
+		if first != nil {
+			m.out.AddTable(tag, first)
+		}
+	}
+	for _, s := range m.scales {
+		if s != 1 {
+			m.dehint = true
+		}
+	}
+	return nil
+}
+
+// mergeOutlines merges the outlines of the glyphs, and updates the bounding
+// box in 'head'.
+func (m *merger) mergeOutlines() error {
+	m.bounds = make([]glyphBounds, len(m.order))
+	merge := m.mergeGlyf
+	if m.cff {
+		merge = m.mergeCFF
+	}
+	if err := merge(); err != nil {
+		return err
+	}
+
+	first := true
+	for _, b := range m.bounds {
+		if b.empty {
+			continue
+		}
+		if first || b.xMin < m.head.XMin {
+			m.head.XMin = b.xMin
+		}
+		if first || b.yMin < m.head.YMin {
+			m.head.YMin = b.yMin
+		}
+		if first || b.xMax > m.head.XMax {
+			m.head.XMax = b.xMax
+		}
+		if first || b.yMax > m.head.YMax {
+			m.head.YMax = b.yMax
+		}
+		first = false
+	}
+	return nil
+}
+
+// mergeGlyf merges 'glyf' and 'loca', renumbering the components of
+// composite glyphs.
+func (m *merger) mergeGlyf() error {
+	glyfs := make([]*sfnt.TableGlyf, len(m.fonts))
+	for i, font := range m.fonts {
+		var err error
+		if glyfs[i], err = font.GlyfTable(); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagGlyf, err)
+		}
+	}
+
+	glyphs := make([][]byte, len(m.order))
+	for n, g := range m.order {
+		glyph, err := glyfs[g.font].Glyph(g.glyph)
+		if err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", g.font, sfnt.TagGlyf, err)
+		}
+		if glyph.IsEmpty() {
+			m.bounds[n].empty = true
+			continue
+		}
+
+		s := m.scales[g.font]
+		if s != 1 {
+			// Rounding keeps the order of coordinates, so the bounding box
+			// of the scaled glyph is the scaled bounding box.
+			glyph.XMin, glyph.YMin = s.Coordinate(glyph.XMin), s.Coordinate(glyph.YMin)
+			glyph.XMax, glyph.YMax = s.Coordinate(glyph.XMax), s.Coordinate(glyph.YMax)
+			for j := range glyph.Points {
+				glyph.Points[j].X, glyph.Points[j].Y = s.Coordinate(glyph.Points[j].X), s.Coordinate(glyph.Points[j].Y)
+			}
+			for j := range glyph.Components {
+				if c := &glyph.Components[j]; c.IsXYOffset() {
+					c.Arg1, c.Arg2 = int32(s.Coordinate(int16(c.Arg1))), int32(s.Coordinate(int16(c.Arg2)))
+				}
+			}
+		}
+		for j := range glyph.Components {
+			c := &glyph.Components[j]
+			if c.Glyph == 0 {
+				continue // The missing glyph of every font is that of the first font.
+			}
+			mapped, found := m.glyphs[g.font][c.Glyph]
+			if !found {
+				return fmt.Errorf("font %d: glyph %d: component %d out of range", g.font, g.glyph, c.Glyph)
+			}
+			c.Glyph = mapped
+		}
+		if s != 1 || glyph.IsComposite() {
+			glyphs[n] = glyph.Bytes()
+		} else {
+			glyphs[n], _ = glyfs[g.font].GlyphBytes(g.glyph)
+		}
+
+		m.bounds[n] = glyphBounds{xMin: glyph.XMin, yMin: glyph.YMin, xMax: glyph.XMax, yMax: glyph.YMax}
+	}
+
+	glyf, loca := sfnt.NewTableGlyf(glyphs)
+	m.out.AddTable(sfnt.TagGlyf, glyf)
+	m.out.AddTable(sfnt.TagLoca, loca)
+	m.head.IndexToLocFormat = 1
+	if loca.Short {
+		m.head.IndexToLocFormat = 0
+	}
+	return nil
+}
+
+// mergeCFF merges the 'CFF ' tables into a CID-keyed table.
+func (m *merger) mergeCFF() error {
+	tables := make([]*sfnt.TableCFF, len(m.fonts))
+	for i, font := range m.fonts {
+		var err error
+		if tables[i], err = font.CFFTable(); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagCFF, err)
+		}
+	}
+	// The glyphs of each font follow those of the previous fonts, as they
+	// do in the merged table.
+	glyphs := make([][]sfnt.GlyphID, len(m.fonts))
+	for _, g := range m.order {
+		glyphs[g.font] = append(glyphs[g.font], g.glyph)
+	}
+	cff, err := sfnt.MergeCFF(tables, glyphs)
+	if err != nil {
+		return fmt.Errorf("merging %q: %w", sfnt.TagCFF, err)
+	}
+
+	for n, g := range m.order {
+		xMin, yMin, xMax, yMax, err := cff.Bounds(sfnt.GlyphID(n))
+		if err != nil {
+			return fmt.Errorf("font %d: glyph %d: %w", g.font, g.glyph, err)
+		}
+		b := glyphBounds{xMin: xMin, yMin: yMin, xMax: xMax, yMax: yMax}
+		m.bounds[n] = b
+		m.bounds[n].empty = b == glyphBounds{}
+	}
+	m.out.AddTable(sfnt.TagCFF, cff)
+	return nil
+}
+
+// mergeMetrics merges 'hmtx', and updates 'hhea' and 'maxp' to fit all glyphs.
+func (m *merger) mergeMetrics() error {
+	hmtxs := make([]*sfnt.TableHmtx, len(m.fonts))
+	hheas := make([]*sfnt.TableHhea, len(m.fonts))
+	maxps := make([]*sfnt.TableMaxp, len(m.fonts))
+	for i, font := range m.fonts {
+		var err error
+		if hmtxs[i], err = font.HmtxTable(); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagHmtx, err)
+		}
+		if hheas[i], err = font.HheaTable(); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagHhea, err)
+		}
+		if maxps[i], err = font.MaxpTable(); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagMaxp, err)
+		}
+	}
+
+	m.metrics = make([]sfnt.HMetric, len(m.order))
+	for n, g := range m.order {
+		metric := hmtxs[g.font].Metric(g.glyph)
+		s := m.scales[g.font]
+		m.metrics[n] = sfnt.HMetric{AdvanceWidth: s.Distance(metric.AdvanceWidth), LeftSideBearing: s.Coordinate(metric.LeftSideBearing)}
+	}
+	hmtx := sfnt.NewTableHmtx(m.metrics)
+
+	hhea := *hheas[0]
+	for i, h := range hheas[1:] {
+		s := m.scales[i+1]
+		hhea.Ascent = maxInt16(hhea.Ascent, s.Coordinate(h.Ascent))
+		hhea.Descent = minInt16(hhea.Descent, s.Coordinate(h.Descent))
+		hhea.LineGap = maxInt16(hhea.LineGap, s.Coordinate(h.LineGap))
+	}
+	hhea.AdvanceWidthMax = 0
+	hhea.MinLeftSideBearing, hhea.MinRightSideBearing, hhea.XMaxExtent = math.MaxInt16, math.MaxInt16, math.MinInt16
+	for n, metric := range m.metrics {
+		if metric.AdvanceWidth > hhea.AdvanceWidthMax {
+			hhea.AdvanceWidthMax = metric.AdvanceWidth
+		}
+		b := m.bounds[n]
+		if b.empty {
+			continue
+		}
+		width := int(b.xMax) - int(b.xMin)
+		hhea.MinLeftSideBearing = minInt16(hhea.MinLeftSideBearing, metric.LeftSideBearing)
+		hhea.MinRightSideBearing = minInt16(hhea.MinRightSideBearing, clampInt16(int(metric.AdvanceWidth)-int(metric.LeftSideBearing)-width))
+		hhea.XMaxExtent = maxInt16(hhea.XMaxExtent, clampInt16(int(metric.LeftSideBearing)+width))
+	}
+	if hhea.XMaxExtent < hhea.MinLeftSideBearing {
+		hhea.MinLeftSideBearing, hhea.MinRightSideBearing, hhea.XMaxExtent = 0, 0, 0
+	}
+	hhea.NumOfLongHorMetrics = int16(len(hmtx.Metrics))
+	m.out.AddTable(sfnt.TagHhea, &hhea)
+	m.out.AddTable(sfnt.TagHmtx, hmtx)
+
+	maxp := *maxps[0]
+	maxp.NumGlyphs = uint16(len(m.order))
+	fields := func(maxp *sfnt.TableMaxp) []*uint16 {
+		return []*uint16{
+			&maxp.MaxPoints, &maxp.MaxContours, &maxp.MaxCompositePoints, &maxp.MaxCompositeContours,
+			&maxp.MaxZones, &maxp.MaxTwilightPoints, &maxp.MaxStorage, &maxp.MaxFunctionDefs,
+			&maxp.MaxInstructionDefs, &maxp.MaxStackElements, &maxp.MaxSizeOfInstructions,
+			&maxp.MaxComponentElements, &maxp.MaxComponentDepth,
+		}
+	}
+	merged := fields(&maxp)
+	for _, other := range maxps[1:] {
+		for j, v := range fields(other) {
+			if *v > *merged[j] {
+				*merged[j] = *v
+			}
+		}
+	}
+	m.out.AddTable(sfnt.TagMaxp, &maxp)
+	return nil
+}
+
+// mergeVertical merges 'vmtx' and updates 'vhea', if every font has them.
+func (m *merger) mergeVertical() error {
+	for _, font := range m.fonts {
+		if !font.HasTable(sfnt.TagVhea) || !font.HasTable(sfnt.TagVmtx) {
+			return nil
+		}
+	}
+	vmtxs := make([]*sfnt.TableVmtx, len(m.fonts))
+	vheas := make([]*sfnt.TableVhea, len(m.fonts))
+	for i, font := range m.fonts {
+		var err error
+		if vmtxs[i], err = font.VmtxTable(); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagVmtx, err)
+		}
+		if vheas[i], err = font.VheaTable(); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagVhea, err)
+		}
+	}
+
+	metrics := make([]sfnt.VMetric, len(m.order))
+	for n, g := range m.order {
+		metric := vmtxs[g.font].Metric(g.glyph)
+		s := m.scales[g.font]
+		metrics[n] = sfnt.VMetric{AdvanceHeight: s.Distance(metric.AdvanceHeight), TopSideBearing: s.Coordinate(metric.TopSideBearing)}
+	}
+	vmtx := sfnt.NewTableVmtx(metrics)
+
+	vhea := *vheas[0]
+	for i, v := range vheas[1:] {
+		s := m.scales[i+1]
+		vhea.Ascent = maxInt16(vhea.Ascent, s.Coordinate(v.Ascent))
+		vhea.Descent = minInt16(vhea.Descent, s.Coordinate(v.Descent))
+		vhea.LineGap = maxInt16(vhea.LineGap, s.Coordinate(v.LineGap))
+	}
+	vhea.AdvanceHeightMax = 0
+	vhea.MinTopSideBearing, vhea.MinBottomSideBearing, vhea.YMaxExtent = math.MaxInt16, math.MaxInt16, math.MinInt16
+	for n, metric := range metrics {
+		if metric.AdvanceHeight > vhea.AdvanceHeightMax {
+			vhea.AdvanceHeightMax = metric.AdvanceHeight
+		}
+		b := m.bounds[n]
+		if b.empty {
+			continue
+		}
+		height := int(b.yMax) - int(b.yMin)
+		vhea.MinTopSideBearing = minInt16(vhea.MinTopSideBearing, metric.TopSideBearing)
+		vhea.MinBottomSideBearing = minInt16(vhea.MinBottomSideBearing, clampInt16(int(metric.AdvanceHeight)-int(metric.TopSideBearing)-height))
+		vhea.YMaxExtent = maxInt16(vhea.YMaxExtent, clampInt16(int(metric.TopSideBearing)+height))
+	}
+	if vhea.YMaxExtent < vhea.MinTopSideBearing {
+		vhea.MinTopSideBearing, vhea.MinBottomSideBearing, vhea.YMaxExtent = 0, 0, 0
+	}
+	vhea.NumOfLongVerMetrics = uint16(len(vmtx.Metrics))
+	m.out.AddTable(sfnt.TagVhea, &vhea)
+	m.out.AddTable(sfnt.TagVmtx, vmtx)
+	return nil
+}
+
+// mergeVORG merges the vertical origins of 'VORG', if any font has it,
+// with the default vertical origin of the first of those fonts. The glyphs
+// of the fonts without 'VORG' have the vertical origins they are laid out
+// with, which are listed if they differ from the default.
+func (m *merger) mergeVORG() error {
+	var vorg *sfnt.TableVORG
+	for i, font := range m.fonts {
+		if !font.HasTable(sfnt.TagVORG) {
+			continue
+		}
+		t, err := font.VORGTable()
+		if err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagVORG, err)
+		}
+		copied := *t
+		copied.DefaultVertOriginY = m.scales[i].Coordinate(t.DefaultVertOriginY)
+		copied.Origins = nil
+		vorg = &copied
+		break
+	}
+	if vorg == nil {
+		return nil
+	}
+
+	for n, g := range m.order {
+		origin, err := m.fonts[g.font].VerticalOrigin(g.glyph, nil)
+		if err != nil {
+			return fmt.Errorf("font %d: glyph %d: %w", g.font, g.glyph, err)
+		}
+		if y := m.scales[g.font].Coordinate(clampInt16(int(math.Round(origin)))); y != vorg.DefaultVertOriginY {
+			vorg.Origins = append(vorg.Origins, sfnt.VertOrigin{Glyph: sfnt.GlyphID(n), VertOriginY: y})
+		}
+	}
+	m.out.AddTable(sfnt.TagVORG, vorg)
+	return nil
+}
+
+// mergeCmap maps the characters of all fonts, using the fonts in the order
+// of precedence of opts.
+func (m *merger) mergeCmap() error {
+	var order []int
+	listed := make(map[int]bool)
+	for _, i := range m.opts.CmapPrecedence {
+		if i < 0 || i >= len(m.fonts) {
+			return fmt.Errorf("cmap precedence: font %d out of range", i)
+		}
+		if !listed[i] {
+			order = append(order, i)
+			listed[i] = true
+		}
+	}
+	for i := range m.fonts {
+		if !listed[i] {
+			order = append(order, i)
+		}
+	}
+
+	mappings := make(map[rune]sfnt.GlyphID)
+	for _, i := range order {
+		cmap, err := m.fonts[i].CmapTable()
+		if err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagCmap, err)
+		}
+		unicode := cmap.UnicodeSubtable()
+		if unicode == nil {
+			continue
+		}
+		for r, g := range unicode.Mappings {
+			if _, found := mappings[r]; found {
+				continue
+			}
+			if mapped, found := m.glyphs[i][g]; found && mapped != 0 {
+				mappings[r] = mapped
+			}
+		}
+	}
+	m.out.AddTable(sfnt.TagCmap, sfnt.NewTableCmap(mappings))
+	return nil
+}
+
+// mergeGDEF merges the 'GDEF' tables, renumbering the mark attachment
+// classes and mark glyph sets of each font after those of the previous fonts.
+func (m *merger) mergeGDEF() error {
+	tables := make([]*layout.GDEF, len(m.fonts))
+	for i, font := range m.fonts {
+		if !font.HasTable(tagGDEF) {
+			continue
+		}
+		table, err := font.Table(tagGDEF)
+		if err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, tagGDEF, err)
+		}
+		if tables[i], err = layout.ParseGDEF(table.Bytes()); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, tagGDEF, err)
+		}
+	}
+	m.layout = &layout.Merger{Glyphs: m.glyphs, Scales: m.scales}
+	buf, err := m.layout.MergeGDEF(tables)
+	if err != nil {
+		return fmt.Errorf("merging %q: %w", tagGDEF, err)
+	}
+	if buf == nil {
+		return nil
+	}
+	return addRawTable(m.out, tagGDEF, buf)
+}
+
+// mergeLayout merges the 'GSUB' and 'GPOS' tables.
+func (m *merger) mergeLayout() error {
+	for _, tag := range []sfnt.Tag{sfnt.TagGsub, sfnt.TagGpos} {
+		tables := make([]*layout.Table, len(m.fonts))
+		found := false
+		for i, font := range m.fonts {
+			if !font.HasTable(tag) {
+				continue
+			}
+			table, err := font.Table(tag)
+			if err != nil {
+				return fmt.Errorf("font %d: parsing %q: %w", i, tag, err)
+			}
+			if tables[i], err = layout.Parse(tag, table.Bytes()); err != nil {
+				return fmt.Errorf("font %d: parsing %q: %w", i, tag, err)
+			}
+			if tables[i].HasFeatureVariations() {
+				return fmt.Errorf("%w: merging feature variations is not supported", ErrUnsupported)
+			}
+			found = true
+		}
+		if !found {
+			continue
+		}
+
+		buf, err := m.layout.MergeLayout(tag, tables)
+		if err != nil {
+			return fmt.Errorf("merging %q: %w", tag, err)
+		}
+		if err := addRawTable(m.out, tag, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergePost merges the glyph names of 'post', renaming the glyphs whose
+// names are already used by adding a suffix such as ".1". Glyphs without
+// names are named after their glyph ID.
+func (m *merger) mergePost() error {
+	posts := make([]*sfnt.TablePost, len(m.fonts))
+	named := false
+	for i, font := range m.fonts {
+		if !font.HasTable(sfnt.TagPost) {
+			continue
+		}
+		var err error
+		if posts[i], err = font.PostTable(); err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagPost, err)
+		}
+		named = named || posts[i].GlyphNames != nil
+	}
+	if posts[0] == nil {
+		return nil
+	}
+
+	post := *posts[0]
+	// The memory usage depends on the glyphs.
+	post.MinMemType42, post.MaxMemType42, post.MinMemType1, post.MaxMemType1 = 0, 0, 0, 0
+	for _, p := range posts {
+		if p != nil && p.IsFixedPitch == 0 {
+			post.IsFixedPitch = 0
+		}
+	}
+
+	post.GlyphNames = nil
+	if named {
+		post.GlyphNames = make([]string, len(m.order))
+		used := make(map[string]bool, len(m.order))
+		for n, g := range m.order {
+			var name string
+			if p := posts[g.font]; p != nil && int(g.glyph) < len(p.GlyphNames) {
+				name = p.GlyphNames[g.glyph]
+			}
+			if name == "" {
+				name = fmt.Sprintf("glyph%05d", n)
+			}
+			for k := 1; used[name]; k++ {
+				if candidate := fmt.Sprintf("%s.%d", name, k); !used[candidate] {
+					name = candidate
+				}
+			}
+			used[name] = true
+			post.GlyphNames[n] = name
+		}
+	}
+	m.out.AddTable(sfnt.TagPost, &post)
+	return nil
+}
+
+// mergeOS2 combines the metrics and character ranges of 'OS/2'.
+func (m *merger) mergeOS2() error {
+	if !m.fonts[0].HasTable(sfnt.TagOS2) {
+		return nil
+	}
+	var os2 *sfnt.TableOS2
+	for i, font := range m.fonts {
+		if !font.HasTable(sfnt.TagOS2) {
+			continue
+		}
+		t, err := font.OS2Table()
+		if err != nil {
+			return fmt.Errorf("font %d: parsing %q: %w", i, sfnt.TagOS2, err)
+		}
+		if i == 0 {
+			copied := *t
+			os2 = &copied
+			continue
+		}
+		s := m.scales[i]
+		os2.STypoAscender = maxInt16(os2.STypoAscender, s.Coordinate(t.STypoAscender))
+		os2.STypoDescender = minInt16(os2.STypoDescender, s.Coordinate(t.STypoDescender))
+		os2.STypoLineGap = maxInt16(os2.STypoLineGap, s.Coordinate(t.STypoLineGap))
+		if d := s.Distance(t.UsWinAscent); d > os2.UsWinAscent {
+			os2.UsWinAscent = d
+		}
+		if d := s.Distance(t.UsWinDescent); d > os2.UsWinDescent {
+			os2.UsWinDescent = d
+		}
+		for j := range os2.UlCharRange {
+			os2.UlCharRange[j] |= t.UlCharRange[j]
+		}
+		os2.UlCodePageRange1 |= t.UlCodePageRange1
+		os2.UlCodePageRange2 |= t.UlCodePageRange2
+		if t.UsMaxContext > os2.UsMaxContext {
+			os2.UsMaxContext = t.UsMaxContext
+		}
+		os2.FSType = mergeFSType(os2.FSType, t.FSType)
+	}
+
+	var total, count int
+	for _, metric := range m.metrics {
+		if metric.AdvanceWidth != 0 {
+			total += int(metric.AdvanceWidth)
+			count++
+		}
+	}
+	if count > 0 {
+		os2.XAvgCharWidth = uint16((total + count/2) / count)
+	}
+
+	cmap, err := m.out.CmapTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagCmap, err)
+	}
+	os2.FsFirstCharIndex, os2.FsLastCharIndex = cmap.CharIndexRange()
+	m.out.AddTable(sfnt.TagOS2, os2)
+	return nil
+}
+
+// embeddingRestrictions are the embedding permissions of 'OS/2' fsType,
+// from the least to the most restrictive.
+var embeddingRestrictions = []uint16{0x0000, 0x0008, 0x0004, 0x0002}
+
+// mergeFSType returns the most restrictive embedding permissions of a and b.
+func mergeFSType(a, b uint16) uint16 {
+	const usage = 0x000F
+	restriction := func(fsType uint16) int {
+		for i := len(embeddingRestrictions) - 1; i > 0; i-- {
+			if fsType&embeddingRestrictions[i] != 0 {
+				return i
+			}
+		}
+		return 0
+	}
+	merged := a
+	if restriction(b) > restriction(a) {
+		merged = b
+	}
+	return merged&usage | (a|b)&^usage
+}
+
+func minInt16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func clampInt16(v int) int16 {
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	return int16(v)
+}
+
+// addRawTable adds a table written as bytes to font.
+func addRawTable(font *sfnt.Font, tag sfnt.Tag, buf []byte) error {
+	table, err := sfnt.ParseTable(tag, buf)
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", tag, err)
+	}
+	font.AddTable(tag, table)
+	return nil
+}
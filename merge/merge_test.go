@@ -0,0 +1,331 @@
+package merge
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ConradIrwin/font/internal/layout"
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+func parseFont(t *testing.T, filename string) *sfnt.Font {
+	t.Helper()
+	buf, err := os.ReadFile(filepath.Join("..", "sfnt", "testdata", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := sfnt.Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+func parseCollection(t *testing.T, filename string) []*sfnt.Font {
+	t.Helper()
+	buf, err := os.ReadFile(filepath.Join("..", "sfnt", "testdata", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fonts, err := sfnt.ParseCollection(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fonts
+}
+
+// reparse writes the font and parses it again.
+func reparse(t *testing.T, font *sfnt.Font) *sfnt.Font {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := font.WriteOTF(&buf); err != nil {
+		t.Fatalf("WriteOTF() err = %q, want nil", err)
+	}
+	font, err := sfnt.StrictParse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("parsing the merged font: %q", err)
+	}
+	return font
+}
+
+func parseLayout(t *testing.T, font *sfnt.Font, tag sfnt.Tag) *layout.Table {
+	t.Helper()
+	table, err := font.Table(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := layout.Parse(tag, table.Bytes())
+	if err != nil {
+		t.Fatalf("parsing %q: %q", tag, err)
+	}
+	return l
+}
+
+func TestMerge(t *testing.T) {
+	font := parseFont(t, "Roboto-BoldItalic.ttf")
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	numGlyphs := sfnt.GlyphID(maxp.NumGlyphs)
+	cmap, err := font.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, v, f, i := cmap.Lookup('A'), cmap.Lookup('V'), cmap.Lookup('f'), cmap.Lookup('i')
+	fi, _ := parseLayout(t, font, sfnt.TagGsub).Ligature(f, i)
+	kern, _ := parseLayout(t, font, sfnt.TagGpos).Kerning(a, v)
+
+	// The glyphs of the second font follow those of the first, without its missing glyph.
+	second := func(g sfnt.GlyphID) sfnt.GlyphID { return g + numGlyphs - 1 }
+
+	for _, test := range []struct {
+		precedence []int
+		want       sfnt.GlyphID // want is the glyph of 'A' in the merged font.
+	}{
+		{want: a},
+		{precedence: []int{1}, want: second(a)},
+	} {
+		merged, err := Merge([]*sfnt.Font{font, font}, Options{CmapPrecedence: test.precedence})
+		if err != nil {
+			t.Fatalf("Merge() err = %q, want nil", err)
+		}
+		merged = reparse(t, merged)
+
+		mergedMaxp, err := merged.MaxpTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := mergedMaxp.NumGlyphs, 2*maxp.NumGlyphs-1; got != want {
+			t.Errorf("NumGlyphs = %d, want %d", got, want)
+		}
+		mergedCmap, err := merged.CmapTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mergedCmap.Lookup('A'); got != test.want {
+			t.Errorf("precedence %v: Lookup('A') = %d, want %d", test.precedence, got, test.want)
+		}
+
+		glyf, err := font.GlyfTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		mergedGlyf, err := merged.GlyfTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := glyf.GlyphBytes(v)
+		for _, g := range []sfnt.GlyphID{v, second(v)} {
+			if got, _ := mergedGlyf.GlyphBytes(g); !bytes.Equal(got, want) {
+				t.Errorf("glyph %d differs from glyph %d of the font", g, v)
+			}
+		}
+
+		// The features of both fonts apply to their own glyphs.
+		gsub, gpos := parseLayout(t, merged, sfnt.TagGsub), parseLayout(t, merged, sfnt.TagGpos)
+		for _, g := range [][3]sfnt.GlyphID{{f, i, fi}, {second(f), second(i), second(fi)}} {
+			if got, ok := gsub.Ligature(g[0], g[1]); !ok || got != g[2] {
+				t.Errorf("ligature of %d %d = %d, %t, want %d", g[0], g[1], got, ok, g[2])
+			}
+		}
+		for _, g := range [][2]sfnt.GlyphID{{a, v}, {second(a), second(v)}} {
+			if got, ok := gpos.Kerning(g[0], g[1]); !ok || got != kern {
+				t.Errorf("kerning of %d %d = %d, %t, want %d", g[0], g[1], got, ok, kern)
+			}
+		}
+		if got := gsub.NumLookups(); got != 2*parseLayout(t, font, sfnt.TagGsub).NumLookups() {
+			t.Errorf("merged 'GSUB' has %d lookups, want those of both fonts", got)
+		}
+	}
+}
+
+func TestMergeScale(t *testing.T) {
+	ttc := parseCollection(t, "TestTTC.ttc")[0]
+	roboto := parseFont(t, "Roboto-BoldItalic.ttf")
+
+	if _, err := Merge([]*sfnt.Font{ttc, roboto}, Options{}); err == nil {
+		t.Fatal("Merge() err = nil, want an error for different unitsPerEm")
+	}
+
+	merged, err := Merge([]*sfnt.Font{ttc, roboto}, Options{ScaleUnits: true})
+	if err != nil {
+		t.Fatalf("Merge() err = %q, want nil", err)
+	}
+	merged = reparse(t, merged)
+
+	head, err := ttc.HeadTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	robotoHead, err := roboto.HeadTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := layout.Scale(float64(head.UnitsPerEm) / float64(robotoHead.UnitsPerEm))
+	maxp, err := ttc.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := sfnt.GlyphID(maxp.NumGlyphs) - 1
+
+	cmap, err := roboto.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, v := cmap.Lookup('A'), cmap.Lookup('V')
+	glyf, err := roboto.GlyfTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergedGlyf, err := merged.GlyfTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := glyf.Glyph(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := mergedGlyf.Glyph(v + offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.XMax != s.Coordinate(want.XMax) || got.Points[0].X != s.Coordinate(want.Points[0].X) {
+		t.Errorf("glyph %d is not scaled by %g", v+offset, s)
+	}
+	if len(got.Instructions) != 0 || merged.HasTable(sfnt.TagFpgm) {
+		t.Error("merged font is hinted, want it dehinted after scaling")
+	}
+
+	kern, _ := parseLayout(t, roboto, sfnt.TagGpos).Kerning(a, v)
+	if got, ok := parseLayout(t, merged, sfnt.TagGpos).Kerning(a+offset, v+offset); !ok || got != s.Apply(kern)&0xFFFF {
+		t.Errorf("kerning = %d, %t, want %d", got, ok, s.Apply(kern)&0xFFFF)
+	}
+
+	hmtx, err := roboto.HmtxTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergedHmtx, err := merged.HmtxTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mergedHmtx.Metric(v+offset).AdvanceWidth, s.Distance(hmtx.Metric(v).AdvanceWidth); got != want {
+		t.Errorf("advance of %d = %d, want %d", v+offset, got, want)
+	}
+}
+
+func TestMergeCFF(t *testing.T) {
+	font := parseFont(t, "Raleway-v4020-Regular.otf")
+	// The second font has vertical origins, which are merged with those the
+	// glyphs of the first font are laid out with.
+	second := parseFont(t, "Raleway-v4020-Regular.otf")
+	cmap, err := font.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, v := cmap.Lookup('A'), cmap.Lookup('V')
+	second.AddTable(sfnt.TagVORG, &sfnt.TableVORG{DefaultVertOriginY: 880, Origins: []sfnt.VertOrigin{{Glyph: a, VertOriginY: 900}}})
+
+	merged, err := Merge([]*sfnt.Font{font, second}, Options{})
+	if err != nil {
+		t.Fatalf("Merge() err = %q, want nil", err)
+	}
+	merged = reparse(t, merged)
+
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset := sfnt.GlyphID(maxp.NumGlyphs) - 1
+	cff, err := font.CFFTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergedCFF, err := merged.CFFTable()
+	if err != nil {
+		t.Fatalf("parsing the merged %q: %q", sfnt.TagCFF, err)
+	}
+	if !mergedCFF.IsCIDKeyed() || len(mergedCFF.CharStrings) != 2*int(maxp.NumGlyphs)-1 {
+		t.Fatalf("merged %q has %d glyphs, CID-keyed %t, want %d CID-keyed glyphs", sfnt.TagCFF, len(mergedCFF.CharStrings), mergedCFF.IsCIDKeyed(), 2*maxp.NumGlyphs-1)
+	}
+	if merged.HasTable(sfnt.TagGlyf) {
+		t.Errorf("merged font has a %q table", sfnt.TagGlyf)
+	}
+
+	xMin, yMin, xMax, yMax, err := cff.Bounds(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []sfnt.GlyphID{v, v + offset} {
+		gotXMin, gotYMin, gotXMax, gotYMax, err := mergedCFF.Bounds(g)
+		if got, want := [4]int16{gotXMin, gotYMin, gotXMax, gotYMax}, [4]int16{xMin, yMin, xMax, yMax}; err != nil || got != want {
+			t.Errorf("Bounds(%d) = %v, %v, want %v", g, got, err, want)
+		}
+	}
+	head, err := font.HeadTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergedHead, err := merged.HeadTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mergedHead.XMin != head.XMin || mergedHead.YMin != head.YMin || mergedHead.XMax != head.XMax || mergedHead.YMax != head.YMax {
+		t.Errorf("merged bounding box differs from that of the font")
+	}
+
+	vorg, err := merged.VORGTable()
+	if err != nil {
+		t.Fatalf("parsing the merged %q: %q", sfnt.TagVORG, err)
+	}
+	ascender, err := font.VerticalOrigin(a, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for g, want := range map[sfnt.GlyphID]int16{a: int16(ascender), a + offset: 900, v + offset: 880} {
+		if got := vorg.Origin(g); got != want {
+			t.Errorf("vertical origin of %d = %d, want %d", g, got, want)
+		}
+	}
+}
+
+func TestMergeGlyphNames(t *testing.T) {
+	fonts := parseCollection(t, "TestTTC.ttc")
+	merged, err := Merge(fonts, Options{})
+	if err != nil {
+		t.Fatalf("Merge() err = %q, want nil", err)
+	}
+	merged = reparse(t, merged)
+
+	post, err := fonts[0].PostTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mergedPost, err := merged.PostTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(post.GlyphNames)
+	if len(mergedPost.GlyphNames) != 2*n-1 {
+		t.Fatalf("merged font has %d glyph names, want %d", len(mergedPost.GlyphNames), 2*n-1)
+	}
+	for i := 1; i < n; i++ {
+		if got, want := mergedPost.GlyphNames[i], post.GlyphNames[i]; got != want {
+			t.Errorf("GlyphNames[%d] = %q, want %q", i, got, want)
+		}
+		if got, want := mergedPost.GlyphNames[n+i-1], post.GlyphNames[i]+".1"; got != want {
+			t.Errorf("GlyphNames[%d] = %q, want %q", n+i-1, got, want)
+		}
+	}
+}
+
+func TestMergeUnsupported(t *testing.T) {
+	fonts := []*sfnt.Font{parseFont(t, "Roboto-BoldItalic.ttf"), parseFont(t, "Raleway-v4020-Regular.otf")}
+	if _, err := Merge(fonts, Options{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Merge() err = %v, want %q", err, ErrUnsupported)
+	}
+}
@@ -0,0 +1,181 @@
+// Package sanitize validates fonts before they are used, in the spirit of the
+// OpenType Sanitizer that browsers run on web fonts.
+//
+// Sanitize walks every table of a font, checking offsets, counts and the
+// invariants between tables. Broken required tables cause the font to be rejected,
+// while unknown or broken optional tables are dropped from the returned font.
+package sanitize
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+// ErrRejected is wrapped by the error returned from Sanitize when a font cannot be repaired.
+var ErrRejected = errors.New("font rejected")
+
+// Change describes a table that was removed or repaired by Sanitize.
+type Change struct {
+	Tag    sfnt.Tag
+	Reason string
+}
+
+// String returns a readable description of the change.
+func (c Change) String() string {
+	return fmt.Sprintf("%q: %s", c.Tag, c.Reason)
+}
+
+// Report lists what Sanitize changed in a font.
+type Report struct {
+	Removed  []Change // Removed contains the tables that were dropped.
+	Repaired []Change // Repaired contains the tables that were rewritten to fix problems.
+}
+
+// String returns a readable description of the report, one change per line.
+func (r *Report) String() string {
+	var str strings.Builder
+	for _, c := range r.Removed {
+		str.WriteString("removed " + c.String() + "\n")
+	}
+	for _, c := range r.Repaired {
+		str.WriteString("repaired " + c.String() + "\n")
+	}
+	return str.String()
+}
+
+// validator checks a table, returning the table to keep (which may be a repaired copy)
+// or an error if the table is broken.
+type validator func(c *checker, table sfnt.Table) (sfnt.Table, error)
+
+// validators contains the tables that Sanitize knows how to check,
+// all other tables are dropped.
+var validators = map[sfnt.Tag]validator{
+	sfnt.TagHead: validateHead,
+	sfnt.TagMaxp: validateMaxp,
+	sfnt.TagHhea: validateHhea,
+	sfnt.TagHmtx: validateHmtx,
+	sfnt.TagCmap: validateCmap,
+	sfnt.TagLoca: validateLoca,
+	sfnt.TagGlyf: validateGlyf,
+	sfnt.TagName: validateName,
+	sfnt.TagOS2:  validateOS2,
+	sfnt.TagPost: validatePost,
+	tagCFF:       validateCFF,
+	tagCFF2:      validateCFF2,
+	sfnt.TagGsub: validateLayout,
+	sfnt.TagGpos: validateLayout,
+	tagGDEF:      validateGDEF,
+	sfnt.TagGasp: validateGasp,
+	sfnt.TagCvt:  validateCvt,
+	sfnt.TagFpgm: validateInstructions,
+	sfnt.TagPrep: validateInstructions,
+	sfnt.TagFvar: validateFvar,
+	sfnt.TagAvar: validateAvar,
+	sfnt.TagGvar: validateGvar,
+	sfnt.TagHvar: validateHvar,
+	sfnt.TagVvar: validateHvar,
+	sfnt.TagMvar: validateMvar,
+	sfnt.TagSTAT: validateSTAT,
+	sfnt.TagCOLR: validateCOLR,
+	sfnt.TagCPAL: validateCPAL,
+	sfnt.TagSVG:  validateSVG,
+	sfnt.TagSbix: validateSbix,
+	sfnt.TagCBLC: validateBitmapLocation,
+	sfnt.TagEBLC: validateBitmapLocation,
+	sfnt.TagCBDT: validateBitmapData,
+	sfnt.TagEBDT: validateBitmapData,
+	sfnt.TagVhea: validateVhea,
+	sfnt.TagVmtx: validateVmtx,
+	sfnt.TagVORG: validateVORG,
+	sfnt.TagBASE: validateBASE,
+	sfnt.TagMATH: validateMATH,
+	sfnt.TagJSTF: validateJSTF,
+}
+
+// required contains the tables without which a font is rejected.
+var required = []sfnt.Tag{sfnt.TagHead, sfnt.TagMaxp, sfnt.TagHhea, sfnt.TagHmtx, sfnt.TagCmap}
+
+// checker holds the state shared between the validators of a font.
+type checker struct {
+	font      *sfnt.Font
+	numGlyphs int
+	report    *Report
+}
+
+func (c *checker) repaired(tag sfnt.Tag, format string, args ...interface{}) {
+	c.report.Repaired = append(c.report.Repaired, Change{Tag: tag, Reason: fmt.Sprintf(format, args...)})
+}
+
+// Sanitize checks every table in font, and returns a new font containing only
+// the tables that are known and valid, along with a report of what was removed
+// or repaired. The original font is not modified.
+//
+// If a table required to use the font is missing or broken, an error wrapping
+// ErrRejected is returned.
+func Sanitize(font *sfnt.Font) (*sfnt.Font, *Report, error) {
+	c := &checker{
+		font:   font,
+		report: &Report{},
+	}
+
+	for _, tag := range required {
+		if !font.HasTable(tag) {
+			return nil, c.report, fmt.Errorf("%w: missing %q table", ErrRejected, tag)
+		}
+	}
+
+	switch {
+	case font.HasTable(sfnt.TagGlyf) || font.HasTable(sfnt.TagLoca):
+		if !font.HasTable(sfnt.TagGlyf) || !font.HasTable(sfnt.TagLoca) {
+			return nil, c.report, fmt.Errorf("%w: 'glyf' and 'loca' tables must both be present", ErrRejected)
+		}
+	case font.HasTable(tagCFF) || font.HasTable(tagCFF2):
+	default:
+		return nil, c.report, fmt.Errorf("%w: no glyph outlines", ErrRejected)
+	}
+
+	// The number of glyphs is needed by most other tables, so check 'maxp' first.
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		return nil, c.report, fmt.Errorf("%w: %q: %s", ErrRejected, sfnt.TagMaxp, err)
+	}
+	c.numGlyphs = int(maxp.NumGlyphs)
+
+	out := sfnt.New(font.Type())
+	for _, tag := range font.Tags() {
+		validate, known := validators[tag]
+		if !known {
+			c.report.Removed = append(c.report.Removed, Change{Tag: tag, Reason: "unknown table"})
+			continue
+		}
+
+		table, err := font.Table(tag)
+		if err == nil {
+			table, err = validate(c, table)
+		}
+
+		if err != nil {
+			if isRequired(tag) {
+				return nil, c.report, fmt.Errorf("%w: %q: %s", ErrRejected, tag, err)
+			}
+			c.report.Removed = append(c.report.Removed, Change{Tag: tag, Reason: err.Error()})
+			continue
+		}
+
+		out.AddTable(tag, table)
+	}
+
+	return out, c.report, nil
+}
+
+func isRequired(tag sfnt.Tag) bool {
+	for _, t := range required {
+		if t == tag {
+			return true
+		}
+	}
+	return tag == sfnt.TagGlyf || tag == sfnt.TagLoca || tag == tagCFF || tag == tagCFF2
+}
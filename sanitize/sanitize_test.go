@@ -0,0 +1,160 @@
+package sanitize
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+func parseFixture(t *testing.T, filename string) []*sfnt.Font {
+	t.Helper()
+
+	buf, err := os.ReadFile(filepath.Join("..", "sfnt", "testdata", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fonts, err := sfnt.ParseCollection(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatalf("ParseCollection(%q) err = %q, want nil", filename, err)
+	}
+	return fonts
+}
+
+func TestSanitizeFixtures(t *testing.T) {
+	filenames := []string{
+		"Roboto-BoldItalic.ttf",
+		"Raleway-v4020-Regular.otf",
+		"open-sans-v15-latin-regular.woff",
+		"TestTTC.ttc",
+	}
+
+	for _, filename := range filenames {
+		for _, font := range parseFixture(t, filename) {
+			sanitized, report, err := Sanitize(font)
+			if err != nil {
+				t.Fatalf("Sanitize(%q) err = %q, want nil", filename, err)
+			}
+
+			for _, c := range report.Removed {
+				if _, known := validators[c.Tag]; known {
+					t.Errorf("Sanitize(%q) removed known table %s", filename, c)
+				}
+			}
+
+			var out bytes.Buffer
+			if _, err := sanitized.WriteOTF(&out); err != nil {
+				t.Fatalf("WriteOTF(Sanitize(%q)) err = %q, want nil", filename, err)
+			}
+			if _, err := sfnt.Parse(bytes.NewReader(out.Bytes())); err != nil {
+				t.Errorf("Parse(WriteOTF(Sanitize(%q))) err = %q, want nil", filename, err)
+			}
+		}
+	}
+}
+
+func TestSanitizeDropsUnknownTables(t *testing.T) {
+	font := parseFixture(t, "Roboto-BoldItalic.ttf")[0]
+
+	unknown := sfnt.MustNamedTag("zzzz")
+	font.AddTable(unknown, sfnt.NewTableName())
+
+	sanitized, report, err := Sanitize(font)
+	if err != nil {
+		t.Fatalf("Sanitize() err = %q, want nil", err)
+	}
+
+	if sanitized.HasTable(unknown) {
+		t.Errorf("Sanitize() kept unknown table %q", unknown)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].Tag != unknown {
+		t.Errorf("Sanitize() report = %q, want %q removed", report, unknown)
+	}
+}
+
+func TestSanitizeRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(font *sfnt.Font)
+	}{
+		{
+			name:   "missing cmap",
+			modify: func(font *sfnt.Font) { font.RemoveTable(sfnt.TagCmap) },
+		},
+		{
+			name:   "missing loca",
+			modify: func(font *sfnt.Font) { font.RemoveTable(sfnt.TagLoca) },
+		},
+		{
+			name: "bad head magic",
+			modify: func(font *sfnt.Font) {
+				head, _ := font.HeadTable()
+				head.MagicNumber = 0
+			},
+		},
+		{
+			name: "too many hmetrics",
+			modify: func(font *sfnt.Font) {
+				hhea, _ := font.HheaTable()
+				hhea.NumOfLongHorMetrics = -1
+			},
+		},
+	}
+
+	for _, test := range tests {
+		font := parseFixture(t, "Roboto-BoldItalic.ttf")[0]
+		test.modify(font)
+
+		_, _, err := Sanitize(font)
+		if !errors.Is(err, ErrRejected) {
+			t.Errorf("Sanitize(%s) err = %v, want %q", test.name, err, ErrRejected)
+		}
+	}
+}
+
+func TestSanitizeRejectsTransformedGlyf(t *testing.T) {
+	// The WOFF2 decoder does not reverse the 'glyf' and 'loca' transforms,
+	// so the outlines cannot be validated.
+	font := parseFixture(t, "Go-Regular.woff2")[0]
+
+	if _, _, err := Sanitize(font); !errors.Is(err, ErrRejected) {
+		t.Errorf("Sanitize() err = %v, want %q", err, ErrRejected)
+	}
+}
+
+func TestSanitizeRepairsCmap(t *testing.T) {
+	font := parseFixture(t, "Roboto-BoldItalic.ttf")[0]
+
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	font.AddTable(sfnt.TagCmap, sfnt.NewTableCmap(map[rune]sfnt.GlyphID{
+		'A': 38,
+		'B': sfnt.GlyphID(maxp.NumGlyphs),
+	}))
+
+	sanitized, report, err := Sanitize(font)
+	if err != nil {
+		t.Fatalf("Sanitize() err = %q, want nil", err)
+	}
+	if len(report.Repaired) != 1 || report.Repaired[0].Tag != sfnt.TagCmap {
+		t.Errorf("Sanitize() report = %q, want 'cmap' repaired", report)
+	}
+
+	cmap, err := sanitized.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cmap.Lookup('A'); got != 38 {
+		t.Errorf("Lookup('A') = %d, want 38", got)
+	}
+	if got := cmap.Lookup('B'); got != 0 {
+		t.Errorf("Lookup('B') = %d, want 0", got)
+	}
+}
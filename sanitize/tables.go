@@ -0,0 +1,760 @@
+package sanitize
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+var (
+	tagCFF  = sfnt.MustNamedTag("CFF ")
+	tagCFF2 = sfnt.MustNamedTag("CFF2")
+	tagGDEF = sfnt.MustNamedTag("GDEF")
+)
+
+const headMagicNumber = 0x5F0F3CF5
+
+func validateHead(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	head := table.(*sfnt.TableHead)
+
+	if head.MagicNumber != headMagicNumber {
+		return nil, fmt.Errorf("invalid magic number 0x%08x", head.MagicNumber)
+	}
+	if head.UnitsPerEm < 16 || head.UnitsPerEm > 16384 {
+		return nil, fmt.Errorf("unitsPerEm %d out of range", head.UnitsPerEm)
+	}
+	if head.IndexToLocFormat != 0 && head.IndexToLocFormat != 1 {
+		return nil, fmt.Errorf("invalid indexToLocFormat %d", head.IndexToLocFormat)
+	}
+	if head.XMin > head.XMax || head.YMin > head.YMax {
+		return nil, fmt.Errorf("invalid bounding box")
+	}
+
+	return head, nil
+}
+
+func validateMaxp(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	maxp := table.(*sfnt.TableMaxp)
+
+	if maxp.NumGlyphs == 0 {
+		return nil, errors.New("font has no glyphs")
+	}
+	if c.font.HasTable(sfnt.TagGlyf) && !maxp.HasV1Fields() {
+		return nil, errors.New("version 1.0 is required for TrueType outlines")
+	}
+
+	return maxp, nil
+}
+
+func validateHhea(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	hhea := table.(*sfnt.TableHhea)
+
+	if n := int(uint16(hhea.NumOfLongHorMetrics)); n == 0 || n > c.numGlyphs {
+		return nil, fmt.Errorf("numberOfHMetrics %d out of range for %d glyphs", n, c.numGlyphs)
+	}
+
+	return hhea, nil
+}
+
+func validateHmtx(c *checker, _ sfnt.Table) (sfnt.Table, error) {
+	return c.font.HmtxTable()
+}
+
+func validateLoca(c *checker, _ sfnt.Table) (sfnt.Table, error) {
+	loca, err := c.font.LocaTable()
+	if err != nil {
+		return nil, err
+	}
+
+	glyf, err := c.font.Table(sfnt.TagGlyf)
+	if err != nil {
+		return nil, err
+	}
+	length := uint32(len(glyf.Bytes()))
+
+	for i := 1; i < len(loca.Offsets); i++ {
+		if loca.Offsets[i] < loca.Offsets[i-1] {
+			return nil, fmt.Errorf("offset of glyph %d is before glyph %d", i, i-1)
+		}
+	}
+	if last := loca.Offsets[len(loca.Offsets)-1]; last > length {
+		return nil, fmt.Errorf("offset %d is beyond the end of 'glyf' (%d bytes)", last, length)
+	}
+
+	return loca, nil
+}
+
+// Flags used in simple glyph descriptions.
+const (
+	glyphXShort     = 0x02
+	glyphYShort     = 0x04
+	glyphRepeat     = 0x08
+	glyphXSameOrPos = 0x10
+	glyphYSameOrPos = 0x20
+)
+
+// Flags used in composite glyph descriptions.
+const (
+	componentArgsAreWords   = 0x0001
+	componentHaveScale      = 0x0008
+	componentMoreComponents = 0x0020
+	componentHaveXYScale    = 0x0040
+	componentHaveTwoByTwo   = 0x0080
+	componentHaveInstrs     = 0x0100
+)
+
+func validateGlyf(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	loca, err := c.font.LocaTable()
+	if err != nil {
+		return nil, err
+	}
+
+	buf := table.Bytes()
+	for i := 0; i+1 < len(loca.Offsets); i++ {
+		start, end := loca.Offsets[i], loca.Offsets[i+1]
+		if start > end || end > uint32(len(buf)) {
+			return nil, fmt.Errorf("glyph %d: invalid offsets %d-%d", i, start, end)
+		}
+		if err := c.validateGlyph(buf[start:end]); err != nil {
+			return nil, fmt.Errorf("glyph %d: %w", i, err)
+		}
+	}
+
+	return table, nil
+}
+
+// validateGlyph checks that the description of a single glyph fits within b.
+func (c *checker) validateGlyph(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+	if len(b) < 10 {
+		return io.ErrUnexpectedEOF
+	}
+
+	numContours := int(int16(binary.BigEndian.Uint16(b)))
+	if numContours == -1 {
+		return c.validateComposite(b[10:])
+	}
+	if numContours < 0 {
+		return fmt.Errorf("invalid numberOfContours %d", numContours)
+	}
+
+	p := 10 + 2*numContours
+	if p+2 > len(b) {
+		return io.ErrUnexpectedEOF
+	}
+
+	numPoints := 0
+	for i := 0; i < numContours; i++ {
+		end := int(binary.BigEndian.Uint16(b[10+2*i:]))
+		if end+1 <= numPoints && i > 0 {
+			return fmt.Errorf("endPtsOfContours[%d] is not increasing", i)
+		}
+		numPoints = end + 1
+	}
+
+	p += 2 + int(binary.BigEndian.Uint16(b[p:]))
+
+	xSize, ySize := 0, 0
+	for n := 0; n < numPoints; {
+		if p >= len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		flag := b[p]
+		p++
+
+		repeat := 1
+		if flag&glyphRepeat != 0 {
+			if p >= len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			repeat += int(b[p])
+			p++
+		}
+		if n+repeat > numPoints {
+			return errors.New("flags exceed number of points")
+		}
+		n += repeat
+
+		if flag&glyphXShort != 0 {
+			xSize += repeat
+		} else if flag&glyphXSameOrPos == 0 {
+			xSize += 2 * repeat
+		}
+		if flag&glyphYShort != 0 {
+			ySize += repeat
+		} else if flag&glyphYSameOrPos == 0 {
+			ySize += 2 * repeat
+		}
+	}
+
+	if p+xSize+ySize > len(b) {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (c *checker) validateComposite(b []byte) error {
+	for {
+		if len(b) < 4 {
+			return io.ErrUnexpectedEOF
+		}
+		flags := binary.BigEndian.Uint16(b)
+		glyph := int(binary.BigEndian.Uint16(b[2:]))
+		if glyph >= c.numGlyphs {
+			return fmt.Errorf("component glyph %d out of range", glyph)
+		}
+
+		size := 4 + 2
+		if flags&componentArgsAreWords != 0 {
+			size += 2
+		}
+		switch {
+		case flags&componentHaveScale != 0:
+			size += 2
+		case flags&componentHaveXYScale != 0:
+			size += 4
+		case flags&componentHaveTwoByTwo != 0:
+			size += 8
+		}
+		if size > len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		b = b[size:]
+
+		if flags&componentMoreComponents == 0 {
+			if flags&componentHaveInstrs != 0 {
+				if len(b) < 2 || 2+int(binary.BigEndian.Uint16(b)) > len(b) {
+					return io.ErrUnexpectedEOF
+				}
+			}
+			return nil
+		}
+	}
+}
+
+func validateCmap(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	cmap := table.(*sfnt.TableCmap)
+
+	unicode := cmap.UnicodeSubtable()
+	if unicode == nil {
+		return nil, errors.New("no Unicode subtable")
+	}
+
+	problem := ""
+	for i, s := range cmap.Subtables {
+		if i > 0 && !encodingLess(cmap.Subtables[i-1], s) {
+			problem = "encoding records are not sorted"
+		}
+		for code, glyph := range s.Mappings {
+			if int(glyph) >= c.numGlyphs {
+				problem = fmt.Sprintf("character %d mapped to glyph %d out of range", code, glyph)
+				break
+			}
+		}
+	}
+
+	if problem == "" {
+		return cmap, nil
+	}
+
+	// Rebuild the table from the Unicode mappings that are valid.
+	mappings := make(map[rune]sfnt.GlyphID, len(unicode.Mappings))
+	for code, glyph := range unicode.Mappings {
+		if int(glyph) < c.numGlyphs {
+			mappings[code] = glyph
+		}
+	}
+	c.repaired(sfnt.TagCmap, "%s, rebuilt from Unicode subtable", problem)
+
+	return sfnt.NewTableCmap(mappings), nil
+}
+
+func encodingLess(a, b *sfnt.CmapSubtable) bool {
+	if a.PlatformID != b.PlatformID {
+		return a.PlatformID < b.PlatformID
+	}
+	if a.EncodingID != b.EncodingID {
+		return a.EncodingID < b.EncodingID
+	}
+	return a.Language < b.Language
+}
+
+func validateName(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	name := table.(*sfnt.TableName)
+
+	entries := name.List()
+	less := func(a, b *sfnt.NameEntry) bool {
+		if a.PlatformID != b.PlatformID {
+			return a.PlatformID < b.PlatformID
+		}
+		if a.EncodingID != b.EncodingID {
+			return a.EncodingID < b.EncodingID
+		}
+		if a.LanguageID != b.LanguageID {
+			return a.LanguageID < b.LanguageID
+		}
+		return a.NameID < b.NameID
+	}
+
+	if sort.SliceIsSorted(entries, func(i, j int) bool { return less(entries[i], entries[j]) }) {
+		return name, nil
+	}
+
+	sorted := make([]*sfnt.NameEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+
+	repaired := sfnt.NewTableName()
+	for _, entry := range sorted {
+		repaired.Add(entry)
+	}
+	c.repaired(sfnt.TagName, "name records are not sorted")
+
+	return repaired, nil
+}
+
+func validateOS2(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	os2 := table.(*sfnt.TableOS2)
+
+	if os2.Version > 5 {
+		return nil, fmt.Errorf("unsupported version %d", os2.Version)
+	}
+	if os2.USWeightClass < 1 || os2.USWeightClass > 1000 {
+		return nil, fmt.Errorf("usWeightClass %d out of range", os2.USWeightClass)
+	}
+	if os2.USWidthClass < 1 || os2.USWidthClass > 9 {
+		return nil, fmt.Errorf("usWidthClass %d out of range", os2.USWidthClass)
+	}
+
+	return os2, nil
+}
+
+func validatePost(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	buf := table.Bytes()
+	if len(buf) < 32 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	switch version := binary.BigEndian.Uint32(buf); version {
+	case 0x00010000, 0x00030000:
+	case 0x00020000:
+		if len(buf) < 34 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		numGlyphs := int(binary.BigEndian.Uint16(buf[32:]))
+		if numGlyphs != c.numGlyphs {
+			return nil, fmt.Errorf("numGlyphs %d does not match 'maxp' (%d)", numGlyphs, c.numGlyphs)
+		}
+		if len(buf) < 34+2*numGlyphs {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		// Count the names stored in the table, and check the indices refer to them.
+		numNames := 0
+		for p := 34 + 2*numGlyphs; p < len(buf); p += 1 + int(buf[p]) {
+			if p+1+int(buf[p]) > len(buf) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			numNames++
+		}
+		for i := 0; i < numGlyphs; i++ {
+			if index := int(binary.BigEndian.Uint16(buf[34+2*i:])); index >= 258+numNames {
+				return nil, fmt.Errorf("glyph %d: name index %d out of range", i, index)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported version 0x%08x", version)
+	}
+
+	return table, nil
+}
+
+func validateCFF(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	return validateCFFHeader(table, 1)
+}
+
+func validateCFF2(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	return validateCFFHeader(table, 2)
+}
+
+func validateCFFHeader(table sfnt.Table, major byte) (sfnt.Table, error) {
+	buf := table.Bytes()
+	if len(buf) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if buf[0] != major {
+		return nil, fmt.Errorf("unsupported major version %d", buf[0])
+	}
+	if hdrSize := int(buf[2]); hdrSize < 4 || hdrSize > len(buf) {
+		return nil, fmt.Errorf("invalid header size %d", hdrSize)
+	}
+	return table, nil
+}
+
+func validateLayout(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	layout := table.(*sfnt.TableLayout)
+
+	for i := 1; i < len(layout.Scripts); i++ {
+		if layout.Scripts[i-1].Tag.Number >= layout.Scripts[i].Tag.Number {
+			return nil, errors.New("script records are not sorted")
+		}
+	}
+
+	return layout, nil
+}
+
+func validateGDEF(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	buf := table.Bytes()
+	if len(buf) < 12 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	major, minor := binary.BigEndian.Uint16(buf), binary.BigEndian.Uint16(buf[2:])
+	if major != 1 || (minor != 0 && minor != 2 && minor != 3) {
+		return nil, fmt.Errorf("unsupported version %d.%d", major, minor)
+	}
+
+	for i := 4; i < 12; i += 2 {
+		if offset := int(binary.BigEndian.Uint16(buf[i:])); offset >= len(buf) {
+			return nil, fmt.Errorf("offset %d beyond end of table", offset)
+		}
+	}
+
+	return table, nil
+}
+
+func validateGasp(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	gasp := table.(*sfnt.TableGasp)
+
+	if len(gasp.Ranges) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	for i := 1; i < len(gasp.Ranges); i++ {
+		if gasp.Ranges[i].MaxPPEM <= gasp.Ranges[i-1].MaxPPEM {
+			return nil, errors.New("ranges are not sorted")
+		}
+	}
+	if last := gasp.Ranges[len(gasp.Ranges)-1].MaxPPEM; last != 0xFFFF {
+		return nil, errors.New("last range does not end at 0xFFFF")
+	}
+
+	return gasp, nil
+}
+
+// validateCvt accepts the control values, whose length is checked when parsed.
+func validateCvt(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	return table, nil
+}
+
+func validateInstructions(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	if !c.font.HasTable(sfnt.TagGlyf) {
+		return nil, errors.New("instructions without TrueType outlines")
+	}
+	return table, nil
+}
+
+func validateFvar(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	fvar := table.(*sfnt.TableFvar)
+
+	if len(fvar.Axes) == 0 {
+		return nil, errors.New("no axes")
+	}
+	for _, axis := range fvar.Axes {
+		if axis.Min > axis.Default || axis.Default > axis.Max {
+			return nil, fmt.Errorf("axis %q: default %g outside range %g-%g", axis.Tag, axis.Default, axis.Min, axis.Max)
+		}
+	}
+
+	return fvar, nil
+}
+
+func validateAvar(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	avar := table.(*sfnt.TableAvar)
+
+	fvar, err := c.font.FvarTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %s", sfnt.TagFvar, err)
+	}
+	if len(avar.SegmentMaps) != len(fvar.Axes) {
+		return nil, fmt.Errorf("%d segment maps for %d axes", len(avar.SegmentMaps), len(fvar.Axes))
+	}
+
+	return avar, nil
+}
+
+func validateGvar(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	gvar := table.(*sfnt.TableGvar)
+
+	fvar, err := c.font.FvarTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %s", sfnt.TagFvar, err)
+	}
+	if gvar.AxisCount != len(fvar.Axes) {
+		return nil, fmt.Errorf("%d axes, but 'fvar' has %d", gvar.AxisCount, len(fvar.Axes))
+	}
+	if gvar.NumGlyphs() != c.numGlyphs {
+		return nil, fmt.Errorf("%d glyphs, but 'maxp' has %d", gvar.NumGlyphs(), c.numGlyphs)
+	}
+
+	return gvar, nil
+}
+
+func validateHvar(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	hvar := table.(*sfnt.TableHvar)
+	if err := validateVarStore(c, hvar.VarStore); err != nil {
+		return nil, err
+	}
+	return hvar, nil
+}
+
+func validateMvar(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	mvar := table.(*sfnt.TableMvar)
+	if mvar.VarStore == nil {
+		return mvar, nil
+	}
+	if err := validateVarStore(c, mvar.VarStore); err != nil {
+		return nil, err
+	}
+	return mvar, nil
+}
+
+// validateVarStore checks that the regions of an item variation store match the axes in 'fvar'.
+func validateVarStore(c *checker, store *sfnt.ItemVariationStore) error {
+	fvar, err := c.font.FvarTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %s", sfnt.TagFvar, err)
+	}
+	for i, region := range store.Regions {
+		if len(region) != len(fvar.Axes) {
+			return fmt.Errorf("region %d has %d axes, but 'fvar' has %d", i, len(region), len(fvar.Axes))
+		}
+	}
+	return nil
+}
+
+func validateSTAT(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	stat := table.(*sfnt.TableSTAT)
+
+	for i, value := range stat.AxisValues {
+		for _, l := range value.Locations {
+			if int(l.AxisIndex) >= len(stat.DesignAxes) {
+				return nil, fmt.Errorf("axis value %d: axis %d out of range", i, l.AxisIndex)
+			}
+		}
+	}
+
+	return stat, nil
+}
+
+func validateCOLR(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	colr := table.(*sfnt.TableCOLR)
+
+	checkGlyph := func(glyph sfnt.GlyphID) error {
+		if int(glyph) >= c.numGlyphs {
+			return fmt.Errorf("glyph %d out of range", glyph)
+		}
+		return nil
+	}
+	for _, base := range colr.BaseGlyphs {
+		if err := checkGlyph(base.Glyph); err != nil {
+			return nil, err
+		}
+		for _, layer := range base.Layers {
+			if err := checkGlyph(layer.Glyph); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for glyph := range colr.Paints {
+		err := colr.Walk(glyph, func(paint sfnt.Paint, _ int) error {
+			switch p := paint.(type) {
+			case *sfnt.PaintGlyph:
+				return checkGlyph(p.Glyph)
+			case *sfnt.PaintColrGlyph:
+				return checkGlyph(p.Glyph)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("color glyph %d: %s", glyph, err)
+		}
+	}
+
+	return colr, nil
+}
+
+func validateCPAL(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	cpal := table.(*sfnt.TableCPAL)
+
+	if len(cpal.Palettes) == 0 {
+		return nil, errors.New("no palettes")
+	}
+
+	return cpal, nil
+}
+
+func validateSVG(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	svg := table.(*sfnt.TableSVG)
+
+	for _, doc := range svg.Documents {
+		if int(doc.EndGlyph) >= c.numGlyphs {
+			return nil, fmt.Errorf("document for glyphs %d to %d out of range", doc.StartGlyph, doc.EndGlyph)
+		}
+	}
+
+	return svg, nil
+}
+
+func validateSbix(c *checker, _ sfnt.Table) (sfnt.Table, error) {
+	return c.font.SbixTable()
+}
+
+func validateBitmapLocation(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	eblc := table.(*sfnt.TableEBLC)
+
+	for i, strike := range eblc.Strikes {
+		for _, s := range strike.Subtables {
+			if int(s.LastGlyph) >= c.numGlyphs {
+				return nil, fmt.Errorf("strike %d: glyphs %d to %d out of range", i, s.FirstGlyph, s.LastGlyph)
+			}
+		}
+	}
+
+	return eblc, nil
+}
+
+// validateBitmapData accepts the bitmap data, which is only checked when the
+// bitmap of a glyph is read.
+func validateBitmapData(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	return table, nil
+}
+
+func validateVhea(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	vhea := table.(*sfnt.TableVhea)
+
+	if n := int(vhea.NumOfLongVerMetrics); n == 0 || n > c.numGlyphs {
+		return nil, fmt.Errorf("numOfLongVerMetrics %d out of range for %d glyphs", n, c.numGlyphs)
+	}
+
+	return vhea, nil
+}
+
+func validateVmtx(c *checker, _ sfnt.Table) (sfnt.Table, error) {
+	return c.font.VmtxTable()
+}
+
+func validateVORG(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	vorg := table.(*sfnt.TableVORG)
+
+	for _, o := range vorg.Origins {
+		if int(o.Glyph) >= c.numGlyphs {
+			return nil, fmt.Errorf("glyph %d out of range", o.Glyph)
+		}
+	}
+
+	return vorg, nil
+}
+
+func validateBASE(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	base := table.(*sfnt.TableBASE)
+
+	for _, axis := range []*sfnt.BaseAxis{base.Horizontal, base.Vertical} {
+		if axis == nil {
+			continue
+		}
+		for _, script := range axis.Scripts {
+			if script.Values == nil {
+				continue
+			}
+			for _, coord := range script.Values.Coords {
+				if coord.Format == 2 && int(coord.ReferenceGlyph) >= c.numGlyphs {
+					return nil, fmt.Errorf("script %q: glyph %d out of range", script.Tag, coord.ReferenceGlyph)
+				}
+			}
+		}
+	}
+
+	return base, nil
+}
+
+func validateMATH(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	math := table.(*sfnt.TableMATH)
+
+	for _, constructions := range []map[sfnt.GlyphID]sfnt.MathGlyphConstruction{math.Variants.Vertical, math.Variants.Horizontal} {
+		for glyph, construction := range constructions {
+			for _, v := range construction.Variants {
+				if int(v.Glyph) >= c.numGlyphs {
+					return nil, fmt.Errorf("glyph %d: variant %d out of range", glyph, v.Glyph)
+				}
+			}
+			if construction.Assembly == nil {
+				continue
+			}
+			for _, part := range construction.Assembly.Parts {
+				if int(part.Glyph) >= c.numGlyphs {
+					return nil, fmt.Errorf("glyph %d: part %d out of range", glyph, part.Glyph)
+				}
+			}
+		}
+	}
+
+	return math, nil
+}
+
+// validateJSTF checks that the lookups enabled and disabled by 'JSTF' are in
+// 'GSUB' and 'GPOS'.
+func validateJSTF(c *checker, table sfnt.Table) (sfnt.Table, error) {
+	jstf := table.(*sfnt.TableJSTF)
+
+	layouts := make(map[sfnt.Tag]*sfnt.TableLayout)
+	for _, tag := range []sfnt.Tag{sfnt.TagGsub, sfnt.TagGpos} {
+		if !c.font.HasTable(tag) {
+			continue
+		}
+		layout, err := c.font.TableLayout(tag)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %s", tag, err)
+		}
+		layouts[tag] = layout
+	}
+
+	for _, script := range jstf.Scripts {
+		for _, glyph := range script.ExtenderGlyphs {
+			if int(glyph) >= c.numGlyphs {
+				return nil, fmt.Errorf("script %q: extender glyph %d out of range", script.Tag, glyph)
+			}
+		}
+		langs := script.Languages
+		if script.DefaultLanguage != nil {
+			langs = append([]*sfnt.JstfLangSys{script.DefaultLanguage}, langs...)
+		}
+		for _, lang := range langs {
+			for _, p := range lang.Priorities {
+				for tag, lists := range map[sfnt.Tag][]sfnt.JstfModList{
+					sfnt.TagGsub: {p.ShrinkageEnableGSUB, p.ShrinkageDisableGSUB, p.ExtensionEnableGSUB, p.ExtensionDisableGSUB},
+					sfnt.TagGpos: {p.ShrinkageEnableGPOS, p.ShrinkageDisableGPOS, p.ExtensionEnableGPOS, p.ExtensionDisableGPOS},
+				} {
+					for _, list := range lists {
+						if len(list) == 0 {
+							continue
+						}
+						if layouts[tag] == nil {
+							return nil, fmt.Errorf("script %q: lookups refer to missing %q table", script.Tag, tag)
+						}
+						if _, err := list.Lookups(layouts[tag]); err != nil {
+							return nil, fmt.Errorf("script %q: %s", script.Tag, err)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return jstf, nil
+}
@@ -0,0 +1,122 @@
+package sfnt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// GlyphBitmap is the bitmap or image of a glyph, as returned by Font.GlyphBitmap.
+type GlyphBitmap struct {
+	PPEM uint16 // PPEM is the size of the strike containing the bitmap, in pixels per em.
+
+	// GraphicType is the format of Data: an image format such as BitmapPNG,
+	// or BitmapRaw for uncompressed bitmaps from 'EBDT' and 'CBDT'.
+	GraphicType Tag
+	Data        []byte
+
+	// Metrics positions bitmaps from 'EBDT' and 'CBDT'. Images from 'sbix'
+	// are positioned by OriginX and OriginY instead.
+	Metrics          BitmapMetrics
+	OriginX, OriginY int16
+
+	// BitDepth is the number of bits per pixel of raw bitmaps, whose rows are
+	// padded to whole bytes unless BitAligned is true.
+	BitDepth   uint8
+	BitAligned bool
+
+	// Components contains the glyphs drawn to make up composite bitmaps,
+	// which have no Data.
+	Components []BitmapComponent
+}
+
+// ErrMissingBitmap is returned by GlyphBitmap if the font has no bitmap for a glyph.
+var ErrMissingBitmap = errors.New("missing glyph bitmap")
+
+// GlyphBitmap returns the bitmap of a glyph from the strike best suited to
+// ppem: the smallest at least ppem pixels per em, or the largest if all are
+// smaller. The 'sbix', 'CBLC' and 'EBLC' tables are searched in turn, and the
+// first containing a bitmap for the glyph is used.
+func (font *Font) GlyphBitmap(glyph GlyphID, ppem int) (*GlyphBitmap, error) {
+	if font.HasTable(TagSbix) {
+		sbix, err := font.SbixTable()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", TagSbix, err)
+		}
+		var strikes []int
+		var found []*SbixStrike
+		for i := range sbix.Strikes {
+			if strike := &sbix.Strikes[i]; strike.Glyph(glyph) != nil {
+				strikes = append(strikes, int(strike.PPEM))
+				found = append(found, strike)
+			}
+		}
+		if i := bestStrike(strikes, ppem); i >= 0 {
+			g := found[i].Glyph(glyph)
+			return &GlyphBitmap{
+				PPEM:        found[i].PPEM,
+				GraphicType: g.GraphicType,
+				Data:        g.Data,
+				OriginX:     g.OriginX,
+				OriginY:     g.OriginY,
+			}, nil
+		}
+	}
+
+	for _, tags := range [][2]Tag{{TagCBLC, TagCBDT}, {TagEBLC, TagEBDT}} {
+		if !font.HasTable(tags[0]) || !font.HasTable(tags[1]) {
+			continue
+		}
+		table, err := font.Table(tags[0])
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", tags[0], err)
+		}
+		location := table.(*TableEBLC)
+
+		var strikes []int
+		var found []*BitmapStrike
+		for i := range location.Strikes {
+			strike := &location.Strikes[i]
+			if _, _, ok := strike.Location(glyph); ok {
+				strikes = append(strikes, int(strike.PPEMY))
+				found = append(found, strike)
+			}
+		}
+		i := bestStrike(strikes, ppem)
+		if i < 0 {
+			continue
+		}
+
+		table, err = font.Table(tags[1])
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", tags[1], err)
+		}
+		strike := found[i]
+		subtable, loc, _ := strike.Location(glyph)
+		bitmap, err := table.(*TableEBDT).Bitmap(strike, subtable, loc)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", tags[1], err)
+		}
+		return bitmap, nil
+	}
+
+	return nil, ErrMissingBitmap
+}
+
+// bestStrike returns the index of the smallest size at least ppem, or of the
+// largest size if all are smaller, or -1 if there are no sizes.
+func bestStrike(sizes []int, ppem int) int {
+	best := -1
+	for i, size := range sizes {
+		switch {
+		case best < 0:
+			best = i
+		case sizes[best] < ppem:
+			if size > sizes[best] {
+				best = i
+			}
+		case size >= ppem && size < sizes[best]:
+			best = i
+		}
+	}
+	return best
+}
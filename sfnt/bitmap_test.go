@@ -0,0 +1,287 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testBitmapStrike struct {
+	ppem, bitDepth uint8
+	subtables      []testIndexSubtable
+}
+
+type testIndexSubtable struct {
+	first, last              GlyphID
+	indexFormat, imageFormat uint16
+	// images contains the data of each glyph from first to last for index
+	// formats 1, 2 and 3, or of each glyph in glyphs for formats 4 and 5.
+	images  [][]byte
+	glyphs  []GlyphID
+	metrics []byte // metrics contains big glyph metrics for formats 2 and 5.
+}
+
+// testBitmapTables returns a bitmap location table with the given major
+// version, and the bitmap data table it refers to.
+func testBitmapTables(major uint16, strikes []testBitmapStrike) ([]byte, []byte) {
+	data := []byte{0, byte(major), 0, 0}
+	buf := make([]byte, eblcHeaderSize+bitmapSizeSize*len(strikes))
+	binary.BigEndian.PutUint16(buf, major)
+	binary.BigEndian.PutUint32(buf[4:], uint32(len(strikes)))
+
+	for i, strike := range strikes {
+		record := eblcHeaderSize + bitmapSizeSize*i
+		arrayOffset := len(buf)
+		binary.BigEndian.PutUint32(buf[record:], uint32(arrayOffset))
+		binary.BigEndian.PutUint32(buf[record+8:], uint32(len(strike.subtables)))
+		start, end := strike.subtables[0].first, strike.subtables[0].last
+		buf = append(buf, make([]byte, 8*len(strike.subtables))...)
+
+		for j, s := range strike.subtables {
+			if s.first < start {
+				start = s.first
+			}
+			if s.last > end {
+				end = s.last
+			}
+			r := buf[arrayOffset+8*j:]
+			binary.BigEndian.PutUint16(r, uint16(s.first))
+			binary.BigEndian.PutUint16(r[2:], uint16(s.last))
+			binary.BigEndian.PutUint32(r[4:], uint32(len(buf)-arrayOffset))
+
+			imageDataOffset := len(data)
+			buf = appendUint16(buf, s.indexFormat)
+			buf = appendUint16(buf, s.imageFormat)
+			buf = append(buf, 0, 0, 0, 0)
+			binary.BigEndian.PutUint32(buf[len(buf)-4:], uint32(imageDataOffset))
+
+			var offsets []int
+			for _, image := range s.images {
+				offsets = append(offsets, len(data)-imageDataOffset)
+				data = append(data, image...)
+			}
+			offsets = append(offsets, len(data)-imageDataOffset)
+
+			appendUint32 := func(v int) {
+				buf = append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+			}
+			switch s.indexFormat {
+			case 1:
+				for _, offset := range offsets {
+					appendUint32(offset)
+				}
+			case 3:
+				for _, offset := range offsets {
+					buf = appendUint16(buf, uint16(offset))
+				}
+			case 2:
+				appendUint32(len(s.images[0]))
+				buf = append(buf, s.metrics...)
+			case 4:
+				appendUint32(len(s.glyphs))
+				for k, glyph := range append(s.glyphs, 0) {
+					buf = appendUint16(buf, uint16(glyph))
+					buf = appendUint16(buf, uint16(offsets[k]))
+				}
+			case 5:
+				appendUint32(len(s.images[0]))
+				buf = append(buf, s.metrics...)
+				appendUint32(len(s.glyphs))
+				for _, glyph := range s.glyphs {
+					buf = appendUint16(buf, uint16(glyph))
+				}
+			}
+		}
+
+		r := buf[record:]
+		binary.BigEndian.PutUint16(r[40:], uint16(start))
+		binary.BigEndian.PutUint16(r[42:], uint16(end))
+		r[44], r[45], r[46], r[47] = strike.ppem, strike.ppem, strike.bitDepth, BitmapHorizontalMetrics
+	}
+	return buf, data
+}
+
+// pngImage returns the data of a PNG bitmap glyph with the given metrics.
+func pngImage(metrics []byte, png string) []byte {
+	b := append([]byte(nil), metrics...)
+	b = append(b, 0, 0, 0, byte(len(png)))
+	return append(b, png...)
+}
+
+func testColorBitmapFont(t *testing.T) *Font {
+	t.Helper()
+
+	small := []byte{16, 14, 1, 12, 15}
+	big := []byte{16, 14, 1, 12, 15, 2, 3, 17}
+	cblc, cbdt := testBitmapTables(3, []testBitmapStrike{
+		{ppem: 20, bitDepth: 32, subtables: []testIndexSubtable{
+			{first: 1, last: 2, indexFormat: 1, imageFormat: 17, images: [][]byte{pngImage(small, "one-20"), pngImage(small, "two-20")}},
+			{first: 3, last: 3, indexFormat: 2, imageFormat: 19, images: [][]byte{pngImage(nil, "three")}, metrics: big},
+			{first: 5, last: 7, indexFormat: 4, imageFormat: 18, glyphs: []GlyphID{5, 7}, images: [][]byte{pngImage(big, "five"), pngImage(big, "seven")}},
+			{first: 8, last: 9, indexFormat: 5, imageFormat: 19, glyphs: []GlyphID{8, 9}, images: [][]byte{pngImage(nil, "eight"), pngImage(nil, "nine!")}, metrics: big},
+		}},
+		{ppem: 40, bitDepth: 32, subtables: []testIndexSubtable{
+			{first: 1, last: 1, indexFormat: 3, imageFormat: 17, images: [][]byte{pngImage(small, "one-40")}},
+		}},
+	})
+
+	font := New(TypeTrueType)
+	for tag, buf := range map[Tag][]byte{TagCBLC: cblc, TagCBDT: cbdt} {
+		table, err := ParseTable(tag, buf)
+		if err != nil {
+			t.Fatalf("ParseTable(%q) err = %q, want nil", tag, err)
+		}
+		font.AddTable(tag, table)
+	}
+	return font
+}
+
+func TestColorBitmaps(t *testing.T) {
+	font := testColorBitmapFont(t)
+
+	smallMetrics := BitmapMetrics{Height: 16, Width: 14, HoriBearingX: 1, HoriBearingY: 12, HoriAdvance: 15}
+	bigMetrics := BitmapMetrics{Height: 16, Width: 14, HoriBearingX: 1, HoriBearingY: 12, HoriAdvance: 15, VertBearingX: 2, VertBearingY: 3, VertAdvance: 17}
+	tests := []struct {
+		glyph   GlyphID
+		ppem    int
+		want    string
+		size    uint16
+		metrics BitmapMetrics
+	}{
+		{1, 16, "one-20", 20, smallMetrics},
+		{1, 20, "one-20", 20, smallMetrics},
+		{1, 30, "one-40", 40, smallMetrics},
+		{1, 100, "one-40", 40, smallMetrics},
+		{2, 100, "two-20", 20, smallMetrics},
+		{3, 12, "three", 20, bigMetrics},
+		{5, 12, "five", 20, bigMetrics},
+		{7, 12, "seven", 20, bigMetrics},
+		{8, 12, "eight", 20, bigMetrics},
+		{9, 12, "nine!", 20, bigMetrics},
+	}
+	for _, test := range tests {
+		bitmap, err := font.GlyphBitmap(test.glyph, test.ppem)
+		if err != nil {
+			t.Errorf("GlyphBitmap(%d, %d) err = %q, want nil", test.glyph, test.ppem, err)
+			continue
+		}
+		if bitmap.GraphicType != BitmapPNG || string(bitmap.Data) != test.want || bitmap.PPEM != test.size {
+			t.Errorf("GlyphBitmap(%d, %d) = %q %q at %d, want 'png ' %q at %d",
+				test.glyph, test.ppem, bitmap.GraphicType, bitmap.Data, bitmap.PPEM, test.want, test.size)
+		}
+		if bitmap.Metrics != test.metrics {
+			t.Errorf("GlyphBitmap(%d, %d).Metrics = %+v, want %+v", test.glyph, test.ppem, bitmap.Metrics, test.metrics)
+		}
+	}
+
+	for _, glyph := range []GlyphID{0, 4, 6, 10} {
+		if _, err := font.GlyphBitmap(glyph, 20); !errors.Is(err, ErrMissingBitmap) {
+			t.Errorf("GlyphBitmap(%d) err = %v, want %v", glyph, err, ErrMissingBitmap)
+		}
+	}
+
+	cblc, err := font.CBLCTable()
+	if err != nil {
+		t.Fatalf("CBLCTable() err = %q, want nil", err)
+	}
+	if got := len(cblc.Strikes); got != 2 {
+		t.Fatalf("len(Strikes) = %d, want 2", got)
+	}
+	if s := cblc.Strikes[0]; s.StartGlyph != 1 || s.EndGlyph != 9 || s.BitDepth != 32 || cblc.MajorVersion != 3 {
+		t.Errorf("Strikes[0] = glyphs %d to %d, bit depth %d, want 1 to 9 and 32", s.StartGlyph, s.EndGlyph, s.BitDepth)
+	}
+}
+
+func TestMonochromeBitmaps(t *testing.T) {
+	small := []byte{2, 8, 0, 2, 9}
+	eblc, ebdt := testBitmapTables(2, []testBitmapStrike{
+		{ppem: 12, bitDepth: 1, subtables: []testIndexSubtable{
+			{first: 1, last: 2, indexFormat: 1, imageFormat: 1, images: [][]byte{append(small, 0xFF, 0x81), nil}},
+			{first: 3, last: 3, indexFormat: 1, imageFormat: 8, images: [][]byte{append(small, 0, 0, 2, 0, 1, 0, 0, 0, 1, 4, 0)}},
+			{first: 4, last: 4, indexFormat: 2, imageFormat: 5, images: [][]byte{{0xF0, 0x0F}}, metrics: []byte{2, 8, 0, 2, 9, 0, 0, 0}},
+		}},
+	})
+
+	font := New(TypeTrueType)
+	for tag, buf := range map[Tag][]byte{TagEBLC: eblc, TagEBDT: ebdt} {
+		table, err := ParseTable(tag, buf)
+		if err != nil {
+			t.Fatalf("ParseTable(%q) err = %q, want nil", tag, err)
+		}
+		font.AddTable(tag, table)
+	}
+	if _, err := font.EBDTTable(); err != nil {
+		t.Errorf("EBDTTable() err = %q, want nil", err)
+	}
+
+	metrics := BitmapMetrics{Height: 2, Width: 8, HoriBearingY: 2, HoriAdvance: 9}
+	tests := []struct {
+		glyph GlyphID
+		want  *GlyphBitmap
+	}{
+		{1, &GlyphBitmap{PPEM: 12, GraphicType: BitmapRaw, Data: []byte{0xFF, 0x81}, Metrics: metrics, BitDepth: 1}},
+		{3, &GlyphBitmap{PPEM: 12, GraphicType: BitmapRaw, Metrics: metrics, BitDepth: 1, Components: []BitmapComponent{{1, 0, 0}, {1, 4, 0}}}},
+		{4, &GlyphBitmap{PPEM: 12, GraphicType: BitmapRaw, Data: []byte{0xF0, 0x0F}, Metrics: metrics, BitDepth: 1, BitAligned: true}},
+	}
+	for _, test := range tests {
+		bitmap, err := font.GlyphBitmap(test.glyph, 12)
+		if err != nil {
+			t.Errorf("GlyphBitmap(%d) err = %q, want nil", test.glyph, err)
+			continue
+		}
+		if !reflect.DeepEqual(bitmap, test.want) {
+			t.Errorf("GlyphBitmap(%d) = %+v, want %+v", test.glyph, bitmap, test.want)
+		}
+	}
+	if _, err := font.GlyphBitmap(2, 12); !errors.Is(err, ErrMissingBitmap) {
+		t.Errorf("GlyphBitmap(2) err = %v, want %v", err, ErrMissingBitmap)
+	}
+}
+
+func TestSbix(t *testing.T) {
+	want := &TableSbix{
+		baseTable: baseTable(TagSbix),
+		Version:   1,
+		Flags:     1,
+		Strikes: []SbixStrike{
+			{PPEM: 32, PPI: 72, Glyphs: []SbixGlyph{{}, {OriginX: 1, OriginY: -2, GraphicType: BitmapPNG, Data: []byte("png-32")}, {GraphicType: BitmapDupe, Data: []byte{0, 1}}}},
+			{PPEM: 64, PPI: 72, Glyphs: []SbixGlyph{{}, {GraphicType: BitmapJPEG, Data: []byte("jpeg-64")}, {}}},
+		},
+	}
+
+	maxp := &TableMaxp{}
+	maxp.NumGlyphs = 3
+	font := testColorBitmapFont(t)
+	font.AddTable(TagMaxp, maxp)
+	font.AddTable(TagSbix, &unparsedTable{baseTable(TagSbix), want.Bytes()})
+
+	sbix, err := font.SbixTable()
+	if err != nil {
+		t.Fatalf("SbixTable() err = %q, want nil", err)
+	}
+	if !reflect.DeepEqual(sbix, want) {
+		t.Errorf("SbixTable() = %+v, want %+v", sbix, want)
+	}
+
+	tests := []struct {
+		glyph GlyphID
+		ppem  int
+		want  *GlyphBitmap
+	}{
+		{1, 20, &GlyphBitmap{PPEM: 32, GraphicType: BitmapPNG, Data: []byte("png-32"), OriginX: 1, OriginY: -2}},
+		{1, 48, &GlyphBitmap{PPEM: 64, GraphicType: BitmapJPEG, Data: []byte("jpeg-64")}},
+		{2, 48, &GlyphBitmap{PPEM: 32, GraphicType: BitmapPNG, Data: []byte("png-32"), OriginX: 1, OriginY: -2}},
+	}
+	for _, test := range tests {
+		bitmap, err := font.GlyphBitmap(test.glyph, test.ppem)
+		if err != nil || !reflect.DeepEqual(bitmap, test.want) {
+			t.Errorf("GlyphBitmap(%d, %d) = %+v, %v, want %+v", test.glyph, test.ppem, bitmap, err, test.want)
+		}
+	}
+
+	// Glyphs without images in 'sbix' fall back to 'CBDT'.
+	if bitmap, err := font.GlyphBitmap(3, 20); err != nil || string(bitmap.Data) != "three" {
+		t.Errorf("GlyphBitmap(3) = %+v, %v, want the 'CBDT' bitmap", bitmap, err)
+	}
+}
@@ -0,0 +1,587 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Operators of CFF2 charstrings, in addition to those of Type 2 charstrings.
+const (
+	csRlineto    = 5
+	csHlineto    = 6
+	csVlineto    = 7
+	csRrcurveto  = 8
+	csVsindex    = 15
+	csBlend      = 16
+	csRcurveline = 24
+	csRlinecurve = 25
+	csVvcurveto  = 26
+	csHhcurveto  = 27
+	csVhcurveto  = 30
+	csHvcurveto  = 31
+	csHflex      = 1234
+	csFlex       = 1235
+	csHflex1     = 1236
+	csFlex1      = 1237
+)
+
+// cff2MaxStack is the maximum number of operands on the stack of a CFF2 charstring.
+const cff2MaxStack = 513
+
+// cff2Value is an operand of a CFF2 charstring or DICT. deltas contains the
+// deltas of a blended value, and is empty otherwise.
+type cff2Value struct {
+	value  float64
+	deltas []float64
+}
+
+// cff2BlendFunc returns the operand to use for a blended value, given the
+// index of the ItemVariationData of the blend, the default value and its
+// deltas for the regions of that data.
+type cff2BlendFunc func(vsindex int, value float64, deltas []float64) cff2Value
+
+// cff2Interpreter runs CFF2 charstrings, calling subroutines and resolving
+// blends with blend.
+type cff2Interpreter struct {
+	table    *TableCFF2
+	fd       int
+	vsindex  int
+	numStems int
+	stack    []cff2Value
+	blend    cff2BlendFunc
+	// visit is called for each operator other than the subroutine and blend
+	// operators, with its encoding followed by any hint mask, and its operands.
+	visit func(op int, code []byte, operands []cff2Value) error
+}
+
+// newCFF2Interpreter returns an interpreter for the charstring of a glyph.
+func (table *TableCFF2) newCFF2Interpreter(glyph GlyphID, blend cff2BlendFunc) *cff2Interpreter {
+	fd := table.fontDict(glyph)
+	c := &cff2Interpreter{table: table, fd: fd, blend: blend}
+	if fd < len(table.FontDicts) {
+		if v := table.FontDicts[fd].private.get(cff2OpVsindex); len(v) == 1 {
+			c.vsindex = int(v[0])
+		}
+	}
+	return c
+}
+
+func (c *cff2Interpreter) run(cs []byte, depth int) error {
+	if err := c.table.checkCallDepth(depth); err != nil {
+		return err
+	}
+	for len(cs) > 0 {
+		op, size, err := readCharStringToken(cs)
+		if err != nil {
+			return err
+		}
+		code := cs[:size]
+		cs = cs[size:]
+		if op < 0 {
+			if len(c.stack) >= cff2MaxStack {
+				return errors.New("too many operands")
+			}
+			c.stack = append(c.stack, cff2Value{value: cff2Number(code)})
+			continue
+		}
+
+		switch op {
+		case csCallsubr, csCallgsubr:
+			if err := c.call(op, depth); err != nil {
+				return err
+			}
+			continue
+		case csReturn:
+			return nil
+		case csBlend:
+			if c.stack, err = blendCFF2Operands(c.stack, c.table.VarStore, c.vsindex, c.blend); err != nil {
+				return err
+			}
+			continue
+		case csVsindex:
+			if len(c.stack) != 1 {
+				return errors.New("invalid vsindex operands")
+			}
+			c.vsindex = int(c.stack[0].value)
+		case csHstem, csVstem, csHstemhm, csVstemhm:
+			c.numStems += len(c.stack) / 2
+		case csHintmask, csCntrmask:
+			c.numStems += len(c.stack) / 2
+			n := (c.numStems + 7) / 8
+			if len(cs) < n {
+				return io.ErrUnexpectedEOF
+			}
+			code = append(append([]byte(nil), code...), cs[:n]...)
+			cs = cs[n:]
+		default:
+			if isCharStringArithmetic(op) {
+				return fmt.Errorf("unsupported arithmetic operator 12 %d", op-1200)
+			}
+		}
+		if err := c.visit(op, code, c.stack); err != nil {
+			return err
+		}
+		c.stack = c.stack[:0]
+	}
+	return nil
+}
+
+// call runs the callsubr or callgsubr operator.
+func (c *cff2Interpreter) call(op int, depth int) error {
+	subrs := c.table.GlobalSubrs
+	if op == csCallsubr {
+		subrs = nil
+		if c.fd < len(c.table.FontDicts) {
+			subrs = c.table.FontDicts[c.fd].Subrs
+		}
+	}
+	if len(c.stack) == 0 {
+		return errors.New("subroutine call without an index")
+	}
+	index := int(c.stack[len(c.stack)-1].value) + CFFSubrBias(len(subrs))
+	c.stack = c.stack[:len(c.stack)-1]
+	if index < 0 || index >= len(subrs) {
+		return fmt.Errorf("subroutine %d out of range", index)
+	}
+	return c.run(subrs[index], depth+1)
+}
+
+// blendCFF2Operands runs the blend operator on the operands in stack, whose
+// last value is the number of blended values.
+func blendCFF2Operands(stack []cff2Value, store *ItemVariationStore, vsindex int, blend cff2BlendFunc) ([]cff2Value, error) {
+	if len(stack) == 0 {
+		return nil, errors.New("blend without operands")
+	}
+	n := int(stack[len(stack)-1].value)
+	stack = stack[:len(stack)-1]
+	if store == nil || vsindex < 0 || vsindex >= len(store.Data) {
+		return nil, fmt.Errorf("blend with invalid vsindex %d", vsindex)
+	}
+	k := len(store.Data[vsindex].RegionIndexes)
+	if n < 0 || n*(k+1) > len(stack) {
+		return nil, errors.New("blend with too few operands")
+	}
+
+	operands := stack[len(stack)-n*(k+1):]
+	for _, v := range operands {
+		if len(v.deltas) > 0 {
+			return nil, errors.New("blend of a blended value")
+		}
+	}
+	for i := 0; i < n; i++ {
+		deltas := make([]float64, k)
+		for j := range deltas {
+			deltas[j] = operands[n+i*k+j].value
+		}
+		operands[i] = blend(vsindex, operands[i].value, deltas)
+	}
+	return stack[:len(stack)-n*k], nil
+}
+
+// cff2Number returns the value of an operand of a CFF2 charstring.
+func cff2Number(b []byte) float64 {
+	if b[0] == 255 {
+		return float64(int32(binary.BigEndian.Uint32(b[1:]))) / 65536
+	}
+	return float64(cffDictInt(b))
+}
+
+// appendCharStringNumber appends the encoding of v to a charstring, as an
+// integer if possible and otherwise as a 16.16 fixed-point number.
+func appendCharStringNumber(buf []byte, v float64) []byte {
+	if v == math.Trunc(v) && v >= math.MinInt16 && v <= math.MaxInt16 {
+		return appendCharStringInt(buf, int32(v))
+	}
+	f := int32(math.Max(math.MinInt32, math.Min(math.MaxInt32, math.Round(v*65536))))
+	return append(buf, 255, byte(f>>24), byte(f>>16), byte(f>>8), byte(f))
+}
+
+// appendCharStringValue appends an operand to a charstring, blending it if
+// it has deltas.
+func appendCharStringValue(buf []byte, v cff2Value) []byte {
+	buf = appendCharStringNumber(buf, v.value)
+	if len(v.deltas) == 0 {
+		return buf
+	}
+	for _, d := range v.deltas {
+		buf = appendCharStringNumber(buf, d)
+	}
+	buf = appendCharStringInt(buf, 1)
+	return append(buf, csBlend)
+}
+
+// Pin replaces the variations of the table with store, which is nil for a
+// font without variations, and rewrites each blended value with pin. pin is
+// given the index of the ItemVariationData of the value, its default value
+// and its deltas for the regions of that data, and returns the new default
+// value and its deltas for the regions of the same ItemVariationData in
+// store. Subroutines are inlined, as the number of deltas blended in them
+// may change.
+func (table *TableCFF2) Pin(store *ItemVariationStore, pin func(vsindex int, value float64, deltas []float64) (float64, []float64)) error {
+	blend := func(vsindex int, value float64, deltas []float64) cff2Value {
+		v, d := pin(vsindex, value, deltas)
+		for _, x := range d {
+			if x != 0 {
+				return cff2Value{value: v, deltas: d}
+			}
+		}
+		return cff2Value{value: v}
+	}
+
+	charStrings := make([][]byte, len(table.CharStrings))
+	for glyph, cs := range table.CharStrings {
+		var out []byte
+		c := table.newCFF2Interpreter(GlyphID(glyph), blend)
+		c.visit = func(op int, code []byte, operands []cff2Value) error {
+			if op == csVsindex && store == nil {
+				return nil
+			}
+			for _, v := range operands {
+				out = appendCharStringValue(out, v)
+			}
+			out = append(out, code...)
+			return nil
+		}
+		if err := c.run(cs, 0); err != nil {
+			return fmt.Errorf("glyph %d: %w", glyph, err)
+		}
+		charStrings[glyph] = out
+	}
+
+	fontDicts := make([]CFFFontDict, len(table.FontDicts))
+	for i, fd := range table.FontDicts {
+		private, err := pinCFF2Private(fd.private, table.VarStore, store != nil, blend)
+		if err != nil {
+			return fmt.Errorf("font dict %d: %w", i, err)
+		}
+		fontDicts[i] = CFFFontDict{dict: fd.dict, private: private.remove(cffOpSubrs)}
+	}
+
+	table.GlobalSubrs, table.CharStrings, table.FontDicts, table.VarStore = nil, charStrings, fontDicts, store
+	return nil
+}
+
+// pinCFF2Private rewrites the blended values of a Private DICT with blend,
+// keeping the vsindex operator if keepVsindex is true.
+func pinCFF2Private(private cffDict, store *ItemVariationStore, keepVsindex bool, blend cff2BlendFunc) (cffDict, error) {
+	vsindex := 0
+	if v := private.get(cff2OpVsindex); len(v) == 1 {
+		vsindex = int(v[0])
+	}
+
+	var out cffDict
+	var stack []cff2Value
+	for _, e := range private {
+		for _, b := range e.operands {
+			stack = append(stack, cff2Value{value: cffDictNumber(b)})
+		}
+		switch e.op {
+		case cff2OpBlend:
+			var err error
+			if stack, err = blendCFF2Operands(stack, store, vsindex, blend); err != nil {
+				return nil, err
+			}
+			continue
+		case cff2OpVsindex:
+			if !keepVsindex {
+				stack = stack[:0]
+				continue
+			}
+		}
+
+		// Blended values are followed by the blend operator, which leaves
+		// them on the stack for the operator that uses them.
+		var operands [][]byte
+		for _, v := range stack {
+			operands = append(operands, appendCFFDictNumber(nil, v.value))
+			if len(v.deltas) == 0 {
+				continue
+			}
+			for _, d := range v.deltas {
+				operands = append(operands, appendCFFDictNumber(nil, d))
+			}
+			operands = append(operands, appendCFFDictNumber(nil, 1))
+			out = append(out, cffDictEntry{op: cff2OpBlend, operands: operands})
+			operands = nil
+		}
+		out = append(out, cffDictEntry{op: e.op, operands: operands})
+		stack = stack[:0]
+	}
+	return out, nil
+}
+
+// cffDictNumber returns the value of an integer or real operand of a DICT.
+func cffDictNumber(b []byte) float64 {
+	if b[0] != 30 {
+		return float64(cffDictInt(b))
+	}
+	var s []byte
+	for _, c := range b[1:] {
+		for _, nibble := range []byte{c >> 4, c & 0x0F} {
+			switch {
+			case nibble <= 9:
+				s = append(s, '0'+nibble)
+			case nibble == 0xA:
+				s = append(s, '.')
+			case nibble == 0xB:
+				s = append(s, 'E')
+			case nibble == 0xC:
+				s = append(s, 'E', '-')
+			case nibble == 0xE:
+				s = append(s, '-')
+			case nibble == 0xF:
+				v, _ := strconv.ParseFloat(string(s), 64)
+				return v
+			}
+		}
+	}
+	return 0
+}
+
+// appendCFFDictNumber appends the shortest encoding of v as a DICT operand to buf.
+func appendCFFDictNumber(buf []byte, v float64) []byte {
+	if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+		switch i := int32(v); {
+		case i >= -107 && i <= 107:
+			return append(buf, byte(i+139))
+		case i >= -1131 && i <= 1131:
+			// These are encoded as in charstrings.
+			return appendCharStringInt(buf, i)
+		case i >= math.MinInt16 && i <= math.MaxInt16:
+			return append(buf, 28, byte(i>>8), byte(i))
+		default:
+			return append(buf, 29, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+		}
+	}
+
+	var nibbles []byte
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			nibbles = append(nibbles, c-'0')
+		case c == '.':
+			nibbles = append(nibbles, 0xA)
+		case c == '-':
+			nibbles = append(nibbles, 0xE)
+		case c == 'e' && i+1 < len(s) && s[i+1] == '-':
+			nibbles = append(nibbles, 0xC)
+			i++
+		case c == 'e':
+			nibbles = append(nibbles, 0xB)
+			if i+1 < len(s) && s[i+1] == '+' {
+				i++
+			}
+		}
+	}
+	nibbles = append(nibbles, 0xF)
+	if len(nibbles)%2 == 1 {
+		nibbles = append(nibbles, 0xF)
+	}
+	buf = append(buf, 30)
+	for i := 0; i < len(nibbles); i += 2 {
+		buf = append(buf, nibbles[i]<<4|nibbles[i+1])
+	}
+	return buf
+}
+
+// Bounds returns the bounding box of a glyph in the default instance, with
+// curves bounded by their extrema, and the coordinates rounded outwards. It
+// returns zeros for a glyph without an outline.
+func (table *TableCFF2) Bounds(glyph GlyphID) (xMin, yMin, xMax, yMax int16, err error) {
+	if int(glyph) >= len(table.CharStrings) {
+		return 0, 0, 0, 0, fmt.Errorf("glyph %d out of range", glyph)
+	}
+	b := &cff2BoundsPen{minX: math.Inf(1), minY: math.Inf(1), maxX: math.Inf(-1), maxY: math.Inf(-1)}
+	c := table.newCFF2Interpreter(glyph, func(_ int, value float64, _ []float64) cff2Value {
+		return cff2Value{value: value}
+	})
+	c.visit = b.visit
+	if err := c.run(table.CharStrings[glyph], 0); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if math.IsInf(b.minX, 1) {
+		return 0, 0, 0, 0, nil
+	}
+	return int16(math.Floor(b.minX)), int16(math.Floor(b.minY)), int16(math.Ceil(b.maxX)), int16(math.Ceil(b.maxY)), nil
+}
+
+// cff2BoundsPen follows the path operators of a charstring, and accumulates
+// the bounds of the segments drawn.
+type cff2BoundsPen struct {
+	x, y                   float64
+	minX, minY, maxX, maxY float64
+}
+
+func (b *cff2BoundsPen) visit(op int, _ []byte, operands []cff2Value) error {
+	v := make([]float64, len(operands))
+	for i, o := range operands {
+		v[i] = o.value
+	}
+	n := len(v)
+	switch op {
+	case csRmoveto:
+		if n == 2 {
+			b.x, b.y = b.x+v[0], b.y+v[1]
+		}
+	case csHmoveto:
+		if n == 1 {
+			b.x += v[0]
+		}
+	case csVmoveto:
+		if n == 1 {
+			b.y += v[0]
+		}
+	case csRlineto:
+		for i := 0; i+2 <= n; i += 2 {
+			b.line(v[i], v[i+1])
+		}
+	case csHlineto, csVlineto:
+		horizontal := op == csHlineto
+		for _, d := range v {
+			if horizontal {
+				b.line(d, 0)
+			} else {
+				b.line(0, d)
+			}
+			horizontal = !horizontal
+		}
+	case csRrcurveto:
+		for i := 0; i+6 <= n; i += 6 {
+			b.curve(v[i], v[i+1], v[i+2], v[i+3], v[i+4], v[i+5])
+		}
+	case csRcurveline:
+		i := 0
+		for ; i+6 <= n-2; i += 6 {
+			b.curve(v[i], v[i+1], v[i+2], v[i+3], v[i+4], v[i+5])
+		}
+		if i+2 <= n {
+			b.line(v[i], v[i+1])
+		}
+	case csRlinecurve:
+		i := 0
+		for ; i+2 <= n-6; i += 2 {
+			b.line(v[i], v[i+1])
+		}
+		if i+6 <= n {
+			b.curve(v[i], v[i+1], v[i+2], v[i+3], v[i+4], v[i+5])
+		}
+	case csVvcurveto, csHhcurveto:
+		first := 0.0
+		if n%4 == 1 {
+			first, v = v[0], v[1:]
+		}
+		for i := 0; i+4 <= len(v); i += 4 {
+			if op == csVvcurveto {
+				b.curve(first, v[i], v[i+1], v[i+2], 0, v[i+3])
+			} else {
+				b.curve(v[i], first, v[i+1], v[i+2], v[i+3], 0)
+			}
+			first = 0
+		}
+	case csHvcurveto, csVhcurveto:
+		horizontal := op == csHvcurveto
+		for i := 0; i+4 <= n; i += 4 {
+			last := 0.0
+			if n-i == 5 {
+				last = v[i+4]
+			}
+			if horizontal {
+				b.curve(v[i], 0, v[i+1], v[i+2], last, v[i+3])
+			} else {
+				b.curve(0, v[i], v[i+1], v[i+2], v[i+3], last)
+			}
+			horizontal = !horizontal
+		}
+	case csFlex:
+		if n == 13 {
+			b.curve(v[0], v[1], v[2], v[3], v[4], v[5])
+			b.curve(v[6], v[7], v[8], v[9], v[10], v[11])
+		}
+	case csHflex:
+		if n == 7 {
+			b.curve(v[0], 0, v[1], v[2], v[3], 0)
+			b.curve(v[4], 0, v[5], -v[2], v[6], 0)
+		}
+	case csHflex1:
+		if n == 9 {
+			b.curve(v[0], v[1], v[2], v[3], v[4], 0)
+			b.curve(v[5], 0, v[6], v[7], v[8], -(v[1] + v[3] + v[7]))
+		}
+	case csFlex1:
+		if n == 11 {
+			dx, dy := v[0]+v[2]+v[4]+v[6]+v[8], v[1]+v[3]+v[5]+v[7]+v[9]
+			dx6, dy6 := v[10], -dy
+			if math.Abs(dx) <= math.Abs(dy) {
+				dx6, dy6 = -dx, v[10]
+			}
+			b.curve(v[0], v[1], v[2], v[3], v[4], v[5])
+			b.curve(v[6], v[7], v[8], v[9], dx6, dy6)
+		}
+	}
+	return nil
+}
+
+func (b *cff2BoundsPen) add(x, y float64) {
+	b.minX, b.maxX = math.Min(b.minX, x), math.Max(b.maxX, x)
+	b.minY, b.maxY = math.Min(b.minY, y), math.Max(b.maxY, y)
+}
+
+func (b *cff2BoundsPen) line(dx, dy float64) {
+	b.add(b.x, b.y)
+	b.x, b.y = b.x+dx, b.y+dy
+	b.add(b.x, b.y)
+}
+
+// curve adds the end points of a cubic curve given by relative offsets, and
+// its extrema between them.
+func (b *cff2BoundsPen) curve(dx1, dy1, dx2, dy2, dx3, dy3 float64) {
+	x0, y0 := b.x, b.y
+	x1, y1 := x0+dx1, y0+dy1
+	x2, y2 := x1+dx2, y1+dy2
+	x3, y3 := x2+dx3, y2+dy3
+	b.add(x0, y0)
+	b.add(x3, y3)
+	for _, t := range cubicExtrema(x0, x1, x2, x3) {
+		b.add(cubicAt(x0, x1, x2, x3, t), cubicAt(y0, y1, y2, y3, t))
+	}
+	for _, t := range cubicExtrema(y0, y1, y2, y3) {
+		b.add(cubicAt(x0, x1, x2, x3, t), cubicAt(y0, y1, y2, y3, t))
+	}
+	b.x, b.y = x3, y3
+}
+
+// cubicExtrema returns the parameters in (0, 1) at which the derivative of a
+// cubic Bézier curve with the given control values is zero.
+func cubicExtrema(p0, p1, p2, p3 float64) []float64 {
+	a := -p0 + 3*p1 - 3*p2 + p3
+	b := 2 * (p0 - 2*p1 + p2)
+	c := p1 - p0
+
+	var roots []float64
+	if math.Abs(a) < 1e-12 {
+		if b != 0 {
+			roots = append(roots, -c/b)
+		}
+	} else if d := b*b - 4*a*c; d >= 0 {
+		s := math.Sqrt(d)
+		roots = append(roots, (-b+s)/(2*a), (-b-s)/(2*a))
+	}
+
+	var ts []float64
+	for _, t := range roots {
+		if t > 0 && t < 1 {
+			ts = append(ts, t)
+		}
+	}
+	return ts
+}
+
+func cubicAt(p0, p1, p2, p3, t float64) float64 {
+	u := 1 - t
+	return u*u*u*p0 + 3*u*u*t*p1 + 3*u*t*t*p2 + t*t*t*p3
+}
@@ -0,0 +1,129 @@
+package sfnt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Bounds returns the bounding box of a glyph, with curves bounded by their
+// extrema, and the coordinates rounded outwards. It returns zeros for a glyph
+// without an outline. The accent of a glyph built with the deprecated seac
+// arguments of endchar is not part of its bounds.
+func (table *TableCFF) Bounds(glyph GlyphID) (xMin, yMin, xMax, yMax int16, err error) {
+	if int(glyph) >= len(table.CharStrings) {
+		return 0, 0, 0, 0, fmt.Errorf("glyph %d out of range", glyph)
+	}
+	fd := 0
+	if int(glyph) < len(table.FDSelect) {
+		fd = int(table.FDSelect[glyph])
+	}
+	b := &cff2BoundsPen{minX: math.Inf(1), minY: math.Inf(1), maxX: math.Inf(-1), maxY: math.Inf(-1)}
+	c := &cffInterpreter{table: table, fd: fd, visit: b.visit}
+	if err := c.run(table.CharStrings[glyph], 0); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	if math.IsInf(b.minX, 1) {
+		return 0, 0, 0, 0, nil
+	}
+	return int16(math.Floor(b.minX)), int16(math.Floor(b.minY)), int16(math.Ceil(b.maxX)), int16(math.Ceil(b.maxY)), nil
+}
+
+// cffInterpreter runs Type 2 charstrings, calling subroutines and removing
+// the advance width from the operands of the first stack-clearing operator.
+type cffInterpreter struct {
+	table      *TableCFF
+	fd         int
+	numStems   int
+	stack      []cff2Value
+	seenClear  bool
+	endOfGlyph bool
+	// visit is called for each operator other than the subroutine operators,
+	// with its encoding followed by any hint mask, and its operands.
+	visit func(op int, code []byte, operands []cff2Value) error
+}
+
+func (c *cffInterpreter) run(cs []byte, depth int) error {
+	if err := c.table.checkCallDepth(depth); err != nil {
+		return err
+	}
+	for len(cs) > 0 && !c.endOfGlyph {
+		op, size, err := readCharStringToken(cs)
+		if err != nil {
+			return err
+		}
+		code := cs[:size]
+		cs = cs[size:]
+		if op < 0 {
+			if len(c.stack) >= cff2MaxStack {
+				return errors.New("too many operands")
+			}
+			c.stack = append(c.stack, cff2Value{value: cff2Number(code)})
+			continue
+		}
+
+		switch op {
+		case csCallsubr, csCallgsubr:
+			if err := c.call(op, depth); err != nil {
+				return err
+			}
+			continue
+		case csReturn:
+			return nil
+		case csHstem, csVstem, csHstemhm, csVstemhm:
+			c.clear(len(c.stack)%2 == 1)
+			c.numStems += len(c.stack) / 2
+		case csHintmask, csCntrmask:
+			c.clear(len(c.stack)%2 == 1)
+			c.numStems += len(c.stack) / 2
+			n := (c.numStems + 7) / 8
+			if len(cs) < n {
+				return io.ErrUnexpectedEOF
+			}
+			code = append(append([]byte(nil), code...), cs[:n]...)
+			cs = cs[n:]
+		case csRmoveto, csHmoveto, csVmoveto, csEndchar:
+			c.clear(hasWidth(op, len(c.stack)))
+			c.endOfGlyph = op == csEndchar
+		default:
+			if isCharStringArithmetic(op) {
+				return fmt.Errorf("unsupported arithmetic operator 12 %d", op-1200)
+			}
+		}
+		if err := c.visit(op, code, c.stack); err != nil {
+			return err
+		}
+		c.stack = c.stack[:0]
+	}
+	return nil
+}
+
+// clear handles a stack-clearing operator, removing the advance width from
+// the stack if it is the first one and hasWidth is true.
+func (c *cffInterpreter) clear(hasWidth bool) {
+	if !c.seenClear && hasWidth {
+		c.stack = c.stack[1:]
+	}
+	c.seenClear = true
+}
+
+// call runs the callsubr or callgsubr operator.
+func (c *cffInterpreter) call(op int, depth int) error {
+	subrs := c.table.GlobalSubrs
+	if op == csCallsubr {
+		subrs = nil
+		if c.fd < len(c.table.FontDicts) {
+			subrs = c.table.FontDicts[c.fd].Subrs
+		}
+	}
+	if len(c.stack) == 0 {
+		return errors.New("subroutine call without an index")
+	}
+	index := int(c.stack[len(c.stack)-1].value) + CFFSubrBias(len(subrs))
+	c.stack = c.stack[:len(c.stack)-1]
+	if index < 0 || index >= len(subrs) {
+		return fmt.Errorf("subroutine %d out of range", index)
+	}
+	return c.run(subrs[index], depth+1)
+}
@@ -0,0 +1,169 @@
+package sfnt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// cffNumStandardStrings is the number of standard strings, which have the
+// string IDs before those of the strings in the table.
+const cffNumStandardStrings = 391
+
+// cffMaxFontDicts is the maximum number of font dicts in the FDArray, whose
+// index is a byte in FDSelect.
+const cffMaxFontDicts = 256
+
+// MergeCFF returns a CID-keyed table containing glyphs[i] of tables[i] for
+// each table, after the glyphs of the previous tables, so that the CID of
+// each glyph is its glyph ID. Each font dict of the tables becomes a font
+// dict of the FDArray, with its Private DICT and local subroutines, and the
+// global subroutines of each table are numbered after those of the previous
+// tables. The Top DICT is that of the first table, and the tables must have
+// the same FontMatrix. The glyph names of name-keyed tables are not kept.
+func MergeCFF(tables []*TableCFF, glyphs [][]GlyphID) (*TableCFF, error) {
+	if len(tables) == 0 || len(glyphs) != len(tables) {
+		return nil, errors.New("a list of glyphs is needed for each table")
+	}
+	matrix := tables[0].fontMatrix()
+	out := &TableCFF{
+		baseTable: baseTable(TagCFF),
+		FontName:  tables[0].FontName,
+		Strings:   append([][]byte(nil), tables[0].Strings...),
+		options:   tables[0].options,
+	}
+
+	subsets := make([]*TableCFF, len(tables))
+	numGlobalSubrs, numFontDicts := 0, 0
+	for i, table := range tables {
+		if m := table.fontMatrix(); !equalFontMatrix(m, matrix) {
+			return nil, fmt.Errorf("table %d: FontMatrix %v differs from %v", i, m, matrix)
+		}
+		sub, err := table.Subset(glyphs[i])
+		if err != nil {
+			return nil, fmt.Errorf("table %d: %w", i, err)
+		}
+		subsets[i] = sub
+		numGlobalSubrs += len(sub.GlobalSubrs)
+		numFontDicts += len(sub.FontDicts)
+	}
+	if numGlobalSubrs > math.MaxUint16 {
+		return nil, fmt.Errorf("merged table has %d global subroutines, more than the maximum of %d", numGlobalSubrs, math.MaxUint16)
+	}
+	if numFontDicts > cffMaxFontDicts {
+		return nil, fmt.Errorf("merged table has %d font dicts, more than the maximum of %d", numFontDicts, cffMaxFontDicts)
+	}
+	bias := CFFSubrBias(numGlobalSubrs)
+
+	for i, sub := range subsets {
+		firstGlobalSubr, firstFontDict := len(out.GlobalSubrs), len(out.FontDicts)
+		operand := func(key cffSubrKey) []byte {
+			if key.fd < 0 {
+				return appendCharStringInt(nil, int32(firstGlobalSubr+key.index-bias))
+			}
+			return appendCharStringInt(nil, int32(key.index-CFFSubrBias(len(sub.FontDicts[key.fd].Subrs))))
+		}
+
+		// The subset only contains the subroutines used by its glyphs, so
+		// following the glyphs finds the calls in every subroutine.
+		s := &cffSubsetter{cff: sub, subrs: make(map[cffSubrKey]*cffUsedSubr)}
+		for g, cs := range sub.CharStrings {
+			fd := 0
+			if g < len(sub.FDSelect) {
+				fd = int(sub.FDSelect[g])
+			}
+			var calls []cffCall
+			w := &cffWalker{s: s, fd: fd}
+			if err := w.walk(cs, 0, &calls); err != nil {
+				return nil, fmt.Errorf("table %d: glyph %d: %w", i, glyphs[i][g], err)
+			}
+			out.CharStrings = append(out.CharStrings, rewriteCFFCalls(cs, calls, operand))
+			out.FDSelect = append(out.FDSelect, uint8(firstFontDict+fd))
+		}
+
+		globalSubrs := make([][]byte, len(sub.GlobalSubrs))
+		fontDicts := make([]CFFFontDict, len(sub.FontDicts))
+		for fd, d := range sub.FontDicts {
+			fontDicts[fd] = CFFFontDict{dict: out.mergedFontDict(sub, d), private: d.private}
+			if len(d.Subrs) > 0 {
+				fontDicts[fd].Subrs = make([][]byte, len(d.Subrs))
+			}
+		}
+		for key, subr := range s.subrs {
+			code := rewriteCFFCalls(s.body(key), subr.calls, operand)
+			if key.fd < 0 {
+				globalSubrs[key.index] = code
+			} else {
+				fontDicts[key.fd].Subrs[key.index] = code
+			}
+		}
+		out.GlobalSubrs = append(out.GlobalSubrs, globalSubrs...)
+		out.FontDicts = append(out.FontDicts, fontDicts...)
+	}
+	if len(out.CharStrings) > math.MaxUint16 {
+		return nil, fmt.Errorf("merged table has %d glyphs, more than the maximum of %d", len(out.CharStrings), math.MaxUint16)
+	}
+
+	out.Charset = make([]uint16, len(out.CharStrings))
+	for g := range out.Charset {
+		out.Charset[g] = uint16(g)
+	}
+
+	// The ROS operator must be the first operator of the Top DICT.
+	ros := cffDict{}.set(cffOpROS, int32(out.addString([]byte("Adobe"))), int32(out.addString([]byte("Identity"))), 0)
+	topDict := tables[0].topDict.remove(cffOpUniqueID, cffOpXUID, cffOpCharset, cffOpEncoding, cffOpCharStrings,
+		cffOpPrivate, cffOpROS, cffOpCIDFontVersion, cffOpCIDFontRevision, cffOpCIDFontType, cffOpCIDCount,
+		cffOpUIDBase, cffOpFDArray, cffOpFDSelect, cffOpFontName)
+	out.topDict = append(ros, topDict...).set(cffOpCIDCount, int32(len(out.CharStrings)))
+	return out, nil
+}
+
+// mergedFontDict returns the Font DICT of a font dict of from in the FDArray
+// of the merged table, adding the name of the font dict to its strings.
+func (table *TableCFF) mergedFontDict(from *TableCFF, fd CFFFontDict) cffDict {
+	if !from.IsCIDKeyed() {
+		return cffDict{}.set(cffOpFontName, int32(table.addString(from.FontName)))
+	}
+	dict := fd.dict
+	if operands := dict.get(cffOpFontName); len(operands) == 1 {
+		if i := int(operands[0]) - cffNumStandardStrings; i >= 0 && i < len(from.Strings) {
+			dict = dict.set(cffOpFontName, int32(table.addString(from.Strings[i])))
+		}
+	}
+	return dict
+}
+
+// addString returns the string ID of s, adding it to the strings of the
+// table if it isn't there.
+func (table *TableCFF) addString(s []byte) int {
+	for i, t := range table.Strings {
+		if string(t) == string(s) {
+			return cffNumStandardStrings + i
+		}
+	}
+	table.Strings = append(table.Strings, s)
+	return cffNumStandardStrings + len(table.Strings) - 1
+}
+
+// fontMatrix returns the FontMatrix of the Top DICT, which maps the units of
+// the charstrings to the em.
+func (table *TableCFF) fontMatrix() []float64 {
+	matrix := []float64{0.001, 0, 0, 0.001, 0, 0}
+	for _, e := range table.topDict {
+		if e.op == cffOpFontMatrix && len(e.operands) == len(matrix) {
+			for i, b := range e.operands {
+				matrix[i] = cffDictNumber(b)
+			}
+		}
+	}
+	return matrix
+}
+
+func equalFontMatrix(a, b []float64) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
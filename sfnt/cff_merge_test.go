@@ -0,0 +1,69 @@
+package sfnt
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestMergeCFF(t *testing.T) {
+	cff := testCFF(t)
+
+	// The second table is CID-keyed, with the glyphs split between two font dicts.
+	cid := testCFF(t)
+	cid.topDict = cid.topDict.remove(cffOpPrivate).set(cffOpROS, 391, 392, 0)
+	fd := cid.FontDicts[0]
+	cid.FontDicts = []CFFFontDict{fd, {private: fd.private, Subrs: fd.Subrs}}
+	cid.FDSelect = make([]uint8, len(cid.CharStrings))
+	for g := len(cid.FDSelect) / 2; g < len(cid.FDSelect); g++ {
+		cid.FDSelect[g] = 1
+	}
+
+	numGlyphs := GlyphID(len(cff.CharStrings))
+	var all []GlyphID
+	for g := GlyphID(0); g < numGlyphs; g++ {
+		all = append(all, g)
+	}
+	glyphs := [][]GlyphID{all, {36, 7, numGlyphs - 1}}
+	merged, err := MergeCFF([]*TableCFF{cff, cid}, glyphs)
+	if err != nil {
+		t.Fatalf("MergeCFF() err = %q, want nil", err)
+	}
+	got := checkCFFRoundTrip(t, merged)
+
+	if !got.IsCIDKeyed() {
+		t.Errorf("IsCIDKeyed() = false, want true")
+	}
+	if len(got.CharStrings) != len(all)+3 {
+		t.Fatalf("MergeCFF() has %d charstrings, want %d", len(got.CharStrings), len(all)+3)
+	}
+	if len(got.FontDicts) != 3 {
+		t.Errorf("MergeCFF() has %d font dicts, want 3", len(got.FontDicts))
+	}
+	if name := got.FontDicts[0].dict.get(cffOpFontName); len(name) != 1 || !bytes.Equal(got.Strings[name[0]-cffNumStandardStrings], cff.FontName) {
+		t.Errorf("font dict 0 has FontName %v, want %q", name, cff.FontName)
+	}
+
+	// The subroutines are renumbered, so the glyphs are compared with their
+	// subroutine calls replaced by the subroutines.
+	n := 0
+	for i, table := range []*TableCFF{cff, cid} {
+		for _, g := range glyphs[i] {
+			if got.Charset[n] != uint16(n) {
+				t.Errorf("glyph %d has CID %d, want %d", n, got.Charset[n], n)
+			}
+			if !bytes.Equal(flattenCharString(t, got, GlyphID(n)), flattenCharString(t, table, g)) {
+				t.Errorf("glyph %d: charstring differs from glyph %d of table %d", n, g, i)
+			}
+			n++
+		}
+	}
+	if len(got.GlobalSubrs) <= len(cff.GlobalSubrs) {
+		t.Errorf("MergeCFF() has %d global subrs, want more than the %d of the first table", len(got.GlobalSubrs), len(cff.GlobalSubrs))
+	}
+
+	scaled := testCFF(t)
+	scaled.topDict = scaled.topDict.set(cffOpFontMatrix, 1, 0, 0, 1, 0, 0)
+	if _, err := MergeCFF([]*TableCFF{cff, scaled}, [][]GlyphID{{0}, {1}}); err == nil {
+		t.Errorf("MergeCFF() with different font matrices err = nil, want an error")
+	}
+}
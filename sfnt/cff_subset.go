@@ -0,0 +1,239 @@
+package sfnt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Subset returns a copy of the table containing only some of its glyphs, so
+// that glyph i of the copy is glyphs[i] of the table. The subroutines and
+// font dicts that are not used by the glyphs are removed, and a custom
+// encoding is replaced by the standard encoding, as it refers to the old
+// glyph IDs.
+func (table *TableCFF) Subset(glyphs []GlyphID) (*TableCFF, error) {
+	s := &cffSubsetter{cff: table, subrs: make(map[cffSubrKey]*cffUsedSubr)}
+
+	out := *table
+	out.CharStrings = make([][]byte, len(glyphs))
+	out.Charset = make([]uint16, len(glyphs))
+	out.topDict = table.topDict.remove(cffOpEncoding)
+	out.encoding = nil
+
+	// fds maps the font dicts that are kept to their new index.
+	fds := make(map[int]int)
+	calls := make([][]cffCall, len(glyphs))
+	for i, id := range glyphs {
+		if int(id) >= len(table.CharStrings) {
+			return nil, fmt.Errorf("glyph %d out of range", id)
+		}
+		fd := 0
+		if int(id) < len(table.FDSelect) {
+			fd = int(table.FDSelect[id])
+		}
+		if _, found := fds[fd]; !found {
+			fds[fd] = len(fds)
+		}
+
+		w := &cffWalker{s: s, fd: fd}
+		if err := w.walk(table.CharStrings[id], 0, &calls[i]); err != nil {
+			return nil, fmt.Errorf("glyph %d: %w", id, err)
+		}
+		out.Charset[i] = table.Charset[id]
+	}
+
+	// Number the subroutines that are used, in their original order.
+	var keys []cffSubrKey
+	for key := range s.subrs {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].fd != keys[j].fd {
+			return keys[i].fd < keys[j].fd
+		}
+		return keys[i].index < keys[j].index
+	})
+	counts := make(map[int]int)
+	for _, key := range keys {
+		s.subrs[key].newIndex = counts[key.fd]
+		counts[key.fd]++
+	}
+	operand := func(key cffSubrKey) []byte {
+		return appendCharStringInt(nil, int32(s.subrs[key].newIndex-CFFSubrBias(counts[key.fd])))
+	}
+
+	for i, id := range glyphs {
+		out.CharStrings[i] = rewriteCFFCalls(table.CharStrings[id], calls[i], operand)
+	}
+	out.GlobalSubrs = nil
+	if n := counts[-1]; n > 0 {
+		out.GlobalSubrs = make([][]byte, n)
+	}
+	out.FontDicts = make([]CFFFontDict, len(fds))
+	for fd, i := range fds {
+		out.FontDicts[i] = table.FontDicts[fd]
+		out.FontDicts[i].Subrs = nil
+		if n := counts[fd]; n > 0 {
+			out.FontDicts[i].Subrs = make([][]byte, n)
+		}
+	}
+	for _, key := range keys {
+		subr := s.subrs[key]
+		code := rewriteCFFCalls(s.body(key), subr.calls, operand)
+		if key.fd < 0 {
+			out.GlobalSubrs[subr.newIndex] = code
+		} else {
+			out.FontDicts[fds[key.fd]].Subrs[subr.newIndex] = code
+		}
+	}
+
+	if table.FDSelect != nil {
+		out.FDSelect = make([]uint8, len(glyphs))
+		for i, id := range glyphs {
+			out.FDSelect[i] = uint8(fds[int(table.FDSelect[id])])
+		}
+	}
+	return &out, nil
+}
+
+// cffCall is a subroutine call in a charstring.
+type cffCall struct {
+	// pos and size locate the operand that is the index of the subroutine.
+	pos, size int
+	key       cffSubrKey
+}
+
+// cffUsedSubr is a subroutine used by the glyphs of a subset.
+type cffUsedSubr struct {
+	calls    []cffCall
+	newIndex int
+}
+
+// cffSubsetter finds the subroutines used by the glyphs of a subset.
+type cffSubsetter struct {
+	cff   *TableCFF
+	subrs map[cffSubrKey]*cffUsedSubr
+}
+
+// body returns the charstring of a subroutine.
+func (s *cffSubsetter) body(key cffSubrKey) []byte {
+	if key.fd < 0 {
+		return s.cff.GlobalSubrs[key.index]
+	}
+	return s.cff.FontDicts[key.fd].Subrs[key.index]
+}
+
+// cffWalker follows a charstring through its subroutine calls, counting
+// the stems so that the hint masks can be skipped.
+type cffWalker struct {
+	s  *cffSubsetter
+	fd int
+
+	numOperands int
+	numStems    int
+	// last is the position and size of the last operand in the charstring
+	// being walked, or has a size of 0 if it was not pushed by that charstring.
+	last       cffCall
+	endOfGlyph bool
+}
+
+// walk follows a charstring, appending its subroutine calls to calls if
+// calls is not nil.
+func (w *cffWalker) walk(cs []byte, depth int, calls *[]cffCall) error {
+	if err := w.s.cff.checkCallDepth(depth); err != nil {
+		return err
+	}
+	w.last.size = 0
+	for pos := 0; pos < len(cs) && !w.endOfGlyph; {
+		op, size, err := readCharStringToken(cs[pos:])
+		if err != nil {
+			return err
+		}
+		start := pos
+		pos += size
+		if op < 0 {
+			w.numOperands++
+			w.last = cffCall{pos: start, size: size}
+			continue
+		}
+
+		switch op {
+		case csHstem, csVstem, csHstemhm, csVstemhm:
+			w.numStems += w.numOperands / 2
+		case csHintmask, csCntrmask:
+			w.numStems += w.numOperands / 2
+			pos += (w.numStems + 7) / 8
+			if pos > len(cs) {
+				return errors.New("hint mask out of range")
+			}
+		case csCallsubr, csCallgsubr:
+			if w.last.size == 0 || w.last.pos+w.last.size != start {
+				return errors.New("subroutine call without an index")
+			}
+			call, err := w.call(op, cs[w.last.pos:start])
+			if err != nil {
+				return err
+			}
+			call.pos, call.size = w.last.pos, w.last.size
+			if calls != nil {
+				*calls = append(*calls, call)
+			}
+			w.numOperands--
+
+			subr, found := w.s.subrs[call.key]
+			var subrCalls *[]cffCall
+			if !found {
+				subr = &cffUsedSubr{}
+				w.s.subrs[call.key] = subr
+				subrCalls = &subr.calls
+			}
+			if err := w.walk(w.s.body(call.key), depth+1, subrCalls); err != nil {
+				return err
+			}
+			w.last.size = 0
+			continue
+		case csReturn:
+			return nil
+		case csEndchar:
+			w.endOfGlyph = true
+		default:
+			if isCharStringArithmetic(op) {
+				return fmt.Errorf("unsupported arithmetic operator 12 %d", op-1200)
+			}
+		}
+		w.numOperands = 0
+		w.last.size = 0
+	}
+	return nil
+}
+
+// call returns the subroutine called by the callsubr or callgsubr operator,
+// with the encoded index.
+func (w *cffWalker) call(op int, index []byte) (cffCall, error) {
+	key := cffSubrKey{fd: -1}
+	n := len(w.s.cff.GlobalSubrs)
+	if op == csCallsubr {
+		key.fd, n = w.fd, 0
+		if w.fd < len(w.s.cff.FontDicts) {
+			n = len(w.s.cff.FontDicts[w.fd].Subrs)
+		}
+	}
+	key.index = int(charStringInt(index)) + CFFSubrBias(n)
+	if key.index < 0 || key.index >= n {
+		return cffCall{}, fmt.Errorf("subroutine %d out of range", key.index)
+	}
+	return cffCall{key: key}, nil
+}
+
+// rewriteCFFCalls returns a copy of a charstring with the index of each
+// call replaced by the operand returned by index.
+func rewriteCFFCalls(cs []byte, calls []cffCall, index func(cffSubrKey) []byte) []byte {
+	out := make([]byte, 0, len(cs))
+	pos := 0
+	for _, call := range calls {
+		out = append(out, cs[pos:call.pos]...)
+		out = append(out, index(call.key)...)
+		pos = call.pos + call.size
+	}
+	return append(out, cs[pos:]...)
+}
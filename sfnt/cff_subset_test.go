@@ -0,0 +1,96 @@
+package sfnt
+
+import (
+	"bytes"
+	"testing"
+)
+
+// flattenCharString returns the tokens of a charstring with its subroutine
+// calls replaced by the subroutines.
+func flattenCharString(t *testing.T, cff *TableCFF, glyph GlyphID) []byte {
+	t.Helper()
+	var out []byte
+	var operands [][]byte
+	numStems := 0
+	var run func(cs []byte, depth int) bool
+	run = func(cs []byte, depth int) bool {
+		for len(cs) > 0 {
+			op, size, err := readCharStringToken(cs)
+			if err != nil || depth > maxCharStringDepth {
+				t.Fatalf("glyph %d: invalid charstring", glyph)
+			}
+			code := cs[:size]
+			cs = cs[size:]
+			switch {
+			case op < 0:
+				operands = append(operands, code)
+				continue
+			case op == csCallsubr || op == csCallgsubr:
+				subrs := cff.GlobalSubrs
+				if op == csCallsubr {
+					subrs = cff.Subrs(glyph)
+				}
+				index := int(charStringInt(operands[len(operands)-1])) + CFFSubrBias(len(subrs))
+				operands = operands[:len(operands)-1]
+				if run(subrs[index], depth+1) {
+					return true
+				}
+				continue
+			case op == csReturn:
+				return false
+			}
+			for _, b := range operands {
+				out = append(out, b...)
+			}
+			out = append(out, code...)
+			switch op {
+			case csHstem, csVstem, csHstemhm, csVstemhm:
+				numStems += len(operands) / 2
+			case csHintmask, csCntrmask:
+				numStems += len(operands) / 2
+				n := (numStems + 7) / 8
+				out = append(out, cs[:n]...)
+				cs = cs[n:]
+			}
+			operands = nil
+			if op == csEndchar {
+				return true
+			}
+		}
+		return false
+	}
+	run(cff.CharStrings[glyph], 0)
+	return out
+}
+
+func TestCFFSubset(t *testing.T) {
+	cff := testCFF(t)
+	glyphs := []GlyphID{0, 36, 7, 100, 7}
+	got, err := cff.Subset(glyphs)
+	if err != nil {
+		t.Fatalf("Subset() err = %q, want nil", err)
+	}
+
+	if len(got.CharStrings) != len(glyphs) {
+		t.Fatalf("Subset() has %d charstrings, want %d", len(got.CharStrings), len(glyphs))
+	}
+	for i, id := range glyphs {
+		if got.Charset[i] != cff.Charset[id] {
+			t.Errorf("glyph %d: string ID %d, want %d", i, got.Charset[i], cff.Charset[id])
+		}
+		if !bytes.Equal(flattenCharString(t, got, GlyphID(i)), flattenCharString(t, cff, id)) {
+			t.Errorf("glyph %d: charstring differs from glyph %d", i, id)
+		}
+	}
+	if n := len(got.Subrs(0)); n == 0 || n >= len(cff.Subrs(0)) {
+		t.Errorf("Subset() kept %d of %d subrs, want some to be removed", n, len(cff.Subrs(0)))
+	}
+	if n := len(got.GlobalSubrs); n == 0 || n >= len(cff.GlobalSubrs) {
+		t.Errorf("Subset() kept %d of %d global subrs, want some to be removed", n, len(cff.GlobalSubrs))
+	}
+	checkCFFRoundTrip(t, got)
+
+	if _, err := cff.Subset([]GlyphID{GlyphID(len(cff.CharStrings))}); err == nil {
+		t.Errorf("Subset() of a missing glyph err = nil, want an error")
+	}
+}
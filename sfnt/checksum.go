@@ -0,0 +1,127 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChecksumMismatch describes a single checksum that did not match the font data.
+type ChecksumMismatch struct {
+	Tag      Tag    // Tag of the table, or the zero Tag for the checksum of the whole font.
+	Expected uint32 // Expected is the checksum recorded in the font.
+	Actual   uint32 // Actual is the checksum computed from the font data.
+}
+
+// String returns a readable description of the mismatch.
+func (m ChecksumMismatch) String() string {
+	name := fmt.Sprintf("table %q", m.Tag)
+	if m.Tag == (Tag{}) {
+		name = "font"
+	}
+	return fmt.Sprintf("%s: expected checksum 0x%08x, got 0x%08x", name, m.Expected, m.Actual)
+}
+
+// ChecksumError is returned by VerifyChecksums and lists every checksum that
+// did not match. It wraps ErrInvalidChecksum.
+type ChecksumError struct {
+	Mismatches []ChecksumMismatch
+}
+
+func (e *ChecksumError) Error() string {
+	var str strings.Builder
+	str.WriteString(ErrInvalidChecksum.Error())
+	for i, m := range e.Mismatches {
+		if i == 0 {
+			str.WriteString(": ")
+		} else {
+			str.WriteString("; ")
+		}
+		str.WriteString(m.String())
+	}
+	return str.String()
+}
+
+func (e *ChecksumError) Unwrap() error {
+	return ErrInvalidChecksum
+}
+
+// headChecksumAdjustmentOffset is the offset of CheckSumAdjustment within the 'head' table.
+const headChecksumAdjustmentOffset = 8
+
+// VerifyChecksums checks the checksum recorded in the table directory for each
+// table against the table data, and the 'head' table's checksum adjustment against
+// the whole font. All mismatches are reported in a *ChecksumError.
+//
+// Checksums are only available for fonts read from OpenType, TrueType, TrueType
+// Collection and WOFF files; for other fonts VerifyChecksums returns nil.
+// For WOFF files the whole font checksum is computed over the sfnt the file
+// decodes to.
+func (font *Font) VerifyChecksums() error {
+	if font.header == nil {
+		return nil
+	}
+
+	sections := make([]*tableSection, 0, len(font.tables))
+	for _, s := range font.tables {
+		sections = append(sections, s)
+	}
+	sort.Slice(sections, func(i, j int) bool {
+		return sections[i].offset < sections[j].offset
+	})
+
+	var mismatches []ChecksumMismatch
+	var adjustment uint32
+	total := font.header.checkSum()
+
+	// Tables of a WOFF file are laid out in the decoded sfnt in the same order.
+	sfntOffset := uint32(otfHeaderLength + directoryEntryLength*len(sections))
+
+	for _, s := range sections {
+		buf, err := font.readTable(s)
+		if err != nil {
+			return fmt.Errorf("reading %q: %w", s.tag, err)
+		}
+
+		actual := checkSum(buf)
+		if s.tag == TagHead && len(buf) >= headChecksumAdjustmentOffset+4 {
+			adjustment = binary.BigEndian.Uint32(buf[headChecksumAdjustmentOffset:])
+			actual -= adjustment
+		}
+
+		if actual != s.checksum {
+			mismatches = append(mismatches, ChecksumMismatch{
+				Tag:      s.tag,
+				Expected: s.checksum,
+				Actual:   actual,
+			})
+		}
+
+		entry := directoryEntry{
+			Tag:      s.tag,
+			CheckSum: s.checksum,
+			Offset:   s.offset,
+			Length:   uint32(len(buf)),
+		}
+		if s.zLength != 0 {
+			entry.Offset = sfntOffset
+			sfntOffset += uint32(len(buf) + padding(len(buf)))
+		}
+
+		total += entry.checkSum() + actual
+	}
+
+	head := TableHead{tableHeadFields: tableHeadFields{CheckSumAdjustment: adjustment}}
+	if expected := head.ExpectedChecksum(); expected != total {
+		mismatches = append(mismatches, ChecksumMismatch{
+			Expected: expected,
+			Actual:   total,
+		})
+	}
+
+	if len(mismatches) > 0 {
+		return &ChecksumError{Mismatches: mismatches}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package sfnt
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksums(t *testing.T) {
+	tests := []struct {
+		filename  string
+		wholeFont bool // whether the fixture's head checksum adjustment is up to date.
+	}{
+		{filename: "Roboto-BoldItalic.ttf", wholeFont: true},
+		{filename: "Raleway-v4020-Regular.otf", wholeFont: true},
+		{filename: "open-sans-v15-latin-regular.woff"},
+		{filename: "TestTTC.ttc"},
+	}
+
+	for _, test := range tests {
+		buf, err := os.ReadFile(filepath.Join("testdata", test.filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fonts, err := ParseCollection(bytes.NewReader(buf))
+		if err != nil {
+			t.Fatalf("ParseCollection(%q) err = %q, want nil", test.filename, err)
+		}
+
+		for _, font := range fonts {
+			err := font.VerifyChecksums()
+			if test.wholeFont && err != nil {
+				t.Errorf("VerifyChecksums(%q) err = %q, want nil", test.filename, err)
+				continue
+			}
+
+			var checksumErr *ChecksumError
+			if errors.As(err, &checksumErr) {
+				for _, m := range checksumErr.Mismatches {
+					if m.Tag != (Tag{}) {
+						t.Errorf("VerifyChecksums(%q) reported %s, want table checksums to match", test.filename, m)
+					}
+				}
+			}
+
+			// Fonts written by this package always have correct checksums.
+			var out bytes.Buffer
+			if _, err := font.WriteOTF(&out); err != nil {
+				t.Fatalf("WriteOTF(%q) err = %q, want nil", test.filename, err)
+			}
+			written, err := Parse(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatalf("Parse(WriteOTF(%q)) err = %q, want nil", test.filename, err)
+			}
+			if err := written.VerifyChecksums(); err != nil {
+				t.Errorf("VerifyChecksums(WriteOTF(%q)) err = %q, want nil", test.filename, err)
+			}
+		}
+	}
+}
+
+func TestVerifyChecksumsMismatch(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", "Roboto-BoldItalic.ttf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	font, err := Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Corrupt the first byte of the 'name' table.
+	name := font.tables[TagName]
+	buf[name.offset]++
+
+	err = font.VerifyChecksums()
+	if !errors.Is(err, ErrInvalidChecksum) {
+		t.Fatalf("VerifyChecksums() err = %v, want %v", err, ErrInvalidChecksum)
+	}
+
+	var checksumErr *ChecksumError
+	if !errors.As(err, &checksumErr) {
+		t.Fatalf("VerifyChecksums() err = %T, want *ChecksumError", err)
+	}
+
+	want := []ChecksumMismatch{
+		{Tag: TagName, Expected: name.checksum, Actual: name.checksum + 1<<24},
+	}
+	if len(checksumErr.Mismatches) != 2 || checksumErr.Mismatches[0] != want[0] {
+		t.Errorf("VerifyChecksums() mismatches = %v, want %v and a whole font mismatch", checksumErr.Mismatches, want)
+	}
+	if len(checksumErr.Mismatches) == 2 && checksumErr.Mismatches[1].Tag != (Tag{}) {
+		t.Errorf("VerifyChecksums() mismatches[1].Tag = %q, want zero Tag", checksumErr.Mismatches[1].Tag)
+	}
+}
@@ -0,0 +1,56 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errInvalidCoverage = errors.New("invalid coverage table")
+
+// parseCoverage returns the glyphs in a coverage table, in the order of their
+// coverage indexes.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#coverage-table
+func parseCoverage(buf []byte) ([]GlyphID, error) {
+	if len(buf) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	format := binary.BigEndian.Uint16(buf)
+	count := int(binary.BigEndian.Uint16(buf[2:]))
+
+	switch format {
+	case 1:
+		if 4+2*count > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		glyphs := make([]GlyphID, count)
+		for i := range glyphs {
+			glyphs[i] = GlyphID(binary.BigEndian.Uint16(buf[4+2*i:]))
+		}
+		return glyphs, nil
+
+	case 2:
+		if 4+6*count > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		var glyphs []GlyphID
+		for i := 0; i < count; i++ {
+			r := buf[4+6*i:]
+			start, end := binary.BigEndian.Uint16(r), binary.BigEndian.Uint16(r[2:])
+			// Glyphs can only be covered once, so ranges that are out of
+			// order or overlap are invalid.
+			if end < start || int(binary.BigEndian.Uint16(r[4:])) != len(glyphs) ||
+				(len(glyphs) > 0 && GlyphID(start) <= glyphs[len(glyphs)-1]) {
+				return nil, errInvalidCoverage
+			}
+			for g := int(start); g <= int(end); g++ {
+				glyphs = append(glyphs, GlyphID(g))
+			}
+		}
+		return glyphs, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported coverage format %d", format)
+	}
+}
@@ -0,0 +1,520 @@
+package sfnt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Flags of head.Flags that only apply to hinted fonts.
+const (
+	headInstructionsDependOnSize  = 1 << 2
+	headInstructionsAlterAdvances = 1 << 4
+)
+
+// hintingTables are the tables that are only used by TrueType hinting.
+var hintingTables = []Tag{TagFpgm, TagPrep, TagCvt, TagHdmx, TagLTSH, TagVDMX}
+
+// Dehint removes the hinting from the font: the TrueType instructions of the
+// glyphs and the tables used to run them, and the hints of CFF charstrings.
+// The 'maxp' and 'head' tables are updated to match.
+//
+// CFF subroutines are kept when they can be dehinted on their own, and are
+// otherwise inlined, since hints can be split across subroutine calls.
+func (font *Font) Dehint() error {
+	for _, tag := range hintingTables {
+		font.RemoveTable(tag)
+	}
+
+	if font.HasTable(TagGlyf) {
+		if err := font.dehintGlyf(); err != nil {
+			return fmt.Errorf("dehinting %q: %w", TagGlyf, err)
+		}
+	}
+	if font.HasTable(TagCFF) {
+		if err := font.dehintCFF(); err != nil {
+			return fmt.Errorf("dehinting %q: %w", TagCFF, err)
+		}
+	}
+
+	head, err := font.HeadTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", TagHead, err)
+	}
+	head.Flags &^= headInstructionsDependOnSize | headInstructionsAlterAdvances
+
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", TagMaxp, err)
+	}
+	if maxp.HasV1Fields() {
+		maxp.MaxZones = 1
+		maxp.MaxTwilightPoints = 0
+		maxp.MaxStorage = 0
+		maxp.MaxFunctionDefs = 0
+		maxp.MaxInstructionDefs = 0
+		maxp.MaxStackElements = 0
+		maxp.MaxSizeOfInstructions = 0
+	}
+	return nil
+}
+
+// dehintGlyf removes the instructions of the glyphs.
+func (font *Font) dehintGlyf() error {
+	glyf, err := font.GlyfTable()
+	if err != nil {
+		return err
+	}
+	glyphs := make([][]byte, glyf.NumGlyphs())
+	for id := range glyphs {
+		g, err := glyf.Glyph(GlyphID(id))
+		if err != nil {
+			return err
+		}
+		if len(g.Instructions) == 0 {
+			glyphs[id], _ = glyf.GlyphBytes(GlyphID(id))
+			continue
+		}
+		g.Instructions = nil
+		glyphs[id] = g.Bytes()
+	}
+
+	newGlyf, loca := NewTableGlyf(glyphs)
+	head, err := font.HeadTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", TagHead, err)
+	}
+	head.IndexToLocFormat = 1
+	if loca.Short {
+		head.IndexToLocFormat = 0
+	}
+	font.AddTable(TagGlyf, newGlyf)
+	font.AddTable(TagLoca, loca)
+	return nil
+}
+
+// cffHintOps are the operators of the Private DICT that are only used by hinting.
+var cffHintOps = []int{
+	cffOpBlueValues, cffOpOtherBlues, cffOpFamilyBlues, cffOpFamilyOtherBlues,
+	cffOpStdHW, cffOpStdVW, cffOpBlueScale, cffOpBlueShift, cffOpBlueFuzz,
+	cffOpStemSnapH, cffOpStemSnapV, cffOpForceBold, cffOpLanguageGroup,
+	cffOpExpansionFactor,
+}
+
+// dehintCFF removes the hints from the charstrings and private dicts.
+func (font *Font) dehintCFF() error {
+	cff, err := font.CFFTable()
+	if err != nil {
+		return err
+	}
+	if err := dehintCFFTable(cff); err != nil {
+		return err
+	}
+	font.AddTable(TagCFF, cff)
+	return nil
+}
+
+func dehintCFFTable(cff *TableCFF) error {
+	d := &cffDehinter{cff: cff, subrs: make(map[cffSubrKey]*cffSubr)}
+	charStrings := make([][]byte, len(cff.CharStrings))
+
+	// The first pass finds the subroutines that can be dehinted on their
+	// own, and the second writes the charstrings and subroutines.
+	for pass := 0; pass < 2; pass++ {
+		if pass == 1 {
+			d.renumber()
+		}
+		for id, cs := range cff.CharStrings {
+			fd := 0
+			if id < len(cff.FDSelect) {
+				fd = int(cff.FDSelect[id])
+			}
+			s := &charStringStripper{d: d, fd: fd}
+			if err := s.run(cs, 0); err != nil {
+				return fmt.Errorf("glyph %d: %w", id, err)
+			}
+			if !s.endOfGlyph {
+				return fmt.Errorf("glyph %d: missing endchar", id)
+			}
+			charStrings[id] = s.out
+		}
+	}
+
+	cff.CharStrings = charStrings
+	cff.GlobalSubrs = d.keptSubrs(-1)
+	for i := range cff.FontDicts {
+		fd := &cff.FontDicts[i]
+		fd.Subrs = d.keptSubrs(i)
+		fd.private = fd.private.remove(cffHintOps...)
+	}
+	return nil
+}
+
+// maxCharStringDepth is the limit on nested subroutine calls in Type 2
+// charstrings, which applies when no lower limit is set in Options.
+const maxCharStringDepth = 10
+
+var (
+	errCharStringDepth = errors.New("subroutine calls are nested too deeply")
+	// errSubrInline is returned when a subroutine can't be dehinted on its
+	// own, because it declares stems or contains the first stack-clearing
+	// operator, which may use operands from its caller.
+	errSubrInline = errors.New("subroutine must be inlined")
+)
+
+// Operators of Type 2 charstrings. Two-byte operators are 1200 plus their
+// second byte.
+const (
+	csHstem      = 1
+	csVstem      = 3
+	csVmoveto    = 4
+	csCallsubr   = 10
+	csReturn     = 11
+	csEscape     = 12
+	csEndchar    = 14
+	csHstemhm    = 18
+	csHintmask   = 19
+	csCntrmask   = 20
+	csRmoveto    = 21
+	csHmoveto    = 22
+	csVstemhm    = 23
+	csCallgsubr  = 29
+	csDotsection = 1200
+)
+
+// cffSubrKey identifies a subroutine by the index of the font dict containing
+// it, or -1 for global subroutines, and its index.
+type cffSubrKey struct {
+	fd, index int
+}
+
+// cffSubr records how a subroutine is dehinted.
+type cffSubr struct {
+	// maskSize is the size of the hint masks in the subroutine, which is
+	// set by the number of stems of its callers, and seenClear is set if it
+	// is called after the first stack-clearing operator.
+	maskSize  int
+	seenClear bool
+	// inline is set if the subroutine is inlined in every charstring,
+	// because it can't be dehinted on its own or its callers differ.
+	inline bool
+
+	// Properties of the dehinted subroutine, which are those of the
+	// charStringStripper that wrote it.
+	hasMask, clears, usesOperands, endchar bool
+
+	newIndex int
+
+	written bool
+	out     []byte // out is the dehinted subroutine, once it is written.
+}
+
+// cffDehinter holds the subroutines of a CFF table while its hints are removed.
+type cffDehinter struct {
+	cff   *TableCFF
+	subrs map[cffSubrKey]*cffSubr
+	// numKept contains the number of subroutines kept in each font dict,
+	// and at -1 the number of global subroutines kept.
+	numKept map[int]int
+}
+
+// renumber numbers the subroutines that are kept, in their original order.
+func (d *cffDehinter) renumber() {
+	var keys []cffSubrKey
+	for key, subr := range d.subrs {
+		if !subr.inline {
+			keys = append(keys, key)
+		}
+		subr.written, subr.out = false, nil
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].fd != keys[j].fd {
+			return keys[i].fd < keys[j].fd
+		}
+		return keys[i].index < keys[j].index
+	})
+	d.numKept = make(map[int]int)
+	for _, key := range keys {
+		d.subrs[key].newIndex = d.numKept[key.fd]
+		d.numKept[key.fd]++
+	}
+}
+
+func (d *cffDehinter) keptSubrs(fd int) [][]byte {
+	if d.numKept[fd] == 0 {
+		return nil
+	}
+	subrs := make([][]byte, d.numKept[fd])
+	for key, subr := range d.subrs {
+		if key.fd == fd && !subr.inline {
+			subrs[subr.newIndex] = subr.out
+		}
+	}
+	return subrs
+}
+
+// charStringStripper writes a charstring or subroutine without its hints.
+type charStringStripper struct {
+	d  *cffDehinter
+	fd int // fd is the index of the font dict containing the local subroutines.
+
+	out      []byte
+	operands [][]byte // operands contains the encoding of each operand on the stack.
+	numStems int
+	// width is the encoded advance width, which is the first operand of
+	// the first stack-clearing operator, until it is written.
+	width     []byte
+	seenClear bool // seenClear is set once a stack-clearing operator has been run.
+	// subr is set when a subroutine is dehinted on its own, so that it
+	// can't declare stems, and top is its depth of calls.
+	subr       bool
+	top        int
+	endOfGlyph bool
+
+	wrote   bool // wrote is set once an operator has been written.
+	hasMask bool // hasMask is set if a hint mask has been removed.
+	clears  bool // clears is set if a stack-clearing operator has been run.
+	// usesOperands is set if a hint mask was removed before any operator
+	// was written, so it may take operands pushed by the caller.
+	usesOperands bool
+}
+
+func (s *charStringStripper) run(cs []byte, depth int) error {
+	if err := s.d.cff.checkCallDepth(depth); err != nil {
+		return err
+	}
+	for len(cs) > 0 && !s.endOfGlyph {
+		op, size, err := readCharStringToken(cs)
+		if err != nil {
+			return err
+		}
+		code := cs[:size]
+		cs = cs[size:]
+		if op < 0 {
+			s.operands = append(s.operands, code)
+			continue
+		}
+
+		switch op {
+		case csHstem, csVstem, csHstemhm, csVstemhm:
+			if s.subr {
+				return errSubrInline
+			}
+			if err := s.clear(len(s.operands)%2 == 1); err != nil {
+				return err
+			}
+			s.numStems += len(s.operands) / 2
+			s.operands = s.operands[:0]
+		case csHintmask, csCntrmask:
+			// Operands before a mask are the arguments of an implied vstemhm.
+			if s.subr && len(s.operands) > 0 {
+				return errSubrInline
+			}
+			s.hasMask = true
+			s.usesOperands = s.usesOperands || !s.wrote
+			if err := s.clear(len(s.operands)%2 == 1); err != nil {
+				return err
+			}
+			s.numStems += len(s.operands) / 2
+			s.operands = s.operands[:0]
+			n := (s.numStems + 7) / 8
+			if len(cs) < n {
+				return io.ErrUnexpectedEOF
+			}
+			cs = cs[n:]
+		case csDotsection:
+			s.operands = s.operands[:0]
+		case csRmoveto, csHmoveto, csVmoveto, csEndchar:
+			if err := s.clear(hasWidth(op, len(s.operands))); err != nil {
+				return err
+			}
+			s.write(code)
+			s.endOfGlyph = op == csEndchar
+		case csCallsubr, csCallgsubr:
+			if err := s.call(op, depth); err != nil {
+				return err
+			}
+		case csReturn:
+			if s.subr && depth == s.top {
+				// Operands left for the caller may be the arguments of a
+				// hint, which would be removed from the caller.
+				if len(s.operands) > 0 {
+					return errSubrInline
+				}
+				s.write(code)
+			}
+			return nil
+		default:
+			if isCharStringArithmetic(op) {
+				return fmt.Errorf("unsupported arithmetic operator 12 %d", op-1200)
+			}
+			s.write(code)
+		}
+	}
+	return nil
+}
+
+// call runs the callsubr or callgsubr operator. The subroutine is kept if
+// it can be dehinted on its own, and is otherwise inlined.
+func (s *charStringStripper) call(op int, depth int) error {
+	key := cffSubrKey{fd: -1}
+	subrs := s.d.cff.GlobalSubrs
+	if op == csCallsubr {
+		key.fd, subrs = s.fd, nil
+		if s.fd < len(s.d.cff.FontDicts) {
+			subrs = s.d.cff.FontDicts[s.fd].Subrs
+		}
+	}
+	if len(s.operands) == 0 {
+		return errors.New("subroutine call without an index")
+	}
+	key.index = int(charStringInt(s.operands[len(s.operands)-1])) + CFFSubrBias(len(subrs))
+	s.operands = s.operands[:len(s.operands)-1]
+	if key.index < 0 || key.index >= len(subrs) {
+		return fmt.Errorf("subroutine %d out of range", key.index)
+	}
+	body := subrs[key.index]
+
+	maskSize := (s.numStems + 7) / 8
+	subr, found := s.d.subrs[key]
+	if !found {
+		subr = &cffSubr{maskSize: maskSize, seenClear: s.seenClear}
+		s.d.subrs[key] = subr
+	}
+	if !subr.inline && !subr.written {
+		t := &charStringStripper{d: s.d, fd: s.fd, numStems: s.numStems, seenClear: s.seenClear, subr: true, top: depth + 1}
+		switch err := t.run(body, depth+1); {
+		case errors.Is(err, errSubrInline):
+			subr.inline = true
+		case err != nil:
+			return err
+		default:
+			subr.written, subr.out = true, t.out
+			subr.hasMask, subr.clears, subr.usesOperands, subr.endchar = t.hasMask, t.clears, t.usesOperands, t.endOfGlyph
+		}
+	}
+	if (subr.hasMask && subr.maskSize != maskSize) || (subr.clears && subr.seenClear != s.seenClear) {
+		subr.inline = true
+	}
+	if subr.inline || (subr.usesOperands && len(s.operands) > 0) {
+		return s.run(body, depth+1)
+	}
+
+	s.hasMask = s.hasMask || subr.hasMask
+	s.clears = s.clears || subr.clears
+	s.usesOperands = s.usesOperands || (subr.usesOperands && !s.wrote)
+	s.operands = append(s.operands, appendCharStringInt(nil, int32(subr.newIndex-CFFSubrBias(s.d.numKept[key.fd]))))
+	s.write([]byte{byte(op)})
+	s.endOfGlyph = subr.endchar
+	return nil
+}
+
+// clear handles the first stack-clearing operator, which has the advance
+// width as an extra first operand if hasWidth is true. The width is kept
+// for the next operator that is written.
+func (s *charStringStripper) clear(hasWidth bool) error {
+	s.clears = true
+	if s.seenClear {
+		return nil
+	}
+	if s.subr {
+		return errSubrInline
+	}
+	s.seenClear = true
+	if hasWidth {
+		s.width = s.operands[0]
+		s.operands = s.operands[1:]
+	}
+	return nil
+}
+
+// hasWidth returns true if a moveto or endchar operator with n operands
+// has the advance width as an extra operand.
+func hasWidth(op int, n int) bool {
+	switch op {
+	case csRmoveto:
+		return n > 2
+	case csHmoveto, csVmoveto:
+		return n > 1
+	}
+	return n == 1 || n == 5
+}
+
+// write writes the operands on the stack, and the width if it hasn't been
+// written, followed by the encoded operator.
+func (s *charStringStripper) write(code []byte) {
+	if s.width != nil {
+		s.out = append(s.out, s.width...)
+		s.width = nil
+	}
+	for _, b := range s.operands {
+		s.out = append(s.out, b...)
+	}
+	s.out = append(s.out, code...)
+	s.operands = s.operands[:0]
+	s.wrote = true
+}
+
+// readCharStringToken returns the operator at the start of a Type 2
+// charstring, or -1 if it starts with an operand, and the size of the
+// operator or operand.
+func readCharStringToken(cs []byte) (op, size int, err error) {
+	b0 := cs[0]
+	op, size = int(b0), 1
+	switch {
+	case b0 == 28:
+		op, size = -1, 3
+	case b0 >= 32 && b0 <= 246:
+		op = -1
+	case b0 >= 247 && b0 <= 254:
+		op, size = -1, 2
+	case b0 == 255:
+		op, size = -1, 5
+	case b0 == csEscape:
+		if len(cs) < 2 {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		op, size = 1200+int(cs[1]), 2
+	}
+	if len(cs) < size {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	return op, size, nil
+}
+
+// isCharStringArithmetic returns true for the arithmetic and storage
+// operators of Type 2 charstrings, whose results can't be followed without
+// running the charstring.
+func isCharStringArithmetic(op int) bool {
+	switch op {
+	case 1203, 1204, 1205, 1209, 1210, 1211, 1212, 1214, 1215, 1218, 1220, 1221,
+		1222, 1223, 1224, 1226, 1227, 1228, 1229, 1230:
+		return true
+	}
+	return false
+}
+
+// charStringInt returns the integer part of an operand of a Type 2 charstring.
+func charStringInt(b []byte) int32 {
+	if b[0] == 255 {
+		return int32(uint32(b[1])<<24|uint32(b[2])<<16|uint32(b[3])<<8|uint32(b[4])) >> 16
+	}
+	return cffDictInt(b)
+}
+
+// appendCharStringInt appends the shortest encoding of v, which must fit in
+// 16 bits, to buf.
+func appendCharStringInt(buf []byte, v int32) []byte {
+	switch {
+	case v >= -107 && v <= 107:
+		return append(buf, byte(v+139))
+	case v >= 108 && v <= 1131:
+		v -= 108
+		return append(buf, byte(v>>8+247), byte(v))
+	case v >= -1131 && v <= -108:
+		v = -v - 108
+		return append(buf, byte(v>>8+251), byte(v))
+	}
+	return append(buf, 28, byte(v>>8), byte(v))
+}
@@ -0,0 +1,222 @@
+package sfnt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDehintCharStrings(t *testing.T) {
+	subrs := [][]byte{
+		{149, 159, csHstem, csReturn},        // 10 20 hstem
+		{139, 139, csRmoveto, 149, 7, 11},    // 0 0 rmoveto 10 vlineto
+		{csHintmask, 0xC0, 149, 7, csReturn}, // hintmask 10 vlineto
+		{149, 159, csReturn},                 // 10 20
+	}
+	for _, test := range []struct {
+		name      string
+		cs        [][]byte
+		want      [][]byte
+		wantSubrs [][]byte
+	}{
+		{
+			"hints and width",
+			[][]byte{{239, 149, 159, csHstem, 139, 139, csRmoveto, csHintmask, 0x80, 149, 7, csEndchar}},
+			[][]byte{{239, 139, 139, csRmoveto, 149, 7, csEndchar}},
+			nil,
+		},
+		{
+			"implied vstem before hintmask",
+			[][]byte{{149, 159, csHstemhm, 149, 159, csHintmask, 0xC0, 139, csHmoveto, csEndchar}},
+			[][]byte{{139, csHmoveto, csEndchar}},
+			nil,
+		},
+		{
+			"width before a hint in a subroutine",
+			[][]byte{{239, 32, csCallsubr, 139, 139, csRmoveto, csEndchar}},
+			[][]byte{{239, 139, 139, csRmoveto, csEndchar}},
+			nil,
+		},
+		{
+			"subroutines before and after moveto",
+			[][]byte{{149, 159, csHstem, 149, 159, csVstem, 33, csCallsubr, 34, csCallsubr, csEndchar}},
+			[][]byte{{32, csCallsubr, 33, csCallsubr, csEndchar}},
+			[][]byte{{139, 139, csRmoveto, 149, 7, csReturn}, {149, 7, csReturn}},
+		},
+		{
+			"implied vstem in a subroutine",
+			[][]byte{
+				{149, 159, csHstemhm, 139, 139, csRmoveto, 34, csCallsubr, csEndchar},
+				{149, 159, csHstemhm, 149, 159, 34, csCallsubr, 139, csHmoveto, csEndchar},
+			},
+			[][]byte{
+				{139, 139, csRmoveto, 32, csCallsubr, csEndchar},
+				{149, 7, 139, csHmoveto, csEndchar},
+			},
+			[][]byte{{149, 7, csReturn}},
+		},
+		{
+			"subroutine with different mask sizes",
+			[][]byte{
+				{149, 159, csHstem, 149, 159, csVstem, 139, 139, csRmoveto, 34, csCallsubr, csEndchar},
+				{139, 139, csRmoveto, 34, csCallsubr, csEndchar},
+			},
+			[][]byte{
+				{139, 139, csRmoveto, 149, 7, csEndchar},
+				{139, 139, csRmoveto, 0xC0, 149, 7, csEndchar},
+			},
+			nil,
+		},
+		{
+			"stems in a subroutine after moveto",
+			[][]byte{{139, csHmoveto, 32, csCallsubr, 139, csVmoveto, csEndchar}},
+			[][]byte{{139, csHmoveto, 139, csVmoveto, csEndchar}},
+			nil,
+		},
+		{
+			"hint arguments in a subroutine",
+			[][]byte{{35, csCallsubr, csHstem, 139, csHmoveto, csEndchar}},
+			[][]byte{{139, csHmoveto, csEndchar}},
+			nil,
+		},
+		{
+			"width with endchar",
+			[][]byte{{239, csEndchar}},
+			[][]byte{{239, csEndchar}},
+			nil,
+		},
+	} {
+		cff := &TableCFF{CharStrings: test.cs, FontDicts: []CFFFontDict{{Subrs: subrs}}}
+		if err := dehintCFFTable(cff); err != nil {
+			t.Errorf("%s: dehintCFFTable() err = %q, want nil", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cff.CharStrings, test.want) {
+			t.Errorf("%s: dehintCFFTable() charstrings = %v, want %v", test.name, cff.CharStrings, test.want)
+		}
+		if !reflect.DeepEqual(cff.FontDicts[0].Subrs, test.wantSubrs) {
+			t.Errorf("%s: dehintCFFTable() subrs = %v, want %v", test.name, cff.FontDicts[0].Subrs, test.wantSubrs)
+		}
+	}
+
+	for _, cs := range [][]byte{
+		{139, 139, csRmoveto},
+		{100, csCallsubr, csEndchar},
+		{139, csCallgsubr, csEndchar},
+		{149, 159, csHstem, csHintmask},
+		{139, 139, csEscape, 10, csEndchar},
+	} {
+		cff := &TableCFF{CharStrings: [][]byte{cs}, FontDicts: []CFFFontDict{{Subrs: subrs}}}
+		if err := dehintCFFTable(cff); err == nil {
+			t.Errorf("dehintCFFTable(%v) err = nil, want an error", cs)
+		}
+	}
+}
+
+func TestDehintCFF(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", "Raleway-v4020-Regular.otf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := font.Dehint(); err != nil {
+		t.Fatalf("Dehint() err = %q, want nil", err)
+	}
+
+	cff, err := font.CFFTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cff.FontDicts[0].private.has(cffOpBlueValues) || cff.FontDicts[0].private.has(cffOpStdVW) {
+		t.Errorf("Dehint() kept the hints of the private dict")
+	}
+	if len(cff.GlobalSubrs) == 0 || len(cff.Subrs(0)) == 0 {
+		t.Errorf("Dehint() removed the subroutines")
+	}
+	checkCFFRoundTrip(t, cff)
+
+	// Walking the dehinted charstrings finds no stems or hint masks.
+	d := &cffDehinter{cff: cff, subrs: make(map[cffSubrKey]*cffSubr)}
+	for id, cs := range cff.CharStrings {
+		s := &charStringStripper{d: d}
+		if err := s.run(cs, 0); err != nil {
+			t.Fatalf("glyph %d: %q", id, err)
+		}
+		if s.numStems != 0 || s.hasMask {
+			t.Errorf("glyph %d: charstring has hints", id)
+		}
+	}
+	for key, subr := range d.subrs {
+		if subr.hasMask {
+			t.Errorf("subroutine %v has hints", key)
+		}
+	}
+}
+
+func TestDehintGlyf(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", "open-sans-v15-latin-regular.woff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hinted, err := Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := font.Dehint(); err != nil {
+		t.Fatalf("Dehint() err = %q, want nil", err)
+	}
+
+	for _, tag := range hintingTables {
+		if font.HasTable(tag) {
+			t.Errorf("Dehint() kept the %q table", tag)
+		}
+	}
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxp.MaxFunctionDefs != 0 || maxp.MaxStackElements != 0 || maxp.MaxSizeOfInstructions != 0 {
+		t.Errorf("Dehint() left maxp = %+v", maxp.tableMaxpV1Fields)
+	}
+	head, err := font.HeadTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.Flags&(headInstructionsDependOnSize|headInstructionsAlterAdvances) != 0 {
+		t.Errorf("Dehint() left head.Flags = %#x", head.Flags)
+	}
+
+	glyf, err := font.GlyfTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := GlyphID(0); int(id) < glyf.NumGlyphs(); id++ {
+		glyph, err := glyf.Glyph(id)
+		if err != nil {
+			t.Fatalf("Glyph(%d) err = %q, want nil", id, err)
+		}
+		if len(glyph.Instructions) > 0 {
+			t.Errorf("glyph %d has instructions", id)
+		}
+		got, err := font.GlyphOutline(id, nil)
+		if err != nil {
+			t.Fatalf("GlyphOutline(%d) err = %q, want nil", id, err)
+		}
+		want, err := hinted.GlyphOutline(id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GlyphOutline(%d) = %+v, want %+v", id, got, want)
+		}
+	}
+}
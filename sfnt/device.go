@@ -0,0 +1,67 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Device adjusts a value in a layout table. It either contains hinting
+// adjustments in pixels for a range of sizes, or refers to the deltas of a
+// variable font in an ItemVariationStore.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/chapter2#device-and-variationindex-tables
+type Device struct {
+	// StartSize and EndSize are the range of sizes in pixels per em with Deltas.
+	StartSize, EndSize uint16
+	// Deltas contains the adjustment in pixels at each size from StartSize to EndSize.
+	Deltas []int8
+	// Variation is true if the device contains VarIndex instead of Deltas.
+	Variation bool
+	VarIndex  VariationIndex
+}
+
+const deviceVariationIndex = 0x8000
+
+func parseDevice(buf []byte) (*Device, error) {
+	if len(buf) < 6 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	d := &Device{
+		StartSize: binary.BigEndian.Uint16(buf),
+		EndSize:   binary.BigEndian.Uint16(buf[2:]),
+	}
+	format := binary.BigEndian.Uint16(buf[4:])
+	if format == deviceVariationIndex {
+		d.Variation = true
+		d.VarIndex = VariationIndex{Outer: d.StartSize, Inner: d.EndSize}
+		d.StartSize, d.EndSize = 0, 0
+		return d, nil
+	}
+	// Formats 1 to 3 pack signed deltas of 2, 4 or 8 bits into each uint16,
+	// other formats have no deltas.
+	if format < 1 || format > 3 || d.EndSize < d.StartSize {
+		return d, nil
+	}
+
+	size := uint(1) << format
+	count := int(d.EndSize-d.StartSize) + 1
+	perWord := 16 / int(size)
+	if 6+2*((count+perWord-1)/perWord) > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	d.Deltas = make([]int8, count)
+	for i := range d.Deltas {
+		word := binary.BigEndian.Uint16(buf[6+2*(i/perWord):])
+		shift := 16 - size*uint(i%perWord+1)
+		// Shift the delta to the top of an int16 to sign extend it.
+		d.Deltas[i] = int8(int16(word<<(16-size-shift)) >> (16 - size))
+	}
+	return d, nil
+}
+
+// PixelDelta returns the adjustment in pixels at a size in pixels per em.
+func (d *Device) PixelDelta(ppem int) int {
+	if ppem < int(d.StartSize) || ppem-int(d.StartSize) >= len(d.Deltas) {
+		return 0
+	}
+	return int(d.Deltas[ppem-int(d.StartSize)])
+}
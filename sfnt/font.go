@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"strings"
 )
@@ -13,6 +14,16 @@ type fixed struct {
 	Minor uint16
 }
 
+// fixedToFloat converts a 16.16 fixed-point number to a float.
+func fixedToFloat(v uint32) float64 {
+	return float64(int32(v)) / (1 << 16)
+}
+
+// floatToFixed converts a float to the nearest 16.16 fixed-point number.
+func floatToFixed(f float64) uint32 {
+	return uint32(int32(math.Round(f * (1 << 16))))
+}
+
 type longdatetime struct {
 	SecondsSince1904 uint64
 }
@@ -24,7 +35,7 @@ func (u *unparsedTable) Bytes() []byte {
 // ErrMissingHead is returned by ParseOTF when the font has no head section.
 var ErrMissingHead = errors.New("missing head table in font")
 
-// ErrInvalidChecksum is returned by ParseOTF if the font's checksum is wrong
+// ErrInvalidChecksum is wrapped by the *ChecksumError returned from VerifyChecksums if the font's checksums are wrong
 var ErrInvalidChecksum = errors.New("invalid checksum")
 
 // ErrUnsupportedFormat is returned from Parse if parsing failed
@@ -42,9 +53,15 @@ var ErrMissingTable = errors.New("missing table")
 type Font struct {
 	file       File
 	collection File
+	resource   *Resource
+	options    *Options
 
 	scalerType Tag
 	tables     map[Tag]*tableSection
+
+	// header is the sfnt header the tables were read with, used to verify
+	// the font checksum. It is nil when no checksums are available.
+	header *otfHeader
 }
 
 // tableSection represents a table within the font file.
@@ -52,9 +69,10 @@ type tableSection struct {
 	tag   Tag
 	table Table
 
-	offset  uint32 // Offset into the file this table starts.
-	length  uint32 // Length of this table within the file.
-	zLength uint32 // Uncompressed length of this table.
+	offset   uint32 // Offset into the file this table starts.
+	length   uint32 // Length of this table within the file.
+	zLength  uint32 // Uncompressed length of this table.
+	checksum uint32 // Checksum of the uncompressed table recorded in the file.
 }
 
 // Tags is the list of tags that are defined in this font, sorted by numeric value.
@@ -133,6 +151,15 @@ func (font *Font) NameTable() (*TableName, error) {
 	return t.(*TableName), nil
 }
 
+// MaxpTable returns the table corresponding to the 'maxp' tag.
+func (font *Font) MaxpTable() (*TableMaxp, error) {
+	t, err := font.Table(TagMaxp)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableMaxp), nil
+}
+
 func (font *Font) HheaTable() (*TableHhea, error) {
 	t, err := font.Table(TagHhea)
 	if err != nil {
@@ -207,7 +234,7 @@ type File interface {
 }
 
 // IsCollection reports whether the file is a font collection,
-// such as TrueType Collection (.ttc, .otc) files.
+// such as TrueType Collection (.ttc, .otc) or resource fork (.dfont) files.
 func IsCollection(file File) (bool, error) {
 	magic, err := ReadTag(file)
 	if err != nil {
@@ -216,18 +243,19 @@ func IsCollection(file File) (bool, error) {
 
 	file.Seek(0, io.SeekStart)
 
-	result := magic == TypeTrueTypeCollection
+	result := magic == TypeTrueTypeCollection || isResourceFork(file)
 
 	return result, nil
 }
 
 // Parse parses an OpenType, TrueType, WOFF, or WOFF2 file and returns a Font.
+// If the file is a resource fork (.dfont), the first 'sfnt' resource is returned.
 // If parsing fails, an error is returned and *[Font] will be nil.
 func Parse(file File) (*Font, error) {
-	return parse(file, nil)
+	return parse(file, nil, nil)
 }
 
-func parse(file, collection File) (*Font, error) {
+func parse(file, collection File, opts *Options) (*Font, error) {
 	magic, err := ReadTag(file)
 	if err != nil {
 		return nil, err
@@ -237,12 +265,19 @@ func parse(file, collection File) (*Font, error) {
 
 	switch magic {
 	case SignatureWOFF:
-		return parseWOFF(file)
+		return parseWOFF(file, opts)
 	case SignatureWOFF2:
-		return parseWOFF2(file)
+		return parseWOFF2(file, opts)
 	case TypeTrueType, TypeOpenType, TypePostScript1, TypeAppleTrueType:
-		return parseOTF(file, collection)
+		return parseOTF(file, collection, opts)
 	default:
+		if collection == nil && isResourceFork(file) {
+			fonts, err := parseResourceFork(file, opts)
+			if err != nil {
+				return nil, err
+			}
+			return fonts[0], nil
+		}
 		return nil, ErrUnsupportedFormat
 	}
 }
@@ -28,7 +28,7 @@ var (
 		"VORG": "Vertical Origin (optional table)",
 
 		// Table related to SVG outlines
-		"SVG": "The SVG (Scalable Vector Graphics) table",
+		"SVG ": "The SVG (Scalable Vector Graphics) table",
 
 		// Tables Related to Bitmap Glyphs
 		"EBDT": "Embedded bitmap data",
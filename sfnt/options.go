@@ -0,0 +1,148 @@
+package sfnt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrLimitExceeded is wrapped by the *LimitError returned when a font exceeds
+// one of the limits in Options.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// LimitError is returned when parsing a font would exceed one of the limits in Options.
+type LimitError struct {
+	Limit string // Limit is the name of the Options field that was exceeded.
+	Max   int64  // Max is the configured limit.
+	Value int64  // Value is the value found in the font.
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s is %d, maximum is %d", ErrLimitExceeded, e.Limit, e.Value, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// Options configures how fonts are parsed. The zero value imposes no limits,
+// which is how Parse behaves.
+//
+// When parsing untrusted fonts the limits should be set, as otherwise the
+// sizes and counts stored in the font are trusted and used to allocate memory.
+type Options struct {
+	// MaxTotalSize limits the sum of the uncompressed sizes of all tables in a font.
+	MaxTotalSize int64
+
+	// MaxTableSize limits the uncompressed size of each table.
+	MaxTableSize int64
+
+	// MaxTables limits the number of tables in a font.
+	MaxTables int
+
+	// MaxCollectionFonts limits the number of fonts in a TrueType Collection
+	// or resource fork file.
+	MaxCollectionFonts int
+
+	// MaxNestingDepth limits how deeply nested structures are followed when
+	// tables are parsed: extension subtables in GPOS and GSUB, composite
+	// glyphs, COLR paints and CFF subroutine calls. Extension subtables are
+	// only checked when it is set.
+	MaxNestingDepth int
+
+	// VerifyChecksums reports an error when the font's checksums are wrong.
+	// See Font.VerifyChecksums.
+	VerifyChecksums bool
+}
+
+// checkLimit returns a *LimitError if max is set and value exceeds it.
+func checkLimit(limit string, max, value int64) error {
+	if max > 0 && value > max {
+		return &LimitError{Limit: limit, Max: max, Value: value}
+	}
+	return nil
+}
+
+func (opts *Options) checkTables(numTables int) error {
+	if opts == nil {
+		return nil
+	}
+	return checkLimit("MaxTables", int64(opts.MaxTables), int64(numTables))
+}
+
+func (opts *Options) checkTableSize(size int64) error {
+	if opts == nil {
+		return nil
+	}
+	return checkLimit("MaxTableSize", opts.MaxTableSize, size)
+}
+
+func (opts *Options) checkTotalSize(size int64) error {
+	if opts == nil {
+		return nil
+	}
+	return checkLimit("MaxTotalSize", opts.MaxTotalSize, size)
+}
+
+func (opts *Options) checkCollectionFonts(numFonts int64) error {
+	if opts == nil {
+		return nil
+	}
+	return checkLimit("MaxCollectionFonts", int64(opts.MaxCollectionFonts), numFonts)
+}
+
+func (opts *Options) checkNestingDepth(depth int) error {
+	if opts == nil {
+		return nil
+	}
+	return checkLimit("MaxNestingDepth", int64(opts.MaxNestingDepth), int64(depth))
+}
+
+// checkSections checks the limits against the table directory of a font,
+// before any of the tables are read.
+func (opts *Options) checkSections(font *Font) error {
+	if opts == nil {
+		return nil
+	}
+
+	var total int64
+	for _, s := range font.tables {
+		size := int64(s.length)
+		if int64(s.zLength) > size {
+			size = int64(s.zLength)
+		}
+		if err := opts.checkTableSize(size); err != nil {
+			return fmt.Errorf("table %q: %w", s.tag, err)
+		}
+		total += size
+	}
+
+	return opts.checkTotalSize(total)
+}
+
+// verify runs the checks that need the table data once a font has been parsed.
+func (opts *Options) verify(font *Font) error {
+	if opts == nil || !opts.VerifyChecksums {
+		return nil
+	}
+	return font.VerifyChecksums()
+}
+
+// ParseWithOptions parses an OpenType, TrueType, WOFF, or WOFF2 file like Parse,
+// but returns an error if the font exceeds any of the limits set in opts.
+// The limits are also applied when the tables of the font are parsed.
+func ParseWithOptions(file File, opts Options) (*Font, error) {
+	return parse(file, nil, &opts)
+}
+
+// ParseCollectionWithOptions parses a collection like ParseCollection, but returns
+// an error if the collection or any of its fonts exceed the limits set in opts.
+func ParseCollectionWithOptions(file File, opts Options) ([]*Font, error) {
+	return parseCollection(file, &opts)
+}
+
+// ParseCollectionIndexWithOptions parses a single font from a collection like
+// ParseCollectionIndex, but returns an error if the collection or the font
+// exceed the limits set in opts.
+func ParseCollectionIndexWithOptions(file File, index uint32, opts Options) (*Font, error) {
+	return parseCollectionIndex(file, index, &opts)
+}
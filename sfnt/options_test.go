@@ -0,0 +1,179 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseWithOptionsLimits(t *testing.T) {
+	tests := []struct {
+		filename string
+		opts     Options
+		limit    string // the limit that is exceeded, or "" if parsing succeeds.
+	}{
+		{filename: "Roboto-BoldItalic.ttf", opts: Options{MaxTables: 13, MaxTableSize: 214830, MaxTotalSize: 1 << 20}},
+		{filename: "Roboto-BoldItalic.ttf", opts: Options{MaxTables: 12}, limit: "MaxTables"},
+		{filename: "Roboto-BoldItalic.ttf", opts: Options{MaxTableSize: 100000}, limit: "MaxTableSize"},
+		{filename: "Roboto-BoldItalic.ttf", opts: Options{MaxTotalSize: 300000}, limit: "MaxTotalSize"},
+		{filename: "open-sans-v15-latin-regular.woff", opts: Options{MaxTableSize: 20000}, limit: "MaxTableSize"},
+		{filename: "Go-Regular.woff2", opts: Options{MaxTotalSize: 100000}, limit: "MaxTotalSize"},
+		{filename: "Go-Regular.woff2", opts: Options{MaxTables: 5}, limit: "MaxTables"},
+		{filename: "Raleway-v4020-Regular.otf", opts: Options{VerifyChecksums: true}},
+	}
+
+	for _, test := range tests {
+		buf, err := os.ReadFile(filepath.Join("testdata", test.filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = ParseWithOptions(bytes.NewReader(buf), test.opts)
+		if test.limit == "" {
+			if err != nil {
+				t.Errorf("ParseWithOptions(%q, %+v) err = %q, want nil", test.filename, test.opts, err)
+			}
+			continue
+		}
+
+		var limitErr *LimitError
+		if !errors.As(err, &limitErr) || !errors.Is(err, ErrLimitExceeded) {
+			t.Errorf("ParseWithOptions(%q, %+v) err = %v, want *LimitError", test.filename, test.opts, err)
+			continue
+		}
+		if limitErr.Limit != test.limit {
+			t.Errorf("ParseWithOptions(%q, %+v) exceeded %s, want %s", test.filename, test.opts, limitErr.Limit, test.limit)
+		}
+	}
+}
+
+func TestParseWOFF2DecompressionLimit(t *testing.T) {
+	// A WOFF2 file whose directory declares a 'head' table of 54 bytes, and
+	// whose Brotli stream expands to size bytes. The stream is stored rather
+	// than compressed, but a compressed one could expand much further.
+	build := func(size int) []byte {
+		compressed := brotliStore(make([]byte, size))
+		var buf bytes.Buffer
+		binary.Write(&buf, binary.BigEndian, woff2Header{
+			Signature:           SignatureWOFF2,
+			Flavor:              TypeTrueType,
+			NumTables:           1,
+			TotalSfntSize:       12 + 16 + 56,
+			TotalCompressedSize: uint32(len(compressed)),
+		})
+		buf.Write(appendUIntBase128([]byte{1}, 54))
+		buf.Write(compressed)
+		return buf.Bytes()
+	}
+	opts := Options{MaxTotalSize: 1 << 16}
+
+	if _, err := ParseWithOptions(bytes.NewReader(build(54)), opts); err != nil {
+		t.Errorf("ParseWithOptions(%+v) err = %q, want nil", opts, err)
+	}
+
+	var limitErr *LimitError
+	_, err := ParseWithOptions(bytes.NewReader(build(1<<20)), opts)
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxTotalSize" {
+		t.Errorf("ParseWithOptions(%+v) of a stream that expands to 1MB err = %v, want *LimitError for MaxTotalSize", opts, err)
+	}
+
+	// Without a limit, the data is only read up to the size in the directory.
+	if _, err := Parse(bytes.NewReader(build(1 << 20))); err == nil || errors.As(err, &limitErr) {
+		t.Errorf("Parse() of a stream that expands to 1MB err = %v, want a size error", err)
+	}
+}
+
+func TestParseCollectionWithOptionsLimits(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", "TestTTC.ttc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseCollectionWithOptions(bytes.NewReader(buf), Options{MaxCollectionFonts: 2}); err != nil {
+		t.Errorf("ParseCollectionWithOptions(MaxCollectionFonts: 2) err = %q, want nil", err)
+	}
+
+	_, err = ParseCollectionWithOptions(bytes.NewReader(buf), Options{MaxCollectionFonts: 1})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ParseCollectionWithOptions(MaxCollectionFonts: 1) err = %v, want %v", err, ErrLimitExceeded)
+	}
+
+	_, err = ParseCollectionIndexWithOptions(bytes.NewReader(buf), 0, Options{MaxCollectionFonts: 1})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ParseCollectionIndexWithOptions(MaxCollectionFonts: 1) err = %v, want %v", err, ErrLimitExceeded)
+	}
+}
+
+func TestParseWithOptionsNestingDepth(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", "Roboto-BoldItalic.ttf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Roboto uses extension lookups in GPOS, which are nested one level deep.
+	font, err := ParseWithOptions(bytes.NewReader(buf), Options{MaxNestingDepth: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := font.GposTable(); err != nil {
+		t.Errorf("GposTable() with MaxNestingDepth: 1 err = %q, want nil", err)
+	}
+
+	// A GSUB table with an extension lookup whose subtable is another extension.
+	gsub := []byte{
+		0, 1, 0, 0, // version 1.0
+		0, 10, 0, 12, 0, 14, // script, feature and lookup list offsets
+		0, 0, // script count
+		0, 0, // feature count
+		0, 1, 0, 4, // lookup count and offset
+		0, 7, 0, 0, 0, 1, 0, 8, // extension lookup with one subtable
+		0, 1, 0, 7, 0, 0, 0, 8, // extension subtable referring to an extension
+		0, 1, 0, 1, 0, 0, 0, 8, // extension subtable referring to a single substitution
+		0, 1, 0, 6, 0, 0, // single substitution subtable
+	}
+
+	if _, err := parseTableLayout(TagGsub, gsub, &Options{MaxNestingDepth: 2}); err != nil {
+		t.Errorf("parseTableLayout(MaxNestingDepth: 2) err = %q, want nil", err)
+	}
+	if _, err := parseTableLayout(TagGsub, gsub, &Options{MaxNestingDepth: 1}); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("parseTableLayout(MaxNestingDepth: 1) err = %v, want %v", err, ErrLimitExceeded)
+	}
+
+	// Extension subtables are not read without a limit, so an unknown
+	// format is only an error when the limit is set.
+	gsub[23] = 2
+	if _, err := parseTableLayout(TagGsub, gsub, nil); err != nil {
+		t.Errorf("parseTableLayout(nil) with extension format 2 err = %q, want nil", err)
+	}
+	if _, err := parseTableLayout(TagGsub, gsub, &Options{MaxNestingDepth: 2}); err == nil {
+		t.Error("parseTableLayout(MaxNestingDepth: 2) with extension format 2 err = nil, want an error")
+	}
+}
+
+func TestCFFNestingDepth(t *testing.T) {
+	subrs := [][]byte{
+		{33, csCallsubr, csReturn},      // call subroutine 1
+		{139, 139, csRmoveto, csReturn}, // 0 0 rmoveto
+	}
+	charStrings := [][]byte{{32, csCallsubr, csEndchar}}
+
+	for _, test := range []struct {
+		opts    *Options
+		wantErr error
+	}{
+		{opts: nil},
+		{opts: &Options{MaxNestingDepth: 2}},
+		{opts: &Options{MaxNestingDepth: 1}, wantErr: ErrLimitExceeded},
+	} {
+		cff := &TableCFF{CharStrings: charStrings, Charset: []uint16{0}, FontDicts: []CFFFontDict{{Subrs: subrs}}, options: test.opts}
+		if _, err := cff.Subset([]GlyphID{0}); !errors.Is(err, test.wantErr) {
+			t.Errorf("Subset() with %+v err = %v, want %v", test.opts, err, test.wantErr)
+		}
+		if err := dehintCFFTable(cff); !errors.Is(err, test.wantErr) {
+			t.Errorf("dehintCFFTable() with %+v err = %v, want %v", test.opts, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package sfnt
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// OutlinePoint is a point in a glyph outline.
+type OutlinePoint struct {
+	X, Y    float64
+	OnCurve bool
+}
+
+// Outline is a glyph outline with composite glyphs resolved, as returned by GlyphOutline.
+type Outline struct {
+	// Points contains the points of every contour, positioned so that the
+	// glyph origin is at (0, 0).
+	Points []OutlinePoint
+	// EndPoints contains the index in Points of the last point of each contour.
+	EndPoints []int
+	// AdvanceWidth is the horizontal advance of the glyph.
+	AdvanceWidth float64
+	// AdvanceHeight is the vertical advance of the glyph, and VerticalOrigin
+	// the y coordinate of its vertical origin. They are from 'vmtx', or from
+	// the ascent and descent in 'hhea' if the font has no 'vmtx' table.
+	AdvanceHeight, VerticalOrigin float64
+}
+
+// numPhantomPoints is the number of points appended to each glyph to track
+// its metrics: the left and right side, and the top and bottom.
+const numPhantomPoints = 4
+
+// maxComponentDepth limits the nesting of composite glyphs when no limit is set in Options.
+const maxComponentDepth = 64
+
+var errComponentDepth = errors.New("composite glyphs are nested too deeply")
+
+// glyphOutline holds the points of a glyph while it is resolved, including
+// the phantom points at the end of Points.
+type glyphOutline struct {
+	Points    []OutlinePoint
+	EndPoints []int
+}
+
+func (g *glyphOutline) phantom() []OutlinePoint {
+	return g.Points[len(g.Points)-numPhantomPoints:]
+}
+
+// outlineContext contains the tables used to build glyph outlines.
+type outlineContext struct {
+	options *Options
+	glyf    *TableGlyf
+	hmtx    *TableHmtx
+	hhea    *TableHhea
+	vmtx    *TableVmtx
+	gvar    *TableGvar
+	coords  []F2Dot14
+}
+
+// GlyphOutline returns the TrueType outline of a glyph, with composite glyphs
+// resolved into a single list of contours. If coords is not nil, it contains
+// the normalized coordinates (as returned by NormalizeCoordinates) at which the
+// variations from 'gvar' are applied, including to the advance width of the glyph.
+func (font *Font) GlyphOutline(glyph GlyphID, coords []F2Dot14) (*Outline, error) {
+	c := &outlineContext{options: font.options, coords: coords}
+
+	var err error
+	if c.glyf, err = font.GlyfTable(); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagGlyf, err)
+	}
+	if c.hmtx, err = font.HmtxTable(); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagHmtx, err)
+	}
+	if c.hhea, err = font.HheaTable(); err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagHhea, err)
+	}
+	if font.HasTable(TagVmtx) {
+		if c.vmtx, err = font.VmtxTable(); err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", TagVmtx, err)
+		}
+	}
+	if coords != nil && font.HasTable(TagGvar) {
+		if c.gvar, err = font.GvarTable(); err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", TagGvar, err)
+		}
+	}
+
+	g, err := c.outline(glyph, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	phantom := g.phantom()
+	origin := phantom[0].X
+	outline := &Outline{
+		Points:         g.Points[:len(g.Points)-numPhantomPoints],
+		EndPoints:      g.EndPoints,
+		AdvanceWidth:   phantom[1].X - origin,
+		AdvanceHeight:  phantom[2].Y - phantom[3].Y,
+		VerticalOrigin: phantom[2].Y,
+	}
+	for i := range outline.Points {
+		outline.Points[i].X -= origin
+	}
+	return outline, nil
+}
+
+func (c *outlineContext) outline(id GlyphID, depth int) (*glyphOutline, error) {
+	if depth > maxComponentDepth {
+		return nil, errComponentDepth
+	}
+	if err := c.options.checkNestingDepth(depth); err != nil {
+		return nil, err
+	}
+
+	glyph, err := c.glyf.Glyph(id)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &glyphOutline{}
+	if glyph.IsComposite() {
+		// The points varied by 'gvar' are the offsets of the components.
+		for _, comp := range glyph.Components {
+			g.Points = append(g.Points, OutlinePoint{X: float64(comp.Arg1), Y: float64(comp.Arg2)})
+		}
+	} else {
+		for _, p := range glyph.Points {
+			g.Points = append(g.Points, OutlinePoint{X: float64(p.X), Y: float64(p.Y), OnCurve: p.OnCurve})
+		}
+		for _, end := range glyph.EndPoints {
+			g.EndPoints = append(g.EndPoints, int(end))
+		}
+	}
+
+	// Without metrics, as when computing bounds, the phantom points are unused.
+	var metrics [numPhantomPoints]OutlinePoint
+	if c.hmtx != nil && c.hhea != nil {
+		metric := c.hmtx.Metric(id)
+		left := float64(glyph.XMin) - float64(metric.LeftSideBearing)
+		metrics = [numPhantomPoints]OutlinePoint{
+			{X: left},
+			{X: left + float64(metric.AdvanceWidth)},
+			{Y: float64(c.hhea.Ascent)},
+			{Y: float64(c.hhea.Descent)},
+		}
+		if c.vmtx != nil {
+			vmetric := c.vmtx.Metric(id)
+			top := float64(glyph.YMax) + float64(vmetric.TopSideBearing)
+			metrics[2].Y = top
+			metrics[3].Y = top - float64(vmetric.AdvanceHeight)
+		}
+	}
+	g.Points = append(g.Points, metrics[:]...)
+
+	if c.gvar != nil {
+		if err := c.applyVariations(id, g); err != nil {
+			return nil, fmt.Errorf("glyph %d: %w", id, err)
+		}
+	}
+
+	if !glyph.IsComposite() {
+		return g, nil
+	}
+
+	offsets := g.Points[:len(glyph.Components)]
+	phantom := append([]OutlinePoint(nil), g.phantom()...)
+	resolved := &glyphOutline{}
+	for i, comp := range glyph.Components {
+		child, err := c.outline(comp.Glyph, depth+1)
+		if err != nil {
+			return nil, err
+		}
+
+		points := child.Points[:len(child.Points)-numPhantomPoints]
+		for j := range points {
+			points[j].X, points[j].Y = comp.Apply(points[j].X, points[j].Y)
+		}
+
+		var dx, dy float64
+		if comp.IsXYOffset() {
+			dx, dy = offsets[i].X, offsets[i].Y
+			if comp.Flags&ComponentScaledOffset != 0 && comp.Flags&ComponentUnscaledOffset == 0 {
+				dx, dy = comp.Apply(dx, dy)
+			}
+			if comp.Flags&ComponentRoundXYToGrid != 0 {
+				dx, dy = math.Round(dx), math.Round(dy)
+			}
+		} else {
+			parent, own := int(comp.Arg1), int(comp.Arg2)
+			if parent >= len(resolved.Points) || own >= len(points) {
+				return nil, fmt.Errorf("glyph %d: component %d matches points out of range", id, i)
+			}
+			dx = resolved.Points[parent].X - points[own].X
+			dy = resolved.Points[parent].Y - points[own].Y
+		}
+
+		start := len(resolved.Points)
+		for _, p := range points {
+			resolved.Points = append(resolved.Points, OutlinePoint{X: p.X + dx, Y: p.Y + dy, OnCurve: p.OnCurve})
+		}
+		for _, end := range child.EndPoints {
+			resolved.EndPoints = append(resolved.EndPoints, start+end)
+		}
+
+		if comp.Flags&ComponentUseMyMetrics != 0 {
+			copy(phantom, child.phantom())
+		}
+	}
+
+	resolved.Points = append(resolved.Points, phantom...)
+	return resolved, nil
+}
+
+// Bounds returns the bounding box of a glyph, with composite glyphs resolved
+// and the coordinates of scaled components rounded.
+func (table *TableGlyf) Bounds(glyph GlyphID) (xMin, yMin, xMax, yMax int16, err error) {
+	c := &outlineContext{glyf: table}
+	g, err := c.outline(glyph, 0)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+
+	points := g.Points[:len(g.Points)-numPhantomPoints]
+	if len(points) == 0 {
+		return 0, 0, 0, 0, nil
+	}
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range points {
+		minX, maxX = math.Min(minX, p.X), math.Max(maxX, p.X)
+		minY, maxY = math.Min(minY, p.Y), math.Max(maxY, p.Y)
+	}
+	return int16(math.Round(minX)), int16(math.Round(minY)), int16(math.Round(maxX)), int16(math.Round(maxY)), nil
+}
+
+// applyVariations adds the deltas from 'gvar' at the context's coordinates to the points of g.
+func (c *outlineContext) applyVariations(id GlyphID, g *glyphOutline) error {
+	variations, err := c.gvar.GlyphVariations(id, len(g.Points))
+	if err != nil {
+		return err
+	}
+
+	deltas := make([]OutlinePoint, len(g.Points))
+	for _, v := range variations {
+		scalar := v.Region.Scalar(c.coords)
+		if scalar == 0 {
+			continue
+		}
+		for i, d := range v.Deltas(g.Points, g.EndPoints) {
+			deltas[i].X += scalar * d.X
+			deltas[i].Y += scalar * d.Y
+		}
+	}
+
+	for i := range g.Points {
+		g.Points[i].X += deltas[i].X
+		g.Points[i].Y += deltas[i].Y
+	}
+	return nil
+}
+
+// Deltas returns the delta of every point of a glyph for the tuple variation,
+// inferring the deltas of points without explicit deltas. points contains the
+// default positions of the glyph's points, including the phantom points, and
+// endPoints the index of the last point of each contour. Deltas are only
+// inferred within contours, so endPoints is nil for composite glyphs and for
+// control values, whose deltas are in X.
+func (v *TupleVariation) Deltas(points []OutlinePoint, endPoints []int) []OutlinePoint {
+	delta := func(i int) OutlinePoint {
+		d := OutlinePoint{X: float64(v.DeltasX[i])}
+		if i < len(v.DeltasY) {
+			d.Y = float64(v.DeltasY[i])
+		}
+		return d
+	}
+
+	deltas := make([]OutlinePoint, len(points))
+	if v.Points == nil {
+		for i := range deltas {
+			if i < len(v.DeltasX) {
+				deltas[i] = delta(i)
+			}
+		}
+		return deltas
+	}
+
+	touched := make([]bool, len(points))
+	for i, p := range v.Points {
+		if int(p) >= len(deltas) || i >= len(v.DeltasX) {
+			continue
+		}
+		deltas[p] = delta(i)
+		touched[p] = true
+	}
+	interpolateUntouched(points, deltas, touched, endPoints)
+	return deltas
+}
+
+// interpolateUntouched infers the deltas of points without explicit deltas
+// (IUP), from the nearest touched points on either side in the same contour.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gvar#inferred-deltas-for-un-referenced-point-numbers
+func interpolateUntouched(points, deltas []OutlinePoint, touched []bool, endPoints []int) {
+	start := 0
+	for _, end := range endPoints {
+		if end >= len(points) {
+			return
+		}
+
+		var refs []int
+		for i := start; i <= end; i++ {
+			if touched[i] {
+				refs = append(refs, i)
+			}
+		}
+
+		switch len(refs) {
+		case 0:
+		case 1:
+			for i := start; i <= end; i++ {
+				deltas[i] = deltas[refs[0]]
+			}
+		default:
+			for k, ref := range refs {
+				next := refs[(k+1)%len(refs)]
+				// Walk the untouched points after ref, wrapping around the contour.
+				for i := ref + 1; ; i++ {
+					if i > end {
+						i = start
+					}
+					if i == next {
+						break
+					}
+					deltas[i].X = interpolateDelta(points[i].X, points[ref].X, points[next].X, deltas[ref].X, deltas[next].X)
+					deltas[i].Y = interpolateDelta(points[i].Y, points[ref].Y, points[next].Y, deltas[ref].Y, deltas[next].Y)
+				}
+			}
+		}
+
+		start = end + 1
+	}
+}
+
+// interpolateDelta returns the delta of a point at coordinate v, between two
+// reference points at v1 and v2 with deltas d1 and d2.
+func interpolateDelta(v, v1, v2, d1, d2 float64) float64 {
+	if v1 == v2 {
+		if d1 == d2 {
+			return d1
+		}
+		return 0
+	}
+	if v1 > v2 {
+		v1, v2 = v2, v1
+		d1, d2 = d2, d1
+	}
+	switch {
+	case v <= v1:
+		return d1
+	case v >= v2:
+		return d2
+	default:
+		return d1 + (v-v1)*(d2-d1)/(v2-v1)
+	}
+}
@@ -0,0 +1,254 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// resourceTypeSfnt is the resource type used for fonts stored in a resource fork.
+var resourceTypeSfnt = MustNamedTag("sfnt")
+
+// ErrInvalidResourceFork is returned when a resource fork (.dfont) file is malformed.
+var ErrInvalidResourceFork = errors.New("invalid resource fork")
+
+// Resource identifies the resource a font was loaded from when it was
+// parsed from a Mac resource fork (.dfont) file.
+type Resource struct {
+	ID   int16  // ID is the resource ID of the 'sfnt' resource.
+	Name string // Name is the resource name, or "" if it has none.
+}
+
+// resourceHeader is the on-disk format of the resource fork header.
+// See https://developer.apple.com/library/archive/documentation/mac/pdf/MoreMacintoshToolbox.pdf
+type resourceHeader struct {
+	DataOffset uint32 // Offset from beginning of resource fork to resource data.
+	MapOffset  uint32 // Offset from beginning of resource fork to resource map.
+	DataLength uint32 // Length of resource data.
+	MapLength  uint32 // Length of resource map.
+}
+
+const resourceHeaderLength = 16
+
+// maxResourceMapLength bounds the resource map; all offsets into it are 16 bit.
+const maxResourceMapLength = 1 << 18
+
+// resourceMapHeader is the on-disk format of the start of the resource map.
+type resourceMapHeader struct {
+	Header         resourceHeader // Copy of the resource header, or zeros.
+	NextMap        uint32         // Reserved for handle to next resource map.
+	FileRef        uint16         // Reserved for file reference number.
+	Attributes     uint16         // Resource fork attributes.
+	TypeListOffset uint16         // Offset from beginning of map to resource type list.
+	NameListOffset uint16         // Offset from beginning of map to resource name list.
+}
+
+// resourceType is the on-disk format of an entry in the resource type list.
+type resourceType struct {
+	Type          Tag    // Resource type.
+	CountMinusOne uint16 // Number of resources of this type in map minus 1.
+	RefListOffset uint16 // Offset from beginning of resource type list to reference list for this type.
+}
+
+// resourceReference is the on-disk format of an entry in a reference list.
+type resourceReference struct {
+	ID         int16  // Resource ID.
+	NameOffset uint16 // Offset from beginning of resource name list to resource name, or 0xFFFF.
+	DataOffset uint32 // Attributes (high byte) and offset from beginning of resource data to data for this resource.
+	Handle     uint32 // Reserved for handle to resource.
+}
+
+// readResourceHeader reads the resource fork header and reports whether
+// the file looks like a resource fork.
+func readResourceHeader(file File) (resourceHeader, bool) {
+	var header resourceHeader
+	var buf [resourceHeaderLength]byte
+	if _, err := file.ReadAt(buf[:], 0); err != nil {
+		return header, false
+	}
+	if err := binary.Read(bytes.NewReader(buf[:]), binary.BigEndian, &header); err != nil {
+		return header, false
+	}
+
+	// The data and the map follow the header and must not overlap.
+	if header.DataOffset < resourceHeaderLength || header.MapLength < uint32(binary.Size(resourceMapHeader{})) ||
+		header.MapLength > maxResourceMapLength {
+		return header, false
+	}
+	if uint64(header.DataOffset)+uint64(header.DataLength) > uint64(header.MapOffset) {
+		return header, false
+	}
+
+	// The resource map starts with either a copy of the header or zeros.
+	var copied [resourceHeaderLength]byte
+	if _, err := file.ReadAt(copied[:], int64(header.MapOffset)); err != nil {
+		return header, false
+	}
+	if copied != buf && copied != [resourceHeaderLength]byte{} {
+		return header, false
+	}
+
+	return header, true
+}
+
+// isResourceFork reports whether file is a resource fork, such as a .dfont file.
+// Resource forks have no signature, so this checks that the header is consistent.
+func isResourceFork(file File) bool {
+	magic, err := ReadTag(io.NewSectionReader(file, 0, 4))
+	if err != nil {
+		return false
+	}
+
+	switch magic {
+	case SignatureWOFF, SignatureWOFF2, TypeTrueType, TypeOpenType, TypePostScript1, TypeAppleTrueType, TypeTrueTypeCollection:
+		return false
+	}
+
+	_, ok := readResourceHeader(file)
+	return ok
+}
+
+// parseResourceFork reads the 'sfnt' resources of a Mac resource fork (.dfont) file
+// and returns a font for each of them.
+func parseResourceFork(file File, opts *Options) ([]*Font, error) {
+	header, ok := readResourceHeader(file)
+	if !ok {
+		return nil, ErrInvalidResourceFork
+	}
+
+	m := make([]byte, header.MapLength)
+	if _, err := file.ReadAt(m, int64(header.MapOffset)); err != nil {
+		return nil, fmt.Errorf("reading resource map: %w", err)
+	}
+
+	// The map is at least as long as its header, as checked by readResourceHeader.
+	var mapHeader resourceMapHeader
+	if err := binary.Read(bytes.NewReader(m), binary.BigEndian, &mapHeader); err != nil {
+		return nil, ErrInvalidResourceFork
+	}
+
+	typeList := int(mapHeader.TypeListOffset)
+	if typeList+2 > len(m) {
+		return nil, ErrInvalidResourceFork
+	}
+	numTypes := int(binary.BigEndian.Uint16(m[typeList:])) + 1
+
+	var fonts []*Font
+	for i := 0; i < numTypes; i++ {
+		start := typeList + 2 + 8*i
+		if start+8 > len(m) {
+			return nil, ErrInvalidResourceFork
+		}
+
+		var t resourceType
+		if err := binary.Read(bytes.NewReader(m[start:]), binary.BigEndian, &t); err != nil {
+			return nil, ErrInvalidResourceFork
+		}
+		if t.Type != resourceTypeSfnt {
+			continue
+		}
+
+		if err := opts.checkCollectionFonts(int64(len(fonts)) + int64(t.CountMinusOne) + 1); err != nil {
+			return nil, err
+		}
+
+		for j := 0; j <= int(t.CountMinusOne); j++ {
+			start := typeList + int(t.RefListOffset) + 12*j
+			if start+12 > len(m) {
+				return nil, ErrInvalidResourceFork
+			}
+
+			var ref resourceReference
+			if err := binary.Read(bytes.NewReader(m[start:]), binary.BigEndian, &ref); err != nil {
+				return nil, ErrInvalidResourceFork
+			}
+			ref.DataOffset &= 0xFFFFFF // The high byte holds the resource attributes.
+
+			font, err := parseResource(file, header, ref, opts)
+			if err != nil {
+				return nil, fmt.Errorf("resource %d: %w", ref.ID, err)
+			}
+
+			if ref.NameOffset != 0xFFFF {
+				name, err := readResourceName(m, int(mapHeader.NameListOffset)+int(ref.NameOffset))
+				if err != nil {
+					return nil, fmt.Errorf("resource %d: %w", ref.ID, err)
+				}
+				font.resource.Name = name
+			}
+
+			fonts = append(fonts, font)
+		}
+	}
+
+	if len(fonts) == 0 {
+		return nil, fmt.Errorf("no %q resources in resource fork", resourceTypeSfnt)
+	}
+
+	return fonts, nil
+}
+
+// parseResource parses a single 'sfnt' resource. The table offsets of the font
+// are relative to the start of the resource data, so the resource is parsed as
+// a file of its own.
+func parseResource(file File, header resourceHeader, ref resourceReference, opts *Options) (*Font, error) {
+	if ref.DataOffset+4 > header.DataLength {
+		return nil, ErrInvalidResourceFork
+	}
+
+	offset := int64(header.DataOffset) + int64(ref.DataOffset)
+
+	var buf [4]byte
+	if _, err := file.ReadAt(buf[:], offset); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(buf[:])
+	if uint64(ref.DataOffset)+4+uint64(length) > uint64(header.DataLength) {
+		return nil, ErrInvalidResourceFork
+	}
+
+	r := io.NewSectionReader(file, offset+4, int64(length))
+	magic, err := ReadTag(r)
+	if err != nil {
+		return nil, err
+	}
+	r.Seek(0, io.SeekStart)
+
+	switch magic {
+	case TypeTrueType, TypeOpenType, TypePostScript1, TypeAppleTrueType:
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+
+	font, err := parseOTF(r, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+	font.resource = &Resource{ID: ref.ID}
+
+	return font, nil
+}
+
+// readResourceName reads a Pascal string at offset in the resource map.
+func readResourceName(m []byte, offset int) (string, error) {
+	if offset >= len(m) || offset+1+int(m[offset]) > len(m) {
+		return "", ErrInvalidResourceFork
+	}
+
+	raw := m[offset+1 : offset+1+int(m[offset])]
+	name, err := charmap.Macintosh.NewDecoder().Bytes(raw)
+	if err != nil {
+		return string(raw), nil
+	}
+	return string(name), nil
+}
+
+// Resource returns the resource the font was read from if it was parsed from
+// a Mac resource fork (.dfont) file, or nil otherwise.
+func (font *Font) Resource() *Resource {
+	return font.resource
+}
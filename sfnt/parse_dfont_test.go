@@ -0,0 +1,137 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testResource struct {
+	id   int16
+	name string
+	data []byte
+}
+
+// buildResourceFork returns a resource fork containing each resource as an 'sfnt' resource.
+func buildResourceFork(resources []testResource) []byte {
+	var data, names, refs bytes.Buffer
+	for _, res := range resources {
+		nameOffset := uint16(0xFFFF)
+		if res.name != "" {
+			nameOffset = uint16(names.Len())
+			names.WriteByte(byte(len(res.name)))
+			names.WriteString(res.name)
+		}
+
+		binary.Write(&refs, binary.BigEndian, resourceReference{
+			ID:         res.id,
+			NameOffset: nameOffset,
+			DataOffset: uint32(data.Len()),
+		})
+
+		binary.Write(&data, binary.BigEndian, uint32(len(res.data)))
+		data.Write(res.data)
+	}
+
+	const dataOffset = 256
+	const typeListOffset = 28
+	const refListOffset = 2 + 8
+
+	header := resourceHeader{
+		DataOffset: dataOffset,
+		MapOffset:  dataOffset + uint32(data.Len()),
+		DataLength: uint32(data.Len()),
+	}
+
+	var m bytes.Buffer
+	binary.Write(&m, binary.BigEndian, resourceMapHeader{
+		TypeListOffset: typeListOffset,
+		NameListOffset: uint16(typeListOffset + refListOffset + refs.Len()),
+	})
+	binary.Write(&m, binary.BigEndian, uint16(0))
+	binary.Write(&m, binary.BigEndian, resourceType{
+		Type:          resourceTypeSfnt,
+		CountMinusOne: uint16(len(resources) - 1),
+		RefListOffset: refListOffset,
+	})
+	m.Write(refs.Bytes())
+	m.Write(names.Bytes())
+	header.MapLength = uint32(m.Len())
+
+	var out bytes.Buffer
+	binary.Write(&out, binary.BigEndian, header)
+	out.Write(make([]byte, dataOffset-resourceHeaderLength))
+	out.Write(data.Bytes())
+	out.Write(m.Bytes())
+	return out.Bytes()
+}
+
+func TestParseResourceFork(t *testing.T) {
+	roboto, err := os.ReadFile(filepath.Join("testdata", "Roboto-BoldItalic.ttf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raleway, err := os.ReadFile(filepath.Join("testdata", "Raleway-v4020-Regular.otf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dfont := buildResourceFork([]testResource{
+		{id: 256, name: "Roboto Bold Italic", data: roboto},
+		{id: 257, data: raleway},
+	})
+
+	isCollection, err := IsCollection(bytes.NewReader(dfont))
+	if err != nil || !isCollection {
+		t.Errorf("IsCollection(dfont) = %v, %v, want true, nil", isCollection, err)
+	}
+
+	fonts, err := StrictParseCollection(bytes.NewReader(dfont))
+	if err != nil {
+		t.Fatalf("StrictParseCollection(dfont) err = %q, want nil", err)
+	}
+	if len(fonts) != 2 {
+		t.Fatalf("StrictParseCollection(dfont) returned %d fonts, want 2", len(fonts))
+	}
+
+	want := []Resource{{ID: 256, Name: "Roboto Bold Italic"}, {ID: 257}}
+	for i, font := range fonts {
+		if got := font.Resource(); got == nil || *got != want[i] {
+			t.Errorf("font[%d].Resource() = %v, want %v", i, got, want[i])
+		}
+	}
+
+	if fonts[1].Type() != TypeOpenType {
+		t.Errorf("font[1].Type() = %q, want %q", fonts[1].Type(), TypeOpenType)
+	}
+
+	font, err := Parse(bytes.NewReader(dfont))
+	if err != nil {
+		t.Fatalf("Parse(dfont) err = %q, want nil", err)
+	}
+	if font.Resource().ID != 256 {
+		t.Errorf("Parse(dfont).Resource().ID = %d, want 256", font.Resource().ID)
+	}
+
+	font, err = ParseCollectionIndex(bytes.NewReader(dfont), 1)
+	if err != nil {
+		t.Fatalf("ParseCollectionIndex(dfont, 1) err = %q, want nil", err)
+	}
+	if font.Resource().ID != 257 {
+		t.Errorf("ParseCollectionIndex(dfont, 1).Resource().ID = %d, want 257", font.Resource().ID)
+	}
+}
+
+func TestIsResourceForkRejectsFonts(t *testing.T) {
+	for _, filename := range []string{"Roboto-BoldItalic.ttf", "Raleway-v4020-Regular.otf", "TestTTC.ttc"} {
+		buf, err := os.ReadFile(filepath.Join("testdata", filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isResourceFork(bytes.NewReader(buf)) {
+			t.Errorf("isResourceFork(%q) = true, want false", filename)
+		}
+	}
+}
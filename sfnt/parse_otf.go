@@ -91,18 +91,24 @@ func readDirectoryEntryFast(r io.Reader, entry *directoryEntry) error {
 
 // parseOTF reads an OpenTyp (.otf) or TrueType (.ttf) file and returns a Font.
 // If parsing fails, then an error is returned and Font will be nil.
-func parseOTF(file, collection File) (*Font, error) {
+func parseOTF(file, collection File, opts *Options) (*Font, error) {
 	var header otfHeader
 	if err := readOTFHeaderFast(file, &header); err != nil {
 		return nil, err
 	}
 
+	if err := opts.checkTables(int(header.NumTables)); err != nil {
+		return nil, err
+	}
+
 	font := &Font{
 		file:       file,
 		collection: collection,
+		options:    opts,
 
 		scalerType: header.ScalerType,
 		tables:     make(map[Tag]*tableSection, header.NumTables),
+		header:     &header,
 	}
 
 	for i := 0; i < int(header.NumTables); i++ {
@@ -111,8 +117,6 @@ func parseOTF(file, collection File) (*Font, error) {
 			return nil, err
 		}
 
-		// TODO Check the checksum.
-
 		if _, found := font.tables[entry.Tag]; found {
 			return nil, fmt.Errorf("found multiple %q tables", entry.Tag)
 		}
@@ -120,8 +124,9 @@ func parseOTF(file, collection File) (*Font, error) {
 		font.tables[entry.Tag] = &tableSection{
 			tag: entry.Tag,
 
-			offset: entry.Offset,
-			length: entry.Length,
+			offset:   entry.Offset,
+			length:   entry.Length,
+			checksum: entry.CheckSum,
 		}
 	}
 
@@ -129,5 +134,13 @@ func parseOTF(file, collection File) (*Font, error) {
 		return nil, ErrMissingHead
 	}
 
+	if err := opts.checkSections(font); err != nil {
+		return nil, err
+	}
+
+	if err := opts.verify(font); err != nil {
+		return nil, err
+	}
+
 	return font, nil
 }
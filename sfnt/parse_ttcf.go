@@ -22,12 +22,16 @@ func parseTTCFHeader(file File) (ttcfHeaderV1, error) {
 }
 
 // parseTTCF reads a TrueType Collection and returns an array of fonts
-func parseTTCF(file File) ([]*Font, error) {
+func parseTTCF(file File, opts *Options) ([]*Font, error) {
 	header, err := parseTTCFHeader(file)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := opts.checkCollectionFonts(int64(header.NumFonts)); err != nil {
+		return nil, err
+	}
+
 	fonts := make([]*Font, header.NumFonts)
 
 	for i := uint32(0); i < header.NumFonts; i++ {
@@ -37,7 +41,7 @@ func parseTTCF(file File) ([]*Font, error) {
 			return nil, err
 		}
 
-		font, err := parse(io.NewSectionReader(file, int64(offset), 1<<63-1), file)
+		font, err := parse(io.NewSectionReader(file, int64(offset), 1<<63-1), file, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -48,23 +52,33 @@ func parseTTCF(file File) ([]*Font, error) {
 }
 
 // ParseCollection parses a TrueType Collection (.ttc) file and returns an array of fonts.
+// Mac resource fork (.dfont) files are also accepted, and a font is returned for each
+// 'sfnt' resource; see [Font.Resource].
 // It also accepts a font file that Parse accepts and returns an array of fonts with a length of 1.
 func ParseCollection(file File) ([]*Font, error) {
+	return parseCollection(file, nil)
+}
+
+func parseCollection(file File, opts *Options) ([]*Font, error) {
 	magic, err := ReadTag(file)
 	if err != nil {
 		return nil, err
 	}
 	file.Seek(0, io.SeekStart)
 
+	if magic != TypeTrueTypeCollection && isResourceFork(file) {
+		return parseResourceFork(file, opts)
+	}
+
 	if magic != TypeTrueTypeCollection {
-		font, err := Parse(file)
+		font, err := parse(file, nil, opts)
 		if err != nil {
 			return nil, err
 		}
 		return []*Font{font}, nil
 	}
 
-	return parseTTCF(file)
+	return parseTTCF(file, opts)
 }
 
 // StrictParseCollection parses a TrueType Collection file and returns
@@ -85,13 +99,28 @@ func StrictParseCollection(file File) ([]*Font, error) {
 	return fonts, nil
 }
 
-// ParseCollectionIndex parses a single font from a TrueType Collection (.ttc) file with
-// font index starting from 0. An error is returned if a file is not a collection.
+// ParseCollectionIndex parses a single font from a TrueType Collection (.ttc) or
+// resource fork (.dfont) file with font index starting from 0.
+// An error is returned if a file is not a collection.
 func ParseCollectionIndex(file File, index uint32) (*Font, error) {
+	return parseCollectionIndex(file, index, nil)
+}
+
+func parseCollectionIndex(file File, index uint32, opts *Options) (*Font, error) {
 	magic, err := ReadTag(file)
 	if err != nil {
 		return nil, err
 	}
+	if magic != TypeTrueTypeCollection && isResourceFork(file) {
+		fonts, err := parseResourceFork(file, opts)
+		if err != nil {
+			return nil, err
+		}
+		if index > uint32(len(fonts)-1) {
+			return nil, fmt.Errorf("index can't be larger than %d (got %d)", len(fonts)-1, index)
+		}
+		return fonts[index], nil
+	}
 	if magic != TypeTrueTypeCollection {
 		return nil, fmt.Errorf("expected \"ttcf\" for head bytes, got \"%s\" instead", magic)
 	}
@@ -102,6 +131,10 @@ func ParseCollectionIndex(file File, index uint32) (*Font, error) {
 		return nil, err
 	}
 
+	if err := opts.checkCollectionFonts(int64(header.NumFonts)); err != nil {
+		return nil, err
+	}
+
 	if index > header.NumFonts-1 {
 		return nil, fmt.Errorf("index can't be larger than %d (got %d)", header.NumFonts-1, index)
 	}
@@ -114,7 +147,7 @@ func ParseCollectionIndex(file File, index uint32) (*Font, error) {
 		return nil, err
 	}
 
-	return parse(io.NewSectionReader(file, int64(offset), 1<<63-1), file)
+	return parse(io.NewSectionReader(file, int64(offset), 1<<63-1), file, opts)
 }
 
 // StrictParseCollectionIndex parses a single font from a TrueType
@@ -72,16 +72,22 @@ func readWOFFEntryFast(r io.Reader, entry *woffEntry) error {
 	return nil
 }
 
-func parseWOFF(file File) (*Font, error) {
+func parseWOFF(file File, opts *Options) (*Font, error) {
 	var header woffHeader
 	if err := readWOFFHeaderFast(file, &header); err != nil {
 		return nil, err
 	}
 
+	if err := opts.checkTables(int(header.NumTables)); err != nil {
+		return nil, err
+	}
+
 	font := &Font{
 		file:       file,
+		options:    opts,
 		scalerType: header.Flavor,
 		tables:     make(map[Tag]*tableSection, header.NumTables),
+		header:     newOTFHeader(header.Flavor, header.NumTables),
 	}
 
 	for i := 0; i < int(header.NumTables); i++ {
@@ -90,8 +96,6 @@ func parseWOFF(file File) (*Font, error) {
 			return nil, err
 		}
 
-		// TODO Check the checksum.
-
 		if _, found := font.tables[entry.Tag]; found {
 			return nil, fmt.Errorf("found multiple %q tables", entry.Tag)
 		}
@@ -99,9 +103,10 @@ func parseWOFF(file File) (*Font, error) {
 		font.tables[entry.Tag] = &tableSection{
 			tag: entry.Tag,
 
-			offset:  entry.Offset,
-			length:  entry.CompLength,
-			zLength: entry.OrigLength,
+			offset:   entry.Offset,
+			length:   entry.CompLength,
+			zLength:  entry.OrigLength,
+			checksum: entry.OrigChecksum,
 		}
 	}
 
@@ -109,5 +114,13 @@ func parseWOFF(file File) (*Font, error) {
 		return nil, ErrMissingHead
 	}
 
+	if err := opts.checkSections(font); err != nil {
+		return nil, err
+	}
+
+	if err := opts.verify(font); err != nil {
+		return nil, err
+	}
+
 	return font, nil
 }
@@ -2,28 +2,163 @@ package sfnt
 
 import (
 	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
 
-	"dmitri.shuralyov.com/font/woff2"
+	"github.com/dsnet/compress/brotli"
 )
 
-func parseWOFF2(file File) (*Font, error) {
-	f, err := woff2.Parse(file)
-	if err != nil {
+var errInvalidWOFF2Directory = errors.New("invalid WOFF2 table directory")
+
+// woff2Directory reads the fields of a WOFF2 table directory, and records
+// in err the first field that is out of range or invalid.
+type woff2Directory struct {
+	buf []byte
+	pos int
+	err error
+}
+
+func (d *woff2Directory) u8() byte {
+	if d.pos+1 > len(d.buf) {
+		d.err = errInvalidWOFF2Directory
+		return 0
+	}
+	d.pos++
+	return d.buf[d.pos-1]
+}
+
+func (d *woff2Directory) u32() uint32 {
+	if d.pos+4 > len(d.buf) {
+		d.err = errInvalidWOFF2Directory
+		return 0
+	}
+	d.pos += 4
+	return binary.BigEndian.Uint32(d.buf[d.pos-4:])
+}
+
+// uintBase128 reads a number in the variable-length encoding of WOFF2.
+// https://www.w3.org/TR/WOFF2/#DataTypes
+func (d *woff2Directory) uintBase128() uint32 {
+	var v uint32
+	for i := 0; i < 5; i++ {
+		b := d.u8()
+		if d.err != nil {
+			return 0
+		}
+		// Leading zeros and values that overflow 32 bits are invalid.
+		if (i == 0 && b == 0x80) || v&0xFE000000 != 0 {
+			d.err = errInvalidWOFF2Directory
+			return 0
+		}
+		v = v<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return v
+		}
+	}
+	d.err = errInvalidWOFF2Directory
+	return 0
+}
+
+// parseWOFF2 reads a WOFF2 file and decompresses its tables. The sizes in
+// the header and the table directory are checked against the limits before
+// the font data is decompressed, and the decompressed data is limited to
+// MaxTotalSize, or otherwise to the sizes in the directory, so that a Brotli
+// stream which expands further is never held in memory.
+func parseWOFF2(file File, opts *Options) (*Font, error) {
+	var header woff2Header
+	if err := binary.Read(io.NewSectionReader(file, 0, woff2HeaderLength), binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Flavor == TypeTrueTypeCollection {
+		return nil, fmt.Errorf("%w: WOFF2 collection", ErrUnsupportedFormat)
+	}
+	if err := opts.checkTables(int(header.NumTables)); err != nil {
+		return nil, err
+	}
+	if err := opts.checkTotalSize(int64(header.TotalSfntSize)); err != nil {
+		return nil, err
+	}
+
+	// Each entry of the directory is at most 15 bytes long.
+	buf := make([]byte, 15*int(header.NumTables))
+	n, err := file.ReadAt(buf, woff2HeaderLength)
+	if err != nil && err != io.EOF {
 		return nil, err
 	}
+	d := &woff2Directory{buf: buf[:n]}
+
 	font := &Font{
-		file:       bytes.NewReader(f.FontData),
-		scalerType: Tag{f.Header.Flavor},
-		tables:     make(map[Tag]*tableSection, f.Header.NumTables),
+		scalerType: header.Flavor,
+		tables:     make(map[Tag]*tableSection, header.NumTables),
+		options:    opts,
 	}
-	for _, t := range f.TableDirectory.Tables() {
-		tag := Tag{t.Tag}
+	var total int64
+	for i := 0; i < int(header.NumTables); i++ {
+		flags := d.u8()
+		var tag Tag
+		if flags&woff2ArbitraryTag == woff2ArbitraryTag {
+			tag = Tag{d.u32()}
+		} else if int(flags&woff2ArbitraryTag) < len(woff2KnownTags) {
+			tag = MustNamedTag(woff2KnownTags[flags&woff2ArbitraryTag])
+		} else {
+			return nil, errInvalidWOFF2Directory
+		}
+		length := d.uintBase128()
+
+		// Transformed tables are followed by their transformed length, which
+		// is the length of their data. The transform version 0 is the null
+		// transform for tables other than 'glyf' and 'loca'.
+		transformed := flags&woff2NullTransform != 0
+		if tag == TagGlyf || tag == TagLoca {
+			transformed = flags&woff2NullTransform != woff2NullTransform
+		}
+		if transformed {
+			length = d.uintBase128()
+		}
+		if d.err != nil {
+			return nil, d.err
+		}
+
+		if _, found := font.tables[tag]; found {
+			return nil, fmt.Errorf("found multiple %q tables", tag)
+		}
+		if err := opts.checkTableSize(int64(length)); err != nil {
+			return nil, fmt.Errorf("table %q: %w", tag, err)
+		}
 		font.tables[tag] = &tableSection{
 			tag:     tag,
-			offset:  uint32(t.Offset),
-			length:  uint32(t.Length),
-			zLength: uint32(t.Length),
+			offset:  uint32(total),
+			length:  length,
+			zLength: length,
 		}
+		total += int64(length)
+	}
+	if err := opts.checkTotalSize(total); err != nil {
+		return nil, err
 	}
+
+	limit := total
+	if opts != nil && opts.MaxTotalSize > 0 {
+		limit = opts.MaxTotalSize
+	}
+	compressed := io.NewSectionReader(file, woff2HeaderLength+int64(d.pos), int64(header.TotalCompressedSize))
+	br, err := brotli.NewReader(compressed, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(br, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompressing WOFF2 font data: %w", err)
+	}
+	if err := opts.checkTotalSize(int64(len(data))); err != nil {
+		return nil, err
+	}
+	if int64(len(data)) != total {
+		return nil, fmt.Errorf("WOFF2 font data is %d bytes, want %d", len(data), total)
+	}
+	font.file = bytes.NewReader(data)
+
 	return font, nil
 }
@@ -12,6 +12,30 @@ var parsers = map[Tag]tableParser{
 	TagOS2:  parseTableOS2,
 	TagGpos: parseTableLayout,
 	TagGsub: parseTableLayout,
+	TagMaxp: parseTableMaxp,
+	TagCmap: parseTableCmap,
+	TagFvar: parseTableFvar,
+	TagAvar: parseTableAvar,
+	TagGvar: parseTableGvar,
+	TagCvar: parseTableCvar,
+	TagHvar: parseTableHvar,
+	TagVvar: parseTableHvar,
+	TagMvar: parseTableMvar,
+	TagSTAT: parseTableSTAT,
+	TagCOLR: parseTableCOLR,
+	TagCPAL: parseTableCPAL,
+	TagSVG:  parseTableSVG,
+	TagCBDT: parseTableEBDT,
+	TagCBLC: parseTableEBLC,
+	TagEBDT: parseTableEBDT,
+	TagEBLC: parseTableEBLC,
+	TagVhea: parseTableVhea,
+	TagVORG: parseTableVORG,
+	TagBASE: parseTableBASE,
+	TagMATH: parseTableMATH,
+	TagJSTF: parseTableJSTF,
+	TagCvt:  parseTableCvt,
+	TagGasp: parseTableGasp,
 }
 
 // Table is an interface for each section of the font file.
@@ -33,13 +57,50 @@ type unparsedTable struct {
 	bytes []byte // Uncompress content of this table.
 }
 
-type tableParser func(tag Tag, buffer []byte) (Table, error)
+// tableParser parses the uncompressed bytes of a table. opts may be nil, and
+// limits the resources used when parsing.
+type tableParser func(tag Tag, buffer []byte, opts *Options) (Table, error)
 
-func newUnparsedTable(tag Tag, buffer []byte) (Table, error) {
+func newUnparsedTable(tag Tag, buffer []byte, _ *Options) (Table, error) {
 	return &unparsedTable{baseTable(tag), buffer}, nil
 }
 
+// ParseTable parses buf as the table identified by tag. Tables that this
+// package does not parse are returned with their bytes unchanged, so that
+// tables modified as bytes can be added to a font with AddTable.
+func ParseTable(tag Tag, buf []byte) (Table, error) {
+	parser, found := parsers[tag]
+	if !found {
+		parser = newUnparsedTable
+	}
+	return parser(tag, buf, nil)
+}
+
 func (font *Font) parseTable(s *tableSection) (Table, error) {
+	buf, err := font.readTable(s)
+	if err != nil {
+		return nil, err
+	}
+
+	parser, found := parsers[s.tag]
+	if !found {
+		parser = newUnparsedTable
+	}
+
+	return parser(s.tag, buf, font.options)
+}
+
+// tableBytes returns the bytes of a table, without parsing it if it has not
+// been parsed yet.
+func (font *Font) tableBytes(s *tableSection) ([]byte, error) {
+	if s.table != nil {
+		return s.table.Bytes(), nil
+	}
+	return font.readTable(s)
+}
+
+// readTable returns the uncompressed bytes of the table as stored in the file.
+func (font *Font) readTable(s *tableSection) ([]byte, error) {
 	var buf []byte
 
 	if s.length != 0 && s.length < s.zLength {
@@ -65,10 +126,5 @@ func (font *Font) parseTable(s *tableSection) (Table, error) {
 		}
 	}
 
-	parser, found := parsers[s.tag]
-	if !found {
-		parser = newUnparsedTable
-	}
-
-	return parser(s.tag, buf)
+	return buf, nil
 }
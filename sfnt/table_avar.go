@@ -0,0 +1,262 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// TableAvar modifies the mapping from user coordinates to normalized coordinates
+// in a variable font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/avar
+type TableAvar struct {
+	baseTable
+
+	// MajorVersion is 1, or 2 if the table has AxisIndexMap and VarStore.
+	MajorVersion uint16
+	// SegmentMaps contains a map for each axis in 'fvar'.
+	SegmentMaps []AvarSegmentMap
+	// AxisIndexMap maps each axis to a delta set in VarStore, it may be nil.
+	AxisIndexMap *DeltaSetIndexMap
+	// VarStore contains the deltas applied to normalized coordinates in version 2.
+	VarStore *ItemVariationStore
+}
+
+// AvarMapping maps a normalized coordinate to a modified value.
+type AvarMapping struct {
+	From, To F2Dot14
+}
+
+// AvarSegmentMap is a piecewise linear mapping of normalized coordinates for one axis.
+type AvarSegmentMap []AvarMapping
+
+// Map applies the segment map to coord. Maps with fewer than 3 entries
+// (which must include -1, 0 and 1) are ignored.
+func (m AvarSegmentMap) Map(coord F2Dot14) F2Dot14 {
+	if len(m) < 3 {
+		return coord
+	}
+
+	if coord <= m[0].From {
+		return coord - m[0].From + m[0].To
+	}
+	for i := 1; i < len(m); i++ {
+		if coord == m[i].From {
+			return m[i].To
+		}
+		if coord < m[i].From {
+			prev := m[i-1]
+			if m[i].From == prev.From {
+				return prev.To
+			}
+			t := float64(coord-prev.From) / float64(m[i].From-prev.From)
+			return prev.To + F2Dot14(math.Round(t*float64(m[i].To-prev.To)))
+		}
+	}
+
+	last := m[len(m)-1]
+	return coord - last.From + last.To
+}
+
+func parseTableAvar(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableAvar{
+		baseTable:    baseTable(tag),
+		MajorVersion: binary.BigEndian.Uint16(buf),
+	}
+	if table.MajorVersion != 1 && table.MajorVersion != 2 {
+		return nil, fmt.Errorf("unsupported 'avar' version %d", table.MajorVersion)
+	}
+
+	axisCount := int(binary.BigEndian.Uint16(buf[6:]))
+	table.SegmentMaps = make([]AvarSegmentMap, axisCount)
+
+	p := 8
+	for i := range table.SegmentMaps {
+		if p+2 > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		count := int(binary.BigEndian.Uint16(buf[p:]))
+		p += 2
+		if p+4*count > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		m := make(AvarSegmentMap, count)
+		for j := range m {
+			m[j] = AvarMapping{
+				From: F2Dot14(binary.BigEndian.Uint16(buf[p:])),
+				To:   F2Dot14(binary.BigEndian.Uint16(buf[p+2:])),
+			}
+			if j > 0 && m[j].From < m[j-1].From {
+				return nil, errors.New("'avar' segment map is not sorted")
+			}
+			p += 4
+		}
+		table.SegmentMaps[i] = m
+	}
+
+	if table.MajorVersion < 2 {
+		return table, nil
+	}
+
+	if p+8 > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	axisIndexMapOffset := int(binary.BigEndian.Uint32(buf[p:]))
+	varStoreOffset := int(binary.BigEndian.Uint32(buf[p+4:]))
+
+	if axisIndexMapOffset != 0 {
+		if axisIndexMapOffset >= len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		m, err := parseDeltaSetIndexMap(buf[axisIndexMapOffset:])
+		if err != nil {
+			return nil, err
+		}
+		table.AxisIndexMap = m
+	}
+
+	if varStoreOffset != 0 {
+		if varStoreOffset >= len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		store, err := ParseItemVariationStore(buf[varStoreOffset:])
+		if err != nil {
+			return nil, err
+		}
+		table.VarStore = store
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableAvar) Bytes() []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint16(buf, table.MajorVersion)
+	binary.BigEndian.PutUint16(buf[6:], uint16(len(table.SegmentMaps)))
+	for _, m := range table.SegmentMaps {
+		buf = appendUint16(buf, uint16(len(m)))
+		for _, mapping := range m {
+			buf = appendUint16(buf, uint16(mapping.From))
+			buf = appendUint16(buf, uint16(mapping.To))
+		}
+	}
+	if table.MajorVersion < 2 {
+		return buf
+	}
+
+	offsets := len(buf)
+	buf = append(buf, make([]byte, 8)...)
+	if table.AxisIndexMap != nil {
+		binary.BigEndian.PutUint32(buf[offsets:], uint32(len(buf)))
+		buf = append(buf, table.AxisIndexMap.Bytes()...)
+	}
+	if table.VarStore != nil {
+		binary.BigEndian.PutUint32(buf[offsets+4:], uint32(len(buf)))
+		buf = append(buf, table.VarStore.Bytes()...)
+	}
+	return buf
+}
+
+// Apply maps default-normalized coordinates, with one value per axis in 'fvar',
+// through the segment maps and, for version 2, the variation store.
+func (table *TableAvar) Apply(coords []F2Dot14) []F2Dot14 {
+	mapped := make([]F2Dot14, len(coords))
+	for i, coord := range coords {
+		if i < len(table.SegmentMaps) {
+			coord = table.SegmentMaps[i].Map(coord)
+		}
+		mapped[i] = coord
+	}
+
+	if table.VarStore == nil {
+		return mapped
+	}
+
+	out := make([]F2Dot14, len(mapped))
+	for i, coord := range mapped {
+		index := VariationIndex{Inner: uint16(i)}
+		if table.AxisIndexMap != nil {
+			index = table.AxisIndexMap.Index(i)
+		}
+		delta := math.Round(table.VarStore.Delta(index, mapped))
+		out[i] = clampNormalized(int(coord) + int(delta))
+	}
+	return out
+}
+
+// clampNormalized clamps a normalized coordinate to the range -1.0 to 1.0.
+func clampNormalized(v int) F2Dot14 {
+	if v < -1<<14 {
+		return -1 << 14
+	}
+	if v > 1<<14 {
+		return 1 << 14
+	}
+	return F2Dot14(v)
+}
+
+// AvarTable returns the table corresponding to the 'avar' tag.
+func (font *Font) AvarTable() (*TableAvar, error) {
+	t, err := font.Table(TagAvar)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableAvar), nil
+}
+
+// normalize returns the default normalization of a user coordinate for the axis,
+// mapping Min, Default and Max to -1, 0 and 1.
+func (axis *VariationAxis) normalize(v float64) F2Dot14 {
+	if v < axis.Min {
+		v = axis.Min
+	}
+	if v > axis.Max {
+		v = axis.Max
+	}
+
+	switch {
+	case v < axis.Default && axis.Default > axis.Min:
+		return NewF2Dot14((v - axis.Default) / (axis.Default - axis.Min))
+	case v > axis.Default && axis.Max > axis.Default:
+		return NewF2Dot14((v - axis.Default) / (axis.Max - axis.Default))
+	default:
+		return 0
+	}
+}
+
+// NormalizeCoordinates converts user coordinates, such as {'wght': 700}, to
+// normalized coordinates with one value per axis in 'fvar'. Axes that are not
+// given use their default value, and tags that are not axes of the font are ignored.
+// The values are clamped to the range of each axis, then mapped through 'avar'
+// if the font has one.
+func (font *Font) NormalizeCoordinates(user map[Tag]float64) ([]F2Dot14, error) {
+	fvar, err := font.FvarTable()
+	if err != nil {
+		return nil, err
+	}
+
+	coords := make([]F2Dot14, len(fvar.Axes))
+	for i := range fvar.Axes {
+		axis := &fvar.Axes[i]
+		if v, ok := user[axis.Tag]; ok {
+			coords[i] = axis.normalize(v)
+		}
+	}
+
+	if !font.HasTable(TagAvar) {
+		return coords, nil
+	}
+	avar, err := font.AvarTable()
+	if err != nil {
+		return nil, err
+	}
+	return avar.Apply(coords), nil
+}
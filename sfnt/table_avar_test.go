@@ -0,0 +1,99 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// testAvarBytes returns an 'avar' table for a single axis, mapping 0.5 to 0.8.
+// Version 2 tables also subtract 0.1 at the maximum of the axis.
+func testAvarBytes(version uint16) []byte {
+	u16 := func(values ...int) []byte {
+		b := make([]byte, 2*len(values))
+		for i, v := range values {
+			binary.BigEndian.PutUint16(b[2*i:], uint16(v))
+		}
+		return b
+	}
+
+	buf := u16(int(version), 0, 0, 1)
+	buf = append(buf, u16(4, -16384, -16384, 0, 0, 8192, 13107, 16384, 16384)...)
+	if version < 2 {
+		return buf
+	}
+
+	// axisIndexMapOffset, varStoreOffset
+	buf = append(buf, u16(0, 0, 0, len(buf)+8)...)
+	// ItemVariationStore header, with a region list at 12 and one data at 22.
+	buf = append(buf, u16(1, 0, 12, 1, 0, 22)...)
+	// One region, peaking at 1.0.
+	buf = append(buf, u16(1, 1, 0, 16384, 16384)...)
+	// One item, with a 16-bit delta for the region.
+	buf = append(buf, u16(1, 1, 1, 0, -1638)...)
+	return buf
+}
+
+func TestNormalizeCoordinates(t *testing.T) {
+	wght := MustNamedTag("wght")
+
+	tests := []struct {
+		name string
+		avar []byte
+		user float64
+		want F2Dot14
+	}{
+		{name: "no avar", user: 650, want: 8192},
+		{name: "no avar min", user: 100, want: -16384},
+		{name: "no avar clamped", user: 1000, want: 16384},
+		{name: "v1 default", avar: testAvarBytes(1), user: 400, want: 0},
+		{name: "v1 mapped", avar: testAvarBytes(1), user: 650, want: 13107},
+		{name: "v1 interpolated", avar: testAvarBytes(1), user: 775, want: 14746},
+		{name: "v2 delta", avar: testAvarBytes(2), user: 775, want: 13272},
+		{name: "v2 default", avar: testAvarBytes(2), user: 400, want: 0},
+	}
+
+	for _, test := range tests {
+		font := New(TypeTrueType)
+		font.AddTable(TagFvar, &TableFvar{
+			baseTable: baseTable(TagFvar),
+			Axes:      []VariationAxis{{Tag: wght, Min: 100, Default: 400, Max: 900}},
+		})
+		if test.avar != nil {
+			avar, err := parseTableAvar(TagAvar, test.avar, nil)
+			if err != nil {
+				t.Fatalf("%s: parseTableAvar() err = %q, want nil", test.name, err)
+			}
+			font.AddTable(TagAvar, avar)
+		}
+
+		coords, err := font.NormalizeCoordinates(map[Tag]float64{wght: test.user})
+		if err != nil {
+			t.Fatalf("%s: NormalizeCoordinates() err = %q, want nil", test.name, err)
+		}
+		if len(coords) != 1 || coords[0] != test.want {
+			t.Errorf("%s: NormalizeCoordinates(%v) = %v, want [%v]", test.name, test.user, coords, test.want)
+		}
+	}
+}
+
+func TestDeltaSetIndexMap(t *testing.T) {
+	// Format 0, 2 byte entries with 4 inner bits, 2 entries.
+	m, err := parseDeltaSetIndexMap([]byte{0, 0x13, 0, 2, 0x00, 0x12, 0x01, 0x05})
+	if err != nil {
+		t.Fatalf("parseDeltaSetIndexMap() err = %q, want nil", err)
+	}
+
+	tests := []struct {
+		item int
+		want VariationIndex
+	}{
+		{0, VariationIndex{Outer: 1, Inner: 2}},
+		{1, VariationIndex{Outer: 16, Inner: 5}},
+		{5, VariationIndex{Outer: 16, Inner: 5}},
+	}
+	for _, test := range tests {
+		if got := m.Index(test.item); got != test.want {
+			t.Errorf("Index(%d) = %v, want %v", test.item, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,443 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// TableBASE contains the positions of the baselines of each script, used to
+// align glyphs of different scripts on a line. Only parsing is supported,
+// Bytes returns the original table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/base
+type TableBASE struct {
+	baseTable
+	bytes []byte
+
+	// Horizontal and Vertical contain the baselines for each direction of
+	// text, and are nil if the font has none.
+	Horizontal, Vertical *BaseAxis
+	// VarStore contains the variations of the coordinates in a variable font, it may be nil.
+	VarStore *ItemVariationStore
+}
+
+// Direction is the direction of a line of text.
+type Direction int
+
+// Directions of text, selecting the axis of the 'BASE' table.
+const (
+	DirectionHorizontal Direction = iota
+	DirectionVertical
+)
+
+// Baseline tags registered in the OpenType specification.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/baselinetags
+var (
+	BaselineHanging        = MustNamedTag("hang")
+	BaselineIdeoFaceBottom = MustNamedTag("icfb")
+	BaselineIdeoFaceTop    = MustNamedTag("icft")
+	BaselineIdeoEmBottom   = MustNamedTag("ideo")
+	BaselineIdeoEmTop      = MustNamedTag("idtp")
+	BaselineMath           = MustNamedTag("math")
+	BaselineRoman          = MustNamedTag("romn")
+)
+
+// BaseAxis contains the baselines for one direction of text.
+type BaseAxis struct {
+	// BaselineTags contains the baselines with values for each script, sorted by tag.
+	BaselineTags []Tag
+	// Scripts are sorted by tag.
+	Scripts []BaseScript
+}
+
+// BaseScript contains the baselines and extents of a script.
+type BaseScript struct {
+	Tag Tag
+	// Values contains the position of each baseline, and is nil if the
+	// script does not define them.
+	Values *BaseValues
+	// DefaultMinMax contains the extents of the script, it may be nil.
+	DefaultMinMax *MinMax
+	// LangSys contains the extents of the script for specific languages, sorted by tag.
+	LangSys []BaseLangSys
+}
+
+// BaseValues contains the position of each baseline in BaseAxis.BaselineTags
+// for a script.
+type BaseValues struct {
+	// DefaultIndex is the index of the baseline used by the script.
+	DefaultIndex uint16
+	Coords       []BaseCoord
+}
+
+// BaseLangSys contains the extents of a script for a language.
+type BaseLangSys struct {
+	Tag    Tag
+	MinMax MinMax
+}
+
+// MinMax contains the extents of glyphs in the direction perpendicular to the
+// line, for example the highest and lowest points of glyphs in horizontal text.
+type MinMax struct {
+	Min, Max *BaseCoord // Min and Max may be nil if they are not defined.
+	// Features contains the extents for glyphs modified by features, sorted by tag.
+	Features []FeatureMinMax
+}
+
+// FeatureMinMax contains the extents of glyphs when a feature is applied.
+type FeatureMinMax struct {
+	Tag      Tag
+	Min, Max *BaseCoord
+}
+
+// BaseCoord is the position of a baseline or extent, in font units on the
+// y axis for horizontal text and the x axis for vertical text.
+type BaseCoord struct {
+	// Format is 1 for a plain Coordinate, 2 if the position is that of
+	// a point in a glyph, or 3 if it has a Device.
+	Format     uint16
+	Coordinate int16
+	// ReferenceGlyph and BaseCoordPoint are the glyph and the index of the
+	// point in its outline whose position is used, in format 2.
+	ReferenceGlyph GlyphID
+	BaseCoordPoint uint16
+	// Device adjusts Coordinate in format 3.
+	Device *Device
+}
+
+// ErrMissingBaseline is returned when a baseline is not defined for a script.
+var ErrMissingBaseline = errors.New("baseline not found")
+
+// scriptDFLT is the script used for scripts without their own record.
+var scriptDFLT = MustNamedTag("DFLT")
+
+func parseTableBASE(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	major, minor := binary.BigEndian.Uint16(buf), binary.BigEndian.Uint16(buf[2:])
+	if major != 1 {
+		return nil, fmt.Errorf("unsupported %q version %d.%d", tag, major, minor)
+	}
+
+	table := &TableBASE{
+		baseTable: baseTable(tag),
+		bytes:     buf,
+	}
+
+	var err error
+	for i, axis := range []**BaseAxis{&table.Horizontal, &table.Vertical} {
+		offset := int(binary.BigEndian.Uint16(buf[4+2*i:]))
+		if offset == 0 {
+			continue
+		}
+		if *axis, err = parseBaseAxis(buf, offset); err != nil {
+			return nil, err
+		}
+	}
+
+	if minor >= 1 {
+		if len(buf) < 12 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if offset := int(binary.BigEndian.Uint32(buf[8:])); offset != 0 {
+			if offset >= len(buf) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if table.VarStore, err = ParseItemVariationStore(buf[offset:]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return table, nil
+}
+
+// baseBytes returns buf from a 16-bit offset in base, which must be within buf.
+func baseBytes(buf []byte, base int, offset uint16, size int) ([]byte, error) {
+	start := base + int(offset)
+	if start+size > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf[start:], nil
+}
+
+func parseBaseAxis(buf []byte, offset int) (*BaseAxis, error) {
+	if offset+4 > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	axis := &BaseAxis{}
+
+	if tagListOffset := binary.BigEndian.Uint16(buf[offset:]); tagListOffset != 0 {
+		b, err := baseBytes(buf, offset, tagListOffset, 2)
+		if err != nil {
+			return nil, err
+		}
+		count := int(binary.BigEndian.Uint16(b))
+		if 2+4*count > len(b) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		axis.BaselineTags = make([]Tag, count)
+		for i := range axis.BaselineTags {
+			axis.BaselineTags[i] = Tag{binary.BigEndian.Uint32(b[2+4*i:])}
+		}
+	}
+
+	scriptListOffset := binary.BigEndian.Uint16(buf[offset+2:])
+	if scriptListOffset == 0 {
+		return axis, nil
+	}
+	listStart := offset + int(scriptListOffset)
+	list, err := baseBytes(buf, offset, scriptListOffset, 2)
+	if err != nil {
+		return nil, err
+	}
+	count := int(binary.BigEndian.Uint16(list))
+	if 2+6*count > len(list) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	axis.Scripts = make([]BaseScript, count)
+	for i := range axis.Scripts {
+		record := list[2+6*i:]
+		script, err := parseBaseScript(buf, listStart+int(binary.BigEndian.Uint16(record[4:])), len(axis.BaselineTags))
+		if err != nil {
+			return nil, err
+		}
+		script.Tag = Tag{binary.BigEndian.Uint32(record)}
+		axis.Scripts[i] = *script
+	}
+
+	return axis, nil
+}
+
+func parseBaseScript(buf []byte, offset, numBaselines int) (*BaseScript, error) {
+	if offset+6 > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := buf[offset:]
+	script := &BaseScript{}
+
+	if valuesOffset := binary.BigEndian.Uint16(b); valuesOffset != 0 {
+		start := offset + int(valuesOffset)
+		values, err := baseBytes(buf, offset, valuesOffset, 4)
+		if err != nil {
+			return nil, err
+		}
+		count := int(binary.BigEndian.Uint16(values[2:]))
+		if count != numBaselines {
+			return nil, fmt.Errorf("script has %d baselines, but the axis has %d", count, numBaselines)
+		}
+		if 4+2*count > len(values) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		script.Values = &BaseValues{
+			DefaultIndex: binary.BigEndian.Uint16(values),
+			Coords:       make([]BaseCoord, count),
+		}
+		for i := range script.Values.Coords {
+			coord, err := parseBaseCoord(buf, start, binary.BigEndian.Uint16(values[4+2*i:]))
+			if err != nil {
+				return nil, err
+			}
+			script.Values.Coords[i] = *coord
+		}
+	}
+
+	if minMaxOffset := binary.BigEndian.Uint16(b[2:]); minMaxOffset != 0 {
+		minMax, err := parseMinMax(buf, offset+int(minMaxOffset))
+		if err != nil {
+			return nil, err
+		}
+		script.DefaultMinMax = minMax
+	}
+
+	count := int(binary.BigEndian.Uint16(b[4:]))
+	if 6+6*count > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	for i := 0; i < count; i++ {
+		record := b[6+6*i:]
+		minMax, err := parseMinMax(buf, offset+int(binary.BigEndian.Uint16(record[4:])))
+		if err != nil {
+			return nil, err
+		}
+		script.LangSys = append(script.LangSys, BaseLangSys{Tag: Tag{binary.BigEndian.Uint32(record)}, MinMax: *minMax})
+	}
+
+	return script, nil
+}
+
+func parseMinMax(buf []byte, offset int) (*MinMax, error) {
+	if offset+6 > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := buf[offset:]
+	minMax := &MinMax{}
+
+	var err error
+	if minMax.Min, err = parseOptionalBaseCoord(buf, offset, binary.BigEndian.Uint16(b)); err != nil {
+		return nil, err
+	}
+	if minMax.Max, err = parseOptionalBaseCoord(buf, offset, binary.BigEndian.Uint16(b[2:])); err != nil {
+		return nil, err
+	}
+
+	count := int(binary.BigEndian.Uint16(b[4:]))
+	if 6+8*count > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	for i := 0; i < count; i++ {
+		record := b[6+8*i:]
+		feature := FeatureMinMax{Tag: Tag{binary.BigEndian.Uint32(record)}}
+		if feature.Min, err = parseOptionalBaseCoord(buf, offset, binary.BigEndian.Uint16(record[4:])); err != nil {
+			return nil, err
+		}
+		if feature.Max, err = parseOptionalBaseCoord(buf, offset, binary.BigEndian.Uint16(record[6:])); err != nil {
+			return nil, err
+		}
+		minMax.Features = append(minMax.Features, feature)
+	}
+
+	return minMax, nil
+}
+
+func parseOptionalBaseCoord(buf []byte, base int, offset uint16) (*BaseCoord, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	return parseBaseCoord(buf, base, offset)
+}
+
+func parseBaseCoord(buf []byte, base int, offset uint16) (*BaseCoord, error) {
+	b, err := baseBytes(buf, base, offset, 4)
+	if err != nil {
+		return nil, err
+	}
+	coord := &BaseCoord{
+		Format:     binary.BigEndian.Uint16(b),
+		Coordinate: int16(binary.BigEndian.Uint16(b[2:])),
+	}
+	switch coord.Format {
+	case 1:
+	case 2:
+		if len(b) < 8 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		coord.ReferenceGlyph = GlyphID(binary.BigEndian.Uint16(b[4:]))
+		coord.BaseCoordPoint = binary.BigEndian.Uint16(b[6:])
+	case 3:
+		if len(b) < 6 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if deviceOffset := binary.BigEndian.Uint16(b[4:]); deviceOffset != 0 {
+			d, err := baseBytes(buf, base+int(offset), deviceOffset, 0)
+			if err != nil {
+				return nil, err
+			}
+			if coord.Device, err = parseDevice(d); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported BaseCoord format %d", coord.Format)
+	}
+	return coord, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableBASE) Bytes() []byte {
+	return table.bytes
+}
+
+// Axis returns the baselines for a direction of text, or nil if there are none.
+func (table *TableBASE) Axis(direction Direction) *BaseAxis {
+	if direction == DirectionVertical {
+		return table.Vertical
+	}
+	return table.Horizontal
+}
+
+// Script returns the record of a script, or of the 'DFLT' script if it has
+// none, or nil if neither is found.
+func (axis *BaseAxis) Script(script Tag) *BaseScript {
+	for _, tag := range []Tag{script, scriptDFLT} {
+		i := sort.Search(len(axis.Scripts), func(i int) bool {
+			return axis.Scripts[i].Tag.Number >= tag.Number
+		})
+		if i < len(axis.Scripts) && axis.Scripts[i].Tag == tag {
+			return &axis.Scripts[i]
+		}
+	}
+	return nil
+}
+
+// Coord returns the position of a baseline for a script, or
+// ErrMissingBaseline if it is not defined.
+func (table *TableBASE) Coord(script, baseline Tag, direction Direction) (*BaseCoord, error) {
+	axis := table.Axis(direction)
+	if axis == nil {
+		return nil, ErrMissingBaseline
+	}
+	s := axis.Script(script)
+	if s == nil || s.Values == nil {
+		return nil, ErrMissingBaseline
+	}
+	for i, tag := range axis.BaselineTags {
+		if tag == baseline {
+			return &s.Values.Coords[i], nil
+		}
+	}
+	return nil, ErrMissingBaseline
+}
+
+// Delta returns the change to a coordinate at the normalized coords, if it
+// refers to a delta in VarStore.
+func (table *TableBASE) Delta(coord *BaseCoord, coords []F2Dot14) float64 {
+	if table.VarStore == nil || coord.Device == nil || !coord.Device.Variation {
+		return 0
+	}
+	return table.VarStore.Delta(coord.Device.VarIndex, coords)
+}
+
+// BASETable returns the table corresponding to the 'BASE' tag.
+func (font *Font) BASETable() (*TableBASE, error) {
+	t, err := font.Table(TagBASE)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableBASE), nil
+}
+
+// Baseline returns the position of a baseline, such as BaselineRoman, for
+// a script in the default instance of the font, in font units on the y axis for
+// horizontal text or the x axis for vertical text. Scripts without
+// baselines in 'BASE' use those of the 'DFLT' script. It returns
+// ErrMissingBaseline if the baseline is not defined.
+func (font *Font) Baseline(script, baseline Tag, direction Direction) (float64, error) {
+	base, err := font.BASETable()
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q: %w", TagBASE, err)
+	}
+	coord, err := base.Coord(script, baseline, direction)
+	if err != nil {
+		return 0, err
+	}
+	if coord.Format != 2 || !font.HasTable(TagGlyf) {
+		return float64(coord.Coordinate), nil
+	}
+
+	outline, err := font.GlyphOutline(coord.ReferenceGlyph, nil)
+	if err != nil {
+		return 0, err
+	}
+	if int(coord.BaseCoordPoint) >= len(outline.Points) {
+		return 0, fmt.Errorf("point %d out of range in glyph %d", coord.BaseCoordPoint, coord.ReferenceGlyph)
+	}
+	point := outline.Points[coord.BaseCoordPoint]
+	if direction == DirectionVertical {
+		return point.X, nil
+	}
+	return point.Y, nil
+}
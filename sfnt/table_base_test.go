@@ -0,0 +1,115 @@
+package sfnt
+
+import (
+	"errors"
+	"testing"
+)
+
+// testBASE returns a 'BASE' table with 'ideo' and 'romn' baselines for
+// horizontal text in the 'DFLT' and 'latn' scripts, and an 'ideo' baseline
+// for vertical text.
+func testBASE() []byte {
+	tag := func(s string) []byte { return []byte(s) }
+
+	var buf []byte
+	for _, b := range [][]byte{
+		u16s(1, 0, 8, 126), // version 1.0, horizontal and vertical axes
+
+		// 8: horizontal axis, tag list and script list
+		u16s(4, 14),
+		u16s(2), tag("ideo"), tag("romn"),
+		u16s(2), tag("DFLT"), u16s(14), tag("latn"), u16s(46),
+
+		// 36: DFLT script with BaseValues at 42
+		u16s(6, 0, 0),
+		u16s(1, 2, 8, 22),
+		u16s(3, 0x10000-120, 6), // 50: format 3 with a device at 56
+		u16s(12, 13, 2, 0xF200), // 56: deltas -1 and 2 at 12 and 13 ppem
+		u16s(1, 0),              // 64
+
+		// 68: latn script with BaseValues at 80, MinMax at 100 and a 'TRK ' language
+		u16s(12, 32, 1), tag("TRK "), u16s(32),
+		u16s(1, 2, 8, 16),
+		u16s(2, 5, 0, 1), // 88: point 1 of glyph 0
+		u16s(1, 10),      // 96
+
+		// 100: MinMax with the extents of 'smcp'
+		u16s(14, 18, 1), tag("smcp"), u16s(0, 22),
+		u16s(1, 0x10000-200), u16s(1, 800), u16s(1, 900),
+
+		// 126: vertical axis
+		u16s(4, 10),
+		u16s(1), tag("ideo"),
+		u16s(1), tag("DFLT"), u16s(8),
+		u16s(6, 0, 0),
+		u16s(0, 1, 6),
+		u16s(3, 500, 0),
+	} {
+		buf = append(buf, b...)
+	}
+	return buf
+}
+
+func TestBASE(t *testing.T) {
+	table, err := ParseTable(TagBASE, testBASE())
+	if err != nil {
+		t.Fatalf("ParseTable(%q) err = %q, want nil", TagBASE, err)
+	}
+	base := table.(*TableBASE)
+
+	font := testGvarFont(t)
+	font.AddTable(TagBASE, base)
+
+	latn, cyrl := MustNamedTag("latn"), MustNamedTag("cyrl")
+	tests := []struct {
+		script, baseline Tag
+		direction        Direction
+		want             float64
+	}{
+		{latn, BaselineRoman, DirectionHorizontal, 10},
+		{latn, BaselineIdeoEmBottom, DirectionHorizontal, 100},
+		{cyrl, BaselineIdeoEmBottom, DirectionHorizontal, -120},
+		{cyrl, BaselineRoman, DirectionHorizontal, 0},
+		{latn, BaselineIdeoEmBottom, DirectionVertical, 500},
+	}
+	for _, test := range tests {
+		got, err := font.Baseline(test.script, test.baseline, test.direction)
+		if err != nil || got != test.want {
+			t.Errorf("Baseline(%q, %q, %d) = %v, %v, want %v", test.script, test.baseline, test.direction, got, err, test.want)
+		}
+	}
+	for _, test := range []struct {
+		baseline  Tag
+		direction Direction
+	}{{BaselineHanging, DirectionHorizontal}, {BaselineRoman, DirectionVertical}} {
+		if _, err := font.Baseline(latn, test.baseline, test.direction); !errors.Is(err, ErrMissingBaseline) {
+			t.Errorf("Baseline(%q, %q, %d) err = %v, want %v", latn, test.baseline, test.direction, err, ErrMissingBaseline)
+		}
+	}
+
+	coord, err := base.Coord(cyrl, BaselineIdeoEmBottom, DirectionHorizontal)
+	if err != nil {
+		t.Fatalf("Coord() err = %q, want nil", err)
+	}
+	for ppem, want := range map[int]int{11: 0, 12: -1, 13: 2, 14: 0} {
+		if got := coord.Device.PixelDelta(ppem); got != want {
+			t.Errorf("PixelDelta(%d) = %d, want %d", ppem, got, want)
+		}
+	}
+
+	script := base.Horizontal.Script(latn)
+	if script.Values.DefaultIndex != 1 || len(script.LangSys) != 1 || script.LangSys[0].Tag != MustNamedTag("TRK ") {
+		t.Errorf("Script(%q) = %+v, want default 'romn' and a 'TRK ' language", latn, script)
+	}
+	minMax := script.DefaultMinMax
+	if minMax.Min.Coordinate != -200 || minMax.Max.Coordinate != 800 {
+		t.Errorf("DefaultMinMax = %d to %d, want -200 to 800", minMax.Min.Coordinate, minMax.Max.Coordinate)
+	}
+	if f := minMax.Features; len(f) != 1 || f[0].Min != nil || f[0].Max.Coordinate != 900 {
+		t.Errorf("DefaultMinMax.Features = %+v, want 'smcp' with max 900", f)
+	}
+
+	if _, err := ParseTable(TagBASE, testBASE()[:120]); err == nil {
+		t.Errorf("ParseTable(%q) of truncated table err = nil, want error", TagBASE)
+	}
+}
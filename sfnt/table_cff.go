@@ -0,0 +1,804 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TableCFF contains PostScript outlines in the Compact Font Format. It is
+// parsed by Font.CFFTable, and written with its offsets recomputed, so that
+// charstrings and subroutines can be changed.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cff
+type TableCFF struct {
+	baseTable
+
+	// FontName is the PostScript name of the font.
+	FontName []byte
+	// Strings contains the strings referred to by string IDs from 391.
+	Strings [][]byte
+	// GlobalSubrs contains the subroutines shared by every charstring.
+	GlobalSubrs [][]byte
+	// CharStrings contains the Type 2 charstring of each glyph.
+	CharStrings [][]byte
+	// Charset contains the string ID of the name of each glyph, or its CID
+	// in CID-keyed fonts.
+	Charset []uint16
+	// FontDicts contains the private data of the font, or of each font in
+	// the FDArray of a CID-keyed font.
+	FontDicts []CFFFontDict
+	// FDSelect contains the index in FontDicts used by each glyph of a
+	// CID-keyed font, and is nil otherwise.
+	FDSelect []uint8
+
+	topDict cffDict
+	// encoding is a custom encoding, which is copied as it is. It is nil if
+	// the font uses a predefined encoding.
+	encoding []byte
+	// options contains the limits of the font the table was parsed from.
+	options *Options
+}
+
+// CFFFontDict contains the private data used by some of the glyphs of a CFF font.
+type CFFFontDict struct {
+	// Subrs contains the local subroutines.
+	Subrs [][]byte
+
+	dict    cffDict // dict is the Font DICT of a CID-keyed font.
+	private cffDict
+}
+
+// Operators of the DICT data in the CFF table. Two-byte operators are
+// 1200 plus their second byte.
+const (
+	cffOpBlueValues       = 6
+	cffOpOtherBlues       = 7
+	cffOpFamilyBlues      = 8
+	cffOpFamilyOtherBlues = 9
+	cffOpStdHW            = 10
+	cffOpStdVW            = 11
+	cffOpUniqueID         = 13
+	cffOpXUID             = 14
+	cffOpCharset          = 15
+	cffOpEncoding         = 16
+	cffOpCharStrings      = 17
+	cffOpPrivate          = 18
+	cffOpSubrs            = 19
+	cffOpFontMatrix       = 1207
+	cffOpBlueScale        = 1209
+	cffOpBlueShift        = 1210
+	cffOpBlueFuzz         = 1211
+	cffOpStemSnapH        = 1212
+	cffOpStemSnapV        = 1213
+	cffOpForceBold        = 1214
+	cffOpLanguageGroup    = 1217
+	cffOpExpansionFactor  = 1218
+	cffOpROS              = 1230
+	cffOpCIDFontVersion   = 1231
+	cffOpCIDFontRevision  = 1232
+	cffOpCIDFontType      = 1233
+	cffOpCIDCount         = 1234
+	cffOpUIDBase          = 1235
+	cffOpFDArray          = 1236
+	cffOpFDSelect         = 1237
+	cffOpFontName         = 1238
+)
+
+// checkCallDepth returns an error if subroutine calls are nested deeper
+// than depth allows.
+func (table *TableCFF) checkCallDepth(depth int) error {
+	if depth > maxCharStringDepth {
+		return errCharStringDepth
+	}
+	return table.options.checkNestingDepth(depth)
+}
+
+// IsCIDKeyed returns true if the glyphs are identified by CIDs, and use the
+// font dict selected by FDSelect.
+func (table *TableCFF) IsCIDKeyed() bool {
+	return table.topDict.has(cffOpROS)
+}
+
+// Subrs returns the local subroutines used by the charstring of a glyph.
+func (table *TableCFF) Subrs(glyph GlyphID) [][]byte {
+	fd := 0
+	if int(glyph) < len(table.FDSelect) {
+		fd = int(table.FDSelect[glyph])
+	}
+	if fd >= len(table.FontDicts) {
+		return nil
+	}
+	return table.FontDicts[fd].Subrs
+}
+
+// CFFSubrBias returns the number added to the operand of callsubr and
+// callgsubr to get the index of the subroutine, for a list of count subroutines.
+func CFFSubrBias(count int) int {
+	switch {
+	case count < 1240:
+		return 107
+	case count < 33900:
+		return 1131
+	default:
+		return 32768
+	}
+}
+
+func parseTableCFF(tag Tag, buf []byte) (*TableCFF, error) {
+	if len(buf) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if buf[0] != 1 {
+		return nil, fmt.Errorf("unsupported major version %d", buf[0])
+	}
+	table := &TableCFF{baseTable: baseTable(tag)}
+
+	names, offset, err := parseCFFIndex(buf, int(buf[2]))
+	if err != nil {
+		return nil, fmt.Errorf("name index: %w", err)
+	}
+	topDicts, offset, err := parseCFFIndex(buf, offset)
+	if err != nil {
+		return nil, fmt.Errorf("top dict index: %w", err)
+	}
+	if len(names) == 0 || len(topDicts) == 0 {
+		return nil, errors.New("no font")
+	}
+	if table.Strings, offset, err = parseCFFIndex(buf, offset); err != nil {
+		return nil, fmt.Errorf("string index: %w", err)
+	}
+	if table.GlobalSubrs, _, err = parseCFFIndex(buf, offset); err != nil {
+		return nil, fmt.Errorf("global subrs: %w", err)
+	}
+	table.FontName = names[0]
+	if table.topDict, err = parseCFFDict(topDicts[0]); err != nil {
+		return nil, fmt.Errorf("top dict: %w", err)
+	}
+
+	offset, err = table.topDict.offset(cffOpCharStrings, len(buf))
+	if err != nil {
+		return nil, err
+	}
+	if table.CharStrings, _, err = parseCFFIndex(buf, offset); err != nil {
+		return nil, fmt.Errorf("charstrings: %w", err)
+	}
+	numGlyphs := len(table.CharStrings)
+	if numGlyphs == 0 {
+		return nil, errors.New("no charstrings")
+	}
+
+	if table.Charset, err = parseCFFCharset(buf, table.topDict, numGlyphs); err != nil {
+		return nil, fmt.Errorf("charset: %w", err)
+	}
+	if table.encoding, err = parseCFFEncoding(buf, table.topDict); err != nil {
+		return nil, fmt.Errorf("encoding: %w", err)
+	}
+
+	if !table.IsCIDKeyed() {
+		fd, err := parseCFFPrivate(buf, nil, table.topDict, parseCFFIndex)
+		if err != nil {
+			return nil, err
+		}
+		table.FontDicts = []CFFFontDict{fd}
+		return table, nil
+	}
+
+	offset, err = table.topDict.offset(cffOpFDArray, len(buf))
+	if err != nil {
+		return nil, err
+	}
+	fontDicts, _, err := parseCFFIndex(buf, offset)
+	if err != nil {
+		return nil, fmt.Errorf("font dict index: %w", err)
+	}
+	for i, b := range fontDicts {
+		dict, err := parseCFFDict(b)
+		if err != nil {
+			return nil, fmt.Errorf("font dict %d: %w", i, err)
+		}
+		fd, err := parseCFFPrivate(buf, dict, dict, parseCFFIndex)
+		if err != nil {
+			return nil, fmt.Errorf("font dict %d: %w", i, err)
+		}
+		table.FontDicts = append(table.FontDicts, fd)
+	}
+	if table.FDSelect, err = parseCFFFDSelect(buf, table.topDict, numGlyphs, len(table.FontDicts)); err != nil {
+		return nil, fmt.Errorf("fd select: %w", err)
+	}
+	return table, nil
+}
+
+// parseCFFPrivate parses the Private DICT and local subroutines referred to
+// by the Private operator in parent, which is the Top DICT or a Font DICT.
+// parseIndex parses the INDEX of the subroutines.
+func parseCFFPrivate(buf []byte, dict, parent cffDict, parseIndex func([]byte, int) ([][]byte, int, error)) (CFFFontDict, error) {
+	fd := CFFFontDict{dict: dict}
+	operands := parent.get(cffOpPrivate)
+	if operands == nil {
+		return fd, nil
+	}
+	if len(operands) != 2 {
+		return fd, errors.New("invalid private dict operands")
+	}
+	size, offset := int(operands[0]), int(operands[1])
+	if size < 0 || offset < 0 || offset+size > len(buf) {
+		return fd, fmt.Errorf("private dict at %d: %w", offset, io.ErrUnexpectedEOF)
+	}
+
+	var err error
+	if fd.private, err = parseCFFDict(buf[offset : offset+size]); err != nil {
+		return fd, fmt.Errorf("private dict: %w", err)
+	}
+	if !fd.private.has(cffOpSubrs) {
+		return fd, nil
+	}
+	subrs, err := fd.private.offset(cffOpSubrs, len(buf)-offset)
+	if err != nil {
+		return fd, err
+	}
+	if fd.Subrs, _, err = parseIndex(buf, offset+subrs); err != nil {
+		return fd, fmt.Errorf("local subrs: %w", err)
+	}
+	return fd, nil
+}
+
+func parseCFFCharset(buf []byte, topDict cffDict, numGlyphs int) ([]uint16, error) {
+	charset := make([]uint16, numGlyphs)
+	offset := 0
+	if operands := topDict.get(cffOpCharset); len(operands) == 1 {
+		offset = int(operands[0])
+	}
+	switch offset {
+	case 0: // ISOAdobe, in which the string IDs are the glyph IDs.
+		for i := range charset {
+			charset[i] = uint16(i)
+		}
+		return charset, nil
+	case 1, 2:
+		return nil, errors.New("expert charsets are not supported")
+	}
+	if offset >= len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	format := buf[offset]
+	r := buf[offset+1:]
+	for i := 1; i < numGlyphs; {
+		switch format {
+		case 0:
+			if len(r) < 2 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			charset[i] = binary.BigEndian.Uint16(r)
+			r = r[2:]
+			i++
+		case 1, 2:
+			size := 3 + int(format-1)
+			if len(r) < size {
+				return nil, io.ErrUnexpectedEOF
+			}
+			first, left := binary.BigEndian.Uint16(r), int(r[2])
+			if format == 2 {
+				left = int(binary.BigEndian.Uint16(r[2:]))
+			}
+			r = r[size:]
+			for j := 0; j <= left && i < numGlyphs; j++ {
+				charset[i] = first + uint16(j)
+				i++
+			}
+		default:
+			return nil, fmt.Errorf("unsupported format %d", format)
+		}
+	}
+	return charset, nil
+}
+
+// parseCFFEncoding returns the bytes of a custom encoding, or nil if the
+// font uses a predefined encoding.
+func parseCFFEncoding(buf []byte, topDict cffDict) ([]byte, error) {
+	operands := topDict.get(cffOpEncoding)
+	if len(operands) != 1 || operands[0] <= 1 {
+		return nil, nil
+	}
+	offset := int(operands[0])
+	if offset+2 > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	format := buf[offset]
+	size := 2
+	switch format & 0x7F {
+	case 0:
+		size += int(buf[offset+1])
+	case 1:
+		size += 2 * int(buf[offset+1])
+	default:
+		return nil, fmt.Errorf("unsupported format %d", format)
+	}
+	if format&0x80 != 0 {
+		if offset+size >= len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		size += 1 + 3*int(buf[offset+size])
+	}
+	if offset+size > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf[offset : offset+size], nil
+}
+
+func parseCFFFDSelect(buf []byte, topDict cffDict, numGlyphs, numFontDicts int) ([]uint8, error) {
+	offset, err := topDict.offset(cffOpFDSelect, len(buf))
+	if err != nil {
+		return nil, err
+	}
+	fdSelect := make([]uint8, numGlyphs)
+	r := buf[offset:]
+	switch r[0] {
+	case 0:
+		if len(r) < 1+numGlyphs {
+			return nil, io.ErrUnexpectedEOF
+		}
+		copy(fdSelect, r[1:])
+	case 3:
+		if len(r) < 3 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		numRanges := int(binary.BigEndian.Uint16(r[1:]))
+		if len(r) < 5+3*numRanges {
+			return nil, io.ErrUnexpectedEOF
+		}
+		r = r[3:]
+		for i := 0; i < numRanges; i++ {
+			first, fd := int(binary.BigEndian.Uint16(r[3*i:])), r[3*i+2]
+			end := int(binary.BigEndian.Uint16(r[3*i+3:]))
+			if first > end || end > numGlyphs {
+				return nil, fmt.Errorf("invalid range %d-%d", first, end)
+			}
+			for g := first; g < end; g++ {
+				fdSelect[g] = fd
+			}
+		}
+	case 4: // Only used in the CFF2 table.
+		if len(r) < 5 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		numRanges := int(binary.BigEndian.Uint32(r[1:]))
+		if numRanges > (len(r)-9)/6 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		r = r[5:]
+		for i := 0; i < numRanges; i++ {
+			first, fd := int(binary.BigEndian.Uint32(r[6*i:])), binary.BigEndian.Uint16(r[6*i+4:])
+			end := int(binary.BigEndian.Uint32(r[6*i+6:]))
+			if first > end || end > numGlyphs {
+				return nil, fmt.Errorf("invalid range %d-%d", first, end)
+			}
+			if int(fd) >= numFontDicts {
+				return nil, fmt.Errorf("font dict %d out of range", fd)
+			}
+			for g := first; g < end; g++ {
+				fdSelect[g] = uint8(fd)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %d", r[0])
+	}
+	for g, fd := range fdSelect {
+		if int(fd) >= numFontDicts {
+			return nil, fmt.Errorf("glyph %d: font dict %d out of range", g, fd)
+		}
+	}
+	return fdSelect, nil
+}
+
+// parseCFFIndex parses the INDEX at offset, returning its items and the
+// offset of the end of the INDEX.
+func parseCFFIndex(buf []byte, offset int) ([][]byte, int, error) {
+	return parseIndexWithCount(buf, offset, 2)
+}
+
+// parseCFF2Index parses an INDEX of the CFF2 table, whose count has 32 bits.
+func parseCFF2Index(buf []byte, offset int) ([][]byte, int, error) {
+	return parseIndexWithCount(buf, offset, 4)
+}
+
+// parseIndexWithCount parses an INDEX whose count has countSize bytes.
+func parseIndexWithCount(buf []byte, offset, countSize int) ([][]byte, int, error) {
+	if offset < 0 || offset+countSize > len(buf) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	count := 0
+	for _, b := range buf[offset : offset+countSize] {
+		count = count<<8 | int(b)
+	}
+	offset += countSize
+	if count == 0 {
+		return nil, offset, nil
+	}
+	if offset+1 > len(buf) {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	offSize := int(buf[offset])
+	if offSize < 1 || offSize > 4 {
+		return nil, 0, fmt.Errorf("invalid offset size %d", offSize)
+	}
+	offsets := buf[offset+1:]
+	if count > len(offsets)/offSize {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	if len(offsets) < (count+1)*offSize {
+		return nil, 0, io.ErrUnexpectedEOF
+	}
+	readOffset := func(i int) int {
+		v := 0
+		for _, b := range offsets[i*offSize : (i+1)*offSize] {
+			v = v<<8 | int(b)
+		}
+		return v
+	}
+
+	// Offsets are relative to the byte before the data.
+	data := offset + 1 + (count+1)*offSize - 1
+	items := make([][]byte, count)
+	for i := range items {
+		start, end := readOffset(i), readOffset(i+1)
+		if start < 1 || start > end || data+end > len(buf) {
+			return nil, 0, fmt.Errorf("item %d: invalid offsets %d-%d", i, start, end)
+		}
+		items[i] = buf[data+start : data+end]
+	}
+	return items, data + readOffset(count), nil
+}
+
+// appendCFFIndex appends an INDEX containing items to buf.
+func appendCFFIndex(buf []byte, items [][]byte) []byte {
+	return appendIndexWithCount(appendUint16(buf, uint16(len(items))), items)
+}
+
+// appendCFF2Index appends an INDEX of the CFF2 table, whose count has 32
+// bits, to buf.
+func appendCFF2Index(buf []byte, items [][]byte) []byte {
+	return appendIndexWithCount(appendUint32(buf, uint32(len(items))), items)
+}
+
+// appendIndexWithCount appends the offsets and data of an INDEX to buf,
+// which ends with its count.
+func appendIndexWithCount(buf []byte, items [][]byte) []byte {
+	if len(items) == 0 {
+		return buf
+	}
+	size := 1
+	for _, item := range items {
+		size += len(item)
+	}
+	offSize := 1
+	for size >= 1<<(8*offSize) {
+		offSize++
+	}
+	buf = append(buf, byte(offSize))
+
+	offset := 1
+	appendOffset := func() {
+		for i := offSize - 1; i >= 0; i-- {
+			buf = append(buf, byte(offset>>(8*i)))
+		}
+	}
+	appendOffset()
+	for _, item := range items {
+		offset += len(item)
+		appendOffset()
+	}
+	for _, item := range items {
+		buf = append(buf, item...)
+	}
+	return buf
+}
+
+// cffDict is the DICT data of the CFF table, as a list of operators with
+// their operands.
+type cffDict []cffDictEntry
+
+type cffDictEntry struct {
+	op int
+	// operands contains the encoding of each operand, so that real numbers
+	// are written as they were read.
+	operands [][]byte
+}
+
+func parseCFFDict(buf []byte) (cffDict, error) {
+	var dict cffDict
+	var operands [][]byte
+	for len(buf) > 0 {
+		b0 := buf[0]
+		size := 1
+		switch {
+		case b0 <= 27:
+			op := int(b0)
+			if b0 == 12 {
+				if len(buf) < 2 {
+					return nil, io.ErrUnexpectedEOF
+				}
+				op, size = 1200+int(buf[1]), 2
+			}
+			dict = append(dict, cffDictEntry{op: op, operands: operands})
+			operands = nil
+			buf = buf[size:]
+			continue
+		case b0 == 28:
+			size = 3
+		case b0 == 29:
+			size = 5
+		case b0 == 30:
+			size = 1
+			for size < len(buf) && buf[size]&0x0F != 0x0F && buf[size]&0xF0 != 0xF0 {
+				size++
+			}
+			size++
+		case b0 >= 32 && b0 <= 246:
+		case b0 >= 247 && b0 <= 254:
+			size = 2
+		default:
+			return nil, fmt.Errorf("invalid operand byte %d", b0)
+		}
+		if len(buf) < size {
+			return nil, io.ErrUnexpectedEOF
+		}
+		operands = append(operands, buf[:size])
+		buf = buf[size:]
+	}
+	if len(operands) > 0 {
+		return nil, errors.New("operands without an operator")
+	}
+	return dict, nil
+}
+
+// cffDictInt returns the value of an integer operand, or 0 for a real number.
+func cffDictInt(b []byte) int32 {
+	switch b0 := b[0]; {
+	case b0 == 28:
+		return int32(int16(binary.BigEndian.Uint16(b[1:])))
+	case b0 == 29:
+		return int32(binary.BigEndian.Uint32(b[1:]))
+	case b0 >= 32 && b0 <= 246:
+		return int32(b0) - 139
+	case b0 >= 247 && b0 <= 250:
+		return (int32(b0)-247)*256 + int32(b[1]) + 108
+	case b0 >= 251 && b0 <= 254:
+		return -(int32(b0)-251)*256 - int32(b[1]) - 108
+	}
+	return 0
+}
+
+func (dict cffDict) has(op int) bool {
+	for _, e := range dict {
+		if e.op == op {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the integer values of the operands of op, or nil if the dict
+// doesn't contain op.
+func (dict cffDict) get(op int) []int32 {
+	for _, e := range dict {
+		if e.op != op {
+			continue
+		}
+		values := make([]int32, len(e.operands))
+		for i, b := range e.operands {
+			values[i] = cffDictInt(b)
+		}
+		return values
+	}
+	return nil
+}
+
+// offset returns the single operand of op, checking that it is an offset
+// into a table of the given size.
+func (dict cffDict) offset(op int, size int) (int, error) {
+	operands := dict.get(op)
+	if len(operands) != 1 {
+		return 0, fmt.Errorf("missing or invalid operator %d", op)
+	}
+	if operands[0] < 0 || int(operands[0]) >= size {
+		return 0, fmt.Errorf("operator %d: offset %d out of range", op, operands[0])
+	}
+	return int(operands[0]), nil
+}
+
+// set returns a copy of dict with the operands of op set to values. The
+// values are encoded in 5 bytes, so the size of the dict doesn't depend on
+// them.
+func (dict cffDict) set(op int, values ...int32) cffDict {
+	operands := make([][]byte, len(values))
+	for i, v := range values {
+		operands[i] = []byte{29, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	}
+	out := make(cffDict, 0, len(dict)+1)
+	found := false
+	for _, e := range dict {
+		if e.op == op {
+			e.operands, found = operands, true
+		}
+		out = append(out, e)
+	}
+	if !found {
+		out = append(out, cffDictEntry{op: op, operands: operands})
+	}
+	return out
+}
+
+// remove returns a copy of dict without the given operators.
+func (dict cffDict) remove(ops ...int) cffDict {
+	var out cffDict
+	for _, e := range dict {
+		removed := false
+		for _, op := range ops {
+			removed = removed || e.op == op
+		}
+		if !removed {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (dict cffDict) bytes() []byte {
+	var buf []byte
+	for _, e := range dict {
+		for _, b := range e.operands {
+			buf = append(buf, b...)
+		}
+		if e.op >= 1200 {
+			buf = append(buf, 12, byte(e.op-1200))
+		} else {
+			buf = append(buf, byte(e.op))
+		}
+	}
+	return buf
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableCFF) Bytes() []byte {
+	topDict := table.topDict.remove(cffOpEncoding).set(cffOpCharset, 0).set(cffOpCharStrings, 0)
+	if table.encoding != nil {
+		topDict = topDict.set(cffOpEncoding, 0)
+	} else if operands := table.topDict.get(cffOpEncoding); len(operands) == 1 && operands[0] <= 1 {
+		topDict = topDict.set(cffOpEncoding, operands[0])
+	}
+	fontDicts := make([]cffDict, len(table.FontDicts))
+	privates := make([]cffDict, len(table.FontDicts))
+	for i, fd := range table.FontDicts {
+		privates[i] = fd.private.remove(cffOpSubrs)
+		if len(fd.Subrs) > 0 {
+			privates[i] = privates[i].set(cffOpSubrs, 0)
+			privates[i] = privates[i].set(cffOpSubrs, int32(len(privates[i].bytes())))
+		}
+		if table.IsCIDKeyed() {
+			fontDicts[i] = fd.dict.set(cffOpPrivate, 0, 0)
+		}
+	}
+	if table.IsCIDKeyed() {
+		topDict = topDict.remove(cffOpPrivate).set(cffOpFDSelect, 0).set(cffOpFDArray, 0)
+	} else {
+		topDict = topDict.set(cffOpPrivate, 0, 0)
+	}
+
+	// Offsets in the dicts are encoded in 5 bytes whatever their value, so
+	// the first pass sets the offsets without changing the layout, and the
+	// second writes them.
+	layout := func() []byte {
+		buf := []byte{1, 0, 4, 4}
+		buf = appendCFFIndex(buf, [][]byte{table.FontName})
+		buf = appendCFFIndex(buf, [][]byte{topDict.bytes()})
+		buf = appendCFFIndex(buf, table.Strings)
+		buf = appendCFFIndex(buf, table.GlobalSubrs)
+
+		topDict = topDict.set(cffOpCharset, int32(len(buf)))
+		buf = appendCFFCharset(buf, table.Charset)
+		if table.encoding != nil {
+			topDict = topDict.set(cffOpEncoding, int32(len(buf)))
+			buf = append(buf, table.encoding...)
+		}
+		if table.IsCIDKeyed() {
+			topDict = topDict.set(cffOpFDSelect, int32(len(buf)))
+			buf = appendCFFFDSelect(buf, table.FDSelect)
+		}
+		topDict = topDict.set(cffOpCharStrings, int32(len(buf)))
+		buf = appendCFFIndex(buf, table.CharStrings)
+
+		fdArray := len(buf)
+		if table.IsCIDKeyed() {
+			topDict = topDict.set(cffOpFDArray, int32(fdArray))
+			items := make([][]byte, len(fontDicts))
+			for i, dict := range fontDicts {
+				items[i] = dict.bytes()
+			}
+			buf = appendCFFIndex(buf, items)
+		}
+		for i, private := range privates {
+			offset := len(buf)
+			b := private.bytes()
+			if table.IsCIDKeyed() {
+				fontDicts[i] = fontDicts[i].set(cffOpPrivate, int32(len(b)), int32(offset))
+			} else {
+				topDict = topDict.set(cffOpPrivate, int32(len(b)), int32(offset))
+			}
+			buf = append(buf, b...)
+			buf = appendCFFIndex(buf, table.FontDicts[i].Subrs)
+		}
+		return buf
+	}
+
+	layout()
+	return layout()
+}
+
+func appendCFFCharset(buf []byte, charset []uint16) []byte {
+	// Use ranges if there are fewer of them than glyphs.
+	var ranges [][2]uint16 // The first string ID and number left of each range.
+	for i := 1; i < len(charset); i++ {
+		if n := len(ranges); n > 0 && charset[i] == ranges[n-1][0]+ranges[n-1][1]+1 && ranges[n-1][1] < 0xFFFF {
+			ranges[n-1][1]++
+		} else {
+			ranges = append(ranges, [2]uint16{charset[i], 0})
+		}
+	}
+	if 4*len(ranges) < 2*(len(charset)-1) {
+		buf = append(buf, 2)
+		for _, r := range ranges {
+			buf = appendUint16(buf, r[0])
+			buf = appendUint16(buf, r[1])
+		}
+		return buf
+	}
+	buf = append(buf, 0)
+	for _, sid := range charset[1:] {
+		buf = appendUint16(buf, sid)
+	}
+	return buf
+}
+
+func appendCFFFDSelect(buf []byte, fdSelect []uint8) []byte {
+	var starts []int
+	for g, fd := range fdSelect {
+		if g == 0 || fd != fdSelect[g-1] {
+			starts = append(starts, g)
+		}
+	}
+	if 3*len(starts)+4 >= len(fdSelect) {
+		buf = append(buf, 0)
+		return append(buf, fdSelect...)
+	}
+	buf = append(buf, 3)
+	buf = appendUint16(buf, uint16(len(starts)))
+	for _, g := range starts {
+		buf = appendUint16(buf, uint16(g))
+		buf = append(buf, fdSelect[g])
+	}
+	return appendUint16(buf, uint16(len(fdSelect)))
+}
+
+// CFFTable parses the 'CFF ' table. The table is parsed each time, so changes
+// to it are only written once it has been added to the font with AddTable.
+func (font *Font) CFFTable() (*TableCFF, error) {
+	s, found := font.tables[TagCFF]
+	if !found {
+		return nil, ErrMissingTable
+	}
+	if t, ok := s.table.(*TableCFF); ok {
+		return t, nil
+	}
+	buf, err := font.tableBytes(s)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseTableCFF(TagCFF, buf)
+	if err != nil {
+		return nil, err
+	}
+	t.options = font.options
+	return t, nil
+}
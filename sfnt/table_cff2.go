@@ -0,0 +1,235 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TableCFF2 contains PostScript outlines in version 2 of the Compact Font
+// Format, whose charstrings and Private DICTs may blend values with the
+// variations in VarStore. It is parsed by Font.CFF2Table, and written with
+// its offsets recomputed, so that charstrings and subroutines can be changed.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cff2
+type TableCFF2 struct {
+	baseTable
+
+	// GlobalSubrs contains the subroutines shared by every charstring.
+	GlobalSubrs [][]byte
+	// CharStrings contains the charstring of each glyph.
+	CharStrings [][]byte
+	// FontDicts contains the private data of each font in the FDArray.
+	FontDicts []CFFFontDict
+	// FDSelect contains the index in FontDicts used by each glyph, and is
+	// nil if there is a single font dict.
+	FDSelect []uint8
+	// VarStore contains the variations of the blended values, and is nil
+	// for fonts without variations.
+	VarStore *ItemVariationStore
+
+	topDict cffDict
+	// options contains the limits of the font the table was parsed from.
+	options *Options
+}
+
+// Operators of the DICT data in the CFF2 table.
+const (
+	cff2OpVsindex = 22
+	cff2OpBlend   = 23
+	cff2OpVstore  = 24
+)
+
+// checkCallDepth returns an error if subroutine calls are nested deeper
+// than depth allows.
+func (table *TableCFF2) checkCallDepth(depth int) error {
+	if depth > maxCharStringDepth {
+		return errCharStringDepth
+	}
+	return table.options.checkNestingDepth(depth)
+}
+
+// fontDict returns the index in FontDicts used by a glyph.
+func (table *TableCFF2) fontDict(glyph GlyphID) int {
+	if int(glyph) < len(table.FDSelect) {
+		return int(table.FDSelect[glyph])
+	}
+	return 0
+}
+
+// Subrs returns the local subroutines used by the charstring of a glyph.
+func (table *TableCFF2) Subrs(glyph GlyphID) [][]byte {
+	fd := table.fontDict(glyph)
+	if fd >= len(table.FontDicts) {
+		return nil
+	}
+	return table.FontDicts[fd].Subrs
+}
+
+func parseTableCFF2(tag Tag, buf []byte) (*TableCFF2, error) {
+	if len(buf) < 5 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if buf[0] != 2 {
+		return nil, fmt.Errorf("unsupported major version %d", buf[0])
+	}
+	table := &TableCFF2{baseTable: baseTable(tag)}
+
+	headerSize, topDictLength := int(buf[2]), int(binary.BigEndian.Uint16(buf[3:]))
+	if headerSize+topDictLength > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	var err error
+	if table.topDict, err = parseCFFDict(buf[headerSize : headerSize+topDictLength]); err != nil {
+		return nil, fmt.Errorf("top dict: %w", err)
+	}
+	if table.GlobalSubrs, _, err = parseCFF2Index(buf, headerSize+topDictLength); err != nil {
+		return nil, fmt.Errorf("global subrs: %w", err)
+	}
+
+	offset, err := table.topDict.offset(cffOpCharStrings, len(buf))
+	if err != nil {
+		return nil, err
+	}
+	if table.CharStrings, _, err = parseCFF2Index(buf, offset); err != nil {
+		return nil, fmt.Errorf("charstrings: %w", err)
+	}
+	numGlyphs := len(table.CharStrings)
+	if numGlyphs == 0 {
+		return nil, errors.New("no charstrings")
+	}
+
+	if table.topDict.has(cff2OpVstore) {
+		offset, err := table.topDict.offset(cff2OpVstore, len(buf))
+		if err != nil {
+			return nil, err
+		}
+		if offset+2 > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		length := int(binary.BigEndian.Uint16(buf[offset:]))
+		if offset+2+length > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if table.VarStore, err = ParseItemVariationStore(buf[offset+2 : offset+2+length]); err != nil {
+			return nil, fmt.Errorf("variation store: %w", err)
+		}
+	}
+
+	offset, err = table.topDict.offset(cffOpFDArray, len(buf))
+	if err != nil {
+		return nil, err
+	}
+	fontDicts, _, err := parseCFF2Index(buf, offset)
+	if err != nil {
+		return nil, fmt.Errorf("font dict index: %w", err)
+	}
+	if len(fontDicts) == 0 {
+		return nil, errors.New("no font dict")
+	}
+	for i, b := range fontDicts {
+		dict, err := parseCFFDict(b)
+		if err != nil {
+			return nil, fmt.Errorf("font dict %d: %w", i, err)
+		}
+		fd, err := parseCFFPrivate(buf, dict, dict, parseCFF2Index)
+		if err != nil {
+			return nil, fmt.Errorf("font dict %d: %w", i, err)
+		}
+		table.FontDicts = append(table.FontDicts, fd)
+	}
+
+	if table.topDict.has(cffOpFDSelect) {
+		if table.FDSelect, err = parseCFFFDSelect(buf, table.topDict, numGlyphs, len(table.FontDicts)); err != nil {
+			return nil, fmt.Errorf("fd select: %w", err)
+		}
+	} else if len(table.FontDicts) > 1 {
+		return nil, errors.New("missing fd select")
+	}
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableCFF2) Bytes() []byte {
+	topDict := table.topDict.remove(cff2OpVstore, cffOpFDSelect).set(cffOpCharStrings, 0).set(cffOpFDArray, 0)
+	if table.VarStore != nil {
+		topDict = topDict.set(cff2OpVstore, 0)
+	}
+	if table.FDSelect != nil {
+		topDict = topDict.set(cffOpFDSelect, 0)
+	}
+	fontDicts := make([]cffDict, len(table.FontDicts))
+	privates := make([]cffDict, len(table.FontDicts))
+	for i, fd := range table.FontDicts {
+		privates[i] = fd.private.remove(cffOpSubrs)
+		if len(fd.Subrs) > 0 {
+			privates[i] = privates[i].set(cffOpSubrs, 0)
+			privates[i] = privates[i].set(cffOpSubrs, int32(len(privates[i].bytes())))
+		}
+		fontDicts[i] = fd.dict.set(cffOpPrivate, 0, 0)
+	}
+
+	// As in the CFF table, the first pass sets the offsets and the second
+	// writes them.
+	layout := func() []byte {
+		top := topDict.bytes()
+		buf := []byte{2, 0, 5}
+		buf = appendUint16(buf, uint16(len(top)))
+		buf = append(buf, top...)
+		buf = appendCFF2Index(buf, table.GlobalSubrs)
+
+		if table.VarStore != nil {
+			topDict = topDict.set(cff2OpVstore, int32(len(buf)))
+			store := table.VarStore.Bytes()
+			buf = appendUint16(buf, uint16(len(store)))
+			buf = append(buf, store...)
+		}
+		if table.FDSelect != nil {
+			topDict = topDict.set(cffOpFDSelect, int32(len(buf)))
+			buf = appendCFFFDSelect(buf, table.FDSelect)
+		}
+		topDict = topDict.set(cffOpCharStrings, int32(len(buf)))
+		buf = appendCFF2Index(buf, table.CharStrings)
+
+		topDict = topDict.set(cffOpFDArray, int32(len(buf)))
+		items := make([][]byte, len(fontDicts))
+		for i, dict := range fontDicts {
+			items[i] = dict.bytes()
+		}
+		buf = appendCFF2Index(buf, items)
+		for i, private := range privates {
+			offset := len(buf)
+			b := private.bytes()
+			fontDicts[i] = fontDicts[i].set(cffOpPrivate, int32(len(b)), int32(offset))
+			buf = append(buf, b...)
+			buf = appendCFF2Index(buf, table.FontDicts[i].Subrs)
+		}
+		return buf
+	}
+
+	layout()
+	return layout()
+}
+
+// CFF2Table parses the 'CFF2' table. The table is parsed each time, so
+// changes to it are only written once it has been added to the font with
+// AddTable.
+func (font *Font) CFF2Table() (*TableCFF2, error) {
+	s, found := font.tables[TagCFF2]
+	if !found {
+		return nil, ErrMissingTable
+	}
+	if t, ok := s.table.(*TableCFF2); ok {
+		return t, nil
+	}
+	buf, err := font.tableBytes(s)
+	if err != nil {
+		return nil, err
+	}
+	t, err := parseTableCFF2(TagCFF2, buf)
+	if err != nil {
+		return nil, err
+	}
+	t.options = font.options
+	return t, nil
+}
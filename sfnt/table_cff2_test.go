@@ -0,0 +1,81 @@
+package sfnt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCFF2Index(t *testing.T) {
+	items := [][]byte{[]byte("a"), bytes.Repeat([]byte("b"), 300)}
+	buf := appendCFF2Index([]byte{0xFF}, items)
+	if !bytes.Equal(buf[1:5], []byte{0, 0, 0, 2}) {
+		t.Errorf("appendCFF2Index() count = %v, want 32 bits", buf[1:5])
+	}
+	got, end, err := parseCFF2Index(buf, 1)
+	if err != nil {
+		t.Fatalf("parseCFF2Index() err = %q, want nil", err)
+	}
+	if end != len(buf) || !reflect.DeepEqual(got, items) {
+		t.Errorf("parseCFF2Index() = %q, %d, want %q, %d", got, end, items, len(buf))
+	}
+	if _, _, err := parseCFF2Index(buf[:len(buf)-1], 1); err == nil {
+		t.Errorf("parseCFF2Index() of a truncated INDEX err = nil, want an error")
+	}
+}
+
+func TestCFFDictNumber(t *testing.T) {
+	for _, v := range []float64{0, -107, 1131, -1132, 40000, -3000000, 0.039625, -1.5e-05, 2.5e+20} {
+		b := appendCFFDictNumber(nil, v)
+		dict, err := parseCFFDict(append(b, cffOpStdHW))
+		if err != nil {
+			t.Errorf("parseCFFDict(%v) err = %q, want nil", b, err)
+			continue
+		}
+		if got := cffDictNumber(dict[0].operands[0]); got != v {
+			t.Errorf("cffDictNumber(%v) = %v, want %v", b, got, v)
+		}
+	}
+	// -25 as a real number, as in TestCFFDict.
+	if got := cffDictNumber([]byte{30, 0xE2, 0x5F}); got != -25 {
+		t.Errorf("cffDictNumber(-25) = %v, want -25", got)
+	}
+}
+
+func TestCFF2Bounds(t *testing.T) {
+	table := &TableCFF2{
+		CharStrings: [][]byte{
+			nil,
+			// 0 0 rmoveto 0 -100 100 0 0 100 rrcurveto
+			{139, 139, 21, 139, 39, 239, 139, 139, 239, 8},
+			// 50 0 rmoveto 10 5 1 blend 20 rlineto
+			{189, 139, 21, 149, 144, 140, 16, 159, 5},
+		},
+		FontDicts: []CFFFontDict{{}},
+		VarStore: &ItemVariationStore{
+			Regions: []VariationRegion{{{Peak: 1 << 14, End: 1 << 14}}},
+			Data:    []ItemVariationData{{RegionIndexes: []uint16{0}}},
+		},
+	}
+
+	parsed, err := parseTableCFF2(TagCFF2, table.Bytes())
+	if err != nil {
+		t.Fatalf("parseTableCFF2() err = %q, want nil", err)
+	}
+	if len(parsed.CharStrings) != 3 || !reflect.DeepEqual(parsed.CharStrings[1:], table.CharStrings[1:]) {
+		t.Errorf("parseTableCFF2() charstrings = %v, want %v", parsed.CharStrings, table.CharStrings)
+	}
+	if parsed.VarStore == nil || !bytes.Equal(parsed.VarStore.Bytes(), table.VarStore.Bytes()) {
+		t.Errorf("parseTableCFF2() store = %v, want %v", parsed.VarStore, table.VarStore)
+	}
+
+	// The curve reaches three quarters of the depth of its control points,
+	// and the first moveto of the line is not part of its bounds. Blends
+	// are at their default value.
+	for glyph, want := range [][4]int16{{0, 0, 0, 0}, {0, -75, 100, 0}, {50, 0, 60, 20}} {
+		xMin, yMin, xMax, yMax, err := parsed.Bounds(GlyphID(glyph))
+		if got := [4]int16{xMin, yMin, xMax, yMax}; err != nil || got != want {
+			t.Errorf("Bounds(%d) = %v, %v, want %v", glyph, got, err, want)
+		}
+	}
+}
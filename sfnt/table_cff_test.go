@@ -0,0 +1,181 @@
+package sfnt
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testCFF(t *testing.T) *TableCFF {
+	t.Helper()
+	buf, err := os.ReadFile(filepath.Join("testdata", "Raleway-v4020-Regular.otf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cff, err := font.CFFTable()
+	if err != nil {
+		t.Fatalf("CFFTable() err = %q, want nil", err)
+	}
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cff.CharStrings) != int(maxp.NumGlyphs) {
+		t.Fatalf("CFFTable() has %d charstrings, want %d", len(cff.CharStrings), maxp.NumGlyphs)
+	}
+	return cff
+}
+
+// checkCFFRoundTrip checks that the table is unchanged when written and parsed again.
+func checkCFFRoundTrip(t *testing.T, want *TableCFF) *TableCFF {
+	t.Helper()
+	buf := want.Bytes()
+	got, err := parseTableCFF(TagCFF, buf)
+	if err != nil {
+		t.Fatalf("parseTableCFF(Bytes()) err = %q, want nil", err)
+	}
+	if !bytes.Equal(got.FontName, want.FontName) ||
+		!reflect.DeepEqual(got.Strings, want.Strings) ||
+		!reflect.DeepEqual(got.GlobalSubrs, want.GlobalSubrs) ||
+		!reflect.DeepEqual(got.CharStrings, want.CharStrings) ||
+		!reflect.DeepEqual(got.Charset, want.Charset) ||
+		!reflect.DeepEqual(got.FDSelect, want.FDSelect) ||
+		!bytes.Equal(got.encoding, want.encoding) {
+		t.Errorf("parseTableCFF(Bytes()) differs from the table")
+	}
+	if len(got.FontDicts) != len(want.FontDicts) {
+		t.Fatalf("parseTableCFF(Bytes()) has %d font dicts, want %d", len(got.FontDicts), len(want.FontDicts))
+	}
+	for i, fd := range got.FontDicts {
+		if !reflect.DeepEqual(fd.Subrs, want.FontDicts[i].Subrs) {
+			t.Errorf("font dict %d: subrs differ", i)
+		}
+		if !bytes.Equal(fd.private.remove(cffOpSubrs).bytes(), want.FontDicts[i].private.remove(cffOpSubrs).bytes()) {
+			t.Errorf("font dict %d: private dict differs", i)
+		}
+	}
+	if again := got.Bytes(); !bytes.Equal(again, buf) {
+		t.Errorf("Bytes() of the parsed table differs")
+	}
+	return got
+}
+
+func TestCFFRoundTrip(t *testing.T) {
+	cff := testCFF(t)
+	if string(cff.FontName) != "Raleway-v4020-Regular" {
+		t.Errorf("FontName = %q, want %q", cff.FontName, "Raleway-v4020-Regular")
+	}
+	if cff.IsCIDKeyed() {
+		t.Errorf("IsCIDKeyed() = true, want false")
+	}
+	if len(cff.FontDicts) != 1 || len(cff.Subrs(1)) == 0 {
+		t.Errorf("got %d font dicts, with %d subrs, want 1 with subrs", len(cff.FontDicts), len(cff.Subrs(1)))
+	}
+	checkCFFRoundTrip(t, cff)
+}
+
+func TestCFFCIDKeyed(t *testing.T) {
+	cff := testCFF(t)
+
+	// Make the font CID-keyed, with the glyphs split between two font dicts.
+	cff.topDict = cff.topDict.remove(cffOpPrivate).set(cffOpROS, 391, 392, 0)
+	fd := cff.FontDicts[0]
+	cff.FontDicts = []CFFFontDict{fd, {private: fd.private, Subrs: fd.Subrs[:1]}}
+	cff.FDSelect = make([]uint8, len(cff.CharStrings))
+	for g := len(cff.FDSelect) / 2; g < len(cff.FDSelect); g++ {
+		cff.FDSelect[g] = 1
+	}
+
+	got := checkCFFRoundTrip(t, cff)
+	if !got.IsCIDKeyed() {
+		t.Errorf("IsCIDKeyed() = false, want true")
+	}
+	if n := len(got.Subrs(GlyphID(len(cff.FDSelect) - 1))); n != 1 {
+		t.Errorf("last glyph has %d subrs, want 1", n)
+	}
+}
+
+func TestCFFBounds(t *testing.T) {
+	table := &TableCFF{
+		CharStrings: [][]byte{
+			{14},
+			// 100 0 0 rmoveto 0 -100 100 0 0 100 rrcurveto endchar
+			{239, 139, 139, 21, 139, 39, 239, 139, 139, 239, 8, 14},
+			// 50 10 20 hstem 30 hmoveto 10 10 rlineto endchar
+			{189, 149, 159, 1, 169, 22, 149, 149, 5, 14},
+			// -107 callgsubr
+			{32, 29},
+		},
+		// 0 0 rmoveto 10 20 rlineto endchar
+		GlobalSubrs: [][]byte{{139, 139, 21, 149, 159, 5, 14}},
+		FontDicts:   []CFFFontDict{{}},
+	}
+
+	// The advance widths before the first stack-clearing operators are not
+	// coordinates.
+	for glyph, want := range [][4]int16{{0, 0, 0, 0}, {0, -75, 100, 0}, {30, 0, 40, 10}, {0, 0, 10, 20}} {
+		xMin, yMin, xMax, yMax, err := table.Bounds(GlyphID(glyph))
+		if got := [4]int16{xMin, yMin, xMax, yMax}; err != nil || got != want {
+			t.Errorf("Bounds(%d) = %v, %v, want %v", glyph, got, err, want)
+		}
+	}
+	if _, _, _, _, err := table.Bounds(4); err == nil {
+		t.Errorf("Bounds(4) err = nil, want an error")
+	}
+}
+
+func TestCFFIndex(t *testing.T) {
+	items := [][]byte{[]byte("a"), nil, bytes.Repeat([]byte("b"), 300)}
+	buf := appendCFFIndex([]byte{0xFF}, items)
+	got, end, err := parseCFFIndex(buf, 1)
+	if err != nil {
+		t.Fatalf("parseCFFIndex() err = %q, want nil", err)
+	}
+	if end != len(buf) || len(got) != len(items) || string(got[0]) != "a" || len(got[1]) != 0 || len(got[2]) != 300 {
+		t.Errorf("parseCFFIndex() = %q, %d, want %q, %d", got, end, items, len(buf))
+	}
+
+	for _, truncated := range [][]byte{buf[:2], buf[:5], buf[:len(buf)-1]} {
+		if _, _, err := parseCFFIndex(truncated, 1); err == nil {
+			t.Errorf("parseCFFIndex(%v) err = nil, want an error", truncated)
+		}
+	}
+	if _, _, err := parseCFFIndex([]byte{0}, 0); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("parseCFFIndex() err = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestCFFDict(t *testing.T) {
+	// 1000 BlueScale, -25 as a real number and 5 StdHW
+	buf := []byte{28, 0x03, 0xE8, 12, 9, 30, 0xE2, 0x5F, 144, 10}
+	dict, err := parseCFFDict(buf)
+	if err != nil {
+		t.Fatalf("parseCFFDict() err = %q, want nil", err)
+	}
+	if got := dict.get(cffOpBlueScale); !reflect.DeepEqual(got, []int32{1000}) {
+		t.Errorf("get(BlueScale) = %v, want [1000]", got)
+	}
+	if got := dict.get(cffOpStdHW); len(got) != 2 || got[1] != 5 {
+		t.Errorf("get(StdHW) = %v, want [0 5], with the real number as 0", got)
+	}
+	if !bytes.Equal(dict.bytes(), buf) {
+		t.Errorf("bytes() = %v, want %v", dict.bytes(), buf)
+	}
+	if got := dict.remove(cffOpBlueScale).set(cffOpSubrs, -2).bytes(); !bytes.Equal(got, []byte{30, 0xE2, 0x5F, 144, 10, 29, 0xFF, 0xFF, 0xFF, 0xFE, 19}) {
+		t.Errorf("remove().set().bytes() = %v", got)
+	}
+
+	for _, invalid := range [][]byte{{139}, {28, 1}, {12}, {255, 1}} {
+		if _, err := parseCFFDict(invalid); err == nil {
+			t.Errorf("parseCFFDict(%v) err = nil, want an error", invalid)
+		}
+	}
+}
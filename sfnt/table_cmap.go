@@ -0,0 +1,561 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// TableCmap maps character codes to glyph indices. TableCmap is read only,
+// use NewTableCmap to build a new table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cmap
+type TableCmap struct {
+	baseTable
+
+	bytes     []byte
+	Subtables []*CmapSubtable // Subtables in the order of their encoding records.
+}
+
+// CmapSubtable is a mapping of character codes for one platform and encoding.
+type CmapSubtable struct {
+	PlatformID PlatformID
+	EncodingID PlatformEncodingID
+	Format     uint16
+	Language   uint32 // Language is only used by subtables for the Mac platform.
+	Offset     uint32 // Offset of the subtable from the beginning of the table.
+
+	// Mappings maps character codes in the encoding of the subtable to glyphs.
+	// Only formats 0, 4, 6, 10, 12 and 13 are parsed, for other formats Mappings is nil.
+	// Characters mapped to glyph 0 are not included.
+	Mappings map[rune]GlyphID
+}
+
+// IsUnicode returns true if the subtable uses a Unicode encoding.
+func (s *CmapSubtable) IsUnicode() bool {
+	switch s.PlatformID {
+	case PlatformUnicode:
+		return s.EncodingID != 5 // 5 is used for Unicode Variation Sequences.
+	case PlatformMicrosoft:
+		return s.EncodingID == 1 || s.EncodingID == 10
+	}
+	return false
+}
+
+type cmapHeader struct {
+	Version   uint16
+	NumTables uint16
+}
+
+type cmapEncodingRecord struct {
+	PlatformID PlatformID
+	EncodingID PlatformEncodingID
+	Offset     uint32
+}
+
+func parseTableCmap(tag Tag, buf []byte, _ *Options) (Table, error) {
+	r := bytes.NewReader(buf)
+
+	var header cmapHeader
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+
+	records := make([]cmapEncodingRecord, header.NumTables)
+	if err := binary.Read(r, binary.BigEndian, &records); err != nil {
+		return nil, fmt.Errorf("reading encoding records: %w", err)
+	}
+
+	table := &TableCmap{
+		baseTable: baseTable(tag),
+		bytes:     buf,
+	}
+
+	// Subtables are often shared between encoding records.
+	parsed := make(map[uint32]*CmapSubtable)
+	for i, record := range records {
+		s, found := parsed[record.Offset]
+		if !found {
+			var err error
+			s, err = parseCmapSubtable(buf, record.Offset)
+			if err != nil {
+				return nil, fmt.Errorf("reading subtable[%d]: %w", i, err)
+			}
+			parsed[record.Offset] = s
+		}
+
+		subtable := *s
+		subtable.PlatformID = record.PlatformID
+		subtable.EncodingID = record.EncodingID
+		table.Subtables = append(table.Subtables, &subtable)
+	}
+
+	return table, nil
+}
+
+func parseCmapSubtable(buf []byte, offset uint32) (*CmapSubtable, error) {
+	if int64(offset)+2 > int64(len(buf)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := buf[offset:]
+
+	s := &CmapSubtable{
+		Format: binary.BigEndian.Uint16(b),
+		Offset: offset,
+	}
+
+	var err error
+	switch s.Format {
+	case 0:
+		err = s.parseFormat0(b)
+	case 4:
+		err = s.parseFormat4(b)
+	case 6:
+		err = s.parseFormat6(b)
+	case 10:
+		err = s.parseFormat10(b)
+	case 12, 13:
+		err = s.parseFormat12(b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("format %d: %w", s.Format, err)
+	}
+
+	return s, nil
+}
+
+func (s *CmapSubtable) add(code rune, glyph GlyphID) {
+	if glyph != 0 {
+		s.Mappings[code] = glyph
+	}
+}
+
+func (s *CmapSubtable) parseFormat0(b []byte) error {
+	if len(b) < 6+256 {
+		return io.ErrUnexpectedEOF
+	}
+	s.Language = uint32(binary.BigEndian.Uint16(b[4:]))
+	s.Mappings = make(map[rune]GlyphID)
+	for code, glyph := range b[6 : 6+256] {
+		s.add(rune(code), GlyphID(glyph))
+	}
+	return nil
+}
+
+func (s *CmapSubtable) parseFormat4(b []byte) error {
+	if len(b) < 14 {
+		return io.ErrUnexpectedEOF
+	}
+	s.Language = uint32(binary.BigEndian.Uint16(b[4:]))
+	segCount := int(binary.BigEndian.Uint16(b[6:]) / 2)
+
+	// The length field of format 4 subtables is often wrong in large fonts,
+	// so the arrays are only checked against the end of the table.
+	endCodes := 14
+	startCodes := endCodes + 2*segCount + 2
+	idDeltas := startCodes + 2*segCount
+	idRangeOffsets := idDeltas + 2*segCount
+	if len(b) < idRangeOffsets+2*segCount {
+		return io.ErrUnexpectedEOF
+	}
+
+	s.Mappings = make(map[rune]GlyphID)
+	next := 0
+	for i := 0; i < segCount; i++ {
+		end := binary.BigEndian.Uint16(b[endCodes+2*i:])
+		start := binary.BigEndian.Uint16(b[startCodes+2*i:])
+		delta := binary.BigEndian.Uint16(b[idDeltas+2*i:])
+		rangeOffset := int(binary.BigEndian.Uint16(b[idRangeOffsets+2*i:]))
+
+		if start > end {
+			return fmt.Errorf("segment %d: start code %d after end code %d", i, start, end)
+		}
+		if int(start) < next {
+			return fmt.Errorf("segment %d: segments overlap or are not sorted", i)
+		}
+		next = int(end) + 1
+
+		for code := int(start); code <= int(end); code++ {
+			if code == 0xFFFF {
+				break
+			}
+			if rangeOffset == 0 {
+				s.add(rune(code), GlyphID(uint16(code)+delta))
+				continue
+			}
+
+			at := idRangeOffsets + 2*i + rangeOffset + 2*(code-int(start))
+			if at+2 > len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			if glyph := binary.BigEndian.Uint16(b[at:]); glyph != 0 {
+				s.add(rune(code), GlyphID(glyph+delta))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *CmapSubtable) parseFormat6(b []byte) error {
+	if len(b) < 10 {
+		return io.ErrUnexpectedEOF
+	}
+	s.Language = uint32(binary.BigEndian.Uint16(b[4:]))
+	first := int(binary.BigEndian.Uint16(b[6:]))
+	count := int(binary.BigEndian.Uint16(b[8:]))
+	if len(b) < 10+2*count {
+		return io.ErrUnexpectedEOF
+	}
+
+	s.Mappings = make(map[rune]GlyphID, count)
+	for i := 0; i < count; i++ {
+		s.add(rune(first+i), GlyphID(binary.BigEndian.Uint16(b[10+2*i:])))
+	}
+	return nil
+}
+
+func (s *CmapSubtable) parseFormat10(b []byte) error {
+	if len(b) < 20 {
+		return io.ErrUnexpectedEOF
+	}
+	s.Language = binary.BigEndian.Uint32(b[8:])
+	first := binary.BigEndian.Uint32(b[12:])
+	count := binary.BigEndian.Uint32(b[16:])
+	if uint64(len(b)) < 20+2*uint64(count) {
+		return io.ErrUnexpectedEOF
+	}
+
+	s.Mappings = make(map[rune]GlyphID, count)
+	for i := uint32(0); i < count; i++ {
+		s.add(rune(first+i), GlyphID(binary.BigEndian.Uint16(b[20+2*i:])))
+	}
+	return nil
+}
+
+// parseFormat12 parses both format 12 (segmented coverage) and format 13
+// (many-to-one range mappings) subtables, which only differ in how glyphs are assigned.
+func (s *CmapSubtable) parseFormat12(b []byte) error {
+	if len(b) < 16 {
+		return io.ErrUnexpectedEOF
+	}
+	s.Language = binary.BigEndian.Uint32(b[8:])
+	count := binary.BigEndian.Uint32(b[12:])
+	if uint64(len(b)) < 16+12*uint64(count) {
+		return io.ErrUnexpectedEOF
+	}
+
+	s.Mappings = make(map[rune]GlyphID)
+	next := uint32(0)
+	for i := uint32(0); i < count; i++ {
+		group := b[16+12*i:]
+		start := binary.BigEndian.Uint32(group)
+		end := binary.BigEndian.Uint32(group[4:])
+		glyph := binary.BigEndian.Uint32(group[8:])
+
+		if start > end || end > 0x10FFFF {
+			return fmt.Errorf("group %d: invalid range %d-%d", i, start, end)
+		}
+		if start < next {
+			return fmt.Errorf("group %d: groups overlap or are not sorted", i)
+		}
+		next = end + 1
+
+		for code := start; code <= end; code++ {
+			if s.Format == 12 {
+				s.add(rune(code), GlyphID(glyph+code-start))
+			} else {
+				s.add(rune(code), GlyphID(glyph))
+			}
+		}
+	}
+	return nil
+}
+
+// cmapUnicodePreference lists the platform and encoding of Unicode subtables,
+// from the most to the least preferred.
+var cmapUnicodePreference = []struct {
+	PlatformID PlatformID
+	EncodingID PlatformEncodingID
+}{
+	{PlatformMicrosoft, 10}, // Unicode full repertoire
+	{PlatformUnicode, 6},    // Unicode full repertoire
+	{PlatformUnicode, 4},    // Unicode 2.0 and onwards, full repertoire
+	{PlatformMicrosoft, 1},  // Unicode BMP
+	{PlatformUnicode, 3},    // Unicode 2.0 and onwards, BMP only
+	{PlatformUnicode, 2},    // ISO/IEC 10646
+	{PlatformUnicode, 1},    // Unicode 1.1
+	{PlatformUnicode, 0},    // Unicode 1.0
+}
+
+// UnicodeSubtable returns the subtable that is used to map Unicode characters
+// to glyphs, or nil if the table has no supported Unicode subtable.
+func (table *TableCmap) UnicodeSubtable() *CmapSubtable {
+	for _, p := range cmapUnicodePreference {
+		for _, s := range table.Subtables {
+			if s.PlatformID == p.PlatformID && s.EncodingID == p.EncodingID && s.Mappings != nil {
+				return s
+			}
+		}
+	}
+	return nil
+}
+
+// Lookup returns the glyph for a Unicode character, or 0 (the missing glyph)
+// if the character is not mapped.
+func (table *TableCmap) Lookup(r rune) GlyphID {
+	if s := table.UnicodeSubtable(); s != nil {
+		return s.Mappings[r]
+	}
+	return 0
+}
+
+// CharIndexRange returns the first and last characters mapped by the
+// Unicode subtable, for the usFirstCharIndex and usLastCharIndex fields of
+// 'OS/2', which are limited to 0xFFFF.
+func (table *TableCmap) CharIndexRange() (first, last uint16) {
+	first, last = 0xFFFF, 0
+	if unicode := table.UnicodeSubtable(); unicode != nil {
+		for r := range unicode.Mappings {
+			if r > 0xFFFF {
+				r = 0xFFFF
+			}
+			if uint16(r) < first {
+				first = uint16(r)
+			}
+			if uint16(r) > last {
+				last = uint16(r)
+			}
+		}
+	}
+	if first > last {
+		first = 0
+	}
+	return first, last
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableCmap) Bytes() []byte {
+	return table.bytes
+}
+
+// NewTableCmap returns a table mapping Unicode characters to glyphs.
+// The table contains format 4 subtables for the Basic Multilingual Plane
+// and, if needed, format 12 subtables for all characters.
+func NewTableCmap(mappings map[rune]GlyphID) *TableCmap {
+	codes := make([]rune, 0, len(mappings))
+	for code, glyph := range mappings {
+		if code >= 0 && code <= 0x10FFFF && glyph != 0 {
+			codes = append(codes, code)
+		}
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	bmp := codes
+	for i, code := range codes {
+		if code > 0xFFFF {
+			bmp = codes[:i]
+			break
+		}
+	}
+
+	type subtable struct {
+		platformID PlatformID
+		encodingID PlatformEncodingID
+		format     uint16
+	}
+	subtables := []subtable{
+		{PlatformUnicode, 3, 4},
+		{PlatformMicrosoft, 1, 4},
+	}
+	if len(bmp) < len(codes) {
+		subtables = []subtable{
+			{PlatformUnicode, 3, 4},
+			{PlatformUnicode, 4, 12},
+			{PlatformMicrosoft, 1, 4},
+			{PlatformMicrosoft, 10, 12},
+		}
+	}
+
+	format4 := encodeCmapFormat4(bmp, mappings)
+	var format12 []byte
+	if len(bmp) < len(codes) {
+		format12 = encodeCmapFormat12(codes, mappings)
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, cmapHeader{0, uint16(len(subtables))})
+
+	offset4 := uint32(4 + 8*len(subtables))
+	offset12 := offset4 + uint32(len(format4))
+
+	table := &TableCmap{baseTable: baseTable(TagCmap)}
+	for _, s := range subtables {
+		offset := offset4
+		if s.format == 12 {
+			offset = offset12
+		}
+		binary.Write(&buf, binary.BigEndian, cmapEncodingRecord{s.platformID, s.encodingID, offset})
+	}
+	buf.Write(format4)
+	buf.Write(format12)
+	table.bytes = buf.Bytes()
+
+	for _, s := range subtables {
+		subtable := &CmapSubtable{
+			PlatformID: s.platformID,
+			EncodingID: s.encodingID,
+			Format:     s.format,
+			Offset:     offset4,
+			Mappings:   make(map[rune]GlyphID),
+		}
+		sub := bmp
+		if s.format == 12 {
+			subtable.Offset = offset12
+			sub = codes
+		}
+		for _, code := range sub {
+			subtable.Mappings[code] = mappings[code]
+		}
+		table.Subtables = append(table.Subtables, subtable)
+	}
+
+	return table
+}
+
+// cmapSegment is a range of characters in a format 4 subtable.
+type cmapSegment struct {
+	start, end rune
+	delta      bool // delta is true if the glyphs are consecutive, and no glyph array is needed.
+}
+
+// encodeCmapFormat4 encodes a format 4 subtable for codes, which must be sorted
+// and within the Basic Multilingual Plane.
+func encodeCmapFormat4(codes []rune, mappings map[rune]GlyphID) []byte {
+	var segments []cmapSegment
+
+	// Split the characters into runs of consecutive codes, and split those
+	// into runs where the glyphs are consecutive too. Runs of consecutive glyphs
+	// that are too short to be worth a segment of their own use the glyph array.
+	const minDeltaRun = 4
+	for i := 0; i < len(codes); {
+		j := i + 1
+		for j < len(codes) && codes[j] == codes[j-1]+1 {
+			j++
+		}
+
+		var pending *cmapSegment
+		for k := i; k < j; {
+			l := k + 1
+			for l < j && int(mappings[codes[l]])-int(codes[l]) == int(mappings[codes[k]])-int(codes[k]) {
+				l++
+			}
+
+			if l-k >= minDeltaRun || (k == i && l == j) {
+				if pending != nil {
+					segments = append(segments, *pending)
+					pending = nil
+				}
+				segments = append(segments, cmapSegment{codes[k], codes[l-1], true})
+			} else if pending != nil {
+				pending.end = codes[l-1]
+			} else {
+				pending = &cmapSegment{codes[k], codes[l-1], false}
+			}
+			k = l
+		}
+		if pending != nil {
+			segments = append(segments, *pending)
+		}
+		i = j
+	}
+	segments = append(segments, cmapSegment{0xFFFF, 0xFFFF, true})
+
+	segCount := len(segments)
+	entrySelector := 0
+	for 1<<(entrySelector+1) <= segCount {
+		entrySelector++
+	}
+	searchRange := 2 * (1 << entrySelector)
+
+	endCodes := make([]uint16, segCount)
+	startCodes := make([]uint16, segCount)
+	idDeltas := make([]uint16, segCount)
+	idRangeOffsets := make([]uint16, segCount)
+	var glyphs []uint16
+
+	for i, s := range segments {
+		endCodes[i] = uint16(s.end)
+		startCodes[i] = uint16(s.start)
+		if s.start == 0xFFFF {
+			idDeltas[i] = 1
+			continue
+		}
+		if s.delta {
+			idDeltas[i] = uint16(mappings[s.start]) - uint16(s.start)
+			continue
+		}
+		idRangeOffsets[i] = uint16(2 * (segCount - i + len(glyphs)))
+		for code := s.start; code <= s.end; code++ {
+			glyphs = append(glyphs, uint16(mappings[code]))
+		}
+	}
+
+	length := 16 + 8*segCount + 2*len(glyphs)
+	if length > 0xFFFF {
+		length = 0xFFFF
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, []uint16{
+		4,                                // format
+		uint16(length),                   // length
+		0,                                // language
+		uint16(2 * segCount),             // segCountX2
+		uint16(searchRange),              // searchRange
+		uint16(entrySelector),            // entrySelector
+		uint16(2*segCount - searchRange), // rangeShift
+	})
+	binary.Write(&buf, binary.BigEndian, endCodes)
+	binary.Write(&buf, binary.BigEndian, uint16(0)) // reservedPad
+	binary.Write(&buf, binary.BigEndian, startCodes)
+	binary.Write(&buf, binary.BigEndian, idDeltas)
+	binary.Write(&buf, binary.BigEndian, idRangeOffsets)
+	binary.Write(&buf, binary.BigEndian, glyphs)
+
+	return buf.Bytes()
+}
+
+// encodeCmapFormat12 encodes a format 12 subtable for codes, which must be sorted.
+func encodeCmapFormat12(codes []rune, mappings map[rune]GlyphID) []byte {
+	var groups []uint32
+	for i := 0; i < len(codes); {
+		j := i + 1
+		for j < len(codes) && codes[j] == codes[j-1]+1 && mappings[codes[j]] == mappings[codes[j-1]]+1 {
+			j++
+		}
+		groups = append(groups, uint32(codes[i]), uint32(codes[j-1]), uint32(mappings[codes[i]]))
+		i = j
+	}
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, []uint16{12, 0})
+	binary.Write(&buf, binary.BigEndian, []uint32{
+		uint32(16 + 4*len(groups)), // length
+		0,                          // language
+		uint32(len(groups) / 3),    // numGroups
+	})
+	binary.Write(&buf, binary.BigEndian, groups)
+
+	return buf.Bytes()
+}
+
+// CmapTable returns the table corresponding to the 'cmap' tag.
+func (font *Font) CmapTable() (*TableCmap, error) {
+	t, err := font.Table(TagCmap)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableCmap), nil
+}
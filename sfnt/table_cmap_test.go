@@ -0,0 +1,81 @@
+package sfnt
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCmapLookup(t *testing.T) {
+	tests := []struct {
+		filename string
+		r        rune
+		want     GlyphID
+	}{
+		{filename: "Roboto-BoldItalic.ttf", r: 'A', want: 38},
+		{filename: "Roboto-BoldItalic.ttf", r: 0x10FFFF, want: 0},
+		{filename: "open-sans-v15-latin-regular.woff", r: 'a', want: 67},
+	}
+
+	for _, test := range tests {
+		file, err := os.Open(filepath.Join("testdata", test.filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+
+		font, err := Parse(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cmap, err := font.CmapTable()
+		if err != nil {
+			t.Fatalf("CmapTable(%q) err = %q, want nil", test.filename, err)
+		}
+
+		if got := cmap.Lookup(test.r); got != test.want {
+			t.Errorf("CmapTable(%q).Lookup(%q) = %d, want %d", test.filename, test.r, got, test.want)
+		}
+	}
+}
+
+func TestNewTableCmapRoundTrip(t *testing.T) {
+	mappings := map[rune]GlyphID{
+		'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, // delta segment
+		'a': 9, 'b': 7, 'c': 8, // glyph array segment
+		0x20AC:  10,
+		0xFFFE:  11,
+		0x1F600: 12, 0x1F601: 13,
+		0x10FFFF: 14,
+	}
+
+	table := NewTableCmap(mappings)
+
+	parsed, err := parseTableCmap(TagCmap, table.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("parseTableCmap(NewTableCmap()) err = %q, want nil", err)
+	}
+
+	cmap := parsed.(*TableCmap)
+	if len(cmap.Subtables) != 4 {
+		t.Fatalf("len(Subtables) = %d, want 4", len(cmap.Subtables))
+	}
+
+	for _, s := range cmap.Subtables {
+		for code, glyph := range mappings {
+			if s.Format == 4 && code > 0xFFFF {
+				continue
+			}
+			if got := s.Mappings[code]; got != glyph {
+				t.Errorf("subtable (%d, %d) format %d maps %U to %d, want %d", s.PlatformID, s.EncodingID, s.Format, code, got, glyph)
+			}
+		}
+	}
+
+	for code, glyph := range mappings {
+		if got := cmap.Lookup(code); got != glyph {
+			t.Errorf("Lookup(%U) = %d, want %d", code, got, glyph)
+		}
+	}
+}
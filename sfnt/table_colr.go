@@ -0,0 +1,802 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// TableCOLR contains color glyphs, drawn with the colors in 'CPAL'. In
+// version 0 color glyphs are stacks of glyphs filled with a solid color, and
+// in version 1 they are graphs of paints, which include gradients, transforms
+// and compositing. The table is read only, so Bytes returns the bytes that
+// were parsed.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/colr
+type TableCOLR struct {
+	baseTable
+
+	bytes   []byte
+	Version uint16
+
+	// BaseGlyphs contains the version 0 color glyphs, sorted by glyph ID.
+	BaseGlyphs []ColorGlyph
+
+	// Paints contains the root paint of the version 1 color glyphs.
+	Paints map[GlyphID]Paint
+	// Layers contains the paints referenced by PaintColrLayers.
+	Layers []Paint
+	// Clips contains the clip boxes of version 1 color glyphs, sorted by glyph ID.
+	Clips []Clip
+
+	// VarIndexMap and VarStore vary the values of variable paints. Both are
+	// nil if the table has no variations, and VarIndexMap is also nil if
+	// variation indexes are used directly.
+	VarIndexMap *DeltaSetIndexMap
+	VarStore    *ItemVariationStore
+}
+
+// ColorGlyph is a version 0 color glyph.
+type ColorGlyph struct {
+	Glyph  GlyphID
+	Layers []ColorLayer // Layers are drawn from first to last.
+}
+
+// ColorLayer is a glyph filled with a palette entry.
+type ColorLayer struct {
+	Glyph        GlyphID
+	PaletteIndex uint16 // PaletteIndex is ForegroundPaletteIndex for the text color.
+}
+
+// ForegroundPaletteIndex is the palette index that selects the text color,
+// instead of an entry in the palette.
+const ForegroundPaletteIndex = 0xFFFF
+
+// NoVarIndex is the VarIndexBase of paints and values that do not vary.
+const NoVarIndex = 0xFFFFFFFF
+
+// Clip limits the drawing of a range of color glyphs to a box.
+type Clip struct {
+	StartGlyph, EndGlyph GlyphID // StartGlyph and EndGlyph are inclusive.
+	Box                  ClipBox
+}
+
+// ClipBox is a rectangle in font units.
+type ClipBox struct {
+	XMin, YMin, XMax, YMax int16
+	VarIndexBase           uint32 // VarIndexBase is NoVarIndex if the box does not vary.
+}
+
+// Paint is a node in the paint graph of a version 1 color glyph. The paints
+// are PaintColrLayers, PaintSolid, PaintLinearGradient, PaintRadialGradient,
+// PaintSweepGradient, PaintGlyph, PaintColrGlyph, PaintTransform,
+// PaintTranslate, PaintScale, PaintRotate, PaintSkew and PaintComposite.
+//
+// Paints with a VarIndexBase other than NoVarIndex are variable: the deltas of
+// their fields are given by TableCOLR.Delta for consecutive indexes starting
+// at VarIndexBase, in the order of the fields in the specification.
+type Paint interface {
+	isPaint()
+}
+
+// PaintColrLayers draws a sequence of layers, from first to last.
+type PaintColrLayers struct {
+	FirstLayerIndex uint32
+	Layers          []Paint // Layers is a slice of TableCOLR.Layers.
+}
+
+// PaintSolid fills with a palette entry.
+type PaintSolid struct {
+	PaletteIndex uint16
+	Alpha        F2Dot14 // Alpha is multiplied by the alpha of the palette entry.
+	VarIndexBase uint32
+}
+
+// Extend modes of color lines, which determine how gradients are drawn
+// outside of the stops.
+const (
+	ExtendPad     = 0
+	ExtendRepeat  = 1
+	ExtendReflect = 2
+)
+
+// ColorLine is the sequence of colors in a gradient.
+type ColorLine struct {
+	Extend uint8
+	Stops  []ColorStop
+}
+
+// ColorStop is the color at a position along a ColorLine.
+type ColorStop struct {
+	StopOffset   F2Dot14
+	PaletteIndex uint16
+	Alpha        F2Dot14
+	VarIndexBase uint32
+}
+
+// PaintLinearGradient fills with a gradient from the start point (X0, Y0) to
+// the end point (X1, Y1), rotated towards the point (X2, Y2).
+type PaintLinearGradient struct {
+	ColorLine      *ColorLine
+	X0, Y0, X1, Y1 int16
+	X2, Y2         int16
+	VarIndexBase   uint32
+}
+
+// PaintRadialGradient fills with a gradient between two circles.
+type PaintRadialGradient struct {
+	ColorLine    *ColorLine
+	X0, Y0       int16
+	Radius0      uint16
+	X1, Y1       int16
+	Radius1      uint16
+	VarIndexBase uint32
+}
+
+// PaintSweepGradient fills with a gradient swept around a center point. The
+// angles are in half turns counter-clockwise, so 1.0 is 180°.
+type PaintSweepGradient struct {
+	ColorLine            *ColorLine
+	CenterX, CenterY     int16
+	StartAngle, EndAngle F2Dot14
+	VarIndexBase         uint32
+}
+
+// PaintGlyph clips Paint to the outline of a glyph.
+type PaintGlyph struct {
+	Paint Paint
+	Glyph GlyphID
+}
+
+// PaintColrGlyph draws another version 1 color glyph.
+type PaintColrGlyph struct {
+	Glyph GlyphID
+}
+
+// Affine is an affine transform, which maps (x, y) to
+// (XX*x + XY*y + DX, YX*x + YY*y + DY).
+type Affine struct {
+	XX, YX, XY, YY, DX, DY float64
+}
+
+// PaintTransform draws Paint transformed by an affine transform.
+type PaintTransform struct {
+	Paint        Paint
+	Transform    Affine
+	VarIndexBase uint32
+}
+
+// PaintTranslate draws Paint translated.
+type PaintTranslate struct {
+	Paint        Paint
+	DX, DY       int16
+	VarIndexBase uint32
+}
+
+// PaintScale draws Paint scaled, around the origin or around a center point.
+// Uniform scales have a single variable scale, which ScaleX and ScaleY share.
+type PaintScale struct {
+	Paint            Paint
+	ScaleX, ScaleY   F2Dot14
+	Uniform          bool
+	AroundCenter     bool
+	CenterX, CenterY int16
+	VarIndexBase     uint32
+}
+
+// PaintRotate draws Paint rotated, around the origin or around a center
+// point. The angle is in half turns counter-clockwise, so 1.0 is 180°.
+type PaintRotate struct {
+	Paint            Paint
+	Angle            F2Dot14
+	AroundCenter     bool
+	CenterX, CenterY int16
+	VarIndexBase     uint32
+}
+
+// PaintSkew draws Paint skewed, around the origin or around a center point.
+// The angles are in half turns, so 1.0 is 180°.
+type PaintSkew struct {
+	Paint                  Paint
+	XSkewAngle, YSkewAngle F2Dot14
+	AroundCenter           bool
+	CenterX, CenterY       int16
+	VarIndexBase           uint32
+}
+
+// PaintComposite draws Source onto Backdrop using a composite mode.
+type PaintComposite struct {
+	Source   Paint
+	Mode     CompositeMode
+	Backdrop Paint
+}
+
+// CompositeMode is the way PaintComposite combines its paints: the Porter-Duff
+// modes from CompositeClear to CompositePlus, and the blend modes from
+// CompositeScreen to CompositeHSLLuminosity.
+type CompositeMode uint8
+
+// Composite modes.
+const (
+	CompositeClear CompositeMode = iota
+	CompositeSrc
+	CompositeDest
+	CompositeSrcOver
+	CompositeDestOver
+	CompositeSrcIn
+	CompositeDestIn
+	CompositeSrcOut
+	CompositeDestOut
+	CompositeSrcAtop
+	CompositeDestAtop
+	CompositeXor
+	CompositePlus
+	CompositeScreen
+	CompositeOverlay
+	CompositeDarken
+	CompositeLighten
+	CompositeColorDodge
+	CompositeColorBurn
+	CompositeHardLight
+	CompositeSoftLight
+	CompositeDifference
+	CompositeExclusion
+	CompositeMultiply
+	CompositeHSLHue
+	CompositeHSLSaturation
+	CompositeHSLColor
+	CompositeHSLLuminosity
+)
+
+func (*PaintColrLayers) isPaint()     {}
+func (*PaintSolid) isPaint()          {}
+func (*PaintLinearGradient) isPaint() {}
+func (*PaintRadialGradient) isPaint() {}
+func (*PaintSweepGradient) isPaint()  {}
+func (*PaintGlyph) isPaint()          {}
+func (*PaintColrGlyph) isPaint()      {}
+func (*PaintTransform) isPaint()      {}
+func (*PaintTranslate) isPaint()      {}
+func (*PaintScale) isPaint()          {}
+func (*PaintRotate) isPaint()         {}
+func (*PaintSkew) isPaint()           {}
+func (*PaintComposite) isPaint()      {}
+
+// Bytes returns the bytes of the table as parsed.
+func (table *TableCOLR) Bytes() []byte {
+	return table.bytes
+}
+
+// ColorGlyph returns the version 0 color glyph for glyph, or nil if there is none.
+func (table *TableCOLR) ColorGlyph(glyph GlyphID) *ColorGlyph {
+	i := sort.Search(len(table.BaseGlyphs), func(i int) bool {
+		return table.BaseGlyphs[i].Glyph >= glyph
+	})
+	if i < len(table.BaseGlyphs) && table.BaseGlyphs[i].Glyph == glyph {
+		return &table.BaseGlyphs[i]
+	}
+	return nil
+}
+
+// GlyphPaint returns the root paint of a color glyph, or nil if the glyph is
+// not a color glyph. Version 0 color glyphs are returned as the equivalent
+// PaintColrLayers, so that renderers can draw both versions the same way.
+func (table *TableCOLR) GlyphPaint(glyph GlyphID) Paint {
+	if paint, ok := table.Paints[glyph]; ok {
+		return paint
+	}
+	base := table.ColorGlyph(glyph)
+	if base == nil {
+		return nil
+	}
+	layers := &PaintColrLayers{Layers: make([]Paint, len(base.Layers))}
+	for i, layer := range base.Layers {
+		layers.Layers[i] = &PaintGlyph{
+			Glyph: layer.Glyph,
+			Paint: &PaintSolid{PaletteIndex: layer.PaletteIndex, Alpha: 1 << 14, VarIndexBase: NoVarIndex},
+		}
+	}
+	return layers
+}
+
+// ClipBox returns the clip box of a version 1 color glyph, or false if it has none.
+func (table *TableCOLR) ClipBox(glyph GlyphID) (ClipBox, bool) {
+	i := sort.Search(len(table.Clips), func(i int) bool {
+		return table.Clips[i].EndGlyph >= glyph
+	})
+	if i < len(table.Clips) && table.Clips[i].StartGlyph <= glyph {
+		return table.Clips[i].Box, true
+	}
+	return ClipBox{}, false
+}
+
+// Delta returns the delta for the value at varIndex, at the normalized coords.
+func (table *TableCOLR) Delta(varIndex uint32, coords []F2Dot14) float64 {
+	if table.VarStore == nil || varIndex == NoVarIndex {
+		return 0
+	}
+	index := VariationIndex{Outer: uint16(varIndex >> 16), Inner: uint16(varIndex)}
+	if table.VarIndexMap != nil {
+		if int(varIndex) >= len(table.VarIndexMap.Map) && len(table.VarIndexMap.Map) > 0 {
+			return 0
+		}
+		index = table.VarIndexMap.Index(int(varIndex))
+	}
+	return table.VarStore.Delta(index, coords)
+}
+
+// Walk calls visit for each paint in the paint graph of a color glyph,
+// depth first, with the depth of the paint below the root. PaintColrGlyph
+// paints are followed into the paints of the glyphs they draw. If visit
+// returns an error, the walk stops and returns it.
+func (table *TableCOLR) Walk(glyph GlyphID, visit func(paint Paint, depth int) error) error {
+	root := table.GlyphPaint(glyph)
+	if root == nil {
+		return nil
+	}
+	return table.walk(root, 0, map[GlyphID]bool{glyph: true}, visit)
+}
+
+func (table *TableCOLR) walk(paint Paint, depth int, glyphs map[GlyphID]bool, visit func(Paint, int) error) error {
+	if paint == nil {
+		return nil
+	}
+	if depth > maxPaintDepth {
+		return errPaintDepth
+	}
+	if err := visit(paint, depth); err != nil {
+		return err
+	}
+
+	var children []Paint
+	switch p := paint.(type) {
+	case *PaintColrLayers:
+		children = p.Layers
+	case *PaintGlyph:
+		children = []Paint{p.Paint}
+	case *PaintTransform:
+		children = []Paint{p.Paint}
+	case *PaintTranslate:
+		children = []Paint{p.Paint}
+	case *PaintScale:
+		children = []Paint{p.Paint}
+	case *PaintRotate:
+		children = []Paint{p.Paint}
+	case *PaintSkew:
+		children = []Paint{p.Paint}
+	case *PaintComposite:
+		children = []Paint{p.Source, p.Backdrop}
+	case *PaintColrGlyph:
+		if glyphs[p.Glyph] {
+			return fmt.Errorf("color glyph %d: %w", p.Glyph, errPaintCycle)
+		}
+		glyphs[p.Glyph] = true
+		defer delete(glyphs, p.Glyph)
+		children = []Paint{table.Paints[p.Glyph]}
+	}
+
+	for _, child := range children {
+		if err := table.walk(child, depth+1, glyphs, visit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// maxPaintDepth limits the nesting of paints when no limit is set in Options.
+const maxPaintDepth = 64
+
+var (
+	errInvalidCOLR = errors.New("invalid 'COLR' table")
+	errPaintDepth  = errors.New("paints are nested too deeply")
+	errPaintCycle  = errors.New("paint graph contains a cycle")
+)
+
+const (
+	colrHeaderSize   = 14
+	colrHeaderSizeV1 = 34
+)
+
+// paintSizes contains the size of each format of paint, excluding the stops
+// of color lines.
+var paintSizes = [...]int{
+	1: 6, 2: 5, 3: 9, 4: 16, 5: 20, 6: 16, 7: 20, 8: 12, 9: 16, 10: 6,
+	11: 3, 12: 7, 13: 7, 14: 8, 15: 12, 16: 8, 17: 12, 18: 12, 19: 16, 20: 6,
+	21: 10, 22: 10, 23: 14, 24: 6, 25: 10, 26: 10, 27: 14, 28: 8, 29: 12,
+	30: 12, 31: 16, 32: 8,
+}
+
+// colrParser holds the state used while the paint graph is parsed.
+type colrParser struct {
+	buf     []byte
+	opts    *Options
+	paints  map[int]Paint // paints contains the parsed paints by offset, as they may be shared.
+	parsing map[int]bool  // parsing contains the offsets of the paints being parsed.
+}
+
+func parseTableCOLR(tag Tag, buf []byte, opts *Options) (Table, error) {
+	if len(buf) < colrHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	table := &TableCOLR{
+		baseTable: baseTable(tag),
+		bytes:     buf,
+		Version:   binary.BigEndian.Uint16(buf),
+	}
+	if table.Version > 1 {
+		return nil, errInvalidCOLR
+	}
+
+	if err := table.parseBaseGlyphs(buf); err != nil {
+		return nil, err
+	}
+	if table.Version == 0 {
+		return table, nil
+	}
+
+	if len(buf) < colrHeaderSizeV1 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	baseGlyphListOffset := int(binary.BigEndian.Uint32(buf[14:]))
+	layerListOffset := int(binary.BigEndian.Uint32(buf[18:]))
+	clipListOffset := int(binary.BigEndian.Uint32(buf[22:]))
+	varIndexMapOffset := int(binary.BigEndian.Uint32(buf[26:]))
+	varStoreOffset := int(binary.BigEndian.Uint32(buf[30:]))
+
+	var err error
+	if varStoreOffset != 0 {
+		if varStoreOffset >= len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if table.VarStore, err = ParseItemVariationStore(buf[varStoreOffset:]); err != nil {
+			return nil, err
+		}
+	}
+	if varIndexMapOffset != 0 {
+		if varIndexMapOffset >= len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if table.VarIndexMap, err = parseDeltaSetIndexMap(buf[varIndexMapOffset:]); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &colrParser{buf: buf, opts: opts, paints: make(map[int]Paint), parsing: make(map[int]bool)}
+
+	// The layers are parsed first, as PaintColrLayers refers to them.
+	if layerListOffset != 0 {
+		offsets, err := p.offsets32(layerListOffset)
+		if err != nil {
+			return nil, err
+		}
+		table.Layers = make([]Paint, len(offsets))
+		for i, offset := range offsets {
+			if table.Layers[i], err = p.paint(layerListOffset+offset, table.Layers, 0); err != nil {
+				return nil, fmt.Errorf("layer %d: %w", i, err)
+			}
+		}
+	}
+
+	table.Paints = make(map[GlyphID]Paint)
+	if baseGlyphListOffset != 0 {
+		if baseGlyphListOffset+4 > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		count := int(binary.BigEndian.Uint32(buf[baseGlyphListOffset:]))
+		if baseGlyphListOffset+4+6*count > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for i := 0; i < count; i++ {
+			b := buf[baseGlyphListOffset+4+6*i:]
+			glyph := GlyphID(binary.BigEndian.Uint16(b))
+			offset := baseGlyphListOffset + int(binary.BigEndian.Uint32(b[2:]))
+			if table.Paints[glyph], err = p.paint(offset, table.Layers, 0); err != nil {
+				return nil, fmt.Errorf("color glyph %d: %w", glyph, err)
+			}
+		}
+	}
+
+	if clipListOffset != 0 {
+		if table.Clips, err = parseClipList(buf, clipListOffset); err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+// parseBaseGlyphs parses the version 0 color glyphs.
+func (table *TableCOLR) parseBaseGlyphs(buf []byte) error {
+	numBaseGlyphs := int(binary.BigEndian.Uint16(buf[2:]))
+	baseGlyphsOffset := int(binary.BigEndian.Uint32(buf[4:]))
+	layersOffset := int(binary.BigEndian.Uint32(buf[8:]))
+	numLayers := int(binary.BigEndian.Uint16(buf[12:]))
+	if numBaseGlyphs == 0 {
+		return nil
+	}
+	if baseGlyphsOffset+6*numBaseGlyphs > len(buf) || layersOffset+4*numLayers > len(buf) {
+		return io.ErrUnexpectedEOF
+	}
+
+	table.BaseGlyphs = make([]ColorGlyph, numBaseGlyphs)
+	for i := range table.BaseGlyphs {
+		b := buf[baseGlyphsOffset+6*i:]
+		first := int(binary.BigEndian.Uint16(b[2:]))
+		count := int(binary.BigEndian.Uint16(b[4:]))
+		if first+count > numLayers {
+			return errInvalidCOLR
+		}
+		glyph := ColorGlyph{
+			Glyph:  GlyphID(binary.BigEndian.Uint16(b)),
+			Layers: make([]ColorLayer, count),
+		}
+		for j := range glyph.Layers {
+			l := buf[layersOffset+4*(first+j):]
+			glyph.Layers[j] = ColorLayer{
+				Glyph:        GlyphID(binary.BigEndian.Uint16(l)),
+				PaletteIndex: binary.BigEndian.Uint16(l[2:]),
+			}
+		}
+		table.BaseGlyphs[i] = glyph
+	}
+	return nil
+}
+
+func parseClipList(buf []byte, offset int) ([]Clip, error) {
+	if offset+5 > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if buf[offset] != 1 {
+		return nil, errInvalidCOLR
+	}
+	count := int(binary.BigEndian.Uint32(buf[offset+1:]))
+	if offset+5+7*count > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	clips := make([]Clip, count)
+	for i := range clips {
+		b := buf[offset+5+7*i:]
+		boxOffset := offset + uint24(b[4:])
+		if boxOffset+9 > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		box := buf[boxOffset:]
+		clip := Clip{
+			StartGlyph: GlyphID(binary.BigEndian.Uint16(b)),
+			EndGlyph:   GlyphID(binary.BigEndian.Uint16(b[2:])),
+			Box: ClipBox{
+				XMin:         int16(binary.BigEndian.Uint16(box[1:])),
+				YMin:         int16(binary.BigEndian.Uint16(box[3:])),
+				XMax:         int16(binary.BigEndian.Uint16(box[5:])),
+				YMax:         int16(binary.BigEndian.Uint16(box[7:])),
+				VarIndexBase: NoVarIndex,
+			},
+		}
+		switch box[0] {
+		case 1:
+		case 2:
+			if boxOffset+13 > len(buf) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			clip.Box.VarIndexBase = binary.BigEndian.Uint32(box[9:])
+		default:
+			return nil, errInvalidCOLR
+		}
+		clips[i] = clip
+	}
+	return clips, nil
+}
+
+func uint24(b []byte) int {
+	return int(b[0])<<16 | int(b[1])<<8 | int(b[2])
+}
+
+// offsets32 returns the offsets in a list of 32-bit offsets with a 32-bit count.
+func (p *colrParser) offsets32(offset int) ([]int, error) {
+	if offset+4 > len(p.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	count := int(binary.BigEndian.Uint32(p.buf[offset:]))
+	if offset+4+4*count > len(p.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	offsets := make([]int, count)
+	for i := range offsets {
+		offsets[i] = int(binary.BigEndian.Uint32(p.buf[offset+4+4*i:]))
+	}
+	return offsets, nil
+}
+
+// paint parses the paint at offset, along with the paints it refers to.
+func (p *colrParser) paint(offset int, layers []Paint, depth int) (Paint, error) {
+	if depth > maxPaintDepth {
+		return nil, errPaintDepth
+	}
+	if err := p.opts.checkNestingDepth(depth); err != nil {
+		return nil, err
+	}
+	if paint, ok := p.paints[offset]; ok {
+		return paint, nil
+	}
+	if p.parsing[offset] {
+		return nil, errPaintCycle
+	}
+	p.parsing[offset] = true
+	defer delete(p.parsing, offset)
+
+	if offset >= len(p.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	format := int(p.buf[offset])
+	if format < 1 || format >= len(paintSizes) {
+		return nil, fmt.Errorf("%w: paint format %d", errInvalidCOLR, format)
+	}
+	if offset+paintSizes[format] > len(p.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := p.buf[offset:]
+
+	// Variable formats are one more than the static formats, and end with
+	// VarIndexBase, apart from PaintVarTransform where it ends the transform.
+	varIndexBase := uint32(NoVarIndex)
+	variable := format >= 3 && format%2 == 1 && format != 11
+	if variable && format != 13 {
+		varIndexBase = binary.BigEndian.Uint32(b[paintSizes[format]-4:])
+	}
+	child := func() (Paint, error) {
+		return p.paint(offset+uint24(b[1:]), layers, depth+1)
+	}
+	i16 := func(i int) int16 { return int16(binary.BigEndian.Uint16(b[i:])) }
+	f2 := func(i int) F2Dot14 { return F2Dot14(binary.BigEndian.Uint16(b[i:])) }
+
+	var paint Paint
+	var err error
+	switch format {
+	case 1:
+		first := binary.BigEndian.Uint32(b[2:])
+		count := uint32(b[1])
+		if uint64(first)+uint64(count) > uint64(len(layers)) {
+			return nil, fmt.Errorf("%w: layers %d to %d out of range", errInvalidCOLR, first, first+count)
+		}
+		paint = &PaintColrLayers{FirstLayerIndex: first, Layers: layers[first : first+count]}
+	case 2, 3:
+		paint = &PaintSolid{PaletteIndex: binary.BigEndian.Uint16(b[1:]), Alpha: f2(3), VarIndexBase: varIndexBase}
+	case 4, 5:
+		g := &PaintLinearGradient{X0: i16(4), Y0: i16(6), X1: i16(8), Y1: i16(10), X2: i16(12), Y2: i16(14), VarIndexBase: varIndexBase}
+		g.ColorLine, err = p.colorLine(offset+uint24(b[1:]), variable)
+		paint = g
+	case 6, 7:
+		g := &PaintRadialGradient{
+			X0: i16(4), Y0: i16(6), Radius0: binary.BigEndian.Uint16(b[8:]),
+			X1: i16(10), Y1: i16(12), Radius1: binary.BigEndian.Uint16(b[14:]),
+			VarIndexBase: varIndexBase,
+		}
+		g.ColorLine, err = p.colorLine(offset+uint24(b[1:]), variable)
+		paint = g
+	case 8, 9:
+		g := &PaintSweepGradient{CenterX: i16(4), CenterY: i16(6), StartAngle: f2(8), EndAngle: f2(10), VarIndexBase: varIndexBase}
+		g.ColorLine, err = p.colorLine(offset+uint24(b[1:]), variable)
+		paint = g
+	case 10:
+		g := &PaintGlyph{Glyph: GlyphID(binary.BigEndian.Uint16(b[4:]))}
+		g.Paint, err = child()
+		paint = g
+	case 11:
+		paint = &PaintColrGlyph{Glyph: GlyphID(binary.BigEndian.Uint16(b[1:]))}
+	case 12, 13:
+		t := &PaintTransform{}
+		if t.Transform, t.VarIndexBase, err = p.affine(offset+uint24(b[4:]), variable); err == nil {
+			t.Paint, err = child()
+		}
+		paint = t
+	case 14, 15:
+		t := &PaintTranslate{DX: i16(4), DY: i16(6), VarIndexBase: varIndexBase}
+		t.Paint, err = child()
+		paint = t
+	case 16, 17, 18, 19, 20, 21, 22, 23:
+		s := &PaintScale{
+			ScaleX:       f2(4),
+			Uniform:      format >= 20,
+			AroundCenter: format >= 18 && format <= 19 || format >= 22,
+			VarIndexBase: varIndexBase,
+		}
+		next := 6
+		if s.Uniform {
+			s.ScaleY = s.ScaleX
+		} else {
+			s.ScaleY, next = f2(6), 8
+		}
+		if s.AroundCenter {
+			s.CenterX, s.CenterY = i16(next), i16(next+2)
+		}
+		s.Paint, err = child()
+		paint = s
+	case 24, 25, 26, 27:
+		r := &PaintRotate{Angle: f2(4), AroundCenter: format >= 26, VarIndexBase: varIndexBase}
+		if r.AroundCenter {
+			r.CenterX, r.CenterY = i16(6), i16(8)
+		}
+		r.Paint, err = child()
+		paint = r
+	case 28, 29, 30, 31:
+		s := &PaintSkew{XSkewAngle: f2(4), YSkewAngle: f2(6), AroundCenter: format >= 30, VarIndexBase: varIndexBase}
+		if s.AroundCenter {
+			s.CenterX, s.CenterY = i16(8), i16(10)
+		}
+		s.Paint, err = child()
+		paint = s
+	case 32:
+		c := &PaintComposite{Mode: CompositeMode(b[4])}
+		if c.Source, err = child(); err == nil {
+			c.Backdrop, err = p.paint(offset+uint24(b[5:]), layers, depth+1)
+		}
+		paint = c
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	p.paints[offset] = paint
+	return paint, nil
+}
+
+// colorLine parses a ColorLine, or a VarColorLine if variable is true.
+func (p *colrParser) colorLine(offset int, variable bool) (*ColorLine, error) {
+	if offset+3 > len(p.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	stopSize := 6
+	if variable {
+		stopSize = 10
+	}
+	count := int(binary.BigEndian.Uint16(p.buf[offset+1:]))
+	if offset+3+stopSize*count > len(p.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	line := &ColorLine{Extend: p.buf[offset], Stops: make([]ColorStop, count)}
+	for i := range line.Stops {
+		b := p.buf[offset+3+stopSize*i:]
+		stop := ColorStop{
+			StopOffset:   F2Dot14(binary.BigEndian.Uint16(b)),
+			PaletteIndex: binary.BigEndian.Uint16(b[2:]),
+			Alpha:        F2Dot14(binary.BigEndian.Uint16(b[4:])),
+			VarIndexBase: NoVarIndex,
+		}
+		if variable {
+			stop.VarIndexBase = binary.BigEndian.Uint32(b[6:])
+		}
+		line.Stops[i] = stop
+	}
+	return line, nil
+}
+
+// affine parses an Affine2x3, or a VarAffine2x3 if variable is true.
+func (p *colrParser) affine(offset int, variable bool) (Affine, uint32, error) {
+	size := 24
+	if variable {
+		size = 28
+	}
+	if offset+size > len(p.buf) {
+		return Affine{}, 0, io.ErrUnexpectedEOF
+	}
+	b := p.buf[offset:]
+	fixed := func(i int) float64 { return fixedToFloat(binary.BigEndian.Uint32(b[4*i:])) }
+	transform := Affine{XX: fixed(0), YX: fixed(1), XY: fixed(2), YY: fixed(3), DX: fixed(4), DY: fixed(5)}
+	varIndexBase := uint32(NoVarIndex)
+	if variable {
+		varIndexBase = binary.BigEndian.Uint32(b[24:])
+	}
+	return transform, varIndexBase, nil
+}
+
+// COLRTable returns the table corresponding to the 'COLR' tag.
+func (font *Font) COLRTable() (*TableCOLR, error) {
+	t, err := font.Table(TagCOLR)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableCOLR), nil
+}
@@ -0,0 +1,286 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCPALRoundTrip(t *testing.T) {
+	want := &TableCPAL{
+		baseTable:         baseTable(TagCPAL),
+		NumPaletteEntries: 2,
+		Palettes: []Palette{
+			{Colors: []color.NRGBA{{R: 0xFF, A: 0xFF}, {G: 0x80, B: 0x40, A: 0x80}}, Type: PaletteUsableWithLightBackground, LabelNameID: 256},
+			{Colors: []color.NRGBA{{R: 0x10, G: 0x20, B: 0x30, A: 0xFF}, {A: 0}}, LabelNameID: NoPaletteLabel},
+		},
+		EntryLabels: []NameID{257, NoPaletteLabel},
+	}
+
+	parsed, err := parseTableCPAL(TagCPAL, want.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("parseTableCPAL() err = %q, want nil", err)
+	}
+	if got := parsed.(*TableCPAL); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTableCPAL() = %+v, want %+v", got, want)
+	}
+
+	// Version 0 has no types or labels.
+	want.Palettes[0].Type, want.Palettes[0].LabelNameID, want.EntryLabels = 0, NoPaletteLabel, nil
+	buf := want.Bytes()
+	if version := binary.BigEndian.Uint16(buf); version != 0 {
+		t.Errorf("Bytes() version = %d, want 0", version)
+	}
+	parsed, err = parseTableCPAL(TagCPAL, buf, nil)
+	if err != nil {
+		t.Fatalf("parseTableCPAL() err = %q, want nil", err)
+	}
+	if got := parsed.(*TableCPAL); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTableCPAL() = %+v, want %+v", got, want)
+	}
+
+	names := NewTableName()
+	if err := names.AddMicrosoftEnglishEntry(256, "Light"); err != nil {
+		t.Fatal(err)
+	}
+	palette := Palette{LabelNameID: 256}
+	if got := palette.Label(names); got != "Light" {
+		t.Errorf("Label() = %q, want %q", got, "Light")
+	}
+	if got, ok := want.Color(1, 0); !ok || got != want.Palettes[1].Colors[0] {
+		t.Errorf("Color(1, 0) = %v, %v, want %v", got, ok, want.Palettes[1].Colors[0])
+	}
+	if _, ok := want.Color(0, ForegroundPaletteIndex); ok {
+		t.Errorf("Color(0, ForegroundPaletteIndex) ok = true, want false")
+	}
+}
+
+// testCOLR returns a 'COLR' table with a version 0 color glyph 5, and version
+// 1 color glyphs 10 and 11. Glyph 10 draws glyph 1 in a variable solid color,
+// composited over glyph 11 scaled by 2 and glyph 2 filled with a linear
+// gradient. Glyph 11 rotates glyph 3, filled with the same solid color.
+func testCOLR() []byte {
+	buf := make([]byte, colrHeaderSizeV1)
+	put16 := func(at, v int) { binary.BigEndian.PutUint16(buf[at:], uint16(v)) }
+	put24 := func(at, v int) { buf[at], buf[at+1], buf[at+2] = byte(v>>16), byte(v>>8), byte(v) }
+	put32 := func(at, v int) { binary.BigEndian.PutUint32(buf[at:], uint32(v)) }
+	add := func(b ...byte) int {
+		buf = append(buf, b...)
+		return len(buf) - len(b)
+	}
+
+	put16(0, 1)
+	put16(2, 1)
+	put32(4, add(0, 5, 0, 0, 0, 2))
+	put32(8, add(0, 6, 0, 0, 0, 7, 0xFF, 0xFF))
+	put16(12, 2)
+
+	baseGlyphList := add(0, 0, 0, 2, 0, 10, 0, 0, 0, 0, 0, 11, 0, 0, 0, 0)
+	put32(14, baseGlyphList)
+	layerList := add(0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0)
+	put32(18, layerList)
+
+	rotate := add(24, 0, 0, 0, 0x20, 0)
+	put32(baseGlyphList+4+6+2, rotate-baseGlyphList)
+	glyph3 := add(10, 0, 0, 0, 0, 3)
+	put24(rotate+1, glyph3-rotate)
+
+	layers := add(1, 2, 0, 0, 0, 0)
+	put32(baseGlyphList+4+2, layers-baseGlyphList)
+	glyph1 := add(10, 0, 0, 0, 0, 1)
+	put32(layerList+4, glyph1-layerList)
+	solid := add(3, 0, 1, 0x40, 0, 0, 0, 0, 0)
+	put24(glyph1+1, solid-glyph1)
+	put24(glyph3+1, solid-glyph3)
+
+	composite := add(32, 0, 0, 0, byte(CompositeSrcOver), 0, 0, 0)
+	put32(layerList+8, composite-layerList)
+	transform := add(12, 0, 0, 0, 0, 0, 0)
+	put24(composite+1, transform-composite)
+	affine := add(0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0)
+	put24(transform+4, affine-transform)
+	colrGlyph := add(11, 0, 11)
+	put24(transform+1, colrGlyph-transform)
+	scale := add(22, 0, 0, 0, 0x20, 0, 0, 100, 0, 100)
+	put24(composite+5, scale-composite)
+	glyph2 := add(10, 0, 0, 0, 0, 2)
+	put24(scale+1, glyph2-scale)
+	linear := add(4, 0, 0, 0, 0, 0, 0, 0, 0, 100, 0, 0, 0, 0, 0, 100)
+	put24(glyph2+1, linear-glyph2)
+	colorLine := add(ExtendReflect, 0, 2, 0, 0, 0, 0, 0x40, 0, 0x40, 0, 0, 1, 0x40, 0)
+	put24(linear+1, colorLine-linear)
+
+	clipList := add(1, 0, 0, 0, 1, 0, 10, 0, 11, 0, 0, 0)
+	put32(22, clipList)
+	box := add(1, 0xFF, 0xCE, 0, 0, 0x03, 0xE8, 0x03, 0xE8)
+	put24(clipList+5+4, box-clipList)
+
+	store := &ItemVariationStore{
+		Regions: []VariationRegion{{{Peak: 1 << 14, End: 1 << 14}}},
+		Data:    []ItemVariationData{{RegionIndexes: []uint16{0}, Deltas: [][]int32{{-8192}}}},
+	}
+	put32(30, add(store.Bytes()...))
+
+	return buf
+}
+
+func TestCOLR(t *testing.T) {
+	parsed, err := parseTableCOLR(TagCOLR, testCOLR(), nil)
+	if err != nil {
+		t.Fatalf("parseTableCOLR() err = %q, want nil", err)
+	}
+	font := New(TypeTrueType)
+	font.AddTable(TagCOLR, parsed)
+	colr, err := font.COLRTable()
+	if err != nil {
+		t.Fatalf("COLRTable() err = %q, want nil", err)
+	}
+
+	wantV0 := &ColorGlyph{Glyph: 5, Layers: []ColorLayer{{6, 0}, {7, ForegroundPaletteIndex}}}
+	if got := colr.ColorGlyph(5); !reflect.DeepEqual(got, wantV0) {
+		t.Errorf("ColorGlyph(5) = %+v, want %+v", got, wantV0)
+	}
+	if got := colr.ColorGlyph(10); got != nil {
+		t.Errorf("ColorGlyph(10) = %+v, want nil", got)
+	}
+
+	solid := &PaintSolid{PaletteIndex: 1, Alpha: 1 << 14, VarIndexBase: 0}
+	want := &PaintColrLayers{FirstLayerIndex: 0, Layers: []Paint{
+		&PaintGlyph{Glyph: 1, Paint: solid},
+		&PaintComposite{
+			Source: &PaintTransform{
+				Paint:        &PaintColrGlyph{Glyph: 11},
+				Transform:    Affine{XX: 2, YY: 2},
+				VarIndexBase: NoVarIndex,
+			},
+			Mode: CompositeSrcOver,
+			Backdrop: &PaintScale{
+				Paint: &PaintGlyph{Glyph: 2, Paint: &PaintLinearGradient{
+					ColorLine: &ColorLine{Extend: ExtendReflect, Stops: []ColorStop{
+						{StopOffset: 0, PaletteIndex: 0, Alpha: 1 << 14, VarIndexBase: NoVarIndex},
+						{StopOffset: 1 << 14, PaletteIndex: 1, Alpha: 1 << 14, VarIndexBase: NoVarIndex},
+					}},
+					X1: 100, Y2: 100,
+					VarIndexBase: NoVarIndex,
+				}},
+				ScaleX: 1 << 13, ScaleY: 1 << 13,
+				Uniform: true, AroundCenter: true, CenterX: 100, CenterY: 100,
+				VarIndexBase: NoVarIndex,
+			},
+		},
+	}}
+	if got := colr.GlyphPaint(10); !reflect.DeepEqual(got, want) {
+		t.Errorf("GlyphPaint(10) = %s, want %s", paintString(got), paintString(want))
+	}
+
+	// Paints referenced from several places are shared.
+	rotate := colr.GlyphPaint(11).(*PaintRotate)
+	if rotate.Angle != 1<<13 || rotate.Paint.(*PaintGlyph).Paint != colr.Layers[0].(*PaintGlyph).Paint {
+		t.Errorf("GlyphPaint(11) = %s, want rotation of the shared solid paint", paintString(rotate))
+	}
+
+	var walked []string
+	err = colr.Walk(10, func(paint Paint, depth int) error {
+		walked = append(walked, fmt.Sprintf("%d:%T", depth, paint))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() err = %q, want nil", err)
+	}
+	wantWalked := "0:*sfnt.PaintColrLayers 1:*sfnt.PaintGlyph 2:*sfnt.PaintSolid 1:*sfnt.PaintComposite " +
+		"2:*sfnt.PaintTransform 3:*sfnt.PaintColrGlyph 4:*sfnt.PaintRotate 5:*sfnt.PaintGlyph 6:*sfnt.PaintSolid " +
+		"2:*sfnt.PaintScale 3:*sfnt.PaintGlyph 4:*sfnt.PaintLinearGradient"
+	if got := strings.Join(walked, " "); got != wantWalked {
+		t.Errorf("Walk() visited %s, want %s", got, wantWalked)
+	}
+
+	// Version 0 glyphs are walked as layers of solid colors.
+	walked = nil
+	err = colr.Walk(5, func(paint Paint, depth int) error {
+		walked = append(walked, fmt.Sprintf("%d:%T", depth, paint))
+		return nil
+	})
+	if got, want := strings.Join(walked, " "), "0:*sfnt.PaintColrLayers 1:*sfnt.PaintGlyph 2:*sfnt.PaintSolid 1:*sfnt.PaintGlyph 2:*sfnt.PaintSolid"; err != nil || got != want {
+		t.Errorf("Walk(5) visited %s, %v, want %s", got, err, want)
+	}
+
+	if box, ok := colr.ClipBox(11); !ok || box != (ClipBox{XMin: -50, XMax: 1000, YMax: 1000, VarIndexBase: NoVarIndex}) {
+		t.Errorf("ClipBox(11) = %+v, %v, want box from -50,0 to 1000,1000", box, ok)
+	}
+	if _, ok := colr.ClipBox(12); ok {
+		t.Errorf("ClipBox(12) ok = true, want false")
+	}
+
+	if got := colr.Delta(solid.VarIndexBase, []F2Dot14{1 << 14}); got != -8192 {
+		t.Errorf("Delta(0) = %v, want -8192", got)
+	}
+	if got := colr.Delta(NoVarIndex, []F2Dot14{1 << 14}); got != 0 {
+		t.Errorf("Delta(NoVarIndex) = %v, want 0", got)
+	}
+}
+
+func TestCOLRInvalid(t *testing.T) {
+	colr, err := parseTableCOLR(TagCOLR, testCOLR(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := colr.(*TableCOLR)
+
+	// Glyph 11 draws glyph 10, which draws glyph 11.
+	table.Paints[11] = &PaintColrGlyph{Glyph: 10}
+	if err := table.Walk(10, func(Paint, int) error { return nil }); !errors.Is(err, errPaintCycle) {
+		t.Errorf("Walk() err = %v, want %v", err, errPaintCycle)
+	}
+
+	// The first layer of glyph 10 is glyph 10 itself.
+	buf := testCOLR()
+	baseGlyphList := int(binary.BigEndian.Uint32(buf[14:]))
+	layerList := int(binary.BigEndian.Uint32(buf[18:]))
+	layers := baseGlyphList + int(binary.BigEndian.Uint32(buf[baseGlyphList+6:]))
+	binary.BigEndian.PutUint32(buf[layerList+4:], uint32(layers-layerList))
+	colr, err = parseTableCOLR(TagCOLR, buf, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := colr.(*TableCOLR).Walk(10, func(Paint, int) error { return nil }); !errors.Is(err, errPaintDepth) {
+		t.Errorf("Walk() err = %v, want %v", err, errPaintDepth)
+	}
+
+	// The paint of glyph 11 is beyond the end of the table.
+	buf = testCOLR()
+	binary.BigEndian.PutUint32(buf[baseGlyphList+12:], uint32(len(buf)))
+	if _, err := parseTableCOLR(TagCOLR, buf, nil); err == nil {
+		t.Errorf("parseTableCOLR() with invalid offset err = nil, want error")
+	}
+}
+
+// paintString formats a paint graph for test failures.
+func paintString(paint Paint) string {
+	switch p := paint.(type) {
+	case *PaintColrLayers:
+		var layers []string
+		for _, l := range p.Layers {
+			layers = append(layers, paintString(l))
+		}
+		return fmt.Sprintf("Layers[%s]", strings.Join(layers, ", "))
+	case *PaintGlyph:
+		return fmt.Sprintf("Glyph(%d, %s)", p.Glyph, paintString(p.Paint))
+	case *PaintTransform:
+		return fmt.Sprintf("Transform(%+v, %s)", p.Transform, paintString(p.Paint))
+	case *PaintScale:
+		return fmt.Sprintf("Scale(%+v, %s)", *p, paintString(p.Paint))
+	case *PaintRotate:
+		return fmt.Sprintf("Rotate(%v, %s)", p.Angle, paintString(p.Paint))
+	case *PaintComposite:
+		return fmt.Sprintf("Composite(%s, %d, %s)", paintString(p.Source), p.Mode, paintString(p.Backdrop))
+	case *PaintLinearGradient:
+		return fmt.Sprintf("Linear(%+v, %+v)", *p, *p.ColorLine)
+	case nil:
+		return "nil"
+	}
+	return fmt.Sprintf("%+v", paint)
+}
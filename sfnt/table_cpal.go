@@ -0,0 +1,229 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"image/color"
+	"io"
+)
+
+// TableCPAL contains the color palettes used by the layers of color glyphs in 'COLR'.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cpal
+type TableCPAL struct {
+	baseTable
+
+	// NumPaletteEntries is the number of colors in each palette.
+	NumPaletteEntries int
+	Palettes          []Palette
+
+	// EntryLabels contains the name of each palette entry, such as "Outline",
+	// or NoPaletteLabel. It is nil if the entries are not named.
+	EntryLabels []NameID
+}
+
+// Palette is a set of colors, indexed by the palette indexes in 'COLR'.
+type Palette struct {
+	// Colors contains NumPaletteEntries colors, which are not premultiplied by alpha.
+	Colors []color.NRGBA
+	// Type contains flags describing the backgrounds the palette is suitable for.
+	Type uint32
+	// LabelNameID is the name of the palette, such as "Dark", or NoPaletteLabel.
+	LabelNameID NameID
+}
+
+// Flags of palette types.
+const (
+	PaletteUsableWithLightBackground = 0x0001
+	PaletteUsableWithDarkBackground  = 0x0002
+)
+
+// NoPaletteLabel is the label of palettes and palette entries that are not named.
+const NoPaletteLabel = NameID(0xFFFF)
+
+// Label returns the name of the palette from names, or "" if it has no name.
+func (palette *Palette) Label(names *TableName) string {
+	if palette.LabelNameID == NoPaletteLabel {
+		return ""
+	}
+	return findName(names, palette.LabelNameID)
+}
+
+// EntryLabel returns the name of a palette entry from names, or "" if it has no name.
+func (table *TableCPAL) EntryLabel(names *TableName, entry int) string {
+	if entry >= len(table.EntryLabels) || table.EntryLabels[entry] == NoPaletteLabel {
+		return ""
+	}
+	return findName(names, table.EntryLabels[entry])
+}
+
+// Color returns a color from a palette, or false if either is out of range.
+func (table *TableCPAL) Color(palette, entry int) (color.NRGBA, bool) {
+	if palette < 0 || palette >= len(table.Palettes) {
+		return color.NRGBA{}, false
+	}
+	colors := table.Palettes[palette].Colors
+	if entry < 0 || entry >= len(colors) {
+		return color.NRGBA{}, false
+	}
+	return colors[entry], true
+}
+
+const (
+	cpalHeaderSize   = 12
+	cpalHeaderSizeV1 = 24
+)
+
+var errInvalidCPAL = errors.New("invalid 'CPAL' table")
+
+func parseTableCPAL(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < cpalHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	version := binary.BigEndian.Uint16(buf)
+	numEntries := int(binary.BigEndian.Uint16(buf[2:]))
+	numPalettes := int(binary.BigEndian.Uint16(buf[4:]))
+	numColors := int(binary.BigEndian.Uint16(buf[6:]))
+	colorsOffset := int(binary.BigEndian.Uint32(buf[8:]))
+	if version > 1 {
+		return nil, errInvalidCPAL
+	}
+
+	indexesEnd := cpalHeaderSize + 2*numPalettes
+	if len(buf) < indexesEnd || colorsOffset+4*numColors > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableCPAL{
+		baseTable:         baseTable(tag),
+		NumPaletteEntries: numEntries,
+		Palettes:          make([]Palette, numPalettes),
+	}
+	for i := range table.Palettes {
+		first := int(binary.BigEndian.Uint16(buf[cpalHeaderSize+2*i:]))
+		if first+numEntries > numColors {
+			return nil, errInvalidCPAL
+		}
+		palette := Palette{
+			Colors:      make([]color.NRGBA, numEntries),
+			LabelNameID: NoPaletteLabel,
+		}
+		for j := range palette.Colors {
+			b := buf[colorsOffset+4*(first+j):]
+			palette.Colors[j] = color.NRGBA{B: b[0], G: b[1], R: b[2], A: b[3]}
+		}
+		table.Palettes[i] = palette
+	}
+
+	if version == 0 {
+		return table, nil
+	}
+
+	if len(buf) < indexesEnd+12 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	typesOffset := int(binary.BigEndian.Uint32(buf[indexesEnd:]))
+	labelsOffset := int(binary.BigEndian.Uint32(buf[indexesEnd+4:]))
+	entryLabelsOffset := int(binary.BigEndian.Uint32(buf[indexesEnd+8:]))
+
+	if typesOffset != 0 {
+		if typesOffset+4*numPalettes > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for i := range table.Palettes {
+			table.Palettes[i].Type = binary.BigEndian.Uint32(buf[typesOffset+4*i:])
+		}
+	}
+	if labelsOffset != 0 {
+		if labelsOffset+2*numPalettes > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for i := range table.Palettes {
+			table.Palettes[i].LabelNameID = NameID(binary.BigEndian.Uint16(buf[labelsOffset+2*i:]))
+		}
+	}
+	if entryLabelsOffset != 0 {
+		if entryLabelsOffset+2*numEntries > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		table.EntryLabels = make([]NameID, numEntries)
+		for i := range table.EntryLabels {
+			table.EntryLabels[i] = NameID(binary.BigEndian.Uint16(buf[entryLabelsOffset+2*i:]))
+		}
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table. Version 1 is written
+// if any palette has a type or label, or the entries are labelled.
+func (table *TableCPAL) Bytes() []byte {
+	numEntries := table.NumPaletteEntries
+	numPalettes := len(table.Palettes)
+
+	var hasTypes, hasLabels bool
+	for _, palette := range table.Palettes {
+		hasTypes = hasTypes || palette.Type != 0
+		hasLabels = hasLabels || palette.LabelNameID != NoPaletteLabel
+	}
+	version := uint16(0)
+	headerSize := cpalHeaderSize + 2*numPalettes
+	if hasTypes || hasLabels || table.EntryLabels != nil {
+		version = 1
+		headerSize += 12
+	}
+
+	colorsOffset := headerSize
+	buf := make([]byte, colorsOffset+4*numEntries*numPalettes)
+	binary.BigEndian.PutUint16(buf, version)
+	binary.BigEndian.PutUint16(buf[2:], uint16(numEntries))
+	binary.BigEndian.PutUint16(buf[4:], uint16(numPalettes))
+	binary.BigEndian.PutUint16(buf[6:], uint16(numEntries*numPalettes))
+	binary.BigEndian.PutUint32(buf[8:], uint32(colorsOffset))
+
+	for i, palette := range table.Palettes {
+		binary.BigEndian.PutUint16(buf[cpalHeaderSize+2*i:], uint16(i*numEntries))
+		for j := 0; j < numEntries && j < len(palette.Colors); j++ {
+			c := palette.Colors[j]
+			copy(buf[colorsOffset+4*(i*numEntries+j):], []byte{c.B, c.G, c.R, c.A})
+		}
+	}
+
+	if version == 0 {
+		return buf
+	}
+
+	arrays := cpalHeaderSize + 2*numPalettes
+	if hasTypes {
+		binary.BigEndian.PutUint32(buf[arrays:], uint32(len(buf)))
+		for _, palette := range table.Palettes {
+			buf = append(buf, 0, 0, 0, 0)
+			binary.BigEndian.PutUint32(buf[len(buf)-4:], palette.Type)
+		}
+	}
+	if hasLabels {
+		binary.BigEndian.PutUint32(buf[arrays+4:], uint32(len(buf)))
+		for _, palette := range table.Palettes {
+			buf = appendUint16(buf, uint16(palette.LabelNameID))
+		}
+	}
+	if table.EntryLabels != nil {
+		binary.BigEndian.PutUint32(buf[arrays+8:], uint32(len(buf)))
+		for i := 0; i < numEntries; i++ {
+			label := NoPaletteLabel
+			if i < len(table.EntryLabels) {
+				label = table.EntryLabels[i]
+			}
+			buf = appendUint16(buf, uint16(label))
+		}
+	}
+	return buf
+}
+
+// CPALTable returns the table corresponding to the 'CPAL' tag.
+func (font *Font) CPALTable() (*TableCPAL, error) {
+	t, err := font.Table(TagCPAL)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableCPAL), nil
+}
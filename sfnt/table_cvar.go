@@ -0,0 +1,59 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// TableCvar contains the variations of the control values in the 'cvt ' table
+// of a variable font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cvar
+type TableCvar struct {
+	baseTable
+
+	bytes []byte
+}
+
+func parseTableCvar(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if major := binary.BigEndian.Uint16(buf); major != 1 {
+		return nil, fmt.Errorf("unsupported 'cvar' version %d", major)
+	}
+	return &TableCvar{baseTable: baseTable(tag), bytes: buf}, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableCvar) Bytes() []byte {
+	return table.bytes
+}
+
+// Variations returns the tuple variations of the control values, with their
+// deltas in DeltasX. axisCount is the number of axes in 'fvar', and numValues
+// the number of values in 'cvt '.
+func (table *TableCvar) Variations(axisCount, numValues int) ([]TupleVariation, error) {
+	return parseTupleVariations(table.bytes, 4, axisCount, nil, numValues, false)
+}
+
+// NewTableCvar returns a 'cvar' table containing the tuple variations of the
+// control values, whose deltas are in DeltasX.
+func NewTableCvar(axisCount int, variations []TupleVariation) *TableCvar {
+	buf := appendUint16(nil, 1)
+	buf = appendUint16(buf, 0)
+	if len(variations) == 0 {
+		buf = append(buf, 0, 0, 0, 8)
+	}
+	buf = appendTupleVariations(buf, 4, axisCount, variations)
+	return &TableCvar{baseTable: baseTable(TagCvar), bytes: buf}
+}
+
+// CvarTable returns the table corresponding to the 'cvar' tag.
+func (font *Font) CvarTable() (*TableCvar, error) {
+	t, err := font.Table(TagCvar)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableCvar), nil
+}
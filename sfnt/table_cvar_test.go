@@ -0,0 +1,57 @@
+package sfnt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCvar(t *testing.T) {
+	buf := []byte{
+		0, 1, 0, 0, // version 1.0
+		0, 1, // tupleVariationCount
+		0, 14, // dataOffset
+		0, 7, // variationDataSize
+		0xA0, 0, // EMBEDDED_PEAK_TUPLE | PRIVATE_POINT_NUMBERS
+		0x40, 0, // peak wght=1.0
+		2, 0x01, 0, 2, // values 0 and 2
+		0x01, 5, 0xFB, // deltas 5 and -5
+	}
+	table, err := parseTableCvar(TagCvar, buf, nil)
+	if err != nil {
+		t.Fatalf("parseTableCvar() err = %q, want nil", err)
+	}
+	got, err := table.(*TableCvar).Variations(1, 3)
+	if err != nil {
+		t.Fatalf("Variations() err = %q, want nil", err)
+	}
+	want := []TupleVariation{{
+		Region:  VariationRegion{{Peak: 1 << 14, End: 1 << 14}},
+		Points:  []uint16{0, 2},
+		DeltasX: []int32{5, -5},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Variations() = %+v, want %+v", got, want)
+	}
+
+	// Deltas are not inferred for control values without explicit deltas.
+	deltas := got[0].Deltas(make([]OutlinePoint, 3), nil)
+	if want := []OutlinePoint{{X: 5}, {}, {X: -5}}; !reflect.DeepEqual(deltas, want) {
+		t.Errorf("Deltas() = %v, want %v", deltas, want)
+	}
+
+	want = append(want, TupleVariation{
+		Region:  VariationRegion{{Start: -1 << 14, Peak: -1 << 13}},
+		DeltasX: []int32{0, 300, -1},
+	})
+	got, err = NewTableCvar(1, want).Variations(1, 3)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("NewTableCvar().Variations() = %+v, %v, want %+v", got, err, want)
+	}
+	if got, err := NewTableCvar(1, nil).Variations(1, 3); err != nil || len(got) != 0 {
+		t.Errorf("NewTableCvar(nil).Variations() = %+v, %v, want none", got, err)
+	}
+
+	if _, err := parseTableCvar(TagCvar, []byte{0, 2, 0, 0, 0, 0, 0, 8}, nil); err == nil {
+		t.Errorf("parseTableCvar() of version 2 err = nil, want an error")
+	}
+}
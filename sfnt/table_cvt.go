@@ -0,0 +1,47 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// TableCvt contains the control values, the distances in font units that are
+// referred to by TrueType hinting instructions.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cvt
+type TableCvt struct {
+	baseTable
+
+	Values []int16
+}
+
+func parseTableCvt(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf)%2 != 0 {
+		return nil, errors.New("length is not a multiple of 2")
+	}
+	table := &TableCvt{
+		baseTable: baseTable(tag),
+		Values:    make([]int16, len(buf)/2),
+	}
+	for i := range table.Values {
+		table.Values[i] = int16(binary.BigEndian.Uint16(buf[2*i:]))
+	}
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableCvt) Bytes() []byte {
+	buf := make([]byte, 2*len(table.Values))
+	for i, v := range table.Values {
+		binary.BigEndian.PutUint16(buf[2*i:], uint16(v))
+	}
+	return buf
+}
+
+// CvtTable returns the table corresponding to the 'cvt ' tag.
+func (font *Font) CvtTable() (*TableCvt, error) {
+	t, err := font.Table(TagCvt)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableCvt), nil
+}
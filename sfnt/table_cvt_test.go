@@ -0,0 +1,28 @@
+package sfnt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCvt(t *testing.T) {
+	want := &TableCvt{
+		baseTable: baseTable(TagCvt),
+		Values:    []int16{0, 1500, -300},
+	}
+	buf := want.Bytes()
+	if len(buf) != 6 {
+		t.Errorf("Bytes() length = %d, want 6", len(buf))
+	}
+	table, err := ParseTable(TagCvt, buf)
+	if err != nil {
+		t.Fatalf("ParseTable(%q) err = %q, want nil", TagCvt, err)
+	}
+	if !reflect.DeepEqual(table, want) {
+		t.Errorf("ParseTable(%q) = %+v, want %+v", TagCvt, table, want)
+	}
+
+	if _, err := ParseTable(TagCvt, buf[:5]); err == nil {
+		t.Errorf("ParseTable(%q) with an odd length err = nil, want error", TagCvt)
+	}
+}
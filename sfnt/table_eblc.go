@@ -0,0 +1,465 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// TableEBLC locates the bitmaps of glyphs in 'EBDT', or in 'CBDT' when
+// parsed from the 'CBLC' table. The bitmaps are grouped in strikes, which
+// each contain the bitmaps for one size. The table is read only, so Bytes
+// returns the bytes that were parsed.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/eblc
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cblc
+type TableEBLC struct {
+	baseTable
+
+	bytes        []byte
+	MajorVersion uint16 // MajorVersion is 2 for 'EBLC', and 3 for 'CBLC'.
+	MinorVersion uint16
+	Strikes      []BitmapStrike
+}
+
+// BitmapStrike contains the locations of the bitmaps of glyphs for one size.
+type BitmapStrike struct {
+	ColorRef             uint32
+	Hori, Vert           SbitLineMetrics
+	StartGlyph, EndGlyph GlyphID
+	PPEMX, PPEMY         uint8
+	BitDepth             uint8 // BitDepth is the number of bits per pixel: 1, 2, 4, 8 or 32 for color.
+	Flags                uint8 // Flags is BitmapHorizontalMetrics, BitmapVerticalMetrics or both.
+
+	// Subtables contains the locations of the glyphs, sorted by glyph ID.
+	Subtables []BitmapIndexSubtable
+}
+
+// Flags of bitmap strikes, which give the direction of small glyph metrics.
+const (
+	BitmapHorizontalMetrics = 0x01
+	BitmapVerticalMetrics   = 0x02
+)
+
+// SbitLineMetrics contains the line metrics of a strike, in pixels.
+type SbitLineMetrics struct {
+	Ascender, Descender   int8
+	WidthMax              uint8
+	CaretSlopeNumerator   int8
+	CaretSlopeDenominator int8
+	CaretOffset           int8
+	MinOriginSB           int8
+	MinAdvanceSB          int8
+	MaxBeforeBL           int8
+	MinAfterBL            int8
+}
+
+// BitmapMetrics contains the size and position of a bitmap, in pixels.
+type BitmapMetrics struct {
+	Height, Width uint8
+	HoriBearingX  int8
+	HoriBearingY  int8
+	HoriAdvance   uint8
+	VertBearingX  int8
+	VertBearingY  int8
+	VertAdvance   uint8
+}
+
+// BitmapIndexSubtable locates the bitmaps of a range of glyphs, which share
+// the same image format in 'EBDT' or 'CBDT'.
+type BitmapIndexSubtable struct {
+	FirstGlyph, LastGlyph GlyphID
+	IndexFormat           uint16 // IndexFormat is the format of the subtable, from 1 to 5.
+	ImageFormat           uint16 // ImageFormat is the format of the glyph data, such as 17 for PNG images.
+	// Metrics contains the metrics shared by all the glyphs for index formats
+	// 2 and 5, and is nil for the others.
+	Metrics *BitmapMetrics
+	// Glyphs contains the location of each glyph with a bitmap, sorted by glyph ID.
+	Glyphs []BitmapLocation
+}
+
+// BitmapLocation is the location of the data of a glyph in 'EBDT' or 'CBDT'.
+type BitmapLocation struct {
+	Glyph          GlyphID
+	Offset, Length uint32
+}
+
+// Bytes returns the bytes of the table as parsed.
+func (table *TableEBLC) Bytes() []byte {
+	return table.bytes
+}
+
+// Location returns the subtable and location of the bitmap of glyph in the
+// strike, or false if it has no bitmap.
+func (strike *BitmapStrike) Location(glyph GlyphID) (*BitmapIndexSubtable, BitmapLocation, bool) {
+	if glyph < strike.StartGlyph || glyph > strike.EndGlyph {
+		return nil, BitmapLocation{}, false
+	}
+	i := sort.Search(len(strike.Subtables), func(i int) bool {
+		return strike.Subtables[i].LastGlyph >= glyph
+	})
+	if i == len(strike.Subtables) || strike.Subtables[i].FirstGlyph > glyph {
+		return nil, BitmapLocation{}, false
+	}
+	subtable := &strike.Subtables[i]
+	j := sort.Search(len(subtable.Glyphs), func(j int) bool {
+		return subtable.Glyphs[j].Glyph >= glyph
+	})
+	if j == len(subtable.Glyphs) || subtable.Glyphs[j].Glyph != glyph {
+		return nil, BitmapLocation{}, false
+	}
+	return subtable, subtable.Glyphs[j], true
+}
+
+const (
+	eblcHeaderSize     = 8
+	bitmapSizeSize     = 48
+	bigGlyphMetricSize = 8
+)
+
+var errInvalidEBLC = errors.New("invalid bitmap location table")
+
+func parseTableEBLC(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < eblcHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	numSizes := int(binary.BigEndian.Uint32(buf[4:]))
+	if eblcHeaderSize+bitmapSizeSize*numSizes > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableEBLC{
+		baseTable:    baseTable(tag),
+		bytes:        buf,
+		MajorVersion: binary.BigEndian.Uint16(buf),
+		MinorVersion: binary.BigEndian.Uint16(buf[2:]),
+		Strikes:      make([]BitmapStrike, numSizes),
+	}
+	if table.MajorVersion != 2 && table.MajorVersion != 3 {
+		return nil, errInvalidEBLC
+	}
+
+	for i := range table.Strikes {
+		b := buf[eblcHeaderSize+bitmapSizeSize*i:]
+		strike := BitmapStrike{
+			ColorRef:   binary.BigEndian.Uint32(b[12:]),
+			Hori:       parseSbitLineMetrics(b[16:]),
+			Vert:       parseSbitLineMetrics(b[28:]),
+			StartGlyph: GlyphID(binary.BigEndian.Uint16(b[40:])),
+			EndGlyph:   GlyphID(binary.BigEndian.Uint16(b[42:])),
+			PPEMX:      b[44],
+			PPEMY:      b[45],
+			BitDepth:   b[46],
+			Flags:      b[47],
+		}
+		arrayOffset := int(binary.BigEndian.Uint32(b))
+		numSubtables := int(binary.BigEndian.Uint32(b[8:]))
+		if arrayOffset+8*numSubtables > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		strike.Subtables = make([]BitmapIndexSubtable, numSubtables)
+		for j := range strike.Subtables {
+			r := buf[arrayOffset+8*j:]
+			subtable := &strike.Subtables[j]
+			subtable.FirstGlyph = GlyphID(binary.BigEndian.Uint16(r))
+			subtable.LastGlyph = GlyphID(binary.BigEndian.Uint16(r[2:]))
+			offset := arrayOffset + int(binary.BigEndian.Uint32(r[4:]))
+			if err := subtable.parse(buf, offset); err != nil {
+				return nil, fmt.Errorf("strike %d subtable %d: %w", i, j, err)
+			}
+		}
+		sort.SliceStable(strike.Subtables, func(a, b int) bool {
+			return strike.Subtables[a].FirstGlyph < strike.Subtables[b].FirstGlyph
+		})
+		table.Strikes[i] = strike
+	}
+
+	return table, nil
+}
+
+func parseSbitLineMetrics(b []byte) SbitLineMetrics {
+	return SbitLineMetrics{
+		Ascender:              int8(b[0]),
+		Descender:             int8(b[1]),
+		WidthMax:              b[2],
+		CaretSlopeNumerator:   int8(b[3]),
+		CaretSlopeDenominator: int8(b[4]),
+		CaretOffset:           int8(b[5]),
+		MinOriginSB:           int8(b[6]),
+		MinAdvanceSB:          int8(b[7]),
+		MaxBeforeBL:           int8(b[8]),
+		MinAfterBL:            int8(b[9]),
+	}
+}
+
+func parseBigGlyphMetrics(b []byte) BitmapMetrics {
+	return BitmapMetrics{
+		Height:       b[0],
+		Width:        b[1],
+		HoriBearingX: int8(b[2]),
+		HoriBearingY: int8(b[3]),
+		HoriAdvance:  b[4],
+		VertBearingX: int8(b[5]),
+		VertBearingY: int8(b[6]),
+		VertAdvance:  b[7],
+	}
+}
+
+// parseSmallGlyphMetrics parses metrics for a single direction, which are
+// vertical if flags only has BitmapVerticalMetrics set.
+func parseSmallGlyphMetrics(b []byte, flags uint8) BitmapMetrics {
+	m := BitmapMetrics{Height: b[0], Width: b[1]}
+	if flags&(BitmapHorizontalMetrics|BitmapVerticalMetrics) == BitmapVerticalMetrics {
+		m.VertBearingX, m.VertBearingY, m.VertAdvance = int8(b[2]), int8(b[3]), b[4]
+	} else {
+		m.HoriBearingX, m.HoriBearingY, m.HoriAdvance = int8(b[2]), int8(b[3]), b[4]
+	}
+	return m
+}
+
+// parse parses the index subtable at offset, after FirstGlyph and LastGlyph
+// have been set from its record.
+func (subtable *BitmapIndexSubtable) parse(buf []byte, offset int) error {
+	if subtable.LastGlyph < subtable.FirstGlyph {
+		return errInvalidEBLC
+	}
+	if offset+8 > len(buf) {
+		return io.ErrUnexpectedEOF
+	}
+	b := buf[offset:]
+	subtable.IndexFormat = binary.BigEndian.Uint16(b)
+	subtable.ImageFormat = binary.BigEndian.Uint16(b[2:])
+	imageDataOffset := binary.BigEndian.Uint32(b[4:])
+	b = b[8:]
+
+	numGlyphs := int(subtable.LastGlyph-subtable.FirstGlyph) + 1
+	add := func(glyph GlyphID, start, end uint32) error {
+		if end < start {
+			return errInvalidEBLC
+		}
+		if end > start {
+			subtable.Glyphs = append(subtable.Glyphs, BitmapLocation{Glyph: glyph, Offset: imageDataOffset + start, Length: end - start})
+		}
+		return nil
+	}
+
+	switch subtable.IndexFormat {
+	case 1, 3:
+		size := 4
+		if subtable.IndexFormat == 3 {
+			size = 2
+		}
+		if size*(numGlyphs+1) > len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		offset := func(i int) uint32 {
+			if size == 4 {
+				return binary.BigEndian.Uint32(b[4*i:])
+			}
+			return uint32(binary.BigEndian.Uint16(b[2*i:]))
+		}
+		for i := 0; i < numGlyphs; i++ {
+			if err := add(subtable.FirstGlyph+GlyphID(i), offset(i), offset(i+1)); err != nil {
+				return err
+			}
+		}
+	case 2:
+		if 4+bigGlyphMetricSize > len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		imageSize := binary.BigEndian.Uint32(b)
+		metrics := parseBigGlyphMetrics(b[4:])
+		subtable.Metrics = &metrics
+		for i := 0; i < numGlyphs; i++ {
+			if err := add(subtable.FirstGlyph+GlyphID(i), uint32(i)*imageSize, uint32(i+1)*imageSize); err != nil {
+				return err
+			}
+		}
+	case 4:
+		if len(b) < 4 {
+			return io.ErrUnexpectedEOF
+		}
+		count := int(binary.BigEndian.Uint32(b))
+		if 4+4*(count+1) > len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		for i := 0; i < count; i++ {
+			r := b[4+4*i:]
+			glyph := GlyphID(binary.BigEndian.Uint16(r))
+			if err := add(glyph, uint32(binary.BigEndian.Uint16(r[2:])), uint32(binary.BigEndian.Uint16(r[6:]))); err != nil {
+				return err
+			}
+		}
+	case 5:
+		if 4+bigGlyphMetricSize+4 > len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		imageSize := binary.BigEndian.Uint32(b)
+		metrics := parseBigGlyphMetrics(b[4:])
+		subtable.Metrics = &metrics
+		count := int(binary.BigEndian.Uint32(b[12:]))
+		if 16+2*count > len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		for i := 0; i < count; i++ {
+			glyph := GlyphID(binary.BigEndian.Uint16(b[16+2*i:]))
+			if err := add(glyph, uint32(i)*imageSize, uint32(i+1)*imageSize); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("%w: index format %d", errInvalidEBLC, subtable.IndexFormat)
+	}
+
+	sort.SliceStable(subtable.Glyphs, func(i, j int) bool {
+		return subtable.Glyphs[i].Glyph < subtable.Glyphs[j].Glyph
+	})
+	return nil
+}
+
+// TableEBDT contains the bitmaps of glyphs, as located by 'EBLC', or by
+// 'CBLC' when parsed from the 'CBDT' table. Bitmaps are read with
+// Font.GlyphBitmap.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/ebdt
+// https://docs.microsoft.com/en-us/typography/opentype/spec/cbdt
+type TableEBDT struct {
+	baseTable
+
+	bytes []byte
+}
+
+func parseTableEBDT(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return &TableEBDT{baseTable: baseTable(tag), bytes: buf}, nil
+}
+
+// Bytes returns the bytes of the table as parsed.
+func (table *TableEBDT) Bytes() []byte {
+	return table.bytes
+}
+
+// BitmapComponent is a glyph drawn as part of a composite bitmap, offset
+// in pixels from the top left of the composite.
+type BitmapComponent struct {
+	Glyph            GlyphID
+	XOffset, YOffset int8
+}
+
+// Bitmap reads the bitmap of a glyph at location, from the strike and subtable that contain it.
+func (table *TableEBDT) Bitmap(strike *BitmapStrike, subtable *BitmapIndexSubtable, location BitmapLocation) (*GlyphBitmap, error) {
+	if uint64(location.Offset)+uint64(location.Length) > uint64(len(table.bytes)) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := table.bytes[location.Offset : location.Offset+location.Length]
+
+	bitmap := &GlyphBitmap{
+		PPEM:        uint16(strike.PPEMY),
+		GraphicType: BitmapRaw,
+		BitDepth:    strike.BitDepth,
+	}
+
+	format := subtable.ImageFormat
+	var metricsSize int
+	switch format {
+	case 1, 2, 8, 17:
+		metricsSize = 5
+		if len(b) < metricsSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		bitmap.Metrics = parseSmallGlyphMetrics(b, strike.Flags)
+	case 6, 7, 9, 18:
+		metricsSize = bigGlyphMetricSize
+		if len(b) < metricsSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		bitmap.Metrics = parseBigGlyphMetrics(b)
+	case 5, 19:
+		if subtable.Metrics == nil {
+			return nil, fmt.Errorf("%w: image format %d without metrics", errInvalidEBLC, format)
+		}
+		bitmap.Metrics = *subtable.Metrics
+	default:
+		return nil, fmt.Errorf("%w: image format %d", errInvalidEBLC, format)
+	}
+	b = b[metricsSize:]
+
+	switch format {
+	case 1, 6:
+		bitmap.Data = b
+	case 2, 5, 7:
+		bitmap.Data, bitmap.BitAligned = b, true
+	case 8, 9:
+		if format == 8 {
+			// Small metrics are followed by a padding byte.
+			if len(b) < 1 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			b = b[1:]
+		}
+		if len(b) < 2 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		count := int(binary.BigEndian.Uint16(b))
+		if 2+4*count > len(b) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		bitmap.Components = make([]BitmapComponent, count)
+		for i := range bitmap.Components {
+			c := b[2+4*i:]
+			bitmap.Components[i] = BitmapComponent{
+				Glyph:   GlyphID(binary.BigEndian.Uint16(c)),
+				XOffset: int8(c[2]),
+				YOffset: int8(c[3]),
+			}
+		}
+	case 17, 18, 19:
+		if len(b) < 4 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		length := binary.BigEndian.Uint32(b)
+		if uint64(length) > uint64(len(b)-4) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		bitmap.GraphicType, bitmap.Data = BitmapPNG, b[4:4+length]
+	}
+	return bitmap, nil
+}
+
+// EBLCTable returns the table corresponding to the 'EBLC' tag.
+func (font *Font) EBLCTable() (*TableEBLC, error) {
+	t, err := font.Table(TagEBLC)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableEBLC), nil
+}
+
+// EBDTTable returns the table corresponding to the 'EBDT' tag.
+func (font *Font) EBDTTable() (*TableEBDT, error) {
+	t, err := font.Table(TagEBDT)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableEBDT), nil
+}
+
+// CBLCTable returns the table corresponding to the 'CBLC' tag.
+func (font *Font) CBLCTable() (*TableEBLC, error) {
+	t, err := font.Table(TagCBLC)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableEBLC), nil
+}
+
+// CBDTTable returns the table corresponding to the 'CBDT' tag.
+func (font *Font) CBDTTable() (*TableEBDT, error) {
+	t, err := font.Table(TagCBDT)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableEBDT), nil
+}
@@ -352,7 +352,7 @@ func (t *TableLayout) parseFeatureList() error {
 //
 // A lookup record starts with type and flag fields, followed by a count of
 // sub-tables.
-func (t *TableLayout) parseLookup(b []byte, offset uint16) (*Lookup, error) {
+func (t *TableLayout) parseLookup(b []byte, offset uint16, opts *Options) (*Lookup, error) {
 	if int(offset) >= len(b) {
 		return nil, io.ErrUnexpectedEOF
 	}
@@ -370,6 +370,14 @@ func (t *TableLayout) parseLookup(b []byte, offset uint16) (*Lookup, error) {
 	// reading of lookup record is complete at this spot
 	// by converting it into a Lookup we lose information about sub-tables' location
 
+	if opts != nil && opts.MaxNestingDepth > 0 && lookup.Type == t.extensionType() {
+		for i, sub := range subs {
+			if err := t.checkExtension(b[offset:], int(sub), opts); err != nil {
+				return nil, fmt.Errorf("reading extension subtable[%d]: %w", i, err)
+			}
+		}
+	}
+
 	// TODO Read lookup.Subtable
 	// TODO Read lookup.MarkFilteringSet
 
@@ -379,9 +387,58 @@ func (t *TableLayout) parseLookup(b []byte, offset uint16) (*Lookup, error) {
 	}, nil
 }
 
+// extensionType returns the lookup type used for extension lookups in this table.
+func (t *TableLayout) extensionType() uint16 {
+	if Tag(t.baseTable) == TagGpos {
+		return 9
+	}
+	return 7
+}
+
+// extensionSubtable is the on-disk format of an extension subtable.
+// See https://docs.microsoft.com/en-us/typography/opentype/spec/gsub#lookuptype-7-extension-substitution
+type extensionSubtable struct {
+	Format              uint16 // Format identifier. Set to 1.
+	ExtensionLookupType uint16 // Lookup type of subtable referenced by extensionOffset.
+	ExtensionOffset     uint32 // Offset to the extension subtable, relative to the start of this subtable.
+}
+
+// checkExtension follows an extension subtable at offset within the lookup table b
+// to the subtable it refers to. Extensions should not refer to other extensions,
+// but if they do the chain is followed up to the nesting limit in opts. It is
+// only called when the limit is set, as subtables are not otherwise read.
+func (t *TableLayout) checkExtension(b []byte, offset int, opts *Options) error {
+	for depth := 1; ; depth++ {
+		if err := opts.checkNestingDepth(depth); err != nil {
+			return err
+		}
+
+		if offset+8 > len(b) {
+			return io.ErrUnexpectedEOF
+		}
+
+		ext := extensionSubtable{
+			Format:              binary.BigEndian.Uint16(b[offset:]),
+			ExtensionLookupType: binary.BigEndian.Uint16(b[offset+2:]),
+			ExtensionOffset:     binary.BigEndian.Uint32(b[offset+4:]),
+		}
+		if ext.Format != 1 {
+			return fmt.Errorf("unsupported extension format %d", ext.Format)
+		}
+		if ext.ExtensionOffset == 0 || int64(offset)+int64(ext.ExtensionOffset) >= int64(len(b)) {
+			return fmt.Errorf("invalid extension offset %d", ext.ExtensionOffset)
+		}
+
+		if ext.ExtensionLookupType != t.extensionType() {
+			return nil
+		}
+		offset += int(ext.ExtensionOffset)
+	}
+}
+
 // parseLookupList parses the LookupList.
 // See https://www.microsoft.com/typography/otspec/chapter2.htm#lulTbl
-func (t *TableLayout) parseLookupList() error {
+func (t *TableLayout) parseLookupList(opts *Options) error {
 	offset := int(t.header.LookupListOffset)
 	if offset >= len(t.bytes) {
 		return io.ErrUnexpectedEOF
@@ -405,7 +462,7 @@ func (t *TableLayout) parseLookupList() error {
 		}
 		t.Lookups = nil
 		for i := 0; i < int(count); i++ {
-			lookup, err := t.parseLookup(b, lookupOffsets[i])
+			lookup, err := t.parseLookup(b, lookupOffsets[i], opts)
 			if err != nil {
 				return err
 			}
@@ -417,7 +474,7 @@ func (t *TableLayout) parseLookupList() error {
 }
 
 // parseTableLayout parses a common Layout Table used by GPOS and GSUB.
-func parseTableLayout(tag Tag, buf []byte) (Table, error) {
+func parseTableLayout(tag Tag, buf []byte, opts *Options) (Table, error) {
 	t := &TableLayout{
 		baseTable: baseTable(tag),
 		bytes:     buf,
@@ -446,7 +503,7 @@ func parseTableLayout(tag Tag, buf []byte) (Table, error) {
 		panic("unsupported minor version")
 	}
 
-	if err := t.parseLookupList(); err != nil {
+	if err := t.parseLookupList(opts); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,245 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// TableFvar describes the axes of a variable font, and the named instances
+// (such as "Bold" or "Condensed Light") defined at points in the design space.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/fvar
+type TableFvar struct {
+	baseTable
+
+	Axes      []VariationAxis
+	Instances []NamedInstance
+
+	// hasPostScriptNameIDs is true if the instance records include postScriptNameID.
+	hasPostScriptNameIDs bool
+}
+
+// VariationAxis is a single axis of variation, for example weight or width.
+type VariationAxis struct {
+	Tag     Tag // Tag identifies the axis, for example 'wght' or 'wdth'.
+	Min     float64
+	Default float64
+	Max     float64
+	Flags   uint16
+	NameID  NameID // NameID is the entry in the 'name' table containing the axis name.
+}
+
+// AxisHidden is the flag set on axes that should not be shown in user interfaces.
+const AxisHidden = 0x0001
+
+// Hidden returns true if the axis should not be shown in user interfaces.
+func (axis *VariationAxis) Hidden() bool {
+	return axis.Flags&AxisHidden != 0
+}
+
+// Name returns the name of the axis from names, or the axis tag if it has no name.
+func (axis *VariationAxis) Name(names *TableName) string {
+	if names != nil {
+		if entry := names.Find(axis.NameID); entry != nil {
+			return entry.String()
+		}
+	}
+	return axis.Tag.String()
+}
+
+// NoPostScriptName is the PostScriptNameID of instances that do not have a PostScript name.
+const NoPostScriptName = NameID(0xFFFF)
+
+// NamedInstance is a predefined point in the design space of a variable font.
+type NamedInstance struct {
+	SubfamilyNameID  NameID
+	Flags            uint16
+	Coordinates      []float64 // Coordinates has one value for each axis, in user space.
+	PostScriptNameID NameID    // PostScriptNameID is NoPostScriptName if the instance has none.
+}
+
+// SubfamilyName returns the subfamily name of the instance from names,
+// or "" if it has no name.
+func (instance *NamedInstance) SubfamilyName(names *TableName) string {
+	return findName(names, instance.SubfamilyNameID)
+}
+
+// PostScriptName returns the PostScript name of the instance from names,
+// or "" if it has no name.
+func (instance *NamedInstance) PostScriptName(names *TableName) string {
+	if instance.PostScriptNameID == NoPostScriptName {
+		return ""
+	}
+	return findName(names, instance.PostScriptNameID)
+}
+
+func findName(names *TableName, id NameID) string {
+	if names == nil {
+		return ""
+	}
+	if entry := names.Find(id); entry != nil {
+		return entry.String()
+	}
+	return ""
+}
+
+type fvarHeader struct {
+	MajorVersion    uint16
+	MinorVersion    uint16
+	AxesArrayOffset uint16
+	Reserved        uint16
+	AxisCount       uint16
+	AxisSize        uint16
+	InstanceCount   uint16
+	InstanceSize    uint16
+}
+
+const (
+	fvarHeaderSize = 16
+	fvarAxisSize   = 20
+)
+
+var errInvalidFvar = errors.New("invalid 'fvar' table")
+
+func parseTableFvar(tag Tag, buf []byte, _ *Options) (Table, error) {
+	var header fvarHeader
+	if err := binary.Read(bytes.NewReader(buf), binary.BigEndian, &header); err != nil {
+		return nil, err
+	}
+
+	if header.MajorVersion != 1 {
+		return nil, errInvalidFvar
+	}
+
+	numAxes := int(header.AxisCount)
+	axisSize := int(header.AxisSize)
+	instanceSize := int(header.InstanceSize)
+	if axisSize < fvarAxisSize || instanceSize < 4+4*numAxes {
+		return nil, errInvalidFvar
+	}
+
+	start := int(header.AxesArrayOffset)
+	instancesStart := start + numAxes*axisSize
+	if instancesStart+int(header.InstanceCount)*instanceSize > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableFvar{
+		baseTable:            baseTable(tag),
+		Axes:                 make([]VariationAxis, numAxes),
+		Instances:            make([]NamedInstance, header.InstanceCount),
+		hasPostScriptNameIDs: instanceSize >= 6+4*numAxes,
+	}
+
+	for i := range table.Axes {
+		b := buf[start+i*axisSize:]
+		table.Axes[i] = VariationAxis{
+			Tag:     Tag{binary.BigEndian.Uint32(b)},
+			Min:     fixedToFloat(binary.BigEndian.Uint32(b[4:])),
+			Default: fixedToFloat(binary.BigEndian.Uint32(b[8:])),
+			Max:     fixedToFloat(binary.BigEndian.Uint32(b[12:])),
+			Flags:   binary.BigEndian.Uint16(b[16:]),
+			NameID:  NameID(binary.BigEndian.Uint16(b[18:])),
+		}
+	}
+
+	for i := range table.Instances {
+		b := buf[instancesStart+i*instanceSize:]
+		instance := NamedInstance{
+			SubfamilyNameID:  NameID(binary.BigEndian.Uint16(b)),
+			Flags:            binary.BigEndian.Uint16(b[2:]),
+			Coordinates:      make([]float64, numAxes),
+			PostScriptNameID: NoPostScriptName,
+		}
+		for j := range instance.Coordinates {
+			instance.Coordinates[j] = fixedToFloat(binary.BigEndian.Uint32(b[4+4*j:]))
+		}
+		if table.hasPostScriptNameIDs {
+			instance.PostScriptNameID = NameID(binary.BigEndian.Uint16(b[4+4*numAxes:]))
+		}
+		table.Instances[i] = instance
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableFvar) Bytes() []byte {
+	numAxes := len(table.Axes)
+
+	hasPostScriptNameIDs := table.hasPostScriptNameIDs
+	for _, instance := range table.Instances {
+		if instance.PostScriptNameID != NoPostScriptName {
+			hasPostScriptNameIDs = true
+		}
+	}
+
+	instanceSize := 4 + 4*numAxes
+	if hasPostScriptNameIDs {
+		instanceSize += 2
+	}
+
+	buf := make([]byte, fvarHeaderSize+numAxes*fvarAxisSize+len(table.Instances)*instanceSize)
+	binary.BigEndian.PutUint16(buf, 1)
+	binary.BigEndian.PutUint16(buf[4:], fvarHeaderSize)
+	binary.BigEndian.PutUint16(buf[6:], 2)
+	binary.BigEndian.PutUint16(buf[8:], uint16(numAxes))
+	binary.BigEndian.PutUint16(buf[10:], fvarAxisSize)
+	binary.BigEndian.PutUint16(buf[12:], uint16(len(table.Instances)))
+	binary.BigEndian.PutUint16(buf[14:], uint16(instanceSize))
+
+	b := buf[fvarHeaderSize:]
+	for _, axis := range table.Axes {
+		binary.BigEndian.PutUint32(b, axis.Tag.Number)
+		binary.BigEndian.PutUint32(b[4:], floatToFixed(axis.Min))
+		binary.BigEndian.PutUint32(b[8:], floatToFixed(axis.Default))
+		binary.BigEndian.PutUint32(b[12:], floatToFixed(axis.Max))
+		binary.BigEndian.PutUint16(b[16:], axis.Flags)
+		binary.BigEndian.PutUint16(b[18:], uint16(axis.NameID))
+		b = b[fvarAxisSize:]
+	}
+
+	for _, instance := range table.Instances {
+		binary.BigEndian.PutUint16(b, uint16(instance.SubfamilyNameID))
+		binary.BigEndian.PutUint16(b[2:], instance.Flags)
+		for j := 0; j < numAxes; j++ {
+			var coord float64
+			if j < len(instance.Coordinates) {
+				coord = instance.Coordinates[j]
+			}
+			binary.BigEndian.PutUint32(b[4+4*j:], floatToFixed(coord))
+		}
+		if hasPostScriptNameIDs {
+			binary.BigEndian.PutUint16(b[4+4*numAxes:], uint16(instance.PostScriptNameID))
+		}
+		b = b[instanceSize:]
+	}
+
+	return buf
+}
+
+// Axis returns the axis with the given tag, or nil if the font has no such axis.
+func (table *TableFvar) Axis(tag Tag) *VariationAxis {
+	for i := range table.Axes {
+		if table.Axes[i].Tag == tag {
+			return &table.Axes[i]
+		}
+	}
+	return nil
+}
+
+// FvarTable returns the table corresponding to the 'fvar' tag.
+func (font *Font) FvarTable() (*TableFvar, error) {
+	t, err := font.Table(TagFvar)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableFvar), nil
+}
+
+// IsVariable returns true if the font is a variable font, which is to say it
+// has an 'fvar' table describing its axes of variation.
+func (font *Font) IsVariable() bool {
+	return font.HasTable(TagFvar)
+}
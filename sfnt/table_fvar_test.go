@@ -0,0 +1,85 @@
+package sfnt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func testFvarTable() *TableFvar {
+	return &TableFvar{
+		baseTable: baseTable(TagFvar),
+		Axes: []VariationAxis{
+			{Tag: MustNamedTag("wght"), Min: 100, Default: 400, Max: 900, NameID: 256},
+			{Tag: MustNamedTag("wdth"), Min: 62.5, Default: 100, Max: 100, Flags: AxisHidden, NameID: 257},
+		},
+		Instances: []NamedInstance{
+			{SubfamilyNameID: 258, Coordinates: []float64{700, 100}, PostScriptNameID: 259},
+			{SubfamilyNameID: 2, Coordinates: []float64{400, 75.5}, PostScriptNameID: NoPostScriptName},
+		},
+	}
+}
+
+func TestFvarRoundTrip(t *testing.T) {
+	want := testFvarTable()
+
+	parsed, err := parseTableFvar(TagFvar, want.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("parseTableFvar() err = %q, want nil", err)
+	}
+
+	got := parsed.(*TableFvar)
+	if !reflect.DeepEqual(got.Axes, want.Axes) {
+		t.Errorf("Axes = %v, want %v", got.Axes, want.Axes)
+	}
+	if !reflect.DeepEqual(got.Instances, want.Instances) {
+		t.Errorf("Instances = %v, want %v", got.Instances, want.Instances)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("Bytes() changed after round trip")
+	}
+}
+
+func TestFvarTableNames(t *testing.T) {
+	font := New(TypeTrueType)
+	if font.IsVariable() {
+		t.Errorf("IsVariable() = true before adding 'fvar', want false")
+	}
+
+	names := NewTableName()
+	for id, value := range map[NameID]string{256: "Weight", 258: "Bold", 259: "Test-Bold"} {
+		if err := names.AddMicrosoftEnglishEntry(id, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	font.AddTable(TagName, names)
+	font.AddTable(TagFvar, testFvarTable())
+
+	if !font.IsVariable() {
+		t.Errorf("IsVariable() = false, want true")
+	}
+
+	fvar, err := font.FvarTable()
+	if err != nil {
+		t.Fatalf("FvarTable() err = %q, want nil", err)
+	}
+
+	if got := fvar.Axis(MustNamedTag("wght")).Name(names); got != "Weight" {
+		t.Errorf("Axis('wght').Name() = %q, want %q", got, "Weight")
+	}
+	if got := fvar.Axis(MustNamedTag("wdth")).Name(names); got != "wdth" {
+		t.Errorf("Axis('wdth').Name() = %q, want %q", got, "wdth")
+	}
+	if !fvar.Axes[1].Hidden() {
+		t.Errorf("Axes[1].Hidden() = false, want true")
+	}
+	if got := fvar.Instances[0].SubfamilyName(names); got != "Bold" {
+		t.Errorf("Instances[0].SubfamilyName() = %q, want %q", got, "Bold")
+	}
+	if got := fvar.Instances[0].PostScriptName(names); got != "Test-Bold" {
+		t.Errorf("Instances[0].PostScriptName() = %q, want %q", got, "Test-Bold")
+	}
+	if got := fvar.Instances[1].PostScriptName(names); got != "" {
+		t.Errorf("Instances[1].PostScriptName() = %q, want %q", got, "")
+	}
+}
@@ -0,0 +1,118 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// TableGasp contains the rasterization behavior preferred at each range of
+// sizes, such as whether to apply hinting.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gasp
+type TableGasp struct {
+	baseTable
+
+	Version uint16
+	// Ranges are sorted by MaxPPEM, and the last should have a MaxPPEM of 0xFFFF.
+	Ranges []GaspRange
+}
+
+// GaspRange is the behavior of the sizes up to and including MaxPPEM, and
+// above the MaxPPEM of the previous range.
+type GaspRange struct {
+	MaxPPEM  uint16
+	Behavior uint16
+}
+
+// Flags of GaspRange.Behavior. The symmetric flags are only used in version 1.
+const (
+	GaspGridfit            = 0x0001
+	GaspDoGray             = 0x0002
+	GaspSymmetricGridfit   = 0x0004
+	GaspSymmetricSmoothing = 0x0008
+)
+
+// String returns the names of the flags set in the behavior.
+func (r GaspRange) String() string {
+	var flags []string
+	for _, f := range []struct {
+		flag uint16
+		name string
+	}{
+		{GaspGridfit, "gridfit"},
+		{GaspDoGray, "grayscale"},
+		{GaspSymmetricGridfit, "symmetric gridfit"},
+		{GaspSymmetricSmoothing, "symmetric smoothing"},
+	} {
+		if r.Behavior&f.flag != 0 {
+			flags = append(flags, f.name)
+		}
+	}
+	if len(flags) == 0 {
+		return "none"
+	}
+	return strings.Join(flags, ", ")
+}
+
+func parseTableGasp(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	table := &TableGasp{
+		baseTable: baseTable(tag),
+		Version:   binary.BigEndian.Uint16(buf),
+	}
+	if table.Version > 1 {
+		return nil, fmt.Errorf("unsupported %q version %d", tag, table.Version)
+	}
+
+	count := int(binary.BigEndian.Uint16(buf[2:]))
+	if 4+4*count > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	table.Ranges = make([]GaspRange, count)
+	for i := range table.Ranges {
+		table.Ranges[i] = GaspRange{
+			MaxPPEM:  binary.BigEndian.Uint16(buf[4+4*i:]),
+			Behavior: binary.BigEndian.Uint16(buf[6+4*i:]),
+		}
+	}
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableGasp) Bytes() []byte {
+	buf := make([]byte, 4+4*len(table.Ranges))
+	binary.BigEndian.PutUint16(buf, table.Version)
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(table.Ranges)))
+	for i, r := range table.Ranges {
+		binary.BigEndian.PutUint16(buf[4+4*i:], r.MaxPPEM)
+		binary.BigEndian.PutUint16(buf[6+4*i:], r.Behavior)
+	}
+	return buf
+}
+
+// Behavior returns the flags of the range containing a size in pixels per em,
+// which is the range with the smallest MaxPPEM that is at least ppem, so that
+// unsorted ranges are still found. Sizes beyond the last range have no flags
+// set.
+func (table *TableGasp) Behavior(ppem int) uint16 {
+	var behavior uint16
+	maxPPEM := -1
+	for _, r := range table.Ranges {
+		if ppem <= int(r.MaxPPEM) && (maxPPEM < 0 || int(r.MaxPPEM) < maxPPEM) {
+			behavior, maxPPEM = r.Behavior, int(r.MaxPPEM)
+		}
+	}
+	return behavior
+}
+
+// GaspTable returns the table corresponding to the 'gasp' tag.
+func (font *Font) GaspTable() (*TableGasp, error) {
+	t, err := font.Table(TagGasp)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableGasp), nil
+}
@@ -0,0 +1,69 @@
+package sfnt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGasp(t *testing.T) {
+	for _, want := range []*TableGasp{
+		{
+			baseTable: baseTable(TagGasp),
+			Version:   0,
+			Ranges:    []GaspRange{{MaxPPEM: 8, Behavior: GaspDoGray}, {MaxPPEM: 0xFFFF, Behavior: GaspGridfit | GaspDoGray}},
+		},
+		{
+			baseTable: baseTable(TagGasp),
+			Version:   1,
+			Ranges:    []GaspRange{{MaxPPEM: 0xFFFF, Behavior: GaspSymmetricGridfit | GaspSymmetricSmoothing}},
+		},
+	} {
+		table, err := ParseTable(TagGasp, want.Bytes())
+		if err != nil {
+			t.Fatalf("ParseTable(%q) version %d err = %q, want nil", TagGasp, want.Version, err)
+		}
+		if !reflect.DeepEqual(table, want) {
+			t.Errorf("ParseTable(%q) = %+v, want %+v", TagGasp, table, want)
+		}
+	}
+
+	if _, err := ParseTable(TagGasp, []byte{0, 2, 0, 0}); err == nil {
+		t.Errorf("ParseTable(%q) version 2 err = nil, want error", TagGasp)
+	}
+	if _, err := ParseTable(TagGasp, []byte{0, 1, 0, 1, 0xFF, 0xFF}); err == nil {
+		t.Errorf("ParseTable(%q) with a truncated range err = nil, want error", TagGasp)
+	}
+}
+
+func TestGaspBehavior(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []GaspRange
+		want   map[int]uint16
+	}{
+		{
+			"sorted",
+			[]GaspRange{{8, GaspDoGray}, {16, GaspGridfit}, {0xFFFF, GaspGridfit | GaspDoGray}},
+			map[int]uint16{1: GaspDoGray, 8: GaspDoGray, 9: GaspGridfit, 16: GaspGridfit, 17: GaspGridfit | GaspDoGray, 0xFFFF: GaspGridfit | GaspDoGray},
+		},
+		{
+			"unsorted",
+			[]GaspRange{{0xFFFF, GaspGridfit | GaspDoGray}, {16, GaspGridfit}, {8, GaspDoGray}},
+			map[int]uint16{1: GaspDoGray, 8: GaspDoGray, 9: GaspGridfit, 16: GaspGridfit, 17: GaspGridfit | GaspDoGray},
+		},
+		{
+			"without a final range",
+			[]GaspRange{{8, GaspDoGray}, {16, GaspGridfit}},
+			map[int]uint16{8: GaspDoGray, 16: GaspGridfit, 17: 0, 0xFFFF: 0},
+		},
+		{"empty", nil, map[int]uint16{12: 0}},
+	}
+	for _, test := range tests {
+		gasp := &TableGasp{Version: 1, Ranges: test.ranges}
+		for ppem, want := range test.want {
+			if got := gasp.Behavior(ppem); got != want {
+				t.Errorf("%s: Behavior(%d) = %#x, want %#x", test.name, ppem, got, want)
+			}
+		}
+	}
+}
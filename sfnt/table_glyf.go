@@ -0,0 +1,576 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TableGlyf contains the TrueType outlines of the glyphs in the font.
+// The table can only be parsed using the 'loca' table, so it is
+// accessed using Font.GlyfTable.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/glyf
+type TableGlyf struct {
+	baseTable
+
+	bytes []byte
+	loca  *TableLoca
+}
+
+// Glyph is a parsed glyph description from the 'glyf' table. Simple glyphs
+// have contours made up of Points, while composite glyphs are made up of Components.
+type Glyph struct {
+	NumberOfContours int16 // NumberOfContours is -1 for composite glyphs.
+	XMin             int16
+	YMin             int16
+	XMax             int16
+	YMax             int16
+
+	// EndPoints contains the index of the last point of each contour of a simple glyph.
+	EndPoints []uint16
+	// Points contains the points of a simple glyph.
+	Points []GlyphPoint
+	// Overlap is true if the contours of a simple glyph overlap (OVERLAP_SIMPLE).
+	Overlap bool
+
+	// Components contains the glyphs that make up a composite glyph.
+	Components []GlyphComponent
+
+	// Instructions contains the TrueType hinting instructions of the glyph.
+	Instructions []byte
+}
+
+// GlyphPoint is a point in the outline of a simple glyph.
+type GlyphPoint struct {
+	X, Y    int16
+	OnCurve bool
+}
+
+// Flags of the components of composite glyphs.
+const (
+	ComponentArgsAreWords     = 0x0001
+	ComponentArgsAreXYValues  = 0x0002
+	ComponentRoundXYToGrid    = 0x0004
+	ComponentHaveScale        = 0x0008
+	ComponentMoreComponents   = 0x0020
+	ComponentHaveXYScale      = 0x0040
+	ComponentHaveTwoByTwo     = 0x0080
+	ComponentHaveInstructions = 0x0100
+	ComponentUseMyMetrics     = 0x0200
+	ComponentOverlapCompound  = 0x0400
+	ComponentScaledOffset     = 0x0800
+	ComponentUnscaledOffset   = 0x1000
+)
+
+// componentEncodedFlags are the flags that Glyph.Bytes computes from the components.
+const componentEncodedFlags = ComponentArgsAreWords | ComponentHaveScale | ComponentMoreComponents |
+	ComponentHaveXYScale | ComponentHaveTwoByTwo | ComponentHaveInstructions
+
+// GlyphComponent is a reference from a composite glyph to another glyph.
+type GlyphComponent struct {
+	Flags uint16
+	Glyph GlyphID
+	// Arg1 and Arg2 are the x and y offset of the component if the
+	// ComponentArgsAreXYValues flag is set, otherwise they are the index of a
+	// point in the composite glyph and the index of a point in the component
+	// that should be aligned.
+	Arg1, Arg2 int32
+	// Transform is the 2x2 matrix applied to the component, in the order
+	// xscale, scale01, scale10, yscale.
+	Transform [4]float64
+}
+
+// IsXYOffset returns true if Arg1 and Arg2 are the offset of the component,
+// rather than point indices.
+func (c *GlyphComponent) IsXYOffset() bool {
+	return c.Flags&ComponentArgsAreXYValues != 0
+}
+
+// Apply transforms the point (x, y) in the component into the coordinate
+// space of the composite glyph, excluding the offset.
+func (c *GlyphComponent) Apply(x, y float64) (float64, float64) {
+	t := c.Transform
+	return t[0]*x + t[2]*y, t[1]*x + t[3]*y
+}
+
+// IsComposite returns true if the glyph is made up of other glyphs.
+func (g *Glyph) IsComposite() bool {
+	return g.NumberOfContours < 0
+}
+
+// IsEmpty returns true if the glyph has no outline, as is the case for spaces.
+func (g *Glyph) IsEmpty() bool {
+	return g.NumberOfContours == 0 && len(g.Components) == 0
+}
+
+// Flags used in simple glyph descriptions.
+const (
+	glyphOnCurve       = 0x01
+	glyphXShort        = 0x02
+	glyphYShort        = 0x04
+	glyphRepeat        = 0x08
+	glyphXSameOrPos    = 0x10
+	glyphYSameOrPos    = 0x20
+	glyphOverlapSimple = 0x40
+)
+
+var errInvalidGlyph = errors.New("invalid glyph")
+
+// ParseGlyph parses a single glyph description from the 'glyf' table.
+// An empty buf is an empty glyph.
+func ParseGlyph(buf []byte) (*Glyph, error) {
+	g := &Glyph{}
+	if len(buf) == 0 {
+		return g, nil
+	}
+	if len(buf) < 10 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	g.NumberOfContours = int16(binary.BigEndian.Uint16(buf))
+	g.XMin = int16(binary.BigEndian.Uint16(buf[2:]))
+	g.YMin = int16(binary.BigEndian.Uint16(buf[4:]))
+	g.XMax = int16(binary.BigEndian.Uint16(buf[6:]))
+	g.YMax = int16(binary.BigEndian.Uint16(buf[8:]))
+
+	switch {
+	case g.NumberOfContours == -1:
+		return g, g.parseComposite(buf[10:])
+	case g.NumberOfContours < 0:
+		return nil, errInvalidGlyph
+	default:
+		return g, g.parseSimple(buf[10:])
+	}
+}
+
+func (g *Glyph) parseSimple(b []byte) error {
+	numContours := int(g.NumberOfContours)
+	if len(b) < 2*numContours+2 {
+		return io.ErrUnexpectedEOF
+	}
+
+	g.EndPoints = make([]uint16, numContours)
+	numPoints := 0
+	for i := range g.EndPoints {
+		g.EndPoints[i] = binary.BigEndian.Uint16(b[2*i:])
+		if i > 0 && g.EndPoints[i] <= g.EndPoints[i-1] {
+			return errInvalidGlyph
+		}
+		numPoints = int(g.EndPoints[i]) + 1
+	}
+
+	p := 2 * numContours
+	instructionLength := int(binary.BigEndian.Uint16(b[p:]))
+	p += 2
+	if p+instructionLength > len(b) {
+		return io.ErrUnexpectedEOF
+	}
+	g.Instructions = b[p : p+instructionLength]
+	p += instructionLength
+
+	flags := make([]byte, 0, numPoints)
+	for len(flags) < numPoints {
+		if p >= len(b) {
+			return io.ErrUnexpectedEOF
+		}
+		flag := b[p]
+		p++
+		flags = append(flags, flag)
+
+		if flag&glyphRepeat != 0 {
+			if p >= len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			count := int(b[p])
+			p++
+			if len(flags)+count > numPoints {
+				return errInvalidGlyph
+			}
+			for i := 0; i < count; i++ {
+				flags = append(flags, flag)
+			}
+		}
+	}
+	if numPoints > 0 {
+		g.Overlap = flags[0]&glyphOverlapSimple != 0
+	}
+
+	g.Points = make([]GlyphPoint, numPoints)
+
+	var x int16
+	for i, flag := range flags {
+		switch {
+		case flag&glyphXShort != 0:
+			if p+1 > len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			if flag&glyphXSameOrPos != 0 {
+				x += int16(b[p])
+			} else {
+				x -= int16(b[p])
+			}
+			p++
+		case flag&glyphXSameOrPos == 0:
+			if p+2 > len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			x += int16(binary.BigEndian.Uint16(b[p:]))
+			p += 2
+		}
+		g.Points[i].X = x
+		g.Points[i].OnCurve = flag&glyphOnCurve != 0
+	}
+
+	var y int16
+	for i, flag := range flags {
+		switch {
+		case flag&glyphYShort != 0:
+			if p+1 > len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			if flag&glyphYSameOrPos != 0 {
+				y += int16(b[p])
+			} else {
+				y -= int16(b[p])
+			}
+			p++
+		case flag&glyphYSameOrPos == 0:
+			if p+2 > len(b) {
+				return io.ErrUnexpectedEOF
+			}
+			y += int16(binary.BigEndian.Uint16(b[p:]))
+			p += 2
+		}
+		g.Points[i].Y = y
+	}
+
+	return nil
+}
+
+func (g *Glyph) parseComposite(b []byte) error {
+	for {
+		if len(b) < 4 {
+			return io.ErrUnexpectedEOF
+		}
+		c := GlyphComponent{
+			Flags:     binary.BigEndian.Uint16(b),
+			Glyph:     GlyphID(binary.BigEndian.Uint16(b[2:])),
+			Transform: [4]float64{1, 0, 0, 1},
+		}
+		b = b[4:]
+
+		if c.Flags&ComponentArgsAreWords != 0 {
+			if len(b) < 4 {
+				return io.ErrUnexpectedEOF
+			}
+			if c.IsXYOffset() {
+				c.Arg1, c.Arg2 = int32(int16(binary.BigEndian.Uint16(b))), int32(int16(binary.BigEndian.Uint16(b[2:])))
+			} else {
+				c.Arg1, c.Arg2 = int32(binary.BigEndian.Uint16(b)), int32(binary.BigEndian.Uint16(b[2:]))
+			}
+			b = b[4:]
+		} else {
+			if len(b) < 2 {
+				return io.ErrUnexpectedEOF
+			}
+			if c.IsXYOffset() {
+				c.Arg1, c.Arg2 = int32(int8(b[0])), int32(int8(b[1]))
+			} else {
+				c.Arg1, c.Arg2 = int32(b[0]), int32(b[1])
+			}
+			b = b[2:]
+		}
+
+		readF2Dot14 := func(n int) ([]float64, error) {
+			if len(b) < 2*n {
+				return nil, io.ErrUnexpectedEOF
+			}
+			values := make([]float64, n)
+			for i := range values {
+				values[i] = F2Dot14(binary.BigEndian.Uint16(b[2*i:])).Float()
+			}
+			b = b[2*n:]
+			return values, nil
+		}
+
+		switch {
+		case c.Flags&ComponentHaveScale != 0:
+			v, err := readF2Dot14(1)
+			if err != nil {
+				return err
+			}
+			c.Transform = [4]float64{v[0], 0, 0, v[0]}
+		case c.Flags&ComponentHaveXYScale != 0:
+			v, err := readF2Dot14(2)
+			if err != nil {
+				return err
+			}
+			c.Transform = [4]float64{v[0], 0, 0, v[1]}
+		case c.Flags&ComponentHaveTwoByTwo != 0:
+			v, err := readF2Dot14(4)
+			if err != nil {
+				return err
+			}
+			c.Transform = [4]float64{v[0], v[1], v[2], v[3]}
+		}
+
+		g.Components = append(g.Components, c)
+
+		if c.Flags&ComponentMoreComponents == 0 {
+			if c.Flags&ComponentHaveInstructions != 0 {
+				if len(b) < 2 {
+					return io.ErrUnexpectedEOF
+				}
+				n := int(binary.BigEndian.Uint16(b))
+				if len(b) < 2+n {
+					return io.ErrUnexpectedEOF
+				}
+				g.Instructions = b[2 : 2+n]
+			}
+			return nil
+		}
+	}
+}
+
+// Bytes returns the encoded glyph description. Empty glyphs are encoded as no bytes.
+// The flags that determine the size of the encoding are recomputed, so that
+// any changes to the points or components are represented.
+func (g *Glyph) Bytes() []byte {
+	if g.IsEmpty() {
+		return nil
+	}
+
+	buf := make([]byte, 10, 10+4*len(g.Points)+8*len(g.Components)+len(g.Instructions))
+	binary.BigEndian.PutUint16(buf[2:], uint16(g.XMin))
+	binary.BigEndian.PutUint16(buf[4:], uint16(g.YMin))
+	binary.BigEndian.PutUint16(buf[6:], uint16(g.XMax))
+	binary.BigEndian.PutUint16(buf[8:], uint16(g.YMax))
+
+	if g.IsComposite() {
+		binary.BigEndian.PutUint16(buf, 0xFFFF)
+		return g.appendComposite(buf)
+	}
+
+	binary.BigEndian.PutUint16(buf, uint16(len(g.EndPoints)))
+	return g.appendSimple(buf)
+}
+
+func (g *Glyph) appendSimple(buf []byte) []byte {
+	for _, end := range g.EndPoints {
+		buf = appendUint16(buf, end)
+	}
+	buf = appendUint16(buf, uint16(len(g.Instructions)))
+	buf = append(buf, g.Instructions...)
+
+	flags := make([]byte, len(g.Points))
+	var xs, ys []byte
+	var prevX, prevY int16
+	for i, p := range g.Points {
+		var flag byte
+		if p.OnCurve {
+			flag |= glyphOnCurve
+		}
+		if i == 0 && g.Overlap {
+			flag |= glyphOverlapSimple
+		}
+
+		dx, dy := int(p.X)-int(prevX), int(p.Y)-int(prevY)
+		prevX, prevY = p.X, p.Y
+
+		switch {
+		case dx == 0:
+			flag |= glyphXSameOrPos
+		case dx >= -255 && dx <= 255:
+			flag |= glyphXShort
+			if dx > 0 {
+				flag |= glyphXSameOrPos
+				xs = append(xs, byte(dx))
+			} else {
+				xs = append(xs, byte(-dx))
+			}
+		default:
+			xs = appendUint16(xs, uint16(dx))
+		}
+
+		switch {
+		case dy == 0:
+			flag |= glyphYSameOrPos
+		case dy >= -255 && dy <= 255:
+			flag |= glyphYShort
+			if dy > 0 {
+				flag |= glyphYSameOrPos
+				ys = append(ys, byte(dy))
+			} else {
+				ys = append(ys, byte(-dy))
+			}
+		default:
+			ys = appendUint16(ys, uint16(dy))
+		}
+
+		flags[i] = flag
+	}
+
+	for i := 0; i < len(flags); {
+		repeat := 0
+		for i+repeat+1 < len(flags) && flags[i+repeat+1] == flags[i] && repeat < 255 {
+			repeat++
+		}
+		if repeat > 0 {
+			buf = append(buf, flags[i]|glyphRepeat, byte(repeat))
+		} else {
+			buf = append(buf, flags[i])
+		}
+		i += repeat + 1
+	}
+
+	buf = append(buf, xs...)
+	return append(buf, ys...)
+}
+
+func (g *Glyph) appendComposite(buf []byte) []byte {
+	for i, c := range g.Components {
+		flags := c.Flags &^ componentEncodedFlags
+		if i < len(g.Components)-1 {
+			flags |= ComponentMoreComponents
+		} else if len(g.Instructions) > 0 {
+			flags |= ComponentHaveInstructions
+		}
+
+		words := false
+		if c.IsXYOffset() {
+			words = c.Arg1 < -128 || c.Arg1 > 127 || c.Arg2 < -128 || c.Arg2 > 127
+		} else {
+			words = c.Arg1 > 255 || c.Arg2 > 255
+		}
+		if words {
+			flags |= ComponentArgsAreWords
+		}
+
+		t := c.Transform
+		var scale []float64
+		switch {
+		case t == [4]float64{1, 0, 0, 1}:
+		case t[1] == 0 && t[2] == 0 && t[0] == t[3]:
+			flags |= ComponentHaveScale
+			scale = t[:1]
+		case t[1] == 0 && t[2] == 0:
+			flags |= ComponentHaveXYScale
+			scale = []float64{t[0], t[3]}
+		default:
+			flags |= ComponentHaveTwoByTwo
+			scale = t[:]
+		}
+
+		buf = appendUint16(buf, flags)
+		buf = appendUint16(buf, uint16(c.Glyph))
+		if words {
+			buf = appendUint16(buf, uint16(c.Arg1))
+			buf = appendUint16(buf, uint16(c.Arg2))
+		} else {
+			buf = append(buf, byte(c.Arg1), byte(c.Arg2))
+		}
+		for _, v := range scale {
+			buf = appendUint16(buf, uint16(NewF2Dot14(v)))
+		}
+	}
+
+	if len(g.Instructions) > 0 {
+		buf = appendUint16(buf, uint16(len(g.Instructions)))
+		buf = append(buf, g.Instructions...)
+	}
+	return buf
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableGlyf) Bytes() []byte {
+	return table.bytes
+}
+
+// NumGlyphs returns the number of glyphs in the table.
+func (table *TableGlyf) NumGlyphs() int {
+	return len(table.loca.Offsets) - 1
+}
+
+// GlyphBytes returns the encoded description of a glyph.
+func (table *TableGlyf) GlyphBytes(glyph GlyphID) ([]byte, error) {
+	if int(glyph) >= table.NumGlyphs() {
+		return nil, fmt.Errorf("glyph %d out of range", glyph)
+	}
+	start, end := table.loca.Offsets[glyph], table.loca.Offsets[glyph+1]
+	if start > end || end > uint32(len(table.bytes)) {
+		return nil, fmt.Errorf("glyph %d: invalid offsets %d-%d", glyph, start, end)
+	}
+	return table.bytes[start:end], nil
+}
+
+// Glyph parses the description of a glyph.
+func (table *TableGlyf) Glyph(glyph GlyphID) (*Glyph, error) {
+	buf, err := table.GlyphBytes(glyph)
+	if err != nil {
+		return nil, err
+	}
+	g, err := ParseGlyph(buf)
+	if err != nil {
+		return nil, fmt.Errorf("glyph %d: %w", glyph, err)
+	}
+	return g, nil
+}
+
+// NewTableGlyf returns 'glyf' and 'loca' tables containing the encoded glyphs.
+// Each glyph is padded to an even length so the short 'loca' format can be
+// used when the table is small enough; the returned loca's Short field should be
+// copied to head.IndexToLocFormat (0 for short, 1 for long).
+func NewTableGlyf(glyphs [][]byte) (*TableGlyf, *TableLoca) {
+	loca := &TableLoca{
+		baseTable: baseTable(TagLoca),
+		Offsets:   make([]uint32, len(glyphs)+1),
+	}
+
+	var buf []byte
+	for i, g := range glyphs {
+		loca.Offsets[i] = uint32(len(buf))
+		buf = append(buf, g...)
+		if len(buf)%2 != 0 {
+			buf = append(buf, 0)
+		}
+	}
+	loca.Offsets[len(glyphs)] = uint32(len(buf))
+	loca.Short = len(buf) < 0x20000
+
+	return &TableGlyf{baseTable: baseTable(TagGlyf), bytes: buf, loca: loca}, loca
+}
+
+// GlyfTable returns the table corresponding to the 'glyf' tag.
+// It requires the 'loca' table to parse.
+func (font *Font) GlyfTable() (*TableGlyf, error) {
+	s, found := font.tables[TagGlyf]
+	if !found {
+		return nil, ErrMissingTable
+	}
+	if t, ok := s.table.(*TableGlyf); ok {
+		return t, nil
+	}
+
+	loca, err := font.LocaTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagLoca, err)
+	}
+
+	buf, err := font.tableBytes(s)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TableGlyf{baseTable: baseTable(TagGlyf), bytes: buf, loca: loca}
+	s.table = t
+	return t, nil
+}
@@ -0,0 +1,147 @@
+package sfnt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGlyphRoundTrip(t *testing.T) {
+	buf, err := os.ReadFile(filepath.Join("testdata", "Roboto-BoldItalic.ttf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	glyf, err := font.GlyfTable()
+	if err != nil {
+		t.Fatalf("GlyfTable() err = %q, want nil", err)
+	}
+
+	composites := 0
+	for id := 0; id < glyf.NumGlyphs(); id++ {
+		glyph, err := glyf.Glyph(GlyphID(id))
+		if err != nil {
+			t.Fatalf("Glyph(%d) err = %q, want nil", id, err)
+		}
+
+		if glyph.IsComposite() {
+			composites++
+		} else if len(glyph.Points) > 0 {
+			xMin, yMin, xMax, yMax := glyph.Points[0].X, glyph.Points[0].Y, glyph.Points[0].X, glyph.Points[0].Y
+			for _, p := range glyph.Points {
+				xMin, xMax = min16(xMin, p.X), max16(xMax, p.X)
+				yMin, yMax = min16(yMin, p.Y), max16(yMax, p.Y)
+			}
+			if [4]int16{xMin, yMin, xMax, yMax} != [4]int16{glyph.XMin, glyph.YMin, glyph.XMax, glyph.YMax} {
+				t.Errorf("Glyph(%d) points have bounds %v, want %v", id,
+					[4]int16{xMin, yMin, xMax, yMax}, [4]int16{glyph.XMin, glyph.YMin, glyph.XMax, glyph.YMax})
+			}
+		}
+
+		reparsed, err := ParseGlyph(glyph.Bytes())
+		if err != nil {
+			t.Fatalf("ParseGlyph(Glyph(%d).Bytes()) err = %q, want nil", id, err)
+		}
+		if glyph.IsEmpty() {
+			continue
+		}
+		if !reflect.DeepEqual(reparsed, glyph) {
+			t.Errorf("ParseGlyph(Glyph(%d).Bytes()) = %+v, want %+v", id, reparsed, glyph)
+		}
+	}
+
+	if composites == 0 {
+		t.Errorf("found no composite glyphs, want some")
+	}
+}
+
+func min16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func TestNewTableGlyf(t *testing.T) {
+	square := &Glyph{
+		NumberOfContours: 1,
+		XMax:             100,
+		YMax:             100,
+		EndPoints:        []uint16{3},
+		Points:           []GlyphPoint{{0, 0, true}, {0, 100, true}, {100, 100, true}, {100, 0, true}},
+	}
+	composite := &Glyph{
+		NumberOfContours: -1,
+		XMax:             100,
+		YMax:             300,
+		Components: []GlyphComponent{
+			{Flags: ComponentArgsAreXYValues, Glyph: 1, Transform: [4]float64{1, 0, 0, 1}},
+			{Flags: ComponentArgsAreXYValues, Glyph: 1, Arg2: 200, Transform: [4]float64{0.5, 0, 0, 1}},
+		},
+	}
+
+	glyf, loca := NewTableGlyf([][]byte{nil, square.Bytes(), composite.Bytes()})
+	if loca.Offsets[0] != 0 || loca.Offsets[1] != 0 {
+		t.Errorf("loca.Offsets = %v, want empty first glyph", loca.Offsets)
+	}
+	for _, offset := range loca.Offsets {
+		if offset%2 != 0 {
+			t.Errorf("loca.Offsets = %v, want even offsets", loca.Offsets)
+		}
+	}
+
+	for id, want := range []*Glyph{{}, square, composite} {
+		got, err := glyf.Glyph(GlyphID(id))
+		if err != nil {
+			t.Fatalf("Glyph(%d) err = %q, want nil", id, err)
+		}
+		if !got.IsEmpty() && !reflect.DeepEqual(got.Points, want.Points) {
+			t.Errorf("Glyph(%d).Points = %v, want %v", id, got.Points, want.Points)
+		}
+		for i := range got.Components {
+			got.Components[i].Flags &^= componentEncodedFlags
+		}
+		if !reflect.DeepEqual(got.Components, want.Components) {
+			t.Errorf("Glyph(%d).Components = %v, want %v", id, got.Components, want.Components)
+		}
+	}
+}
+
+func TestGlyfBounds(t *testing.T) {
+	square := &Glyph{
+		NumberOfContours: 1,
+		XMax:             100,
+		YMax:             100,
+		EndPoints:        []uint16{3},
+		Points:           []GlyphPoint{{0, 0, true}, {0, 100, true}, {100, 100, true}, {100, 0, true}},
+	}
+	composite := &Glyph{
+		NumberOfContours: -1,
+		Components: []GlyphComponent{
+			{Flags: ComponentArgsAreXYValues, Glyph: 0, Arg1: -50, Transform: [4]float64{1, 0, 0, 1}},
+			{Flags: ComponentArgsAreXYValues, Glyph: 0, Arg2: 200, Transform: [4]float64{0.5, 0, 0, 1.5}},
+		},
+	}
+	glyf, _ := NewTableGlyf([][]byte{square.Bytes(), composite.Bytes()})
+
+	xMin, yMin, xMax, yMax, err := glyf.Bounds(1)
+	if err != nil {
+		t.Fatalf("Bounds(1) err = %q, want nil", err)
+	}
+	if got, want := [4]int16{xMin, yMin, xMax, yMax}, [4]int16{-50, 0, 50, 350}; got != want {
+		t.Errorf("Bounds(1) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,559 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// TableGvar contains the variations of TrueType glyph outlines in a variable font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/gvar
+type TableGvar struct {
+	baseTable
+
+	bytes []byte
+
+	AxisCount int
+	// SharedTuples contains peak coordinates referenced by the tuple variations of many glyphs.
+	SharedTuples [][]F2Dot14
+
+	// glyphData contains the serialized variation data of each glyph.
+	glyphData [][]byte
+}
+
+// TupleVariation contains the deltas applied to a glyph's points, or to the
+// values of the 'cvt ' table, in a region of the design space.
+type TupleVariation struct {
+	// Region is the region in which the deltas apply, with one RegionAxis per axis.
+	Region VariationRegion
+	// Points contains the indices of the points with explicit deltas, or is nil
+	// if every point, including the phantom points, has a delta.
+	Points []uint16
+	// DeltasX and DeltasY contain the deltas of each of the Points. DeltasY
+	// is nil for the variations of control values.
+	DeltasX, DeltasY []int32
+}
+
+// Flags of the gvar header and tuple variation headers.
+const (
+	gvarLongOffsets         = 0x0001
+	tupleCountMask          = 0x0FFF
+	tupleSharedPointNumbers = 0x8000
+	tupleEmbeddedPeak       = 0x8000
+	tupleIntermediateRegion = 0x4000
+	tuplePrivatePoints      = 0x2000
+	tupleIndexMask          = 0x0FFF
+)
+
+var errInvalidGvar = errors.New("invalid 'gvar' table")
+
+func parseTableGvar(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 20 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if major := binary.BigEndian.Uint16(buf); major != 1 {
+		return nil, fmt.Errorf("unsupported 'gvar' version %d", major)
+	}
+
+	axisCount := int(binary.BigEndian.Uint16(buf[4:]))
+	sharedTupleCount := int(binary.BigEndian.Uint16(buf[6:]))
+	sharedTuplesOffset := int(binary.BigEndian.Uint32(buf[8:]))
+	glyphCount := int(binary.BigEndian.Uint16(buf[12:]))
+	flags := binary.BigEndian.Uint16(buf[14:])
+	dataOffset := int(binary.BigEndian.Uint32(buf[16:]))
+
+	table := &TableGvar{
+		baseTable:    baseTable(tag),
+		bytes:        buf,
+		AxisCount:    axisCount,
+		SharedTuples: make([][]F2Dot14, sharedTupleCount),
+		glyphData:    make([][]byte, glyphCount),
+	}
+
+	if sharedTuplesOffset+2*axisCount*sharedTupleCount > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	for i := range table.SharedTuples {
+		table.SharedTuples[i] = readF2Dot14s(buf[sharedTuplesOffset+2*axisCount*i:], axisCount)
+	}
+
+	offsetSize := 2
+	if flags&gvarLongOffsets != 0 {
+		offsetSize = 4
+	}
+	if 20+offsetSize*(glyphCount+1) > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	offset := func(i int) int {
+		if offsetSize == 4 {
+			return dataOffset + int(binary.BigEndian.Uint32(buf[20+4*i:]))
+		}
+		return dataOffset + 2*int(binary.BigEndian.Uint16(buf[20+2*i:]))
+	}
+
+	for i := range table.glyphData {
+		start, end := offset(i), offset(i+1)
+		if start > end || end > len(buf) {
+			return nil, errInvalidGvar
+		}
+		table.glyphData[i] = buf[start:end]
+	}
+
+	return table, nil
+}
+
+func readF2Dot14s(b []byte, n int) []F2Dot14 {
+	values := make([]F2Dot14, n)
+	for i := range values {
+		values[i] = F2Dot14(binary.BigEndian.Uint16(b[2*i:]))
+	}
+	return values
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableGvar) Bytes() []byte {
+	return table.bytes
+}
+
+// NumGlyphs returns the number of glyphs with variation data in the table.
+func (table *TableGvar) NumGlyphs() int {
+	return len(table.glyphData)
+}
+
+// GlyphVariations returns the tuple variations of a glyph. numPoints is the
+// number of points in the glyph, including the four phantom points (for composite
+// glyphs, each component counts as one point).
+func (table *TableGvar) GlyphVariations(glyph GlyphID, numPoints int) ([]TupleVariation, error) {
+	if int(glyph) >= len(table.glyphData) {
+		return nil, nil
+	}
+	b := table.glyphData[glyph]
+	if len(b) == 0 {
+		return nil, nil
+	}
+	return parseTupleVariations(b, 0, table.AxisCount, table.SharedTuples, numPoints, true)
+}
+
+// parseTupleVariations parses the tuple variations in b, starting with the
+// tuple count at offset start. The offset of the serialized data is relative
+// to the start of b. numPoints is the number of points or values varied, and
+// hasY is false if the deltas are for values, which only have DeltasX.
+func parseTupleVariations(b []byte, start, axisCount int, sharedTuples [][]F2Dot14, numPoints int, hasY bool) ([]TupleVariation, error) {
+	if len(b) < start+4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	countFlags := binary.BigEndian.Uint16(b[start:])
+	count := int(countFlags & tupleCountMask)
+	dataOffset := int(binary.BigEndian.Uint16(b[start+2:]))
+	if dataOffset > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := b[dataOffset:]
+
+	var sharedPoints []uint16
+	if countFlags&tupleSharedPointNumbers != 0 {
+		var err error
+		if sharedPoints, data, err = readPackedPoints(data); err != nil {
+			return nil, err
+		}
+	}
+
+	variations := make([]TupleVariation, 0, count)
+	header := b[start+4:]
+	for i := 0; i < count; i++ {
+		if len(header) < 4 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		size := int(binary.BigEndian.Uint16(header))
+		index := binary.BigEndian.Uint16(header[2:])
+		header = header[4:]
+
+		var peak []F2Dot14
+		if index&tupleEmbeddedPeak != 0 {
+			if len(header) < 2*axisCount {
+				return nil, io.ErrUnexpectedEOF
+			}
+			peak = readF2Dot14s(header, axisCount)
+			header = header[2*axisCount:]
+		} else {
+			shared := int(index & tupleIndexMask)
+			if shared >= len(sharedTuples) {
+				return nil, errInvalidGvar
+			}
+			peak = sharedTuples[shared]
+		}
+
+		var starts, ends []F2Dot14
+		if index&tupleIntermediateRegion != 0 {
+			if len(header) < 4*axisCount {
+				return nil, io.ErrUnexpectedEOF
+			}
+			starts = readF2Dot14s(header, axisCount)
+			ends = readF2Dot14s(header[2*axisCount:], axisCount)
+			header = header[4*axisCount:]
+		}
+
+		if size > len(data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		tuple := data[:size]
+		data = data[size:]
+
+		v := TupleVariation{Region: make(VariationRegion, axisCount), Points: sharedPoints}
+		for j, p := range peak {
+			r := RegionAxis{Peak: p}
+			switch {
+			case starts != nil:
+				r.Start, r.End = starts[j], ends[j]
+			case p < 0:
+				r.Start = p
+			default:
+				r.End = p
+			}
+			v.Region[j] = r
+		}
+
+		if index&tuplePrivatePoints != 0 {
+			var err error
+			if v.Points, tuple, err = readPackedPoints(tuple); err != nil {
+				return nil, err
+			}
+		}
+
+		n := numPoints
+		if v.Points != nil {
+			n = len(v.Points)
+		}
+		var err error
+		if v.DeltasX, tuple, err = readPackedDeltas(tuple, n); err != nil {
+			return nil, err
+		}
+		if !hasY {
+			variations = append(variations, v)
+			continue
+		}
+		if v.DeltasY, _, err = readPackedDeltas(tuple, n); err != nil {
+			return nil, err
+		}
+
+		variations = append(variations, v)
+	}
+
+	return variations, nil
+}
+
+// Flags of packed point numbers and deltas.
+const (
+	pointsAreWords = 0x80
+	pointRunMask   = 0x7F
+	deltasAreZero  = 0x80
+	deltasAreWords = 0x40
+	deltasAreLongs = 0xC0
+	deltaRunMask   = 0x3F
+	deltaSizeMask  = 0xC0
+)
+
+// readPackedPoints reads packed point numbers from b, returning nil if all
+// points are referenced, and the remaining bytes.
+func readPackedPoints(b []byte) ([]uint16, []byte, error) {
+	if len(b) < 1 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	count := int(b[0])
+	b = b[1:]
+	if count&pointsAreWords != 0 {
+		if len(b) < 1 {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+		count = (count&pointRunMask)<<8 | int(b[0])
+		b = b[1:]
+	}
+	if count == 0 {
+		return nil, b, nil
+	}
+
+	points := make([]uint16, 0, count)
+	var point uint16
+	for len(points) < count {
+		if len(b) < 1 {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+		control := b[0]
+		b = b[1:]
+		run := int(control&pointRunMask) + 1
+
+		size := 1
+		if control&pointsAreWords != 0 {
+			size = 2
+		}
+		if len(b) < run*size {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+		for i := 0; i < run && len(points) < count; i++ {
+			if size == 2 {
+				point += binary.BigEndian.Uint16(b[2*i:])
+			} else {
+				point += uint16(b[i])
+			}
+			points = append(points, point)
+		}
+		b = b[run*size:]
+	}
+	return points, b, nil
+}
+
+// readPackedDeltas reads n packed deltas from b, returning them and the remaining bytes.
+func readPackedDeltas(b []byte, n int) ([]int32, []byte, error) {
+	deltas := make([]int32, 0, n)
+	for len(deltas) < n {
+		if len(b) < 1 {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+		control := b[0]
+		b = b[1:]
+		run := int(control&deltaRunMask) + 1
+		if len(deltas)+run > n {
+			return nil, nil, errInvalidGvar
+		}
+
+		var size int
+		switch control & deltaSizeMask {
+		case deltasAreZero:
+			size = 0
+		case deltasAreWords:
+			size = 2
+		case deltasAreLongs:
+			size = 4
+		default:
+			size = 1
+		}
+		if len(b) < run*size {
+			return nil, nil, io.ErrUnexpectedEOF
+		}
+
+		for i := 0; i < run; i++ {
+			switch size {
+			case 0:
+				deltas = append(deltas, 0)
+			case 1:
+				deltas = append(deltas, int32(int8(b[i])))
+			case 2:
+				deltas = append(deltas, int32(int16(binary.BigEndian.Uint16(b[2*i:]))))
+			case 4:
+				deltas = append(deltas, int32(binary.BigEndian.Uint32(b[4*i:])))
+			}
+		}
+		b = b[run*size:]
+	}
+	return deltas, b, nil
+}
+
+// NewTableGvar returns a 'gvar' table containing the tuple variations of each
+// glyph. The peak of each variation is stored with the variation, rather than
+// as a shared tuple.
+func NewTableGvar(axisCount int, glyphs [][]TupleVariation) *TableGvar {
+	table := &TableGvar{
+		baseTable: baseTable(TagGvar),
+		AxisCount: axisCount,
+		glyphData: make([][]byte, len(glyphs)),
+	}
+
+	size := 0
+	for i, variations := range glyphs {
+		data := appendTupleVariations(nil, 0, axisCount, variations)
+		if len(data)%2 != 0 {
+			data = append(data, 0)
+		}
+		table.glyphData[i] = data
+		size += len(data)
+	}
+
+	offsetSize := 2
+	var flags uint16
+	if size > 0x1FFFE {
+		offsetSize, flags = 4, gvarLongOffsets
+	}
+	dataOffset := 20 + offsetSize*(len(glyphs)+1)
+
+	buf := make([]byte, dataOffset, dataOffset+size)
+	binary.BigEndian.PutUint16(buf, 1)
+	binary.BigEndian.PutUint16(buf[4:], uint16(axisCount))
+	binary.BigEndian.PutUint32(buf[8:], uint32(dataOffset))
+	binary.BigEndian.PutUint16(buf[12:], uint16(len(glyphs)))
+	binary.BigEndian.PutUint16(buf[14:], flags)
+	binary.BigEndian.PutUint32(buf[16:], uint32(dataOffset))
+
+	for i, data := range table.glyphData {
+		start := len(buf)
+		buf = append(buf, data...)
+		table.glyphData[i] = buf[start:]
+		if offsetSize == 4 {
+			binary.BigEndian.PutUint32(buf[20+4*(i+1):], uint32(len(buf)-dataOffset))
+		} else {
+			binary.BigEndian.PutUint16(buf[20+2*(i+1):], uint16((len(buf)-dataOffset)/2))
+		}
+	}
+
+	table.bytes = buf
+	return table
+}
+
+// appendTupleVariations appends serialized tuple variations to buf, starting
+// with the tuple count. The offset of the serialized data is relative to
+// prefix bytes before the tuple count. DeltasY is only written if it is not nil.
+func appendTupleVariations(buf []byte, prefix, axisCount int, variations []TupleVariation) []byte {
+	if len(variations) == 0 {
+		return buf
+	}
+
+	var headers, data []byte
+	for _, v := range variations {
+		start := len(data)
+		index := uint16(tupleEmbeddedPeak)
+		if v.Points != nil {
+			index |= tuplePrivatePoints
+			data = appendPackedPoints(data, v.Points)
+		}
+		data = appendPackedDeltas(data, v.DeltasX)
+		data = appendPackedDeltas(data, v.DeltasY)
+
+		intermediate := false
+		for _, axis := range v.Region {
+			implied := RegionAxis{Peak: axis.Peak}
+			if axis.Peak < 0 {
+				implied.Start = axis.Peak
+			} else {
+				implied.End = axis.Peak
+			}
+			intermediate = intermediate || axis != implied
+		}
+		if intermediate {
+			index |= tupleIntermediateRegion
+		}
+
+		headers = appendUint16(headers, uint16(len(data)-start))
+		headers = appendUint16(headers, index)
+		for i := 0; i < axisCount; i++ {
+			headers = appendUint16(headers, uint16(v.Region.axis(i).Peak))
+		}
+		if intermediate {
+			for i := 0; i < axisCount; i++ {
+				headers = appendUint16(headers, uint16(v.Region.axis(i).Start))
+			}
+			for i := 0; i < axisCount; i++ {
+				headers = appendUint16(headers, uint16(v.Region.axis(i).End))
+			}
+		}
+	}
+
+	buf = appendUint16(buf, uint16(len(variations)))
+	buf = appendUint16(buf, uint16(prefix+4+len(headers)))
+	buf = append(buf, headers...)
+	return append(buf, data...)
+}
+
+// axis returns the range of the region along axis i, which is zero if the
+// region does not cover the axis.
+func (region VariationRegion) axis(i int) RegionAxis {
+	if i < len(region) {
+		return region[i]
+	}
+	return RegionAxis{}
+}
+
+// appendPackedPoints appends the packed representation of points, which must be sorted, to buf.
+func appendPackedPoints(buf []byte, points []uint16) []byte {
+	if len(points) < pointsAreWords {
+		buf = append(buf, byte(len(points)))
+	} else {
+		buf = appendUint16(buf, uint16(len(points))|pointsAreWords<<8)
+	}
+
+	var last uint16
+	for i := 0; i < len(points); {
+		words := points[i]-last > 0xFF
+		control := len(buf)
+		buf = append(buf, 0)
+
+		run := 0
+		for ; i < len(points) && run <= pointRunMask; i, run = i+1, run+1 {
+			diff := points[i] - last
+			if (diff > 0xFF) != words {
+				break
+			}
+			if words {
+				buf = appendUint16(buf, diff)
+			} else {
+				buf = append(buf, byte(diff))
+			}
+			last = points[i]
+		}
+
+		buf[control] = byte(run - 1)
+		if words {
+			buf[control] |= pointsAreWords
+		}
+	}
+	return buf
+}
+
+// appendPackedDeltas appends the packed representation of deltas to buf.
+func appendPackedDeltas(buf []byte, deltas []int32) []byte {
+	// size returns the number of bytes needed to store d.
+	size := func(d int32) int {
+		switch {
+		case d == 0:
+			return 0
+		case d >= math.MinInt8 && d <= math.MaxInt8:
+			return 1
+		case d >= math.MinInt16 && d <= math.MaxInt16:
+			return 2
+		default:
+			return 4
+		}
+	}
+
+	for i := 0; i < len(deltas); {
+		n := size(deltas[i])
+		run := 1
+		for i+run < len(deltas) && run <= deltaRunMask && size(deltas[i+run]) == n {
+			run++
+		}
+
+		control := byte(run - 1)
+		switch n {
+		case 0:
+			control |= deltasAreZero
+		case 2:
+			control |= deltasAreWords
+		case 4:
+			control |= deltasAreLongs
+		}
+		buf = append(buf, control)
+
+		for _, d := range deltas[i : i+run] {
+			switch n {
+			case 1:
+				buf = append(buf, byte(int8(d)))
+			case 2:
+				buf = appendUint16(buf, uint16(int16(d)))
+			case 4:
+				buf = appendUint16(buf, uint16(uint32(d)>>16))
+				buf = appendUint16(buf, uint16(d))
+			}
+		}
+		i += run
+	}
+	return buf
+}
+
+// GvarTable returns the table corresponding to the 'gvar' tag.
+func (font *Font) GvarTable() (*TableGvar, error) {
+	t, err := font.Table(TagGvar)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableGvar), nil
+}
@@ -0,0 +1,179 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// testGvarFont returns a font with a single square glyph. At wght=1.0 the top
+// of the square moves up by 50 units, its right side moves right by 50 units,
+// and the advance width increases by 50 units. Only points 1, 2 and the right
+// phantom point have explicit deltas, the others are interpolated.
+func testGvarFont(t *testing.T) *Font {
+	square := &Glyph{
+		NumberOfContours: 1,
+		XMax:             100,
+		YMax:             100,
+		EndPoints:        []uint16{3},
+		Points:           []GlyphPoint{{0, 0, true}, {0, 100, true}, {100, 100, true}, {100, 0, true}},
+	}
+	glyf, loca := NewTableGlyf([][]byte{square.Bytes()})
+
+	head := &TableHead{baseTable: baseTable(TagHead)}
+	head.UnitsPerEm = 1000
+	if !loca.Short {
+		head.IndexToLocFormat = 1
+	}
+	hhea := &TableHhea{baseTable: baseTable(TagHhea)}
+	hhea.Ascent, hhea.Descent, hhea.NumOfLongHorMetrics = 800, -200, 1
+	maxp := &TableMaxp{baseTable: baseTable(TagMaxp)}
+	maxp.Version.Major, maxp.NumGlyphs = 1, 1
+
+	variation := []byte{
+		0, 1, // tupleVariationCount
+		0, 10, // dataOffset
+		0, 13, // variationDataSize
+		0xA0, 0, // EMBEDDED_PEAK_TUPLE | PRIVATE_POINT_NUMBERS
+		0x40, 0, // peak wght=1.0
+		3, 0x02, 1, 1, 3, // points 1, 2, 5
+		0x02, 0, 50, 50, // x deltas
+		0x02, 50, 50, 0, // y deltas
+		0, // padding
+	}
+	gvar := make([]byte, 24, 24+len(variation))
+	binary.BigEndian.PutUint16(gvar, 1)                             // majorVersion
+	binary.BigEndian.PutUint16(gvar[4:], 1)                         // axisCount
+	binary.BigEndian.PutUint16(gvar[12:], 1)                        // glyphCount
+	binary.BigEndian.PutUint32(gvar[16:], 24)                       // glyphVariationDataArrayOffset
+	binary.BigEndian.PutUint16(gvar[22:], uint16(len(variation)/2)) // offsets[1]
+	gvar = append(gvar, variation...)
+
+	parsed, err := parseTableGvar(TagGvar, gvar, nil)
+	if err != nil {
+		t.Fatalf("parseTableGvar() err = %q, want nil", err)
+	}
+
+	font := New(TypeTrueType)
+	font.AddTable(TagHead, head)
+	font.AddTable(TagHhea, hhea)
+	font.AddTable(TagMaxp, maxp)
+	font.AddTable(TagHmtx, &TableHmtx{baseTable: baseTable(TagHmtx), Metrics: []HMetric{{AdvanceWidth: 100}}})
+	font.AddTable(TagLoca, loca)
+	font.AddTable(TagGlyf, glyf)
+	font.AddTable(TagGvar, parsed)
+	return font
+}
+
+func TestGlyphOutlineVariations(t *testing.T) {
+	font := testGvarFont(t)
+
+	tests := []struct {
+		coords  []F2Dot14
+		points  [][2]float64
+		advance float64
+	}{
+		{coords: nil, points: [][2]float64{{0, 0}, {0, 100}, {100, 100}, {100, 0}}, advance: 100},
+		{coords: []F2Dot14{0}, points: [][2]float64{{0, 0}, {0, 100}, {100, 100}, {100, 0}}, advance: 100},
+		{coords: []F2Dot14{1 << 14}, points: [][2]float64{{0, 50}, {0, 150}, {150, 150}, {150, 50}}, advance: 150},
+		{coords: []F2Dot14{1 << 13}, points: [][2]float64{{0, 25}, {0, 125}, {125, 125}, {125, 25}}, advance: 125},
+		{coords: []F2Dot14{-1 << 14}, points: [][2]float64{{0, 0}, {0, 100}, {100, 100}, {100, 0}}, advance: 100},
+	}
+
+	for _, test := range tests {
+		outline, err := font.GlyphOutline(0, test.coords)
+		if err != nil {
+			t.Fatalf("GlyphOutline(0, %v) err = %q, want nil", test.coords, err)
+		}
+
+		var points [][2]float64
+		for _, p := range outline.Points {
+			points = append(points, [2]float64{p.X, p.Y})
+		}
+		if !reflect.DeepEqual(points, test.points) {
+			t.Errorf("GlyphOutline(0, %v).Points = %v, want %v", test.coords, points, test.points)
+		}
+		if outline.AdvanceWidth != test.advance {
+			t.Errorf("GlyphOutline(0, %v).AdvanceWidth = %v, want %v", test.coords, outline.AdvanceWidth, test.advance)
+		}
+	}
+}
+
+func TestPackedPointsAndDeltas(t *testing.T) {
+	// Three points: a run of two words, then a run of one byte.
+	points, rest, err := readPackedPoints([]byte{3, 0x81, 0, 1, 1, 0, 0x00, 7, 0xAA})
+	if err != nil {
+		t.Fatalf("readPackedPoints() err = %q, want nil", err)
+	}
+	if want := []uint16{1, 257, 264}; !reflect.DeepEqual(points, want) {
+		t.Errorf("readPackedPoints() = %v, want %v", points, want)
+	}
+	if !reflect.DeepEqual(rest, []byte{0xAA}) {
+		t.Errorf("readPackedPoints() rest = %v, want [170]", rest)
+	}
+
+	deltas, _, err := readPackedDeltas([]byte{0x81, 0x40, 0xFF, 0x38, 0x01, 5, 0xFB}, 5)
+	if err != nil {
+		t.Fatalf("readPackedDeltas() err = %q, want nil", err)
+	}
+	if want := []int32{0, 0, -200, 5, -5}; !reflect.DeepEqual(deltas, want) {
+		t.Errorf("readPackedDeltas() = %v, want %v", deltas, want)
+	}
+}
+
+func TestNewTableGvarRoundTrip(t *testing.T) {
+	points := make([]uint16, 200)
+	for i := range points {
+		points[i] = uint16(3 * i)
+	}
+	points[199] = 1000
+	deltas := make([]int32, 200)
+	for i := range deltas {
+		deltas[i] = int32(i*i) - 100
+	}
+
+	glyphs := [][]TupleVariation{
+		nil,
+		{
+			{
+				Region:  VariationRegion{{Peak: 1 << 14, End: 1 << 14}, {}},
+				DeltasX: []int32{0, 0, 1, -1, 200, 0},
+				DeltasY: []int32{70000, -70000, 0, 0, 0, 5},
+			},
+			{
+				Region:  VariationRegion{{Start: 1 << 13, Peak: 1 << 14, End: 1 << 14}, {Start: -1 << 14, Peak: -1 << 14}},
+				Points:  []uint16{0, 5},
+				DeltasX: []int32{10, -10},
+				DeltasY: []int32{0, 300},
+			},
+		},
+		{
+			{
+				Region:  VariationRegion{{}, {Peak: 1 << 14, End: 1 << 14}},
+				Points:  points,
+				DeltasX: deltas,
+				DeltasY: deltas,
+			},
+		},
+	}
+
+	buf := NewTableGvar(2, glyphs).Bytes()
+	parsed, err := parseTableGvar(TagGvar, buf, nil)
+	if err != nil {
+		t.Fatalf("parseTableGvar() err = %q, want nil", err)
+	}
+	gvar := parsed.(*TableGvar)
+
+	for id, want := range glyphs {
+		got, err := gvar.GlyphVariations(GlyphID(id), 6)
+		if err != nil {
+			t.Fatalf("GlyphVariations(%d) err = %q, want nil", id, err)
+		}
+		if len(want) == 0 && len(got) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GlyphVariations(%d) = %+v, want %+v", id, got, want)
+		}
+	}
+}
@@ -32,7 +32,7 @@ type tableHeadFields struct {
 	GlyphDataFormat    int16
 }
 
-func parseTableHead(tag Tag, buf []byte) (Table, error) {
+func parseTableHead(tag Tag, buf []byte, _ *Options) (Table, error) {
 	r := bytes.NewBuffer(buf)
 
 	var fields tableHeadFields
@@ -49,7 +49,7 @@ func parseTableHead(tag Tag, buf []byte) (Table, error) {
 // Bytes returns the byte representation of this header.
 func (table *TableHead) Bytes() []byte {
 	var buffer bytes.Buffer
-	if err := binary.Write(&buffer, binary.BigEndian, table); err != nil {
+	if err := binary.Write(&buffer, binary.BigEndian, table.tableHeadFields); err != nil {
 		panic(err) // should never happen
 	}
 	return buffer.Bytes()
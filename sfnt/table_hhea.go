@@ -30,7 +30,7 @@ type tableHheaFields struct {
 	NumOfLongHorMetrics int16
 }
 
-func parseTableHhea(tag Tag, buf []byte) (Table, error) {
+func parseTableHhea(tag Tag, buf []byte, _ *Options) (Table, error) {
 	r := bytes.NewBuffer(buf)
 
 	var fields tableHheaFields
@@ -46,7 +46,7 @@ func parseTableHhea(tag Tag, buf []byte) (Table, error) {
 // Bytes returns the byte representation of this header.
 func (table *TableHhea) Bytes() []byte {
 	var buffer bytes.Buffer
-	if err := binary.Write(&buffer, binary.BigEndian, table); err != nil {
+	if err := binary.Write(&buffer, binary.BigEndian, table.tableHheaFields); err != nil {
 		panic(err) // should never happen
 	}
 	return buffer.Bytes()
@@ -0,0 +1,146 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// GlyphID is the index of a glyph in the font.
+type GlyphID uint16
+
+// HMetric contains the horizontal metrics of a glyph.
+type HMetric struct {
+	AdvanceWidth    uint16
+	LeftSideBearing int16
+}
+
+// TableHmtx contains the horizontal metrics for each glyph in the font.
+// The table can only be parsed using the 'hhea' and 'maxp' tables, so it is
+// accessed using Font.HmtxTable.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/hmtx
+type TableHmtx struct {
+	baseTable
+
+	// Metrics contains one entry for each of the first hhea.NumOfLongHorMetrics glyphs.
+	Metrics []HMetric
+	// LeftSideBearings contains the left side bearings of the remaining glyphs,
+	// which all use the advance width of the last entry in Metrics.
+	LeftSideBearings []int16
+}
+
+func parseTableHmtx(tag Tag, buf []byte, numHMetrics, numGlyphs int) (*TableHmtx, error) {
+	if numHMetrics == 0 && numGlyphs > 0 {
+		return nil, fmt.Errorf("hhea.NumOfLongHorMetrics is 0")
+	}
+	if numHMetrics > numGlyphs {
+		return nil, fmt.Errorf("hhea.NumOfLongHorMetrics %d exceeds number of glyphs %d", numHMetrics, numGlyphs)
+	}
+	if len(buf) < 4*numHMetrics+2*(numGlyphs-numHMetrics) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableHmtx{
+		baseTable:        baseTable(tag),
+		Metrics:          make([]HMetric, numHMetrics),
+		LeftSideBearings: make([]int16, numGlyphs-numHMetrics),
+	}
+
+	for i := range table.Metrics {
+		table.Metrics[i] = HMetric{
+			AdvanceWidth:    binary.BigEndian.Uint16(buf[4*i:]),
+			LeftSideBearing: int16(binary.BigEndian.Uint16(buf[4*i+2:])),
+		}
+	}
+	buf = buf[4*numHMetrics:]
+	for i := range table.LeftSideBearings {
+		table.LeftSideBearings[i] = int16(binary.BigEndian.Uint16(buf[2*i:]))
+	}
+
+	return table, nil
+}
+
+// NumGlyphs returns the number of glyphs with metrics in this table.
+func (table *TableHmtx) NumGlyphs() int {
+	return len(table.Metrics) + len(table.LeftSideBearings)
+}
+
+// Metric returns the advance width and left side bearing of a glyph.
+// Glyphs not in the table have the metrics of the last glyph.
+func (table *TableHmtx) Metric(glyph GlyphID) HMetric {
+	if int(glyph) < len(table.Metrics) {
+		return table.Metrics[glyph]
+	}
+
+	var m HMetric
+	if len(table.Metrics) > 0 {
+		m.AdvanceWidth = table.Metrics[len(table.Metrics)-1].AdvanceWidth
+	}
+	if i := int(glyph) - len(table.Metrics); i < len(table.LeftSideBearings) {
+		m.LeftSideBearing = table.LeftSideBearings[i]
+	} else if len(table.LeftSideBearings) > 0 {
+		m.LeftSideBearing = table.LeftSideBearings[len(table.LeftSideBearings)-1]
+	}
+	return m
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableHmtx) Bytes() []byte {
+	buf := make([]byte, 4*len(table.Metrics)+2*len(table.LeftSideBearings))
+	for i, m := range table.Metrics {
+		binary.BigEndian.PutUint16(buf[4*i:], m.AdvanceWidth)
+		binary.BigEndian.PutUint16(buf[4*i+2:], uint16(m.LeftSideBearing))
+	}
+	lsb := buf[4*len(table.Metrics):]
+	for i, v := range table.LeftSideBearings {
+		binary.BigEndian.PutUint16(lsb[2*i:], uint16(v))
+	}
+	return buf
+}
+
+// NewTableHmtx returns an 'hmtx' table containing metrics. Glyphs at the
+// end with the same advance only need a left side bearing.
+func NewTableHmtx(metrics []HMetric) *TableHmtx {
+	numLong := len(metrics)
+	for numLong > 1 && metrics[numLong-1].AdvanceWidth == metrics[numLong-2].AdvanceWidth {
+		numLong--
+	}
+	hmtx := &TableHmtx{Metrics: metrics[:numLong]}
+	for _, m := range metrics[numLong:] {
+		hmtx.LeftSideBearings = append(hmtx.LeftSideBearings, m.LeftSideBearing)
+	}
+	return hmtx
+}
+
+// HmtxTable returns the table corresponding to the 'hmtx' tag.
+// It requires the 'hhea' and 'maxp' tables to parse.
+func (font *Font) HmtxTable() (*TableHmtx, error) {
+	s, found := font.tables[TagHmtx]
+	if !found {
+		return nil, ErrMissingTable
+	}
+	if t, ok := s.table.(*TableHmtx); ok {
+		return t, nil
+	}
+
+	hhea, err := font.HheaTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagHhea, err)
+	}
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagMaxp, err)
+	}
+
+	buf, err := font.tableBytes(s)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := parseTableHmtx(TagHmtx, buf, int(uint16(hhea.NumOfLongHorMetrics)), int(maxp.NumGlyphs))
+	if err != nil {
+		return nil, err
+	}
+	s.table = t
+	return t, nil
+}
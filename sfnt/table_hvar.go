@@ -0,0 +1,175 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// TableHvar contains the variations of glyph metrics in a variable font. It is
+// used for both the 'HVAR' and 'VVAR' tables, which differ only in that 'VVAR'
+// also has a VOrgMap.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/hvar
+// https://docs.microsoft.com/en-us/typography/opentype/spec/vvar
+type TableHvar struct {
+	baseTable
+
+	VarStore *ItemVariationStore
+	// AdvanceMap maps glyphs to the deltas of their advances. If it is nil,
+	// glyph IDs are used directly as inner indexes into the first ItemVariationData.
+	AdvanceMap *DeltaSetIndexMap
+	// StartSideMap maps glyphs to the deltas of their left (or top) side bearings, it may be nil.
+	StartSideMap *DeltaSetIndexMap
+	// EndSideMap maps glyphs to the deltas of their right (or bottom) side bearings, it may be nil.
+	EndSideMap *DeltaSetIndexMap
+	// VOrgMap maps glyphs to the deltas of their vertical origins. It is only
+	// present in 'VVAR', and may be nil.
+	VOrgMap *DeltaSetIndexMap
+}
+
+func parseTableHvar(tag Tag, buf []byte, _ *Options) (Table, error) {
+	numMaps := 3
+	if tag == TagVvar {
+		numMaps = 4
+	}
+	if len(buf) < 8+4*numMaps {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if major := binary.BigEndian.Uint16(buf); major != 1 {
+		return nil, fmt.Errorf("unsupported %q version %d", tag, major)
+	}
+
+	table := &TableHvar{
+		baseTable: baseTable(tag),
+	}
+
+	storeOffset := int(binary.BigEndian.Uint32(buf[4:]))
+	if storeOffset == 0 || storeOffset >= len(buf) {
+		return nil, fmt.Errorf("%q has no item variation store", tag)
+	}
+	store, err := ParseItemVariationStore(buf[storeOffset:])
+	if err != nil {
+		return nil, err
+	}
+	table.VarStore = store
+
+	maps := []**DeltaSetIndexMap{&table.AdvanceMap, &table.StartSideMap, &table.EndSideMap, &table.VOrgMap}
+	for i, m := range maps[:numMaps] {
+		offset := int(binary.BigEndian.Uint32(buf[8+4*i:]))
+		if offset == 0 {
+			continue
+		}
+		if offset >= len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if *m, err = parseDeltaSetIndexMap(buf[offset:]); err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableHvar) Bytes() []byte {
+	maps := []*DeltaSetIndexMap{table.AdvanceMap, table.StartSideMap, table.EndSideMap}
+	if Tag(table.baseTable) == TagVvar || table.VOrgMap != nil {
+		maps = append(maps, table.VOrgMap)
+	}
+
+	buf := make([]byte, 8+4*len(maps))
+	binary.BigEndian.PutUint16(buf, 1)
+	for i, m := range maps {
+		if m != nil {
+			binary.BigEndian.PutUint32(buf[8+4*i:], uint32(len(buf)))
+			buf = append(buf, m.Bytes()...)
+		}
+	}
+	binary.BigEndian.PutUint32(buf[4:], uint32(len(buf)))
+	return append(buf, table.VarStore.Bytes()...)
+}
+
+// AdvanceDelta returns the change to the advance of a glyph at the normalized coords.
+func (table *TableHvar) AdvanceDelta(glyph GlyphID, coords []F2Dot14) float64 {
+	index := VariationIndex{Inner: uint16(glyph)}
+	if table.AdvanceMap != nil {
+		index = table.AdvanceMap.Index(int(glyph))
+	}
+	return table.VarStore.Delta(index, coords)
+}
+
+// StartSideDelta returns the change to the left (or top) side bearing of a
+// glyph at the normalized coords. It returns false if the table has no
+// StartSideMap, in which case the side bearing must be computed from the outline.
+func (table *TableHvar) StartSideDelta(glyph GlyphID, coords []F2Dot14) (float64, bool) {
+	if table.StartSideMap == nil {
+		return 0, false
+	}
+	return table.VarStore.Delta(table.StartSideMap.Index(int(glyph)), coords), true
+}
+
+// EndSideDelta returns the change to the right (or bottom) side bearing of a
+// glyph at the normalized coords. It returns false if the table has no EndSideMap.
+func (table *TableHvar) EndSideDelta(glyph GlyphID, coords []F2Dot14) (float64, bool) {
+	if table.EndSideMap == nil {
+		return 0, false
+	}
+	return table.VarStore.Delta(table.EndSideMap.Index(int(glyph)), coords), true
+}
+
+// VOrgDelta returns the change to the vertical origin of a glyph at the
+// normalized coords. It returns false if the table has no VOrgMap.
+func (table *TableHvar) VOrgDelta(glyph GlyphID, coords []F2Dot14) (float64, bool) {
+	if table.VOrgMap == nil {
+		return 0, false
+	}
+	return table.VarStore.Delta(table.VOrgMap.Index(int(glyph)), coords), true
+}
+
+// HvarTable returns the table corresponding to the 'HVAR' tag.
+func (font *Font) HvarTable() (*TableHvar, error) {
+	t, err := font.Table(TagHvar)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableHvar), nil
+}
+
+// VvarTable returns the table corresponding to the 'VVAR' tag.
+func (font *Font) VvarTable() (*TableHvar, error) {
+	t, err := font.Table(TagVvar)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableHvar), nil
+}
+
+// AdvanceWidth returns the advance width of a glyph. If coords is not nil, it
+// contains the normalized coordinates at which the variations from 'HVAR' are
+// applied, or from 'gvar' if the font has no 'HVAR' table.
+func (font *Font) AdvanceWidth(glyph GlyphID, coords []F2Dot14) (float64, error) {
+	hmtx, err := font.HmtxTable()
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q: %w", TagHmtx, err)
+	}
+	advance := float64(hmtx.Metric(glyph).AdvanceWidth)
+	if coords == nil {
+		return advance, nil
+	}
+
+	if font.HasTable(TagHvar) {
+		hvar, err := font.HvarTable()
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: %w", TagHvar, err)
+		}
+		return advance + hvar.AdvanceDelta(glyph, coords), nil
+	}
+	if font.HasTable(TagGvar) && font.HasTable(TagGlyf) {
+		outline, err := font.GlyphOutline(glyph, coords)
+		if err != nil {
+			return 0, err
+		}
+		return outline.AdvanceWidth, nil
+	}
+	return advance, nil
+}
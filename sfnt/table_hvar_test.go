@@ -0,0 +1,145 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func u16s(values ...int) []byte {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return b
+}
+
+// testVarStoreBytes returns an ItemVariationStore for a single axis with two
+// items, which change by 50 and -20 units at the maximum of the axis.
+func testVarStoreBytes() []byte {
+	// Header, with a region list at 12 and one data at 22.
+	buf := u16s(1, 0, 12, 1, 0, 22)
+	// One region, peaking at 1.0.
+	buf = append(buf, u16s(1, 1, 0, 16384, 16384)...)
+	// Two items, with a 16-bit delta for the region.
+	return append(buf, u16s(2, 1, 1, 0, 50, -20)...)
+}
+
+func TestHvarAdvanceWidth(t *testing.T) {
+	hvar, err := parseTableHvar(TagHvar, append(u16s(1, 0, 0, 20, 0, 0, 0, 0, 0, 0), testVarStoreBytes()...), nil)
+	if err != nil {
+		t.Fatalf("parseTableHvar() err = %q, want nil", err)
+	}
+
+	font := New(TypeTrueType)
+	hhea := &TableHhea{baseTable: baseTable(TagHhea)}
+	hhea.NumOfLongHorMetrics = 2
+	maxp := &TableMaxp{baseTable: baseTable(TagMaxp)}
+	maxp.Version.Major, maxp.NumGlyphs = 1, 2
+	font.AddTable(TagHhea, hhea)
+	font.AddTable(TagMaxp, maxp)
+	font.AddTable(TagHmtx, &TableHmtx{baseTable: baseTable(TagHmtx), Metrics: []HMetric{{AdvanceWidth: 500}, {AdvanceWidth: 600}}})
+	font.AddTable(TagHvar, hvar)
+
+	tests := []struct {
+		glyph  GlyphID
+		coords []F2Dot14
+		want   float64
+	}{
+		{0, nil, 500},
+		{0, []F2Dot14{0}, 500},
+		{0, []F2Dot14{1 << 14}, 550},
+		{0, []F2Dot14{1 << 13}, 525},
+		{1, []F2Dot14{1 << 14}, 580},
+		{1, []F2Dot14{-1 << 14}, 600},
+	}
+	for _, test := range tests {
+		got, err := font.AdvanceWidth(test.glyph, test.coords)
+		if err != nil {
+			t.Fatalf("AdvanceWidth(%d, %v) err = %q, want nil", test.glyph, test.coords, err)
+		}
+		if got != test.want {
+			t.Errorf("AdvanceWidth(%d, %v) = %v, want %v", test.glyph, test.coords, got, test.want)
+		}
+	}
+
+	if _, ok := hvar.(*TableHvar).StartSideDelta(0, []F2Dot14{1 << 14}); ok {
+		t.Errorf("StartSideDelta() ok = true, want false without a side bearing map")
+	}
+}
+
+func TestMvarMetric(t *testing.T) {
+	// Two value records, out of order, with the store after them at 28.
+	buf := u16s(1, 0, 0, 8, 2, 28)
+	buf = append(buf, []byte("xhgt")...)
+	buf = append(buf, u16s(0, 1)...)
+	buf = append(buf, []byte("hasc")...)
+	buf = append(buf, u16s(0, 0)...)
+	mvar, err := parseTableMvar(TagMvar, append(buf, testVarStoreBytes()...), nil)
+	if err != nil {
+		t.Fatalf("parseTableMvar() err = %q, want nil", err)
+	}
+
+	font := New(TypeTrueType)
+	os2 := &TableOS2{baseTable: baseTable(TagOS2)}
+	os2.STypoAscender, os2.SxHeigh, os2.SCapHeight = 800, 500, 700
+	font.AddTable(TagOS2, os2)
+	font.AddTable(TagMvar, mvar)
+
+	tests := []struct {
+		tag    Tag
+		coords []F2Dot14
+		want   float64
+	}{
+		{MetricHorizontalAscender, nil, 800},
+		{MetricHorizontalAscender, []F2Dot14{1 << 14}, 850},
+		{MetricXHeight, []F2Dot14{1 << 13}, 490},
+		{MetricCapHeight, []F2Dot14{1 << 14}, 700},
+	}
+	for _, test := range tests {
+		got, err := font.Metric(test.tag, test.coords)
+		if err != nil {
+			t.Fatalf("Metric(%q, %v) err = %q, want nil", test.tag, test.coords, err)
+		}
+		if got != test.want {
+			t.Errorf("Metric(%q, %v) = %v, want %v", test.tag, test.coords, got, test.want)
+		}
+	}
+
+	if _, err := font.Metric(MustNamedTag("zzzz"), nil); err == nil {
+		t.Errorf("Metric(%q) err = nil, want error", "zzzz")
+	}
+}
+
+func TestHvarRoundTrip(t *testing.T) {
+	store := &ItemVariationStore{
+		Regions: []VariationRegion{{{Peak: 1 << 14, End: 1 << 14}}, {{Start: -1 << 14, Peak: -1 << 14}}},
+		Data: []ItemVariationData{
+			{RegionIndexes: []uint16{0, 1}, Deltas: [][]int32{{1, 300}, {-2, 4}}},
+			{RegionIndexes: []uint16{1}, Deltas: [][]int32{{100000}}},
+		},
+	}
+	hvar := &TableHvar{
+		baseTable:  baseTable(TagHvar),
+		VarStore:   store,
+		AdvanceMap: &DeltaSetIndexMap{Map: []VariationIndex{{0, 1}, {1, 0}, {0, 0}}},
+	}
+
+	parsed, err := parseTableHvar(TagHvar, hvar.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("parseTableHvar() err = %q, want nil", err)
+	}
+	got := parsed.(*TableHvar)
+	if !reflect.DeepEqual(got.AdvanceMap, hvar.AdvanceMap) {
+		t.Errorf("AdvanceMap = %v, want %v", got.AdvanceMap, hvar.AdvanceMap)
+	}
+
+	coords := [][]F2Dot14{{1 << 14}, {-1 << 14}, {1 << 13}}
+	for glyph := GlyphID(0); glyph < 3; glyph++ {
+		for _, c := range coords {
+			if got, want := got.AdvanceDelta(glyph, c), hvar.AdvanceDelta(glyph, c); got != want {
+				t.Errorf("AdvanceDelta(%d, %v) = %v, want %v", glyph, c, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// TableJSTF contains the lookups used to justify lines of text, by shrinking
+// or extending them in order of priority. Only parsing is supported, Bytes
+// returns the original table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/jstf
+type TableJSTF struct {
+	baseTable
+	bytes []byte
+
+	Scripts []*JstfScript // Scripts are sorted by tag.
+}
+
+// JstfScript contains the justification data of a script.
+type JstfScript struct {
+	Tag Tag
+	// ExtenderGlyphs contains the glyphs, such as kashida, that can be
+	// inserted to extend a line, sorted by glyph ID.
+	ExtenderGlyphs  []GlyphID
+	DefaultLanguage *JstfLangSys   // DefaultLanguage may be nil.
+	Languages       []*JstfLangSys // Languages are sorted by tag.
+}
+
+// JstfLangSys contains the justification data of a language.
+type JstfLangSys struct {
+	Tag Tag
+	// Priorities contains the changes to try in order, starting with the
+	// preferred one, until the line is justified.
+	Priorities []*JstfPriority
+}
+
+// JstfPriority contains the lookups enabled, disabled and applied to shrink
+// or extend a line at one priority.
+type JstfPriority struct {
+	ShrinkageEnableGSUB  JstfModList
+	ShrinkageDisableGSUB JstfModList
+	ShrinkageEnableGPOS  JstfModList
+	ShrinkageDisableGPOS JstfModList
+	// ShrinkageMax contains 'GPOS' type lookups, stored in 'JSTF', which
+	// limit how much the line can shrink.
+	ShrinkageMax []*Lookup
+
+	ExtensionEnableGSUB  JstfModList
+	ExtensionDisableGSUB JstfModList
+	ExtensionEnableGPOS  JstfModList
+	ExtensionDisableGPOS JstfModList
+	// ExtensionMax contains 'GPOS' type lookups, stored in 'JSTF', which
+	// limit how much the line can extend.
+	ExtensionMax []*Lookup
+}
+
+// JstfModList contains the indexes of lookups in the 'GSUB' or 'GPOS' table.
+type JstfModList []uint16
+
+// Lookups returns the lookups in the list from layout, the 'GSUB' or 'GPOS'
+// table that the list refers to.
+func (l JstfModList) Lookups(layout *TableLayout) ([]*Lookup, error) {
+	lookups := make([]*Lookup, len(l))
+	for i, index := range l {
+		if int(index) >= len(layout.Lookups) {
+			return nil, fmt.Errorf("lookup %d out of range in %q", index, Tag(layout.baseTable))
+		}
+		lookups[i] = layout.Lookups[index]
+	}
+	return lookups, nil
+}
+
+func parseTableJSTF(tag Tag, buf []byte, opts *Options) (Table, error) {
+	if len(buf) < 6 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if major := binary.BigEndian.Uint16(buf); major != 1 {
+		return nil, fmt.Errorf("unsupported %q version %d", tag, major)
+	}
+	count := int(binary.BigEndian.Uint16(buf[4:]))
+	if 6+6*count > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableJSTF{
+		baseTable: baseTable(tag),
+		bytes:     buf,
+		Scripts:   make([]*JstfScript, count),
+	}
+	for i := range table.Scripts {
+		record := buf[6+6*i:]
+		script, err := parseJstfScript(buf, int(binary.BigEndian.Uint16(record[4:])), opts)
+		if err != nil {
+			return nil, fmt.Errorf("script %d: %w", i, err)
+		}
+		script.Tag = Tag{binary.BigEndian.Uint32(record)}
+		table.Scripts[i] = script
+	}
+
+	return table, nil
+}
+
+// jstfBytes returns buf from a 16-bit offset relative to base, which must
+// have at least size bytes.
+func jstfBytes(buf []byte, base int, offset uint16, size int) ([]byte, error) {
+	start := base + int(offset)
+	if start+size > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return buf[start:], nil
+}
+
+func parseJstfScript(buf []byte, offset int, opts *Options) (*JstfScript, error) {
+	b, err := jstfBytes(buf, offset, 0, 6)
+	if err != nil {
+		return nil, err
+	}
+	script := &JstfScript{}
+
+	if extenderOffset := binary.BigEndian.Uint16(b); extenderOffset != 0 {
+		extender, err := jstfBytes(buf, offset, extenderOffset, 2)
+		if err != nil {
+			return nil, err
+		}
+		glyphs, err := parseUint16List(extender)
+		if err != nil {
+			return nil, err
+		}
+		script.ExtenderGlyphs = make([]GlyphID, len(glyphs))
+		for i, glyph := range glyphs {
+			script.ExtenderGlyphs[i] = GlyphID(glyph)
+		}
+	}
+
+	if defaultOffset := binary.BigEndian.Uint16(b[2:]); defaultOffset != 0 {
+		if script.DefaultLanguage, err = parseJstfLangSys(buf, offset+int(defaultOffset), opts); err != nil {
+			return nil, err
+		}
+	}
+
+	count := int(binary.BigEndian.Uint16(b[4:]))
+	if 6+6*count > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	script.Languages = make([]*JstfLangSys, count)
+	for i := range script.Languages {
+		record := b[6+6*i:]
+		lang, err := parseJstfLangSys(buf, offset+int(binary.BigEndian.Uint16(record[4:])), opts)
+		if err != nil {
+			return nil, err
+		}
+		lang.Tag = Tag{binary.BigEndian.Uint32(record)}
+		script.Languages[i] = lang
+	}
+
+	return script, nil
+}
+
+func parseJstfLangSys(buf []byte, offset int, opts *Options) (*JstfLangSys, error) {
+	b, err := jstfBytes(buf, offset, 0, 2)
+	if err != nil {
+		return nil, err
+	}
+	count := int(binary.BigEndian.Uint16(b))
+	if 2+2*count > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	lang := &JstfLangSys{Priorities: make([]*JstfPriority, count)}
+	for i := range lang.Priorities {
+		priority, err := parseJstfPriority(buf, offset+int(binary.BigEndian.Uint16(b[2+2*i:])), opts)
+		if err != nil {
+			return nil, fmt.Errorf("priority %d: %w", i, err)
+		}
+		lang.Priorities[i] = priority
+	}
+	return lang, nil
+}
+
+func parseJstfPriority(buf []byte, offset int, opts *Options) (*JstfPriority, error) {
+	b, err := jstfBytes(buf, offset, 0, 20)
+	if err != nil {
+		return nil, err
+	}
+	p := &JstfPriority{}
+
+	modLists := []*JstfModList{
+		&p.ShrinkageEnableGSUB, &p.ShrinkageDisableGSUB, &p.ShrinkageEnableGPOS, &p.ShrinkageDisableGPOS, nil,
+		&p.ExtensionEnableGSUB, &p.ExtensionDisableGSUB, &p.ExtensionEnableGPOS, &p.ExtensionDisableGPOS, nil,
+	}
+	for i, list := range modLists {
+		o := binary.BigEndian.Uint16(b[2*i:])
+		if o == 0 {
+			continue
+		}
+		sub, err := jstfBytes(buf, offset, o, 2)
+		if err != nil {
+			return nil, err
+		}
+		if list == nil {
+			lookups, err := parseJstfMax(sub, opts)
+			if err != nil {
+				return nil, fmt.Errorf("JstfMax: %w", err)
+			}
+			if i == 4 {
+				p.ShrinkageMax = lookups
+			} else {
+				p.ExtensionMax = lookups
+			}
+			continue
+		}
+		if *list, err = parseUint16List(sub); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// parseJstfMax parses the lookups of a JstfMax table, which have the same
+// format as those in 'GPOS'.
+func parseJstfMax(b []byte, opts *Options) ([]*Lookup, error) {
+	offsets, err := parseUint16List(b)
+	if err != nil {
+		return nil, err
+	}
+	gpos := &TableLayout{baseTable: baseTable(TagGpos)}
+	lookups := make([]*Lookup, len(offsets))
+	for i, offset := range offsets {
+		if lookups[i], err = gpos.parseLookup(b, offset, opts); err != nil {
+			return nil, err
+		}
+	}
+	return lookups, nil
+}
+
+// parseUint16List parses a count followed by that many 16-bit values, such as glyph IDs.
+func parseUint16List(b []byte) ([]uint16, error) {
+	count := int(binary.BigEndian.Uint16(b))
+	if 2+2*count > len(b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	list := make([]uint16, count)
+	for i := range list {
+		list[i] = binary.BigEndian.Uint16(b[2+2*i:])
+	}
+	return list, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableJSTF) Bytes() []byte {
+	return table.bytes
+}
+
+// Language returns the justification data of a language in a script, or the
+// default language of the script if the language has none. It returns nil
+// if the script has no justification data.
+func (table *TableJSTF) Language(script, language Tag) *JstfLangSys {
+	i := sort.Search(len(table.Scripts), func(i int) bool {
+		return table.Scripts[i].Tag.Number >= script.Number
+	})
+	if i == len(table.Scripts) || table.Scripts[i].Tag != script {
+		return nil
+	}
+	s := table.Scripts[i]
+	for _, lang := range s.Languages {
+		if lang.Tag == language {
+			return lang
+		}
+	}
+	return s.DefaultLanguage
+}
+
+// JSTFTable returns the table corresponding to the 'JSTF' tag.
+func (font *Font) JSTFTable() (*TableJSTF, error) {
+	t, err := font.Table(TagJSTF)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableJSTF), nil
+}
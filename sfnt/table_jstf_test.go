@@ -0,0 +1,79 @@
+package sfnt
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testJSTF returns a 'JSTF' table for the 'arab' script, with two priorities
+// for the default language and one for 'URD '.
+func testJSTF() []byte {
+	var buf []byte
+	for _, b := range [][]byte{
+		u16s(1, 0, 1), []byte("arab"), u16s(12),
+		u16s(12, 16, 1), []byte("URD "), u16s(82), // 12: JstfScript
+		u16s(1, 5), // 24: extender glyphs
+		u16s(2, 6, 40),
+		u16s(20, 0, 0, 0, 24, 0, 0, 0, 0, 0), // 34: shrinkage
+		u16s(1, 1),
+		u16s(1, 4), u16s(1, 0, 0), // 58: JstfMax with one lookup
+		u16s(0, 0, 0, 0, 0, 0, 0, 0, 20, 0), // 68: extension
+		u16s(2, 0, 2),
+		u16s(1, 4), // 94: 'URD '
+		u16s(0, 0, 0, 0, 0, 20, 0, 0, 0, 0),
+		u16s(1, 1),
+	} {
+		buf = append(buf, b...)
+	}
+	return buf
+}
+
+func TestJSTF(t *testing.T) {
+	table, err := ParseTable(TagJSTF, testJSTF())
+	if err != nil {
+		t.Fatalf("ParseTable(%q) err = %q, want nil", TagJSTF, err)
+	}
+	jstf := table.(*TableJSTF)
+
+	arab, urdu := MustNamedTag("arab"), MustNamedTag("URD ")
+	if len(jstf.Scripts) != 1 || jstf.Scripts[0].Tag != arab {
+		t.Fatalf("Scripts = %+v, want 'arab'", jstf.Scripts)
+	}
+	if got, want := jstf.Scripts[0].ExtenderGlyphs, []GlyphID{5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtenderGlyphs = %v, want %v", got, want)
+	}
+
+	lang := jstf.Language(arab, MustNamedTag("FAR "))
+	if lang == nil || len(lang.Priorities) != 2 {
+		t.Fatalf("Language(arab, FAR) = %+v, want the default language", lang)
+	}
+	want := []*JstfPriority{
+		{ShrinkageEnableGSUB: JstfModList{1}, ShrinkageMax: []*Lookup{{Type: 1}}},
+		{ExtensionDisableGPOS: JstfModList{0, 2}},
+	}
+	if !reflect.DeepEqual(lang.Priorities, want) {
+		t.Errorf("Priorities = %+v, want %+v", lang.Priorities, want)
+	}
+	if lang := jstf.Language(arab, urdu); lang == nil || lang.Tag != urdu || !reflect.DeepEqual(lang.Priorities[0].ExtensionEnableGSUB, JstfModList{1}) {
+		t.Errorf("Language(arab, URD) = %+v, want 'URD ' enabling lookup 1", lang)
+	}
+	if lang := jstf.Language(MustNamedTag("latn"), urdu); lang != nil {
+		t.Errorf("Language(latn, URD) = %+v, want nil", lang)
+	}
+
+	gpos := &TableLayout{baseTable: baseTable(TagGpos), Lookups: []*Lookup{{Type: 1}, {Type: 4}, {Type: 2}}}
+	lookups, err := want[1].ExtensionDisableGPOS.Lookups(gpos)
+	if err != nil {
+		t.Fatalf("Lookups() err = %q, want nil", err)
+	}
+	if len(lookups) != 2 || lookups[0] != gpos.Lookups[0] || lookups[1] != gpos.Lookups[2] {
+		t.Errorf("Lookups() = %v, want lookups 0 and 2", lookups)
+	}
+	if _, err := (JstfModList{3}).Lookups(gpos); err == nil {
+		t.Errorf("Lookups() of lookup 3 err = nil, want error")
+	}
+
+	if _, err := ParseTable(TagJSTF, testJSTF()[:90]); err == nil {
+		t.Errorf("ParseTable(%q) of truncated table err = nil, want error", TagJSTF)
+	}
+}
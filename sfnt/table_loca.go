@@ -0,0 +1,98 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// TableLoca contains the offset of each glyph in the 'glyf' table.
+// The table can only be parsed using the 'head' and 'maxp' tables, so it is
+// accessed using Font.LocaTable.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/loca
+type TableLoca struct {
+	baseTable
+
+	// Offsets contains numGlyphs+1 offsets into the 'glyf' table. The data of
+	// glyph i is from Offsets[i] to Offsets[i+1].
+	Offsets []uint32
+	// Short is true if the offsets are stored as uint16 values divided by 2,
+	// which matches head.IndexToLocFormat = 0.
+	Short bool
+}
+
+func parseTableLoca(tag Tag, buf []byte, numGlyphs int, short bool) (*TableLoca, error) {
+	table := &TableLoca{
+		baseTable: baseTable(tag),
+		Offsets:   make([]uint32, numGlyphs+1),
+		Short:     short,
+	}
+
+	if short {
+		if len(buf) < 2*len(table.Offsets) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for i := range table.Offsets {
+			table.Offsets[i] = 2 * uint32(binary.BigEndian.Uint16(buf[2*i:]))
+		}
+	} else {
+		if len(buf) < 4*len(table.Offsets) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		for i := range table.Offsets {
+			table.Offsets[i] = binary.BigEndian.Uint32(buf[4*i:])
+		}
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableLoca) Bytes() []byte {
+	if table.Short {
+		buf := make([]byte, 2*len(table.Offsets))
+		for i, offset := range table.Offsets {
+			binary.BigEndian.PutUint16(buf[2*i:], uint16(offset/2))
+		}
+		return buf
+	}
+
+	buf := make([]byte, 4*len(table.Offsets))
+	for i, offset := range table.Offsets {
+		binary.BigEndian.PutUint32(buf[4*i:], offset)
+	}
+	return buf
+}
+
+// LocaTable returns the table corresponding to the 'loca' tag.
+// It requires the 'head' and 'maxp' tables to parse.
+func (font *Font) LocaTable() (*TableLoca, error) {
+	s, found := font.tables[TagLoca]
+	if !found {
+		return nil, ErrMissingTable
+	}
+	if t, ok := s.table.(*TableLoca); ok {
+		return t, nil
+	}
+
+	head, err := font.HeadTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagHead, err)
+	}
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagMaxp, err)
+	}
+
+	buf, err := font.tableBytes(s)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := parseTableLoca(TagLoca, buf, int(maxp.NumGlyphs), head.IndexToLocFormat == 0)
+	if err != nil {
+		return nil, err
+	}
+	s.table = t
+	return t, nil
+}
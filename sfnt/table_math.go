@@ -0,0 +1,547 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// TableMATH contains the constants and glyph information used to lay out
+// mathematical formulas. Only parsing is supported, Bytes returns the
+// original table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/math
+type TableMATH struct {
+	baseTable
+	bytes []byte
+
+	Constants MathConstants
+	GlyphInfo MathGlyphInfo
+	Variants  MathVariants
+}
+
+// MathValue is a value in font units, with a Device that adjusts it,
+// which may be nil.
+type MathValue struct {
+	Value  int16
+	Device *Device
+}
+
+// MathConstants contains the values used to position the parts of formulas,
+// as described in the OpenType specification.
+type MathConstants struct {
+	ScriptPercentScaleDown       int16
+	ScriptScriptPercentScaleDown int16
+	DelimitedSubFormulaMinHeight uint16
+	DisplayOperatorMinHeight     uint16
+
+	MathLeading                              MathValue
+	AxisHeight                               MathValue
+	AccentBaseHeight                         MathValue
+	FlattenedAccentBaseHeight                MathValue
+	SubscriptShiftDown                       MathValue
+	SubscriptTopMax                          MathValue
+	SubscriptBaselineDropMin                 MathValue
+	SuperscriptShiftUp                       MathValue
+	SuperscriptShiftUpCramped                MathValue
+	SuperscriptBottomMin                     MathValue
+	SuperscriptBaselineDropMax               MathValue
+	SubSuperscriptGapMin                     MathValue
+	SuperscriptBottomMaxWithSubscript        MathValue
+	SpaceAfterScript                         MathValue
+	UpperLimitGapMin                         MathValue
+	UpperLimitBaselineRiseMin                MathValue
+	LowerLimitGapMin                         MathValue
+	LowerLimitBaselineDropMin                MathValue
+	StackTopShiftUp                          MathValue
+	StackTopDisplayStyleShiftUp              MathValue
+	StackBottomShiftDown                     MathValue
+	StackBottomDisplayStyleShiftDown         MathValue
+	StackGapMin                              MathValue
+	StackDisplayStyleGapMin                  MathValue
+	StretchStackTopShiftUp                   MathValue
+	StretchStackBottomShiftDown              MathValue
+	StretchStackGapAboveMin                  MathValue
+	StretchStackGapBelowMin                  MathValue
+	FractionNumeratorShiftUp                 MathValue
+	FractionNumeratorDisplayStyleShiftUp     MathValue
+	FractionDenominatorShiftDown             MathValue
+	FractionDenominatorDisplayStyleShiftDown MathValue
+	FractionNumeratorGapMin                  MathValue
+	FractionNumDisplayStyleGapMin            MathValue
+	FractionRuleThickness                    MathValue
+	FractionDenominatorGapMin                MathValue
+	FractionDenomDisplayStyleGapMin          MathValue
+	SkewedFractionHorizontalGap              MathValue
+	SkewedFractionVerticalGap                MathValue
+	OverbarVerticalGap                       MathValue
+	OverbarRuleThickness                     MathValue
+	OverbarExtraAscender                     MathValue
+	UnderbarVerticalGap                      MathValue
+	UnderbarRuleThickness                    MathValue
+	UnderbarExtraDescender                   MathValue
+	RadicalVerticalGap                       MathValue
+	RadicalDisplayStyleVerticalGap           MathValue
+	RadicalRuleThickness                     MathValue
+	RadicalExtraAscender                     MathValue
+	RadicalKernBeforeDegree                  MathValue
+	RadicalKernAfterDegree                   MathValue
+
+	RadicalDegreeBottomRaisePercent int16
+}
+
+// values returns the MathValue fields of the constants in the order they are stored.
+func (c *MathConstants) values() []*MathValue {
+	return []*MathValue{
+		&c.MathLeading, &c.AxisHeight, &c.AccentBaseHeight, &c.FlattenedAccentBaseHeight,
+		&c.SubscriptShiftDown, &c.SubscriptTopMax, &c.SubscriptBaselineDropMin,
+		&c.SuperscriptShiftUp, &c.SuperscriptShiftUpCramped, &c.SuperscriptBottomMin,
+		&c.SuperscriptBaselineDropMax, &c.SubSuperscriptGapMin, &c.SuperscriptBottomMaxWithSubscript,
+		&c.SpaceAfterScript, &c.UpperLimitGapMin, &c.UpperLimitBaselineRiseMin,
+		&c.LowerLimitGapMin, &c.LowerLimitBaselineDropMin, &c.StackTopShiftUp,
+		&c.StackTopDisplayStyleShiftUp, &c.StackBottomShiftDown, &c.StackBottomDisplayStyleShiftDown,
+		&c.StackGapMin, &c.StackDisplayStyleGapMin, &c.StretchStackTopShiftUp,
+		&c.StretchStackBottomShiftDown, &c.StretchStackGapAboveMin, &c.StretchStackGapBelowMin,
+		&c.FractionNumeratorShiftUp, &c.FractionNumeratorDisplayStyleShiftUp,
+		&c.FractionDenominatorShiftDown, &c.FractionDenominatorDisplayStyleShiftDown,
+		&c.FractionNumeratorGapMin, &c.FractionNumDisplayStyleGapMin, &c.FractionRuleThickness,
+		&c.FractionDenominatorGapMin, &c.FractionDenomDisplayStyleGapMin,
+		&c.SkewedFractionHorizontalGap, &c.SkewedFractionVerticalGap,
+		&c.OverbarVerticalGap, &c.OverbarRuleThickness, &c.OverbarExtraAscender,
+		&c.UnderbarVerticalGap, &c.UnderbarRuleThickness, &c.UnderbarExtraDescender,
+		&c.RadicalVerticalGap, &c.RadicalDisplayStyleVerticalGap, &c.RadicalRuleThickness,
+		&c.RadicalExtraAscender, &c.RadicalKernBeforeDegree, &c.RadicalKernAfterDegree,
+	}
+}
+
+// MathGlyphInfo contains positioning information for individual glyphs.
+type MathGlyphInfo struct {
+	ItalicsCorrection   map[GlyphID]MathValue
+	TopAccentAttachment map[GlyphID]MathValue
+	// ExtendedShapes contains the glyphs that are extended shapes, such as
+	// tall delimiters, to which superscripts and subscripts attach differently.
+	ExtendedShapes map[GlyphID]bool
+	Kerns          map[GlyphID]MathKernInfo
+}
+
+// MathKernInfo contains the kerning of a glyph with superscripts and
+// subscripts at each corner. Corners without kerning are nil.
+type MathKernInfo struct {
+	TopRight, TopLeft, BottomRight, BottomLeft *MathKern
+}
+
+// MathKern contains the kerning at a corner of a glyph, which varies with the height.
+type MathKern struct {
+	// CorrectionHeights contains the heights, in increasing order, at which
+	// the kerning changes.
+	CorrectionHeights []MathValue
+	// KernValues contains the kerning below the first height, between each
+	// pair of heights and above the last, so has one more entry than
+	// CorrectionHeights.
+	KernValues []MathValue
+}
+
+// Kern returns the kerning at a height.
+func (k *MathKern) Kern(height int) int16 {
+	for i, h := range k.CorrectionHeights {
+		if height < int(h.Value) {
+			return k.KernValues[i].Value
+		}
+	}
+	return k.KernValues[len(k.KernValues)-1].Value
+}
+
+// MathVariants contains the larger variants of glyphs, and how to assemble
+// stretchy glyphs of any size from parts.
+type MathVariants struct {
+	// MinConnectorOverlap is the minimum overlap of the connectors of parts in an assembly.
+	MinConnectorOverlap uint16
+	Vertical            map[GlyphID]MathGlyphConstruction
+	Horizontal          map[GlyphID]MathGlyphConstruction
+}
+
+// MathGlyphConstruction contains the ways to make a larger version of a glyph.
+type MathGlyphConstruction struct {
+	// Assembly contains the parts that make up the glyph at any size, it may be nil.
+	Assembly *GlyphAssembly
+	// Variants contains glyphs of increasing size, usually starting with the glyph itself.
+	Variants []MathGlyphVariant
+}
+
+// MathGlyphVariant is a larger version of a glyph.
+type MathGlyphVariant struct {
+	Glyph GlyphID
+	// AdvanceMeasurement is the size of the glyph in the direction of the
+	// stretch, in font units.
+	AdvanceMeasurement uint16
+}
+
+// GlyphAssembly describes a glyph built from parts, some of which can be
+// repeated to stretch the glyph.
+type GlyphAssembly struct {
+	ItalicsCorrection MathValue
+	// Parts are ordered from bottom to top for vertical assemblies, and from
+	// left to right for horizontal ones.
+	Parts []GlyphPart
+}
+
+// GlyphPart is a part of a GlyphAssembly.
+type GlyphPart struct {
+	Glyph GlyphID
+	// StartConnectorLength and EndConnectorLength are the lengths of the
+	// straight ends of the part, which can overlap with its neighbours.
+	StartConnectorLength uint16
+	EndConnectorLength   uint16
+	FullAdvance          uint16
+	Flags                uint16 // Flags is GlyphPartExtender for parts that can be repeated.
+}
+
+// GlyphPartExtender is the flag set on parts of a GlyphAssembly that can be
+// repeated, or skipped, to stretch the glyph.
+const GlyphPartExtender = 0x0001
+
+// Extender returns true if the part can be repeated.
+func (p GlyphPart) Extender() bool {
+	return p.Flags&GlyphPartExtender != 0
+}
+
+const mathConstantsSize = 4*2 + 51*4 + 2
+
+func parseTableMATH(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 10 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if major := binary.BigEndian.Uint16(buf); major != 1 {
+		return nil, fmt.Errorf("unsupported %q version %d", tag, major)
+	}
+
+	table := &TableMATH{
+		baseTable: baseTable(tag),
+		bytes:     buf,
+	}
+	p := mathParser{buf: buf}
+	if offset := int(binary.BigEndian.Uint16(buf[4:])); offset != 0 {
+		if err := p.parseConstants(offset, &table.Constants); err != nil {
+			return nil, fmt.Errorf("MathConstants: %w", err)
+		}
+	}
+	if offset := int(binary.BigEndian.Uint16(buf[6:])); offset != 0 {
+		if err := p.parseGlyphInfo(offset, &table.GlyphInfo); err != nil {
+			return nil, fmt.Errorf("MathGlyphInfo: %w", err)
+		}
+	}
+	if offset := int(binary.BigEndian.Uint16(buf[8:])); offset != 0 {
+		if err := p.parseVariants(offset, &table.Variants); err != nil {
+			return nil, fmt.Errorf("MathVariants: %w", err)
+		}
+	}
+
+	return table, nil
+}
+
+// mathParser reads the subtables of 'MATH', at offsets from the start of the table.
+type mathParser struct {
+	buf []byte
+}
+
+// bytes returns the table from offset, which must have at least size bytes.
+func (p *mathParser) bytes(offset, size int) ([]byte, error) {
+	if offset+size > len(p.buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return p.buf[offset:], nil
+}
+
+// offset returns the offset of a subtable from the 16-bit offset at record,
+// relative to base, or 0 if it is null.
+func (p *mathParser) offset(base, record int) int {
+	if o := binary.BigEndian.Uint16(p.buf[record:]); o != 0 {
+		return base + int(o)
+	}
+	return 0
+}
+
+// value parses a MathValueRecord at offset, whose device is relative to base.
+func (p *mathParser) value(base, offset int) (MathValue, error) {
+	b, err := p.bytes(offset, 4)
+	if err != nil {
+		return MathValue{}, err
+	}
+	v := MathValue{Value: int16(binary.BigEndian.Uint16(b))}
+	if device := p.offset(base, offset+2); device != 0 {
+		d, err := p.bytes(device, 0)
+		if err != nil {
+			return MathValue{}, err
+		}
+		if v.Device, err = parseDevice(d); err != nil {
+			return MathValue{}, err
+		}
+	}
+	return v, nil
+}
+
+func (p *mathParser) coverage(offset int) ([]GlyphID, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	b, err := p.bytes(offset, 0)
+	if err != nil {
+		return nil, err
+	}
+	return parseCoverage(b)
+}
+
+func (p *mathParser) parseConstants(offset int, c *MathConstants) error {
+	b, err := p.bytes(offset, mathConstantsSize)
+	if err != nil {
+		return err
+	}
+	c.ScriptPercentScaleDown = int16(binary.BigEndian.Uint16(b))
+	c.ScriptScriptPercentScaleDown = int16(binary.BigEndian.Uint16(b[2:]))
+	c.DelimitedSubFormulaMinHeight = binary.BigEndian.Uint16(b[4:])
+	c.DisplayOperatorMinHeight = binary.BigEndian.Uint16(b[6:])
+	for i, v := range c.values() {
+		if *v, err = p.value(offset, offset+8+4*i); err != nil {
+			return err
+		}
+	}
+	c.RadicalDegreeBottomRaisePercent = int16(binary.BigEndian.Uint16(b[mathConstantsSize-2:]))
+	return nil
+}
+
+// valuesByGlyph parses a table of a coverage offset, a count and
+// MathValueRecords, such as MathItalicsCorrectionInfo.
+func (p *mathParser) valuesByGlyph(offset int) (map[GlyphID]MathValue, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	b, err := p.bytes(offset, 4)
+	if err != nil {
+		return nil, err
+	}
+	glyphs, err := p.coverage(p.offset(offset, offset))
+	if err != nil {
+		return nil, err
+	}
+	count := int(binary.BigEndian.Uint16(b[2:]))
+	if count < len(glyphs) {
+		glyphs = glyphs[:count]
+	}
+
+	values := make(map[GlyphID]MathValue, len(glyphs))
+	for i, glyph := range glyphs {
+		if values[glyph], err = p.value(offset, offset+4+4*i); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+func (p *mathParser) parseGlyphInfo(offset int, info *MathGlyphInfo) error {
+	if _, err := p.bytes(offset, 8); err != nil {
+		return err
+	}
+
+	var err error
+	if info.ItalicsCorrection, err = p.valuesByGlyph(p.offset(offset, offset)); err != nil {
+		return err
+	}
+	if info.TopAccentAttachment, err = p.valuesByGlyph(p.offset(offset, offset+2)); err != nil {
+		return err
+	}
+
+	extended, err := p.coverage(p.offset(offset, offset+4))
+	if err != nil {
+		return err
+	}
+	if extended != nil {
+		info.ExtendedShapes = make(map[GlyphID]bool, len(extended))
+		for _, glyph := range extended {
+			info.ExtendedShapes[glyph] = true
+		}
+	}
+
+	kernInfo := p.offset(offset, offset+6)
+	if kernInfo == 0 {
+		return nil
+	}
+	b, err := p.bytes(kernInfo, 4)
+	if err != nil {
+		return err
+	}
+	glyphs, err := p.coverage(p.offset(kernInfo, kernInfo))
+	if err != nil {
+		return err
+	}
+	if count := int(binary.BigEndian.Uint16(b[2:])); count < len(glyphs) {
+		glyphs = glyphs[:count]
+	}
+	if _, err := p.bytes(kernInfo, 4+8*len(glyphs)); err != nil {
+		return err
+	}
+
+	info.Kerns = make(map[GlyphID]MathKernInfo, len(glyphs))
+	for i, glyph := range glyphs {
+		var k MathKernInfo
+		for j, corner := range []**MathKern{&k.TopRight, &k.TopLeft, &k.BottomRight, &k.BottomLeft} {
+			if *corner, err = p.parseKern(p.offset(kernInfo, kernInfo+4+8*i+2*j)); err != nil {
+				return err
+			}
+		}
+		info.Kerns[glyph] = k
+	}
+	return nil
+}
+
+func (p *mathParser) parseKern(offset int) (*MathKern, error) {
+	if offset == 0 {
+		return nil, nil
+	}
+	b, err := p.bytes(offset, 2)
+	if err != nil {
+		return nil, err
+	}
+	count := int(binary.BigEndian.Uint16(b))
+
+	kern := &MathKern{
+		CorrectionHeights: make([]MathValue, count),
+		KernValues:        make([]MathValue, count+1),
+	}
+	records := offset + 2
+	for i := range kern.CorrectionHeights {
+		if kern.CorrectionHeights[i], err = p.value(offset, records+4*i); err != nil {
+			return nil, err
+		}
+	}
+	records += 4 * count
+	for i := range kern.KernValues {
+		if kern.KernValues[i], err = p.value(offset, records+4*i); err != nil {
+			return nil, err
+		}
+	}
+	return kern, nil
+}
+
+func (p *mathParser) parseVariants(offset int, variants *MathVariants) error {
+	b, err := p.bytes(offset, 10)
+	if err != nil {
+		return err
+	}
+	variants.MinConnectorOverlap = binary.BigEndian.Uint16(b)
+	vertCount := int(binary.BigEndian.Uint16(b[6:]))
+	horizCount := int(binary.BigEndian.Uint16(b[8:]))
+	if _, err := p.bytes(offset, 10+2*(vertCount+horizCount)); err != nil {
+		return err
+	}
+
+	constructions := offset + 10
+	for i, dir := range []struct {
+		constructions *map[GlyphID]MathGlyphConstruction
+		count         int
+	}{{&variants.Vertical, vertCount}, {&variants.Horizontal, horizCount}} {
+		glyphs, err := p.coverage(p.offset(offset, offset+2+2*i))
+		if err != nil {
+			return err
+		}
+		if dir.count < len(glyphs) {
+			glyphs = glyphs[:dir.count]
+		}
+		if glyphs != nil {
+			*dir.constructions = make(map[GlyphID]MathGlyphConstruction, len(glyphs))
+		}
+		for j, glyph := range glyphs {
+			c, err := p.parseConstruction(p.offset(offset, constructions+2*j))
+			if err != nil {
+				return fmt.Errorf("glyph %d: %w", glyph, err)
+			}
+			(*dir.constructions)[glyph] = c
+		}
+		constructions += 2 * dir.count
+	}
+	return nil
+}
+
+func (p *mathParser) parseConstruction(offset int) (MathGlyphConstruction, error) {
+	var c MathGlyphConstruction
+	if offset == 0 {
+		return c, nil
+	}
+	b, err := p.bytes(offset, 4)
+	if err != nil {
+		return c, err
+	}
+	count := int(binary.BigEndian.Uint16(b[2:]))
+	if 4+4*count > len(b) {
+		return c, io.ErrUnexpectedEOF
+	}
+	c.Variants = make([]MathGlyphVariant, count)
+	for i := range c.Variants {
+		c.Variants[i] = MathGlyphVariant{
+			Glyph:              GlyphID(binary.BigEndian.Uint16(b[4+4*i:])),
+			AdvanceMeasurement: binary.BigEndian.Uint16(b[6+4*i:]),
+		}
+	}
+
+	assembly := p.offset(offset, offset)
+	if assembly == 0 {
+		return c, nil
+	}
+	a, err := p.bytes(assembly, 6)
+	if err != nil {
+		return c, err
+	}
+	c.Assembly = &GlyphAssembly{}
+	if c.Assembly.ItalicsCorrection, err = p.value(assembly, assembly); err != nil {
+		return c, err
+	}
+	count = int(binary.BigEndian.Uint16(a[4:]))
+	if 6+10*count > len(a) {
+		return c, io.ErrUnexpectedEOF
+	}
+	c.Assembly.Parts = make([]GlyphPart, count)
+	for i := range c.Assembly.Parts {
+		r := a[6+10*i:]
+		c.Assembly.Parts[i] = GlyphPart{
+			Glyph:                GlyphID(binary.BigEndian.Uint16(r)),
+			StartConnectorLength: binary.BigEndian.Uint16(r[2:]),
+			EndConnectorLength:   binary.BigEndian.Uint16(r[4:]),
+			FullAdvance:          binary.BigEndian.Uint16(r[6:]),
+			Flags:                binary.BigEndian.Uint16(r[8:]),
+		}
+	}
+	return c, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableMATH) Bytes() []byte {
+	return table.bytes
+}
+
+// VerticalVariants returns the taller versions of a glyph, in increasing
+// size, or nil if there are none.
+func (table *TableMATH) VerticalVariants(glyph GlyphID) []MathGlyphVariant {
+	return table.Variants.Vertical[glyph].Variants
+}
+
+// HorizontalVariants returns the wider versions of a glyph, in increasing
+// size, or nil if there are none.
+func (table *TableMATH) HorizontalVariants(glyph GlyphID) []MathGlyphVariant {
+	return table.Variants.Horizontal[glyph].Variants
+}
+
+// Assembly returns the parts used to stretch a glyph in a direction, or nil
+// if it cannot be stretched that way.
+func (table *TableMATH) Assembly(glyph GlyphID, direction Direction) *GlyphAssembly {
+	if direction == DirectionVertical {
+		return table.Variants.Vertical[glyph].Assembly
+	}
+	return table.Variants.Horizontal[glyph].Assembly
+}
+
+// MATHTable returns the table corresponding to the 'MATH' tag.
+func (font *Font) MATHTable() (*TableMATH, error) {
+	t, err := font.Table(TagMATH)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableMATH), nil
+}
@@ -0,0 +1,128 @@
+package sfnt
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testMATH returns a 'MATH' table with every subtable, whose offsets are
+// noted in the comments.
+func testMATH() []byte {
+	var buf []byte
+	add := func(b []byte) { buf = append(buf, b...) }
+
+	add(u16s(1, 0, 10, 232, 316))
+
+	// 10: MathConstants, with AxisHeight adjusted by the device at 224.
+	add(u16s(80, 60, 1500, 1300))
+	for i := 0; i < 51; i++ {
+		if i == 1 {
+			add(u16s(250, 214))
+		} else {
+			add(u16s(0, 0))
+		}
+	}
+	add(u16s(60))
+	add(u16s(10, 11, 3, 0x01FF))
+
+	// 232: MathGlyphInfo
+	add(u16s(8, 0, 28, 38))
+	add(u16s(12, 2, 30, 0, 40, 0)) // 240: italics corrections of glyphs 3 and 7
+	add(u16s(1, 2, 3, 7))
+	add(u16s(2, 1, 9, 10, 0))      // 260: glyphs 9 and 10 are extended shapes
+	add(u16s(12, 1, 18, 0, 0, 40)) // 270: kerning of glyph 3
+	add(u16s(1, 1, 3))             // 282
+	add(u16s(2, 100, 0, 200, 0))   // 288: top right
+	add(u16s(10, 0, 20, 0, 30, 0)) //
+	add(u16s(0, 0x10000-5, 0))     // 310: bottom left
+
+	// 316: MathVariants, glyph 5 stretches vertically and 6 horizontally.
+	add(u16s(20, 14, 20, 1, 1, 26, 74))
+	add(u16s(1, 1, 5))
+	add(u16s(1, 1, 6))
+	add(u16s(12, 2, 5, 1000, 8, 1500)) // 342
+	add(u16s(15, 0, 3))                // 354: assembly
+	add(u16s(20, 0, 100, 500, 0))
+	add(u16s(21, 100, 100, 300, 1))
+	add(u16s(22, 100, 0, 500, 0))
+	add(u16s(0, 1, 6, 600)) // 390
+
+	return buf
+}
+
+func TestMATH(t *testing.T) {
+	table, err := ParseTable(TagMATH, testMATH())
+	if err != nil {
+		t.Fatalf("ParseTable(%q) err = %q, want nil", TagMATH, err)
+	}
+	math := table.(*TableMATH)
+
+	c := math.Constants
+	if c.ScriptPercentScaleDown != 80 || c.DisplayOperatorMinHeight != 1300 || c.RadicalDegreeBottomRaisePercent != 60 {
+		t.Errorf("Constants = %+v, want 80, 1300 and 60", c)
+	}
+	if c.AxisHeight.Value != 250 || c.AxisHeight.Device == nil || c.AxisHeight.Device.PixelDelta(11) != -1 {
+		t.Errorf("AxisHeight = %+v, want 250 adjusted by -1 at 11 ppem", c.AxisHeight)
+	}
+	if c.MathLeading != (MathValue{}) || c.RadicalKernAfterDegree != (MathValue{}) {
+		t.Errorf("MathLeading, RadicalKernAfterDegree = %+v, %+v, want 0", c.MathLeading, c.RadicalKernAfterDegree)
+	}
+
+	info := math.GlyphInfo
+	if got, want := info.ItalicsCorrection, map[GlyphID]MathValue{3: {Value: 30}, 7: {Value: 40}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ItalicsCorrection = %v, want %v", got, want)
+	}
+	if info.TopAccentAttachment != nil {
+		t.Errorf("TopAccentAttachment = %v, want nil", info.TopAccentAttachment)
+	}
+	if got, want := info.ExtendedShapes, map[GlyphID]bool{9: true, 10: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtendedShapes = %v, want %v", got, want)
+	}
+	kern := info.Kerns[3]
+	if kern.TopLeft != nil || kern.BottomRight != nil || kern.TopRight == nil || kern.BottomLeft == nil {
+		t.Fatalf("Kerns[3] = %+v, want top right and bottom left", kern)
+	}
+	for height, want := range map[int]int16{50: 10, 100: 20, 150: 20, 250: 30} {
+		if got := kern.TopRight.Kern(height); got != want {
+			t.Errorf("TopRight.Kern(%d) = %d, want %d", height, got, want)
+		}
+	}
+	if got := kern.BottomLeft.Kern(0); got != -5 {
+		t.Errorf("BottomLeft.Kern(0) = %d, want -5", got)
+	}
+
+	if math.Variants.MinConnectorOverlap != 20 {
+		t.Errorf("MinConnectorOverlap = %d, want 20", math.Variants.MinConnectorOverlap)
+	}
+	if got, want := math.VerticalVariants(5), []MathGlyphVariant{{5, 1000}, {8, 1500}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("VerticalVariants(5) = %v, want %v", got, want)
+	}
+	if got, want := math.HorizontalVariants(6), []MathGlyphVariant{{6, 600}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("HorizontalVariants(6) = %v, want %v", got, want)
+	}
+	if got := math.VerticalVariants(6); got != nil {
+		t.Errorf("VerticalVariants(6) = %v, want nil", got)
+	}
+
+	assembly := math.Assembly(5, DirectionVertical)
+	if assembly == nil || assembly.ItalicsCorrection.Value != 15 || len(assembly.Parts) != 3 {
+		t.Fatalf("Assembly(5) = %+v, want 3 parts", assembly)
+	}
+	for i, part := range assembly.Parts {
+		if want := i == 1; part.Extender() != want {
+			t.Errorf("Parts[%d].Extender() = %t, want %t", i, part.Extender(), want)
+		}
+	}
+	if part := assembly.Parts[1]; part.Glyph != 21 || part.StartConnectorLength != 100 || part.FullAdvance != 300 {
+		t.Errorf("Parts[1] = %+v, want glyph 21", part)
+	}
+	for _, glyph := range []GlyphID{5, 6} {
+		if got := math.Assembly(glyph, DirectionHorizontal); got != nil {
+			t.Errorf("Assembly(%d, horizontal) = %+v, want nil", glyph, got)
+		}
+	}
+
+	if _, err := ParseTable(TagMATH, testMATH()[:380]); err == nil {
+		t.Errorf("ParseTable(%q) of truncated table err = nil, want error", TagMATH)
+	}
+}
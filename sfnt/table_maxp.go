@@ -0,0 +1,73 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// TableMaxp contains the memory requirements of the font, most importantly
+// the number of glyphs.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/maxp
+type TableMaxp struct {
+	baseTable
+	tableMaxpFields
+	tableMaxpV1Fields // Only present in version 1.0, used by fonts with TrueType outlines.
+}
+
+type tableMaxpFields struct {
+	Version   fixed
+	NumGlyphs uint16
+}
+
+type tableMaxpV1Fields struct {
+	MaxPoints             uint16
+	MaxContours           uint16
+	MaxCompositePoints    uint16
+	MaxCompositeContours  uint16
+	MaxZones              uint16
+	MaxTwilightPoints     uint16
+	MaxStorage            uint16
+	MaxFunctionDefs       uint16
+	MaxInstructionDefs    uint16
+	MaxStackElements      uint16
+	MaxSizeOfInstructions uint16
+	MaxComponentElements  uint16
+	MaxComponentDepth     uint16
+}
+
+func parseTableMaxp(tag Tag, buf []byte, _ *Options) (Table, error) {
+	r := bytes.NewBuffer(buf)
+
+	table := &TableMaxp{baseTable: baseTable(tag)}
+	if err := binary.Read(r, binary.BigEndian, &table.tableMaxpFields); err != nil {
+		return nil, err
+	}
+
+	if table.HasV1Fields() {
+		if err := binary.Read(r, binary.BigEndian, &table.tableMaxpV1Fields); err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+// HasV1Fields returns true if the table is version 1.0, which contains
+// the fields used by TrueType outlines.
+func (table *TableMaxp) HasV1Fields() bool {
+	return table.Version.Major == 1
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableMaxp) Bytes() []byte {
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.BigEndian, table.tableMaxpFields); err != nil {
+		panic(err) // should never happen
+	}
+	if table.HasV1Fields() {
+		if err := binary.Write(&buffer, binary.BigEndian, table.tableMaxpV1Fields); err != nil {
+			panic(err) // should never happen
+		}
+	}
+	return buffer.Bytes()
+}
@@ -0,0 +1,252 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Tags of the font-wide metrics varied by the 'MVAR' table.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/mvar#value-tags
+var (
+	MetricHorizontalAscender    = MustNamedTag("hasc") // OS/2.sTypoAscender
+	MetricHorizontalDescender   = MustNamedTag("hdsc") // OS/2.sTypoDescender
+	MetricHorizontalLineGap     = MustNamedTag("hlgp") // OS/2.sTypoLineGap
+	MetricHorizontalClipAscent  = MustNamedTag("hcla") // OS/2.usWinAscent
+	MetricHorizontalClipDescent = MustNamedTag("hcld") // OS/2.usWinDescent
+	MetricVerticalAscender      = MustNamedTag("vasc") // vhea.ascent
+	MetricVerticalDescender     = MustNamedTag("vdsc") // vhea.descent
+	MetricVerticalLineGap       = MustNamedTag("vlgp") // vhea.lineGap
+	MetricHorizontalCaretRise   = MustNamedTag("hcrs") // hhea.caretSlopeRise
+	MetricHorizontalCaretRun    = MustNamedTag("hcrn") // hhea.caretSlopeRun
+	MetricHorizontalCaretOffset = MustNamedTag("hcof") // hhea.caretOffset
+	MetricVerticalCaretRise     = MustNamedTag("vcrs") // vhea.caretSlopeRise
+	MetricVerticalCaretRun      = MustNamedTag("vcrn") // vhea.caretSlopeRun
+	MetricVerticalCaretOffset   = MustNamedTag("vcof") // vhea.caretOffset
+	MetricXHeight               = MustNamedTag("xhgt") // OS/2.sxHeight
+	MetricCapHeight             = MustNamedTag("cpht") // OS/2.sCapHeight
+	MetricSubscriptXSize        = MustNamedTag("sbxs") // OS/2.ySubscriptXSize
+	MetricSubscriptYSize        = MustNamedTag("sbys") // OS/2.ySubscriptYSize
+	MetricSubscriptXOffset      = MustNamedTag("sbxo") // OS/2.ySubscriptXOffset
+	MetricSubscriptYOffset      = MustNamedTag("sbyo") // OS/2.ySubscriptYOffset
+	MetricSuperscriptXSize      = MustNamedTag("spxs") // OS/2.ySuperscriptXSize
+	MetricSuperscriptYSize      = MustNamedTag("spys") // OS/2.ySuperscriptYSize
+	MetricSuperscriptXOffset    = MustNamedTag("spxo") // OS/2.ySuperscriptXOffset
+	MetricSuperscriptYOffset    = MustNamedTag("spyo") // OS/2.ySuperscriptYOffset
+	MetricStrikeoutSize         = MustNamedTag("strs") // OS/2.yStrikeoutSize
+	MetricStrikeoutOffset       = MustNamedTag("stro") // OS/2.yStrikeoutPosition
+	MetricUnderlineSize         = MustNamedTag("unds") // post.underlineThickness
+	MetricUnderlineOffset       = MustNamedTag("undo") // post.underlinePosition
+)
+
+// TableMvar contains the variations of font-wide metrics in a variable font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/mvar
+type TableMvar struct {
+	baseTable
+
+	VarStore *ItemVariationStore
+	// Values contains the metrics varied by the table, sorted by tag.
+	Values []MvarValue
+}
+
+// MvarValue associates a metric, such as MetricXHeight, with its deltas.
+type MvarValue struct {
+	Tag   Tag
+	Index VariationIndex
+}
+
+func parseTableMvar(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 12 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if major := binary.BigEndian.Uint16(buf); major != 1 {
+		return nil, fmt.Errorf("unsupported 'MVAR' version %d", major)
+	}
+
+	recordSize := int(binary.BigEndian.Uint16(buf[6:]))
+	recordCount := int(binary.BigEndian.Uint16(buf[8:]))
+	storeOffset := int(binary.BigEndian.Uint16(buf[10:]))
+	if recordCount > 0 && recordSize < 8 {
+		return nil, fmt.Errorf("invalid 'MVAR' value record size %d", recordSize)
+	}
+	if len(buf) < 12+recordSize*recordCount {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableMvar{
+		baseTable: baseTable(tag),
+		Values:    make([]MvarValue, recordCount),
+	}
+	for i := range table.Values {
+		b := buf[12+recordSize*i:]
+		table.Values[i] = MvarValue{
+			Tag: NewTag(b),
+			Index: VariationIndex{
+				Outer: binary.BigEndian.Uint16(b[4:]),
+				Inner: binary.BigEndian.Uint16(b[6:]),
+			},
+		}
+	}
+	sort.Slice(table.Values, func(i, j int) bool {
+		return table.Values[i].Tag.Number < table.Values[j].Tag.Number
+	})
+
+	if storeOffset != 0 {
+		if storeOffset >= len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		store, err := ParseItemVariationStore(buf[storeOffset:])
+		if err != nil {
+			return nil, err
+		}
+		table.VarStore = store
+	} else if recordCount > 0 {
+		return nil, fmt.Errorf("'MVAR' has no item variation store")
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableMvar) Bytes() []byte {
+	buf := make([]byte, 12, 12+8*len(table.Values))
+	binary.BigEndian.PutUint16(buf, 1)
+	binary.BigEndian.PutUint16(buf[6:], 8)
+	binary.BigEndian.PutUint16(buf[8:], uint16(len(table.Values)))
+	for _, value := range table.Values {
+		buf = append(buf, value.Tag.bytes()...)
+		buf = appendUint16(buf, value.Index.Outer)
+		buf = appendUint16(buf, value.Index.Inner)
+	}
+	if table.VarStore != nil {
+		binary.BigEndian.PutUint16(buf[10:], uint16(len(buf)))
+		buf = append(buf, table.VarStore.Bytes()...)
+	}
+	return buf
+}
+
+// Delta returns the change to the metric with the given tag at the normalized
+// coords, or 0 if the metric does not vary.
+func (table *TableMvar) Delta(tag Tag, coords []F2Dot14) float64 {
+	i := sort.Search(len(table.Values), func(i int) bool {
+		return table.Values[i].Tag.Number >= tag.Number
+	})
+	if i == len(table.Values) || table.Values[i].Tag != tag {
+		return 0
+	}
+	return table.VarStore.Delta(table.Values[i].Index, coords)
+}
+
+// MvarTable returns the table corresponding to the 'MVAR' tag.
+func (font *Font) MvarTable() (*TableMvar, error) {
+	t, err := font.Table(TagMvar)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableMvar), nil
+}
+
+// Metric returns the value of a font-wide metric, such as MetricXHeight, from
+// the 'OS/2', 'hhea', 'vhea' or 'post' table. If coords is not nil, it contains the
+// normalized coordinates at which the variations from 'MVAR' are applied.
+func (font *Font) Metric(tag Tag, coords []F2Dot14) (float64, error) {
+	value, err := font.defaultMetric(tag)
+	if err != nil {
+		return 0, err
+	}
+	if coords == nil || !font.HasTable(TagMvar) {
+		return value, nil
+	}
+
+	mvar, err := font.MvarTable()
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q: %w", TagMvar, err)
+	}
+	return value + mvar.Delta(tag, coords), nil
+}
+
+// defaultMetric returns the value of a metric in the default instance of the font.
+func (font *Font) defaultMetric(tag Tag) (float64, error) {
+	switch tag {
+	case MetricHorizontalCaretRise, MetricHorizontalCaretRun, MetricHorizontalCaretOffset:
+		hhea, err := font.HheaTable()
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: %w", TagHhea, err)
+		}
+		switch tag {
+		case MetricHorizontalCaretRise:
+			return float64(hhea.CaretSlopeRise), nil
+		case MetricHorizontalCaretRun:
+			return float64(hhea.CaretSlopeRun), nil
+		default:
+			return float64(hhea.CaretOffset), nil
+		}
+
+	case MetricVerticalAscender, MetricVerticalDescender, MetricVerticalLineGap,
+		MetricVerticalCaretRise, MetricVerticalCaretRun, MetricVerticalCaretOffset:
+		vhea, err := font.VheaTable()
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: %w", TagVhea, err)
+		}
+		switch tag {
+		case MetricVerticalAscender:
+			return float64(vhea.Ascent), nil
+		case MetricVerticalDescender:
+			return float64(vhea.Descent), nil
+		case MetricVerticalLineGap:
+			return float64(vhea.LineGap), nil
+		case MetricVerticalCaretRise:
+			return float64(vhea.CaretSlopeRise), nil
+		case MetricVerticalCaretRun:
+			return float64(vhea.CaretSlopeRun), nil
+		default:
+			return float64(vhea.CaretOffset), nil
+		}
+
+	case MetricUnderlineSize, MetricUnderlineOffset:
+		post, err := font.Table(TagPost)
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: %w", TagPost, err)
+		}
+		buf := post.Bytes()
+		if len(buf) < 12 {
+			return 0, fmt.Errorf("parsing %q: %w", TagPost, io.ErrUnexpectedEOF)
+		}
+		if tag == MetricUnderlineOffset {
+			return float64(int16(binary.BigEndian.Uint16(buf[8:]))), nil
+		}
+		return float64(int16(binary.BigEndian.Uint16(buf[10:]))), nil
+	}
+
+	field, ok := os2Metrics[tag]
+	if !ok {
+		return 0, fmt.Errorf("unsupported metric %q", tag)
+	}
+	os2, err := font.OS2Table()
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q: %w", TagOS2, err)
+	}
+	return field(os2), nil
+}
+
+// os2Metrics contains the functions returning the metrics stored in the 'OS/2' table.
+var os2Metrics = map[Tag]func(*TableOS2) float64{
+	MetricHorizontalAscender:    func(t *TableOS2) float64 { return float64(t.STypoAscender) },
+	MetricHorizontalDescender:   func(t *TableOS2) float64 { return float64(t.STypoDescender) },
+	MetricHorizontalLineGap:     func(t *TableOS2) float64 { return float64(t.STypoLineGap) },
+	MetricHorizontalClipAscent:  func(t *TableOS2) float64 { return float64(t.UsWinAscent) },
+	MetricHorizontalClipDescent: func(t *TableOS2) float64 { return float64(t.UsWinDescent) },
+	MetricXHeight:               func(t *TableOS2) float64 { return float64(t.SxHeigh) },
+	MetricCapHeight:             func(t *TableOS2) float64 { return float64(t.SCapHeight) },
+	MetricSubscriptXSize:        func(t *TableOS2) float64 { return float64(t.YSubscriptXSize) },
+	MetricSubscriptYSize:        func(t *TableOS2) float64 { return float64(t.YSubscriptYSize) },
+	MetricSubscriptXOffset:      func(t *TableOS2) float64 { return float64(t.YSubscriptXOffset) },
+	MetricSubscriptYOffset:      func(t *TableOS2) float64 { return float64(t.YSubscriptYOffset) },
+	MetricSuperscriptXSize:      func(t *TableOS2) float64 { return float64(t.YSuperscriptXSize) },
+	MetricSuperscriptYSize:      func(t *TableOS2) float64 { return float64(t.YSuperscriptYSize) },
+	MetricSuperscriptXOffset:    func(t *TableOS2) float64 { return float64(t.YSuperscriptXOffset) },
+	MetricSuperscriptYOffset:    func(t *TableOS2) float64 { return float64(t.YSuperscriptYOffset) },
+	MetricStrikeoutSize:         func(t *TableOS2) float64 { return float64(t.YStrikeoutSize) },
+	MetricStrikeoutOffset:       func(t *TableOS2) float64 { return float64(t.YStrikeoutPosition) },
+}
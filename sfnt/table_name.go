@@ -219,7 +219,7 @@ func (nameEntry *NameEntry) Platform() string {
 	return nameEntry.PlatformID.String()
 }
 
-func parseTableName(tag Tag, buf []byte) (Table, error) {
+func parseTableName(tag Tag, buf []byte, _ *Options) (Table, error) {
 	r := bytes.NewBuffer(buf)
 
 	var header nameHeader
@@ -368,6 +368,29 @@ func (table *TableName) Bytes() []byte {
 	return table.bytes
 }
 
+// Find returns the entry with the given name ID, or nil if there is none. If
+// several entries have the ID, the Microsoft English entry is preferred,
+// followed by any other Microsoft entry, and then the first entry in the table.
+func (table *TableName) Find(nameID NameID) *NameEntry {
+	var found *NameEntry
+	for _, entry := range table.entries {
+		if entry.NameID != nameID {
+			continue
+		}
+		if entry.PlatformID == PlatformMicrosoft {
+			if entry.LanguageID == PlatformLanguageMicrosoftEnglish {
+				return entry
+			}
+			if found == nil || found.PlatformID != PlatformMicrosoft {
+				found = entry
+			}
+		} else if found == nil {
+			found = entry
+		}
+	}
+	return found
+}
+
 // List returns a list of all the strings defined in this table.
 func (table *TableName) List() []*NameEntry {
 	return table.entries
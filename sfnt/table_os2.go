@@ -54,7 +54,7 @@ type TableOS2 struct {
 	bytes []byte
 }
 
-func parseTableOS2(tag Tag, buf []byte) (Table, error) {
+func parseTableOS2(tag Tag, buf []byte, _ *Options) (Table, error) {
 	r := bytes.NewBuffer(buf)
 
 	var v4fields v4Fields
@@ -76,6 +76,20 @@ func parseTableOS2(tag Tag, buf []byte) (Table, error) {
 	}, nil
 }
 
+// Bytes returns the byte representation of this table. Any bytes after the
+// fields known to this package are kept from the parsed table.
 func (t *TableOS2) Bytes() []byte {
-	return t.bytes
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.BigEndian, t.v4Fields); err != nil {
+		panic(err) // should never happen
+	}
+	if t.Version == 5 {
+		if err := binary.Write(&buffer, binary.BigEndian, t.v5Fields); err != nil {
+			panic(err) // should never happen
+		}
+	}
+	if len(t.bytes) > buffer.Len() {
+		buffer.Write(t.bytes[buffer.Len():])
+	}
+	return buffer.Bytes()
 }
@@ -0,0 +1,230 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// TablePost contains information for PostScript printers, and the names of
+// the glyphs.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/post
+type TablePost struct {
+	baseTable
+	tablePostFields
+
+	// GlyphNames contains the name of each glyph, for versions 1 and 2 of
+	// the table, and is nil for version 3. Tables of version 2.5 are parsed
+	// as version 2.
+	GlyphNames []string
+}
+
+type tablePostFields struct {
+	Version            fixed
+	ItalicAngle        fixed
+	UnderlinePosition  int16
+	UnderlineThickness int16
+	IsFixedPitch       uint32
+	MinMemType42       uint32
+	MaxMemType42       uint32
+	MinMemType1        uint32
+	MaxMemType1        uint32
+}
+
+const postHeaderSize = 32
+
+// Versions of the 'post' table.
+var (
+	postVersion1  = fixed{Major: 1}
+	postVersion2  = fixed{Major: 2}
+	postVersion25 = fixed{Major: 2, Minor: 0x5000}
+	postVersion3  = fixed{Major: 3}
+)
+
+func parseTablePost(tag Tag, buf []byte) (*TablePost, error) {
+	var fields tablePostFields
+	if err := binary.Read(bytes.NewReader(buf), binary.BigEndian, &fields); err != nil {
+		return nil, err
+	}
+	table := &TablePost{baseTable: baseTable(tag), tablePostFields: fields}
+
+	switch fields.Version {
+	case postVersion1:
+		table.GlyphNames = append([]string(nil), macGlyphNames...)
+	case postVersion2:
+		if len(buf) < postHeaderSize+2 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		numGlyphs := int(binary.BigEndian.Uint16(buf[postHeaderSize:]))
+		indexes := buf[postHeaderSize+2:]
+		if len(indexes) < 2*numGlyphs {
+			return nil, io.ErrUnexpectedEOF
+		}
+		var names []string
+		for p := 2 * numGlyphs; p < len(indexes); p += 1 + int(indexes[p]) {
+			if p+1+int(indexes[p]) > len(indexes) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			names = append(names, string(indexes[p+1:p+1+int(indexes[p])]))
+		}
+		table.GlyphNames = make([]string, numGlyphs)
+		for i := range table.GlyphNames {
+			index := int(binary.BigEndian.Uint16(indexes[2*i:]))
+			switch {
+			case index < len(macGlyphNames):
+				table.GlyphNames[i] = macGlyphNames[index]
+			case index-len(macGlyphNames) < len(names):
+				table.GlyphNames[i] = names[index-len(macGlyphNames)]
+			default:
+				return nil, fmt.Errorf("glyph %d: name index %d out of range", i, index)
+			}
+		}
+	case postVersion25:
+		if len(buf) < postHeaderSize+2 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		numGlyphs := int(binary.BigEndian.Uint16(buf[postHeaderSize:]))
+		offsets := buf[postHeaderSize+2:]
+		if len(offsets) < numGlyphs {
+			return nil, io.ErrUnexpectedEOF
+		}
+		table.Version = postVersion2
+		table.GlyphNames = make([]string, numGlyphs)
+		for i := range table.GlyphNames {
+			index := i + int(int8(offsets[i]))
+			if index < 0 || index >= len(macGlyphNames) {
+				return nil, fmt.Errorf("glyph %d: name index %d out of range", i, index)
+			}
+			table.GlyphNames[i] = macGlyphNames[index]
+		}
+	case postVersion3:
+	default:
+		return nil, fmt.Errorf("unsupported %q version %d.%d", tag, fields.Version.Major, fields.Version.Minor)
+	}
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table. Tables with glyph
+// names are written as version 2, unless they are the names of version 1.
+func (table *TablePost) Bytes() []byte {
+	fields := table.tablePostFields
+	switch {
+	case table.GlyphNames == nil:
+		fields.Version = postVersion3
+	case isMacGlyphNames(table.GlyphNames):
+		fields.Version = postVersion1
+	default:
+		fields.Version = postVersion2
+	}
+
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.BigEndian, fields); err != nil {
+		panic(err) // should never happen
+	}
+	if fields.Version != postVersion2 {
+		return buffer.Bytes()
+	}
+
+	standard := make(map[string]int, len(macGlyphNames))
+	for i, name := range macGlyphNames {
+		standard[name] = i
+	}
+	custom := make(map[string]int)
+	var names []byte
+	indexes := make([]byte, 2+2*len(table.GlyphNames))
+	binary.BigEndian.PutUint16(indexes, uint16(len(table.GlyphNames)))
+	for i, name := range table.GlyphNames {
+		index, found := standard[name]
+		if !found {
+			if index, found = custom[name]; !found {
+				index = len(macGlyphNames) + len(custom)
+				custom[name] = index
+				if len(name) > 255 {
+					name = name[:255]
+				}
+				names = append(names, byte(len(name)))
+				names = append(names, name...)
+			}
+		}
+		binary.BigEndian.PutUint16(indexes[2+2*i:], uint16(index))
+	}
+	buffer.Write(indexes)
+	buffer.Write(names)
+	return buffer.Bytes()
+}
+
+// isMacGlyphNames returns true if names are the standard Macintosh glyph names.
+func isMacGlyphNames(names []string) bool {
+	if len(names) != len(macGlyphNames) {
+		return false
+	}
+	for i, name := range names {
+		if name != macGlyphNames[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// PostTable returns the table corresponding to the 'post' tag. The table is
+// parsed each time it is called, unless one was added with AddTable.
+func (font *Font) PostTable() (*TablePost, error) {
+	s, found := font.tables[TagPost]
+	if !found {
+		return nil, ErrMissingTable
+	}
+	if t, ok := s.table.(*TablePost); ok {
+		return t, nil
+	}
+	buf, err := font.tableBytes(s)
+	if err != nil {
+		return nil, err
+	}
+	return parseTablePost(TagPost, buf)
+}
+
+// macGlyphNames are the names of the standard Macintosh glyphs, which
+// version 2 of 'post' refers to by index.
+var macGlyphNames = []string{
+	".notdef", ".null", "nonmarkingreturn", "space", "exclam", "quotedbl",
+	"numbersign", "dollar", "percent", "ampersand", "quotesingle",
+	"parenleft", "parenright", "asterisk", "plus", "comma", "hyphen",
+	"period", "slash", "zero", "one", "two", "three", "four", "five", "six",
+	"seven", "eight", "nine", "colon", "semicolon", "less", "equal",
+	"greater", "question", "at", "A", "B", "C", "D", "E", "F", "G", "H", "I",
+	"J", "K", "L", "M", "N", "O", "P", "Q", "R", "S", "T", "U", "V", "W", "X",
+	"Y", "Z", "bracketleft", "backslash", "bracketright", "asciicircum",
+	"underscore", "grave", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j",
+	"k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y",
+	"z", "braceleft", "bar", "braceright", "asciitilde", "Adieresis",
+	"Aring", "Ccedilla", "Eacute", "Ntilde", "Odieresis", "Udieresis",
+	"aacute", "agrave", "acircumflex", "adieresis", "atilde", "aring",
+	"ccedilla", "eacute", "egrave", "ecircumflex", "edieresis", "iacute",
+	"igrave", "icircumflex", "idieresis", "ntilde", "oacute", "ograve",
+	"ocircumflex", "odieresis", "otilde", "uacute", "ugrave", "ucircumflex",
+	"udieresis", "dagger", "degree", "cent", "sterling", "section",
+	"bullet", "paragraph", "germandbls", "registered", "copyright",
+	"trademark", "acute", "dieresis", "notequal", "AE", "Oslash",
+	"infinity", "plusminus", "lessequal", "greaterequal", "yen", "mu",
+	"partialdiff", "summation", "product", "pi", "integral", "ordfeminine",
+	"ordmasculine", "Omega", "ae", "oslash", "questiondown", "exclamdown",
+	"logicalnot", "radical", "florin", "approxequal", "Delta",
+	"guillemotleft", "guillemotright", "ellipsis", "nonbreakingspace",
+	"Agrave", "Atilde", "Otilde", "OE", "oe", "endash", "emdash",
+	"quotedblleft", "quotedblright", "quoteleft", "quoteright", "divide",
+	"lozenge", "ydieresis", "Ydieresis", "fraction", "currency",
+	"guilsinglleft", "guilsinglright", "fi", "fl", "daggerdbl",
+	"periodcentered", "quotesinglbase", "quotedblbase", "perthousand",
+	"Acircumflex", "Ecircumflex", "Aacute", "Edieresis", "Egrave",
+	"Iacute", "Icircumflex", "Idieresis", "Igrave", "Oacute",
+	"Ocircumflex", "apple", "Ograve", "Uacute", "Ucircumflex", "Ugrave",
+	"dotlessi", "circumflex", "tilde", "macron", "breve", "dotaccent",
+	"ring", "cedilla", "hungarumlaut", "ogonek", "caron", "Lslash",
+	"lslash", "Scaron", "scaron", "Zcaron", "zcaron", "brokenbar", "Eth",
+	"eth", "Yacute", "yacute", "Thorn", "thorn", "minus", "multiply",
+	"onesuperior", "twosuperior", "threesuperior", "onehalf",
+	"onequarter", "threequarters", "franc", "Gbreve", "gbreve",
+	"Idotaccent", "Scedilla", "scedilla", "Cacute", "cacute", "Ccaron",
+	"ccaron", "dcroat",
+}
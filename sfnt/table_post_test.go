@@ -0,0 +1,63 @@
+package sfnt
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPostRoundTrip(t *testing.T) {
+	if len(macGlyphNames) != 258 {
+		t.Fatalf("len(macGlyphNames) = %d, want 258", len(macGlyphNames))
+	}
+
+	tests := []struct {
+		filename string
+		version  fixed
+		name     string // name of glyph 1.
+	}{
+		{filename: "open-sans-v15-latin-regular.woff", version: postVersion2, name: "null"},
+		{filename: "Roboto-BoldItalic.ttf", version: postVersion3},
+	}
+	for _, test := range tests {
+		file, err := os.Open(filepath.Join("testdata", test.filename))
+		if err != nil {
+			t.Fatalf("Failed to open %q: %s\n", test.filename, err)
+		}
+		defer file.Close()
+		font, err := StrictParse(file)
+		if err != nil {
+			t.Fatalf("StrictParse(%q) err = %q, want nil", test.filename, err)
+		}
+
+		post, err := font.PostTable()
+		if err != nil {
+			t.Fatalf("PostTable(%q) err = %q, want nil", test.filename, err)
+		}
+		if post.Version != test.version {
+			t.Errorf("PostTable(%q).Version = %v, want %v", test.filename, post.Version, test.version)
+		}
+		if test.version == postVersion3 {
+			if post.GlyphNames != nil {
+				t.Errorf("PostTable(%q).GlyphNames = %q, want nil", test.filename, post.GlyphNames)
+			}
+		} else if len(post.GlyphNames) < 2 || post.GlyphNames[1] != test.name {
+			t.Errorf("PostTable(%q).GlyphNames[1] = %q, want %q", test.filename, post.GlyphNames[1:2], test.name)
+		}
+
+		got, err := parseTablePost(TagPost, post.Bytes())
+		if err != nil {
+			t.Fatalf("parseTablePost(%q) err = %q, want nil", test.filename, err)
+		}
+		if !reflect.DeepEqual(got, post) {
+			t.Errorf("parseTablePost(%q) = %+v, want %+v", test.filename, got, post)
+		}
+	}
+
+	// Tables with the standard names are written as version 1.
+	post := &TablePost{baseTable: baseTable(TagPost), GlyphNames: macGlyphNames}
+	if got, err := parseTablePost(TagPost, post.Bytes()); err != nil || got.Version != postVersion1 {
+		t.Errorf("parseTablePost() = %+v, %v, want version 1", got, err)
+	}
+}
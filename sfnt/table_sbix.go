@@ -0,0 +1,194 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// TableSbix contains color glyphs as images, such as PNG files, at several sizes.
+// The table can only be parsed using the 'maxp' table, so it is accessed
+// using Font.SbixTable.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/sbix
+type TableSbix struct {
+	baseTable
+
+	Version uint16
+	Flags   uint16 // Flags is SbixDrawOutlines if the outlines are drawn as well as the images.
+	Strikes []SbixStrike
+}
+
+// SbixDrawOutlines is the flag set on 'sbix' tables whose glyph outlines are
+// drawn in addition to their images.
+const SbixDrawOutlines = 0x0002
+
+// SbixStrike contains the images of glyphs for one size.
+type SbixStrike struct {
+	PPEM uint16 // PPEM is the size the images were designed for, in pixels per em.
+	PPI  uint16 // PPI is the pixel density the images were designed for.
+	// Glyphs contains one entry for each glyph in the font, with empty Data
+	// for glyphs without an image.
+	Glyphs []SbixGlyph
+}
+
+// SbixGlyph is the image of a glyph.
+type SbixGlyph struct {
+	// OriginX and OriginY are the position of the bottom left of the image,
+	// in pixels from the glyph origin.
+	OriginX, OriginY int16
+	// GraphicType is the format of Data, such as BitmapPNG, or BitmapDupe if
+	// Data contains the ID of the glyph with the same image.
+	GraphicType Tag
+	Data        []byte
+}
+
+// Graphic types of the images in 'sbix' and the bitmaps returned by Font.GlyphBitmap.
+var (
+	BitmapPNG  = MustNamedTag("png ")
+	BitmapJPEG = MustNamedTag("jpg ")
+	BitmapTIFF = MustNamedTag("tiff")
+	BitmapDupe = MustNamedTag("dupe")
+	// BitmapRaw is used for the uncompressed bitmaps in 'EBDT' and 'CBDT',
+	// and is not found in 'sbix'.
+	BitmapRaw = MustNamedTag("bits")
+)
+
+var errInvalidSbix = errors.New("invalid 'sbix' table")
+
+const sbixHeaderSize = 8
+
+func parseTableSbix(tag Tag, buf []byte, numGlyphs int) (*TableSbix, error) {
+	if len(buf) < sbixHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	numStrikes := int(binary.BigEndian.Uint32(buf[4:]))
+	if sbixHeaderSize+4*numStrikes > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableSbix{
+		baseTable: baseTable(tag),
+		Version:   binary.BigEndian.Uint16(buf),
+		Flags:     binary.BigEndian.Uint16(buf[2:]),
+		Strikes:   make([]SbixStrike, numStrikes),
+	}
+	for i := range table.Strikes {
+		offset := int(binary.BigEndian.Uint32(buf[sbixHeaderSize+4*i:]))
+		if offset+4+4*(numGlyphs+1) > len(buf) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		b := buf[offset:]
+		strike := SbixStrike{
+			PPEM:   binary.BigEndian.Uint16(b),
+			PPI:    binary.BigEndian.Uint16(b[2:]),
+			Glyphs: make([]SbixGlyph, numGlyphs),
+		}
+		for j := range strike.Glyphs {
+			start := int(binary.BigEndian.Uint32(b[4+4*j:]))
+			end := int(binary.BigEndian.Uint32(b[8+4*j:]))
+			if start == end {
+				continue
+			}
+			if start > end || end > len(b) || end-start < 8 {
+				return nil, fmt.Errorf("strike %d glyph %d: %w", i, j, errInvalidSbix)
+			}
+			strike.Glyphs[j] = SbixGlyph{
+				OriginX:     int16(binary.BigEndian.Uint16(b[start:])),
+				OriginY:     int16(binary.BigEndian.Uint16(b[start+2:])),
+				GraphicType: Tag{binary.BigEndian.Uint32(b[start+4:])},
+				Data:        b[start+8 : end],
+			}
+		}
+		table.Strikes[i] = strike
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableSbix) Bytes() []byte {
+	buf := make([]byte, sbixHeaderSize+4*len(table.Strikes))
+	binary.BigEndian.PutUint16(buf, table.Version)
+	binary.BigEndian.PutUint16(buf[2:], table.Flags)
+	binary.BigEndian.PutUint32(buf[4:], uint32(len(table.Strikes)))
+
+	for i, strike := range table.Strikes {
+		start := len(buf)
+		binary.BigEndian.PutUint32(buf[sbixHeaderSize+4*i:], uint32(start))
+
+		buf = append(buf, make([]byte, 4+4*(len(strike.Glyphs)+1))...)
+		b := buf[start:]
+		binary.BigEndian.PutUint16(b, strike.PPEM)
+		binary.BigEndian.PutUint16(b[2:], strike.PPI)
+
+		offset := len(b)
+		for j, glyph := range strike.Glyphs {
+			binary.BigEndian.PutUint32(buf[start+4+4*j:], uint32(offset))
+			if len(glyph.Data) == 0 {
+				continue
+			}
+			buf = appendUint16(buf, uint16(glyph.OriginX))
+			buf = appendUint16(buf, uint16(glyph.OriginY))
+			buf = appendUint16(buf, uint16(glyph.GraphicType.Number>>16))
+			buf = appendUint16(buf, uint16(glyph.GraphicType.Number))
+			buf = append(buf, glyph.Data...)
+			offset = len(buf) - start
+		}
+		binary.BigEndian.PutUint32(buf[start+4+4*len(strike.Glyphs):], uint32(offset))
+	}
+	return buf
+}
+
+// Glyph returns the image of a glyph in the strike, following references
+// to duplicate images, or nil if the glyph has no image.
+func (strike *SbixStrike) Glyph(glyph GlyphID) *SbixGlyph {
+	// Duplicates should refer to glyphs with images, but limit how many are
+	// followed in case they form a loop.
+	for i := 0; i < 8; i++ {
+		if int(glyph) >= len(strike.Glyphs) {
+			return nil
+		}
+		g := &strike.Glyphs[glyph]
+		if len(g.Data) == 0 {
+			return nil
+		}
+		if g.GraphicType != BitmapDupe {
+			return g
+		}
+		if len(g.Data) < 2 {
+			return nil
+		}
+		glyph = GlyphID(binary.BigEndian.Uint16(g.Data))
+	}
+	return nil
+}
+
+// SbixTable returns the table corresponding to the 'sbix' tag.
+// It requires the 'maxp' table to parse.
+func (font *Font) SbixTable() (*TableSbix, error) {
+	s, found := font.tables[TagSbix]
+	if !found {
+		return nil, ErrMissingTable
+	}
+	if t, ok := s.table.(*TableSbix); ok {
+		return t, nil
+	}
+
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagMaxp, err)
+	}
+
+	buf, err := font.tableBytes(s)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := parseTableSbix(TagSbix, buf, int(maxp.NumGlyphs))
+	if err != nil {
+		return nil, err
+	}
+	s.table = t
+	return t, nil
+}
@@ -0,0 +1,389 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// TableSTAT describes the design axes of a font family, and the names of
+// values along those axes (such as "Bold" on the weight axis), which are
+// used to build the style names of fonts and instances within the family.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/stat
+type TableSTAT struct {
+	baseTable
+
+	DesignAxes []DesignAxis
+	AxisValues []AxisValue
+
+	// ElidedFallbackNameID is the name used for the style when the names of
+	// all axis values are elided, for example "Regular".
+	ElidedFallbackNameID NameID
+}
+
+// DesignAxis is an axis of the design space of a font family. Unlike the
+// axes in 'fvar', design axes include those along which the fonts of the
+// family differ, such as italic, even if no font varies along them.
+type DesignAxis struct {
+	Tag    Tag
+	NameID NameID // NameID is the entry in the 'name' table containing the axis name.
+	// Ordering is the position of the names of values along this axis
+	// within style names, with lower values first.
+	Ordering uint16
+}
+
+// Name returns the name of the axis from names, or the axis tag if it has no name.
+func (axis *DesignAxis) Name(names *TableName) string {
+	if name := findName(names, axis.NameID); name != "" {
+		return name
+	}
+	return axis.Tag.String()
+}
+
+// Flags of axis values.
+const (
+	// AxisValueOlderSiblingFontAttribute is set on axis values that
+	// describe the fonts of older families, rather than this font.
+	AxisValueOlderSiblingFontAttribute = 0x0001
+	// AxisValueElidable is set on axis values whose name is omitted from
+	// style names, for example "Regular" on the weight axis.
+	AxisValueElidable = 0x0002
+)
+
+// AxisValue names a value, or a range of values, along one or more design axes.
+type AxisValue struct {
+	// Format is the format of the axis value record, from 1 to 4:
+	//   1. a single value along one axis.
+	//   2. a range of values along one axis, around the nominal value of its location.
+	//   3. a single value along one axis, linked to a related style, such as
+	//      Regular and Bold.
+	//   4. a combination of values along several axes.
+	Format uint16
+	Flags  uint16
+	NameID NameID
+
+	// Locations contains a single value for formats 1, 2 and 3, and any
+	// number of values for format 4.
+	Locations []AxisLocation
+
+	RangeMin, RangeMax float64 // RangeMin and RangeMax are only used by format 2.
+	LinkedValue        float64 // LinkedValue is only used by format 3.
+}
+
+// AxisLocation is a value along a design axis.
+type AxisLocation struct {
+	AxisIndex uint16 // AxisIndex is the index of the axis in TableSTAT.DesignAxes.
+	Value     float64
+}
+
+// Name returns the name of the axis value from names, or "" if it has no name.
+func (value *AxisValue) Name(names *TableName) string {
+	return findName(names, value.NameID)
+}
+
+// Elidable returns true if the name of the axis value should be omitted from style names.
+func (value *AxisValue) Elidable() bool {
+	return value.Flags&AxisValueElidable != 0
+}
+
+// Matches returns true if the axis value applies at location, which contains
+// values along the axes identified by their tags. Axis values only match if
+// location includes all of their axes.
+func (value *AxisValue) Matches(location map[Tag]float64, axes []DesignAxis) bool {
+	if len(value.Locations) == 0 {
+		return false
+	}
+	for _, l := range value.Locations {
+		if int(l.AxisIndex) >= len(axes) {
+			return false
+		}
+		v, ok := location[axes[l.AxisIndex].Tag]
+		if !ok {
+			return false
+		}
+		if value.Format == 2 {
+			if v < value.RangeMin || v > value.RangeMax {
+				return false
+			}
+		} else if v != l.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// precedes returns true if the axis value is a better name for its axes than
+// other: combinations of more axes come first, then single values, then ranges.
+func (value *AxisValue) precedes(other *AxisValue) bool {
+	if len(value.Locations) != len(other.Locations) {
+		return len(value.Locations) > len(other.Locations)
+	}
+	return other.Format == 2 && value.Format != 2
+}
+
+// ElidedFallbackName returns the name of the style when all axis value
+// names are elided, or "Regular" if it has no name.
+func (table *TableSTAT) ElidedFallbackName(names *TableName) string {
+	if name := findName(names, table.ElidedFallbackNameID); name != "" {
+		return name
+	}
+	return "Regular"
+}
+
+// StyleName returns the style name at a location in the design space, for
+// example "Condensed Bold", built from the names of the axis values that
+// match the location in the order of their axes. Format 4 axis values take
+// precedence over the values of the individual axes that they combine.
+func (table *TableSTAT) StyleName(names *TableName, location map[Tag]float64) string {
+	matched := make([]*AxisValue, len(table.DesignAxes))
+	for i := range table.AxisValues {
+		value := &table.AxisValues[i]
+		if value.Flags&AxisValueOlderSiblingFontAttribute != 0 || !value.Matches(location, table.DesignAxes) {
+			continue
+		}
+		for _, l := range value.Locations {
+			if existing := matched[l.AxisIndex]; existing == nil || value.precedes(existing) {
+				matched[l.AxisIndex] = value
+			}
+		}
+	}
+
+	order := make([]int, len(table.DesignAxes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return table.DesignAxes[order[i]].Ordering < table.DesignAxes[order[j]].Ordering
+	})
+
+	var parts []string
+	seen := make(map[*AxisValue]bool)
+	for _, i := range order {
+		value := matched[i]
+		if value == nil || seen[value] || value.Elidable() {
+			continue
+		}
+		seen[value] = true
+		if name := value.Name(names); name != "" {
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) == 0 {
+		return table.ElidedFallbackName(names)
+	}
+	return strings.Join(parts, " ")
+}
+
+const (
+	statHeaderSize     = 20
+	statDesignAxisSize = 8
+)
+
+var errInvalidSTAT = errors.New("invalid 'STAT' table")
+
+func parseTableSTAT(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < 18 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if binary.BigEndian.Uint16(buf) != 1 {
+		return nil, errInvalidSTAT
+	}
+	minorVersion := binary.BigEndian.Uint16(buf[2:])
+	axisSize := int(binary.BigEndian.Uint16(buf[4:]))
+	axisCount := int(binary.BigEndian.Uint16(buf[6:]))
+	axesOffset := int(binary.BigEndian.Uint32(buf[8:]))
+	valueCount := int(binary.BigEndian.Uint16(buf[12:]))
+	valuesOffset := int(binary.BigEndian.Uint32(buf[14:]))
+
+	table := &TableSTAT{
+		baseTable:  baseTable(tag),
+		DesignAxes: make([]DesignAxis, axisCount),
+		AxisValues: make([]AxisValue, valueCount),
+	}
+	if minorVersion >= 1 {
+		if len(buf) < statHeaderSize {
+			return nil, io.ErrUnexpectedEOF
+		}
+		table.ElidedFallbackNameID = NameID(binary.BigEndian.Uint16(buf[18:]))
+	}
+
+	if axisCount > 0 && axisSize < statDesignAxisSize {
+		return nil, errInvalidSTAT
+	}
+	if axesOffset+axisCount*axisSize > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	for i := range table.DesignAxes {
+		b := buf[axesOffset+i*axisSize:]
+		table.DesignAxes[i] = DesignAxis{
+			Tag:      Tag{binary.BigEndian.Uint32(b)},
+			NameID:   NameID(binary.BigEndian.Uint16(b[4:])),
+			Ordering: binary.BigEndian.Uint16(b[6:]),
+		}
+	}
+
+	if valuesOffset+2*valueCount > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	for i := range table.AxisValues {
+		offset := valuesOffset + int(binary.BigEndian.Uint16(buf[valuesOffset+2*i:]))
+		value, err := parseAxisValue(buf, offset)
+		if err != nil {
+			return nil, err
+		}
+		table.AxisValues[i] = value
+	}
+
+	return table, nil
+}
+
+// axisValueSizes contains the size of each format of axis value record,
+// excluding the locations of format 4.
+var axisValueSizes = [...]int{1: 12, 2: 20, 3: 16, 4: 8}
+
+func parseAxisValue(buf []byte, offset int) (AxisValue, error) {
+	if offset+2 > len(buf) {
+		return AxisValue{}, io.ErrUnexpectedEOF
+	}
+	format := binary.BigEndian.Uint16(buf[offset:])
+	if format < 1 || format > 4 {
+		return AxisValue{}, errInvalidSTAT
+	}
+	if offset+axisValueSizes[format] > len(buf) {
+		return AxisValue{}, io.ErrUnexpectedEOF
+	}
+	b := buf[offset:]
+	value := AxisValue{
+		Format: format,
+		Flags:  binary.BigEndian.Uint16(b[4:]),
+		NameID: NameID(binary.BigEndian.Uint16(b[6:])),
+	}
+
+	if format == 4 {
+		count := int(binary.BigEndian.Uint16(b[2:]))
+		if 8+6*count > len(b) {
+			return AxisValue{}, io.ErrUnexpectedEOF
+		}
+		value.Locations = make([]AxisLocation, count)
+		for i := range value.Locations {
+			r := b[8+6*i:]
+			value.Locations[i] = AxisLocation{
+				AxisIndex: binary.BigEndian.Uint16(r),
+				Value:     fixedToFloat(binary.BigEndian.Uint32(r[2:])),
+			}
+		}
+		return value, nil
+	}
+
+	value.Locations = []AxisLocation{{
+		AxisIndex: binary.BigEndian.Uint16(b[2:]),
+		Value:     fixedToFloat(binary.BigEndian.Uint32(b[8:])),
+	}}
+	switch format {
+	case 2:
+		value.RangeMin = fixedToFloat(binary.BigEndian.Uint32(b[12:]))
+		value.RangeMax = fixedToFloat(binary.BigEndian.Uint32(b[16:]))
+	case 3:
+		value.LinkedValue = fixedToFloat(binary.BigEndian.Uint32(b[12:]))
+	}
+	return value, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableSTAT) Bytes() []byte {
+	minorVersion := uint16(1)
+	for _, value := range table.AxisValues {
+		if value.Format == 4 {
+			minorVersion = 2
+		}
+	}
+
+	axesSize := len(table.DesignAxes) * statDesignAxisSize
+	buf := make([]byte, statHeaderSize+axesSize, statHeaderSize+axesSize+2*len(table.AxisValues))
+	binary.BigEndian.PutUint16(buf, 1)
+	binary.BigEndian.PutUint16(buf[2:], minorVersion)
+	binary.BigEndian.PutUint16(buf[4:], statDesignAxisSize)
+	binary.BigEndian.PutUint16(buf[6:], uint16(len(table.DesignAxes)))
+	if len(table.DesignAxes) > 0 {
+		binary.BigEndian.PutUint32(buf[8:], statHeaderSize)
+	}
+	binary.BigEndian.PutUint16(buf[12:], uint16(len(table.AxisValues)))
+	binary.BigEndian.PutUint16(buf[18:], uint16(table.ElidedFallbackNameID))
+
+	b := buf[statHeaderSize:]
+	for _, axis := range table.DesignAxes {
+		binary.BigEndian.PutUint32(b, axis.Tag.Number)
+		binary.BigEndian.PutUint16(b[4:], uint16(axis.NameID))
+		binary.BigEndian.PutUint16(b[6:], axis.Ordering)
+		b = b[statDesignAxisSize:]
+	}
+
+	if len(table.AxisValues) == 0 {
+		return buf
+	}
+
+	// The axis values follow their offsets, which are relative to the start
+	// of the offsets.
+	valuesOffset := len(buf)
+	binary.BigEndian.PutUint32(buf[14:], uint32(valuesOffset))
+	buf = append(buf, make([]byte, 2*len(table.AxisValues))...)
+	for i, value := range table.AxisValues {
+		binary.BigEndian.PutUint16(buf[valuesOffset+2*i:], uint16(len(buf)-valuesOffset))
+		buf = value.appendBytes(buf)
+	}
+	return buf
+}
+
+func (value *AxisValue) appendBytes(buf []byte) []byte {
+	format := value.Format
+	if format < 1 || format > 4 {
+		format = 1
+	}
+	size := axisValueSizes[format]
+	if format == 4 {
+		size += 6 * len(value.Locations)
+	}
+	start := len(buf)
+	buf = append(buf, make([]byte, size)...)
+	b := buf[start:]
+
+	binary.BigEndian.PutUint16(b, format)
+	binary.BigEndian.PutUint16(b[4:], value.Flags)
+	binary.BigEndian.PutUint16(b[6:], uint16(value.NameID))
+
+	if format == 4 {
+		binary.BigEndian.PutUint16(b[2:], uint16(len(value.Locations)))
+		for i, l := range value.Locations {
+			r := b[8+6*i:]
+			binary.BigEndian.PutUint16(r, l.AxisIndex)
+			binary.BigEndian.PutUint32(r[2:], floatToFixed(l.Value))
+		}
+		return buf
+	}
+
+	var location AxisLocation
+	if len(value.Locations) > 0 {
+		location = value.Locations[0]
+	}
+	binary.BigEndian.PutUint16(b[2:], location.AxisIndex)
+	binary.BigEndian.PutUint32(b[8:], floatToFixed(location.Value))
+	switch format {
+	case 2:
+		binary.BigEndian.PutUint32(b[12:], floatToFixed(value.RangeMin))
+		binary.BigEndian.PutUint32(b[16:], floatToFixed(value.RangeMax))
+	case 3:
+		binary.BigEndian.PutUint32(b[12:], floatToFixed(value.LinkedValue))
+	}
+	return buf
+}
+
+// STATTable returns the table corresponding to the 'STAT' tag.
+func (font *Font) STATTable() (*TableSTAT, error) {
+	t, err := font.Table(TagSTAT)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableSTAT), nil
+}
@@ -0,0 +1,98 @@
+package sfnt
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func testSTATTable() *TableSTAT {
+	return &TableSTAT{
+		baseTable: baseTable(TagSTAT),
+		DesignAxes: []DesignAxis{
+			{Tag: MustNamedTag("wght"), NameID: 256, Ordering: 1},
+			{Tag: MustNamedTag("wdth"), NameID: 257, Ordering: 0},
+			{Tag: MustNamedTag("ital"), NameID: 258, Ordering: 2},
+		},
+		AxisValues: []AxisValue{
+			{Format: 3, Flags: AxisValueElidable, NameID: 2, Locations: []AxisLocation{{0, 400}}, LinkedValue: 700},
+			{Format: 1, NameID: 259, Locations: []AxisLocation{{0, 700}}},
+			{Format: 2, NameID: 260, Locations: []AxisLocation{{1, 75}}, RangeMin: 62.5, RangeMax: 87.5},
+			{Format: 2, Flags: AxisValueElidable, NameID: 261, Locations: []AxisLocation{{1, 100}}, RangeMin: 87.5, RangeMax: 100},
+			{Format: 1, Flags: AxisValueElidable, NameID: 2, Locations: []AxisLocation{{2, 0}}},
+			{Format: 1, NameID: 262, Locations: []AxisLocation{{2, 1}}},
+			{Format: 4, NameID: 263, Locations: []AxisLocation{{0, 900}, {1, 75}}},
+		},
+		ElidedFallbackNameID: 2,
+	}
+}
+
+func TestSTATRoundTrip(t *testing.T) {
+	want := testSTATTable()
+
+	parsed, err := parseTableSTAT(TagSTAT, want.Bytes(), nil)
+	if err != nil {
+		t.Fatalf("parseTableSTAT() err = %q, want nil", err)
+	}
+
+	got := parsed.(*TableSTAT)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTableSTAT() = %+v, want %+v", got, want)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Errorf("Bytes() changed after round trip")
+	}
+
+	if _, err := parseTableSTAT(TagSTAT, want.Bytes()[:30], nil); err == nil {
+		t.Errorf("parseTableSTAT() of truncated table err = nil, want error")
+	}
+}
+
+func TestSTATStyleName(t *testing.T) {
+	names := NewTableName()
+	for id, value := range map[NameID]string{
+		2:   "Regular",
+		256: "Weight",
+		259: "Bold",
+		260: "Condensed",
+		261: "Normal",
+		262: "Italic",
+		263: "Black Condensed",
+	} {
+		if err := names.AddMicrosoftEnglishEntry(id, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	font := New(TypeTrueType)
+	font.AddTable(TagSTAT, testSTATTable())
+	stat, err := font.STATTable()
+	if err != nil {
+		t.Fatalf("STATTable() err = %q, want nil", err)
+	}
+
+	if got := stat.DesignAxes[0].Name(names); got != "Weight" {
+		t.Errorf("DesignAxes[0].Name() = %q, want %q", got, "Weight")
+	}
+	if got := stat.DesignAxes[2].Name(names); got != "ital" {
+		t.Errorf("DesignAxes[2].Name() = %q, want %q", got, "ital")
+	}
+
+	wght, wdth, ital := MustNamedTag("wght"), MustNamedTag("wdth"), MustNamedTag("ital")
+	tests := []struct {
+		location map[Tag]float64
+		want     string
+	}{
+		{map[Tag]float64{wght: 400, wdth: 100, ital: 0}, "Regular"},
+		{map[Tag]float64{wght: 700, wdth: 100, ital: 0}, "Bold"},
+		{map[Tag]float64{wght: 700, wdth: 80, ital: 1}, "Condensed Bold Italic"},
+		{map[Tag]float64{wght: 400, wdth: 70}, "Condensed"},
+		{map[Tag]float64{wght: 900, wdth: 75, ital: 1}, "Black Condensed Italic"},
+		{map[Tag]float64{wght: 500}, "Regular"},
+	}
+	for _, test := range tests {
+		if got := stat.StyleName(names, test.location); got != test.want {
+			t.Errorf("StyleName(%v) = %q, want %q", test.location, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package sfnt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TableSVG contains color glyphs as SVG documents. Each document contains the
+// glyphs in a range of glyph IDs, as elements with the id "glyph<ID>".
+// https://docs.microsoft.com/en-us/typography/opentype/spec/svg
+type TableSVG struct {
+	baseTable
+
+	// Documents are sorted by glyph ID, and their ranges do not overlap.
+	Documents []SVGDocument
+}
+
+// SVGDocument is an SVG document containing the glyphs from StartGlyph to
+// EndGlyph inclusive.
+type SVGDocument struct {
+	StartGlyph, EndGlyph GlyphID
+	// Data is the document as stored in the font, which may be gzip compressed.
+	Data []byte
+}
+
+// Compressed returns true if the document is gzip compressed.
+func (doc *SVGDocument) Compressed() bool {
+	return len(doc.Data) >= 3 && doc.Data[0] == 0x1F && doc.Data[1] == 0x8B && doc.Data[2] == 0x08
+}
+
+// SVG returns the document, uncompressed if it is gzip compressed.
+func (doc *SVGDocument) SVG() ([]byte, error) {
+	if !doc.Compressed() {
+		return doc.Data, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(doc.Data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// NewTableSVG returns an empty 'SVG ' table, to which documents can be added.
+func NewTableSVG() *TableSVG {
+	return &TableSVG{baseTable: baseTable(TagSVG)}
+}
+
+// ErrOverlappingSVGDocument is returned by AddDocument if the glyphs of a
+// document are already in another document.
+var ErrOverlappingSVGDocument = errors.New("glyphs overlap an existing SVG document")
+
+// AddDocument adds an SVG document containing the glyphs from start to end
+// inclusive, gzip compressing it if compress is true.
+func (table *TableSVG) AddDocument(start, end GlyphID, svg []byte, compress bool) error {
+	if end < start {
+		return fmt.Errorf("invalid glyph range %d to %d", start, end)
+	}
+	i := sort.Search(len(table.Documents), func(i int) bool {
+		return table.Documents[i].EndGlyph >= start
+	})
+	if i < len(table.Documents) && table.Documents[i].StartGlyph <= end {
+		return fmt.Errorf("glyphs %d to %d: %w", start, end, ErrOverlappingSVGDocument)
+	}
+
+	doc := SVGDocument{StartGlyph: start, EndGlyph: end, Data: svg}
+	if compress {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(svg); err != nil {
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+		doc.Data = buf.Bytes()
+	}
+
+	table.Documents = append(table.Documents, SVGDocument{})
+	copy(table.Documents[i+1:], table.Documents[i:])
+	table.Documents[i] = doc
+	return nil
+}
+
+// AddDirectory adds the SVG files at the top of a directory, such as one
+// returned by os.DirFS, as documents containing a single glyph. Each file is
+// named after its glyph: "glyph<ID>.svg", the name of the glyph in 'post'
+// followed by ".svg", or the character mapped to the glyph by 'cmap', as in
+// "uni20AC.svg" or "u1F600.svg". If no element of a document has the id
+// "glyph<ID>", it is given to the svg element. Other files are ignored.
+func (table *TableSVG) AddDirectory(font *Font, dir fs.FS, compress bool) error {
+	entries, err := fs.ReadDir(dir, ".")
+	if err != nil {
+		return err
+	}
+	glyphs, err := newSVGGlyphNames(font)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		ext := path.Ext(name)
+		if entry.IsDir() || !strings.EqualFold(ext, ".svg") {
+			continue
+		}
+		glyph, ok := glyphs.lookup(strings.TrimSuffix(name, ext))
+		if !ok {
+			return fmt.Errorf("%s: no glyph named %q", name, strings.TrimSuffix(name, ext))
+		}
+		svg, err := fs.ReadFile(dir, name)
+		if err != nil {
+			return err
+		}
+		if svg, err = setSVGGlyphID(svg, glyph); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := table.AddDocument(glyph, glyph, svg, compress); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// svgGlyphNames finds the glyphs that SVG files are named after.
+type svgGlyphNames struct {
+	numGlyphs int
+	names     map[string]GlyphID
+	cmap      *TableCmap
+}
+
+func newSVGGlyphNames(font *Font) (*svgGlyphNames, error) {
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagMaxp, err)
+	}
+	g := &svgGlyphNames{numGlyphs: int(maxp.NumGlyphs), names: make(map[string]GlyphID)}
+	if font.HasTable(TagPost) {
+		post, err := font.PostTable()
+		if err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", TagPost, err)
+		}
+		for i, name := range post.GlyphNames {
+			if _, found := g.names[name]; !found && i < g.numGlyphs {
+				g.names[name] = GlyphID(i)
+			}
+		}
+	}
+	if font.HasTable(TagCmap) {
+		if g.cmap, err = font.CmapTable(); err != nil {
+			return nil, fmt.Errorf("parsing %q: %w", TagCmap, err)
+		}
+	}
+	return g, nil
+}
+
+// lookup returns the glyph that a file is named after, without the ".svg".
+func (g *svgGlyphNames) lookup(name string) (GlyphID, bool) {
+	if digits := strings.TrimPrefix(name, "glyph"); digits != name {
+		if id, err := strconv.ParseUint(digits, 10, 16); err == nil && int(id) < g.numGlyphs {
+			return GlyphID(id), true
+		}
+	}
+	if id, found := g.names[name]; found {
+		return id, true
+	}
+
+	var hex string
+	switch {
+	case strings.HasPrefix(name, "uni") && len(name) == 7:
+		hex = name[3:]
+	case strings.HasPrefix(name, "u") && len(name) >= 5 && len(name) <= 7:
+		hex = name[1:]
+	}
+	if r, err := strconv.ParseUint(hex, 16, 32); err == nil && g.cmap != nil {
+		if id := g.cmap.Lookup(rune(r)); id != 0 {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+var (
+	svgRootElement = regexp.MustCompile(`<svg[\s/>]`)
+	svgIDAttribute = regexp.MustCompile(`\sid\s*=`)
+)
+
+// setSVGGlyphID returns the document, with the id "glyph<ID>" added to its
+// svg element unless an element already has it.
+func setSVGGlyphID(svg []byte, glyph GlyphID) ([]byte, error) {
+	id := fmt.Sprintf("glyph%d", glyph)
+	if bytes.Contains(svg, []byte(`id="`+id+`"`)) || bytes.Contains(svg, []byte(`id='`+id+`'`)) {
+		return svg, nil
+	}
+	root := svgRootElement.FindIndex(svg)
+	if root == nil {
+		return nil, errors.New("no svg element")
+	}
+	start := root[0] + len("<svg")
+	end := bytes.IndexByte(svg[start:], '>')
+	if end < 0 {
+		return nil, errors.New("unterminated svg element")
+	}
+	if svgIDAttribute.Match(svg[start : start+end]) {
+		return nil, fmt.Errorf("the svg element has an id other than %q", id)
+	}
+
+	out := make([]byte, 0, len(svg)+len(id)+6)
+	out = append(out, svg[:start]...)
+	out = append(out, ` id="`+id+`"`...)
+	return append(out, svg[start:]...), nil
+}
+
+// Document returns the document containing glyph, or nil if there is none.
+func (table *TableSVG) Document(glyph GlyphID) *SVGDocument {
+	i := sort.Search(len(table.Documents), func(i int) bool {
+		return table.Documents[i].EndGlyph >= glyph
+	})
+	if i < len(table.Documents) && table.Documents[i].StartGlyph <= glyph {
+		return &table.Documents[i]
+	}
+	return nil
+}
+
+const (
+	svgHeaderSize = 10
+	svgRecordSize = 12
+)
+
+var errInvalidSVG = errors.New("invalid 'SVG ' table")
+
+func parseTableSVG(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < svgHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if version := binary.BigEndian.Uint16(buf); version != 0 {
+		return nil, errInvalidSVG
+	}
+	listOffset := int(binary.BigEndian.Uint32(buf[2:]))
+	if listOffset+2 > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	list := buf[listOffset:]
+	count := int(binary.BigEndian.Uint16(list))
+	if 2+count*svgRecordSize > len(list) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableSVG{
+		baseTable: baseTable(tag),
+		Documents: make([]SVGDocument, count),
+	}
+	for i := range table.Documents {
+		b := list[2+i*svgRecordSize:]
+		offset := int64(binary.BigEndian.Uint32(b[4:]))
+		length := int64(binary.BigEndian.Uint32(b[8:]))
+		if offset+length > int64(len(list)) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		doc := SVGDocument{
+			StartGlyph: GlyphID(binary.BigEndian.Uint16(b)),
+			EndGlyph:   GlyphID(binary.BigEndian.Uint16(b[2:])),
+			Data:       list[offset : offset+length],
+		}
+		if doc.EndGlyph < doc.StartGlyph || (i > 0 && doc.StartGlyph <= table.Documents[i-1].EndGlyph) {
+			return nil, errInvalidSVG
+		}
+		table.Documents[i] = doc
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table. Documents with the
+// same data are only stored once.
+func (table *TableSVG) Bytes() []byte {
+	count := len(table.Documents)
+	listOffset := svgHeaderSize
+	buf := make([]byte, listOffset+2+count*svgRecordSize)
+	binary.BigEndian.PutUint32(buf[2:], uint32(listOffset))
+	binary.BigEndian.PutUint16(buf[listOffset:], uint16(count))
+
+	offsets := make(map[string]int)
+	for i, doc := range table.Documents {
+		offset, ok := offsets[string(doc.Data)]
+		if !ok {
+			offset = len(buf) - listOffset
+			offsets[string(doc.Data)] = offset
+			buf = append(buf, doc.Data...)
+		}
+
+		b := buf[listOffset+2+i*svgRecordSize:]
+		binary.BigEndian.PutUint16(b, uint16(doc.StartGlyph))
+		binary.BigEndian.PutUint16(b[2:], uint16(doc.EndGlyph))
+		binary.BigEndian.PutUint32(b[4:], uint32(offset))
+		binary.BigEndian.PutUint32(b[8:], uint32(len(doc.Data)))
+	}
+	return buf
+}
+
+// SVGTable returns the table corresponding to the 'SVG ' tag.
+func (font *Font) SVGTable() (*TableSVG, error) {
+	t, err := font.Table(TagSVG)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableSVG), nil
+}
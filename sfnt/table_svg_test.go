@@ -0,0 +1,133 @@
+package sfnt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSVGDocuments(t *testing.T) {
+	star := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><path id="glyph3" d="M0 0L10 10"/></svg>`)
+	shared := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><g id="glyph5"/><g id="glyph6"/></svg>`)
+
+	table := NewTableSVG()
+	if err := table.AddDocument(5, 6, shared, true); err != nil {
+		t.Fatalf("AddDocument(5, 6) err = %q, want nil", err)
+	}
+	if err := table.AddDocument(3, 3, star, false); err != nil {
+		t.Fatalf("AddDocument(3, 3) err = %q, want nil", err)
+	}
+	if err := table.AddDocument(8, 9, shared, true); err != nil {
+		t.Fatalf("AddDocument(8, 9) err = %q, want nil", err)
+	}
+	if err := table.AddDocument(4, 5, star, false); !errors.Is(err, ErrOverlappingSVGDocument) {
+		t.Errorf("AddDocument(4, 5) err = %v, want %v", err, ErrOverlappingSVGDocument)
+	}
+
+	buf := table.Bytes()
+	parsed, err := parseTableSVG(TagSVG, buf, nil)
+	if err != nil {
+		t.Fatalf("parseTableSVG() err = %q, want nil", err)
+	}
+	font := New(TypeTrueType)
+	font.AddTable(TagSVG, parsed)
+	svg, err := font.SVGTable()
+	if err != nil {
+		t.Fatalf("SVGTable() err = %q, want nil", err)
+	}
+	if !reflect.DeepEqual(svg.Documents, table.Documents) {
+		t.Errorf("Documents = %v, want %v", svg.Documents, table.Documents)
+	}
+
+	// Identical documents are only stored once.
+	if len(buf) >= svgHeaderSize+2+3*svgRecordSize+len(star)+2*len(table.Documents[1].Data) {
+		t.Errorf("len(Bytes()) = %d, want identical documents to be shared", len(buf))
+	}
+
+	tests := []struct {
+		glyph      GlyphID
+		want       []byte
+		start, end GlyphID
+		compressed bool
+	}{
+		{3, star, 3, 3, false},
+		{6, shared, 5, 6, true},
+		{8, shared, 8, 9, true},
+		{4, nil, 0, 0, false},
+		{10, nil, 0, 0, false},
+	}
+	for _, test := range tests {
+		doc := svg.Document(test.glyph)
+		if test.want == nil {
+			if doc != nil {
+				t.Errorf("Document(%d) = %v, want nil", test.glyph, doc)
+			}
+			continue
+		}
+		if doc == nil {
+			t.Errorf("Document(%d) = nil, want glyphs %d to %d", test.glyph, test.start, test.end)
+			continue
+		}
+		got, err := doc.SVG()
+		if err != nil || string(got) != string(test.want) {
+			t.Errorf("Document(%d).SVG() = %q, %v, want %q", test.glyph, got, err, test.want)
+		}
+		if doc.StartGlyph != test.start || doc.EndGlyph != test.end || doc.Compressed() != test.compressed {
+			t.Errorf("Document(%d) = glyphs %d to %d, compressed %v, want %d to %d, %v",
+				test.glyph, doc.StartGlyph, doc.EndGlyph, doc.Compressed(), test.start, test.end, test.compressed)
+		}
+	}
+}
+
+func TestSVGAddDirectory(t *testing.T) {
+	maxp := &TableMaxp{baseTable: baseTable(TagMaxp)}
+	maxp.NumGlyphs = 4
+	font := New(TypeTrueType)
+	font.AddTable(TagMaxp, maxp)
+	font.AddTable(TagPost, &TablePost{baseTable: baseTable(TagPost), GlyphNames: []string{".notdef", "A", "Euro", "smile"}})
+	font.AddTable(TagCmap, NewTableCmap(map[rune]GlyphID{'A': 1, '€': 2, '😀': 3}))
+
+	dir := fstest.MapFS{
+		"glyph1.svg":     {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><path id="glyph1" d="M0 0L10 10"/></svg>`)},
+		"Euro.svg":       {Data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><path d="M0 0L10 10"/></svg>`)},
+		"u1F600.svg":     {Data: []byte("<?xml version=\"1.0\"?>\n<svg\nviewBox=\"0 0 10 10\"/>")},
+		"notes.txt":      {Data: []byte("not a glyph")},
+		"old/glyph0.svg": {Data: []byte(`<svg/>`)},
+	}
+	table := NewTableSVG()
+	if err := table.AddDirectory(font, dir, true); err != nil {
+		t.Fatalf("AddDirectory() err = %q, want nil", err)
+	}
+	want := []string{
+		`<svg xmlns="http://www.w3.org/2000/svg"><path id="glyph1" d="M0 0L10 10"/></svg>`,
+		`<svg id="glyph2" xmlns="http://www.w3.org/2000/svg"><path d="M0 0L10 10"/></svg>`,
+		"<?xml version=\"1.0\"?>\n<svg id=\"glyph3\"\nviewBox=\"0 0 10 10\"/>",
+	}
+	if len(table.Documents) != len(want) {
+		t.Fatalf("AddDirectory() added %d documents, want %d", len(table.Documents), len(want))
+	}
+	for i, doc := range table.Documents {
+		got, err := doc.SVG()
+		glyph := GlyphID(i + 1)
+		if err != nil || doc.StartGlyph != glyph || doc.EndGlyph != glyph || string(got) != want[i] {
+			t.Errorf("document %d = glyphs %d to %d, %q, %v, want glyph %d, %q", i, doc.StartGlyph, doc.EndGlyph, got, err, glyph, want[i])
+		}
+	}
+
+	for _, dir := range []fstest.MapFS{
+		{"unknown.svg": {Data: []byte(`<svg/>`)}},
+		{"glyph4.svg": {Data: []byte(`<svg/>`)}},
+		{"uni0042.svg": {Data: []byte(`<svg/>`)}},
+		{"glyph2.svg": {Data: []byte(`<svg id="euro"/>`)}},
+		{"glyph2.svg": {Data: []byte(`<path/>`)}},
+	} {
+		if err := NewTableSVG().AddDirectory(font, dir, false); err == nil {
+			t.Errorf("AddDirectory(%v) err = nil, want an error", dir)
+		}
+	}
+	dir = fstest.MapFS{"glyph1.svg": {Data: []byte(`<svg/>`)}, "A.svg": {Data: []byte(`<svg/>`)}}
+	if err := NewTableSVG().AddDirectory(font, dir, false); !errors.Is(err, ErrOverlappingSVGDocument) {
+		t.Errorf("AddDirectory() of two files for glyph 1 err = %v, want %v", err, ErrOverlappingSVGDocument)
+	}
+}
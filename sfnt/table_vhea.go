@@ -0,0 +1,64 @@
+package sfnt
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// TableVhea contains the vertical header, used to lay out vertical text.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/vhea
+type TableVhea struct {
+	baseTable
+	tableVheaFields
+}
+
+type tableVheaFields struct {
+	Version              fixed
+	Ascent               int16 // Ascent is the distance from the centerline to the previous line's descent.
+	Descent              int16 // Descent is the distance from the centerline to the next line's ascent.
+	LineGap              int16
+	AdvanceHeightMax     uint16
+	MinTopSideBearing    int16
+	MinBottomSideBearing int16
+	YMaxExtent           int16
+	CaretSlopeRise       int16
+	CaretSlopeRun        int16
+	CaretOffset          int16
+	Reserved1            int16
+	Reserved2            int16
+	Reserved3            int16
+	Reserved4            int16
+	MetricDataformat     int16
+	NumOfLongVerMetrics  uint16
+}
+
+func parseTableVhea(tag Tag, buf []byte, _ *Options) (Table, error) {
+	r := bytes.NewBuffer(buf)
+
+	var fields tableVheaFields
+	if err := binary.Read(r, binary.BigEndian, &fields); err != nil {
+		return nil, err
+	}
+	return &TableVhea{
+		baseTable:       baseTable(tag),
+		tableVheaFields: fields,
+	}, nil
+}
+
+// Bytes returns the byte representation of this header.
+func (table *TableVhea) Bytes() []byte {
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.BigEndian, table.tableVheaFields); err != nil {
+		panic(err) // should never happen
+	}
+	return buffer.Bytes()
+}
+
+// VheaTable returns the table corresponding to the 'vhea' tag.
+func (font *Font) VheaTable() (*TableVhea, error) {
+	t, err := font.Table(TagVhea)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableVhea), nil
+}
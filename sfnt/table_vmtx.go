@@ -0,0 +1,222 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// VMetric contains the vertical metrics of a glyph.
+type VMetric struct {
+	AdvanceHeight  uint16
+	TopSideBearing int16
+}
+
+// TableVmtx contains the vertical metrics for each glyph in the font.
+// The table can only be parsed using the 'vhea' and 'maxp' tables, so it is
+// accessed using Font.VmtxTable.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/vmtx
+type TableVmtx struct {
+	baseTable
+
+	// Metrics contains one entry for each of the first vhea.NumOfLongVerMetrics glyphs.
+	Metrics []VMetric
+	// TopSideBearings contains the top side bearings of the remaining glyphs,
+	// which all use the advance height of the last entry in Metrics.
+	TopSideBearings []int16
+}
+
+func parseTableVmtx(tag Tag, buf []byte, numVMetrics, numGlyphs int) (*TableVmtx, error) {
+	if numVMetrics == 0 && numGlyphs > 0 {
+		return nil, fmt.Errorf("vhea.NumOfLongVerMetrics is 0")
+	}
+	if numVMetrics > numGlyphs {
+		return nil, fmt.Errorf("vhea.NumOfLongVerMetrics %d exceeds number of glyphs %d", numVMetrics, numGlyphs)
+	}
+	if len(buf) < 4*numVMetrics+2*(numGlyphs-numVMetrics) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableVmtx{
+		baseTable:       baseTable(tag),
+		Metrics:         make([]VMetric, numVMetrics),
+		TopSideBearings: make([]int16, numGlyphs-numVMetrics),
+	}
+
+	for i := range table.Metrics {
+		table.Metrics[i] = VMetric{
+			AdvanceHeight:  binary.BigEndian.Uint16(buf[4*i:]),
+			TopSideBearing: int16(binary.BigEndian.Uint16(buf[4*i+2:])),
+		}
+	}
+	buf = buf[4*numVMetrics:]
+	for i := range table.TopSideBearings {
+		table.TopSideBearings[i] = int16(binary.BigEndian.Uint16(buf[2*i:]))
+	}
+
+	return table, nil
+}
+
+// NumGlyphs returns the number of glyphs with metrics in this table.
+func (table *TableVmtx) NumGlyphs() int {
+	return len(table.Metrics) + len(table.TopSideBearings)
+}
+
+// Metric returns the advance height and top side bearing of a glyph.
+// Glyphs not in the table have the metrics of the last glyph.
+func (table *TableVmtx) Metric(glyph GlyphID) VMetric {
+	if int(glyph) < len(table.Metrics) {
+		return table.Metrics[glyph]
+	}
+
+	var m VMetric
+	if len(table.Metrics) > 0 {
+		m.AdvanceHeight = table.Metrics[len(table.Metrics)-1].AdvanceHeight
+	}
+	if i := int(glyph) - len(table.Metrics); i < len(table.TopSideBearings) {
+		m.TopSideBearing = table.TopSideBearings[i]
+	} else if len(table.TopSideBearings) > 0 {
+		m.TopSideBearing = table.TopSideBearings[len(table.TopSideBearings)-1]
+	}
+	return m
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableVmtx) Bytes() []byte {
+	buf := make([]byte, 4*len(table.Metrics)+2*len(table.TopSideBearings))
+	for i, m := range table.Metrics {
+		binary.BigEndian.PutUint16(buf[4*i:], m.AdvanceHeight)
+		binary.BigEndian.PutUint16(buf[4*i+2:], uint16(m.TopSideBearing))
+	}
+	tsb := buf[4*len(table.Metrics):]
+	for i, v := range table.TopSideBearings {
+		binary.BigEndian.PutUint16(tsb[2*i:], uint16(v))
+	}
+	return buf
+}
+
+// NewTableVmtx returns a 'vmtx' table containing metrics. Glyphs at the
+// end with the same advance only need a top side bearing.
+func NewTableVmtx(metrics []VMetric) *TableVmtx {
+	numLong := len(metrics)
+	for numLong > 1 && metrics[numLong-1].AdvanceHeight == metrics[numLong-2].AdvanceHeight {
+		numLong--
+	}
+	vmtx := &TableVmtx{Metrics: metrics[:numLong]}
+	for _, m := range metrics[numLong:] {
+		vmtx.TopSideBearings = append(vmtx.TopSideBearings, m.TopSideBearing)
+	}
+	return vmtx
+}
+
+// VmtxTable returns the table corresponding to the 'vmtx' tag.
+// It requires the 'vhea' and 'maxp' tables to parse.
+func (font *Font) VmtxTable() (*TableVmtx, error) {
+	s, found := font.tables[TagVmtx]
+	if !found {
+		return nil, ErrMissingTable
+	}
+	if t, ok := s.table.(*TableVmtx); ok {
+		return t, nil
+	}
+
+	vhea, err := font.VheaTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagVhea, err)
+	}
+	maxp, err := font.MaxpTable()
+	if err != nil {
+		return nil, fmt.Errorf("parsing %q: %w", TagMaxp, err)
+	}
+
+	buf, err := font.tableBytes(s)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := parseTableVmtx(TagVmtx, buf, int(vhea.NumOfLongVerMetrics), int(maxp.NumGlyphs))
+	if err != nil {
+		return nil, err
+	}
+	s.table = t
+	return t, nil
+}
+
+// AdvanceHeight returns the vertical advance of a glyph. If coords is not nil,
+// it contains the normalized coordinates at which the variations from 'VVAR'
+// are applied, or from 'gvar' if the font has no 'VVAR' table.
+// Fonts without a 'vmtx' table use the distance between the typographic
+// ascender and descender in 'OS/2' for every glyph.
+func (font *Font) AdvanceHeight(glyph GlyphID, coords []F2Dot14) (float64, error) {
+	if !font.HasTable(TagVmtx) {
+		ascender, err := font.Metric(MetricHorizontalAscender, coords)
+		if err != nil {
+			return 0, err
+		}
+		descender, err := font.Metric(MetricHorizontalDescender, coords)
+		if err != nil {
+			return 0, err
+		}
+		return ascender - descender, nil
+	}
+
+	vmtx, err := font.VmtxTable()
+	if err != nil {
+		return 0, fmt.Errorf("parsing %q: %w", TagVmtx, err)
+	}
+	advance := float64(vmtx.Metric(glyph).AdvanceHeight)
+	if coords == nil {
+		return advance, nil
+	}
+
+	if font.HasTable(TagVvar) {
+		vvar, err := font.VvarTable()
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: %w", TagVvar, err)
+		}
+		return advance + vvar.AdvanceDelta(glyph, coords), nil
+	}
+	if font.HasTable(TagGvar) && font.HasTable(TagGlyf) {
+		outline, err := font.GlyphOutline(glyph, coords)
+		if err != nil {
+			return 0, err
+		}
+		return outline.AdvanceHeight, nil
+	}
+	return advance, nil
+}
+
+// VerticalOrigin returns the y coordinate of the vertical origin of a glyph,
+// the point at the top of the glyph from which it is laid out in vertical text.
+// It is read from 'VORG' if present, and otherwise is the top side bearing
+// from 'vmtx' above the top of the TrueType outline. Fonts without either
+// use the typographic ascender in 'OS/2'. If coords is not nil, it contains
+// the normalized coordinates at which variations are applied.
+func (font *Font) VerticalOrigin(glyph GlyphID, coords []F2Dot14) (float64, error) {
+	if font.HasTable(TagVORG) {
+		vorg, err := font.VORGTable()
+		if err != nil {
+			return 0, fmt.Errorf("parsing %q: %w", TagVORG, err)
+		}
+		origin := float64(vorg.Origin(glyph))
+		if coords != nil && font.HasTable(TagVvar) {
+			vvar, err := font.VvarTable()
+			if err != nil {
+				return 0, fmt.Errorf("parsing %q: %w", TagVvar, err)
+			}
+			delta, _ := vvar.VOrgDelta(glyph, coords)
+			origin += delta
+		}
+		return origin, nil
+	}
+
+	if font.HasTable(TagVmtx) && font.HasTable(TagGlyf) {
+		outline, err := font.GlyphOutline(glyph, coords)
+		if err != nil {
+			return 0, err
+		}
+		return outline.VerticalOrigin, nil
+	}
+
+	return font.Metric(MetricHorizontalAscender, coords)
+}
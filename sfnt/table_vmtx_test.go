@@ -0,0 +1,118 @@
+package sfnt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVmtxRoundTrip(t *testing.T) {
+	want := &TableVmtx{
+		baseTable:       baseTable(TagVmtx),
+		Metrics:         []VMetric{{AdvanceHeight: 1000, TopSideBearing: 20}, {AdvanceHeight: 900, TopSideBearing: -10}},
+		TopSideBearings: []int16{30},
+	}
+
+	vhea := &TableVhea{baseTable: baseTable(TagVhea)}
+	vhea.NumOfLongVerMetrics = 2
+	maxp := &TableMaxp{baseTable: baseTable(TagMaxp)}
+	maxp.NumGlyphs = 3
+
+	font := New(TypeTrueType)
+	font.AddTable(TagMaxp, maxp)
+	font.AddTable(TagVhea, vhea)
+	font.AddTable(TagVmtx, &unparsedTable{baseTable(TagVmtx), want.Bytes()})
+
+	vmtx, err := font.VmtxTable()
+	if err != nil {
+		t.Fatalf("VmtxTable() err = %q, want nil", err)
+	}
+	if !reflect.DeepEqual(vmtx, want) {
+		t.Errorf("VmtxTable() = %+v, want %+v", vmtx, want)
+	}
+	if got, want := vmtx.Metric(2), (VMetric{AdvanceHeight: 900, TopSideBearing: 30}); got != want {
+		t.Errorf("Metric(2) = %+v, want %+v", got, want)
+	}
+
+	table, err := ParseTable(TagVhea, vhea.Bytes())
+	if err != nil {
+		t.Fatalf("ParseTable(%q) err = %q, want nil", TagVhea, err)
+	}
+	if !reflect.DeepEqual(table, vhea) {
+		t.Errorf("ParseTable(%q) = %+v, want %+v", TagVhea, table, vhea)
+	}
+}
+
+func TestVORG(t *testing.T) {
+	want := &TableVORG{
+		baseTable:          baseTable(TagVORG),
+		DefaultVertOriginY: 880,
+		Origins:            []VertOrigin{{Glyph: 2, VertOriginY: 900}, {Glyph: 5, VertOriginY: -10}},
+	}
+	table, err := ParseTable(TagVORG, want.Bytes())
+	if err != nil {
+		t.Fatalf("ParseTable(%q) err = %q, want nil", TagVORG, err)
+	}
+	vorg := table.(*TableVORG)
+	if !reflect.DeepEqual(vorg, want) {
+		t.Errorf("ParseTable(%q) = %+v, want %+v", TagVORG, vorg, want)
+	}
+
+	for glyph, want := range map[GlyphID]int16{0: 880, 2: 900, 3: 880, 5: -10, 6: 880} {
+		if got := vorg.Origin(glyph); got != want {
+			t.Errorf("Origin(%d) = %d, want %d", glyph, got, want)
+		}
+	}
+
+	unsorted := &TableVORG{Origins: []VertOrigin{{Glyph: 5}, {Glyph: 2}}}
+	if _, err := ParseTable(TagVORG, unsorted.Bytes()); err == nil {
+		t.Errorf("ParseTable(%q) with unsorted glyphs err = nil, want error", TagVORG)
+	}
+}
+
+func TestVerticalMetrics(t *testing.T) {
+	os2 := &TableOS2{baseTable: baseTable(TagOS2)}
+	os2.STypoAscender, os2.STypoDescender = 800, -200
+
+	font := testGvarFont(t)
+	font.AddTable(TagOS2, os2)
+
+	// Without 'vmtx', the metrics come from 'OS/2'.
+	if got, err := font.AdvanceHeight(0, nil); err != nil || got != 1000 {
+		t.Errorf("AdvanceHeight() = %v, %v, want 1000", got, err)
+	}
+	if got, err := font.VerticalOrigin(0, nil); err != nil || got != 800 {
+		t.Errorf("VerticalOrigin() = %v, %v, want 800", got, err)
+	}
+
+	vhea := &TableVhea{baseTable: baseTable(TagVhea)}
+	vhea.Ascent, vhea.Descent, vhea.NumOfLongVerMetrics = 500, -500, 1
+	font.AddTable(TagVhea, vhea)
+	font.AddTable(TagVmtx, &TableVmtx{baseTable: baseTable(TagVmtx), Metrics: []VMetric{{AdvanceHeight: 900, TopSideBearing: 20}}})
+
+	// The square glyph's top is at 100, or 150 at wght=1.0, while the phantom
+	// points have no deltas, so its top side bearing shrinks.
+	tests := []struct {
+		coords          []F2Dot14
+		advance, origin float64
+	}{
+		{nil, 900, 120},
+		{[]F2Dot14{1 << 14}, 900, 120},
+	}
+	for _, test := range tests {
+		if got, err := font.AdvanceHeight(0, test.coords); err != nil || got != test.advance {
+			t.Errorf("AdvanceHeight(0, %v) = %v, %v, want %v", test.coords, got, err, test.advance)
+		}
+		if got, err := font.VerticalOrigin(0, test.coords); err != nil || got != test.origin {
+			t.Errorf("VerticalOrigin(0, %v) = %v, %v, want %v", test.coords, got, err, test.origin)
+		}
+	}
+
+	if got, err := font.Metric(MetricVerticalDescender, nil); err != nil || got != -500 {
+		t.Errorf("Metric(%q) = %v, %v, want -500", MetricVerticalDescender, got, err)
+	}
+
+	font.AddTable(TagVORG, &TableVORG{baseTable: baseTable(TagVORG), DefaultVertOriginY: 880})
+	if got, err := font.VerticalOrigin(0, nil); err != nil || got != 880 {
+		t.Errorf("VerticalOrigin() with 'VORG' = %v, %v, want 880", got, err)
+	}
+}
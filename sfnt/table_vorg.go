@@ -0,0 +1,93 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// TableVORG contains the y coordinates of the vertical origins of glyphs in
+// fonts with PostScript outlines.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/vorg
+type TableVORG struct {
+	baseTable
+
+	// DefaultVertOriginY is the vertical origin of glyphs not in Origins.
+	DefaultVertOriginY int16
+	// Origins contains the glyphs with a different vertical origin, sorted by glyph ID.
+	Origins []VertOrigin
+}
+
+// VertOrigin is the y coordinate of the vertical origin of a glyph.
+type VertOrigin struct {
+	Glyph       GlyphID
+	VertOriginY int16
+}
+
+const vorgHeaderSize = 8
+
+func parseTableVORG(tag Tag, buf []byte, _ *Options) (Table, error) {
+	if len(buf) < vorgHeaderSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if major := binary.BigEndian.Uint16(buf); major != 1 {
+		return nil, fmt.Errorf("unsupported %q version %d", tag, major)
+	}
+	count := int(binary.BigEndian.Uint16(buf[6:]))
+	if vorgHeaderSize+4*count > len(buf) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	table := &TableVORG{
+		baseTable:          baseTable(tag),
+		DefaultVertOriginY: int16(binary.BigEndian.Uint16(buf[4:])),
+		Origins:            make([]VertOrigin, count),
+	}
+	for i := range table.Origins {
+		b := buf[vorgHeaderSize+4*i:]
+		table.Origins[i] = VertOrigin{
+			Glyph:       GlyphID(binary.BigEndian.Uint16(b)),
+			VertOriginY: int16(binary.BigEndian.Uint16(b[2:])),
+		}
+		if i > 0 && table.Origins[i].Glyph <= table.Origins[i-1].Glyph {
+			return nil, fmt.Errorf("%q glyphs are not sorted", tag)
+		}
+	}
+
+	return table, nil
+}
+
+// Bytes returns the byte representation of this table.
+func (table *TableVORG) Bytes() []byte {
+	buf := make([]byte, vorgHeaderSize+4*len(table.Origins))
+	binary.BigEndian.PutUint16(buf, 1)
+	binary.BigEndian.PutUint16(buf[4:], uint16(table.DefaultVertOriginY))
+	binary.BigEndian.PutUint16(buf[6:], uint16(len(table.Origins)))
+	for i, o := range table.Origins {
+		b := buf[vorgHeaderSize+4*i:]
+		binary.BigEndian.PutUint16(b, uint16(o.Glyph))
+		binary.BigEndian.PutUint16(b[2:], uint16(o.VertOriginY))
+	}
+	return buf
+}
+
+// Origin returns the y coordinate of the vertical origin of a glyph.
+func (table *TableVORG) Origin(glyph GlyphID) int16 {
+	i := sort.Search(len(table.Origins), func(i int) bool {
+		return table.Origins[i].Glyph >= glyph
+	})
+	if i < len(table.Origins) && table.Origins[i].Glyph == glyph {
+		return table.Origins[i].VertOriginY
+	}
+	return table.DefaultVertOriginY
+}
+
+// VORGTable returns the table corresponding to the 'VORG' tag.
+func (font *Font) VORGTable() (*TableVORG, error) {
+	t, err := font.Table(TagVORG)
+	if err != nil {
+		return nil, err
+	}
+	return t.(*TableVORG), nil
+}
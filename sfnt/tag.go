@@ -24,6 +24,76 @@ var (
 	TagGpos = MustNamedTag("GPOS")
 	// TagGsub represents the 'GSUB' table, which contains Glyph Substitution features
 	TagGsub = MustNamedTag("GSUB")
+	// TagCmap represents the 'cmap' table, which maps characters to glyphs
+	TagCmap = MustNamedTag("cmap")
+	// TagLoca represents the 'loca' table, which contains the offsets of TrueType glyphs
+	TagLoca = MustNamedTag("loca")
+	// TagGlyf represents the 'glyf' table, which contains TrueType glyph outlines
+	TagGlyf = MustNamedTag("glyf")
+	// TagFvar represents the 'fvar' table, which describes the axes of a variable font
+	TagFvar = MustNamedTag("fvar")
+	// TagAvar represents the 'avar' table, which modifies the normalization of axis coordinates
+	TagAvar = MustNamedTag("avar")
+	// TagGvar represents the 'gvar' table, which contains the variations of TrueType outlines
+	TagGvar = MustNamedTag("gvar")
+	// TagCvar represents the 'cvar' table, which contains the variations of the TrueType control values
+	TagCvar = MustNamedTag("cvar")
+	// TagHvar represents the 'HVAR' table, which contains the variations of horizontal glyph metrics
+	TagHvar = MustNamedTag("HVAR")
+	// TagVvar represents the 'VVAR' table, which contains the variations of vertical glyph metrics
+	TagVvar = MustNamedTag("VVAR")
+	// TagMvar represents the 'MVAR' table, which contains the variations of font-wide metrics
+	TagMvar = MustNamedTag("MVAR")
+	// TagSTAT represents the 'STAT' table, which contains the style attributes of a font family
+	TagSTAT = MustNamedTag("STAT")
+	// TagCOLR represents the 'COLR' table, which contains color glyphs
+	TagCOLR = MustNamedTag("COLR")
+	// TagCPAL represents the 'CPAL' table, which contains the color palettes of color glyphs
+	TagCPAL = MustNamedTag("CPAL")
+	// TagSVG represents the 'SVG ' table, which contains color glyphs as SVG documents
+	TagSVG = MustNamedTag("SVG ")
+	// TagSbix represents the 'sbix' table, which contains color glyphs as images
+	TagSbix = MustNamedTag("sbix")
+	// TagCBDT represents the 'CBDT' table, which contains color bitmap glyphs
+	TagCBDT = MustNamedTag("CBDT")
+	// TagCBLC represents the 'CBLC' table, which contains the locations of color bitmap glyphs
+	TagCBLC = MustNamedTag("CBLC")
+	// TagEBDT represents the 'EBDT' table, which contains bitmap glyphs
+	TagEBDT = MustNamedTag("EBDT")
+	// TagEBLC represents the 'EBLC' table, which contains the locations of bitmap glyphs
+	TagEBLC = MustNamedTag("EBLC")
+	// TagVhea represents the 'vhea' table, which contains the vertical header
+	TagVhea = MustNamedTag("vhea")
+	// TagVmtx represents the 'vmtx' table, which contains the vertical metrics
+	TagVmtx = MustNamedTag("vmtx")
+	// TagVORG represents the 'VORG' table, which contains the vertical origins of PostScript glyphs
+	TagVORG = MustNamedTag("VORG")
+	// TagBASE represents the 'BASE' table, which contains the baselines of scripts
+	TagBASE = MustNamedTag("BASE")
+	// TagMATH represents the 'MATH' table, which contains the layout information of mathematical formulas
+	TagMATH = MustNamedTag("MATH")
+	// TagJSTF represents the 'JSTF' table, which contains the lookups used to justify text
+	TagJSTF = MustNamedTag("JSTF")
+	// TagCvt represents the 'cvt ' table, which contains the control values of TrueType hinting
+	TagCvt = MustNamedTag("cvt ")
+	// TagFpgm represents the 'fpgm' table, which contains the TrueType hinting functions
+	TagFpgm = MustNamedTag("fpgm")
+	// TagPrep represents the 'prep' table, which contains the TrueType control value program
+	TagPrep = MustNamedTag("prep")
+	// TagGasp represents the 'gasp' table, which contains the preferred rasterization at each size
+	TagGasp = MustNamedTag("gasp")
+	// TagCFF represents the 'CFF ' table, which contains PostScript outlines in the Compact Font Format
+	TagCFF = MustNamedTag("CFF ")
+	// TagCFF2 represents the 'CFF2' table, which contains variable PostScript outlines in version 2 of the Compact Font Format
+	TagCFF2 = MustNamedTag("CFF2")
+	// TagHdmx represents the 'hdmx' table, which contains the hinted advance widths of glyphs at some sizes
+	TagHdmx = MustNamedTag("hdmx")
+	// TagLTSH represents the 'LTSH' table, which contains the sizes from which glyph advances scale linearly
+	TagLTSH = MustNamedTag("LTSH")
+	// TagVDMX represents the 'VDMX' table, which contains the hinted vertical extents of the font at some sizes
+	TagVDMX = MustNamedTag("VDMX")
+	// TagPost represents the 'post' table, which contains PostScript information
+	TagPost = MustNamedTag("post")
 
 	// TypeTrueType is the first four bytes of an OpenType file containing a TrueType font
 	TypeTrueType = Tag{0x00010000}
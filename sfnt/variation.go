@@ -0,0 +1,449 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+)
+
+// F2Dot14 is a 2.14 fixed-point number, used for normalized coordinates in
+// variable fonts. The range -1.0 to 1.0 is represented by -16384 to 16384.
+type F2Dot14 int16
+
+// NewF2Dot14 returns the F2Dot14 nearest to f, clamped to the representable range.
+func NewF2Dot14(f float64) F2Dot14 {
+	v := math.Round(f * (1 << 14))
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	}
+	if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return F2Dot14(v)
+}
+
+// Float returns the value as a float.
+func (f F2Dot14) Float() float64 {
+	return float64(f) / (1 << 14)
+}
+
+// String returns the value formatted as a decimal number.
+func (f F2Dot14) String() string {
+	return fmt.Sprintf("%g", f.Float())
+}
+
+var errInvalidVariationStore = errors.New("invalid item variation store")
+
+// VariationIndex identifies a delta set in an ItemVariationStore.
+type VariationIndex struct {
+	Outer uint16 // Outer is the index of the ItemVariationData.
+	Inner uint16 // Inner is the index of the delta set within the ItemVariationData.
+}
+
+// DeltaSetIndexMap maps items, such as glyphs or axes, to delta sets in an ItemVariationStore.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/otvarcommonformats#associating-target-items-to-variation-data
+type DeltaSetIndexMap struct {
+	Map []VariationIndex
+}
+
+// Index returns the delta set for item i. Items beyond the end of the map use
+// the last entry.
+func (m *DeltaSetIndexMap) Index(i int) VariationIndex {
+	if len(m.Map) == 0 {
+		return VariationIndex{Outer: uint16(i >> 16), Inner: uint16(i)}
+	}
+	if i >= len(m.Map) {
+		i = len(m.Map) - 1
+	}
+	return m.Map[i]
+}
+
+func parseDeltaSetIndexMap(buf []byte) (*DeltaSetIndexMap, error) {
+	if len(buf) < 4 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	format, entryFormat := buf[0], buf[1]
+	var count, start int
+	switch format {
+	case 0:
+		count, start = int(binary.BigEndian.Uint16(buf[2:])), 4
+	case 1:
+		if len(buf) < 6 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		count, start = int(binary.BigEndian.Uint32(buf[2:])), 6
+	default:
+		return nil, fmt.Errorf("unsupported delta set index map format %d", format)
+	}
+
+	entrySize := int(entryFormat&0x30)>>4 + 1
+	innerBits := uint(entryFormat&0x0F) + 1
+	if len(buf) < start+count*entrySize {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	m := &DeltaSetIndexMap{Map: make([]VariationIndex, count)}
+	for i := range m.Map {
+		var entry uint32
+		for _, b := range buf[start+i*entrySize : start+(i+1)*entrySize] {
+			entry = entry<<8 | uint32(b)
+		}
+		m.Map[i] = VariationIndex{
+			Outer: uint16(entry >> innerBits),
+			Inner: uint16(entry & (1<<innerBits - 1)),
+		}
+	}
+	return m, nil
+}
+
+// Bytes returns the byte representation of the map, using the smallest entries
+// that can represent every index.
+func (m *DeltaSetIndexMap) Bytes() []byte {
+	var maxOuter, maxInner uint16
+	for _, index := range m.Map {
+		if index.Outer > maxOuter {
+			maxOuter = index.Outer
+		}
+		if index.Inner > maxInner {
+			maxInner = index.Inner
+		}
+	}
+	innerBits := uint(bits.Len16(maxInner))
+	if innerBits == 0 {
+		innerBits = 1
+	}
+	entrySize := (innerBits + uint(bits.Len16(maxOuter)) + 7) / 8
+
+	var buf []byte
+	if len(m.Map) > 0xFFFF {
+		buf = make([]byte, 6, 6+int(entrySize)*len(m.Map))
+		buf[0] = 1
+		binary.BigEndian.PutUint32(buf[2:], uint32(len(m.Map)))
+	} else {
+		buf = make([]byte, 4, 4+int(entrySize)*len(m.Map))
+		binary.BigEndian.PutUint16(buf[2:], uint16(len(m.Map)))
+	}
+	buf[1] = byte((entrySize-1)<<4 | (innerBits - 1))
+
+	for _, index := range m.Map {
+		entry := uint32(index.Outer)<<innerBits | uint32(index.Inner)
+		for i := int(entrySize) - 1; i >= 0; i-- {
+			buf = append(buf, byte(entry>>(8*uint(i))))
+		}
+	}
+	return buf
+}
+
+// RegionAxis is the range of a variation region along a single axis.
+type RegionAxis struct {
+	Start, Peak, End F2Dot14
+}
+
+// scalar returns the contribution of the region along this axis at coord.
+func (r RegionAxis) scalar(coord F2Dot14) float64 {
+	switch {
+	case r.Start > r.Peak || r.Peak > r.End:
+		return 1
+	case r.Start < 0 && r.End > 0 && r.Peak != 0:
+		return 1
+	case r.Peak == 0 || coord == r.Peak:
+		return 1
+	case coord <= r.Start || coord >= r.End:
+		return 0
+	case coord < r.Peak:
+		return float64(coord-r.Start) / float64(r.Peak-r.Start)
+	default:
+		return float64(r.End-coord) / float64(r.End-r.Peak)
+	}
+}
+
+// VariationRegion is a region of the design space, with one RegionAxis per axis.
+type VariationRegion []RegionAxis
+
+// Scalar returns how much deltas associated with the region apply at coords.
+func (region VariationRegion) Scalar(coords []F2Dot14) float64 {
+	scalar := 1.0
+	for i, axis := range region {
+		var coord F2Dot14
+		if i < len(coords) {
+			coord = coords[i]
+		}
+		scalar *= axis.scalar(coord)
+		if scalar == 0 {
+			break
+		}
+	}
+	return scalar
+}
+
+// ItemVariationData contains delta sets that apply to a subset of the regions.
+type ItemVariationData struct {
+	RegionIndexes []uint16
+	// Deltas contains one delta set per item, with a delta for each of the
+	// RegionIndexes.
+	Deltas [][]int32
+}
+
+// ItemVariationStore contains deltas used to vary values, such as metrics,
+// in a variable font.
+// https://docs.microsoft.com/en-us/typography/opentype/spec/otvarcommonformats#item-variation-store
+type ItemVariationStore struct {
+	Regions []VariationRegion
+	Data    []ItemVariationData
+}
+
+// Delta returns the interpolated delta for index at the normalized coords.
+func (store *ItemVariationStore) Delta(index VariationIndex, coords []F2Dot14) float64 {
+	if int(index.Outer) >= len(store.Data) {
+		return 0
+	}
+	data := &store.Data[index.Outer]
+	if int(index.Inner) >= len(data.Deltas) {
+		return 0
+	}
+
+	var delta float64
+	for i, d := range data.Deltas[index.Inner] {
+		if d == 0 {
+			continue
+		}
+		region := int(data.RegionIndexes[i])
+		if region < len(store.Regions) {
+			delta += float64(d) * store.Regions[region].Scalar(coords)
+		}
+	}
+	return delta
+}
+
+// ParseItemVariationStore parses an ItemVariationStore, as embedded in tables
+// such as 'GDEF' that are not otherwise parsed by this package.
+func ParseItemVariationStore(buf []byte) (*ItemVariationStore, error) {
+	if len(buf) < 8 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if format := binary.BigEndian.Uint16(buf); format != 1 {
+		return nil, fmt.Errorf("unsupported item variation store format %d", format)
+	}
+
+	regionsOffset := int(binary.BigEndian.Uint32(buf[2:]))
+	dataCount := int(binary.BigEndian.Uint16(buf[6:]))
+	if len(buf) < 8+4*dataCount {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	store := &ItemVariationStore{
+		Data: make([]ItemVariationData, dataCount),
+	}
+
+	var err error
+	if store.Regions, err = parseVariationRegionList(buf, regionsOffset); err != nil {
+		return nil, err
+	}
+
+	for i := range store.Data {
+		offset := int(binary.BigEndian.Uint32(buf[8+4*i:]))
+		if offset == 0 || offset >= len(buf) {
+			return nil, errInvalidVariationStore
+		}
+		if err := store.Data[i].parse(buf[offset:], len(store.Regions)); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+func parseVariationRegionList(buf []byte, offset int) ([]VariationRegion, error) {
+	if offset == 0 || offset+4 > len(buf) {
+		return nil, errInvalidVariationStore
+	}
+	b := buf[offset:]
+
+	axisCount := int(binary.BigEndian.Uint16(b))
+	regionCount := int(binary.BigEndian.Uint16(b[2:]))
+	if len(b) < 4+regionCount*axisCount*6 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	regions := make([]VariationRegion, regionCount)
+	p := 4
+	for i := range regions {
+		regions[i] = make(VariationRegion, axisCount)
+		for j := range regions[i] {
+			regions[i][j] = RegionAxis{
+				Start: F2Dot14(binary.BigEndian.Uint16(b[p:])),
+				Peak:  F2Dot14(binary.BigEndian.Uint16(b[p+2:])),
+				End:   F2Dot14(binary.BigEndian.Uint16(b[p+4:])),
+			}
+			p += 6
+		}
+	}
+	return regions, nil
+}
+
+// longWords is set in wordDeltaCount when deltas are stored as int32 and int16,
+// rather than int16 and int8.
+const longWords = 0x8000
+
+func (data *ItemVariationData) parse(b []byte, numRegions int) error {
+	if len(b) < 6 {
+		return io.ErrUnexpectedEOF
+	}
+
+	itemCount := int(binary.BigEndian.Uint16(b))
+	wordDeltaCount := binary.BigEndian.Uint16(b[2:])
+	regionIndexCount := int(binary.BigEndian.Uint16(b[4:]))
+
+	wordCount := int(wordDeltaCount &^ longWords)
+	wordSize, shortSize := 2, 1
+	if wordDeltaCount&longWords != 0 {
+		wordSize, shortSize = 4, 2
+	}
+	if wordCount > regionIndexCount {
+		return errInvalidVariationStore
+	}
+
+	rowSize := wordCount*wordSize + (regionIndexCount-wordCount)*shortSize
+	if len(b) < 6+2*regionIndexCount+itemCount*rowSize {
+		return io.ErrUnexpectedEOF
+	}
+
+	data.RegionIndexes = make([]uint16, regionIndexCount)
+	for i := range data.RegionIndexes {
+		data.RegionIndexes[i] = binary.BigEndian.Uint16(b[6+2*i:])
+		if int(data.RegionIndexes[i]) >= numRegions {
+			return errInvalidVariationStore
+		}
+	}
+
+	data.Deltas = make([][]int32, itemCount)
+	p := 6 + 2*regionIndexCount
+	for i := range data.Deltas {
+		deltas := make([]int32, regionIndexCount)
+		for j := range deltas {
+			size := shortSize
+			if j < wordCount {
+				size = wordSize
+			}
+			switch size {
+			case 1:
+				deltas[j] = int32(int8(b[p]))
+			case 2:
+				deltas[j] = int32(int16(binary.BigEndian.Uint16(b[p:])))
+			case 4:
+				deltas[j] = int32(binary.BigEndian.Uint32(b[p:]))
+			}
+			p += size
+		}
+		data.Deltas[i] = deltas
+	}
+	return nil
+}
+
+// Bytes returns the byte representation of the store.
+func (store *ItemVariationStore) Bytes() []byte {
+	axisCount := 0
+	if len(store.Regions) > 0 {
+		axisCount = len(store.Regions[0])
+	}
+
+	regionsOffset := 8 + 4*len(store.Data)
+	buf := make([]byte, regionsOffset, regionsOffset+4+6*axisCount*len(store.Regions))
+	binary.BigEndian.PutUint16(buf, 1)
+	binary.BigEndian.PutUint32(buf[2:], uint32(regionsOffset))
+	binary.BigEndian.PutUint16(buf[6:], uint16(len(store.Data)))
+
+	buf = appendUint16(buf, uint16(axisCount))
+	buf = appendUint16(buf, uint16(len(store.Regions)))
+	for _, region := range store.Regions {
+		for _, axis := range region {
+			buf = appendUint16(buf, uint16(axis.Start))
+			buf = appendUint16(buf, uint16(axis.Peak))
+			buf = appendUint16(buf, uint16(axis.End))
+		}
+	}
+
+	for i := range store.Data {
+		binary.BigEndian.PutUint32(buf[8+4*i:], uint32(len(buf)))
+		buf = store.Data[i].appendBytes(buf)
+	}
+	return buf
+}
+
+// appendBytes appends the ItemVariationData to buf. The deltas are stored in
+// the smallest size that fits each region, with the larger regions first.
+func (data *ItemVariationData) appendBytes(buf []byte) []byte {
+	// size returns the number of bytes needed for the deltas of column j.
+	size := func(j int) int {
+		n := 1
+		for _, deltas := range data.Deltas {
+			d := deltas[j]
+			switch {
+			case d < math.MinInt16 || d > math.MaxInt16:
+				return 4
+			case d < math.MinInt8 || d > math.MaxInt8:
+				n = 2
+			}
+		}
+		return n
+	}
+
+	sizes := make([]int, len(data.RegionIndexes))
+	long := false
+	for j := range sizes {
+		sizes[j] = size(j)
+		long = long || sizes[j] == 4
+	}
+
+	// Long words store 32 and 16 bit deltas, otherwise 16 and 8 bit deltas.
+	wordSize := 2
+	if long {
+		wordSize = 4
+	}
+	var order []int
+	for j, n := range sizes {
+		if n*2 > wordSize {
+			order = append(order, j)
+		}
+	}
+	wordCount := len(order)
+	for j, n := range sizes {
+		if n*2 <= wordSize {
+			order = append(order, j)
+		}
+	}
+
+	wordDeltaCount := uint16(wordCount)
+	if long {
+		wordDeltaCount |= longWords
+	}
+	buf = appendUint16(buf, uint16(len(data.Deltas)))
+	buf = appendUint16(buf, wordDeltaCount)
+	buf = appendUint16(buf, uint16(len(order)))
+	for _, j := range order {
+		buf = appendUint16(buf, data.RegionIndexes[j])
+	}
+
+	for _, deltas := range data.Deltas {
+		for k, j := range order {
+			n := wordSize / 2
+			if k < wordCount {
+				n = wordSize
+			}
+			switch n {
+			case 1:
+				buf = append(buf, byte(int8(deltas[j])))
+			case 2:
+				buf = appendUint16(buf, uint16(int16(deltas[j])))
+			case 4:
+				buf = appendUint16(buf, uint16(uint32(deltas[j])>>16))
+				buf = appendUint16(buf, uint16(deltas[j]))
+			}
+		}
+	}
+	return buf
+}
@@ -22,19 +22,7 @@ var outputOrder = map[Tag]int{
 // font contains TrueType glyphs.
 func (font *Font) WriteOTF(w io.Writer) (n int, err error) {
 
-	todo := font.Tags()
-	sort.Slice(todo, func(i, j int) bool {
-		iScore, ok := outputOrder[todo[i]]
-		if !ok {
-			iScore = int(todo[i].Number)
-		}
-		jScore, ok := outputOrder[todo[j]]
-		if !ok {
-			jScore = int(todo[j].Number)
-		}
-
-		return iScore < jScore
-	})
+	todo := font.outputTags()
 
 	headTable, err := font.HeadTable()
 	if err != nil {
@@ -125,6 +113,29 @@ func (font *Font) WriteOTF(w io.Writer) (n int, err error) {
 	return 0, nil
 }
 
+// outputTags returns the tags of the font in the order they should be serialized.
+func (font *Font) outputTags() []Tag {
+	tags := font.Tags()
+	sort.Slice(tags, func(i, j int) bool {
+		iScore, ok := outputOrder[tags[i]]
+		if !ok {
+			iScore = int(tags[i].Number)
+		}
+		jScore, ok := outputOrder[tags[j]]
+		if !ok {
+			jScore = int(tags[j].Number)
+		}
+
+		return iScore < jScore
+	})
+	return tags
+}
+
+// padding returns the number of zero bytes needed to align length to 4 bytes.
+func padding(length int) int {
+	return (4 - length%4) % 4
+}
+
 func checkSum(buffer []byte) uint32 {
 	total := uint32(0)
 
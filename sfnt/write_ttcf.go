@@ -0,0 +1,180 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ttcfDSIGFields follow the offset table in a version 2.0 TrueType Collection header.
+type ttcfDSIGFields struct {
+	DsigTag    uint32
+	DsigLength uint32
+	DsigOffset uint32
+}
+
+const ttcfHeaderV1Length = 12
+const ttcfDSIGFieldsLength = 12
+
+// collectionFragment is a table written to the collection. Head tables are
+// kept as tables, as their bytes depend on the checksum of the owning font.
+type collectionFragment struct {
+	bytes []byte
+	head  *TableHead
+}
+
+// collectionMember holds the serialization state of one font in a collection.
+type collectionMember struct {
+	font    *Font
+	tags    []Tag
+	head    *TableHead
+	entries []directoryEntry
+}
+
+// ErrEmptyCollection is returned by WriteCollection when no fonts are given.
+var ErrEmptyCollection = errors.New("collection must contain at least one font")
+
+// WriteCollection serializes fonts into a TrueType Collection (ttcf version 2.0)
+// suitable for writing to a file such as *.ttc or *.otc.
+// Tables that are byte-identical across fonts are only written once and shared
+// between the fonts. Each font gets its own 'head' table so that its checksum
+// adjustment can be computed independently.
+func WriteCollection(w io.Writer, fonts []*Font) (n int, err error) {
+	if len(fonts) == 0 {
+		return 0, ErrEmptyCollection
+	}
+
+	members := make([]*collectionMember, len(fonts))
+
+	offset := ttcfHeaderV1Length + 4*len(fonts) + ttcfDSIGFieldsLength
+	fontOffsets := make([]uint32, len(fonts))
+	for i, font := range fonts {
+		head, err := font.HeadTable()
+		if err != nil {
+			return 0, err
+		}
+
+		tags := font.outputTags()
+		members[i] = &collectionMember{
+			font:    font,
+			tags:    tags,
+			head:    head,
+			entries: make([]directoryEntry, len(tags)),
+		}
+
+		fontOffsets[i] = uint32(offset)
+		offset += otfHeaderLength + directoryEntryLength*len(tags)
+	}
+
+	// Lay out the table data, sharing identical tables between fonts.
+	var fragments []collectionFragment
+	shared := make(map[string]uint32)
+	for _, m := range members {
+		for i, tag := range m.tags {
+			var fragment []byte
+			var head *TableHead
+			if tag == TagHead {
+				m.head.ClearExpectedChecksum()
+				fragment = m.head.Bytes()
+				head = m.head
+			} else {
+				t, err := m.font.Table(tag)
+				if err != nil {
+					return 0, err
+				}
+				fragment = t.Bytes()
+
+				if tableOffset, found := shared[string(fragment)]; found {
+					m.entries[i] = directoryEntry{
+						Tag:      tag,
+						CheckSum: checkSum(fragment),
+						Offset:   tableOffset,
+						Length:   uint32(len(fragment)),
+					}
+					continue
+				}
+				shared[string(fragment)] = uint32(offset)
+			}
+
+			m.entries[i] = directoryEntry{
+				Tag:      tag,
+				CheckSum: checkSum(fragment),
+				Offset:   uint32(offset),
+				Length:   uint32(len(fragment)),
+			}
+			fragments = append(fragments, collectionFragment{fragment, head})
+			offset += len(fragment) + padding(len(fragment))
+		}
+	}
+
+	// The checksum adjustment of each font covers its own offset table and tables.
+	for _, m := range members {
+		header := newOTFHeader(m.font.scalerType, uint16(len(m.tags)))
+		checksum := header.checkSum()
+		for _, entry := range m.entries {
+			checksum += entry.CheckSum + entry.checkSum()
+		}
+		m.head.SetExpectedChecksum(checksum)
+	}
+	defer func() {
+		for _, m := range members {
+			m.head.ClearExpectedChecksum()
+		}
+	}()
+
+	header := ttcfHeaderV1{
+		ScalerType:   TypeTrueTypeCollection,
+		MajorVersion: 2,
+		MinorVersion: 0,
+		NumFonts:     uint32(len(fonts)),
+	}
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return n, err
+	}
+	n += ttcfHeaderV1Length
+
+	if err := binary.Write(w, binary.BigEndian, fontOffsets); err != nil {
+		return n, err
+	}
+	n += 4 * len(fontOffsets)
+
+	if err := binary.Write(w, binary.BigEndian, ttcfDSIGFields{}); err != nil {
+		return n, err
+	}
+	n += ttcfDSIGFieldsLength
+
+	for _, m := range members {
+		header := newOTFHeader(m.font.scalerType, uint16(len(m.tags)))
+		if err := binary.Write(w, binary.BigEndian, header); err != nil {
+			return n, err
+		}
+		n += otfHeaderLength
+
+		if err := binary.Write(w, binary.BigEndian, m.entries); err != nil {
+			return n, err
+		}
+		n += directoryEntryLength * len(m.entries)
+	}
+
+	zeros := make([]byte, 3)
+	for _, f := range fragments {
+		fragment := f.bytes
+		if f.head != nil {
+			fragment = f.head.Bytes()
+		}
+
+		m, err := w.Write(fragment)
+		n += m
+		if err != nil {
+			return n, err
+		}
+
+		m, err = w.Write(zeros[:padding(len(fragment))])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
@@ -0,0 +1,82 @@
+package sfnt
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteCollectionRoundTrip checks that a written collection can be parsed
+// again, and that tables shared between the fonts are written only once.
+func TestWriteCollectionRoundTrip(t *testing.T) {
+	filename := filepath.Join("testdata", "TestTTC.ttc")
+	file, err := os.Open(filename)
+	if err != nil {
+		t.Fatalf("Failed to open %q: %s\n", filename, err)
+	}
+	defer file.Close()
+
+	fonts, err := ParseCollection(file)
+	if err != nil {
+		t.Fatalf("ParseCollection(%q) err = %q, want nil", filename, err)
+	}
+
+	var buf bytes.Buffer
+	n, err := WriteCollection(&buf, fonts)
+	if err != nil {
+		t.Fatalf("WriteCollection(%q) err = %q, want nil", filename, err)
+	}
+	if n != buf.Len() {
+		t.Errorf("WriteCollection(%q) = %d, want %d", filename, n, buf.Len())
+	}
+
+	got, err := StrictParseCollection(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("StrictParseCollection() err = %q, want nil", err)
+	}
+	if len(got) != len(fonts) {
+		t.Fatalf("StrictParseCollection() returned %d fonts, want %d", len(got), len(fonts))
+	}
+
+	for i := range fonts {
+		for _, tag := range fonts[i].Tags() {
+			if tag == TagHead {
+				continue
+			}
+			want, _ := fonts[i].Table(tag)
+			have, err := got[i].Table(tag)
+			if err != nil {
+				t.Errorf("font[%d]: Table(%q) err = %q, want nil", i, tag, err)
+				continue
+			}
+			if !bytes.Equal(want.Bytes(), have.Bytes()) {
+				t.Errorf("font[%d]: table %q differs after round trip", i, tag)
+			}
+		}
+
+		head, err := got[i].HeadTable()
+		if err != nil {
+			t.Fatalf("font[%d]: HeadTable() err = %q, want nil", i, err)
+		}
+		if head.CheckSumAdjustment == 0 {
+			t.Errorf("font[%d]: head.CheckSumAdjustment = 0, want non-zero", i)
+		}
+	}
+
+	// The fixture contains two fonts with identical 'glyf' tables.
+	glyf := MustNamedTag("glyf")
+	if got[0].tables[glyf].offset != got[1].tables[glyf].offset {
+		t.Errorf("glyf offsets = %d and %d, want shared offset",
+			got[0].tables[glyf].offset, got[1].tables[glyf].offset)
+	}
+	if got[0].tables[TagHead].offset == got[1].tables[TagHead].offset {
+		t.Errorf("head offsets are shared, want one head table per font")
+	}
+}
+
+func TestWriteCollectionEmpty(t *testing.T) {
+	if _, err := WriteCollection(&bytes.Buffer{}, nil); err != ErrEmptyCollection {
+		t.Errorf("WriteCollection(nil) err = %v, want %v", err, ErrEmptyCollection)
+	}
+}
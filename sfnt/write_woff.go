@@ -0,0 +1,136 @@
+package sfnt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+)
+
+const woffHeaderLength = 44
+const woffEntryLength = 20
+
+// woffTable is a table of a font written as WOFF or WOFF2.
+type woffTable struct {
+	tag      Tag
+	checksum uint32
+	bytes    []byte
+}
+
+// woffTables returns the tables of the font sorted by tag, as required by
+// WOFF and WOFF2, along with the size of the sfnt font they decode to. The
+// checksum adjustment in 'head' is that of the decoded font.
+func (font *Font) woffTables() ([]woffTable, int, error) {
+	head, err := font.HeadTable()
+	if err != nil {
+		return nil, 0, err
+	}
+	head.ClearExpectedChecksum()
+
+	tags := font.Tags()
+	tables := make([]woffTable, len(tags))
+	header := newOTFHeader(font.scalerType, uint16(len(tags)))
+	checksum := header.checkSum()
+	offset := otfHeaderLength + directoryEntryLength*len(tags)
+	for i, tag := range tags {
+		t, err := font.Table(tag)
+		if err != nil {
+			return nil, 0, err
+		}
+		fragment := t.Bytes()
+		entry := directoryEntry{
+			Tag:      tag,
+			CheckSum: checkSum(fragment),
+			Offset:   uint32(offset),
+			Length:   uint32(len(fragment)),
+		}
+		checksum += entry.CheckSum + entry.checkSum()
+		tables[i] = woffTable{tag: tag, checksum: entry.CheckSum, bytes: fragment}
+		offset += len(fragment) + padding(len(fragment))
+	}
+
+	head.SetExpectedChecksum(checksum)
+	for i := range tables {
+		if tables[i].tag == TagHead {
+			tables[i].bytes = head.Bytes()
+		}
+	}
+	head.ClearExpectedChecksum()
+	return tables, offset, nil
+}
+
+// WriteWOFF serializes a Font into WOFF format suitable for writing to a
+// file such as *.woff. Each table is compressed with zlib, unless that does
+// not make it smaller.
+func (font *Font) WriteWOFF(w io.Writer) (n int, err error) {
+	tables, sfntSize, err := font.woffTables()
+	if err != nil {
+		return 0, err
+	}
+	head, err := font.HeadTable()
+	if err != nil {
+		return 0, err
+	}
+
+	entries := make([]woffEntry, len(tables))
+	fragments := make([][]byte, len(tables))
+	offset := woffHeaderLength + woffEntryLength*len(tables)
+	for i, t := range tables {
+		var buf bytes.Buffer
+		z, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := z.Write(t.bytes); err != nil {
+			return 0, err
+		}
+		if err := z.Close(); err != nil {
+			return 0, err
+		}
+		fragments[i] = buf.Bytes()
+		if len(fragments[i]) >= len(t.bytes) {
+			fragments[i] = t.bytes
+		}
+
+		entries[i] = woffEntry{
+			Tag:          t.tag,
+			Offset:       uint32(offset),
+			CompLength:   uint32(len(fragments[i])),
+			OrigLength:   uint32(len(t.bytes)),
+			OrigChecksum: t.checksum,
+		}
+		offset += len(fragments[i]) + padding(len(fragments[i]))
+	}
+
+	header := woffHeader{
+		Signature:     SignatureWOFF,
+		Flavor:        font.scalerType,
+		Length:        uint32(offset),
+		NumTables:     uint16(len(tables)),
+		TotalSfntSize: uint32(sfntSize),
+		Version:       head.FontRevision,
+	}
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return n, err
+	}
+	n += woffHeaderLength
+	if err := binary.Write(w, binary.BigEndian, entries); err != nil {
+		return n, err
+	}
+	n += woffEntryLength * len(entries)
+
+	zeros := make([]byte, 3)
+	for _, fragment := range fragments {
+		m, err := w.Write(fragment)
+		n += m
+		if err != nil {
+			return n, err
+		}
+		m, err = w.Write(zeros[:padding(len(fragment))])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,171 @@
+package sfnt
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+type woff2Header struct {
+	Signature           Tag
+	Flavor              Tag
+	Length              uint32
+	NumTables           uint16
+	Reserved            uint16
+	TotalSfntSize       uint32
+	TotalCompressedSize uint32
+	Version             fixed
+	MetaOffset          uint32
+	MetaLength          uint32
+	MetaOrigLength      uint32
+	PrivOffset          uint32
+	PrivLength          uint32
+}
+
+const woff2HeaderLength = 48
+
+// woff2KnownTags are the tags that are stored in the WOFF2 table directory
+// as their index in this list.
+// https://www.w3.org/TR/WOFF2/#table_dir_format
+var woff2KnownTags = []string{
+	"cmap", "head", "hhea", "hmtx", "maxp", "name", "OS/2", "post",
+	"cvt ", "fpgm", "glyf", "loca", "prep", "CFF ", "VORG", "EBDT",
+	"EBLC", "gasp", "hdmx", "kern", "LTSH", "PCLT", "VDMX", "vhea",
+	"vmtx", "BASE", "GDEF", "GPOS", "GSUB", "EBSC", "JSTF", "MATH",
+	"CBDT", "CBLC", "COLR", "CPAL", "SVG ", "sbix", "acnt", "avar",
+	"bdat", "bloc", "bsln", "cvar", "fdsc", "feat", "fmtx", "fvar",
+	"gvar", "hsty", "just", "lcar", "mort", "morx", "opbd", "prop",
+	"trak", "Zapf", "Silf", "Glat", "Gloc", "Feat", "Sill",
+}
+
+// Flags of WOFF2 table directory entries.
+const (
+	woff2ArbitraryTag = 0x3F
+	// woff2NullTransform is the transform version of 'glyf' and 'loca'
+	// tables that are stored as they are.
+	woff2NullTransform = 0xC0
+)
+
+// brotliBlockSize is the size of the uncompressed meta-blocks written by
+// WriteWOFF2, which is the largest that fits in a 16-bit length.
+const brotliBlockSize = 1 << 16
+
+// WriteWOFF2 serializes a Font into WOFF2 format suitable for writing to a
+// file such as *.woff2.
+//
+// No Brotli encoder is available to this package, so the tables are stored
+// in uncompressed Brotli meta-blocks, and are not transformed. The result is
+// a valid WOFF2 file that is about the size of the font, and is best
+// compressed by the server, or written with WriteWOFF instead.
+func (font *Font) WriteWOFF2(w io.Writer) (n int, err error) {
+	tables, sfntSize, err := font.woffTables()
+	if err != nil {
+		return 0, err
+	}
+	head, err := font.HeadTable()
+	if err != nil {
+		return 0, err
+	}
+
+	var directory, data []byte
+	for _, t := range tables {
+		flags := byte(woff2ArbitraryTag)
+		for i, known := range woff2KnownTags {
+			if t.tag.String() == known {
+				flags = byte(i)
+			}
+		}
+		if t.tag == TagGlyf || t.tag == TagLoca {
+			flags |= woff2NullTransform
+		}
+		directory = append(directory, flags)
+		if flags&woff2ArbitraryTag == woff2ArbitraryTag {
+			directory = append(directory, byte(t.tag.Number>>24), byte(t.tag.Number>>16), byte(t.tag.Number>>8), byte(t.tag.Number))
+		}
+		directory = appendUIntBase128(directory, uint32(len(t.bytes)))
+		data = append(data, t.bytes...)
+	}
+	compressed := brotliStore(data)
+
+	length := woff2HeaderLength + len(directory) + len(compressed)
+	header := woff2Header{
+		Signature:           SignatureWOFF2,
+		Flavor:              font.scalerType,
+		Length:              uint32(length + padding(length)),
+		NumTables:           uint16(len(tables)),
+		TotalSfntSize:       uint32(sfntSize),
+		TotalCompressedSize: uint32(len(compressed)),
+		Version:             head.FontRevision,
+	}
+	if err := binary.Write(w, binary.BigEndian, header); err != nil {
+		return n, err
+	}
+	n += woff2HeaderLength
+	for _, b := range [][]byte{directory, compressed, make([]byte, padding(length))} {
+		m, err := w.Write(b)
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// appendUIntBase128 appends v in the variable-length encoding of WOFF2.
+func appendUIntBase128(buf []byte, v uint32) []byte {
+	n := 1
+	for v>>(7*n) != 0 && n < 5 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		b := byte(v>>(7*i)) & 0x7F
+		if i > 0 {
+			b |= 0x80
+		}
+		buf = append(buf, b)
+	}
+	return buf
+}
+
+// brotliStore returns a Brotli stream containing data in uncompressed
+// meta-blocks.
+// https://www.rfc-editor.org/rfc/rfc7932#section-9.2
+func brotliStore(data []byte) []byte {
+	// The stream header is a single 0 bit, for a window of 16 bits, which
+	// is followed by the header of the first meta-block in the same byte.
+	var out []byte
+	var bits, numBits uint32
+	writeBits := func(v, n uint32) {
+		bits |= v << numBits
+		numBits += n
+		for numBits >= 8 {
+			out = append(out, byte(bits))
+			bits >>= 8
+			numBits -= 8
+		}
+	}
+	flush := func() {
+		if numBits > 0 {
+			writeBits(0, 8-numBits)
+		}
+	}
+
+	writeBits(0, 1) // WBITS
+	for len(data) > 0 {
+		block := data
+		if len(block) > brotliBlockSize {
+			block = block[:brotliBlockSize]
+		}
+		data = data[len(block):]
+
+		writeBits(0, 1)                     // ISLAST
+		writeBits(0, 2)                     // MNIBBLES, for 4 nibbles
+		writeBits(uint32(len(block)-1), 16) // MLEN - 1
+		writeBits(1, 1)                     // ISUNCOMPRESSED
+		flush()
+		out = append(out, block...)
+	}
+	writeBits(1, 1) // ISLAST
+	writeBits(1, 1) // ISLASTEMPTY
+	flush()
+	return out
+}
@@ -0,0 +1,88 @@
+package sfnt
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteWOFFRoundTrip checks that fonts written as WOFF and WOFF2 decode
+// to the same tables, with checksums that match.
+func TestWriteWOFFRoundTrip(t *testing.T) {
+	writers := []struct {
+		name  string
+		write func(*Font, io.Writer) (int, error)
+	}{
+		{name: "WriteWOFF", write: (*Font).WriteWOFF},
+		{name: "WriteWOFF2", write: (*Font).WriteWOFF2},
+	}
+
+	for _, filename := range []string{"Roboto-BoldItalic.ttf", "Raleway-v4020-Regular.otf"} {
+		file, err := os.Open(filepath.Join("testdata", filename))
+		if err != nil {
+			t.Fatalf("Failed to open %q: %s\n", filename, err)
+		}
+		defer file.Close()
+
+		font, err := StrictParse(file)
+		if err != nil {
+			t.Fatalf("StrictParse(%q) err = %q, want nil", filename, err)
+		}
+
+		for _, writer := range writers {
+			var buf bytes.Buffer
+			n, err := writer.write(font, &buf)
+			if err != nil {
+				t.Fatalf("%s(%q) err = %q, want nil", writer.name, filename, err)
+			}
+			if n != buf.Len() {
+				t.Errorf("%s(%q) = %d, want %d", writer.name, filename, n, buf.Len())
+			}
+			if buf.Len()%4 != 0 {
+				t.Errorf("%s(%q) wrote %d bytes, want a multiple of 4", writer.name, filename, buf.Len())
+			}
+
+			got, err := StrictParse(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("StrictParse(%s(%q)) err = %q, want nil", writer.name, filename, err)
+			}
+			for _, tag := range font.Tags() {
+				if tag == TagHead {
+					continue
+				}
+				want, _ := font.Table(tag)
+				have, err := got.Table(tag)
+				if err != nil {
+					t.Errorf("%s(%q): Table(%q) err = %q, want nil", writer.name, filename, tag, err)
+					continue
+				}
+				if !bytes.Equal(want.Bytes(), have.Bytes()) {
+					t.Errorf("%s(%q): table %q differs after round trip", writer.name, filename, tag)
+				}
+			}
+			if err := got.VerifyChecksums(); err != nil {
+				t.Errorf("VerifyChecksums(%s(%q)) err = %q, want nil", writer.name, filename, err)
+			}
+		}
+	}
+}
+
+func TestAppendUIntBase128(t *testing.T) {
+	tests := []struct {
+		v    uint32
+		want []byte
+	}{
+		{0, []byte{0}},
+		{0x7F, []byte{0x7F}},
+		{0x80, []byte{0x81, 0x00}},
+		{0x3FFF, []byte{0xFF, 0x7F}},
+		{0xFFFFFFFF, []byte{0x8F, 0xFF, 0xFF, 0xFF, 0x7F}},
+	}
+	for _, test := range tests {
+		if got := appendUIntBase128(nil, test.v); !bytes.Equal(got, test.want) {
+			t.Errorf("appendUIntBase128(%#x) = %x, want %x", test.v, got, test.want)
+		}
+	}
+}
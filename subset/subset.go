@@ -0,0 +1,552 @@
+// Package subset creates subsets of fonts, which contain only the glyphs
+// needed to display some text, so that they can be embedded in documents or
+// served to web pages at a fraction of their size.
+//
+// The glyphs of a subset are those of the requested characters and glyph
+// IDs, along with the glyphs that 'GSUB' can substitute for them, such as
+// ligatures and alternates, and the components of composite glyphs. The
+// 'GSUB', 'GPOS' and 'GDEF' tables are restricted to those glyphs.
+//
+// Tables that refer to glyphs but are not supported by this package, such as
+// 'kern', 'hdmx', 'HVAR', 'COLR', 'SVG ', 'MATH' and the bitmap tables, are
+// removed from the subset, as are tables this package does not know about.
+// The 'post' table is replaced by version 3, without glyph names.
+package subset
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ConradIrwin/font/internal/layout"
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+var (
+	tagCFF2 = sfnt.MustNamedTag("CFF2")
+	tagGDEF = sfnt.MustNamedTag("GDEF")
+)
+
+// ErrUnsupported is wrapped by the error returned from Subset for fonts
+// that cannot be subset by this package.
+var ErrUnsupported = errors.New("unsupported font")
+
+// copiedTables are the tables that do not refer to glyphs, and are copied to
+// the subset as they are.
+var copiedTables = []sfnt.Tag{
+	sfnt.TagName, sfnt.TagCvt, sfnt.TagFpgm, sfnt.TagPrep, sfnt.TagGasp,
+	sfnt.TagFvar, sfnt.TagAvar, sfnt.TagSTAT, sfnt.TagMvar, sfnt.TagBASE,
+}
+
+// Options selects the contents of a subset.
+type Options struct {
+	// Runes contains the characters to keep. Characters that are not in the
+	// font are ignored.
+	Runes []rune
+	// Glyphs contains the glyphs to keep, in addition to those of Runes.
+	Glyphs []sfnt.GlyphID
+
+	// Features contains the layout features to keep. If it is nil every
+	// feature is kept.
+	Features []sfnt.Tag
+	// DropLayout removes the 'GSUB', 'GPOS' and 'GDEF' tables.
+	DropLayout bool
+	// DropHinting removes the hinting, as by Font.Dehint.
+	DropHinting bool
+	// RetainGlyphIDs keeps the glyph IDs of the font, so that the glyphs
+	// that are not kept are left empty, rather than renumbering the glyphs
+	// of the subset from 0.
+	RetainGlyphIDs bool
+}
+
+// subsetter holds the state used while subsetting a font.
+type subsetter struct {
+	font *sfnt.Font
+	out  *sfnt.Font // out is the subset, which shares unmodified tables with font.
+	opts Options
+
+	// glyphs maps the glyphs that are kept to their glyph ID in the subset.
+	glyphs layout.GlyphMap
+	// order contains the glyph of the font for each glyph of the subset.
+	// With RetainGlyphIDs it contains every glyph up to the last one kept,
+	// including those that are not in glyphs.
+	order []sfnt.GlyphID
+
+	// head is the 'head' table of the subset, which is a copy of the
+	// table of the font so that it can be modified.
+	head *sfnt.TableHead
+
+	// gsub and gpos are the parsed layout tables, or nil if there are none
+	// or they are dropped.
+	gsub, gpos *layout.Table
+}
+
+// Subset returns a new font containing only the glyphs selected by opts,
+// which always include glyph 0, the missing glyph.
+//
+// The original font is not modified, though unchanged tables are shared
+// between the two fonts.
+func Subset(font *sfnt.Font, opts Options) (*sfnt.Font, error) {
+	if font.HasTable(tagCFF2) {
+		return nil, fmt.Errorf("%w: subsetting %q outlines is not supported", ErrUnsupported, tagCFF2)
+	}
+	s := &subsetter{font: font, out: sfnt.New(font.Type()), opts: opts}
+
+	steps := []func() error{
+		s.parseLayout,
+		s.selectGlyphs,
+		s.copyTables,
+		s.subsetCmap,
+		s.subsetGlyf,
+		s.subsetCFF,
+		s.subsetMetrics,
+		s.subsetVertical,
+		s.subsetLayout,
+		s.subsetPost,
+		s.subsetOS2,
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.DropHinting {
+		if err := s.out.Dehint(); err != nil {
+			return nil, err
+		}
+	}
+	return s.out, nil
+}
+
+// parseLayout parses 'GSUB' and 'GPOS', unless the layout is dropped.
+func (s *subsetter) parseLayout() error {
+	if s.opts.DropLayout {
+		return nil
+	}
+	for _, t := range []struct {
+		tag   sfnt.Tag
+		table **layout.Table
+	}{{sfnt.TagGsub, &s.gsub}, {sfnt.TagGpos, &s.gpos}} {
+		if !s.font.HasTable(t.tag) {
+			continue
+		}
+		table, err := s.font.Table(t.tag)
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", t.tag, err)
+		}
+		if *t.table, err = layout.Parse(t.tag, table.Bytes()); err != nil {
+			return fmt.Errorf("parsing %q: %w", t.tag, err)
+		}
+	}
+	return nil
+}
+
+// selectGlyphs selects the glyphs of the subset and numbers them.
+func (s *subsetter) selectGlyphs() error {
+	maxp, err := s.font.MaxpTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagMaxp, err)
+	}
+	numGlyphs := int(maxp.NumGlyphs)
+
+	set := layout.GlyphSet{0: true}
+	if len(s.opts.Runes) > 0 {
+		cmap, err := s.font.CmapTable()
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagCmap, err)
+		}
+		for _, r := range s.opts.Runes {
+			set[cmap.Lookup(r)] = true
+		}
+	}
+	for _, g := range s.opts.Glyphs {
+		if int(g) >= numGlyphs {
+			return fmt.Errorf("glyph %d out of range", g)
+		}
+		set[g] = true
+	}
+
+	if s.gsub != nil {
+		s.gsub.Closure(s.gsub.LookupFilter(s.gsub.FeatureFilter(s.opts.Features)), set)
+	}
+	if err := s.componentClosure(set); err != nil {
+		return err
+	}
+
+	kept := make([]sfnt.GlyphID, 0, len(set))
+	for g := range set {
+		if int(g) < numGlyphs {
+			kept = append(kept, g)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i] < kept[j] })
+
+	s.glyphs = make(layout.GlyphMap, len(kept))
+	if !s.opts.RetainGlyphIDs {
+		for i, g := range kept {
+			s.glyphs[g] = sfnt.GlyphID(i)
+		}
+		s.order = kept
+		return nil
+	}
+	for _, g := range kept {
+		s.glyphs[g] = g
+	}
+	s.order = make([]sfnt.GlyphID, int(kept[len(kept)-1])+1)
+	for i := range s.order {
+		s.order[i] = sfnt.GlyphID(i)
+	}
+	return nil
+}
+
+// componentClosure adds the components of the composite glyphs in set.
+func (s *subsetter) componentClosure(set layout.GlyphSet) error {
+	if !s.font.HasTable(sfnt.TagGlyf) {
+		return nil
+	}
+	glyf, err := s.font.GlyfTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagGlyf, err)
+	}
+
+	var add func(g sfnt.GlyphID, depth int) error
+	add = func(g sfnt.GlyphID, depth int) error {
+		if int(g) >= glyf.NumGlyphs() {
+			return nil
+		}
+		if depth > maxComponentDepth {
+			return fmt.Errorf("glyph %d: components are nested too deeply", g)
+		}
+		glyph, err := glyf.Glyph(g)
+		if err != nil {
+			return err
+		}
+		for _, c := range glyph.Components {
+			set[c.Glyph] = true
+			if err := add(c.Glyph, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	glyphs := make([]sfnt.GlyphID, 0, len(set))
+	for g := range set {
+		glyphs = append(glyphs, g)
+	}
+	for _, g := range glyphs {
+		if err := add(g, 0); err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagGlyf, err)
+		}
+	}
+	return nil
+}
+
+// maxComponentDepth limits the nesting of composite glyphs, to avoid
+// recursing forever in fonts where a glyph contains itself.
+const maxComponentDepth = 64
+
+// copyTables copies the tables that are not modified to the subset, and
+// 'head', which is modified by later steps.
+func (s *subsetter) copyTables() error {
+	head, err := s.font.HeadTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHead, err)
+	}
+	newHead := *head
+	s.head = &newHead
+	s.out.AddTable(sfnt.TagHead, s.head)
+
+	for _, tag := range copiedTables {
+		if !s.font.HasTable(tag) {
+			continue
+		}
+		table, err := s.font.Table(tag)
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", tag, err)
+		}
+		s.out.AddTable(tag, table)
+	}
+	return nil
+}
+
+// kept returns true if glyph i of the subset is one of the glyphs kept,
+// which is only false for the empty glyphs left with RetainGlyphIDs.
+func (s *subsetter) kept(i int) bool {
+	_, found := s.glyphs[s.order[i]]
+	return found
+}
+
+// subsetCmap maps the characters of Runes to their glyphs in the subset.
+func (s *subsetter) subsetCmap() error {
+	cmap, err := s.font.CmapTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagCmap, err)
+	}
+	mappings := make(map[rune]sfnt.GlyphID)
+	if unicode := cmap.UnicodeSubtable(); unicode != nil {
+		for _, r := range s.opts.Runes {
+			if g, found := s.glyphs[unicode.Mappings[r]]; found && g != 0 {
+				mappings[r] = g
+			}
+		}
+	}
+	s.out.AddTable(sfnt.TagCmap, sfnt.NewTableCmap(mappings))
+	return nil
+}
+
+// subsetGlyf subsets 'glyf', 'loca' and 'gvar', renumbering the components
+// of composite glyphs.
+func (s *subsetter) subsetGlyf() error {
+	if !s.font.HasTable(sfnt.TagGlyf) {
+		return nil
+	}
+	glyf, err := s.font.GlyfTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagGlyf, err)
+	}
+	var gvar *sfnt.TableGvar
+	if s.font.HasTable(sfnt.TagGvar) {
+		if gvar, err = s.font.GvarTable(); err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagGvar, err)
+		}
+	}
+
+	glyphs := make([][]byte, len(s.order))
+	variations := make([][]sfnt.TupleVariation, len(s.order))
+	for i, id := range s.order {
+		if !s.kept(i) {
+			continue
+		}
+		glyph, err := glyf.Glyph(id)
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagGlyf, err)
+		}
+		// The points varied by 'gvar' are the points of simple glyphs, or
+		// the offsets of the components of composite glyphs, followed by
+		// four phantom points.
+		numPoints := len(glyph.Points) + 4
+		if glyph.IsComposite() {
+			for j := range glyph.Components {
+				glyph.Components[j].Glyph = s.glyphs[glyph.Components[j].Glyph]
+			}
+			numPoints = len(glyph.Components) + 4
+			glyphs[i] = glyph.Bytes()
+		} else {
+			glyphs[i], _ = glyf.GlyphBytes(id)
+		}
+		if gvar != nil {
+			if variations[i], err = gvar.GlyphVariations(id, numPoints); err != nil {
+				return fmt.Errorf("parsing %q: glyph %d: %w", sfnt.TagGvar, id, err)
+			}
+		}
+	}
+
+	newGlyf, loca := sfnt.NewTableGlyf(glyphs)
+	s.out.AddTable(sfnt.TagGlyf, newGlyf)
+	s.out.AddTable(sfnt.TagLoca, loca)
+	if gvar != nil {
+		s.out.AddTable(sfnt.TagGvar, sfnt.NewTableGvar(gvar.AxisCount, variations))
+	}
+
+	s.head.IndexToLocFormat = 1
+	if loca.Short {
+		s.head.IndexToLocFormat = 0
+	}
+	return nil
+}
+
+// cffEndchar is a charstring that draws nothing, used for the glyphs that
+// are not kept with RetainGlyphIDs.
+var cffEndchar = []byte{14}
+
+func (s *subsetter) subsetCFF() error {
+	if !s.font.HasTable(sfnt.TagCFF) {
+		return nil
+	}
+	cff, err := s.font.CFFTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagCFF, err)
+	}
+	if s.opts.RetainGlyphIDs {
+		copied := *cff
+		copied.CharStrings = append([][]byte(nil), cff.CharStrings...)
+		for i := range s.order {
+			if !s.kept(i) && i < len(copied.CharStrings) {
+				copied.CharStrings[i] = cffEndchar
+			}
+		}
+		cff = &copied
+	}
+	newCFF, err := cff.Subset(s.order)
+	if err != nil {
+		return fmt.Errorf("subsetting %q: %w", sfnt.TagCFF, err)
+	}
+	s.out.AddTable(sfnt.TagCFF, newCFF)
+	return nil
+}
+
+// subsetMetrics subsets 'hmtx', and updates 'hhea' and 'maxp' to match.
+func (s *subsetter) subsetMetrics() error {
+	hmtx, err := s.font.HmtxTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHmtx, err)
+	}
+	hhea, err := s.font.HheaTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagHhea, err)
+	}
+	newHhea := *hhea
+
+	metrics := make([]sfnt.HMetric, len(s.order))
+	for i, id := range s.order {
+		if s.kept(i) {
+			metrics[i] = hmtx.Metric(id)
+		}
+	}
+
+	newHmtx := sfnt.NewTableHmtx(metrics)
+	newHhea.NumOfLongHorMetrics = int16(len(newHmtx.Metrics))
+	s.out.AddTable(sfnt.TagHhea, &newHhea)
+	s.out.AddTable(sfnt.TagHmtx, newHmtx)
+
+	maxp, err := s.font.MaxpTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagMaxp, err)
+	}
+	newMaxp := *maxp
+	newMaxp.NumGlyphs = uint16(len(s.order))
+	s.out.AddTable(sfnt.TagMaxp, &newMaxp)
+	return nil
+}
+
+// subsetVertical subsets 'vmtx' and 'VORG', and updates 'vhea' to match.
+func (s *subsetter) subsetVertical() error {
+	if s.font.HasTable(sfnt.TagVhea) && s.font.HasTable(sfnt.TagVmtx) {
+		vmtx, err := s.font.VmtxTable()
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagVmtx, err)
+		}
+		vhea, err := s.font.VheaTable()
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagVhea, err)
+		}
+		newVhea := *vhea
+
+		metrics := make([]sfnt.VMetric, len(s.order))
+		for i, id := range s.order {
+			if s.kept(i) {
+				metrics[i] = vmtx.Metric(id)
+			}
+		}
+		newVmtx := sfnt.NewTableVmtx(metrics)
+		newVhea.NumOfLongVerMetrics = uint16(len(newVmtx.Metrics))
+		s.out.AddTable(sfnt.TagVhea, &newVhea)
+		s.out.AddTable(sfnt.TagVmtx, newVmtx)
+	}
+
+	if s.font.HasTable(sfnt.TagVORG) {
+		vorg, err := s.font.VORGTable()
+		if err != nil {
+			return fmt.Errorf("parsing %q: %w", sfnt.TagVORG, err)
+		}
+		newVORG := *vorg
+		newVORG.Origins = nil
+		for _, o := range vorg.Origins {
+			if g, found := s.glyphs[o.Glyph]; found {
+				newVORG.Origins = append(newVORG.Origins, sfnt.VertOrigin{Glyph: g, VertOriginY: o.VertOriginY})
+			}
+		}
+		s.out.AddTable(sfnt.TagVORG, &newVORG)
+	}
+	return nil
+}
+
+// subsetLayout subsets 'GSUB', 'GPOS' and 'GDEF', unless they are dropped.
+func (s *subsetter) subsetLayout() error {
+	for _, t := range []*layout.Table{s.gsub, s.gpos} {
+		if t == nil {
+			continue
+		}
+		features := t.FeatureFilter(s.opts.Features)
+		m := &layout.Mapping{Glyphs: s.glyphs, Lookups: t.LookupFilter(features)}
+		buf, err := t.Write(m, features)
+		if err != nil {
+			return fmt.Errorf("subsetting %q: %w", t.Tag, err)
+		}
+		if err := s.addRawTable(t.Tag, buf); err != nil {
+			return err
+		}
+	}
+
+	if s.opts.DropLayout || !s.font.HasTable(tagGDEF) {
+		return nil
+	}
+	gdef, err := s.font.Table(tagGDEF)
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", tagGDEF, err)
+	}
+	t, err := layout.ParseGDEF(gdef.Bytes())
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", tagGDEF, err)
+	}
+	buf, err := t.Mapped(s.glyphs).Bytes()
+	if err != nil {
+		return fmt.Errorf("subsetting %q: %w", tagGDEF, err)
+	}
+	return s.addRawTable(tagGDEF, buf)
+}
+
+// subsetPost replaces 'post' with version 3, which has no glyph names.
+func (s *subsetter) subsetPost() error {
+	if !s.font.HasTable(sfnt.TagPost) {
+		return nil
+	}
+	post, err := s.font.Table(sfnt.TagPost)
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagPost, err)
+	}
+	const headerSize = 32
+	buf := make([]byte, headerSize)
+	copy(buf, post.Bytes())
+	// Set the version to 3.0, and clear the memory usage, which depends on the glyphs.
+	copy(buf, []byte{0, 3, 0, 0})
+	for i := 16; i < headerSize; i++ {
+		buf[i] = 0
+	}
+	return s.addRawTable(sfnt.TagPost, buf)
+}
+
+// subsetOS2 updates the range of characters in 'OS/2'.
+func (s *subsetter) subsetOS2() error {
+	if !s.font.HasTable(sfnt.TagOS2) {
+		return nil
+	}
+	os2, err := s.font.OS2Table()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagOS2, err)
+	}
+	newOS2 := *os2
+	cmap, err := s.out.CmapTable()
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", sfnt.TagCmap, err)
+	}
+	newOS2.FsFirstCharIndex, newOS2.FsLastCharIndex = cmap.CharIndexRange()
+	s.out.AddTable(sfnt.TagOS2, &newOS2)
+	return nil
+}
+
+// addRawTable adds a table written as bytes to the subset.
+func (s *subsetter) addRawTable(tag sfnt.Tag, buf []byte) error {
+	return addRawTable(s.out, tag, buf)
+}
+
+// addRawTable adds a table written as bytes to font.
+func addRawTable(font *sfnt.Font, tag sfnt.Tag, buf []byte) error {
+	table, err := sfnt.ParseTable(tag, buf)
+	if err != nil {
+		return fmt.Errorf("parsing %q: %w", tag, err)
+	}
+	font.AddTable(tag, table)
+	return nil
+}
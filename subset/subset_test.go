@@ -0,0 +1,275 @@
+package subset
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/ConradIrwin/font/internal/layout"
+	"github.com/ConradIrwin/font/sfnt"
+)
+
+func parseFont(t *testing.T, filename string) *sfnt.Font {
+	t.Helper()
+	buf, err := os.ReadFile(filepath.Join("..", "sfnt", "testdata", filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := sfnt.Parse(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return font
+}
+
+// reparse writes the font and parses it again.
+func reparse(t *testing.T, font *sfnt.Font) *sfnt.Font {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := font.WriteOTF(&buf); err != nil {
+		t.Fatalf("WriteOTF() err = %q, want nil", err)
+	}
+	font, err := sfnt.StrictParse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("parsing the subset: %q", err)
+	}
+	return font
+}
+
+func parseLayout(t *testing.T, font *sfnt.Font, tag sfnt.Tag) *layout.Table {
+	t.Helper()
+	table, err := font.Table(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := layout.Parse(tag, table.Bytes())
+	if err != nil {
+		t.Fatalf("parsing %q: %q", tag, err)
+	}
+	return l
+}
+
+func TestSubset(t *testing.T) {
+	font := parseFont(t, "Roboto-BoldItalic.ttf")
+	cmap, err := font.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	gsub := parseLayout(t, font, sfnt.TagGsub)
+	fi, ok := gsub.Ligature(cmap.Lookup('f'), cmap.Lookup('i'))
+	if !ok {
+		t.Fatal("font has no fi ligature")
+	}
+	gpos := parseLayout(t, font, sfnt.TagGpos)
+	kern, ok := gpos.Kerning(cmap.Lookup('A'), cmap.Lookup('V'))
+	if !ok || kern == 0 {
+		t.Fatal("font has no kerning for AV")
+	}
+
+	subset, err := Subset(font, Options{Runes: []rune("AVfiÅ")})
+	if err != nil {
+		t.Fatalf("Subset() err = %q, want nil", err)
+	}
+	subset = reparse(t, subset)
+
+	maxp, err := subset.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxp.NumGlyphs < 6 || maxp.NumGlyphs > 100 {
+		t.Errorf("subset has %d glyphs, want a few more than 6", maxp.NumGlyphs)
+	}
+
+	newCmap, err := subset.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Glyphs are numbered in their original order.
+	for _, r := range "AVfiÅ" {
+		g := newCmap.Lookup(r)
+		if g == 0 {
+			t.Fatalf("subset has no glyph for %q", r)
+		}
+		want, err := font.GlyphOutline(cmap.Lookup(r), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := subset.GlyphOutline(g, nil)
+		if err != nil {
+			t.Fatalf("GlyphOutline(%d) err = %q, want nil", g, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("outline of %q differs from the font", r)
+		}
+	}
+	if g := newCmap.Lookup('B'); g != 0 {
+		t.Errorf("subset maps 'B' to glyph %d, want 0", g)
+	}
+	if newCmap.Lookup('A') >= newCmap.Lookup('V') || newCmap.Lookup('V') >= newCmap.Lookup('f') {
+		t.Errorf("subset glyphs are not in the order of the font")
+	}
+
+	newFi, ok := parseLayout(t, subset, sfnt.TagGsub).Ligature(newCmap.Lookup('f'), newCmap.Lookup('i'))
+	if !ok {
+		t.Fatal("subset has no fi ligature")
+	}
+	want, err := font.GlyphOutline(fi, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := subset.GlyphOutline(newFi, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("outline of the fi ligature differs from the font")
+	}
+
+	if got, ok := parseLayout(t, subset, sfnt.TagGpos).Kerning(newCmap.Lookup('A'), newCmap.Lookup('V')); !ok || got != kern {
+		t.Errorf("subset kerning for AV = %d, %v, want %d, true", got, ok, kern)
+	}
+	if !subset.HasTable(tagGDEF) {
+		t.Errorf("subset has no %q table", tagGDEF)
+	}
+}
+
+func TestSubsetOptions(t *testing.T) {
+	font := parseFont(t, "Roboto-BoldItalic.ttf")
+	liga := sfnt.MustNamedTag("liga")
+
+	subset, err := Subset(font, Options{Runes: []rune("fi"), Features: []sfnt.Tag{liga}, DropHinting: true})
+	if err != nil {
+		t.Fatalf("Subset() err = %q, want nil", err)
+	}
+	subset = reparse(t, subset)
+	for _, tag := range []sfnt.Tag{sfnt.TagGsub, sfnt.TagGpos} {
+		for _, f := range parseLayout(t, subset, tag).FeatureTags() {
+			if f != liga {
+				t.Errorf("subset %q has feature %q, want only %q", tag, f, liga)
+			}
+		}
+	}
+	if subset.HasTable(sfnt.TagFpgm) || subset.HasTable(sfnt.TagPrep) {
+		t.Errorf("subset with DropHinting has hinting tables")
+	}
+
+	subset, err = Subset(font, Options{Runes: []rune("fi"), DropLayout: true})
+	if err != nil {
+		t.Fatalf("Subset() err = %q, want nil", err)
+	}
+	for _, tag := range []sfnt.Tag{sfnt.TagGsub, sfnt.TagGpos, tagGDEF} {
+		if subset.HasTable(tag) {
+			t.Errorf("subset with DropLayout has %q table", tag)
+		}
+	}
+	maxp, err := subset.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxp.NumGlyphs != 3 {
+		t.Errorf("subset with DropLayout has %d glyphs, want 3", maxp.NumGlyphs)
+	}
+
+	if _, err := Subset(font, Options{Glyphs: []sfnt.GlyphID{0xFFFF}}); err == nil {
+		t.Errorf("Subset() of a missing glyph err = nil, want an error")
+	}
+	cff2 := sfnt.New(sfnt.TypeOpenType)
+	cff2.AddTable(tagCFF2, nil)
+	if _, err := Subset(cff2, Options{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Subset() of a CFF2 font err = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestSubsetRetainGlyphIDs(t *testing.T) {
+	font := parseFont(t, "Roboto-BoldItalic.ttf")
+	cmap, err := font.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := cmap.Lookup('a'), cmap.Lookup('b')
+
+	subset, err := Subset(font, Options{Runes: []rune("b"), DropLayout: true, RetainGlyphIDs: true})
+	if err != nil {
+		t.Fatalf("Subset() err = %q, want nil", err)
+	}
+	subset = reparse(t, subset)
+	maxp, err := subset.MaxpTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int(maxp.NumGlyphs) != int(b)+1 {
+		t.Errorf("subset has %d glyphs, want %d", maxp.NumGlyphs, b+1)
+	}
+	newCmap, err := subset.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := newCmap.Lookup('b'); g != b {
+		t.Errorf("subset maps 'b' to glyph %d, want %d", g, b)
+	}
+	outline, err := subset.GlyphOutline(a, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outline.Points) != 0 || outline.AdvanceWidth != 0 {
+		t.Errorf("glyph of 'a' is not empty")
+	}
+}
+
+func TestSubsetCFF(t *testing.T) {
+	font := parseFont(t, "Raleway-v4020-Regular.otf")
+	cmap, err := font.CmapTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cff, err := font.CFFTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmtx, err := font.HmtxTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, retain := range []bool{false, true} {
+		subset, err := Subset(font, Options{Runes: []rune("Raleway"), RetainGlyphIDs: retain})
+		if err != nil {
+			t.Fatalf("Subset() err = %q, want nil", err)
+		}
+		subset = reparse(t, subset)
+		newCmap, err := subset.CmapTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		newCFF, err := subset.CFFTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		newHmtx, err := subset.HmtxTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		maxp, err := subset.MaxpTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(newCFF.CharStrings) != int(maxp.NumGlyphs) {
+			t.Errorf("subset has %d charstrings and %d glyphs", len(newCFF.CharStrings), maxp.NumGlyphs)
+		}
+		for _, r := range "Raleway" {
+			g, old := newCmap.Lookup(r), cmap.Lookup(r)
+			if retain && g != old {
+				t.Errorf("subset maps %q to glyph %d, want %d", r, g, old)
+			}
+			if newCFF.Charset[g] != cff.Charset[old] {
+				t.Errorf("glyph of %q has string ID %d, want %d", r, newCFF.Charset[g], cff.Charset[old])
+			}
+			if newHmtx.Metric(g) != hmtx.Metric(old) {
+				t.Errorf("metrics of %q = %v, want %v", r, newHmtx.Metric(g), hmtx.Metric(old))
+			}
+		}
+	}
+}